@@ -0,0 +1,84 @@
+package counts
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The binary encoding of a count is its value as an unsigned varint,
+// in the format used by protocol buffers and by `encoding/binary`:
+// seven bits per byte, least significant group first, with the high
+// bit set on every byte but the last. The encoding is canonical: a
+// value has exactly one encoding, and encodings with superfluous
+// trailing zero groups are rejected. A saturated count is encoded like
+// any other value.
+
+var (
+	// ErrTruncated is returned when a buffer ends in the middle of
+	// an encoded count.
+	ErrTruncated = errors.New("truncated count encoding")
+
+	// ErrNotCanonical is returned when an encoded count is
+	// overlong or doesn't fit in 64 bits.
+	ErrNotCanonical = errors.New("non-canonical count encoding")
+
+	// ErrOutOfRange is returned when an encoded count is too big
+	// for the type that it is being decoded into.
+	ErrOutOfRange = errors.New("count out of range")
+)
+
+// AppendBinary appends the binary encoding of `n` to `buf` and returns
+// the extended buffer.
+func (n Count32) AppendBinary(buf []byte) []byte {
+	return appendUvarint(buf, uint64(n))
+}
+
+// AppendBinary appends the binary encoding of `n` to `buf` and returns
+// the extended buffer.
+func (n Count64) AppendBinary(buf []byte) []byte {
+	return appendUvarint(buf, uint64(n))
+}
+
+// ParseCount32 decodes a `Count32` from the start of `buf`, returning
+// it and the rest of the buffer.
+func ParseCount32(buf []byte) (Count32, []byte, error) {
+	n, rest, err := parseUvarint(buf)
+	if err != nil {
+		return 0, buf, err
+	}
+	if n > math.MaxUint32 {
+		return 0, buf, ErrOutOfRange
+	}
+	return Count32(n), rest, nil
+}
+
+// ParseCount64 decodes a `Count64` from the start of `buf`, returning
+// it and the rest of the buffer.
+func ParseCount64(buf []byte) (Count64, []byte, error) {
+	n, rest, err := parseUvarint(buf)
+	if err != nil {
+		return 0, buf, err
+	}
+	return Count64(n), rest, nil
+}
+
+func appendUvarint(buf []byte, n uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(tmp[:], n)
+	return append(buf, tmp[:l]...)
+}
+
+func parseUvarint(buf []byte) (uint64, []byte, error) {
+	n, l := binary.Uvarint(buf)
+	switch {
+	case l == 0:
+		return 0, buf, ErrTruncated
+	case l < 0:
+		return 0, buf, ErrNotCanonical
+	case l > 1 && buf[l-1] == 0:
+		// The last group is zero, so a shorter encoding exists.
+		return 0, buf, ErrNotCanonical
+	}
+	return n, buf[l:], nil
+}
@@ -0,0 +1,105 @@
+package counts_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+)
+
+func TestCountBinary(t *testing.T) {
+	for _, p := range []struct {
+		value   uint64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{300, []byte{0xac, 0x02}},
+		{math.MaxUint32, []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+		{
+			math.MaxUint64,
+			[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+	} {
+		buf := counts.Count64(p.value).AppendBinary([]byte("prefix"))
+		assert.Equalf(t, append([]byte("prefix"), p.encoded...), buf, "encoding %d", p.value)
+
+		n, rest, err := counts.ParseCount64(append(p.encoded, "suffix"...))
+		if assert.NoErrorf(t, err, "decoding %d", p.value) {
+			assert.Equal(t, counts.Count64(p.value), n)
+			assert.Equal(t, []byte("suffix"), rest)
+		}
+
+		n32, _, err := counts.ParseCount32(p.encoded)
+		if p.value > math.MaxUint32 {
+			assert.ErrorIs(t, err, counts.ErrOutOfRange)
+		} else if assert.NoError(t, err) {
+			assert.Equal(t, counts.Count32(p.value), n32)
+			assert.Equal(t, p.encoded, n32.AppendBinary(nil))
+		}
+	}
+
+	for _, p := range []struct {
+		name    string
+		encoded []byte
+		err     error
+	}{
+		{"empty", nil, counts.ErrTruncated},
+		{"truncated", []byte{0x80}, counts.ErrTruncated},
+		{"overlong", []byte{0x81, 0x00}, counts.ErrNotCanonical},
+		{
+			"too big",
+			[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02},
+			counts.ErrNotCanonical,
+		},
+	} {
+		_, rest, err := counts.ParseCount64(p.encoded)
+		assert.ErrorIsf(t, err, p.err, "decoding %s value", p.name)
+		assert.Equal(t, p.encoded, rest)
+	}
+}
+
+func FuzzCountBinary(f *testing.F) {
+	for _, seed := range [][]byte{
+		{0x00},
+		{0x80, 0x01},
+		{0xff, 0xff, 0xff, 0xff, 0x0f},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0x81, 0x00},
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		n, rest, err := counts.ParseCount64(buf)
+		if err != nil {
+			require.Equal(t, buf, rest)
+			return
+		}
+
+		// A successfully-decoded value re-encodes to exactly the
+		// bytes that were consumed:
+		encoded := n.AppendBinary(nil)
+		require.True(t, bytes.Equal(encoded, buf[:len(buf)-len(rest)]))
+
+		// ...and the encoding of the value decodes back to it:
+		n2, rest2, err := counts.ParseCount64(encoded)
+		require.NoError(t, err)
+		require.Equal(t, n, n2)
+		require.Empty(t, rest2)
+
+		n32, _, err := counts.ParseCount32(buf)
+		if n > math.MaxUint32 {
+			require.ErrorIs(t, err, counts.ErrOutOfRange)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, uint64(n), uint64(n32))
+		}
+	})
+}
@@ -32,9 +32,17 @@ func (n1 Count32) Plus(n2 Count32) Count32 {
 	return n
 }
 
-// Increment increases `*n1` by `n2`, capped at math.MaxUint32.
-func (n1 *Count32) Increment(n2 Count32) {
-	*n1 = n1.Plus(n2)
+// Increment increases `*n1` by `n2`, capped at math.MaxUint32. It
+// returns true iff the sum had to be capped, which (unlike a value of
+// math.MaxUint32) tells for sure that the count saturated.
+func (n1 *Count32) Increment(n2 Count32) bool {
+	n := *n1 + n2
+	if n < *n1 {
+		*n1 = math.MaxUint32
+		return true
+	}
+	*n1 = n
+	return false
 }
 
 // AdjustMaxIfNecessary adjusts `*n1` to be `max(*n1, n2)`. Return
@@ -83,9 +91,17 @@ func (n1 Count64) Plus(n2 Count64) Count64 {
 	return n
 }
 
-// Increment increases `*n1` by `n2`, capped at math.MaxUint64.
-func (n1 *Count64) Increment(n2 Count64) {
-	*n1 = n1.Plus(n2)
+// Increment increases `*n1` by `n2`, capped at math.MaxUint64. It
+// returns true iff the sum had to be capped, which (unlike a value of
+// math.MaxUint64) tells for sure that the count saturated.
+func (n1 *Count64) Increment(n2 Count64) bool {
+	n := *n1 + n2
+	if n < *n1 {
+		*n1 = math.MaxUint64
+		return true
+	}
+	*n1 = n
+	return false
 }
 
 // AdjustMaxIfNecessary adjusts `*n1` to be `max(*n1, n2)`. Return
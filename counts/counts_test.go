@@ -1,6 +1,8 @@
 package counts_test
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/github/git-sizer/counts"
@@ -51,3 +53,57 @@ func TestCount64(t *testing.T) {
 	assert.Equalf(uint64(0xffffffffffffffff), value, "Count64(0xffffffffffffffff).ToUint64() value")
 	assert.True(overflow, "NewCount64(0xffffffffffffffff).ToUint64() overflows")
 }
+
+func TestIncrementSaturation(t *testing.T) {
+	assert := assert.New(t)
+
+	// Reaching the maximum exactly is not saturation:
+	c32 := counts.Count32(math.MaxUint32 - 1)
+	assert.False(c32.Increment(1))
+	assert.Equal(counts.Count32(math.MaxUint32), c32)
+	assert.False(c32.Increment(0))
+
+	// ...but going past it is:
+	assert.True(c32.Increment(1))
+	assert.Equal(counts.Count32(math.MaxUint32), c32)
+
+	c64 := counts.Count64(math.MaxUint64 - 1)
+	assert.False(c64.Increment(1))
+	assert.True(c64.Increment(1))
+	assert.Equal(counts.Count64(math.MaxUint64), c64)
+}
+
+func TestSizedCount(t *testing.T) {
+	assert := assert.New(t)
+
+	c := counts.SizedCount{Value: 42}
+	assert.Equal("42", c.String())
+
+	j, err := json.Marshal(c)
+	assert.NoError(err)
+	assert.Equal(`{"value":42}`, string(j))
+
+	c = counts.SizedCount{Value: math.MaxUint32, Approximate: true}
+	assert.Equal("4294967295~", c.String())
+
+	j, err = json.Marshal(c)
+	assert.NoError(err)
+	assert.Equal(`{"value":4294967295,"approximate":true}`, string(j))
+
+	var c2 counts.SizedCount
+	assert.NoError(json.Unmarshal(j, &c2))
+	assert.Equal(c, c2)
+
+	// A sum is approximate if either term is:
+	sum := counts.SizedCount{Value: 1}.Plus(c)
+	assert.True(sum.Approximate)
+	assert.Equal(uint64(math.MaxUint32+1), sum.Value)
+
+	// ...or if it saturates:
+	sum = counts.SizedCount{Value: math.MaxUint64 - 1}.Plus(counts.SizedCount{Value: 2})
+	assert.Equal(counts.SizedCount{Value: math.MaxUint64, Approximate: true}, sum)
+
+	// The largest value that doesn't saturate is exact:
+	sum = counts.SizedCount{Value: math.MaxUint64 - 1}.Plus(counts.SizedCount{Value: 1})
+	assert.Equal(counts.SizedCount{Value: math.MaxUint64}, sum)
+}
@@ -94,12 +94,15 @@ func (h *Humaner) FormatNumber(n uint64, unit string) (numeral string, unitStrin
 
 // Format formats values, aligned, in `len(unit) + 10` or fewer
 // characters (except for extremely large numbers). It returns strings
-// representing the numeral and the unit string.
+// representing the numeral and the unit string. A value that has
+// saturated is only a lower bound, so its numeral is prefixed with
+// ">=".
 func (h *Humaner) Format(value Humanable, unit string) (numeral string, unitString string) {
 	n, overflow := value.ToUint64()
+	numeral, unitString = h.FormatNumber(n, unit)
 	if overflow {
-		return "∞", unit
+		numeral = ">=" + numeral
 	}
 
-	return h.FormatNumber(n, unit)
+	return numeral, unitString
 }
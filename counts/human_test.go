@@ -114,8 +114,8 @@ func TestLimits32(t *testing.T) {
 
 	c := counts.NewCount32(0xffffffff)
 	number, unit := counts.Metric.Format(c, "cd")
-	assert.Equalf("∞", number, "Number for Count32(0xffffffff) in metric")
-	assert.Equalf("cd", unit, "Unit for Count32(0xffffffff) in metric")
+	assert.Equalf(">=4.29", number, "Number for Count32(0xffffffff) in metric")
+	assert.Equalf("Gcd", unit, "Unit for Count32(0xffffffff) in metric")
 }
 
 func TestLimits64(t *testing.T) {
@@ -123,6 +123,6 @@ func TestLimits64(t *testing.T) {
 
 	c := counts.NewCount64(0xffffffffffffffff)
 	number, unit := counts.Metric.Format(c, "B")
-	assert.Equalf("∞", number, "Number for Count64(0xffffffffffffffff) in metric")
-	assert.Equalf("B", unit, "Unit for Count64(0xffffffffffffffff) in metric")
+	assert.Equalf(">=18447", number, "Number for Count64(0xffffffffffffffff) in metric")
+	assert.Equalf("PB", unit, "Unit for Count64(0xffffffffffffffff) in metric")
 }
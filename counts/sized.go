@@ -0,0 +1,74 @@
+package counts
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// SizedCount is the value of a count, together with whether it is
+// only approximate. A `Count32` or `Count64` that saturates sticks at
+// its maximum value, which is indistinguishable from a count that
+// really has that value; a `SizedCount` records the saturation
+// explicitly (as reported by `Increment()` when the count was
+// accumulated), so that consumers needn't know the convention.
+type SizedCount struct {
+	Value uint64
+
+	// Approximate is true if the count saturated, in which case
+	// `Value` is only a lower bound.
+	Approximate bool
+}
+
+// ToUint64 returns the value of `n`, and whether it is approximate.
+// It implements `Humanable`.
+func (n SizedCount) ToUint64() (uint64, bool) {
+	return n.Value, n.Approximate
+}
+
+// Plus returns the sum of two `SizedCount`s, capped at
+// math.MaxUint64. The sum is approximate if either term is, or if
+// the sum was capped.
+func (n1 SizedCount) Plus(n2 SizedCount) SizedCount {
+	n := SizedCount{
+		Value:       n1.Value + n2.Value,
+		Approximate: n1.Approximate || n2.Approximate,
+	}
+	if n.Value < n1.Value {
+		// Overflow
+		n.Value = math.MaxUint64
+		n.Approximate = true
+	}
+	return n
+}
+
+// String returns the value of `n` in decimal, followed by `~` if it
+// is approximate.
+func (n SizedCount) String() string {
+	s := strconv.FormatUint(n.Value, 10)
+	if n.Approximate {
+		s += "~"
+	}
+	return s
+}
+
+type sizedCountJSON struct {
+	Value       uint64 `json:"value"`
+	Approximate bool   `json:"approximate,omitempty"`
+}
+
+// MarshalJSON encodes `n` as an object with a "value" key and, if
+// `n` is approximate, an `"approximate": true` key.
+func (n SizedCount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sizedCountJSON(n))
+}
+
+// UnmarshalJSON decodes the format written by `MarshalJSON()`.
+func (n *SizedCount) UnmarshalJSON(data []byte) error {
+	var j sizedCountJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*n = SizedCount(j)
+	return nil
+}
@@ -8,11 +8,14 @@ import (
 	"io"
 	"os"
 	"runtime/pprof"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 
+	"github.com/github/git-sizer/counts"
 	"github.com/github/git-sizer/git"
 	"github.com/github/git-sizer/internal/refopts"
 	"github.com/github/git-sizer/isatty"
@@ -40,20 +43,237 @@ const usage = `usage: git-sizer [OPTS] [ROOT...]
                                * 'full' - show full names
                                Default is '--names=full'. Can be set via
                                gitconfig: 'sizer.names'.
+      --compact                output a compact table that is sized to fit
+                               the terminal (80 columns if the output is not
+                               a terminal)
+      --color=WHEN             when to use colors in '--compact' output:
+                               'auto' (only if the output is a terminal),
+                               'always', or 'never'. Default: '--color=auto'
+      --no-color               equivalent to '--color=never'
+      --level-names            in '--compact' output, show the name of each
+                               level of concern instead of a bar of stars.
+                               The names can be set via gitconfig:
+                               'sizer.concernNames' (four comma-separated
+                               names; default: 'ok,low,high,critical')
   -j, --json                   output results in JSON format
+      --label=NAME=VALUE       attach the label NAME (lowercase letters,
+                               digits, and '_'; not 'path' or 'json_version')
+                               with value VALUE to the report. It appears in
+                               the JSON output under 'labels' and at the top
+                               of the tables. Can be repeated
+      --github-annotations     output the statistics whose level of concern
+                               is at least the threshold as GitHub Actions
+                               workflow commands ('::warning ...::' or,
+                               for critical ones, '::error ...::'), so that
+                               they appear as annotations, instead of the
+                               usual table
+      --metric=NAME            output only the value of the metric NAME, as a
+                               'NAME=VALUE' line (or, with '--json', as a key
+                               of a JSON object). NAME is one of the keys of
+                               '--json-version=2' output; e.g.,
+                               'uniqueBlobSize'. Can be repeated
+      --folded-stacks          instead of the usual statistics, output the
+                               path and size of each blob in the "folded
+                               stacks" format used by flame graph tools
+      --treemap=REV            instead of the usual statistics, output the
+                               path and recursive blob size of the root tree
+                               of REV and of every directory under it
+      --dominant-prefix=REV    instead of the usual statistics, output the
+                               deepest directory in REV that holds at least
+                               '--prefix-coverage' percent of its blob data
+      --prefix-coverage=PCT    the share used by '--dominant-prefix'.
+                               Default: '--prefix-coverage=80'
+      --purge-path=PATTERN     instead of the usual statistics, estimate how
+                               much blob data would be reclaimed if the files
+                               matching PATTERN (a gitignore-style pattern)
+                               were removed from history. Can be repeated
+      --purge-blob=OBJECT      like '--purge-path', but for a blob, wherever
+                               it appears. Can be repeated, and combined with
+                               '--purge-path'. Also lists the paths at which
+                               each such blob appears
+      --max-paths-per-object=N
+                               with '--purge-blob', list at most N of the
+                               paths at which each blob appears (the rest are
+                               only counted). Default: 10
+      --repack-estimate        instead of the usual statistics, list large
+                               blobs that are stored without deltas but look
+                               like versions of the same file, and estimate
+                               how much a repack might save (a heuristic)
+      --similar-blobs[=N]      instead of the usual statistics, compare the
+                               starts of the N (default 50) largest blobs
+                               and list clusters of them whose contents look
+                               related; e.g., versions of the same data file
+                               (a heuristic)
+      --size-clusters[=TOL]    instead of the usual statistics, list groups
+                               of at least 100 distinct blobs (of at least
+                               10 KiB each) whose sizes are within a fraction
+                               TOL (default 0.01) of each other; e.g.,
+                               generated or exported files
+      --lfs-check[=REV]        instead of the usual statistics, check the
+                               largest blobs in REV (default HEAD) against
+                               the Git LFS patterns in its .gitattributes
+                               files, listing blobs that match a pattern but
+                               aren't LFS pointers, and large binary blobs
+                               that no pattern covers
+      --unreachable-tags[=N]   instead of the usual statistics, count the
+                               tags whose commits aren't contained in any
+                               branch and the data that only they keep
+                               alive, and list the N (default 10) tags that
+                               keep the most data alive by themselves
+      --storage-growth[=WINDOWS]
+                               instead of the usual statistics, report how
+                               many objects, and how much disk space, arrived
+                               within each of WINDOWS (a comma-separated list
+                               like the default, '24h,7d,30d'), judging by
+                               the modification times of the packs and loose
+                               object files that hold them. Repacking resets
+                               these times, so old objects in a new pack
+                               count as new
+      --pack-report            instead of the usual statistics, list the
+                               packfiles, largest first, with the number of
+                               objects in each, their uncompressed size, and
+                               how many of them are also in other packs. Packs
+                               covered by the multi-pack index are marked
+      --historical-growth=REV  instead of the usual statistics, sample the
+                               first-parent history of REV, taking the most
+                               recent commit in each '--growth-interval', and
+                               output the size of each sample's checkout as
+                               CSV (or, with '--json', as JSON)
+      --growth-interval=DURATION
+                               the interval used by '--historical-growth';
+                               e.g., '1d' or '2w'. Default: '7d'
+      --max-samples=N          with '--historical-growth', sample at most the
+                               N most recent intervals. Default: 52
+      --save-inventory=FILE    instead of the usual statistics, record the
+                               number and disk size of the objects of each
+                               type, and a Bloom filter of a sample of their
+                               OIDs, in FILE
+      --compare-inventory=FILE instead of the usual statistics, report how
+                               the number and disk size of the objects of
+                               each type have changed since the inventory in
+                               FILE was saved, and estimate how many of the
+                               objects are new. Can be combined with
+                               '--save-inventory' to update the inventory
+      --inventory-sample-rate=F
+                               the fraction of OIDs that are recorded in the
+                               inventory's Bloom filter. Default: 0.0625
+      --inventory-bloom-fpr=P  size the Bloom filter for a false-positive
+                               rate of P. Default: 0.01
+      --inventory-bloom-bits=N use a Bloom filter of N bits instead
+      --inventory-bloom-hashes=K
+                               use K hash functions in the Bloom filter
+      --inventory-max-growth=PCT
+                               with '--compare-inventory', flag growth of
+                               more than PCT percent in the number of
+                               objects of any type. Default: 50
+      --delta-chains[=N]       instead of the usual statistics, report how
+                               many objects are stored as deltas and list
+                               the N (default 20) with the longest delta
+                               chains. Long chains, which make objects slow
+                               to read, come from repacking with a high
+                               '--depth'
+      --chunk-size=N           with '--delta-chains', write the list of
+                               objects to files of at most N objects each
+                               (one JSON object per line), and report only
+                               the files' names, record counts, and SHA-256
+                               hashes
+      --chunk-dir=DIR          the directory for '--chunk-size' files.
+                               Default: the current directory
+      --simulate-delete=REFS   after scanning, instead of the usual
+                               statistics, estimate what deleting REFS (a
+                               refgroup name or a reference prefix like
+                               'refs/remotes/') would remove, and project
+                               the headline metrics
+      --keep-reflogs           with '--simulate-delete', treat objects that
+                               are still reachable from reflogs as retained
+      --as-of=DATE             scan the commits that the selected references
+                               pointed at as of DATE (e.g., '2020-01-01' or
+                               '1 year ago'), to see how big the repository
+                               was then. This uses the references' reflogs
+                               when they go back far enough; otherwise, it
+                               uses the newest commit reachable from each
+                               reference that is not newer than DATE, which
+                               is only an approximation. Explicit ROOTs are
+                               used as given; to size a single branch at a
+                               date, pass a ROOT like 'main@{2020-01-01}'
       --json-version=[1|2]     choose which JSON format version to output.
                                Default: --json-version=1. Can be set via
                                gitconfig: 'sizer.jsonVersion'.
+      --max-filename-length=N  report filenames longer than N bytes. Default:
+                               '--max-filename-length=255'. Can be set via
+                               gitconfig: 'sizer.maxFilenameLength'.
+      --wide-tree-entries=N    report trees with more than N entries. Default:
+                               '--wide-tree-entries=10000'. Can be set via
+                               gitconfig: 'sizer.wideTreeEntries'.
+      --min-dedup-blob-size=N  to save memory, only remember blobs of at
+                               least N bytes when making sure that each blob
+                               is counted once in the per-extension
+                               statistics. Smaller blobs are counted again
+                               for each tree that refers to them, which
+                               overstates the small-file totals. Default: 0
+                               (remember all blobs)
+      --spawn-budget=N         fail rather than run more than N git
+                               processes in total. Default: 0 (no limit)
+      --default-branch[=REF]   also report how much blob data is reachable
+                               from references other than REF (by default,
+                               the branch that HEAD points at)
+      --check-symrefs          also check that HEAD and the other symbolic
+                               references point at references that exist
+      --check-storage          also examine the loose objects and packfiles
+                               in the object store (all of them, not only
+                               those reachable from the selected references)
+      --check-config           also check the gitconfig settings that affect
+                               the repository's size (e.g., gc.auto and
+                               core.compression)
+      --ref-kinds              also report how much object data is reachable
+                               from branches, from tags but not branches, and
+                               only from other references (e.g., refs/stash,
+                               notes, or pull-request references)
+      --[no-]detached-head     process [don't process] the commit at HEAD if
+                               it is detached. By default, it is processed
+                               only if neither ROOTs nor reference selection
+                               options are given
+      --use-replace-refs       honor the replacements in 'refs/replace/*'
+                               when reading objects. By default, objects are
+                               measured as they are stored, ignoring
+                               replacements.
+      --max-line-count-check   also read every blob to find the text blob
+                               with the most lines (slow)
+      --file-ages              also compute how many days ago the blobs were
+                               introduced (slow)
+      --bitmap-status          also report on the reachability bitmap, and
+                               warn if there is none even though the
+                               repository has more than 100k commits
+      --verify-oids            rehash the contents of every object and
+                               report any whose OIDs don't match (slow)
+      --normalize-names        treat filenames that differ only in Unicode
+                               normalization (e.g., NFC vs. NFD, as written
+                               on macOS) as case collisions. Filename and
+                               path lengths are still measured as stored
+      --strict                 fail if a tree has an entry with an empty
+                               filename, which Git considers corrupt. By
+                               default, such entries are only reported
+      --no-fast-path           don't use the repository's reachability bitmap.
+                               By default, if the repository is fully packed
+                               (a single pack with a bitmap, plus a
+                               commit-graph), reachability queries like
+                               those of '--default-branch' use the bitmap,
+                               and the output says so
       --[no-]progress          report (don't report) progress to stderr. Can
                                be set via gitconfig: 'sizer.progress'.
       --version                only report the git-sizer version number
+      --capabilities           only report, as JSON, the git-sizer version
+                               and the output formats, options, and metrics
+                               that it supports, for use by wrapper scripts
 
  Object selection:
 
  git-sizer traverses through your Git history to find objects to
  process. By default, it processes all objects that are reachable from
- any reference. You can tell it to process only some of your
- references; see "Reference selection" below.
+ any reference, or from HEAD if it is detached. You can tell it to
+ process only some of your references; see "Reference selection"
+ below. In that case, a detached HEAD is only processed if
+ '--detached-head' is given.
 
  If explicit ROOTs are specified on the command line, each one should
  be a string that 'git rev-parse' can convert into a single Git object
@@ -86,6 +306,10 @@ const usage = `usage: git-sizer [OPTS] [ROOT...]
                                process [don't process] references matching the
                                specified regular expression (e.g.,
                                '--include=refs/tags/release-.*')
+      --include GLOB, --exclude GLOB
+                               process [don't process] references matching the
+                               specified glob pattern, which contains '*',
+                               '?', or '[' (e.g., '--include=refs/pull/*/head')
       --include @REFGROUP, --exclude @REFGROUP
                                process [don't process] references in the
                                specified reference group (see below)
@@ -96,6 +320,9 @@ const usage = `usage: git-sizer [OPTS] [ROOT...]
 
  REGEXP patterns must match the full reference name.
 
+ GLOB patterns match at a boundary, like PREFIX; their wildcards don't
+ match '/'.
+
  REFGROUP can be the name of a predefined reference group ('branches',
  'tags', 'remotes', 'pulls', 'changes', 'notes', or 'stash'), or one
  defined via gitconfig settings like the following (the
@@ -130,7 +357,60 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 	var threshold sizes.Threshold = 1
 	var progress bool
 	var version bool
+	var capabilities bool
 	var showRefs bool
+	var maxFilenameLength int
+	var wideTreeEntries int
+	var minDedupBlobSize uint32
+	var spawnBudget int
+	var defaultBranch string
+	var refKinds bool
+	var checkSymrefs bool
+	var checkStorage bool
+	var checkConfig bool
+	var compact bool
+	var noColor bool
+	var color string
+	var levelNames bool
+	var foldedStacks bool
+	var treemap string
+	var dominantPrefix string
+	var prefixCoverage float64
+	var purgePaths []string
+	var purgeBlobs []string
+	var maxPathsPerObject int
+	var repackEstimate bool
+	var metricNames []string
+	var githubAnnotations bool
+	var labelArgs []string
+	var similarBlobs int
+	var sizeClusters float64
+	var lfsCheck string
+	var unreachableTags int
+	var storageGrowth string
+	var packReport bool
+	var historicalGrowth string
+	var growthInterval string
+	var maxSamples int
+	var saveInventory string
+	var compareInventory string
+	var inventoryOpts sizes.InventoryOptions
+	var deltaChains int
+	var chunkSize int
+	var chunkDir string
+	var simulateDelete string
+	var keepReflogs bool
+	var asOf string
+	var detachedHead bool
+	var useReplaceRefs bool
+	var verifyOIDs bool
+	var normalizeNames bool
+	var strict bool
+	var noFastPath bool
+	var dumpState bool
+	var maxLineCountCheck bool
+	var fileAges bool
+	var bitmapStatus bool
 
 	// Try to open the repository, but it's not an error yet if this
 	// fails, because the user might only be asking for `--help`.
@@ -173,8 +453,192 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 			"        --names=full            show full names",
 	)
 
+	flags.BoolVar(&compact, "compact", false, "output a compact table sized to fit the terminal")
+	markOutputFormat(flags, "compact")
+	flags.StringVar(&color, "color", "auto", "when to use colors in compact output (auto, always, or never)")
+	flags.BoolVar(&noColor, "no-color", false, "don't use colors in compact output")
+	flags.BoolVar(
+		&levelNames, "level-names", false,
+		"show the names of the levels of concern in compact output",
+	)
 	flags.BoolVarP(&jsonOutput, "json", "j", false, "output results in JSON format")
+	markOutputFormat(flags, "json")
+	flags.BoolVar(
+		&foldedStacks, "folded-stacks", false,
+		"output blob paths and sizes in folded stacks format",
+	)
+	flags.StringVar(
+		&treemap, "treemap", "",
+		"output the recursive size of every directory in the specified revision",
+	)
+	flags.StringVar(
+		&dominantPrefix, "dominant-prefix", "",
+		"output the deepest directory holding most of the blob data in the specified revision",
+	)
+	flags.Float64Var(
+		&prefixCoverage, "prefix-coverage", 100*sizes.DefaultPrefixCoverage,
+		"the percentage of blob data that --dominant-prefix has to cover",
+	)
+	flags.StringArrayVar(
+		&purgePaths, "purge-path", nil,
+		"estimate the savings of removing the files matching this pattern from history",
+	)
+	flags.StringArrayVar(
+		&purgeBlobs, "purge-blob", nil,
+		"estimate the savings of removing this blob from history",
+	)
+	flags.IntVar(
+		&maxPathsPerObject, "max-paths-per-object", sizes.DefaultMaxPathsPerObject,
+		"list at most this many of the paths at which each blob appears",
+	)
+	flags.BoolVar(
+		&repackEstimate, "repack-estimate", false,
+		"estimate how much a repack could save by deltifying large blobs",
+	)
+	flags.Float64Var(
+		&sizeClusters, "size-clusters", -1,
+		"list clusters of blobs whose sizes are within this fraction of each other",
+	)
+	flags.Lookup("size-clusters").NoOptDefVal = "0.01"
+	flags.StringVar(
+		&lfsCheck, "lfs-check", "",
+		"check the largest blobs in this commit against the Git LFS patterns",
+	)
+	flags.Lookup("lfs-check").NoOptDefVal = "HEAD"
+	flags.IntVar(
+		&unreachableTags, "unreachable-tags", 0,
+		"report the tags whose commits aren't contained in any branch",
+	)
+	flags.Lookup("unreachable-tags").NoOptDefVal = "10"
+	flags.IntVar(
+		&similarBlobs, "similar-blobs", 0,
+		"list clusters of large blobs with similar contents",
+	)
+	flags.Lookup("similar-blobs").NoOptDefVal = strconv.Itoa(sizes.DefaultSimilarBlobCount)
+	flags.StringVar(
+		&storageGrowth, "storage-growth", "",
+		"report how much object data arrived within the specified windows",
+	)
+	flags.Lookup("storage-growth").NoOptDefVal = "24h,7d,30d"
+	flags.BoolVar(
+		&packReport, "pack-report", false,
+		"report the size and contents of each packfile",
+	)
+	flags.StringVar(
+		&historicalGrowth, "historical-growth", "",
+		"sample the sizes of checkouts in the first-parent history of this commit",
+	)
+	flags.StringVar(
+		&growthInterval, "growth-interval", "7d",
+		"the interval between samples for --historical-growth",
+	)
+	flags.IntVar(
+		&maxSamples, "max-samples", sizes.DefaultMaxGrowthSamples,
+		"the maximum number of samples for --historical-growth",
+	)
+	flags.StringVar(
+		&saveInventory, "save-inventory", "",
+		"save an inventory of the object store to this file",
+	)
+	flags.StringVar(
+		&compareInventory, "compare-inventory", "",
+		"compare the object store with the inventory in this file",
+	)
+	flags.Float64Var(
+		&inventoryOpts.SampleRate, "inventory-sample-rate", sizes.DefaultInventorySampleRate,
+		"the fraction of OIDs that are recorded in the inventory's Bloom filter",
+	)
+	flags.Float64Var(
+		&inventoryOpts.FalsePositiveRate, "inventory-bloom-fpr",
+		sizes.DefaultInventoryFalsePositiveRate,
+		"the false-positive rate that the inventory's Bloom filter is sized for",
+	)
+	flags.Uint64Var(
+		&inventoryOpts.BloomBits, "inventory-bloom-bits", 0,
+		"the number of bits in the inventory's Bloom filter",
+	)
+	flags.IntVar(
+		&inventoryOpts.BloomHashes, "inventory-bloom-hashes", 0,
+		"the number of hash functions used by the inventory's Bloom filter",
+	)
+	flags.Float64Var(
+		&inventoryOpts.MaxGrowthPercent, "inventory-max-growth", sizes.DefaultMaxGrowthPercent,
+		"the growth, in percent, above which --compare-inventory flags a type",
+	)
+	flags.IntVar(
+		&deltaChains, "delta-chains", 0,
+		"report the objects with the longest delta chains",
+	)
+	flags.Lookup("delta-chains").NoOptDefVal = "20"
+	flags.IntVar(
+		&chunkSize, "chunk-size", 0,
+		"write long lists of objects to files of at most this many objects",
+	)
+	flags.StringVar(&chunkDir, "chunk-dir", ".", "the directory for --chunk-size files")
+	flags.StringVar(
+		&simulateDelete, "simulate-delete", "",
+		"estimate what deleting the specified refgroup or reference prefix would remove",
+	)
+	flags.BoolVar(
+		&keepReflogs, "keep-reflogs", false,
+		"with --simulate-delete, treat objects reachable from reflogs as retained",
+	)
+	flags.StringVar(
+		&asOf, "as-of", "",
+		"scan the commits that the selected references pointed at as of this date",
+	)
 	flags.IntVar(&jsonVersion, "json-version", 1, "JSON format version to output (1 or 2)")
+	flags.StringArrayVar(
+		&labelArgs, "label", nil,
+		"attach a label NAME=VALUE to the report (can be repeated)",
+	)
+	flags.StringArrayVar(
+		&metricNames, "metric", nil,
+		"output only the value of this metric (can be repeated)",
+	)
+	markOutputFormat(flags, "metric")
+	flags.BoolVar(
+		&githubAnnotations, "github-annotations", false,
+		"output concerning statistics as GitHub Actions annotations",
+	)
+	markOutputFormat(flags, "github-annotations")
+	flags.IntVar(
+		&maxFilenameLength, "max-filename-length", sizes.DefaultMaxFilenameLength,
+		"report filenames longer than this many bytes",
+	)
+	flags.IntVar(
+		&wideTreeEntries, "wide-tree-entries", sizes.DefaultWideTreeEntries,
+		"report trees with more than this many entries",
+	)
+	flags.Uint32Var(
+		&minDedupBlobSize, "min-dedup-blob-size", 0,
+		"only deduplicate blobs of at least this many bytes in the per-extension statistics",
+	)
+	flags.IntVar(
+		&spawnBudget, "spawn-budget", 0,
+		"fail rather than run more than this many git processes",
+	)
+	flags.StringVar(
+		&defaultBranch, "default-branch", "",
+		"compare the blob data reachable from this branch with that of all references",
+	)
+	flags.Lookup("default-branch").NoOptDefVal = "HEAD"
+	flags.BoolVar(
+		&checkSymrefs, "check-symrefs", false,
+		"check that HEAD and the other symbolic references point at existing references",
+	)
+	flags.BoolVar(
+		&checkStorage, "check-storage", false,
+		"examine the loose objects and packfiles in the object store",
+	)
+	flags.BoolVar(
+		&checkConfig, "check-config", false,
+		"check the gitconfig settings that affect the repository's size",
+	)
+	flags.BoolVar(
+		&refKinds, "ref-kinds", false,
+		"report how much object data is reachable from branches, tags, and other references",
+	)
 
 	defaultProgress := false
 	if f, ok := stderr.(*os.File); ok {
@@ -184,8 +648,54 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		}
 	}
 
+	flags.BoolVar(
+		&detachedHead, "detached-head", true,
+		"process the commit at HEAD if it is detached",
+	)
+	flags.Var(
+		&NegatedBoolValue{&detachedHead}, "no-detached-head",
+		"don't process the commit at HEAD if it is detached",
+	)
+	flags.Lookup("no-detached-head").NoOptDefVal = "true"
+
+	flags.BoolVar(
+		&useReplaceRefs, "use-replace-refs", false,
+		"honor the replacements in refs/replace/* when reading objects",
+	)
+	flags.BoolVar(
+		&maxLineCountCheck, "max-line-count-check", false,
+		"read every blob to find the one with the most lines",
+	)
+	flags.BoolVar(
+		&fileAges, "file-ages", false,
+		"compute the ages of the blobs",
+	)
+	flags.BoolVar(
+		&bitmapStatus, "bitmap-status", false,
+		"report on the reachability bitmap",
+	)
+	flags.BoolVar(
+		&verifyOIDs, "verify-oids", false,
+		"rehash every object and report any whose OIDs don't match",
+	)
+	flags.BoolVar(
+		&normalizeNames, "normalize-names", false,
+		"compare filenames in Unicode Normalization Form C when looking for case collisions",
+	)
+	flags.BoolVar(
+		&strict, "strict", false,
+		"fail if a corrupt tree is found, instead of just reporting it",
+	)
+	flags.BoolVar(
+		&noFastPath, "no-fast-path", false,
+		"don't use the reachability bitmap, even if the repository is fully packed",
+	)
 	flags.BoolVar(&progress, "progress", defaultProgress, "report progress to stderr")
 	flags.BoolVar(&version, "version", false, "report the git-sizer version number")
+	flags.BoolVar(
+		&capabilities, "capabilities", false,
+		"report the version, output formats, options, and metrics as JSON",
+	)
 	flags.Var(&NegatedBoolValue{&progress}, "no-progress", "suppress progress output")
 	flags.Lookup("no-progress").NoOptDefVal = "true"
 
@@ -194,6 +704,14 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		return fmt.Errorf("marking option hidden: %w", err)
 	}
 
+	flags.BoolVar(
+		&dumpState, "dump-state", false,
+		"dump the scan's internal state to stderr when it ends",
+	)
+	if err := flags.MarkHidden("dump-state"); err != nil {
+		return fmt.Errorf("marking option hidden: %w", err)
+	}
+
 	var configger refopts.Configger
 	if repo != nil {
 		configger = repo
@@ -218,6 +736,16 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		return err
 	}
 
+	labels := make(map[string]string, len(labelArgs))
+	for _, arg := range labelArgs {
+		if err := sizes.ParseLabel(labels, arg); err != nil {
+			return fmt.Errorf("parsing --label: %w", err)
+		}
+	}
+	if err := sizes.ValidateLabels(labels); err != nil {
+		return fmt.Errorf("parsing --label: %w", err)
+	}
+
 	if cpuprofile != "" {
 		f, err := os.Create(cpuprofile)
 		if err != nil {
@@ -238,10 +766,42 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		return nil
 	}
 
+	if capabilities {
+		rg, err := rgb.Finish(true)
+		if err != nil {
+			return err
+		}
+		return writeCapabilities(stdout, flags, rg.Groups())
+	}
+
 	if repoErr != nil {
 		return fmt.Errorf("couldn't open Git repository: %w", repoErr)
 	}
 
+	if useReplaceRefs {
+		repo, err = git.NewRepositoryFromPathWithOptions(
+			".", git.RepositoryOptions{UseReplaceRefs: true},
+		)
+		if err != nil {
+			return fmt.Errorf("couldn't open Git repository: %w", err)
+		}
+	}
+
+	if spawnBudget < 0 {
+		return errors.New("--spawn-budget must not be negative")
+	}
+	repo.SetSpawnBudget(spawnBudget)
+
+	if chunkSize < 0 {
+		return fmt.Errorf("invalid --chunk-size %d", chunkSize)
+	}
+	if chunkSize > 0 && deltaChains == 0 {
+		return errors.New("--chunk-size can only be used with --delta-chains")
+	}
+	if flags.Changed("chunk-dir") && chunkSize == 0 {
+		return errors.New("--chunk-dir can only be used with --chunk-size")
+	}
+
 	if jsonOutput {
 		if !flags.Changed("json-version") {
 			v, err := repo.ConfigIntDefault("sizer.jsonVersion", jsonVersion)
@@ -249,10 +809,10 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 				return err
 			}
 			jsonVersion = v
-			if !(jsonVersion == 1 || jsonVersion == 2) {
+			if _, ok := jsonEncoders[jsonVersion]; !ok {
 				return fmt.Errorf("JSON version (read from gitconfig) must be 1 or 2")
 			}
-		} else if !(jsonVersion == 1 || jsonVersion == 2) {
+		} else if _, ok := jsonEncoders[jsonVersion]; !ok {
 			return fmt.Errorf("JSON version must be 1 or 2")
 		}
 	}
@@ -283,6 +843,28 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		}
 	}
 
+	if !flags.Changed("max-filename-length") {
+		v, err := repo.ConfigIntDefault("sizer.maxFilenameLength", maxFilenameLength)
+		if err != nil {
+			return err
+		}
+		maxFilenameLength = v
+	}
+	if maxFilenameLength <= 0 {
+		return fmt.Errorf("maximum filename length must be positive")
+	}
+
+	if !flags.Changed("wide-tree-entries") {
+		v, err := repo.ConfigIntDefault("sizer.wideTreeEntries", wideTreeEntries)
+		if err != nil {
+			return err
+		}
+		wideTreeEntries = v
+	}
+	if wideTreeEntries <= 0 {
+		return fmt.Errorf("wide tree entry count must be positive")
+	}
+
 	if !flags.Changed("progress") && !flags.Changed("no-progress") {
 		v, err := repo.ConfigBoolDefault("sizer.progress", progress)
 		if err != nil {
@@ -291,6 +873,30 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		progress = v
 	}
 
+	if noColor {
+		color = "never"
+	}
+	switch color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid --color value %q (must be auto, always, or never)", color)
+	}
+
+	var concernStyles sizes.ConcernStyles
+	if s, err := repo.ConfigStringDefault("sizer.concernNames", ""); err != nil {
+		return err
+	} else if s != "" {
+		concernStyles, err = sizes.ParseConcernNames(s)
+		if err != nil {
+			return fmt.Errorf("parsing gitconfig value for 'sizer.concernNames': %w", err)
+		}
+	}
+
+	if !flags.Changed("detached-head") && !flags.Changed("no-detached-head") {
+		// Only add the detached `HEAD` to the default selection:
+		detachedHead = len(flags.Args()) == 0 && !rgb.HasRefopts()
+	}
+
 	rg, err := rgb.Finish(len(flags.Args()) == 0)
 	if err != nil {
 		return err
@@ -306,14 +912,212 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		progressMeter = meter.NewProgressMeter(stderr, 100*time.Millisecond)
 	}
 
+	if treemap != "" {
+		if err := sizes.WriteTreemap(ctx, repo, treemap, stdout); err != nil {
+			return fmt.Errorf("writing treemap: %w", err)
+		}
+		return nil
+	}
+
+	if dominantPrefix != "" {
+		if prefixCoverage <= 0 || prefixCoverage > 100 {
+			return fmt.Errorf("--prefix-coverage must be between 0 and 100")
+		}
+		p, err := sizes.FindDominantPrefix(
+			ctx, repo, dominantPrefix,
+			sizes.DominantPrefixOptions{Coverage: prefixCoverage / 100},
+		)
+		if err != nil {
+			return fmt.Errorf("finding dominant prefix: %w", err)
+		}
+		return sizes.WriteDominantPrefix(stdout, p)
+	}
+
+	if storageGrowth != "" {
+		windows, err := sizes.ParseGrowthWindows(storageGrowth)
+		if err != nil {
+			return fmt.Errorf("--storage-growth: %w", err)
+		}
+		g, err := sizes.ScanStorageGrowth(repo, time.Now(), windows)
+		if err != nil {
+			return fmt.Errorf("scanning storage growth: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(g, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", g, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteStorageGrowth(stdout, g)
+	}
+
+	if packReport {
+		r, err := sizes.ScanPacks(repo)
+		if err != nil {
+			return fmt.Errorf("scanning packfiles: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(r, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", r, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WritePackReport(stdout, r)
+	}
+
+	if historicalGrowth != "" {
+		intervals, err := sizes.ParseGrowthWindows(growthInterval)
+		if err != nil {
+			return fmt.Errorf("--growth-interval: %w", err)
+		}
+		if len(intervals) != 1 {
+			return errors.New("--growth-interval must be a single duration")
+		}
+		if maxSamples <= 0 {
+			return errors.New("--max-samples must be positive")
+		}
+		oid, err := repo.ResolveObject(historicalGrowth + "^{commit}")
+		if err != nil {
+			return fmt.Errorf("resolving --historical-growth argument %q: %w", historicalGrowth, err)
+		}
+		samples, err := sizes.AnalyzeHistoricalGrowth(
+			ctx, repo, sizes.NewMemoryCacheBackend(), oid, intervals[0], maxSamples,
+		)
+		if err != nil {
+			return fmt.Errorf("analyzing historical growth: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(samples, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", samples, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteSizeSamplesCSV(stdout, samples)
+	}
+
+	if saveInventory != "" || compareInventory != "" {
+		if inventoryOpts.SampleRate <= 0 || inventoryOpts.SampleRate > 1 {
+			return errors.New("--inventory-sample-rate must be greater than 0 and at most 1")
+		}
+		if inventoryOpts.FalsePositiveRate <= 0 || inventoryOpts.FalsePositiveRate >= 1 {
+			return errors.New("--inventory-bloom-fpr must be between 0 and 1")
+		}
+		if inventoryOpts.BloomHashes < 0 {
+			return errors.New("--inventory-bloom-hashes must not be negative")
+		}
+
+		var inv *sizes.Inventory
+		if compareInventory != "" {
+			old, err := sizes.ReadInventoryFile(compareInventory)
+			if err != nil {
+				return err
+			}
+			var delta *sizes.InventoryDelta
+			inv, delta, err = sizes.CompareInventory(ctx, repo, old, inventoryOpts)
+			if err != nil {
+				return fmt.Errorf("comparing inventory: %w", err)
+			}
+			if jsonOutput {
+				j, err := json.MarshalIndent(delta, "", "    ")
+				if err != nil {
+					return fmt.Errorf("could not convert %v to json: %w", delta, err)
+				}
+				fmt.Fprintf(stdout, "%s\n", j)
+			} else if err := sizes.WriteInventoryDelta(stdout, delta); err != nil {
+				return err
+			}
+		} else {
+			var err error
+			inv, err = sizes.TakeInventory(ctx, repo, inventoryOpts)
+			if err != nil {
+				return fmt.Errorf("taking inventory: %w", err)
+			}
+		}
+
+		if saveInventory != "" {
+			if err := sizes.WriteInventoryFile(saveInventory, inv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if deltaChains > 0 {
+		stats, err := sizes.ScanDeltaChains(ctx, repo, deltaChains)
+		if err != nil {
+			return err
+		}
+		if chunkSize > 0 {
+			stats.Chunks, err = sizes.WriteChunkFiles(
+				chunkDir, "delta-chains", stats.Longest, chunkSize,
+			)
+			if err != nil {
+				return err
+			}
+			stats.Longest = nil
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(stats, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", stats, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteDeltaChains(stdout, stats)
+	}
+
+	accessPath, err := sizes.ChooseAccessPath(repo, noFastPath)
+	if err != nil {
+		return fmt.Errorf("examining packfiles: %w", err)
+	}
+
 	refRoots, err := sizes.CollectReferences(ctx, repo, rg)
 	if err != nil {
 		return fmt.Errorf("determining which reference to scan: %w", err)
 	}
 
+	deletionSpec := sizes.RefDeletionSpec{KeepReflogs: keepReflogs}
+	if strings.HasPrefix(simulateDelete, "refs/") {
+		deletionSpec.Filter = git.PrefixFilter(simulateDelete)
+	} else if simulateDelete != "" {
+		deletionSpec.Group = sizes.RefGroupSymbol(simulateDelete)
+		if !hasRefGroup(rg.Groups(), deletionSpec.Group) {
+			return fmt.Errorf("--simulate-delete: unknown refgroup %q", simulateDelete)
+		}
+	}
+
 	roots := make([]sizes.Root, 0, len(refRoots)+len(flags.Args()))
-	for _, refRoot := range refRoots {
-		roots = append(roots, refRoot)
+	if asOf != "" {
+		if simulateDelete != "" {
+			return errors.New("--as-of cannot be combined with --simulate-delete")
+		}
+		roots, err = sizes.RootsAsOf(repo, refRoots, asOf)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, refRoot := range refRoots {
+			roots = append(roots, refRoot)
+		}
+
+		if detachedHead {
+			// A detached `HEAD` might not be reachable from any
+			// reference, but it's what the user has checked out:
+			root, ok, err := sizes.DetachedHeadRoot(repo)
+			if err != nil {
+				return err
+			}
+			if ok {
+				roots = append(roots, root)
+			}
+		}
 	}
 
 	for _, arg := range flags.Args() {
@@ -324,29 +1128,266 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 		roots = append(roots, sizes.NewExplicitRoot(arg, oid))
 	}
 
-	historySize, err := sizes.ScanRepositoryUsingGraph(
-		ctx, repo, roots, nameStyle, progressMeter,
+	if foldedStacks {
+		if err := sizes.WriteFoldedStacks(ctx, repo, roots, stdout); err != nil {
+			return fmt.Errorf("writing folded stacks: %w", err)
+		}
+		return nil
+	}
+
+	if len(purgePaths) != 0 || len(purgeBlobs) != 0 {
+		if maxPathsPerObject <= 0 {
+			return errors.New("--max-paths-per-object must be positive")
+		}
+		spec := sizes.PurgeSpec{Paths: purgePaths, MaxPathsPerBlob: maxPathsPerObject}
+		for _, arg := range purgeBlobs {
+			oid, err := repo.ResolveObject(arg)
+			if err != nil {
+				return fmt.Errorf("resolving --purge-blob argument %q: %w", arg, err)
+			}
+			spec.Blobs = append(spec.Blobs, oid)
+		}
+		e, err := sizes.EstimatePurge(ctx, repo, roots, spec)
+		if err != nil {
+			return fmt.Errorf("estimating purge: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(e, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", e, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WritePurgeEstimate(stdout, e)
+	}
+
+	if repackEstimate {
+		e, err := sizes.EstimateRepack(ctx, repo, roots, sizes.RepackOptions{})
+		if err != nil {
+			return fmt.Errorf("estimating repack savings: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(e, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", e, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteRepackEstimate(stdout, e, 20)
+	}
+
+	if similarBlobs > 0 {
+		clusters, err := sizes.FindSimilarBlobs(ctx, repo, roots, sizes.SimilarBlobOptions{
+			MaxBlobs:    similarBlobs,
+			MaxExamples: 5,
+		})
+		if err != nil {
+			return fmt.Errorf("finding similar blobs: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(clusters, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", clusters, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteSimilarBlobs(stdout, clusters)
+	}
+
+	if sizeClusters >= 0 {
+		clusters, err := sizes.AnalyzeSizeClusters(ctx, repo, roots, sizes.SizeClusterOptions{
+			Tolerance:   sizeClusters,
+			MinBlobSize: 10 * 1024,
+			MaxExamples: 5,
+		})
+		if err != nil {
+			return fmt.Errorf("finding size clusters: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(clusters, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", clusters, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteSizeClusters(stdout, clusters)
+	}
+
+	if lfsCheck != "" {
+		r, err := sizes.AnalyzeLFS(ctx, repo, sizes.LFSOptions{Tip: lfsCheck, TopN: 20, Nested: true})
+		if err != nil {
+			return fmt.Errorf("checking LFS patterns: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(r, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", r, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteLFSReport(stdout, r)
+	}
+
+	if unreachableTags > 0 {
+		r, err := sizes.AnalyzeBranchUnreachableTags(ctx, repo, unreachableTags)
+		if err != nil {
+			return fmt.Errorf("finding tags not reachable from any branch: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(r, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", r, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteBranchUnreachableTags(stdout, r)
+	}
+
+	var dumpStateWriter io.Writer
+	if dumpState {
+		dumpStateWriter = stderr
+	}
+
+	historySize, err := sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{
+			NameStyle:         nameStyle,
+			MaxFilenameLength: maxFilenameLength,
+			WideTreeEntries:   wideTreeEntries,
+			MinDedupBlobSize:  counts.Count32(minDedupBlobSize),
+			DefaultBranch:     defaultBranch,
+			RefKinds:          refKinds,
+			CheckSymbolicRefs: checkSymrefs,
+			CheckStorage:      checkStorage,
+			CheckConfig:       checkConfig,
+			VerifyOIDs:        verifyOIDs,
+			Strict:            strict,
+			NormalizeNames:    normalizeNames,
+			DumpState:         dumpStateWriter,
+			Labels:            labels,
+		},
+		progressMeter,
 	)
 	if err != nil {
 		return fmt.Errorf("error scanning repository: %w", err)
 	}
 
+	historySize.LinkedWorktree, err = repo.IsLinkedWorktree()
+	if err != nil {
+		return fmt.Errorf("checking for a linked worktree: %w", err)
+	}
+
+	if maxLineCountCheck {
+		lines, blob, err := sizes.MaxBlobLineCount(
+			ctx, repo, roots, sizes.DefaultMaxLineCountReadSize,
+		)
+		if err != nil {
+			return fmt.Errorf("counting lines: %w", err)
+		}
+		historySize.MaxBlobLineCount = &lines
+		historySize.MaxBlobLineCountBlob = blob
+	}
+
+	if fileAges {
+		ages, err := sizes.FileAgesInHistory(ctx, repo, roots, time.Now())
+		if err != nil {
+			return fmt.Errorf("computing file ages: %w", err)
+		}
+		historySize.MaxFileAgeInHistory = &ages.MaxAge
+		historySize.MaxFileAgeInHistoryBlob = ages.MaxAgeBlob
+		historySize.MedianFileAgeInHistory = &ages.MedianAge
+	}
+
+	if bitmapStatus {
+		historySize.Bitmap, err = sizes.CheckBitmap(repo)
+		if err != nil {
+			return fmt.Errorf("checking the reachability bitmap: %w", err)
+		}
+	}
+
+	if simulateDelete != "" {
+		e, err := sizes.SimulateRefDeletion(ctx, repo, refRoots, deletionSpec, &historySize)
+		if err != nil {
+			return fmt.Errorf("simulating reference deletion: %w", err)
+		}
+		if jsonOutput {
+			j, err := json.MarshalIndent(e, "", "    ")
+			if err != nil {
+				return fmt.Errorf("could not convert %v to json: %w", e, err)
+			}
+			fmt.Fprintf(stdout, "%s\n", j)
+			return nil
+		}
+		return sizes.WriteRefDeletionEstimate(stdout, e)
+	}
+
+	historySize.Repository, err = repo.Metadata()
+	if err != nil {
+		return fmt.Errorf("reading repository metadata: %w", err)
+	}
+
+	if repo.RanReachabilityQuery() {
+		historySize.AccessPath = accessPath
+	}
+
+	historySize.GitSpawns = repo.SpawnCounts()
+
+	problems := historySize.Problems(rg.Groups())
+
+	if len(metricNames) != 0 {
+		metrics, err := historySize.SelectMetrics(rg.Groups(), metricNames)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return sizes.EncodeSelectedMetrics(stdout, metrics)
+		}
+		return sizes.WriteSelectedMetrics(stdout, metrics)
+	}
+
+	if githubAnnotations {
+		return historySize.WriteGitHubAnnotations(stdout, rg.Groups(), threshold, nameStyle)
+	}
+
 	if jsonOutput {
-		var j []byte
-		var err error
-		switch jsonVersion {
-		case 1:
-			j, err = json.MarshalIndent(historySize, "", "    ")
-		case 2:
-			j, err = historySize.JSON(rg.Groups(), threshold, nameStyle)
-		default:
+		encode, ok := jsonEncoders[jsonVersion]
+		if !ok {
 			return fmt.Errorf("JSON version must be 1 or 2")
 		}
+		j, err := encode(&historySize, problems, rg.Groups(), threshold, nameStyle)
 		if err != nil {
 			return fmt.Errorf("could not convert %v to json: %w", historySize, err)
 		}
 		fmt.Fprintf(stdout, "%s\n", j)
+	} else if compact {
+		opts := sizes.TerminalTableOptions{
+			Color:      color == "always",
+			Styles:     concernStyles,
+			LevelNames: levelNames,
+		}
+		if f, ok := stdout.(*os.File); ok {
+			if width, ok := isatty.TerminalWidth(f.Fd()); ok {
+				opts.Width = width
+				opts.Color = color != "never"
+			}
+		}
+		if _, err := io.WriteString(
+			stdout,
+			historySize.TerminalTableString(rg.Groups(), threshold, nameStyle, opts),
+		); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
 	} else {
+		// The JSON and compact outputs include the problems, and
+		// the others are meant for scripts, so only the table
+		// reports the serious ones, on stderr:
+		writeProblems(stderr, problems)
 		if _, err := io.WriteString(
 			stdout, historySize.TableString(rg.Groups(), threshold, nameStyle),
 		); err != nil {
@@ -356,3 +1397,123 @@ func mainImplementation(ctx context.Context, stdout, stderr io.Writer, args []st
 
 	return nil
 }
+
+// writeProblems writes the examples of those of `problems` that are
+// at least warnings to `w`, one per line. Notices are skipped, since
+// they are already reflected in the statistics.
+func writeProblems(w io.Writer, problems []sizes.ProblemGroup) {
+	for _, g := range problems {
+		if g.Severity < sizes.SeverityWarning {
+			continue
+		}
+		for _, example := range g.Examples {
+			fmt.Fprintf(w, "%s: %s\n", g.Severity, example)
+		}
+		if more := int(g.Count) - len(g.Examples); more > 0 {
+			fmt.Fprintf(w, "%s: ...and %d more [%s]\n", g.Severity, more, g.Code)
+		}
+	}
+}
+
+// jsonEncoders encode a report in each of the supported versions of
+// the JSON output, keyed by the value of `--json-version`.
+var jsonEncoders = map[int]func(
+	historySize *sizes.HistorySize, problems []sizes.ProblemGroup,
+	refGroups []sizes.RefGroup, threshold sizes.Threshold, nameStyle sizes.NameStyle,
+) ([]byte, error){
+	1: func(
+		historySize *sizes.HistorySize, problems []sizes.ProblemGroup,
+		_ []sizes.RefGroup, _ sizes.Threshold, _ sizes.NameStyle,
+	) ([]byte, error) {
+		return json.MarshalIndent(
+			struct {
+				sizes.HistorySize
+				Problems []sizes.ProblemGroup `json:"problems,omitempty"`
+			}{*historySize, problems},
+			"", "    ",
+		)
+	},
+	2: func(
+		historySize *sizes.HistorySize, _ []sizes.ProblemGroup,
+		refGroups []sizes.RefGroup, threshold sizes.Threshold, nameStyle sizes.NameStyle,
+	) ([]byte, error) {
+		return historySize.JSON(refGroups, threshold, nameStyle)
+	},
+}
+
+// defaultOutputFormat is the output format that is used if none of
+// the options that select an output format is set.
+const defaultOutputFormat = "table"
+
+// outputFormatAnnotation is the annotation that marks the options that
+// select an output format, so that `--capabilities` can list them.
+const outputFormatAnnotation = "output-format"
+
+// markOutputFormat marks the option called `name` as one that selects
+// an output format.
+func markOutputFormat(flags *pflag.FlagSet, name string) {
+	if err := flags.SetAnnotation(name, outputFormatAnnotation, []string{name}); err != nil {
+		panic(err)
+	}
+}
+
+// writeCapabilities writes a JSON description of this build of
+// git-sizer to `w`: its version, the output formats and JSON versions
+// that it supports, the names of its (non-hidden) options, and the
+// metrics that it reports. The output formats and options are taken
+// from `flags`, the JSON versions from `jsonEncoders`, and the metrics
+// from the metric registry, so they can't get out of date.
+func writeCapabilities(w io.Writer, flags *pflag.FlagSet, refGroups []sizes.RefGroup) error {
+	type capabilities struct {
+		Version       string             `json:"version"`
+		Release       bool               `json:"release"`
+		OutputFormats []string           `json:"output_formats"`
+		JSONVersions  []int              `json:"json_versions"`
+		Options       []string           `json:"options"`
+		Metrics       []sizes.MetricInfo `json:"metrics"`
+	}
+
+	c := capabilities{
+		Version:       BuildVersion,
+		OutputFormats: []string{defaultOutputFormat},
+		JSONVersions:  []int{},
+		Options:       []string{},
+		Metrics:       sizes.AvailableMetrics(refGroups),
+	}
+	if ReleaseVersion != "" {
+		c.Version = ReleaseVersion
+		c.Release = true
+	}
+
+	for v := range jsonEncoders {
+		c.JSONVersions = append(c.JSONVersions, v)
+	}
+	sort.Ints(c.JSONVersions)
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !f.Hidden {
+			c.Options = append(c.Options, f.Name)
+		}
+		if _, ok := f.Annotations[outputFormatAnnotation]; ok {
+			c.OutputFormats = append(c.OutputFormats, f.Name)
+		}
+	})
+	sort.Strings(c.Options)
+
+	j, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not convert capabilities to json: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", j)
+	return nil
+}
+
+// hasRefGroup reports whether `symbol` is one of `groups`.
+func hasRefGroup(groups []sizes.RefGroup, symbol sizes.RefGroupSymbol) bool {
+	for _, g := range groups {
+		if g.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// ErrNoObjectsOfType is returned by `SmallestObjectForType()` and
+// `LargestObjectForType()` if the repository doesn't contain any
+// objects of the requested type.
+var ErrNoObjectsOfType = errors.New("no objects of the requested type")
+
+// SmallestObjectForType returns the OID and size of the smallest
+// object of type `objType` in the repository (whether or not it is
+// reachable). If there are several, the one with the lowest OID is
+// returned. This is meant as a diagnostic utility.
+func (repo *Repository) SmallestObjectForType(objType ObjectType) (OID, counts.Count32, error) {
+	return repo.extremeObjectForType(
+		objType, func(size, best counts.Count32) bool { return size < best },
+	)
+}
+
+// LargestObjectForType returns the OID and size of the largest object
+// of type `objType` in the repository (whether or not it is
+// reachable). If there are several, the one with the lowest OID is
+// returned. This is meant as a diagnostic utility.
+func (repo *Repository) LargestObjectForType(objType ObjectType) (OID, counts.Count32, error) {
+	return repo.extremeObjectForType(
+		objType, func(size, best counts.Count32) bool { return size > best },
+	)
+}
+
+// extremeObjectForType streams the headers of all objects in the
+// repository and returns the one of type `objType` that is `better`
+// than all of the others.
+func (repo *Repository) extremeObjectForType(
+	objType ObjectType, better func(size, best counts.Count32) bool,
+) (OID, counts.Count32, error) {
+	cmd, err := repo.gitCommand("cat-file", "--batch-all-objects", "--batch-check")
+	if err != nil {
+		return NullOID, 0, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return NullOID, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return NullOID, 0, fmt.Errorf("starting 'git cat-file --batch-all-objects': %w", err)
+	}
+
+	found := false
+	var bestOID OID
+	var bestSize counts.Count32
+
+	err = func() error {
+		f := bufio.NewReader(out)
+		for {
+			line, err := f.ReadString('\n')
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return fmt.Errorf("reading from 'git cat-file': %w", err)
+			}
+			header, err := ParseBatchHeader("", line)
+			if err != nil {
+				return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+			}
+			if header.ObjectType != objType {
+				continue
+			}
+			if !found || better(header.ObjectSize, bestSize) {
+				found = true
+				bestOID = header.OID
+				bestSize = header.ObjectSize
+			}
+		}
+	}()
+	if err != nil {
+		_, _ = io.Copy(io.Discard, out)
+		_ = cmd.Wait()
+		return NullOID, 0, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return NullOID, 0, fmt.Errorf("running 'git cat-file --batch-all-objects': %w", err)
+	}
+
+	if !found {
+		return NullOID, 0, fmt.Errorf("%w: %s", ErrNoObjectsOfType, objType)
+	}
+
+	return bestOID, bestSize, nil
+}
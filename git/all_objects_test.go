@@ -0,0 +1,47 @@
+package git_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestExtremeObjectForType(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "extreme-objects")
+	defer testRepo.Remove(t)
+
+	createBlob := func(contents string) git.OID {
+		return testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+	}
+
+	small := createBlob("a")
+	createBlob("medium")
+	large := createBlob("the largest blob of them all")
+
+	repo := testRepo.Repository(t)
+
+	oid, size, err := repo.SmallestObjectForType("blob")
+	require.NoError(t, err)
+	assert.Equal(t, small, oid)
+	assert.Equal(t, counts.Count32(1), size)
+
+	oid, size, err = repo.LargestObjectForType("blob")
+	require.NoError(t, err)
+	assert.Equal(t, large, oid)
+	assert.Equal(t, counts.Count32(28), size)
+
+	_, _, err = repo.LargestObjectForType("tag")
+	assert.True(t, errors.Is(err, git.ErrNoObjectsOfType))
+}
@@ -0,0 +1,130 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ParseDate converts `date` (in any format that git's `--since`
+// option accepts; e.g., "2020-01-01" or "1 year ago") into a
+// timestamp in seconds since the epoch.
+func (repo *Repository) ParseDate(date string) (int64, error) {
+	cmd, err := repo.gitCommand("rev-parse", "--since="+date)
+	if err != nil {
+		return 0, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("running 'git rev-parse --since=%s': %w", date, err)
+	}
+	s := string(bytes.TrimSpace(out))
+	if !strings.HasPrefix(s, "--max-age=") {
+		return 0, fmt.Errorf("unexpected output from 'git rev-parse --since': %q", s)
+	}
+	t, err := strconv.ParseInt(strings.TrimPrefix(s, "--max-age="), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+	return t, nil
+}
+
+// CommitAsOf returns the commit that the reference `refname` pointed
+// at as of `t` (in seconds since the epoch; see `ParseDate()`). The
+// second return value is `false` if the reference didn't point at a
+// commit then.
+//
+// If the reference's reflog goes back far enough, the answer comes
+// from the reflog, which is exact. Otherwise (e.g., in bare
+// repositories, where reflogs are off by default, or after `git
+// reflog expire`), it falls back to the newest commit reachable from
+// the reference's current value whose committer date is not after
+// `t`, which is only an approximation: it doesn't know about commits
+// that have since been removed from the reference, and it is confused
+// by commits with wrong dates.
+func (repo *Repository) CommitAsOf(refname string, t int64) (OID, bool, error) {
+	oid, ok, err := repo.reflogCommitAsOf(refname, t)
+	if err != nil || ok {
+		return oid, ok, err
+	}
+
+	cmd, err := repo.gitCommand(
+		"rev-list", "-1", fmt.Sprintf("--min-age=%d", t), "--end-of-options",
+		refname+"^{commit}",
+	)
+	if err != nil {
+		return NullOID, false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The reference doesn't point at a commit.
+			return NullOID, false, nil
+		}
+		return NullOID, false, fmt.Errorf("running 'git rev-list': %w", err)
+	}
+	s := string(bytes.TrimSpace(out))
+	if s == "" {
+		// All of its commits are newer than `t`.
+		return NullOID, false, nil
+	}
+	oid, err = NewOID(s)
+	if err != nil {
+		return NullOID, false, fmt.Errorf("parsing output of 'git rev-list': %w", err)
+	}
+	return oid, true, nil
+}
+
+// reflogCommitAsOf reads the reflog of `refname` and returns the
+// commit that the newest entry that is not after `t` set it to, like
+// `git rev-parse REF@{DATE}`. The second return value is `false` if
+// the reference has no reflog or if its reflog doesn't go back as far
+// as `t`.
+func (repo *Repository) reflogCommitAsOf(refname string, t int64) (OID, bool, error) {
+	cmd, err := repo.gitCommand(
+		"log", "-g", "--format=%H %gd", "--date=unix", "--end-of-options", refname, "--",
+	)
+	if err != nil {
+		return NullOID, false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// E.g., the reference doesn't exist.
+			return NullOID, false, nil
+		}
+		return NullOID, false, fmt.Errorf("running 'git log -g %s': %w", refname, err)
+	}
+
+	// The entries are listed newest first, and each line looks like
+	// `OID REFNAME@{TIMESTAMP}`:
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		i := strings.IndexByte(line, ' ')
+		j := strings.LastIndexByte(line, '{')
+		if i == -1 || j < i || !strings.HasSuffix(line, "}") {
+			return NullOID, false, fmt.Errorf("unexpected output from 'git log -g': %q", line)
+		}
+		entryTime, err := strconv.ParseInt(line[j+1:len(line)-1], 10, 64)
+		if err != nil {
+			return NullOID, false, fmt.Errorf("unexpected output from 'git log -g': %q", line)
+		}
+		if entryTime > t {
+			continue
+		}
+		oid, err := NewOID(line[:i])
+		if err != nil {
+			return NullOID, false, fmt.Errorf("parsing output of 'git log -g': %w", err)
+		}
+		return oid, true, nil
+	}
+
+	return NullOID, false, nil
+}
@@ -0,0 +1,97 @@
+package git_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestCommitAsOf(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "as-of")
+	defer testRepo.Remove(t)
+
+	start := time.Unix(1112911993, 0)
+	timestamp := start
+	at := func(offset time.Duration) string {
+		return start.Add(offset).UTC().Format(time.RFC3339)
+	}
+
+	revParse := func(name string) git.OID {
+		t.Helper()
+		out, err := testRepo.GitCommand(t, "rev-parse", name).Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	// Commits at `start` and `start+1m`; then, at `start+2m`, rewind
+	// the branch to the first commit:
+	testRepo.AddFile(t, "a.txt", "a\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "first")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+	first := revParse("HEAD")
+
+	testRepo.AddFile(t, "b.txt", "b\n")
+	cmd = testRepo.GitCommand(t, "commit", "-m", "second")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+	second := revParse("HEAD")
+
+	cmd = testRepo.GitCommand(t, "reset", "-q", "--hard", first.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	// Tags have no reflogs:
+	testRepo.UpdateRef(t, "refs/tags/v2", second)
+
+	repo := testRepo.Repository(t)
+
+	branch, ok, err := repo.SymbolicRef("HEAD")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ts, err := repo.ParseDate(at(0))
+	require.NoError(t, err)
+	assert.Equal(t, start.Unix(), ts)
+
+	for _, tc := range []struct {
+		name     string
+		refname  string
+		date     string
+		expected git.OID
+		ok       bool
+	}{
+		{"reflog-first", branch, at(30 * time.Second), first, true},
+		// The reflog knows that the branch pointed at `second` for a
+		// while, even though `second` is no longer reachable from it:
+		{"reflog-second", branch, at(90 * time.Second), second, true},
+		{"reflog-rewound", branch, at(150 * time.Second), first, true},
+		{"before-reflog", branch, at(-time.Hour), git.NullOID, false},
+		{"no-reflog-first", "refs/tags/v2", at(30 * time.Second), first, true},
+		{"no-reflog-second", "refs/tags/v2", at(90 * time.Second), second, true},
+		{"no-reflog-before", "refs/tags/v2", at(-time.Hour), git.NullOID, false},
+		{"missing", "refs/heads/missing", at(0), git.NullOID, false},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			when, err := repo.ParseDate(tc.date)
+			require.NoError(t, err)
+			oid, ok, err := repo.CommitAsOf(tc.refname, when)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.expected, oid)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// BaseNameGroup is a set of files in a tree that have the same base
+// name but live in different directories, as returned by
+// `FilesWithSameBaseName()`.
+type BaseNameGroup struct {
+	// BaseName is the final component of the files' paths.
+	BaseName string
+
+	// Paths are the full paths of the files, relative to the root of
+	// the tree, in sorted order.
+	Paths []string
+}
+
+// FilesWithSameBaseName returns the `topN` biggest groups of files in
+// the tree of `oid` (a commit or tree) that share a base name, most
+// paths first. Groups with the same number of paths are ordered by
+// base name. Submodules are ignored.
+//
+// This is a code-health check (such files are confusing and are often
+// the result of copy-and-paste) rather than a size metric. It lists
+// every path in the tree, so it is only run on request.
+func (repo *Repository) FilesWithSameBaseName(oid OID, topN int) ([]BaseNameGroup, error) {
+	if topN < 0 {
+		return nil, fmt.Errorf("invalid number of groups %d", topN)
+	}
+	if topN == 0 {
+		return nil, nil
+	}
+
+	entries, err := repo.ReadTreeRecursive(oid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.ObjectType != "blob" {
+			continue
+		}
+		baseName := path.Base(entry.Path)
+		paths[baseName] = append(paths[baseName], entry.Path)
+	}
+
+	// Among groups with the same number of paths, the one with the
+	// lower base name ranks higher:
+	top := topn.New(topN, func(g1, g2 BaseNameGroup) bool {
+		if len(g1.Paths) != len(g2.Paths) {
+			return len(g1.Paths) < len(g2.Paths)
+		}
+		return g1.BaseName > g2.BaseName
+	})
+	for baseName, paths := range paths {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		top.Add(BaseNameGroup{
+			BaseName: baseName,
+			Paths:    paths,
+		})
+	}
+
+	return top.Items(), nil
+}
@@ -0,0 +1,66 @@
+package git_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestFilesWithSameBaseName(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "base-names")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "README", "top\n")
+	testRepo.AddFile(t, "a/README", "a\n")
+	testRepo.AddFile(t, "b/c/README", "c\n")
+	testRepo.AddFile(t, "a/util.go", "package a\n")
+	testRepo.AddFile(t, "b/util.go", "package b\n")
+	testRepo.AddFile(t, "b/index.js", "")
+	testRepo.AddFile(t, "c/index.js", "")
+	testRepo.AddFile(t, "unique.txt", "only one\n")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	out, err := testRepo.GitCommand(t, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	head, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+
+	groups, err := repo.FilesWithSameBaseName(head, 2)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]git.BaseNameGroup{
+			{BaseName: "README", Paths: []string{"README", "a/README", "b/c/README"}},
+			{BaseName: "index.js", Paths: []string{"b/index.js", "c/index.js"}},
+		},
+		groups,
+	)
+
+	// Base names that occur only once are never reported:
+	groups, err = repo.FilesWithSameBaseName(head, 100)
+	require.NoError(t, err)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, "util.go", groups[2].BaseName)
+
+	groups, err = repo.FilesWithSameBaseName(head, 0)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	_, err = repo.FilesWithSameBaseName(head, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// GetObjectBatchCheckDedup returns the headers of the objects named
+// by `oids`, as reported by `git cat-file --batch-check`. Each OID is
+// sent to `git cat-file` only once, no matter how often it appears in
+// `oids` (as happens, for example, when many trees refer to the same
+// blob), so the output has exactly one line per distinct object. An
+// object that doesn't exist is not an error; its header has the
+// `ObjectType` "missing" and a zero size.
+func (repo *Repository) GetObjectBatchCheckDedup(oids []OID) (map[OID]BatchHeader, error) {
+	headers := make(map[OID]BatchHeader, len(oids))
+	if len(oids) == 0 {
+		return headers, nil
+	}
+
+	var input bytes.Buffer
+	var unique []OID
+	for _, oid := range oids {
+		if _, ok := headers[oid]; ok {
+			continue
+		}
+		// Reserve the slot, so that later duplicates are
+		// skipped:
+		headers[oid] = BatchHeader{}
+		unique = append(unique, oid)
+		fmt.Fprintln(&input, oid.String())
+	}
+
+	cmd, err := repo.gitCommand(
+		"cat-file", "--batch-check=%(objectname) %(objecttype) %(objectsize)",
+	)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = &input
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git cat-file --batch-check': %w", err)
+	}
+
+	lines := strings.SplitAfter(string(out), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) != len(unique) {
+		return nil, fmt.Errorf(
+			"'git cat-file' returned %d headers for %d objects", len(lines), len(unique),
+		)
+	}
+
+	// The responses come in the same order as the requests:
+	for i, line := range lines {
+		oid := unique[i]
+		if line == oid.String()+" missing\n" {
+			headers[oid] = BatchHeader{OID: oid, ObjectType: missingHeader.ObjectType}
+			continue
+		}
+		header, err := ParseBatchHeader(oid.String(), line)
+		if err != nil {
+			return nil, err
+		}
+		if header.OID != oid {
+			return nil, fmt.Errorf(
+				"'git cat-file' returned header for %s instead of %s", header.OID, oid,
+			)
+		}
+		headers[oid] = header
+	}
+
+	return headers, nil
+}
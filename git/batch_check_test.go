@@ -0,0 +1,61 @@
+package git_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestGetObjectBatchCheckDedup(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "batch-check-dedup")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "a/LICENSE", "Same license\n")
+	testRepo.AddFile(t, "b/LICENSE", "Same license\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	repo := testRepo.Repository(t)
+
+	resolve := func(name string) git.OID {
+		t.Helper()
+		oid, err := repo.ResolveObject(name)
+		require.NoError(t, err)
+		return oid
+	}
+
+	license := resolve("HEAD:a/LICENSE")
+	require.Equal(t, license, resolve("HEAD:b/LICENSE"))
+	tree := resolve("HEAD^{tree}")
+	missing, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+
+	headers, err := repo.GetObjectBatchCheckDedup(
+		[]git.OID{license, tree, license, missing, license, missing},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[git.OID]git.BatchHeader{
+			license: {OID: license, ObjectType: "blob", ObjectSize: counts.Count32(13)},
+			tree:    {OID: tree, ObjectType: "tree", ObjectSize: counts.Count32(56)},
+			missing: {OID: missing, ObjectType: "missing"},
+		},
+		headers,
+	)
+
+	headers, err = repo.GetObjectBatchCheckDedup(nil)
+	require.NoError(t, err)
+	assert.Empty(t, headers)
+}
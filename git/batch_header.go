@@ -19,25 +19,30 @@ var missingHeader = BatchHeader{
 }
 
 // Parse a `cat-file --batch[-check]` output header line (including
-// the trailing LF). `spec`, if not "", is used in error messages.
+// the trailing LF). `spec`, if not "", is used in error messages. A
+// header that doesn't have the expected three fields (OID, type, and
+// size) results in an error that quotes it, rather than a panic.
 func ParseBatchHeader(spec string, header string) (BatchHeader, error) {
-	header = header[:len(header)-1]
-	words := strings.Split(header, " ")
-	if words[len(words)-1] == "missing" {
+	line := strings.TrimSuffix(header, "\n")
+	words := strings.Split(line, " ")
+	if len(words) >= 2 && words[len(words)-1] == "missing" {
 		if spec == "" {
 			spec = words[0]
 		}
 		return missingHeader, fmt.Errorf("missing object %s", spec)
 	}
+	if len(words) != 3 || words[1] == "" {
+		return missingHeader, fmt.Errorf("malformed header from 'git cat-file': %q", header)
+	}
 
 	oid, err := NewOID(words[0])
 	if err != nil {
-		return missingHeader, err
+		return missingHeader, fmt.Errorf("malformed header from 'git cat-file': %q: %w", header, err)
 	}
 
 	size, err := strconv.ParseUint(words[2], 10, 0)
 	if err != nil {
-		return missingHeader, err
+		return missingHeader, fmt.Errorf("malformed header from 'git cat-file': %q: %w", header, err)
 	}
 	return BatchHeader{
 		OID:        oid,
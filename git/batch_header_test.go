@@ -0,0 +1,42 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+func TestParseBatchHeader(t *testing.T) {
+	t.Parallel()
+
+	const oid = "0123456789abcdef0123456789abcdef01234567"
+
+	header, err := git.ParseBatchHeader("", oid+" blob 42\n")
+	require.NoError(t, err)
+	assert.Equal(t, oid, header.OID.String())
+	assert.Equal(t, git.ObjectType("blob"), header.ObjectType)
+	assert.Equal(t, counts.Count32(42), header.ObjectSize)
+
+	_, err = git.ParseBatchHeader("HEAD:foo", oid+" missing\n")
+	assert.EqualError(t, err, "missing object HEAD:foo")
+
+	for _, h := range []string{
+		"",
+		"\n",
+		oid + "\n",
+		oid + " blob\n",
+		oid + "  42\n",
+		oid + " blob 42 extra\n",
+		oid + " blob forty-two\n",
+		"not-an-oid blob 42\n",
+	} {
+		_, err := git.ParseBatchHeader("", h)
+		if assert.Error(t, err, "%q", h) {
+			assert.Contains(t, err.Error(), "malformed header", "%q", h)
+		}
+	}
+}
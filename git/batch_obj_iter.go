@@ -12,17 +12,40 @@ import (
 type ObjectRecord struct {
 	BatchHeader
 	Data []byte
+
+	// Tree, if set, streams the entries of a tree whose contents
+	// weren't read into `Data`. See
+	// `BatchObjectIterOptions.StreamTrees`.
+	Tree *TreeStream
+}
+
+// BatchObjectIterOptions holds options for `NewBatchObjectIterWithOptions()`.
+type BatchObjectIterOptions struct {
+	// StreamTrees, if set, causes trees to be returned with a nil
+	// `Data` and a non-nil `Tree`, which parses the tree's entries as
+	// they are read, so that huge trees don't have to be held in
+	// memory. The rest of the objects aren't read until the stream
+	// has been closed (which `Next()` does automatically).
+	StreamTrees bool
 }
 
 // BatchObjectIter iterates over objects whose names are fed into its
 // stdin. The output is buffered, so it has to be closed before you
 // can be sure that you have gotten all of the objects.
 type BatchObjectIter struct {
-	ctx   context.Context
+	ctx context.Context
+
+	// cancel cancels `ctx`, which kills the subprocess.
+	cancel context.CancelFunc
+
 	p     *pipe.Pipeline
 	oidCh chan OID
 	objCh chan ObjectRecord
 	errCh chan error
+
+	// stream is the `TreeStream` that was returned most recently,
+	// if any.
+	stream *TreeStream
 }
 
 // NewBatchObjectIter returns a `*BatchObjectIterator` and an
@@ -31,12 +54,22 @@ type BatchObjectIter struct {
 // `io.WriteCloser` should normally be closed and the iterator's
 // output drained before `Close()` is called.
 func (repo *Repository) NewBatchObjectIter(ctx context.Context) (*BatchObjectIter, error) {
+	return repo.NewBatchObjectIterWithOptions(ctx, BatchObjectIterOptions{})
+}
+
+// NewBatchObjectIterWithOptions is like `NewBatchObjectIter()`, but
+// allows options to be specified.
+func (repo *Repository) NewBatchObjectIterWithOptions(
+	ctx context.Context, opts BatchObjectIterOptions,
+) (*BatchObjectIter, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	iter := BatchObjectIter{
-		ctx:   ctx,
-		p:     pipe.New(),
-		oidCh: make(chan OID),
-		objCh: make(chan ObjectRecord),
-		errCh: make(chan error),
+		ctx:    ctx,
+		cancel: cancel,
+		p:      pipe.New(),
+		oidCh:  make(chan OID),
+		objCh:  make(chan ObjectRecord),
+		errCh:  make(chan error),
 	}
 
 	iter.p.Add(
@@ -92,6 +125,13 @@ func (repo *Repository) NewBatchObjectIter(ctx context.Context) (*BatchObjectIte
 						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
 					}
 
+					if opts.StreamTrees && batchHeader.ObjectType == "tree" {
+						if err := iter.streamTree(f, batchHeader); err != nil {
+							return err
+						}
+						continue
+					}
+
 					// Read the object contents plus the trailing LF
 					// (which is discarded below while creating the
 					// `ObjectRecord`):
@@ -117,12 +157,42 @@ func (repo *Repository) NewBatchObjectIter(ctx context.Context) (*BatchObjectIte
 	)
 
 	if err := iter.p.Start(ctx); err != nil {
+		cancel()
 		return nil, err
 	}
 
 	return &iter, nil
 }
 
+// streamTree passes a `TreeStream` for the tree described by
+// `header`, whose contents are next in `f`, to the consumer, and waits
+// until the consumer is done with it. Then it reads the LF that
+// follows the tree.
+func (iter *BatchObjectIter) streamTree(f *bufio.Reader, header BatchHeader) error {
+	stream := newTreeStream(f, int64(header.ObjectSize))
+	select {
+	case iter.objCh <- ObjectRecord{BatchHeader: header, Tree: stream}:
+	case <-iter.ctx.Done():
+		return iter.ctx.Err()
+	}
+
+	select {
+	case <-stream.done:
+	case <-iter.ctx.Done():
+		return iter.ctx.Err()
+	}
+	if stream.ioErr != nil {
+		return fmt.Errorf(
+			"reading tree '%s' from 'git cat-file': %w", header.OID, stream.ioErr,
+		)
+	}
+
+	if c, err := f.ReadByte(); err != nil || c != '\n' {
+		return fmt.Errorf("missing LF after tree '%s' from 'git cat-file'", header.OID)
+	}
+	return nil
+}
+
 // RequestObject requests that the object with the specified `oid` be
 // processed. The objects registered via this method can be read using
 // `Next()` in the order that they were requested.
@@ -135,22 +205,67 @@ func (iter *BatchObjectIter) RequestObject(oid OID) error {
 	}
 }
 
-// Close closes the iterator and frees up resources. Close must be
-// called exactly once.
+// Close closes the iterator and frees up resources. Either Close or
+// `CloseContext()` must be called exactly once.
 func (iter *BatchObjectIter) Close() {
 	close(iter.oidCh)
 }
 
+// CloseContext closes the iterator like `Close()`, and then waits for
+// `git cat-file` to exit, discarding any objects that haven't been
+// read yet. If `ctx` is done before then (e.g., because the process
+// is wedged), the process is killed (with `SIGTERM`, followed by
+// `SIGKILL` if it still hasn't exited after a couple of seconds), and
+// an error wrapping `ErrSubprocessKilled` is returned. Otherwise, the
+// return value is the error, if any, that the pipeline finished with.
+func (iter *BatchObjectIter) CloseContext(ctx context.Context) error {
+	if iter.stream != nil {
+		_ = iter.stream.Close()
+		iter.stream = nil
+	}
+	iter.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		for obj := range iter.objCh {
+			if obj.Tree != nil {
+				_ = obj.Tree.Close()
+			}
+		}
+		done <- iter.p.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		iter.cancel()
+		return err
+	case <-ctx.Done():
+		iter.cancel()
+		<-done
+		return fmt.Errorf("closing 'git cat-file': %w (%v)", ErrSubprocessKilled, ctx.Err())
+	}
+}
+
 // Next either returns the next object (its header and contents), or a
 // `false` boolean value if no more objects are left. Objects need to
 // be read asynchronously, but the last objects won't necessarily show
 // up here until `Close()` has been called.
 func (iter *BatchObjectIter) Next() (ObjectRecord, bool, error) {
+	if iter.stream != nil {
+		// Let the reader continue past the previous tree. If it
+		// failed, the reader reports the error:
+		_ = iter.stream.Close()
+		iter.stream = nil
+	}
+
 	obj, ok := <-iter.objCh
 	if !ok {
+		err := iter.p.Wait()
+		iter.cancel()
 		return ObjectRecord{
 			BatchHeader: missingHeader,
-		}, false, iter.p.Wait()
+		}, false, err
 	}
+	iter.stream = obj.Tree
 	return obj, true, nil
 }
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/github/git-sizer/internal/bitmap"
+)
+
+// BitmapStats describes the reachability bitmap that git would use
+// for `repo`.
+type BitmapStats struct {
+	// Path is the path of the bitmap file.
+	Path string
+
+	// CommitCount is the number of commits that have bitmaps.
+	CommitCount int
+
+	// BitCount is the number of objects that the bitmap covers.
+	BitCount int
+}
+
+// bitmapPaths returns the paths of the reachability bitmaps in `repo`,
+// multi-pack bitmaps (which git prefers) first.
+func (repo *Repository) bitmapPaths() ([]string, error) {
+	packDir, err := repo.GitPath("objects/pack")
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(packDir, "multi-pack-index-*.bitmap"))
+	if err != nil {
+		return nil, fmt.Errorf("looking for multi-pack bitmaps: %w", err)
+	}
+
+	packs, err := repo.Packfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		path := strings.TrimSuffix(pack.Path, ".pack") + ".bitmap"
+		ok, err := fileExists(path)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// HasReachabilityBitmap returns true if `repo` has a reachability
+// bitmap, either for a single pack or for a multi-pack index.
+func (repo *Repository) HasReachabilityBitmap() (bool, error) {
+	paths, err := repo.bitmapPaths()
+	if err != nil {
+		return false, err
+	}
+	return len(paths) > 0, nil
+}
+
+// BitmapStats reads the header of the reachability bitmap that git
+// would use for `repo`. It returns nil if there is no bitmap.
+func (repo *Repository) BitmapStats() (*BitmapStats, error) {
+	paths, err := repo.bitmapPaths()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	path := paths[0]
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bitmap: %w", err)
+	}
+	defer f.Close()
+
+	stats, err := bitmap.Read(f, len(NullOID.v))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return &BitmapStats{
+		Path:        path,
+		CommitCount: stats.CommitCount,
+		BitCount:    stats.BitCount,
+	}, nil
+}
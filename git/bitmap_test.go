@@ -0,0 +1,52 @@
+package git_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestBitmapStats(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "bitmap-stats")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		testRepo.AddFile(t, name, name+"\n")
+		cmd := testRepo.GitCommand(t, "commit", "-m", name)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+	}
+
+	repo := testRepo.Repository(t)
+
+	ok, err := repo.HasReachabilityBitmap()
+	require.NoError(t, err)
+	assert.False(t, ok)
+	stats, err := repo.BitmapStats()
+	require.NoError(t, err)
+	assert.Nil(t, stats)
+
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adbq").Run())
+
+	ok, err = repo.HasReachabilityBitmap()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	stats, err = repo.BitmapStats()
+	require.NoError(t, err)
+	require.NotNil(t, stats)
+	assert.True(t, strings.HasPrefix(filepath.Base(stats.Path), "pack-"))
+	assert.True(t, strings.HasSuffix(stats.Path, ".bitmap"))
+	assert.Equal(t, 3, stats.CommitCount)
+	// Three commits, three trees, and three blobs:
+	assert.Equal(t, 9, stats.BitCount)
+}
@@ -0,0 +1,279 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/github/go-pipe/pipe"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// ReadBlobs calls `fn` for each of `oids`, in order, with the blob's
+// full size and its contents, truncated to at most `limit` bytes. All
+// of the blobs are read by a single `git cat-file --batch` process, and
+// the part of each blob beyond `limit` is discarded as it is read, so
+// memory use doesn't depend on the size of the blobs. If `fn` returns
+// an error, reading is aborted and that error is returned.
+func (repo *Repository) ReadBlobs(
+	ctx context.Context, oids []OID, limit int64,
+	fn func(oid OID, size counts.Count32, data []byte) error,
+) error {
+	return repo.streamBlobs(
+		ctx, oids,
+		func(oid OID, size counts.Count32, r io.Reader) error {
+			n := int64(size)
+			if n > limit {
+				n = limit
+			}
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return fmt.Errorf("reading blob %s: %w", oid, err)
+			}
+			return fn(oid, size, data)
+		},
+	)
+}
+
+// streamBlobs calls `fn` for each of `oids`, in order, with the blob's
+// size and a reader of its contents, using a single `git cat-file
+// --batch` process. Whatever part of the blob `fn` doesn't read is
+// discarded. If `fn` returns an error, reading is aborted and that
+// error is returned.
+func (repo *Repository) streamBlobs(
+	ctx context.Context, oids []OID,
+	fn func(oid OID, size counts.Count32, r io.Reader) error,
+) error {
+	if len(oids) == 0 {
+		return nil
+	}
+
+	p := pipe.New()
+	p.Add(
+		// Write the OIDs to the stdin of `git cat-file`:
+		pipe.Function(
+			"request-blobs",
+			func(_ context.Context, _ pipe.Env, _ io.Reader, stdout io.Writer) error {
+				out := bufio.NewWriter(stdout)
+				for _, oid := range oids {
+					if _, err := fmt.Fprintln(out, oid.String()); err != nil {
+						return fmt.Errorf("writing to 'git cat-file': %w", err)
+					}
+				}
+				return out.Flush()
+			},
+		),
+
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand("cat-file", "--batch", "--buffer"),
+		),
+
+		pipe.Function(
+			"blob-reader",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for _, oid := range oids {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					header, err := ParseBatchHeader(oid.String(), line)
+					if err != nil {
+						return err
+					}
+					if header.ObjectType != "blob" {
+						return fmt.Errorf(
+							"expected blob; read %s %s", header.ObjectType, header.OID,
+						)
+					}
+
+					r := io.LimitReader(in, int64(header.ObjectSize)).(*io.LimitedReader)
+					if err := fn(oid, header.ObjectSize, r); err != nil {
+						return err
+					}
+					// Skip the rest of the blob and the trailing LF:
+					if _, err := io.CopyN(io.Discard, in, r.N+1); err != nil {
+						return fmt.Errorf("reading blob %s: %w", oid, err)
+					}
+				}
+				return nil
+			},
+		),
+	)
+
+	return p.Run(ctx)
+}
+
+// TreeListEntry is one entry of the output of `git ls-tree -r -l`.
+type TreeListEntry struct {
+	// Filemode is the mode of the entry.
+	Filemode uint
+
+	// ObjectType is the type of the object ("blob", "commit", or,
+	// for `WalkTrees()`, "tree").
+	ObjectType ObjectType
+
+	// OID is the object ID of the entry.
+	OID OID
+
+	// Size is the size of the blob, or zero for submodules and
+	// trees.
+	Size uint64
+
+	// Path is the full path of the entry, relative to the root of
+	// the tree.
+	Path string
+}
+
+// ReadTreeRecursive returns all of the non-tree entries reachable
+// from `treeish` (anything that `git ls-tree` accepts; e.g., a commit
+// or tree name), with their full paths and sizes.
+func (repo *Repository) ReadTreeRecursive(treeish string) ([]TreeListEntry, error) {
+	cmd, err := repo.gitCommand("ls-tree", "-r", "-l", "-z", "--full-tree", treeish)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git ls-tree %s': %w", treeish, err)
+	}
+
+	return parseTreeList(out)
+}
+
+// ReadTreeAtPath returns the entry at `path` (relative to the root)
+// in the tree of `treeish` (anything that `git ls-tree` accepts). The
+// second return value is `false` if there is no such entry.
+func (repo *Repository) ReadTreeAtPath(treeish, path string) (TreeListEntry, bool, error) {
+	cmd, err := repo.gitCommand("ls-tree", "-l", "-z", "--full-tree", treeish, "--", path)
+	if err != nil {
+		return TreeListEntry{}, false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return TreeListEntry{}, false, fmt.Errorf("running 'git ls-tree %s': %w", treeish, err)
+	}
+
+	entries, err := parseTreeList(out)
+	if err != nil {
+		return TreeListEntry{}, false, err
+	}
+	for _, entry := range entries {
+		// `git ls-tree` treats `path` as a pattern, so make sure
+		// that this is an exact match:
+		if entry.Path == path {
+			return entry, true, nil
+		}
+	}
+	return TreeListEntry{}, false, nil
+}
+
+// WalkTrees calls `fn` for each tree reachable from `treeish`
+// (anything that `git ls-tree` accepts), not including the root tree
+// itself, in depth-first order, parents before children. The output
+// of `git ls-tree` is streamed, so memory use doesn't depend on the
+// number of trees. If `fn` returns an error, the walk is aborted and
+// that error is returned.
+func (repo *Repository) WalkTrees(
+	ctx context.Context, treeish string, fn func(TreeListEntry) error,
+) error {
+	p := pipe.New()
+	p.Add(
+		pipe.CommandStage(
+			"git-ls-tree",
+			repo.GitCommand("ls-tree", "-r", "-d", "-l", "-z", "--full-tree", treeish),
+		),
+		pipe.Function(
+			"tree-parser",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					record, err := in.ReadBytes(0)
+					if err != nil {
+						if err == io.EOF {
+							if len(record) != 0 {
+								return errors.New("'git ls-tree' output ends unexpectedly")
+							}
+							return nil
+						}
+						return fmt.Errorf("reading from 'git ls-tree': %w", err)
+					}
+
+					entry, err := parseTreeListRecord(record[:len(record)-1])
+					if err != nil {
+						return err
+					}
+					if err := fn(entry); err != nil {
+						return err
+					}
+				}
+			},
+		),
+	)
+
+	return p.Run(ctx)
+}
+
+// parseTreeList parses the output of `git ls-tree -r -l -z`, which
+// consists of records like
+//
+//     MODE SP TYPE SP OID SP+ SIZE TAB PATH NUL
+func parseTreeList(out []byte) ([]TreeListEntry, error) {
+	var entries []TreeListEntry
+	for len(out) > 0 {
+		nulAt := bytes.IndexByte(out, 0)
+		if nulAt == -1 {
+			return nil, errors.New("'git ls-tree' output ends unexpectedly")
+		}
+		record := out[:nulAt]
+		out = out[nulAt+1:]
+
+		entry, err := parseTreeListRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseTreeListRecord parses a single record from the output of `git
+// ls-tree -l -z` (without the terminating NUL).
+func parseTreeListRecord(record []byte) (TreeListEntry, error) {
+	tabAt := bytes.IndexByte(record, '\t')
+	if tabAt == -1 {
+		return TreeListEntry{}, fmt.Errorf("malformed 'git ls-tree' record: %q", record)
+	}
+	words := bytes.Fields(record[:tabAt])
+	if len(words) != 4 {
+		return TreeListEntry{}, fmt.Errorf("malformed 'git ls-tree' record: %q", record)
+	}
+
+	var entry TreeListEntry
+	mode, err := strconv.ParseUint(string(words[0]), 8, 32)
+	if err != nil {
+		return TreeListEntry{}, fmt.Errorf("malformed mode in 'git ls-tree' output: %w", err)
+	}
+	entry.Filemode = uint(mode)
+	entry.ObjectType = ObjectType(words[1])
+	entry.OID, err = NewOID(string(words[2]))
+	if err != nil {
+		return TreeListEntry{}, fmt.Errorf("malformed OID in 'git ls-tree' output: %w", err)
+	}
+	if string(words[3]) != "-" {
+		entry.Size, err = strconv.ParseUint(string(words[3]), 10, 64)
+		if err != nil {
+			return TreeListEntry{}, fmt.Errorf("malformed size in 'git ls-tree' output: %w", err)
+		}
+	}
+	entry.Path = string(record[tabAt+1:])
+
+	return entry, nil
+}
@@ -0,0 +1,140 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// BlobIntroduction records that a commit introduces a blob; i.e., that
+// the blob is at some path in the commit's tree, but not at that path
+// in the tree of at least one of its parents (or, for a root commit,
+// at all).
+type BlobIntroduction struct {
+	// Commit is the commit that introduces the blob.
+	Commit OID
+
+	// Time is the committer date of `Commit`, in seconds since the
+	// epoch.
+	Time int64
+
+	// OID is the blob's object ID.
+	OID OID
+
+	// Path is the path of the blob in the tree of `Commit`.
+	Path string
+}
+
+// WalkBlobIntroductions calls `fn` for each blob that is introduced by
+// each of the commits that are reachable from `tips`, which must be
+// commits. The history is read in a single `git log --raw` pass, which
+// diffs each commit against each of its parents, so every blob that is
+// reachable from `tips` is reported at least once, including by the
+// commit that introduced it first. Symlinks count as blobs; submodules
+// are skipped. The order of the calls is unspecified. If `fn` returns
+// an error, the walk is aborted and that error is returned.
+func (repo *Repository) WalkBlobIntroductions(
+	ctx context.Context, tips []OID, fn func(BlobIntroduction) error,
+) error {
+	if len(tips) == 0 {
+		return nil
+	}
+
+	cmd, err := repo.gitCommand(
+		"log", "--stdin", "-z", "--format=commit %H %ct", "--raw", "--root", "-m",
+		"--no-abbrev", "--no-renames", "--no-color", "--no-show-signature",
+	)
+	if err != nil {
+		return err
+	}
+
+	p := pipe.New(pipe.WithStdin(revListInput(tips, nil)))
+	p.Add(
+		pipe.CommandStage("git-log", cmd),
+		pipe.Function(
+			"find-introductions",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				return parseBlobIntroductions(bufio.NewReader(stdin), fn)
+			},
+		),
+	)
+
+	if err := p.Run(ctx); err != nil {
+		return fmt.Errorf("reading the history for blob introductions: %w", err)
+	}
+	return nil
+}
+
+// parseBlobIntroductions parses the output of `git log -z --raw
+// --format="commit %H %ct"`, which consists of NUL-terminated fields:
+// a commit header followed by the raw diff records of that commit,
+// each of which is followed by its path as a separate field. Git puts
+// a LF before the first diff record of each commit (and, for some
+// commits, before the next header), which is ignored.
+func parseBlobIntroductions(in *bufio.Reader, fn func(BlobIntroduction) error) error {
+	var intro BlobIntroduction
+	for {
+		field, err := in.ReadBytes(0)
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimLeft(field, "\n")) != 0 {
+				return errors.New("'git log' output ends unexpectedly")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading 'git log' output: %w", err)
+		}
+		field = bytes.TrimLeft(field[:len(field)-1], "\n")
+
+		switch {
+		case bytes.HasPrefix(field, []byte("commit ")):
+			oid, t, ok := strings.Cut(string(field[len("commit "):]), " ")
+			if !ok {
+				return fmt.Errorf("malformed 'git log' header %q", field)
+			}
+			intro.Commit, err = NewOID(oid)
+			if err != nil {
+				return fmt.Errorf("parsing 'git log' output: %w", err)
+			}
+			intro.Time, err = strconv.ParseInt(t, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing 'git log' output: %w", err)
+			}
+
+		case len(field) > 0 && field[0] == ':':
+			entry, err := parseDiffTreeRecord(field[1:])
+			if err != nil {
+				return err
+			}
+			path, err := in.ReadBytes(0)
+			if err != nil {
+				return fmt.Errorf("reading path from 'git log' output: %w", err)
+			}
+			if intro.Commit == NullOID {
+				return errors.New("'git log' diff record precedes any commit")
+			}
+
+			switch entry.NewMode & 0o170000 {
+			case 0o100000, 0o120000:
+				intro.OID = entry.NewOID
+				intro.Path = string(path[:len(path)-1])
+				if err := fn(intro); err != nil {
+					return err
+				}
+			}
+
+		case len(field) == 0:
+			// Commits without any changes produce empty fields.
+
+		default:
+			return fmt.Errorf("unexpected 'git log' output %q", field)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package git_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestWalkBlobIntroductions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "blob-introductions")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, args...)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "running git %s", strings.Join(args, " "))
+	}
+
+	revParse := func(name string) git.OID {
+		t.Helper()
+		out, err := testRepo.GitCommand(t, "rev-parse", "--verify", name).Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	testRepo.AddFile(t, "a.txt", "a\n")
+	testRepo.AddFile(t, "dir/we\tird", "weird\n")
+	run("commit", "-m", "initial")
+	root := revParse("HEAD")
+
+	run("commit", "--allow-empty", "-m", "empty")
+
+	run("checkout", "-q", "-b", "side")
+	testRepo.AddFile(t, "a.txt", "a2\n")
+	run("commit", "-m", "modify")
+	modify := revParse("HEAD")
+
+	run("checkout", "-q", "master")
+	run("rm", "-q", "dir/we\tird")
+	run("commit", "-m", "delete")
+
+	// A merge that also introduces a file of its own:
+	run("merge", "-q", "--no-ff", "--no-commit", "side")
+	testRepo.AddFile(t, "evil.txt", "evil\n")
+	run("commit", "-m", "merge")
+	merge := revParse("HEAD")
+
+	repo := testRepo.Repository(t)
+
+	type intro struct {
+		commit git.OID
+		blob   git.OID
+		path   string
+	}
+	var intros []intro
+	require.NoError(t, repo.WalkBlobIntroductions(
+		ctx, []git.OID{merge},
+		func(i git.BlobIntroduction) error {
+			assert.NotZero(t, i.Time)
+			intros = append(intros, intro{i.Commit, i.OID, i.Path})
+			return nil
+		},
+	))
+
+	assert.ElementsMatch(
+		t,
+		[]intro{
+			{root, revParse(root.String() + ":a.txt"), "a.txt"},
+			{root, revParse(root.String() + ":dir/we\tird"), "dir/we\tird"},
+			{modify, revParse(modify.String() + ":a.txt"), "a.txt"},
+			// The merge is diffed against both of its parents:
+			{merge, revParse(modify.String() + ":a.txt"), "a.txt"},
+			{merge, revParse(merge.String() + ":evil.txt"), "evil.txt"},
+			{merge, revParse(merge.String() + ":evil.txt"), "evil.txt"},
+		},
+		intros,
+	)
+
+	require.NoError(t, repo.WalkBlobIntroductions(ctx, nil, func(git.BlobIntroduction) error {
+		t.Error("unexpected blob")
+		return nil
+	}))
+}
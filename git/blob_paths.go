@@ -0,0 +1,145 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// BlobPath is a blob together with the path at which `git rev-list
+// --objects` first encountered it.
+type BlobPath struct {
+	OID  OID
+	Size counts.Count32
+
+	// Path is relative to the root of the tree in which the blob
+	// was found. It is empty if the blob was itself one of the
+	// roots.
+	Path string
+}
+
+// WalkBlobPaths calls `fn` for each blob reachable from `roots`, in
+// the order that `git rev-list --objects` finds them. Each blob is
+// reported only once, with the first path at which it was seen. The
+// output is streamed, so memory use doesn't depend on the number of
+// blobs. If `fn` returns an error, the walk is aborted and that error
+// is returned.
+func (repo *Repository) WalkBlobPaths(
+	ctx context.Context, roots []OID, fn func(BlobPath) error,
+) error {
+	return repo.walkReachableObjects(
+		ctx, roots, "%(objectname) %(objecttype) %(objectsize) %(rest)",
+		func(line string) error {
+			blob, ok, err := parseBlobPath(line)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			return fn(blob)
+		},
+	)
+}
+
+// walkReachableObjects runs `git rev-list --objects` on `roots`, pipes
+// the output through `git cat-file --batch-check=<format>`, and calls
+// `fn` for each line of output, without the trailing LF. `format` can
+// use `%(rest)` to include the paths that `rev-list` outputs.
+func (repo *Repository) walkReachableObjects(
+	ctx context.Context, roots []OID, format string, fn func(string) error,
+) error {
+	p := pipe.New()
+	p.Add(
+		// Write the roots to the stdin of `git rev-list`:
+		pipe.Function(
+			"request-roots",
+			func(_ context.Context, _ pipe.Env, _ io.Reader, stdout io.Writer) error {
+				out := bufio.NewWriter(stdout)
+				for _, oid := range roots {
+					if _, err := fmt.Fprintln(out, oid.String()); err != nil {
+						return fmt.Errorf("writing to 'git rev-list': %w", err)
+					}
+				}
+				return out.Flush()
+			},
+		),
+
+		// Output the OIDs of all reachable objects, followed by the
+		// paths of blobs and trees:
+		pipe.CommandStage(
+			"git-rev-list",
+			repo.GitCommand("rev-list", "--objects", "--stdin"),
+		),
+
+		// Add the requested information about each object, passing
+		// the paths through unchanged:
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand("cat-file", "--buffer", "--batch-check="+format),
+		),
+
+		// Pass the output to `fn`:
+		pipe.Function(
+			"object-parser",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+
+					if err := fn(line[:len(line)-1]); err != nil {
+						return err
+					}
+				}
+			},
+		),
+	)
+
+	return p.Run(ctx)
+}
+
+// parseBlobPath parses a line of the form
+//
+//     OID SP TYPE SP SIZE [SP PATH]
+//
+// If the object is not a blob, return `false`.
+func parseBlobPath(line string) (BlobPath, bool, error) {
+	words := strings.SplitN(line, " ", 4)
+	if len(words) < 3 {
+		return BlobPath{}, false, fmt.Errorf("malformed 'git cat-file' output: %q", line)
+	}
+	if words[1] != "blob" {
+		return BlobPath{}, false, nil
+	}
+
+	oid, err := NewOID(words[0])
+	if err != nil {
+		return BlobPath{}, false, err
+	}
+	size, err := strconv.ParseUint(words[2], 10, 64)
+	if err != nil {
+		return BlobPath{}, false, fmt.Errorf("malformed size in 'git cat-file' output: %w", err)
+	}
+
+	blob := BlobPath{
+		OID:  oid,
+		Size: counts.NewCount32(size),
+	}
+	if len(words) == 4 {
+		blob.Path = words[3]
+	}
+	return blob, true, nil
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// BlobStorage describes how a blob is stored.
+type BlobStorage struct {
+	BlobPath
+
+	// DiskSize is the number of bytes that the blob takes up on
+	// disk: the size of the loose object file, or of the (possibly
+	// deltified) entry in a packfile.
+	DiskSize counts.Count64
+
+	// DeltaBase is the OID of the object that the blob is stored as
+	// a delta against, or `NullOID` if it is stored in full (e.g.,
+	// because it is loose).
+	DeltaBase OID
+}
+
+// IsDelta returns true iff the blob is stored as a delta.
+func (b BlobStorage) IsDelta() bool {
+	return b.DeltaBase != NullOID
+}
+
+// WalkBlobStorage is like `WalkBlobPaths()`, but also reports how each
+// blob is stored.
+func (repo *Repository) WalkBlobStorage(
+	ctx context.Context, roots []OID, fn func(BlobStorage) error,
+) error {
+	return repo.walkReachableObjects(
+		ctx, roots,
+		"%(objectname) %(objecttype) %(objectsize) %(objectsize:disk) %(deltabase) %(rest)",
+		func(line string) error {
+			blob, ok, err := parseBlobStorage(line)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			return fn(blob)
+		},
+	)
+}
+
+// parseBlobStorage parses a line of the form
+//
+//	OID SP TYPE SP SIZE SP DISKSIZE SP DELTABASE [SP PATH]
+//
+// If the object is not a blob, return `false`.
+func parseBlobStorage(line string) (BlobStorage, bool, error) {
+	words := strings.SplitN(line, " ", 6)
+	if len(words) < 5 {
+		return BlobStorage{}, false, fmt.Errorf("malformed 'git cat-file' output: %q", line)
+	}
+	if words[1] != "blob" {
+		return BlobStorage{}, false, nil
+	}
+
+	oid, err := NewOID(words[0])
+	if err != nil {
+		return BlobStorage{}, false, err
+	}
+	size, err := strconv.ParseUint(words[2], 10, 64)
+	if err != nil {
+		return BlobStorage{}, false, fmt.Errorf("malformed size in 'git cat-file' output: %w", err)
+	}
+	diskSize, err := strconv.ParseUint(words[3], 10, 64)
+	if err != nil {
+		return BlobStorage{}, false, fmt.Errorf(
+			"malformed disk size in 'git cat-file' output: %w", err,
+		)
+	}
+	deltaBase, err := NewOID(words[4])
+	if err != nil {
+		return BlobStorage{}, false, fmt.Errorf(
+			"malformed delta base in 'git cat-file' output: %w", err,
+		)
+	}
+
+	blob := BlobStorage{
+		BlobPath: BlobPath{
+			OID:  oid,
+			Size: counts.NewCount32(size),
+		},
+		DiskSize:  counts.NewCount64(diskSize),
+		DeltaBase: deltaBase,
+	}
+	if len(words) == 6 {
+		blob.Path = words[5]
+	}
+	return blob, true, nil
+}
@@ -2,6 +2,8 @@ package git
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/github/git-sizer/counts"
 )
@@ -11,14 +13,36 @@ type Commit struct {
 	Size    counts.Count32
 	Parents []OID
 	Tree    OID
+
+	// AuthorEmail and CommitterEmail are the email addresses from
+	// the "author" and "committer" headers, without the angle
+	// brackets. They are empty if the header is missing or
+	// malformed.
+	AuthorEmail    string
+	CommitterEmail string
+
+	// AuthorTime and CommitterTime are the timestamps (in seconds
+	// since the epoch) from the "author" and "committer" headers.
+	// They are -1 if the header is missing or malformed.
+	AuthorTime    int64
+	CommitterTime int64
+
+	// Headers describes anything unusual about the commit's
+	// headers.
+	Headers HeaderStats
 }
 
 // ParseCommit parses the commit object whose contents are in `data`.
-// `oid` is used only in error messages.
+// `oid` is used only in error messages. Unknown and duplicated headers
+// are tolerated (only the first "tree" header is used), but are
+// recorded in the `Headers` field.
 func ParseCommit(oid OID, data []byte) (*Commit, error) {
 	var parents []OID
 	var tree OID
 	var treeFound bool
+	var authorEmail, committerEmail string
+	authorTime, committerTime := int64(-1), int64(-1)
+	headers := newHeaderStatsBuilder(commitHeaders)
 	iter, err := NewObjectHeaderIter(oid.String(), data)
 	if err != nil {
 		return nil, err
@@ -28,22 +52,38 @@ func ParseCommit(oid OID, data []byte) (*Commit, error) {
 		if err != nil {
 			return nil, err
 		}
+		headers.add(key, value)
 		switch key {
 		case "parent":
 			parent, err := NewOID(value)
 			if err != nil {
 				return nil, fmt.Errorf("malformed parent header in commit %s", oid)
 			}
+			headers.addParent(parent)
 			parents = append(parents, parent)
 		case "tree":
 			if treeFound {
-				return nil, fmt.Errorf("multiple trees found in commit %s", oid)
+				continue
 			}
 			tree, err = NewOID(value)
 			if err != nil {
 				return nil, fmt.Errorf("malformed tree header in commit %s", oid)
 			}
 			treeFound = true
+		case "author":
+			if authorEmail == "" {
+				authorEmail = identEmail(value)
+			}
+			if authorTime == -1 {
+				authorTime = identTime(value)
+			}
+		case "committer":
+			if committerEmail == "" {
+				committerEmail = identEmail(value)
+			}
+			if committerTime == -1 {
+				committerTime = identTime(value)
+			}
 		}
 	}
 	if !treeFound {
@@ -53,5 +93,46 @@ func ParseCommit(oid OID, data []byte) (*Commit, error) {
 		Size:    counts.NewCount32(uint64(len(data))),
 		Parents: parents,
 		Tree:    tree,
+
+		AuthorEmail:    authorEmail,
+		CommitterEmail: committerEmail,
+		AuthorTime:     authorTime,
+		CommitterTime:  committerTime,
+
+		Headers: headers.stats,
 	}, nil
 }
+
+// identEmail extracts the email address from an identity like
+// `A U Thor <author@example.com> 1112911993 -0700`. It returns "" if
+// there is no email address.
+func identEmail(ident string) string {
+	start := strings.IndexByte(ident, '<')
+	if start == -1 {
+		return ""
+	}
+	end := strings.IndexByte(ident[start+1:], '>')
+	if end == -1 {
+		return ""
+	}
+	return ident[start+1 : start+1+end]
+}
+
+// identTime extracts the timestamp from an identity like
+// `A U Thor <author@example.com> 1112911993 -0700`. It returns -1 if
+// there is no valid timestamp.
+func identTime(ident string) int64 {
+	end := strings.LastIndexByte(ident, '>')
+	if end == -1 {
+		return -1
+	}
+	fields := strings.Fields(ident[end+1:])
+	if len(fields) == 0 {
+		return -1
+	}
+	t, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || t < 0 {
+		return -1
+	}
+	return t
+}
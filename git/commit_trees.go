@@ -0,0 +1,72 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CommitTree is a commit, together with its tree and its committer
+// timestamp.
+type CommitTree struct {
+	OID  OID
+	Tree OID
+
+	// Time is the committer timestamp, in seconds since the epoch.
+	Time int64
+}
+
+// CommitTrees returns the commits that are reachable from any of
+// `tips`, which must be commits, in the order that `git rev-list`
+// emits them.
+func (repo *Repository) CommitTrees(tips []OID) ([]CommitTree, error) {
+	if len(tips) == 0 {
+		return nil, nil
+	}
+
+	cmd, err := repo.gitCommand(repo.revListArgs("rev-list", "--format=%T %ct", "--stdin")...)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = revListInput(tips, nil)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git rev-list': %w", err)
+	}
+
+	// Each commit is output as two lines:
+	//
+	//     commit <oid>
+	//     <tree> <timestamp>
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines)%2 != 0 {
+		return nil, fmt.Errorf("'git rev-list' output has an odd number of lines")
+	}
+	commits := make([]CommitTree, 0, len(lines)/2)
+	for i := 0; i < len(lines); i += 2 {
+		if !strings.HasPrefix(lines[i], "commit ") {
+			return nil, fmt.Errorf("malformed line from 'git rev-list': %q", lines[i])
+		}
+		oid, err := NewOID(strings.TrimPrefix(lines[i], "commit "))
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+
+		treeString, timestamp, ok := strings.Cut(lines[i+1], " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed line from 'git rev-list': %q", lines[i+1])
+		}
+		tree, err := NewOID(treeString)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+		t, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+
+		commits = append(commits, CommitTree{OID: oid, Tree: tree, Time: t})
+	}
+
+	return commits, nil
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// SkipAncestors can be returned by the callback passed to
+// `WalkCommits()` to indicate that the ancestors of the current commit
+// should not be visited. Like the commits after `--not` in `git
+// rev-list`, they are excluded even if they are also reachable from
+// other commits that are visited. It is not returned as an error by
+// `WalkCommits()` itself.
+var SkipAncestors = errors.New("skip ancestors")
+
+// WalkCommits calls `fn` once for each commit that is reachable from
+// `tips`, which must be commits. Beyond the guarantee that each commit
+// is visited before its parents, the order of the visits is
+// unspecified. If `fn` returns `SkipAncestors`, the walk doesn't visit
+// that commit's ancestors; if it returns any other error, the walk is
+// aborted and the error is returned.
+//
+// Skipped commits are pruned by `git rev-list` itself, which is
+// restarted for each skip, so skipping is meant for cutting off large
+// parts of the history rather than individual commits.
+func (repo *Repository) WalkCommits(
+	ctx context.Context, tips []OID, fn func(oid OID, commit *Commit) error,
+) error {
+	// `wanted` holds the commits that still have to be visited: the
+	// tips, plus the parents of the commits visited so far. If the
+	// walk is restarted, these are the tips of the new walk.
+	wanted := make(map[OID]struct{}, len(tips))
+	for _, oid := range tips {
+		wanted[oid] = struct{}{}
+	}
+
+	var skipped []OID
+	for len(wanted) > 0 {
+		pending := make([]OID, 0, len(wanted))
+		for oid := range wanted {
+			pending = append(pending, oid)
+		}
+
+		skip, err := repo.walkCommits(ctx, pending, skipped, wanted, fn)
+		if err != nil {
+			return err
+		}
+		if skip == nil {
+			return nil
+		}
+		skipped = append(skipped, *skip)
+	}
+
+	return nil
+}
+
+// walkCommits runs a single `git rev-list` over the commits reachable
+// from `tips` but not from `exclude`, calling `fn` for each one and
+// keeping `wanted` up to date. If `fn` returns `SkipAncestors`, the
+// walk stops and the skipped commit is returned, so that the caller
+// can restart it without that commit's ancestors.
+func (repo *Repository) walkCommits(
+	ctx context.Context, tips, exclude []OID, wanted map[OID]struct{},
+	fn func(oid OID, commit *Commit) error,
+) (*OID, error) {
+	var skip *OID
+
+	p := pipe.New(pipe.WithStdin(revListInput(tips, exclude)))
+	p.Add(
+		pipe.CommandStage(
+			"git-rev-list",
+			repo.GitCommand("rev-list", "--topo-order", "--stdin"),
+		),
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand("cat-file", "--batch", "--buffer"),
+		),
+		pipe.Function(
+			"walk-commits",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					header, err := ParseBatchHeader("", line)
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+
+					// Read the object contents plus the trailing LF:
+					data := make([]byte, header.ObjectSize+1)
+					if _, err := io.ReadFull(in, data); err != nil {
+						return fmt.Errorf(
+							"reading commit '%s' from 'git cat-file': %w", header.OID, err,
+						)
+					}
+
+					if header.ObjectType != "commit" {
+						return fmt.Errorf(
+							"expected commit but found %s: %s", header.ObjectType, header.OID,
+						)
+					}
+					commit, err := ParseCommit(header.OID, data[:header.ObjectSize])
+					if err != nil {
+						return err
+					}
+
+					// `git rev-list --topo-order` emits every commit
+					// before its parents, so none of the commits
+					// visited so far can be reached from this one.
+					delete(wanted, header.OID)
+
+					switch err := fn(header.OID, commit); {
+					case err == nil:
+						for _, parent := range commit.Parents {
+							wanted[parent] = struct{}{}
+						}
+					case errors.Is(err, SkipAncestors):
+						oid := header.OID
+						skip = &oid
+						return pipe.FinishEarly
+					default:
+						return err
+					}
+				}
+			},
+		),
+	)
+
+	if err := p.Run(ctx); err != nil {
+		return nil, err
+	}
+	return skip, nil
+}
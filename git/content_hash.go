@@ -0,0 +1,103 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// BlobContentHash returns the SHA-256 of the contents of the blob
+// `oid` (without the object header), regardless of which hash
+// algorithm the repository uses for its OIDs.
+func (repo *Repository) BlobContentHash(oid OID) ([32]byte, error) {
+	var sum [32]byte
+	err := repo.BlobContentHashes(
+		context.TODO(), []OID{oid}, false,
+		func(_ OID, s [32]byte) error {
+			sum = s
+			return nil
+		},
+	)
+	return sum, err
+}
+
+// BlobContentHashes calls `fn` for each of `oids`, in order, with the
+// SHA-256 of the blob's contents, like `BlobContentHash()`. If
+// `normalize` is set, CRLF line endings are converted to LF before
+// hashing, so blobs that differ only in their line endings have the
+// same hash. All of the blobs are read by a single `git cat-file
+// --batch` process and hashed as they are read, so memory use doesn't
+// depend on the size of the blobs.
+func (repo *Repository) BlobContentHashes(
+	ctx context.Context, oids []OID, normalize bool,
+	fn func(oid OID, sum [32]byte) error,
+) error {
+	return repo.streamBlobs(
+		ctx, oids,
+		func(oid OID, _ counts.Count32, r io.Reader) error {
+			h := sha256.New()
+			var w io.Writer = h
+			var nw *crlfNormalizer
+			if normalize {
+				nw = &crlfNormalizer{w: h}
+				w = nw
+			}
+			if _, err := io.Copy(w, r); err != nil {
+				return fmt.Errorf("reading blob %s: %w", oid, err)
+			}
+			if nw != nil {
+				if err := nw.Flush(); err != nil {
+					return err
+				}
+			}
+
+			var sum [32]byte
+			copy(sum[:], h.Sum(nil))
+			return fn(oid, sum)
+		},
+	)
+}
+
+// crlfNormalizer is an `io.Writer` that writes its input to `w` with
+// each CRLF replaced by LF. A CR at the end of one write is held back
+// until it is known whether it is followed by LF, so `Flush()` must
+// be called after the last write.
+type crlfNormalizer struct {
+	w         io.Writer
+	pendingCR bool
+	buf       []byte
+}
+
+func (n *crlfNormalizer) Write(p []byte) (int, error) {
+	n.buf = n.buf[:0]
+	for _, b := range p {
+		if n.pendingCR {
+			n.pendingCR = false
+			if b != '\n' {
+				n.buf = append(n.buf, '\r')
+			}
+		}
+		if b == '\r' {
+			n.pendingCR = true
+			continue
+		}
+		n.buf = append(n.buf, b)
+	}
+	if _, err := n.w.Write(n.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes a CR that was held back at the end of the input.
+func (n *crlfNormalizer) Flush() error {
+	if !n.pendingCR {
+		return nil
+	}
+	n.pendingCR = false
+	_, err := n.w.Write([]byte{'\r'})
+	return err
+}
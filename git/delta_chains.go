@@ -0,0 +1,140 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// DeltaChain describes an object that is stored as a delta, as
+// reported by `WalkDeltaChains()`.
+type DeltaChain struct {
+	OID        OID        `json:"oid"`
+	ObjectType ObjectType `json:"object_type"`
+
+	// Base is the object that this one is a delta against.
+	Base OID `json:"base"`
+
+	// Length is the number of deltas that have to be applied to
+	// reconstruct the object (i.e., 1 if `Base` is stored in full).
+	Length uint32 `json:"length"`
+}
+
+// WalkDeltaChains calls `fn` for each object in `repo` (including
+// unreachable objects) that is stored as a delta, in OID order. Long
+// chains make objects slow to read; they come from repacking with a
+// high `--depth` (or `pack.depth`).
+//
+// If an object is stored more than once, the copy that git would
+// read is used.
+func (repo *Repository) WalkDeltaChains(ctx context.Context, fn func(DeltaChain) error) error {
+	type delta struct {
+		objectType ObjectType
+		base       OID
+	}
+	deltas := make(map[OID]delta)
+
+	p := pipe.New()
+	p.Add(
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand(
+				"cat-file", "--batch-all-objects",
+				"--batch-check=%(objectname) %(objecttype) %(deltabase)",
+			),
+		),
+		pipe.Function(
+			"read-delta-bases",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							if line != "" {
+								return errors.New("'git cat-file' output ends unexpectedly")
+							}
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					words := strings.Fields(line)
+					if len(words) != 3 {
+						return fmt.Errorf("malformed line from 'git cat-file': %q", line)
+					}
+					base, err := NewOID(words[2])
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					if base == NullOID {
+						continue
+					}
+					oid, err := NewOID(words[0])
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					deltas[oid] = delta{ObjectType(words[1]), base}
+				}
+			},
+		),
+	)
+	if err := p.Run(ctx); err != nil {
+		return err
+	}
+
+	// Compute the chain lengths, remembering them so that each
+	// chain is only followed once:
+	lengths := make(map[OID]uint32, len(deltas))
+	var chain []OID
+	lengthOf := func(oid OID) (uint32, error) {
+		chain = chain[:0]
+		var length uint32
+		for {
+			if n, ok := lengths[oid]; ok {
+				length = n
+				break
+			}
+			d, ok := deltas[oid]
+			if !ok {
+				// Stored in full.
+				break
+			}
+			chain = append(chain, oid)
+			if len(chain) > len(deltas) {
+				return 0, fmt.Errorf("delta cycle involving %s", oid)
+			}
+			oid = d.base
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			length++
+			lengths[chain[i]] = length
+		}
+		return length, nil
+	}
+
+	oids := make([]OID, 0, len(deltas))
+	for oid := range deltas {
+		oids = append(oids, oid)
+	}
+	sort.Slice(oids, func(i, j int) bool { return oids[i].String() < oids[j].String() })
+
+	for _, oid := range oids {
+		length, err := lengthOf(oid)
+		if err != nil {
+			return err
+		}
+		d := deltas[oid]
+		if err := fn(DeltaChain{
+			OID: oid, ObjectType: d.objectType, Base: d.base, Length: length,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
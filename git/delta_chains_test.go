@@ -0,0 +1,97 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestWalkDeltaChains(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "delta-chains")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// Successive versions of a file, each of which rewrites a
+	// different few lines of its predecessor. Each version is thus
+	// cheapest to express as a delta against its neighbor, so git
+	// stores them as a chain of deltas:
+	lines := make([]string, 400)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d of a file that is going to be deltified\n", i)
+	}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			lines[20*i+j] = fmt.Sprintf("line %d rewritten in version %d: %x\n", 20*i+j, i, 7919*(i*97+j))
+		}
+		testRepo.AddFile(t, "file.txt", strings.Join(lines, ""))
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("version %d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+	}
+
+	repo := testRepo.Repository(t)
+
+	var chains []git.DeltaChain
+	require.NoError(t, repo.WalkDeltaChains(ctx, func(c git.DeltaChain) error {
+		chains = append(chains, c)
+		return nil
+	}))
+	assert.Empty(t, chains, "loose objects are not deltified")
+
+	cmd := testRepo.GitCommand(t, "repack", "-adf", "--depth=50", "--window=250")
+	require.NoError(t, cmd.Run())
+
+	// `git verify-pack -v` reports the chain length of each
+	// deltified object in its sixth column:
+	packs, err := filepath.Glob(filepath.Join(testRepo.Path, ".git", "objects", "pack", "*.idx"))
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	out, err := testRepo.GitCommand(t, "verify-pack", "-v", packs[0]).Output()
+	require.NoError(t, err)
+	expected := make(map[string]uint32)
+	for _, line := range strings.Split(string(out), "\n") {
+		words := strings.Fields(line)
+		if len(words) != 7 {
+			continue
+		}
+		depth, err := strconv.ParseUint(words[5], 10, 32)
+		require.NoError(t, err)
+		expected[words[0]] = uint32(depth)
+	}
+	require.NotEmpty(t, expected)
+
+	chains = nil
+	require.NoError(t, repo.WalkDeltaChains(ctx, func(c git.DeltaChain) error {
+		chains = append(chains, c)
+		return nil
+	}))
+	actual := make(map[string]uint32)
+	var maxLength uint32
+	for i, c := range chains {
+		if i > 0 {
+			assert.Less(t, chains[i-1].OID.String(), c.OID.String())
+		}
+		assert.Equal(t, git.ObjectType("blob"), c.ObjectType)
+		actual[c.OID.String()] = c.Length
+		if c.Length > maxLength {
+			maxLength = c.Length
+		}
+	}
+	assert.Equal(t, expected, actual)
+	assert.Greater(t, maxLength, uint32(1))
+}
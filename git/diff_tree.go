@@ -0,0 +1,128 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DiffTreeEntry represents one changed path, as reported by `git
+// diff-tree -r`.
+type DiffTreeEntry struct {
+	// OldMode and NewMode are the file modes before and after the
+	// change. A mode of zero means that the path didn't exist on
+	// that side.
+	OldMode uint
+	NewMode uint
+
+	// OldOID and NewOID are the object IDs before and after the
+	// change. `NullOID` means that the path didn't exist on that
+	// side.
+	OldOID OID
+	NewOID OID
+
+	// Status is the status letter ('A', 'D', 'M', or 'T').
+	Status byte
+
+	// Path is the path of the entry relative to the root of the
+	// trees being compared.
+	Path string
+}
+
+// IsBlob returns true iff either side of the change is a blob
+// (including a symlink), as opposed to a submodule.
+func (e DiffTreeEntry) IsBlob() bool {
+	isBlobMode := func(mode uint) bool {
+		return mode&0o170000 == 0o100000 || mode&0o170000 == 0o120000
+	}
+	return isBlobMode(e.OldMode) || isBlobMode(e.NewMode)
+}
+
+// DiffTree returns the entries that differ between the trees
+// `oldTree` and `newTree`, recursing into subtrees. Renames are not
+// detected, and no blob contents are read.
+func (repo *Repository) DiffTree(oldTree, newTree OID) ([]DiffTreeEntry, error) {
+	cmd, err := repo.gitCommand(
+		"diff-tree", "-r", "-z", "--no-renames", "--no-commit-id",
+		oldTree.String(), newTree.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"running 'git diff-tree %s %s': %w", oldTree, newTree, err,
+		)
+	}
+
+	return parseDiffTree(out)
+}
+
+// parseDiffTree parses the output of `git diff-tree -r -z`, which
+// consists of records like
+//
+//     :OLDMODE SP NEWMODE SP OLDOID SP NEWOID SP STATUS NUL PATH NUL
+func parseDiffTree(out []byte) ([]DiffTreeEntry, error) {
+	var entries []DiffTreeEntry
+	for len(out) > 0 {
+		nulAt := bytes.IndexByte(out, 0)
+		if nulAt == -1 || out[0] != ':' {
+			return nil, errors.New("malformed output from 'git diff-tree'")
+		}
+		record := out[1:nulAt]
+		out = out[nulAt+1:]
+		entry, err := parseDiffTreeRecord(record)
+		if err != nil {
+			return nil, err
+		}
+
+		nulAt = bytes.IndexByte(out, 0)
+		if nulAt == -1 {
+			return nil, errors.New("'git diff-tree' output ends unexpectedly")
+		}
+		entry.Path = string(out[:nulAt])
+		out = out[nulAt+1:]
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseDiffTreeRecord parses the part of a `git diff-tree --raw`
+// record before the path (without the leading colon); i.e.,
+//
+//     OLDMODE SP NEWMODE SP OLDOID SP NEWOID SP STATUS
+//
+// The path of the returned entry is left empty.
+func parseDiffTreeRecord(record []byte) (DiffTreeEntry, error) {
+	words := bytes.Split(record, []byte(" "))
+	if len(words) != 5 || len(words[4]) == 0 {
+		return DiffTreeEntry{}, fmt.Errorf("malformed 'git diff-tree' record: %q", record)
+	}
+
+	var entry DiffTreeEntry
+	oldMode, err := strconv.ParseUint(string(words[0]), 8, 32)
+	if err != nil {
+		return DiffTreeEntry{}, fmt.Errorf("malformed mode in 'git diff-tree' output: %w", err)
+	}
+	entry.OldMode = uint(oldMode)
+	newMode, err := strconv.ParseUint(string(words[1]), 8, 32)
+	if err != nil {
+		return DiffTreeEntry{}, fmt.Errorf("malformed mode in 'git diff-tree' output: %w", err)
+	}
+	entry.NewMode = uint(newMode)
+	entry.OldOID, err = NewOID(string(words[2]))
+	if err != nil {
+		return DiffTreeEntry{}, fmt.Errorf("malformed OID in 'git diff-tree' output: %w", err)
+	}
+	entry.NewOID, err = NewOID(string(words[3]))
+	if err != nil {
+		return DiffTreeEntry{}, fmt.Errorf("malformed OID in 'git diff-tree' output: %w", err)
+	}
+	entry.Status = words[4][0]
+
+	return entry, nil
+}
@@ -0,0 +1,40 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiffTree(t *testing.T) {
+	t.Parallel()
+
+	oid1 := "1111111111111111111111111111111111111111"
+	oid2 := "2222222222222222222222222222222222222222"
+	null := "0000000000000000000000000000000000000000"
+
+	out := ":100644 100644 " + oid1 + " " + oid2 + " M\x00dir/file.txt\x00" +
+		":000000 120000 " + null + " " + oid1 + " A\x00link\x00" +
+		":160000 000000 " + oid2 + " " + null + " D\x00sub\x00"
+
+	entries, err := parseDiffTree([]byte(out))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, uint(0o100644), entries[0].OldMode)
+	assert.Equal(t, byte('M'), entries[0].Status)
+	assert.Equal(t, "dir/file.txt", entries[0].Path)
+	assert.Equal(t, oid2, entries[0].NewOID.String())
+	assert.True(t, entries[0].IsBlob())
+
+	assert.Equal(t, NullOID, entries[1].OldOID)
+	assert.Equal(t, "link", entries[1].Path)
+	assert.True(t, entries[1].IsBlob())
+
+	assert.Equal(t, "sub", entries[2].Path)
+	assert.False(t, entries[2].IsBlob())
+
+	_, err = parseDiffTree([]byte(":100644 100644 " + oid1 + " M\x00f\x00"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrAmbiguousRef is returned (wrapped) by `ExpandRef()` if a name
+// could refer to more than one reference.
+var ErrAmbiguousRef = errors.New("ambiguous reference name")
+
+// ErrRefNotFound is returned (wrapped) by `ExpandRef()` if a name
+// doesn't refer to any reference.
+var ErrRefNotFound = errors.New("reference not found")
+
+// refExpansions are the patterns that git tries, in order, when
+// looking up an abbreviated reference name (see gitrevisions(7)).
+var refExpansions = []string{
+	"%s",
+	"refs/%s",
+	"refs/tags/%s",
+	"refs/heads/%s",
+	"refs/remotes/%s",
+	"refs/remotes/%s/HEAD",
+}
+
+// ExpandRef returns the full name of the reference that `name` (e.g.,
+// "main" or "origin/main") abbreviates, using the same rules as git:
+// `name` is first normalized with `git check-ref-format --normalize`,
+// then expanded as `refs/NAME`, `refs/tags/NAME`, `refs/heads/NAME`,
+// `refs/remotes/NAME`, and `refs/remotes/NAME/HEAD`. (Names that are
+// already full, like `refs/heads/main`, stand for themselves.) If
+// none of the candidates exists, it returns an error wrapping
+// `ErrRefNotFound`; if more than one does, an error wrapping
+// `ErrAmbiguousRef`. Unlike git, which warns and then picks the first
+// candidate, this refuses to guess.
+func (repo *Repository) ExpandRef(name string) (string, error) {
+	cmd, err := repo.gitCommand("check-ref-format", "--allow-onelevel", "--normalize", name)
+	if err != nil {
+		return "", err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("invalid reference name %q", name)
+		}
+		return "", fmt.Errorf("running 'git check-ref-format': %w", err)
+	}
+	normalized := string(bytes.TrimSpace(out))
+
+	var found []string
+	for _, pattern := range refExpansions {
+		candidate := fmt.Sprintf(pattern, normalized)
+		if !strings.HasPrefix(candidate, "refs/") {
+			continue
+		}
+		exists, err := repo.RefExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			found = append(found, candidate)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf(
+			"%w: %q could mean %s", ErrAmbiguousRef, name, strings.Join(found, " or "),
+		)
+	}
+}
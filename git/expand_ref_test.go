@@ -0,0 +1,66 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestExpandRef(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "expand-ref")
+	defer testRepo.Remove(t)
+
+	for _, refname := range []string{
+		"refs/heads/main",
+		"refs/heads/topic",
+		"refs/tags/v1.0",
+		"refs/tags/topic",
+		"refs/remotes/origin/main",
+		"refs/stash",
+	} {
+		testRepo.CreateReferencedOrphan(t, refname)
+	}
+	cmd := testRepo.GitCommand(
+		t, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main",
+	)
+	require.NoError(t, cmd.Run())
+
+	repo := testRepo.Repository(t)
+
+	for _, tc := range []struct {
+		name     string
+		expected string
+		err      error
+	}{
+		{"main", "refs/heads/main", nil},
+		{"refs/heads/main", "refs/heads/main", nil},
+		{"heads/main", "refs/heads/main", nil},
+		{"//main", "refs/heads/main", nil},
+		{"v1.0", "refs/tags/v1.0", nil},
+		{"origin/main", "refs/remotes/origin/main", nil},
+		{"origin", "refs/remotes/origin/HEAD", nil},
+		{"stash", "refs/stash", nil},
+		{"topic", "", git.ErrAmbiguousRef},
+		{"missing", "", git.ErrRefNotFound},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			refname, err := repo.ExpandRef(tc.name)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, refname)
+		})
+	}
+
+	_, err := repo.ExpandRef("bad..name")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CommitTime is a commit, together with its committer timestamp.
+type CommitTime struct {
+	OID OID
+
+	// Time is the committer timestamp, in seconds since the epoch.
+	Time int64
+}
+
+// FirstParentHistory returns the commits in the first-parent history
+// of `tip` (i.e., `tip`, its first parent, that commit's first
+// parent, and so on), starting with `tip`.
+func (repo *Repository) FirstParentHistory(tip OID) ([]CommitTime, error) {
+	cmd, err := repo.gitCommand(
+		"rev-list", "--first-parent", "--timestamp", tip.String(), "--",
+	)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git rev-list --first-parent %s': %w", tip, err)
+	}
+
+	var commits []CommitTime
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		timestamp, oidString, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed line from 'git rev-list': %q", line)
+		}
+		t, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+		oid, err := NewOID(oidString)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+		commits = append(commits, CommitTime{OID: oid, Time: t})
+	}
+
+	return commits, nil
+}
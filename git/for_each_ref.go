@@ -0,0 +1,166 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// ForEachRefOptions controls which references
+// `Repository.ForEachRefWithOptions()` reports, in what order, and
+// what it reports about them.
+type ForEachRefOptions struct {
+	// Sort, if set, is a `git for-each-ref` sort key (e.g.,
+	// `creatordate`, or `-version:refname` for the newest version
+	// first) by which the references are ordered. By default, they
+	// are ordered by refname.
+	Sort string
+
+	// Count, if positive, is the maximum number of references to
+	// report. Combined with `Sort`, this selects the top N.
+	Count int
+
+	// Patterns, if set, limits the references to those matching any
+	// of these `git for-each-ref` patterns (e.g., `refs/tags/`).
+	Patterns []string
+
+	// Fields are the names of additional `git for-each-ref` fields
+	// (e.g., `creatordate:unix` or `*objectname`), without
+	// the surrounding `%(...)`, whose values should be reported in
+	// `Ref.Fields`.
+	Fields []string
+}
+
+// Ref is a reference as reported by
+// `Repository.ForEachRefWithOptions()`.
+type Ref struct {
+	Reference
+
+	// Fields maps each of the names in `ForEachRefOptions.Fields` to
+	// the value of that field for this reference. It is nil if no
+	// extra fields were requested.
+	Fields map[string]string
+}
+
+// ForEachRefWithOptions calls `fn` for each of the references in
+// `repo` that match `opts`, in the order requested by `opts`. If `fn`
+// returns an error, the iteration is stopped and that error is
+// returned as-is.
+func (repo *Repository) ForEachRefWithOptions(
+	opts ForEachRefOptions, fn func(ref Ref) error,
+) error {
+	// Fields are NUL-terminated, so that values (like `contents`)
+	// may contain LFs; `git for-each-ref` follows each reference
+	// with an LF.
+	var format strings.Builder
+	format.WriteString("--format=%(objectname)%00%(objecttype)%00%(objectsize)%00%(refname)%00")
+	for _, field := range opts.Fields {
+		if field == "" || strings.ContainsAny(field, "%()") {
+			return fmt.Errorf("invalid 'git for-each-ref' field name %q", field)
+		}
+		fmt.Fprintf(&format, "%%(%s)%%00", field)
+	}
+
+	args := []string{"for-each-ref", format.String()}
+	if opts.Sort != "" {
+		args = append(args, "--sort="+opts.Sort)
+	}
+	if opts.Count > 0 {
+		args = append(args, fmt.Sprintf("--count=%d", opts.Count))
+	}
+	args = append(args, "--")
+	args = append(args, opts.Patterns...)
+
+	cmd, err := repo.gitCommand(args...)
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting 'git for-each-ref': %w", err)
+	}
+
+	err = func() error {
+		f := bufio.NewReader(out)
+		values := make([]string, 4+len(opts.Fields))
+		for {
+			for i := range values {
+				value, err := f.ReadBytes(0)
+				// References start with the LF that terminated the
+				// previous one:
+				if i == 0 {
+					value = bytes.TrimPrefix(value, []byte{'\n'})
+				}
+				if err != nil {
+					if err == io.EOF && i == 0 && len(value) == 0 {
+						return nil
+					}
+					return fmt.Errorf("reading from 'git for-each-ref': %w", err)
+				}
+				values[i] = string(value[:len(value)-1])
+			}
+
+			ref, err := parseForEachRefValues(values, opts.Fields)
+			if err != nil {
+				return fmt.Errorf("parsing output of 'git for-each-ref': %w", err)
+			}
+			if err := fn(ref); err != nil {
+				return err
+			}
+		}
+	}()
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_, _ = io.Copy(io.Discard, out)
+		_ = cmd.Wait()
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("running 'git for-each-ref': %w", err)
+	}
+	return nil
+}
+
+// parseForEachRefValues builds a `Ref` out of the values output by
+// `git for-each-ref` for one reference: its OID, object type, object
+// size, refname, and then the values of `fields`.
+func parseForEachRefValues(values []string, fields []string) (Ref, error) {
+	oid, err := NewOID(values[0])
+	if err != nil {
+		return Ref{}, fmt.Errorf("SHA-1 improperly formatted: %#v", values[0])
+	}
+	objectSize, err := strconv.ParseUint(values[2], 10, 32)
+	if err != nil {
+		return Ref{}, fmt.Errorf("object size improperly formatted: %#v", values[2])
+	}
+	if values[3] == "" {
+		return Ref{}, errors.New("empty refname")
+	}
+
+	ref := Ref{
+		Reference: Reference{
+			Refname:    values[3],
+			ObjectType: ObjectType(values[1]),
+			ObjectSize: counts.Count32(objectSize),
+			OID:        oid,
+		},
+	}
+	if len(fields) != 0 {
+		ref.Fields = make(map[string]string, len(fields))
+		for i, field := range fields {
+			ref.Fields[field] = values[4+i]
+		}
+	}
+
+	return ref, nil
+}
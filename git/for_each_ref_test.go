@@ -0,0 +1,97 @@
+package git_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestForEachRefWithOptions(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "for-each-ref")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	// Tags whose creation dates are in the opposite order from their
+	// names; the last one has a multiline message:
+	for _, args := range [][]string{
+		{"tag", "-a", "-m", "third", "v3", "HEAD"},
+		{"tag", "-a", "-m", "second", "v2", "HEAD"},
+		{"tag", "-a", "-m", "first\n\nwith a body", "v1", "HEAD"},
+	} {
+		cmd := testRepo.GitCommand(t, args...)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+	}
+
+	repo := testRepo.Repository(t)
+
+	collect := func(opts git.ForEachRefOptions) []git.Ref {
+		t.Helper()
+		var refs []git.Ref
+		require.NoError(t, repo.ForEachRefWithOptions(opts, func(ref git.Ref) error {
+			refs = append(refs, ref)
+			return nil
+		}))
+		return refs
+	}
+
+	refnames := func(refs []git.Ref) []string {
+		var names []string
+		for _, ref := range refs {
+			names = append(names, ref.Refname)
+		}
+		return names
+	}
+
+	refs := collect(git.ForEachRefOptions{})
+	assert.Equal(
+		t,
+		[]string{"refs/heads/master", "refs/tags/v1", "refs/tags/v2", "refs/tags/v3"},
+		refnames(refs),
+	)
+	assert.Nil(t, refs[0].Fields)
+	assert.Equal(t, git.ObjectType("commit"), refs[0].ObjectType)
+	assert.Equal(t, git.ObjectType("tag"), refs[1].ObjectType)
+
+	// The two most recently created tags:
+	refs = collect(git.ForEachRefOptions{
+		Sort:     "-creatordate",
+		Count:    2,
+		Patterns: []string{"refs/tags/"},
+		Fields:   []string{"creatordate:unix", "contents"},
+	})
+	require.Equal(t, []string{"refs/tags/v1", "refs/tags/v2"}, refnames(refs))
+	assert.Equal(t, "first\n\nwith a body\n", refs[0].Fields["contents"])
+	assert.Equal(t, "second\n", refs[1].Fields["contents"])
+	assert.Greater(t, refs[0].Fields["creatordate:unix"], refs[1].Fields["creatordate:unix"])
+
+	// Errors from the callback stop the iteration:
+	errStop := errors.New("stop")
+	var count int
+	err := repo.ForEachRefWithOptions(git.ForEachRefOptions{}, func(git.Ref) error {
+		count++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, count)
+
+	err = repo.ForEachRefWithOptions(
+		git.ForEachRefOptions{Fields: []string{"%(refname)"}},
+		func(git.Ref) error { return nil },
+	)
+	assert.Error(t, err)
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 // ObjectType represents the type of a Git object ("blob", "tree",
@@ -21,9 +23,54 @@ type Repository struct {
 	// directory.
 	gitDir string
 
+	// workTree is the path to the top level of the working tree, if
+	// the repository was opened via a path within one, or "" if it
+	// is bare or was opened via its `GIT_DIR`. Like `gitDir`, it
+	// might be absolute or relative to the current directory.
+	workTree string
+
 	// gitBin is the path of the `git` executable that should be used
 	// when running commands in this repository.
 	gitBin string
+
+	// useReplaceRefs is true if git should honor replace references
+	// when running our commands.
+	useReplaceRefs bool
+
+	// useBitmapIndex is true if reachability queries should use the
+	// reachability bitmap. See `SetUseBitmapIndex()`.
+	useBitmapIndex bool
+
+	// ranReachabilityQuery is set to 1 (atomically) once a
+	// reachability query has been started. See
+	// `RanReachabilityQuery()`.
+	ranReachabilityQuery int32
+
+	// submodules caches the results of `SubmoduleList()`, keyed by
+	// tree OID. Protected by `submodulesLock`.
+	submodulesLock sync.Mutex
+	submodules     map[OID][]SubmoduleConfig
+
+	// spawns counts the `git` processes that are started for this
+	// repository. See `SpawnCounts()`.
+	spawns spawnCounter
+}
+
+// RepositoryOptions holds options that affect how `git` commands are
+// run in a `Repository`.
+type RepositoryOptions struct {
+	// UseReplaceRefs controls whether the replacements recorded in
+	// `refs/replace/*` are honored. By default (false), git-sizer
+	// passes `--no-replace-objects` to every `git` command, so each
+	// object is measured as it is actually stored: a replaced object
+	// contributes its own size, and the replacement object is
+	// measured only if it is reachable in its own right (e.g., via
+	// its `refs/replace/*` reference). If this option is true, git
+	// substitutes the replacement wherever the replaced object is
+	// referenced, so the replaced object's OID is reported with the
+	// replacement's size and contents. The totals then describe the
+	// history as users see it, rather than what is stored on disk.
+	UseReplaceRefs bool
 }
 
 // smartJoin returns `relPath` if it is an absolute path. If not, it
@@ -41,6 +88,15 @@ func smartJoin(path, relPath string) string {
 // be used for running `git` commands, given the value of `GIT_DIR`
 // for the repository.
 func NewRepositoryFromGitDir(gitDir string) (*Repository, error) {
+	return NewRepositoryFromGitDirWithOptions(gitDir, RepositoryOptions{})
+}
+
+// NewRepositoryFromGitDirWithOptions is like
+// `NewRepositoryFromGitDir()`, but configures the repository
+// according to `opts`.
+func NewRepositoryFromGitDirWithOptions(
+	gitDir string, opts RepositoryOptions,
+) (*Repository, error) {
 	// Find the `git` executable to be used:
 	gitBin, err := findGitBin()
 	if err != nil {
@@ -50,8 +106,9 @@ func NewRepositoryFromGitDir(gitDir string) (*Repository, error) {
 	}
 
 	repo := Repository{
-		gitDir: gitDir,
-		gitBin: gitBin,
+		gitDir:         gitDir,
+		gitBin:         gitBin,
+		useReplaceRefs: opts.UseReplaceRefs,
 	}
 
 	full, err := repo.IsFull()
@@ -70,6 +127,14 @@ func NewRepositoryFromGitDir(gitDir string) (*Repository, error) {
 // `git` what `GIT_DIR` to use. Git, in turn, bases its decision on
 // the path and the environment.
 func NewRepositoryFromPath(path string) (*Repository, error) {
+	return NewRepositoryFromPathWithOptions(path, RepositoryOptions{})
+}
+
+// NewRepositoryFromPathWithOptions is like `NewRepositoryFromPath()`,
+// but configures the repository according to `opts`.
+func NewRepositoryFromPathWithOptions(
+	path string, opts RepositoryOptions,
+) (*Repository, error) {
 	gitBin, err := findGitBin()
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -79,7 +144,7 @@ func NewRepositoryFromPath(path string) (*Repository, error) {
 
 	//nolint:gosec // `gitBin` is chosen carefully, and `path` is the
 	// path to the repository.
-	cmd := exec.Command(gitBin, "-C", path, "rev-parse", "--git-dir")
+	cmd := exec.Command(gitBin, "-C", path, "rev-parse", "--git-dir", "--show-cdup")
 	out, err := cmd.Output()
 	if err != nil {
 		switch err := err.(type) {
@@ -95,9 +160,20 @@ func NewRepositoryFromPath(path string) (*Repository, error) {
 			return nil, err
 		}
 	}
-	gitDir := smartJoin(path, string(bytes.TrimSpace(out)))
+	// The output is the `GIT_DIR`, followed (only if `path` is within
+	// a working tree) by the relative path to the top of the working
+	// tree, which is empty if `path` is the top:
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	gitDir := smartJoin(path, lines[0])
 
-	return NewRepositoryFromGitDir(gitDir)
+	repo, err := NewRepositoryFromGitDirWithOptions(gitDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > 1 {
+		repo.workTree = filepath.Clean(smartJoin(path, lines[1]))
+	}
+	return repo, nil
 }
 
 // IsFull returns `true` iff `repo` appears to be a full clone.
@@ -121,16 +197,31 @@ func (repo *Repository) IsFull() (bool, error) {
 	return true, nil
 }
 
+// GitCommand returns an `*exec.Cmd` that runs `git` in `repo` with
+// the specified arguments. Every call is counted as a spawn (see
+// `SpawnCounts()`), but isn't subject to the spawn budget; see
+// `SetSpawnBudget()`.
 func (repo *Repository) GitCommand(callerArgs ...string) *exec.Cmd {
-	args := []string{
+	_ = repo.spawns.record(subcommandName(callerArgs), false)
+	return repo.newGitCommand(callerArgs...)
+}
+
+// newGitCommand returns an `*exec.Cmd` that runs `git` in `repo` with
+// the specified arguments, without counting it as a spawn.
+func (repo *Repository) newGitCommand(callerArgs ...string) *exec.Cmd {
+	var args []string
+	if !repo.useReplaceRefs {
 		// Disable replace references when running our commands:
-		"--no-replace-objects",
+		args = append(args, "--no-replace-objects")
+	}
 
+	args = append(
+		args,
 		// Disable the warning that grafts are deprecated, since we
 		// want to set the grafts file to `/dev/null` below (to
 		// disable grafts even where they are supported):
 		"-c", "advice.graftFileDeprecated=false",
-	}
+	)
 
 	args = append(args, callerArgs...)
 
@@ -156,9 +247,16 @@ func (repo *Repository) GitDir() string {
 
 // GitPath returns that path of a file within the git repository, by
 // calling `git rev-parse --git-path $relPath`. The returned path is
-// relative to the current directory.
+// relative to the current directory. If `repo` was opened via a
+// linked worktree, paths of shared files (e.g., under `objects/` or
+// `refs/`) are in the common directory; see `CommonDir()`. So any
+// code that looks at files in the repository must use this method
+// rather than joining paths onto `GitDir()`.
 func (repo *Repository) GitPath(relPath string) (string, error) {
-	cmd := repo.GitCommand("rev-parse", "--git-path", relPath)
+	cmd, err := repo.gitCommand("rev-parse", "--git-path", relPath)
+	if err != nil {
+		return "", err
+	}
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf(
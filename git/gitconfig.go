@@ -37,7 +37,10 @@ type Config struct {
 // `configKeyMatchesPrefix()`), and strip off the prefix in the keys
 // that are returned.
 func (repo *Repository) GetConfig(prefix string) (*Config, error) {
-	cmd := repo.GitCommand("config", "--list", "-z")
+	cmd, err := repo.gitCommand("config", "--list", "-z")
+	if err != nil {
+		return nil, err
+	}
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -117,9 +120,12 @@ func configKeyMatchesPrefix(key, prefix string) (bool, string) {
 func (repo *Repository) ConfigStringDefault(key string, defaultValue string) (string, error) {
 	// Note that `git config --get` didn't get `--default` until Git
 	// 2.18 (released 2018-06-21).
-	cmd := repo.GitCommand(
+	cmd, err := repo.gitCommand(
 		"config", "--get", key,
 	)
+	if err != nil {
+		return "", err
+	}
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -140,9 +146,12 @@ func (repo *Repository) ConfigStringDefault(key string, defaultValue string) (st
 func (repo *Repository) ConfigBoolDefault(key string, defaultValue bool) (bool, error) {
 	// Note that `git config --get` didn't get `--type=bool` or
 	// `--default` until Git 2.18 (released 2018-06-21).
-	cmd := repo.GitCommand(
+	cmd, err := repo.gitCommand(
 		"config", "--get", "--bool", key,
 	)
+	if err != nil {
+		return false, err
+	}
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -165,9 +174,12 @@ func (repo *Repository) ConfigBoolDefault(key string, defaultValue bool) (bool,
 func (repo *Repository) ConfigIntDefault(key string, defaultValue int) (int, error) {
 	// Note that `git config --get` didn't get `--type=int` or
 	// `--default` until Git 2.18 (released 2018-06-21).
-	cmd := repo.GitCommand(
+	cmd, err := repo.gitCommand(
 		"config", "--get", "--int", key,
 	)
+	if err != nil {
+		return 0, err
+	}
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"github.com/github/git-sizer/counts"
+)
+
+// HeaderStats describes anything unusual about the headers of a commit
+// or tag object. Git itself never writes such objects, but crafted or
+// buggy ones (e.g., with hundreds of duplicate "parent" lines or a
+// giant "encoding" header) can confuse other parsers.
+type HeaderStats struct {
+	// UnknownHeaderCount is the number of headers whose keys git
+	// doesn't know.
+	UnknownHeaderCount counts.Count32
+
+	// DuplicateHeaderCount is the number of headers that appear
+	// more often than git allows (for "parent", the number of
+	// repeated parent OIDs).
+	DuplicateHeaderCount counts.Count32
+
+	// MaxHeaderLineLength is the length of the longest header line
+	// (including continuation lines), in bytes.
+	MaxHeaderLineLength counts.Count32
+}
+
+// Unusual returns true iff there is something unusual about the
+// headers (other than their length).
+func (s HeaderStats) Unusual() bool {
+	return s.UnknownHeaderCount != 0 || s.DuplicateHeaderCount != 0
+}
+
+// headerMultiplicity says how many times git allows each known header
+// to appear. Zero means that it can appear any number of times.
+type headerMultiplicity map[string]int
+
+var (
+	commitHeaders = headerMultiplicity{
+		"tree":          1,
+		"parent":        0,
+		"author":        1,
+		"committer":     1,
+		"encoding":      1,
+		"gpgsig":        1,
+		"gpgsig-sha256": 1,
+		"mergetag":      0,
+	}
+
+	tagHeaders = headerMultiplicity{
+		"object": 1,
+		"type":   1,
+		"tag":    1,
+		"tagger": 1,
+	}
+)
+
+// headerStatsBuilder accumulates `HeaderStats` for one object.
+type headerStatsBuilder struct {
+	known   headerMultiplicity
+	seen    map[string]int
+	parents map[OID]struct{}
+	stats   HeaderStats
+}
+
+func newHeaderStatsBuilder(known headerMultiplicity) *headerStatsBuilder {
+	return &headerStatsBuilder{
+		known: known,
+		seen:  make(map[string]int),
+	}
+}
+
+// add records the header with the specified `key` and `value`. A
+// continuation line has an empty key.
+func (b *headerStatsBuilder) add(key, value string) {
+	// The length of the line, including the key and the space but
+	// not the LF:
+	b.stats.MaxHeaderLineLength.AdjustMaxIfNecessary(
+		counts.NewCount32(uint64(len(key) + 1 + len(value))),
+	)
+
+	if key == "" {
+		return
+	}
+
+	max, ok := b.known[key]
+	if !ok {
+		b.stats.UnknownHeaderCount.Increment(1)
+		return
+	}
+
+	b.seen[key]++
+	if max != 0 && b.seen[key] > max {
+		b.stats.DuplicateHeaderCount.Increment(1)
+	}
+}
+
+// addParent records a parent OID, counting it as a duplicate header if
+// it has been seen before in this commit.
+func (b *headerStatsBuilder) addParent(parent OID) {
+	if b.parents == nil {
+		b.parents = make(map[OID]struct{})
+	}
+	if _, ok := b.parents[parent]; ok {
+		b.stats.DuplicateHeaderCount.Increment(1)
+		return
+	}
+	b.parents[parent] = struct{}{}
+}
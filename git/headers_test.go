@@ -0,0 +1,126 @@
+package git_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+func TestParseCommitHeaders(t *testing.T) {
+	t.Parallel()
+
+	oid, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+
+	const (
+		tree    = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+		parent1 = "1111111111111111111111111111111111111111"
+		parent2 = "2222222222222222222222222222222222222222"
+	)
+
+	for _, p := range []struct {
+		name     string
+		data     string
+		parents  int
+		expected git.HeaderStats
+	}{
+		{
+			name: "normal",
+			data: "tree " + tree + "\n" +
+				"parent " + parent1 + "\n" +
+				"parent " + parent2 + "\n" +
+				"author A U Thor <author@example.com> 1112911993 -0700\n" +
+				"committer C O Mitter <committer@example.com> 1112911993 -0700\n" +
+				"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+				" \n" +
+				" -----END PGP SIGNATURE-----\n" +
+				"\n" +
+				"message\n",
+			parents: 2,
+			expected: git.HeaderStats{
+				MaxHeaderLineLength: 61,
+			},
+		},
+		{
+			name: "unusual",
+			data: "tree " + tree + "\n" +
+				"tree " + tree + "\n" +
+				"parent " + parent1 + "\n" +
+				"parent " + parent1 + "\n" +
+				"parent " + parent1 + "\n" +
+				"author A U Thor <author@example.com> 1112911993 -0700\n" +
+				"committer C O Mitter <committer@example.com> 1112911993 -0700\n" +
+				"encoding " + strings.Repeat("x", 1000) + "\n" +
+				"x-custom foo\n" +
+				"\n" +
+				"message\n",
+			parents: 3,
+			expected: git.HeaderStats{
+				UnknownHeaderCount:   1,
+				DuplicateHeaderCount: 3,
+				MaxHeaderLineLength:  1009,
+			},
+		},
+	} {
+		p := p
+		t.Run(p.name, func(t *testing.T) {
+			t.Parallel()
+
+			commit, err := git.ParseCommit(oid, []byte(p.data))
+			require.NoError(t, err)
+			assert.Equal(t, tree, commit.Tree.String())
+			assert.Len(t, commit.Parents, p.parents)
+			assert.Equal(t, "author@example.com", commit.AuthorEmail)
+			assert.Equal(t, "committer@example.com", commit.CommitterEmail)
+			assert.Equal(t, int64(1112911993), commit.AuthorTime)
+			assert.Equal(t, int64(1112911993), commit.CommitterTime)
+			assert.Equal(t, p.expected, commit.Headers)
+		})
+	}
+}
+
+func TestParseCommitTimes(t *testing.T) {
+	t.Parallel()
+
+	oid, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+
+	commit, err := git.ParseCommit(oid, []byte(
+		"tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n"+
+			"author A U Thor <author@example.com> 4102444800 +0000\n"+
+			"committer C O Mitter <committer@example.com> yesterday\n"+
+			"\n"+
+			"message\n",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4102444800), commit.AuthorTime)
+	assert.Equal(t, int64(-1), commit.CommitterTime)
+}
+
+func TestParseTagHeaders(t *testing.T) {
+	t.Parallel()
+
+	oid, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+
+	data := "object 1111111111111111111111111111111111111111\n" +
+		"type commit\n" +
+		"type tree\n" +
+		"tag v1\n" +
+		"tagger T A Gger <tagger@example.com> 1112911993 -0700\n" +
+		"unknown value\n" +
+		"\n" +
+		"message\n"
+
+	tag, err := git.ParseTag(oid, []byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, git.ObjectType("commit"), tag.ReferentType)
+	assert.Equal(t, counts.Count32(1), tag.Headers.UnknownHeaderCount)
+	assert.Equal(t, counts.Count32(1), tag.Headers.DuplicateHeaderCount)
+	assert.True(t, tag.Headers.Unusual())
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// ErrBareRepository is returned by methods that need a working tree if
+// the repository is bare.
+var ErrBareRepository = errors.New("repository is bare")
+
+// WorkTree returns the path to the top level of `repo`'s working tree,
+// or "" if it is bare or was opened via its `GIT_DIR`. It might be
+// absolute or it might be relative to the current directory.
+func (repo *Repository) WorkTree() string {
+	return repo.workTree
+}
+
+// LsFilesIter is an iterator over the paths output by `git ls-files`.
+type LsFilesIter struct {
+	pathCh chan string
+	errCh  chan error
+}
+
+// ListIgnoredFiles returns an iterator over the untracked files in
+// `repo`'s working tree that are ignored by `.gitignore`,
+// `.git/info/exclude`, or `core.excludesFile` (i.e., the output of
+// `git ls-files --others --ignored --exclude-standard`). The paths
+// are relative to the top level of the working tree. It returns
+// `ErrBareRepository` if there is no working tree. To stop iterating
+// early, cancel `ctx`.
+func (repo *Repository) ListIgnoredFiles(ctx context.Context) (*LsFilesIter, error) {
+	if repo.workTree == "" {
+		bare, err := repo.IsBare()
+		if err != nil {
+			return nil, err
+		}
+		if bare {
+			return nil, ErrBareRepository
+		}
+		return nil, errors.New("the repository was opened without its working tree")
+	}
+
+	iter := LsFilesIter{
+		pathCh: make(chan string),
+		errCh:  make(chan error, 1),
+	}
+
+	// The command has to run at the top of the working tree, so
+	// make sure that the paths that it is given aren't relative to
+	// the current directory. (Later settings in `cmd.Env` win.)
+	gitDir, err := filepath.Abs(repo.gitDir)
+	if err != nil {
+		return nil, err
+	}
+	workTree, err := filepath.Abs(repo.workTree)
+	if err != nil {
+		return nil, err
+	}
+	cmd := repo.GitCommand("ls-files", "-z", "--others", "--ignored", "--exclude-standard")
+	cmd.Dir = workTree
+	cmd.Env = append(cmd.Env, "GIT_DIR="+gitDir, "GIT_WORK_TREE="+workTree)
+
+	p := pipe.New()
+	p.Add(
+		pipe.CommandStage("git-ls-files", cmd),
+		pipe.Function(
+			"parse-paths",
+			func(ctx context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				defer close(iter.pathCh)
+
+				in := bufio.NewReader(stdin)
+				for {
+					path, err := in.ReadString(0)
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading 'git ls-files' output: %w", err)
+					}
+
+					select {
+					case iter.pathCh <- path[:len(path)-1]:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			},
+		),
+	)
+
+	if err := p.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		iter.errCh <- p.Wait()
+	}()
+
+	return &iter, nil
+}
+
+// Next returns either the next path or a boolean `false` value
+// indicating that the iteration is over. On errors, return an error.
+func (iter *LsFilesIter) Next() (string, bool, error) {
+	path, ok := <-iter.pathCh
+	if !ok {
+		return "", false, <-iter.errCh
+	}
+
+	return path, true, nil
+}
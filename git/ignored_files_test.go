@@ -0,0 +1,59 @@
+package git_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestListIgnoredFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "ignored-files")
+	defer testRepo.Remove(t)
+
+	testRepo.AddFile(t, ".gitignore", "*.log\nbuild/\n")
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit", "-m", "ignore some files")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	for _, name := range []string{"debug.log", "build/out.bin", "build/sub/x.o", "notes.txt"} {
+		path := filepath.Join(testRepo.Path, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+	}
+
+	repo := testRepo.Repository(t)
+	iter, err := repo.ListIgnoredFiles(ctx)
+	require.NoError(t, err)
+
+	var paths []string
+	for {
+		path, ok, err := iter.Next()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{"build/out.bin", "build/sub/x.o", "debug.log"}, paths)
+
+	bareRepo := testutils.NewTestRepo(t, true, "ignored-files-bare")
+	defer bareRepo.Remove(t)
+
+	_, err = bareRepo.Repository(t).ListIgnoredFiles(ctx)
+	assert.ErrorIs(t, err, git.ErrBareRepository)
+}
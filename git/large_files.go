@@ -0,0 +1,65 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// LargeFile describes a file in a tree, as returned by
+// `FindLargeFiles()`.
+type LargeFile struct {
+	// Path is the full path of the file, relative to the root of
+	// the tree.
+	Path string
+
+	// OID is the object ID of the file's blob.
+	OID OID
+
+	// Size is the size of the blob.
+	Size counts.Count64
+
+	// Mode is the file's mode; e.g., 0o100644.
+	Mode uint
+}
+
+// FindLargeFiles returns the `topN` biggest files in the tree of
+// `oid` (a commit or tree), biggest first. Files with the same size
+// are ordered by path. Submodules are ignored. A file that appears at
+// several paths is reported once for each path.
+func (repo *Repository) FindLargeFiles(oid OID, topN int) ([]LargeFile, error) {
+	if topN < 0 {
+		return nil, fmt.Errorf("invalid number of files %d", topN)
+	}
+	if topN == 0 {
+		return nil, nil
+	}
+
+	entries, err := repo.ReadTreeRecursive(oid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Among files with the same size, the one with the lower path
+	// ranks higher:
+	top := topn.New(topN, func(f1, f2 LargeFile) bool {
+		if f1.Size != f2.Size {
+			return f1.Size < f2.Size
+		}
+		return f1.Path > f2.Path
+	})
+	for _, entry := range entries {
+		if entry.ObjectType != "blob" {
+			continue
+		}
+		top.Add(LargeFile{
+			Path: entry.Path,
+			OID:  entry.OID,
+			Size: counts.NewCount64(entry.Size),
+			Mode: entry.Filemode,
+		})
+	}
+
+	return top.Items(), nil
+}
@@ -0,0 +1,77 @@
+package git_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestFindLargeFiles(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "large-files")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "small.txt", "x\n")
+	testRepo.AddFile(t, "big.txt", strings.Repeat("x", 1000))
+	testRepo.AddFile(t, "dir/medium.txt", strings.Repeat("x", 100))
+	testRepo.AddFile(t, "dir/also-medium.txt", strings.Repeat("y", 100))
+	testRepo.AddFile(t, "dir/sub/tiny.txt", "")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	out, err := testRepo.GitCommand(t, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	head, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+
+	paths := func(files []git.LargeFile) []string {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+
+	files, err := repo.FindLargeFiles(head, 3)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{"big.txt", "dir/also-medium.txt", "dir/medium.txt"},
+		paths(files),
+	)
+	assert.Equal(t, counts.Count64(1000), files[0].Size)
+	assert.Equal(t, uint(0o100644), files[0].Mode)
+
+	// Asking for more files than there are returns all of them:
+	files, err = repo.FindLargeFiles(head, 100)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"big.txt", "dir/also-medium.txt", "dir/medium.txt",
+			"small.txt", "dir/sub/tiny.txt",
+		},
+		paths(files),
+	)
+
+	files, err = repo.FindLargeFiles(head, 0)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, err = repo.FindLargeFiles(head, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,326 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// LooseObject is an object that is stored in its own file under
+// `$GIT_DIR/objects/xx/`.
+type LooseObject struct {
+	OID OID
+
+	// DiskSize is the size of the (compressed) file holding the
+	// object.
+	DiskSize counts.Count64
+
+	// ModTime is the modification time of the file holding the
+	// object.
+	ModTime time.Time
+}
+
+// ErrObjectNotFound is returned (possibly wrapped) if an object
+// doesn't exist in the repository.
+var ErrObjectNotFound = errors.New("object not found")
+
+// LooseObjectPath returns the path at which `oid` would be stored if
+// it were a loose object. The object doesn't need to exist. The path
+// is relative to the current directory (see `GitPath()`).
+func (repo *Repository) LooseObjectPath(oid OID) (string, error) {
+	hex := oid.String()
+	return repo.GitPath(fmt.Sprintf("objects/%s/%s", hex[:2], hex[2:]))
+}
+
+// IsObjectStoredInPack returns true if `oid` exists in the repository
+// but not as a loose object (i.e., it is in a pack or an alternate),
+// and false if it is stored as a loose object. If it doesn't exist at
+// all, it returns an error wrapping `ErrObjectNotFound`.
+func (repo *Repository) IsObjectStoredInPack(oid OID) (bool, error) {
+	path, err := repo.LooseObjectPath(oid)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("checking for loose object %s: %w", oid, err)
+	}
+
+	cmd, err := repo.gitCommand("cat-file", "-e", oid.String())
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, fmt.Errorf("%w: %s", ErrObjectNotFound, oid)
+		}
+		return false, fmt.Errorf("running 'git cat-file -e %s': %w", oid, err)
+	}
+
+	return true, nil
+}
+
+// LooseObjects returns the loose objects in `repo`, by reading the
+// `objects/xx/` fan-out directories. Objects in alternates are not
+// included.
+func (repo *Repository) LooseObjects() ([]LooseObject, error) {
+	objectsDir, err := repo.GitPath("objects")
+	if err != nil {
+		return nil, err
+	}
+
+	fanouts, err := os.ReadDir(objectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading object directory: %w", err)
+	}
+
+	var objects []LooseObject
+	for _, fanout := range fanouts {
+		if !fanout.IsDir() || len(fanout.Name()) != 2 {
+			continue
+		}
+		dir := filepath.Join(objectsDir, fanout.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// It might have been pruned in the meantime.
+				continue
+			}
+			return nil, fmt.Errorf("reading object directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			oid, err := NewOID(fanout.Name() + entry.Name())
+			if err != nil {
+				// Not an object (e.g., a temporary file).
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, fmt.Errorf("reading loose object %s: %w", oid, err)
+			}
+			objects = append(objects, LooseObject{
+				OID:      oid,
+				DiskSize: counts.Count64(info.Size()),
+				ModTime:  info.ModTime(),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// Packfile describes one of a repository's packfiles.
+type Packfile struct {
+	// Path is the path to the `.pack` file.
+	Path string
+
+	// DiskSize is the size of the `.pack` file, in bytes.
+	DiskSize counts.Count64
+
+	// ModTime is the modification time of the `.pack` file.
+	ModTime time.Time
+}
+
+// Packfiles returns the packfiles in `repo`'s `objects/pack`
+// directory. Packs in alternates are not included.
+func (repo *Repository) Packfiles() ([]Packfile, error) {
+	packDir, err := repo.GitPath("objects/pack")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(packDir, "*.pack"))
+	if err != nil {
+		return nil, fmt.Errorf("listing packfiles: %w", err)
+	}
+
+	packs := make([]Packfile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// It might have been removed by a repack in
+				// the meantime.
+				continue
+			}
+			return nil, fmt.Errorf("reading packfile: %w", err)
+		}
+		packs = append(packs, Packfile{
+			Path:     path,
+			DiskSize: counts.Count64(info.Size()),
+			ModTime:  info.ModTime(),
+		})
+	}
+
+	return packs, nil
+}
+
+// PackedObjects returns the subset of `candidates` that are stored in
+// one of `repo`'s packfiles. The packs' index files are read using
+// `git show-index`, so that the objects in the packs don't have to be
+// looked up one by one (and so that loose copies don't get in the
+// way).
+func (repo *Repository) PackedObjects(candidates OIDSet) (*ExactOIDSet, error) {
+	packDir, err := repo.GitPath("objects/pack")
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := filepath.Glob(filepath.Join(packDir, "*.idx"))
+	if err != nil {
+		return nil, fmt.Errorf("listing pack indexes: %w", err)
+	}
+
+	packed := NewExactOIDSet()
+	for _, index := range indexes {
+		if err := repo.readPackIndex(index, candidates, packed); err != nil {
+			return nil, err
+		}
+	}
+
+	return packed, nil
+}
+
+// readPackIndex adds the objects from the pack index at `path` that
+// are in `candidates` to `packed`.
+func (repo *Repository) readPackIndex(path string, candidates OIDSet, packed *ExactOIDSet) error {
+	return repo.walkPackIndex(path, func(_ uint64, oid OID) error {
+		if candidates.Contains(oid) {
+			packed.Add(oid)
+		}
+		return nil
+	})
+}
+
+// walkPackIndex calls `fn` with the offset and OID of each object in
+// the pack index at `path`, in the order that they appear in the
+// index (i.e., sorted by OID). If the index doesn't exist (e.g.,
+// because the pack was removed by a concurrent repack), there is
+// nothing to walk.
+func (repo *Repository) walkPackIndex(path string, fn func(offset uint64, oid OID) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("opening pack index: %w", err)
+	}
+	defer f.Close()
+
+	cmd, err := repo.gitCommand("show-index")
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = f
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("running 'git show-index': %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("running 'git show-index': %w", err)
+	}
+	abort := func(err error) error {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+
+	// Each line looks like
+	//
+	//     <offset> <oid> (<crc32>)
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) < 2 {
+			return abort(fmt.Errorf("unexpected output from 'git show-index': %q", scanner.Text()))
+		}
+		offset, err := strconv.ParseUint(string(fields[0]), 10, 64)
+		if err != nil {
+			return abort(fmt.Errorf("parsing output of 'git show-index': %w", err))
+		}
+		oid, err := NewOID(string(fields[1]))
+		if err != nil {
+			return abort(fmt.Errorf("parsing output of 'git show-index': %w", err))
+		}
+		if err := fn(offset, oid); err != nil {
+			return abort(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return abort(fmt.Errorf("reading output of 'git show-index': %w", err))
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("running 'git show-index' for %s: %w", path, err)
+	}
+	return nil
+}
+
+// PackObject is an object stored in a packfile, as reported by
+// `PackObjects()`.
+type PackObject struct {
+	OID OID
+
+	// DiskSize is the number of bytes that the object's entry takes
+	// up in the pack (compressed, and possibly as a delta).
+	DiskSize counts.Count64
+}
+
+// PackObjects returns the objects in `pack`, with the space that each
+// one takes up in it, by reading the pack's index. The entries'
+// sizes are the distances between their offsets.
+func (repo *Repository) PackObjects(pack Packfile) ([]PackObject, error) {
+	type entry struct {
+		offset uint64
+		oid    OID
+	}
+	var entries []entry
+	err := repo.walkPackIndex(
+		strings.TrimSuffix(pack.Path, ".pack")+".idx",
+		func(offset uint64, oid OID) error {
+			entries = append(entries, entry{offset, oid})
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].offset < entries[j].offset })
+
+	// The last entry is followed by the pack's trailing checksum:
+	end := uint64(pack.DiskSize)
+	if end >= uint64(len(NullOID.v)) {
+		end -= uint64(len(NullOID.v))
+	}
+	objects := make([]PackObject, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		var size uint64
+		if entries[i].offset < end {
+			size = end - entries[i].offset
+		}
+		objects[i] = PackObject{OID: entries[i].oid, DiskSize: counts.NewCount64(size)}
+		end = entries[i].offset
+	}
+	return objects, nil
+}
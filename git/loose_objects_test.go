@@ -0,0 +1,60 @@
+package git_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestIsObjectStoredInPack(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "stored-in-pack")
+	defer testRepo.Remove(t)
+
+	createBlob := func(contents string) git.OID {
+		return testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+	}
+
+	packedOID := createBlob("packed\n")
+	testRepo.UpdateRef(t, "refs/tags/packed", packedOID)
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-a", "-d", "-q").Run())
+
+	looseOID := createBlob("loose\n")
+
+	repo := testRepo.Repository(t)
+
+	path, err := repo.LooseObjectPath(looseOID)
+	require.NoError(t, err)
+	hex := looseOID.String()
+	assert.Equal(t, filepath.Join("objects", hex[:2], hex[2:]), filepath.Join(
+		filepath.Base(filepath.Dir(filepath.Dir(path))),
+		filepath.Base(filepath.Dir(path)),
+		filepath.Base(path),
+	))
+	_, err = os.Stat(path)
+	assert.NoError(t, err, "loose object file exists")
+
+	packed, err := repo.IsObjectStoredInPack(packedOID)
+	require.NoError(t, err)
+	assert.True(t, packed)
+
+	packed, err = repo.IsObjectStoredInPack(looseOID)
+	require.NoError(t, err)
+	assert.False(t, packed)
+
+	missingOID, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+	_, err = repo.IsObjectStoredInPack(missingOID)
+	assert.ErrorIs(t, err, git.ErrObjectNotFound)
+}
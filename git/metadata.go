@@ -0,0 +1,112 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RepositoryMetadata identifies a repository and describes the state
+// of its `HEAD`, so that the results of analyzing many repositories
+// can be told apart.
+type RepositoryMetadata struct {
+	// Path is the absolute path of the top level of the working
+	// tree, or of the `GIT_DIR` if there is no working tree (or if
+	// the repository was opened via its `GIT_DIR`).
+	Path string `json:"path"`
+
+	// GitDir is the absolute path of the `GIT_DIR`.
+	GitDir string `json:"git_dir"`
+
+	// HeadOID is the object that `HEAD` points at, or `NullOID` if
+	// `HEAD` points at an unborn branch.
+	HeadOID OID `json:"head_oid"`
+
+	// HeadBranch is the full name of the branch that `HEAD` points
+	// at, or "" if `HEAD` is detached.
+	HeadBranch string `json:"head_branch,omitempty"`
+
+	// DefaultBranch is the full name of the repository's default
+	// branch: the branch that `refs/remotes/origin/HEAD` points at,
+	// if it exists, or otherwise `HeadBranch`.
+	DefaultBranch string `json:"default_branch,omitempty"`
+
+	IsShallow bool `json:"is_shallow"`
+	IsBare    bool `json:"is_bare"`
+
+	// AnalyzedAt is the time when the metadata was collected.
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
+
+// Metadata collects the `RepositoryMetadata` of `repo`.
+func (repo *Repository) Metadata() (*RepositoryMetadata, error) {
+	md := RepositoryMetadata{
+		AnalyzedAt: time.Now(),
+	}
+
+	var err error
+	md.GitDir, err = filepath.Abs(repo.gitDir)
+	if err != nil {
+		return nil, err
+	}
+	md.Path = md.GitDir
+	if repo.workTree != "" {
+		md.Path, err = filepath.Abs(repo.workTree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cmd, err := repo.gitCommand("rev-parse", "--is-bare-repository", "--is-shallow-repository")
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git rev-parse': %w", err)
+	}
+	flags := strings.Fields(string(out))
+	if len(flags) != 2 {
+		return nil, fmt.Errorf("unexpected output from 'git rev-parse': %q", out)
+	}
+	md.IsBare = flags[0] == "true"
+	md.IsShallow = flags[1] == "true"
+
+	md.HeadBranch, _, err = repo.SymbolicRef("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	// `HEAD` might point at an unborn branch:
+	cmd, err = repo.gitCommand("rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	out, err = cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("resolving HEAD: %w", err)
+		}
+	} else {
+		md.HeadOID, err = NewOID(string(bytes.TrimSpace(out)))
+		if err != nil {
+			return nil, fmt.Errorf("resolving HEAD: %w", err)
+		}
+	}
+
+	var symbolic bool
+	md.DefaultBranch, symbolic, err = repo.SymbolicRef("refs/remotes/origin/HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if !symbolic {
+		md.DefaultBranch = md.HeadBranch
+	}
+
+	return &md, nil
+}
@@ -0,0 +1,73 @@
+package git_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "metadata")
+	defer testRepo.Remove(t)
+
+	top, err := filepath.EvalSymlinks(testRepo.Path)
+	require.NoError(t, err)
+
+	// Before the first commit, `HEAD` points at an unborn branch:
+	md, err := testRepo.Repository(t).Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, git.NullOID, md.HeadOID)
+	assert.Equal(t, "refs/heads/master", md.HeadBranch)
+
+	timestamp := time.Unix(1112911993, 0)
+	testRepo.AddFile(t, "dir/a.txt", "Hello, world!\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+	require.NoError(t, testRepo.GitCommand(
+		t, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main",
+	).Run())
+
+	// Open the repository from a subdirectory of its working tree:
+	repo, err := git.NewRepositoryFromPath(filepath.Join(testRepo.Path, "dir"))
+	require.NoError(t, err)
+
+	before := time.Now()
+	md, err = repo.Metadata()
+	require.NoError(t, err)
+
+	head, err := repo.ResolveObject("HEAD")
+	require.NoError(t, err)
+
+	path, err := filepath.EvalSymlinks(md.Path)
+	require.NoError(t, err)
+	assert.Equal(t, top, path)
+	gitDir, err := filepath.EvalSymlinks(md.GitDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(top, ".git"), gitDir)
+	assert.Equal(t, head, md.HeadOID)
+	assert.Equal(t, "refs/heads/master", md.HeadBranch)
+	assert.Equal(t, "refs/remotes/origin/main", md.DefaultBranch)
+	assert.False(t, md.IsShallow)
+	assert.False(t, md.IsBare)
+	assert.False(t, md.AnalyzedAt.Before(before))
+
+	// A bare repository has no working tree:
+	bare := testRepo.Clone(t, "metadata-bare")
+	defer bare.Remove(t)
+
+	md, err = bare.Repository(t).Metadata()
+	require.NoError(t, err)
+	assert.True(t, md.IsBare)
+	assert.Equal(t, md.GitDir, md.Path)
+	assert.Equal(t, head, md.HeadOID)
+	assert.Equal(t, "refs/heads/master", md.DefaultBranch)
+}
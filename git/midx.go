@@ -0,0 +1,87 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// MultiPackIndexPacks returns the names of the pack index files
+// (e.g., "pack-1234abcd.idx") that `repo`'s multi-pack index covers,
+// by reading the index's pack-name ("PNAM") chunk, as described in
+// Git's `Documentation/technical/multi-pack-index.txt`. `ok` is false
+// if there is no multi-pack index.
+func (repo *Repository) MultiPackIndexPacks() (names []string, ok bool, err error) {
+	path, err := repo.GitPath("objects/pack/multi-pack-index")
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("reading multi-pack index: %w", err)
+	}
+
+	names, err = parseMultiPackIndexPacks(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return names, true, nil
+}
+
+// parseMultiPackIndexPacks returns the pack names listed in the
+// multi-pack index file `data`.
+func parseMultiPackIndexPacks(data []byte) ([]string, error) {
+	const headerSize = 12
+	const chunkEntrySize = 12
+
+	if len(data) < headerSize || string(data[:4]) != "MIDX" {
+		return nil, errors.New("not a multi-pack index")
+	}
+	if version := data[4]; version != 1 {
+		return nil, fmt.Errorf("unsupported multi-pack index version %d", version)
+	}
+	chunkCount := int(data[6])
+	packCount := int(binary.BigEndian.Uint32(data[8:12]))
+
+	// The chunk table has one more entry than there are chunks,
+	// whose offset marks the end of the last chunk:
+	table := data[headerSize:]
+	if len(table) < (chunkCount+1)*chunkEntrySize {
+		return nil, errors.New("truncated chunk table")
+	}
+	for i := 0; i < chunkCount; i++ {
+		entry := table[i*chunkEntrySize : (i+2)*chunkEntrySize]
+		if string(entry[:4]) != "PNAM" {
+			continue
+		}
+		start := binary.BigEndian.Uint64(entry[4:12])
+		end := binary.BigEndian.Uint64(entry[16:24])
+		if start > end || end > uint64(len(data)) {
+			return nil, errors.New("invalid PNAM chunk offsets")
+		}
+
+		// The names are NUL-terminated, and the chunk may be
+		// padded with additional NULs:
+		chunk := bytes.TrimRight(data[start:end], "\x00")
+		names := make([]string, 0, packCount)
+		if len(chunk) != 0 {
+			for _, name := range bytes.Split(chunk, []byte{0}) {
+				names = append(names, string(name))
+			}
+		}
+		if len(names) != packCount {
+			return nil, fmt.Errorf(
+				"PNAM chunk lists %d packs instead of %d", len(names), packCount,
+			)
+		}
+		return names, nil
+	}
+
+	return nil, errors.New("no PNAM chunk")
+}
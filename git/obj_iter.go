@@ -11,7 +11,11 @@ import (
 
 // ObjectIter iterates over objects in a Git repository.
 type ObjectIter struct {
-	ctx      context.Context
+	ctx context.Context
+
+	// cancel cancels `ctx`, which kills the subprocesses.
+	cancel context.CancelFunc
+
 	p        *pipe.Pipeline
 	oidCh    chan OID
 	errCh    chan error
@@ -23,8 +27,10 @@ type ObjectIter struct {
 // second return value is the stdin of the `rev-list` command. The
 // caller can feed values into it but must close it in any case.
 func (repo *Repository) NewObjectIter(ctx context.Context) (*ObjectIter, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	iter := ObjectIter{
 		ctx:      ctx,
+		cancel:   cancel,
 		p:        pipe.New(),
 		oidCh:    make(chan OID),
 		errCh:    make(chan error),
@@ -116,6 +122,7 @@ func (repo *Repository) NewObjectIter(ctx context.Context) (*ObjectIter, error)
 	)
 
 	if err := iter.p.Start(ctx); err != nil {
+		cancel()
 		return nil, err
 	}
 
@@ -132,17 +139,46 @@ func (iter *ObjectIter) AddRoot(oid OID) error {
 	}
 }
 
-// Close closes the iterator and frees up resources.
+// Close closes the iterator and frees up resources. Either Close or
+// `CloseContext()` must be called exactly once.
 func (iter *ObjectIter) Close() {
 	close(iter.oidCh)
 }
 
+// CloseContext closes the iterator like `Close()`, and then waits for
+// the `git` subprocesses to exit, discarding any objects that haven't
+// been read yet. If `ctx` is done before then, the processes are
+// killed, and an error wrapping `ErrSubprocessKilled` is returned.
+// See `BatchObjectIter.CloseContext()`.
+func (iter *ObjectIter) CloseContext(ctx context.Context) error {
+	iter.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		for range iter.headerCh {
+		}
+		done <- iter.p.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		iter.cancel()
+		return err
+	case <-ctx.Done():
+		iter.cancel()
+		<-done
+		return fmt.Errorf("closing 'git rev-list': %w (%v)", ErrSubprocessKilled, ctx.Err())
+	}
+}
+
 // Next returns either the next object (its OID, type, and size), or a
 // `false` boolean value to indicate that there are no data left.
 func (iter *ObjectIter) Next() (BatchHeader, bool, error) {
 	header, ok := <-iter.headerCh
 	if !ok {
-		return missingHeader, false, iter.p.Wait()
+		err := iter.p.Wait()
+		iter.cancel()
+		return missingHeader, false, err
 	}
 	return header, true, nil
 }
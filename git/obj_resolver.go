@@ -6,7 +6,10 @@ import (
 )
 
 func (repo *Repository) ResolveObject(name string) (OID, error) {
-	cmd := repo.GitCommand("rev-parse", "--verify", "--end-of-options", name)
+	cmd, err := repo.gitCommand("rev-parse", "--verify", "--end-of-options", name)
+	if err != nil {
+		return NullOID, err
+	}
 	output, err := cmd.Output()
 	if err != nil {
 		return NullOID, fmt.Errorf("resolving object %q: %w", name, err)
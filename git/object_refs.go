@@ -0,0 +1,342 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// MapObjectsToRefs returns a map from each of `oids` to the sorted
+// names of those of `refs` from which it is reachable; i.e., it
+// answers "which branches and tags contain this blob?". OIDs that
+// aren't reachable from any of `refs` are omitted.
+//
+// The history reachable from all of `refs` is read in a single `git
+// log --raw` pass, which finds the commits that introduce each of
+// `oids`. The sets of targets are then propagated from parents to
+// children, giving an inverse index from each commit to the targets
+// reachable from it, which is looked up for each reference. References
+// that don't peel to a commit (e.g., tags of trees) are walked
+// separately. An annotated tag is only found if it is the target of
+// one of `refs` itself, not if it is nested inside another tag.
+func (repo *Repository) MapObjectsToRefs(
+	ctx context.Context, oids []OID, refs []Reference,
+) (map[OID][]string, error) {
+	result := make(map[OID][]string)
+	if len(oids) == 0 || len(refs) == 0 {
+		return result, nil
+	}
+
+	targets := make(map[OID]int, len(oids))
+	var targetOIDs []OID
+	for _, oid := range oids {
+		if _, ok := targets[oid]; !ok {
+			targets[oid] = len(targetOIDs)
+			targetOIDs = append(targetOIDs, oid)
+		}
+	}
+
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.OID.String()
+	}
+	peeled, err := repo.PeeledCommits(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var tips []OID
+	seenTips := make(map[OID]struct{}, len(peeled))
+	for _, commit := range peeled {
+		if _, ok := seenTips[commit]; !ok {
+			seenTips[commit] = struct{}{}
+			tips = append(tips, commit)
+		}
+	}
+	sort.Slice(tips, func(i, j int) bool {
+		return bytes.Compare(tips[i].Bytes(), tips[j].Bytes()) < 0
+	})
+
+	reachable, err := repo.reachableTargetSets(ctx, tips, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(oid OID, refname string) {
+		for _, r := range result[oid] {
+			if r == refname {
+				return
+			}
+		}
+		result[oid] = append(result[oid], refname)
+	}
+
+	for i, ref := range refs {
+		if _, ok := targets[ref.OID]; ok {
+			add(ref.OID, ref.Refname)
+		}
+
+		commit, ok := peeled[names[i]]
+		if !ok {
+			found, err := repo.reachableTargets(ctx, ref.OID, targets)
+			if err != nil {
+				return nil, err
+			}
+			for _, oid := range found {
+				add(oid, ref.Refname)
+			}
+			continue
+		}
+
+		reachable[commit].forEach(func(i int) {
+			add(targetOIDs[i], ref.Refname)
+		})
+	}
+
+	for _, refnames := range result {
+		sort.Strings(refnames)
+	}
+
+	return result, nil
+}
+
+// targetSet is a set of targets of `MapObjectsToRefs()`, as a bit
+// set indexed by their positions in the list of targets. Sets are
+// shared between commits whenever possible, so they mustn't be
+// modified once they've been stored.
+type targetSet []uint64
+
+// with returns a set containing the members of `s` and target `i`.
+// If `i` is already a member, it returns `s` itself.
+func (s targetSet) with(i, n int) targetSet {
+	if s != nil && s[i/64]&(1<<(i%64)) != 0 {
+		return s
+	}
+	s2 := make(targetSet, (n+63)/64)
+	copy(s2, s)
+	s2[i/64] |= 1 << (i % 64)
+	return s2
+}
+
+// union returns a set containing the members of `s` and `s2`. If one
+// of them is a superset of the other, it is returned itself.
+func (s targetSet) union(s2 targetSet) targetSet {
+	switch {
+	case s2.subsetOf(s):
+		return s
+	case s.subsetOf(s2):
+		return s2
+	}
+	u := make(targetSet, len(s))
+	for i := range u {
+		u[i] = s[i] | s2[i]
+	}
+	return u
+}
+
+// subsetOf returns true iff every member of `s` is also a member of
+// `s2`.
+func (s targetSet) subsetOf(s2 targetSet) bool {
+	if s == nil {
+		return true
+	}
+	if s2 == nil {
+		return false
+	}
+	for i := range s {
+		if s[i]&^s2[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// forEach calls `fn` with the index of each member of `s`.
+func (s targetSet) forEach(fn func(i int)) {
+	for w, word := range s {
+		for b := 0; b < 64; b++ {
+			if word&(1<<b) != 0 {
+				fn(64*w + b)
+			}
+		}
+	}
+}
+
+// reachableTargetSets returns a map from each commit that is
+// reachable from `tips` to the set of `targets` (indexed by the
+// values of `targets`) that are reachable from it. Commits from which
+// no targets are reachable are omitted. The history is read
+// parents first, along with the diff of each commit against each of
+// its parents (or, for root commits, against the empty tree). Every
+// commit that contains a target either has a parent that contains it
+// too, or introduces it in one of those diffs, so the targets reachable
+// from a commit are the commit itself and its tree, those that it
+// introduces, and those that are reachable from its parents.
+func (repo *Repository) reachableTargetSets(
+	ctx context.Context, tips []OID, targets map[OID]int,
+) (map[OID]targetSet, error) {
+	reachable := make(map[OID]targetSet)
+	if len(tips) == 0 {
+		return reachable, nil
+	}
+
+	cmd, err := repo.gitCommand(
+		"log", "--stdin", "--topo-order", "--reverse",
+		"--format=%x00%H %T %P", "--raw", "-t", "--root", "-m",
+		"--no-abbrev", "--no-renames", "--no-color", "--no-show-signature",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The commit whose diffs are currently being read. A merge
+	// commit's header is repeated before its diff against each
+	// parent.
+	var current OID
+	var set targetSet
+
+	addTarget := func(oid OID) {
+		if i, ok := targets[oid]; ok {
+			set = set.with(i, len(targets))
+		}
+	}
+
+	p := pipe.New(pipe.WithStdin(revListInput(tips, nil)))
+	p.Add(
+		pipe.CommandStage("git-log", cmd),
+		pipe.LinewiseFunction(
+			"find-targets",
+			func(_ context.Context, _ pipe.Env, line []byte, _ *bufio.Writer) error {
+				switch {
+				case len(line) == 0:
+					return nil
+
+				case line[0] == 0:
+					// A commit header: "<commit> <tree> <parent>...".
+					fields := strings.Fields(string(line[1:]))
+					if len(fields) < 2 {
+						return fmt.Errorf("malformed 'git log' header %q", line[1:])
+					}
+					oid, err := NewOID(fields[0])
+					if err != nil {
+						return fmt.Errorf("parsing 'git log' output: %w", err)
+					}
+					if oid == current {
+						return nil
+					}
+					if set != nil {
+						reachable[current] = set
+					}
+					current = oid
+					set = nil
+
+					addTarget(oid)
+					tree, err := NewOID(fields[1])
+					if err != nil {
+						return fmt.Errorf("parsing 'git log' output: %w", err)
+					}
+					addTarget(tree)
+					for _, field := range fields[2:] {
+						parent, err := NewOID(field)
+						if err != nil {
+							return fmt.Errorf("parsing 'git log' output: %w", err)
+						}
+						// Parents that weren't read (e.g., in a
+						// shallow clone) contribute nothing:
+						set = set.union(reachable[parent])
+					}
+					return nil
+
+				case line[0] == ':':
+					// A diff entry: ":<mode> <mode> <oid> <oid>
+					// <status>\t<path>". Only the new object can be
+					// introduced by this commit. Submodule commits
+					// aren't reachable objects, so skip those.
+					tab := bytes.IndexByte(line, '\t')
+					if tab == -1 {
+						return fmt.Errorf("malformed 'git log' diff entry %q", line)
+					}
+					fields := strings.Fields(string(line[1:tab]))
+					if len(fields) < 5 {
+						return fmt.Errorf("malformed 'git log' diff entry %q", line)
+					}
+					if fields[1] == "160000" {
+						return nil
+					}
+					oid, err := NewOID(fields[3])
+					if err != nil {
+						return fmt.Errorf("parsing 'git log' output: %w", err)
+					}
+					addTarget(oid)
+					return nil
+
+				default:
+					return fmt.Errorf("unexpected 'git log' output %q", line)
+				}
+			},
+		),
+	)
+
+	if err := p.Run(ctx); err != nil {
+		return nil, fmt.Errorf("reading the history for targets: %w", err)
+	}
+	if set != nil {
+		reachable[current] = set
+	}
+
+	return reachable, nil
+}
+
+// reachableTargets returns those of `targets` that are reachable from
+// `tip`. The walk is abandoned as soon as all of them have been seen.
+func (repo *Repository) reachableTargets(
+	ctx context.Context, tip OID, targets map[OID]int,
+) ([]OID, error) {
+	var found []OID
+	seen := make(map[OID]struct{}, len(targets))
+
+	p := pipe.New(pipe.WithStdin(revListInput([]OID{tip}, nil)))
+	p.Add(
+		pipe.CommandStage(
+			"git-rev-list",
+			repo.GitCommand(repo.revListArgs("rev-list", "--objects", "--stdin")...),
+		),
+		pipe.LinewiseFunction(
+			"find-targets",
+			func(_ context.Context, _ pipe.Env, line []byte, _ *bufio.Writer) error {
+				// Strip off the path that `git rev-list --objects`
+				// emits:
+				if i := bytes.IndexByte(line, ' '); i != -1 {
+					line = line[:i]
+				}
+				oid, err := NewOID(string(line))
+				if err != nil {
+					return fmt.Errorf("parsing 'git rev-list' output: %w", err)
+				}
+				if _, ok := targets[oid]; !ok {
+					return nil
+				}
+				if _, ok := seen[oid]; ok {
+					return nil
+				}
+				seen[oid] = struct{}{}
+				found = append(found, oid)
+				if len(seen) == len(targets) {
+					return pipe.FinishEarly
+				}
+				return nil
+			},
+		),
+	)
+
+	if err := p.Run(ctx); err != nil {
+		return nil, fmt.Errorf("walking the objects reachable from %s: %w", tip, err)
+	}
+
+	return found, nil
+}
@@ -0,0 +1,119 @@
+package git_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestMapObjectsToRefs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "object-refs")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	revParse := func(name string) git.OID {
+		t.Helper()
+		out, err := testRepo.GitCommand(t, "rev-parse", "--verify", name).Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	testRepo.AddFile(t, "common.txt", "in every branch\n")
+	commit("initial")
+	require.NoError(t, testRepo.GitCommand(t, "tag", "v1").Run())
+
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "-b", "feature").Run())
+	testRepo.AddFile(t, "big.bin", strings.Repeat("x", 10000))
+	commit("add a big file")
+	big := revParse("HEAD:big.bin")
+	featureTree := revParse("HEAD^{tree}")
+
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "master").Run())
+	testRepo.AddFile(t, "master.txt", "only on master\n")
+	commit("master only")
+
+	common := revParse("master:common.txt")
+	masterOnly := revParse("master:master.txt")
+
+	// A merge that introduces a file of its own:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "-b", "merged").Run())
+	cmd := testRepo.GitCommand(t, "merge", "-q", "--no-ff", "--no-commit", "feature")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "merging")
+	testRepo.AddFile(t, "evil.txt", "only in the merge\n")
+	commit("merge feature")
+	evil := revParse("merged:evil.txt")
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "master").Run())
+	unreachable := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "not reachable from any reference\n")
+		return err
+	})
+
+	refs := []git.Reference{
+		{Refname: "refs/heads/master", OID: revParse("master")},
+		{Refname: "refs/heads/feature", OID: revParse("feature")},
+		{Refname: "refs/heads/feature-copy", OID: revParse("feature")},
+		{Refname: "refs/tags/v1", OID: revParse("v1")},
+		{Refname: "refs/heads/merged", OID: revParse("merged")},
+		// A reference to a tree is walked separately:
+		{Refname: "refs/tags/tree", OID: featureTree},
+	}
+
+	repo := testRepo.Repository(t)
+
+	m, err := repo.MapObjectsToRefs(
+		ctx, []git.OID{common, big, masterOnly, featureTree, evil, unreachable}, refs,
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[git.OID][]string{
+			common: {
+				"refs/heads/feature", "refs/heads/feature-copy",
+				"refs/heads/master", "refs/heads/merged",
+				"refs/tags/tree", "refs/tags/v1",
+			},
+			big: {
+				"refs/heads/feature", "refs/heads/feature-copy",
+				"refs/heads/merged", "refs/tags/tree",
+			},
+			featureTree: {
+				"refs/heads/feature", "refs/heads/feature-copy",
+				"refs/heads/merged", "refs/tags/tree",
+			},
+			masterOnly: {"refs/heads/master", "refs/heads/merged"},
+			evil:       {"refs/heads/merged"},
+		},
+		m,
+	)
+
+	m, err = repo.MapObjectsToRefs(ctx, []git.OID{common}, refs)
+	require.NoError(t, err)
+	assert.Len(t, m[common], 6)
+
+	m, err = repo.MapObjectsToRefs(ctx, nil, refs)
+	require.NoError(t, err)
+	assert.Empty(t, m)
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// ObjectSizes returns the sizes of the objects named by `oids`, as
+// reported by `git cat-file --batch-check`. It is an error if any of
+// them is missing.
+func (repo *Repository) ObjectSizes(oids []OID) (map[OID]counts.Count64, error) {
+	sizes := make(map[OID]counts.Count64, len(oids))
+	if len(oids) == 0 {
+		return sizes, nil
+	}
+
+	var input bytes.Buffer
+	for _, oid := range oids {
+		fmt.Fprintln(&input, oid.String())
+	}
+
+	cmd, err := repo.gitCommand("cat-file", "--batch-check=%(objectname) %(objectsize)")
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = &input
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git cat-file --batch-check': %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		words := strings.Fields(line)
+		if len(words) != 2 || words[1] == "missing" {
+			return nil, fmt.Errorf("unexpected output from 'git cat-file': %q", line)
+		}
+		oid, err := NewOID(words[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git cat-file' output: %w", err)
+		}
+		n, err := strconv.ParseUint(words[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git cat-file' output: %w", err)
+		}
+		sizes[oid] = counts.NewCount64(n)
+	}
+
+	return sizes, nil
+}
@@ -14,6 +14,23 @@ type OID struct {
 // NullOID is the null object ID; i.e., all zeros.
 var NullOID OID
 
+// EmptyBlobOID and EmptyTreeOID are the well-known names of the empty
+// blob and the empty tree. Since `OID` only supports SHA-1, these are
+// the SHA-1 names. (In a SHA-256 repository, they would be different,
+// as would everything else.)
+var (
+	EmptyBlobOID = mustNewOID("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391")
+	EmptyTreeOID = mustNewOID("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+)
+
+func mustNewOID(s string) OID {
+	oid, err := NewOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
 // OIDFromBytes converts a byte slice containing an object ID in
 // binary format into an `OID`.
 func OIDFromBytes(oidBytes []byte) (OID, error) {
@@ -0,0 +1,198 @@
+package git
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// OIDSet is a set of object IDs, used (for example) to remember which
+// objects have already been seen during a traversal.
+type OIDSet interface {
+	// Add adds `oid` to the set. It returns true iff `oid` was not
+	// (as far as the set can tell) already present.
+	Add(oid OID) bool
+
+	// Contains returns true iff `oid` is (as far as the set can
+	// tell) present in the set.
+	Contains(oid OID) bool
+}
+
+// ExactOIDSet is an `OIDSet` backed by a Go map. It is always
+// correct, but its memory usage is proportional to the number of
+// OIDs that it contains.
+type ExactOIDSet struct {
+	oids map[OID]struct{}
+}
+
+// NewExactOIDSet returns a new, empty `ExactOIDSet`.
+func NewExactOIDSet() *ExactOIDSet {
+	return &ExactOIDSet{
+		oids: make(map[OID]struct{}),
+	}
+}
+
+func (set *ExactOIDSet) Add(oid OID) bool {
+	if _, ok := set.oids[oid]; ok {
+		return false
+	}
+	set.oids[oid] = struct{}{}
+	return true
+}
+
+func (set *ExactOIDSet) Contains(oid OID) bool {
+	_, ok := set.oids[oid]
+	return ok
+}
+
+// Len returns the number of OIDs in the set.
+func (set *ExactOIDSet) Len() int {
+	return len(set.oids)
+}
+
+// BloomOIDSet is an approximate `OIDSet` backed by a Bloom filter. Its
+// memory usage is fixed when it is created, and is much smaller than
+// that of an `ExactOIDSet` holding the same number of OIDs. The price
+// is that `Contains()` sometimes returns true for an OID that was
+// never added (a "false positive"), and `Add()` correspondingly
+// sometimes returns false for an OID that is new. It never gives a
+// false negative.
+//
+// When such a set is used to avoid processing objects twice, a false
+// positive causes an object to be wrongly treated as already seen, so
+// totals computed with it are lower bounds that undercount by roughly
+// the false-positive rate.
+type BloomOIDSet struct {
+	bits      []uint64
+	bitCount  uint64
+	hashCount int
+}
+
+// NewBloomOIDSet returns a new, empty `BloomOIDSet` sized so that
+// after `expectedCount` OIDs have been added, the probability of a
+// false positive is approximately `falsePositiveRate`.
+func NewBloomOIDSet(expectedCount uint64, falsePositiveRate float64) *BloomOIDSet {
+	if expectedCount == 0 {
+		expectedCount = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+
+	// The optimal number of bits and hash functions for the
+	// requested parameters:
+	m := math.Ceil(
+		-float64(expectedCount) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2),
+	)
+	bitCount := uint64(m)
+	if bitCount < 64 {
+		bitCount = 64
+	}
+	hashCount := int(math.Round(m / float64(expectedCount) * math.Ln2))
+	if hashCount < 1 {
+		hashCount = 1
+	}
+
+	return &BloomOIDSet{
+		bits:      make([]uint64, (bitCount+63)/64),
+		bitCount:  bitCount,
+		hashCount: hashCount,
+	}
+}
+
+// NewBloomOIDSetWithSize returns a new, empty `BloomOIDSet` with
+// exactly `bitCount` bits (at least 64) and `hashCount` hash
+// functions. It is useful for recreating a filter whose parameters
+// were recorded earlier.
+func NewBloomOIDSetWithSize(bitCount uint64, hashCount int) *BloomOIDSet {
+	if bitCount < 64 {
+		bitCount = 64
+	}
+	if hashCount < 1 {
+		hashCount = 1
+	}
+	return &BloomOIDSet{
+		bits:      make([]uint64, (bitCount+63)/64),
+		bitCount:  bitCount,
+		hashCount: hashCount,
+	}
+}
+
+// positions calls `fn` with each of the bit positions that correspond
+// to `oid`. OIDs are cryptographic hashes, so their bytes can be used
+// directly as hash values; the different hash functions are derived
+// from two of them (see Kirsch and Mitzenmacher, "Less Hashing, Same
+// Performance").
+func (set *BloomOIDSet) positions(oid OID, fn func(uint64)) {
+	h1 := binary.LittleEndian.Uint64(oid.v[0:8])
+	h2 := binary.LittleEndian.Uint64(oid.v[8:16]) | 1
+	for i := 0; i < set.hashCount; i++ {
+		fn((h1 + uint64(i)*h2) % set.bitCount)
+	}
+}
+
+func (set *BloomOIDSet) Add(oid OID) bool {
+	added := false
+	set.positions(oid, func(pos uint64) {
+		word, mask := pos/64, uint64(1)<<(pos%64)
+		if set.bits[word]&mask == 0 {
+			set.bits[word] |= mask
+			added = true
+		}
+	})
+	return added
+}
+
+func (set *BloomOIDSet) Contains(oid OID) bool {
+	present := true
+	set.positions(oid, func(pos uint64) {
+		if set.bits[pos/64]&(uint64(1)<<(pos%64)) == 0 {
+			present = false
+		}
+	})
+	return present
+}
+
+// HashCount returns the number of hash functions used by the filter.
+func (set *BloomOIDSet) HashCount() int {
+	return set.hashCount
+}
+
+// BitCount returns the number of bits in the filter.
+func (set *BloomOIDSet) BitCount() uint64 {
+	return set.bitCount
+}
+
+// FalsePositiveRate estimates the probability that `Contains()`
+// returns true for an OID that wasn't added, given that `count` OIDs
+// have been added.
+func (set *BloomOIDSet) FalsePositiveRate(count uint64) float64 {
+	k := float64(set.hashCount)
+	return math.Pow(1-math.Exp(-k*float64(count)/float64(set.bitCount)), k)
+}
+
+// MarshalBinary returns the bits of the filter. Together with
+// `BitCount()` and `HashCount()`, they are enough to recreate it
+// using `NewBloomOIDSetWithSize()` and `UnmarshalBinary()`.
+func (set *BloomOIDSet) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 8*len(set.bits))
+	for i, word := range set.bits {
+		binary.LittleEndian.PutUint64(data[8*i:], word)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces the bits of the filter with `data`, which
+// must have been produced by `MarshalBinary()` for a filter of the
+// same size.
+func (set *BloomOIDSet) UnmarshalBinary(data []byte) error {
+	if len(data) != 8*len(set.bits) {
+		return fmt.Errorf(
+			"Bloom filter data has %d bytes; expected %d", len(data), 8*len(set.bits),
+		)
+	}
+	for i := range set.bits {
+		set.bits[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+	return nil
+}
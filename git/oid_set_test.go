@@ -0,0 +1,90 @@
+package git_test
+
+import (
+	"crypto/sha1" //nolint:gosec // Used only to generate test OIDs.
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+)
+
+func testOID(t *testing.T, i uint64) git.OID {
+	t.Helper()
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	sum := sha1.Sum(buf[:]) //nolint:gosec // Used only to generate test OIDs.
+	oid, err := git.OIDFromBytes(sum[:])
+	require.NoError(t, err)
+	return oid
+}
+
+func TestExactOIDSet(t *testing.T) {
+	t.Parallel()
+
+	set := git.NewExactOIDSet()
+	assert.False(t, set.Contains(testOID(t, 1)))
+	assert.True(t, set.Add(testOID(t, 1)))
+	assert.False(t, set.Add(testOID(t, 1)))
+	assert.True(t, set.Contains(testOID(t, 1)))
+	assert.False(t, set.Contains(testOID(t, 2)))
+	assert.Equal(t, 1, set.Len())
+}
+
+func TestBloomOIDSet(t *testing.T) {
+	t.Parallel()
+
+	const n = 10000
+	set := git.NewBloomOIDSet(n, 0.01)
+
+	for i := uint64(0); i < n; i++ {
+		set.Add(testOID(t, i))
+	}
+
+	// There must never be false negatives:
+	for i := uint64(0); i < n; i++ {
+		require.True(t, set.Contains(testOID(t, i)))
+	}
+
+	// The false-positive rate should be in the right ballpark:
+	falsePositives := 0
+	for i := uint64(n); i < 2*n; i++ {
+		if set.Contains(testOID(t, i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, n*3/100)
+}
+
+func TestBloomOIDSetMarshal(t *testing.T) {
+	t.Parallel()
+
+	set := git.NewBloomOIDSetWithSize(1000, 5)
+	assert.Equal(t, uint64(1000), set.BitCount())
+	assert.Equal(t, 5, set.HashCount())
+
+	var oids []git.OID
+	for i := uint64(0); i < 50; i++ {
+		oid := testOID(t, i)
+		oids = append(oids, oid)
+		set.Add(oid)
+	}
+
+	data, err := set.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := git.NewBloomOIDSetWithSize(set.BitCount(), set.HashCount())
+	require.NoError(t, restored.UnmarshalBinary(data))
+	for _, oid := range oids {
+		assert.True(t, restored.Contains(oid))
+	}
+
+	assert.InDelta(t, 0.0, set.FalsePositiveRate(0), 1e-12)
+	assert.Less(t, set.FalsePositiveRate(50), 0.01)
+	assert.Greater(t, set.FalsePositiveRate(500), set.FalsePositiveRate(50))
+
+	assert.Error(t, git.NewBloomOIDSetWithSize(64, 1).UnmarshalBinary(data))
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync/atomic"
+)
+
+// PackState describes how well-maintained a repository's object store
+// is, as far as the indexes that speed up reachability queries are
+// concerned.
+type PackState struct {
+	// PackCount is the number of packfiles (not counting packs in
+	// alternates).
+	PackCount int
+
+	// HasBitmap is true if there is a reachability bitmap, either
+	// for a single pack or for a multi-pack index.
+	HasBitmap bool
+
+	// HasCommitGraph is true if there is a commit-graph file (or a
+	// chain of them).
+	HasCommitGraph bool
+}
+
+// FullyPacked returns true if all of the objects are in a single pack
+// that has a reachability bitmap, and there is a commit-graph, as is
+// the case right after `git gc` or `git repack -adb` followed by
+// `git commit-graph write --reachable`. Loose objects are tolerated,
+// since git walks them as needed.
+func (ps PackState) FullyPacked() bool {
+	return ps.PackCount == 1 && ps.HasBitmap && ps.HasCommitGraph
+}
+
+// PackState examines the object store of `repo`.
+func (repo *Repository) PackState() (PackState, error) {
+	var ps PackState
+
+	packs, err := repo.Packfiles()
+	if err != nil {
+		return PackState{}, err
+	}
+	ps.PackCount = len(packs)
+
+	ps.HasBitmap, err = repo.HasReachabilityBitmap()
+	if err != nil {
+		return PackState{}, err
+	}
+
+	for _, relPath := range []string{
+		"objects/info/commit-graph",
+		"objects/info/commit-graphs/commit-graph-chain",
+	} {
+		path, err := repo.GitPath(relPath)
+		if err != nil {
+			return PackState{}, err
+		}
+		ok, err := fileExists(path)
+		if err != nil {
+			return PackState{}, err
+		}
+		if ok {
+			ps.HasCommitGraph = true
+			break
+		}
+	}
+
+	return ps, nil
+}
+
+// SetUseBitmapIndex controls whether the reachability queries of
+// `repo` (e.g., `ReachableCommits()` and `WalkExclusiveObjects()`)
+// ask git to use the reachability bitmap. The results are the same
+// either way, but with an up-to-date bitmap they are much faster to
+// compute. (The commit-graph, if present, is used by git
+// automatically.) This must not be called while other methods of
+// `repo` are running.
+func (repo *Repository) SetUseBitmapIndex(useBitmapIndex bool) {
+	repo.useBitmapIndex = useBitmapIndex
+}
+
+// RanReachabilityQuery returns true if any reachability query has
+// been run against `repo`, in which case the setting of
+// `SetUseBitmapIndex()` took effect.
+func (repo *Repository) RanReachabilityQuery() bool {
+	return atomic.LoadInt32(&repo.ranReachabilityQuery) != 0
+}
+
+// revListArgs returns the arguments for a `git rev-list` command that
+// answers a reachability query, starting with `args`.
+func (repo *Repository) revListArgs(args ...string) []string {
+	atomic.StoreInt32(&repo.ranReachabilityQuery, 1)
+	if repo.useBitmapIndex {
+		args = append(args, "--use-bitmap-index")
+	}
+	return args
+}
+
+// fileExists returns true if there is a file at `path`.
+func fileExists(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("checking for %s: %w", path, err)
+	}
+	return true, nil
+}
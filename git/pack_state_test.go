@@ -0,0 +1,77 @@
+package git_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestPackState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "pack-state")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	var commits []git.OID
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		testRepo.AddFile(t, name, name+"\n")
+		cmd := testRepo.GitCommand(t, "commit", "-m", name)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+
+		out, err := testRepo.GitCommand(t, "rev-parse", "HEAD").Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		commits = append(commits, oid)
+	}
+
+	repo := testRepo.Repository(t)
+
+	ps, err := repo.PackState()
+	require.NoError(t, err)
+	assert.Equal(t, git.PackState{}, ps)
+	assert.False(t, ps.FullyPacked())
+	assert.False(t, repo.RanReachabilityQuery())
+
+	reachable := func() (int, uint64) {
+		t.Helper()
+		set, err := repo.ReachableCommits(commits[2:], commits[:1])
+		require.NoError(t, err)
+		assert.Equal(t, 2, set.Len())
+		assert.True(t, set.Contains(commits[1]))
+		count, size, err := repo.ReachableObjectsSize(ctx, commits[2:], commits[:1])
+		require.NoError(t, err)
+		return int(count), uint64(size)
+	}
+	count, size := reachable()
+	assert.True(t, repo.RanReachabilityQuery())
+
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adbq").Run())
+	ps, err = repo.PackState()
+	require.NoError(t, err)
+	assert.Equal(t, git.PackState{PackCount: 1, HasBitmap: true}, ps)
+	assert.False(t, ps.FullyPacked())
+
+	require.NoError(t, testRepo.GitCommand(t, "commit-graph", "write", "--reachable").Run())
+	ps, err = repo.PackState()
+	require.NoError(t, err)
+	assert.True(t, ps.FullyPacked())
+
+	// The bitmap gives the same answers:
+	repo.SetUseBitmapIndex(true)
+	bitmapCount, bitmapSize := reachable()
+	assert.Equal(t, count, bitmapCount)
+	assert.Equal(t, size, bitmapSize)
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCannotPeelToCommit is returned (wrapped) by `PeelToCommit()` if
+// the object doesn't peel to a commit (e.g., it is a tree, a blob, or
+// a tag of one of those).
+var ErrCannotPeelToCommit = errors.New("object cannot be peeled to a commit")
+
+// PeelToCommit returns the commit that `oid` refers to: `oid` itself
+// if it is a commit, or, if it is an annotated tag, the commit at the
+// end of its chain of tags. It is useful for normalizing the targets
+// of references, which needn't be commits.
+func (repo *Repository) PeelToCommit(oid OID) (OID, error) {
+	// Ask for the object itself (to learn its type) and for the
+	// commit that it peels to, using a single process:
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "%s\n%s^{commit}\n", oid, oid)
+
+	cmd, err := repo.gitCommand("cat-file", "--batch-check=%(objectname) %(objecttype)")
+	if err != nil {
+		return NullOID, err
+	}
+	cmd.Stdin = &input
+	out, err := cmd.Output()
+	if err != nil {
+		return NullOID, fmt.Errorf("running 'git cat-file --batch-check': %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 2 {
+		return NullOID, fmt.Errorf("unexpected output from 'git cat-file': %q", out)
+	}
+	object := strings.Fields(lines[0])
+	if len(object) != 2 {
+		return NullOID, fmt.Errorf("unexpected output from 'git cat-file': %q", lines[0])
+	}
+	if object[1] == "missing" {
+		return NullOID, fmt.Errorf("missing object %s", oid)
+	}
+
+	peeled := strings.Fields(lines[1])
+	if len(peeled) != 2 {
+		return NullOID, fmt.Errorf("unexpected output from 'git cat-file': %q", lines[1])
+	}
+	if peeled[1] != "commit" {
+		return NullOID, fmt.Errorf("%w: %s is a %s", ErrCannotPeelToCommit, oid, object[1])
+	}
+	return NewOID(peeled[0])
+}
@@ -0,0 +1,58 @@
+package git_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestPeelToCommit(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "peel-to-commit")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	for _, args := range [][]string{
+		{"tag", "-a", "-m", "a tag", "v1", "HEAD"},
+		{"tag", "-a", "-m", "a tag of a tag", "nested", "v1"},
+		{"tag", "-a", "-m", "a tag of a tree", "tree-tag", "HEAD^{tree}"},
+	} {
+		cmd := testRepo.GitCommand(t, args...)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "running 'git %s'", strings.Join(args, " "))
+	}
+
+	repo := testRepo.Repository(t)
+
+	resolve := func(name string) git.OID {
+		t.Helper()
+		oid, err := repo.ResolveObject(name)
+		require.NoError(t, err)
+		return oid
+	}
+
+	commit := resolve("HEAD")
+	for _, name := range []string{"HEAD", "refs/tags/v1", "refs/tags/nested"} {
+		peeled, err := repo.PeelToCommit(resolve(name))
+		require.NoError(t, err, name)
+		assert.Equal(t, commit, peeled, name)
+	}
+
+	for _, name := range []string{"HEAD^{tree}", "HEAD:a.txt", "refs/tags/tree-tag"} {
+		_, err := repo.PeelToCommit(resolve(name))
+		assert.ErrorIs(t, err, git.ErrCannotPeelToCommit, name)
+	}
+}
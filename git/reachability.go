@@ -0,0 +1,235 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// revListInput returns the input for `git rev-list --stdin` that
+// selects the objects reachable from `include` but not from
+// `exclude`.
+func revListInput(include, exclude []OID) *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, oid := range include {
+		fmt.Fprintln(&buf, oid.String())
+	}
+	for _, oid := range exclude {
+		fmt.Fprintf(&buf, "^%s\n", oid)
+	}
+	return &buf
+}
+
+// ReachableCommits returns the set of commits that are reachable from
+// any of `include` but from none of `exclude`.
+func (repo *Repository) ReachableCommits(include, exclude []OID) (*ExactOIDSet, error) {
+	set := NewExactOIDSet()
+	if len(include) == 0 {
+		return set, nil
+	}
+
+	cmd, err := repo.gitCommand(repo.revListArgs("rev-list", "--stdin")...)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = revListInput(include, exclude)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git rev-list': %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		oid, err := NewOID(line)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git rev-list' output: %w", err)
+		}
+		set.Add(oid)
+	}
+
+	return set, nil
+}
+
+// ReachableObjectsSize returns the number and the total size of the
+// objects of any type that are reachable from any of `include` but
+// from none of `exclude`.
+func (repo *Repository) ReachableObjectsSize(
+	ctx context.Context, include, exclude []OID,
+) (counts.Count32, counts.Count64, error) {
+	return repo.reachableObjectsSize(ctx, include, exclude, "")
+}
+
+// ReachableBlobsSize returns the number and the total size of the
+// blobs that are reachable from any of `include` but from none of
+// `exclude`.
+func (repo *Repository) ReachableBlobsSize(
+	ctx context.Context, include, exclude []OID,
+) (counts.Count32, counts.Count64, error) {
+	return repo.reachableObjectsSize(ctx, include, exclude, "blob")
+}
+
+// reachableObjectsSize returns the number and the total size of the
+// objects of type `objectType` (or of any type, if it is "") that are
+// reachable from any of `include` but from none of `exclude`.
+func (repo *Repository) reachableObjectsSize(
+	ctx context.Context, include, exclude []OID, objectType ObjectType,
+) (counts.Count32, counts.Count64, error) {
+	var count counts.Count32
+	var size counts.Count64
+	err := repo.WalkExclusiveObjects(
+		ctx, include, exclude, ExclusiveObjectsOptions{},
+		func(_ OID, t ObjectType, objectSize counts.Count64) error {
+			if objectType != "" && t != objectType {
+				return nil
+			}
+			count.Increment(1)
+			size.Increment(objectSize)
+			return nil
+		},
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, size, nil
+}
+
+// ExclusiveObjectsOptions controls `WalkExclusiveObjects()`.
+type ExclusiveObjectsOptions struct {
+	// ExcludeReflogs, if set, also excludes the objects that are
+	// reachable from any reflog entry.
+	ExcludeReflogs bool
+}
+
+// WalkExclusiveObjects calls `fn` with the OID, type, and size of
+// each object that is reachable from any of `include` but from none
+// of `exclude`, in the order that `git rev-list --objects` emits
+// them.
+func (repo *Repository) WalkExclusiveObjects(
+	ctx context.Context, include, exclude []OID, opts ExclusiveObjectsOptions,
+	fn func(oid OID, objectType ObjectType, size counts.Count64) error,
+) error {
+	if len(include) == 0 {
+		return nil
+	}
+
+	args := repo.revListArgs("rev-list", "--objects", "--stdin")
+	if opts.ExcludeReflogs {
+		args = append(args, "--not", "--reflog")
+	}
+
+	p := pipe.New(pipe.WithStdin(revListInput(include, exclude)))
+	p.Add(
+		pipe.CommandStage("git-rev-list", repo.GitCommand(args...)),
+
+		// Strip off the paths that `git rev-list --objects` emits:
+		pipe.LinewiseFunction(
+			"copy-oids",
+			func(_ context.Context, _ pipe.Env, line []byte, stdout *bufio.Writer) error {
+				if len(line) < 40 {
+					return fmt.Errorf("line too short: '%s'", line)
+				}
+				if _, err := stdout.Write(line[:40]); err != nil {
+					return fmt.Errorf("writing OID to 'git cat-file': %w", err)
+				}
+				return stdout.WriteByte('\n')
+			},
+		),
+
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand(
+				"cat-file",
+				"--batch-check=%(objectname) %(objecttype) %(objectsize)", "--buffer",
+			),
+		),
+
+		pipe.Function(
+			"walk-objects",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					words := strings.Fields(line)
+					if len(words) != 3 {
+						return fmt.Errorf("malformed line from 'git cat-file': %q", line)
+					}
+					oid, err := NewOID(words[0])
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					n, err := strconv.ParseUint(words[2], 10, 64)
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					if err := fn(oid, ObjectType(words[1]), counts.NewCount64(n)); err != nil {
+						return err
+					}
+				}
+			},
+		),
+	)
+
+	return p.Run(ctx)
+}
+
+// PeeledCommits returns a map from each of `names` to the commit that
+// it peels to (following any chain of annotated tags). Names that
+// don't peel to a commit (e.g., tags that point at trees or blobs)
+// are omitted from the result.
+func (repo *Repository) PeeledCommits(names []string) (map[string]OID, error) {
+	peeled := make(map[string]OID, len(names))
+	if len(names) == 0 {
+		return peeled, nil
+	}
+
+	var input bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&input, "%s^{commit}\n", name)
+	}
+
+	cmd, err := repo.gitCommand("cat-file", "--batch-check=%(objectname)")
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = &input
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git cat-file --batch-check': %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != len(names) {
+		return nil, fmt.Errorf(
+			"'git cat-file' returned %d lines for %d names", len(lines), len(names),
+		)
+	}
+	for i, line := range lines {
+		if strings.HasSuffix(line, " missing") || strings.HasSuffix(line, " ambiguous") {
+			continue
+		}
+		oid, err := NewOID(line)
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git cat-file' output: %w", err)
+		}
+		peeled[names[i]] = oid
+	}
+
+	return peeled, nil
+}
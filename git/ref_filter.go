@@ -1,6 +1,7 @@
 package git
 
 import (
+	"path"
 	"regexp"
 	"strings"
 )
@@ -121,10 +122,11 @@ func (f prefixFilter) Filter(refname string) bool {
 }
 
 // RegexpFilter returns a `ReferenceFilter` that matches references
-// whose names match the specified `prefix`, which must match the
-// whole reference name.
+// whose names match the specified `pattern`, which must match the
+// whole reference name (even if it contains alternatives; e.g.,
+// `refs/heads/main|refs/tags/.*`).
 func RegexpFilter(pattern string) (ReferenceFilter, error) {
-	pattern = "^" + pattern + "$"
+	pattern = "^(?:" + pattern + ")$"
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		return nil, err
@@ -140,3 +142,74 @@ type regexpFilter struct {
 func (f regexpFilter) Filter(refname string) bool {
 	return f.re.MatchString(refname)
 }
+
+// globChars are the characters that are special in glob patterns.
+// None of them can appear in a valid reference name.
+const globChars = "*?["
+
+// IsGlob returns true iff `pattern` contains glob metacharacters, and
+// therefore can't be a literal reference name or prefix.
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, globChars)
+}
+
+// GlobFilter returns a `ReferenceFilter` that matches references
+// whose names match the glob `pattern` (as understood by
+// `path.Match()`; e.g., `refs/pull/*/head`). Wildcards don't match
+// `/`. Like a prefix, the pattern matches at a component boundary,
+// so `refs/pull/*` matches "refs/pull/1/head", too. A trailing `/`
+// means that the pattern only matches references below it.
+func GlobFilter(pattern string) (ReferenceFilter, error) {
+	below := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	return globFilter{
+		pattern:    pattern,
+		components: strings.Count(pattern, "/") + 1,
+		below:      below,
+	}, nil
+}
+
+type globFilter struct {
+	pattern string
+
+	// components is the number of components in `pattern`.
+	components int
+
+	// below is true if the pattern only matches references with
+	// more components than `pattern`.
+	below bool
+}
+
+func (f globFilter) Filter(refname string) bool {
+	// Find the end of the leading `f.components` components of
+	// `refname`:
+	end := 0
+	for i := 0; i < f.components; i++ {
+		if i > 0 {
+			end++ // skip the slash
+		}
+		if end > len(refname) {
+			return false
+		}
+		j := strings.IndexByte(refname[end:], '/')
+		if j == -1 {
+			if i != f.components-1 {
+				return false
+			}
+			end = len(refname)
+		} else {
+			end += j
+		}
+	}
+	if f.below && end == len(refname) {
+		return false
+	}
+
+	// The error was checked in `GlobFilter()`:
+	matched, _ := path.Match(f.pattern, refname[:end])
+	return matched
+}
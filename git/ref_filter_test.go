@@ -34,6 +34,15 @@ func TestPrefixFilter(t *testing.T) {
 
 		{"refs/stash", "refs/stash", true},
 		{"refs/remotes", "refs/remotes/origin/master", true},
+
+		// Names that CI systems and odd tools create:
+		{"refs/pull", "refs/pull/123/head", true},
+		{"refs/pull/123", "refs/pull/1234/head", false},
+		{"refs/changes/45", "refs/changes/45/12345/42", true},
+		{"refs/heads/user@host", "refs/heads/user@host/topic", true},
+		{"refs/heads/f\u00fcr", "refs/heads/f\u00fcr/alle", true},
+		{"refs/heads/f\u00fcr", "refs/heads/f\u00fcrst", false},
+		{"refs/heads/a b", "refs/heads/a b/c", true},
 	} {
 		t.Run(
 			fmt.Sprintf("prefix '%s', refname '%s'", p.prefix, p.refname),
@@ -73,6 +82,17 @@ func TestRegexpFilter(t *testing.T) {
 		{`heads/.*`, "refs/heads/master", false},
 		{`refs/tags/release-\d+\.\d+\.\d+`, "refs/tags/release-1.22.333", true},
 		{`refs/tags/release-\d+\.\d+\.\d+`, "refs/tags/release-1.2.3rc1", false},
+
+		// Alternatives must match the whole name, too:
+		{`refs/heads/main|refs/tags/v.*`, "refs/heads/main", true},
+		{`refs/heads/main|refs/tags/v.*`, "refs/heads/maintenance", false},
+		{`refs/heads/main|refs/tags/v.*`, "refs/tags/v1", true},
+		{`refs/heads/main|refs/tags/v.*`, "xrefs/tags/v1", false},
+
+		{`refs/pull/\d+/head`, "refs/pull/123/head", true},
+		{`refs/pull/\d+/head`, "refs/pull/123/merge", false},
+		{`refs/heads/f\x{fc}r`, "refs/heads/f\u00fcr", true},
+		{`refs/heads/.*@.*`, "refs/heads/user@host", true},
 	} {
 		t.Run(
 			fmt.Sprintf("pattern '%s', refname '%s'", p.pattern, p.refname),
@@ -87,6 +107,52 @@ func TestRegexpFilter(t *testing.T) {
 	}
 }
 
+func TestGlobFilter(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []struct {
+		pattern  string
+		refname  string
+		expected bool
+	}{
+		{"refs/pull/*/head", "refs/pull/1/head", true},
+		{"refs/pull/*/head", "refs/pull/1234/head", true},
+		{"refs/pull/*/head", "refs/pull/1/merge", false},
+		{"refs/pull/*/head", "refs/pull/1", false},
+		{"refs/pull/*/head", "refs/pull/a/b/head", false},
+
+		// Like a prefix, a glob matches at component boundaries:
+		{"refs/pull/*", "refs/pull/1/head", true},
+		{"refs/pull/*", "refs/pull/1", true},
+		{"refs/pull/*", "refs/pull", false},
+		{"refs/pull/*/", "refs/pull/1", false},
+		{"refs/pull/*/", "refs/pull/1/head", true},
+		{"refs/pu*", "refs/pull/1/head", true},
+		{"refs/pu*", "refs/push", true},
+		{"refs/pu?l", "refs/pull", true},
+
+		{"refs/changes/[0-9][0-9]/*", "refs/changes/45/12345/42", true},
+		{"refs/changes/[0-9][0-9]/*", "refs/changes/4/12345/42", false},
+		{"refs/heads/*@*", "refs/heads/user@host", true},
+		{"refs/heads/f\u00fc?", "refs/heads/f\u00fcr", true},
+	} {
+		t.Run(
+			fmt.Sprintf("pattern '%s', refname '%s'", p.pattern, p.refname),
+			func(t *testing.T) {
+				f, err := git.GlobFilter(p.pattern)
+				require.NoError(t, err)
+				assert.Equal(t, p.expected, f.Filter(p.refname))
+			},
+		)
+	}
+
+	assert.True(t, git.IsGlob("refs/pull/*/head"))
+	assert.False(t, git.IsGlob("refs/heads/user@host"))
+
+	_, err := git.GlobFilter("refs/[unclosed")
+	assert.Error(t, err)
+}
+
 func TestIncludeExcludeFilter(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// RelevantConfigKeys are the gitconfig settings that affect how big a
+// repository gets on disk, or how quickly garbage is cleaned up.
+var RelevantConfigKeys = []string{
+	"core.bigFileThreshold",
+	"core.commitGraph",
+	"core.compression",
+	"core.looseCompression",
+	"fetch.writeCommitGraph",
+	"gc.auto",
+	"gc.autoPackLimit",
+	"gc.cruftPacks",
+	"gc.pruneExpire",
+	"gc.reflogExpire",
+	"gc.reflogExpireUnreachable",
+	"pack.compression",
+	"pack.depth",
+	"pack.window",
+	"receive.autogc",
+	"repack.writeBitmaps",
+}
+
+// ConfigGetAll returns all of the values of `key` in the gitconfig
+// (following any includes), in the order that Git reads them. The
+// second return value is `false` if the key is not set at all, which
+// is different from being set to the empty string.
+func (repo *Repository) ConfigGetAll(key string) ([]string, bool, error) {
+	cmd, err := repo.gitCommand("config", "-z", "--get-all", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		if err, ok := err.(*exec.ExitError); ok && err.ExitCode() == 1 {
+			// This indicates that the value was not found.
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("running 'git config': %w", err)
+	}
+
+	var values []string
+	for len(out) > 0 {
+		end := bytes.IndexByte(out, 0)
+		if end == -1 {
+			return nil, false, fmt.Errorf("invalid output from 'git config': %q", out)
+		}
+		values = append(values, string(out[:end]))
+		out = out[end+1:]
+	}
+
+	return values, true, nil
+}
+
+// RelevantConfig returns the values of those of `RelevantConfigKeys`
+// that are set. Keys that are not set are omitted, whereas keys that
+// are set to the empty string map to "". If a key is set more than
+// once, the last value wins, as it does for Git. All of the keys are
+// read using a single `git config --list` process.
+func (repo *Repository) RelevantConfig() (map[string]string, error) {
+	// None of the relevant keys has a subsection, so they can be
+	// matched case-insensitively:
+	relevant := make(map[string]string, len(RelevantConfigKeys))
+	for _, key := range RelevantConfigKeys {
+		relevant[strings.ToLower(key)] = key
+	}
+
+	cmd, err := repo.gitCommand("config", "-z", "--list")
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git config': %w", err)
+	}
+
+	config := make(map[string]string)
+	for len(out) > 0 {
+		end := bytes.IndexByte(out, 0)
+		if end == -1 {
+			return nil, fmt.Errorf("invalid output from 'git config': %q", out)
+		}
+		entry := string(out[:end])
+		out = out[end+1:]
+
+		// Entries look like "key\nvalue", or just "key" for a
+		// key without a value:
+		key, value, _ := strings.Cut(entry, "\n")
+		if key, ok := relevant[strings.ToLower(key)]; ok {
+			config[key] = value
+		}
+	}
+	return config, nil
+}
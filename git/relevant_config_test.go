@@ -0,0 +1,56 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestRelevantConfig(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "relevant-config")
+	defer testRepo.Remove(t)
+
+	testRepo.ConfigAdd(t, "gc.auto", "0")
+	testRepo.ConfigAdd(t, "core.compression", "0")
+	// Set to the empty string, which is different from being unset:
+	testRepo.ConfigAdd(t, "gc.reflogExpire", "")
+	// The last value wins:
+	testRepo.ConfigAdd(t, "gc.pruneExpire", "2.weeks.ago")
+	testRepo.ConfigAdd(t, "gc.pruneExpire", "never")
+
+	// Values from included files count, too:
+	include := filepath.Join(testRepo.Path, "included.config")
+	require.NoError(t, os.WriteFile(include, []byte("[receive]\n\tautogc = false\n"), 0o644))
+	testRepo.ConfigAdd(t, "include.path", include)
+
+	repo := testRepo.Repository(t)
+
+	values, ok, err := repo.ConfigGetAll("gc.pruneExpire")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"2.weeks.ago", "never"}, values)
+
+	config, err := repo.RelevantConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "0", config["gc.auto"])
+	assert.Equal(t, "0", config["core.compression"])
+	assert.Equal(t, "never", config["gc.pruneExpire"])
+	assert.Equal(t, "false", config["receive.autogc"])
+
+	v, ok := config["gc.reflogExpire"]
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+
+	_, ok = config["gc.cruftPacks"]
+	assert.False(t, ok)
+	_, ok, err = repo.ConfigGetAll("gc.cruftPacks")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
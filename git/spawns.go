@@ -0,0 +1,119 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// SpawnBudgetError is returned if running a `git` command would
+// exceed the budget set by `SetSpawnBudget()`.
+type SpawnBudgetError struct {
+	// Command is the git subcommand that was not run (e.g.,
+	// "rev-parse").
+	Command string
+
+	// Budget is the maximum number of processes that may be
+	// spawned.
+	Budget int
+}
+
+func (err *SpawnBudgetError) Error() string {
+	return fmt.Sprintf(
+		"not running 'git %s': the budget of %d git processes has been used up",
+		err.Command, err.Budget,
+	)
+}
+
+// spawnCounter counts the `git` processes that are spawned for a
+// `Repository`.
+type spawnCounter struct {
+	lock   sync.Mutex
+	counts map[string]int
+	total  int
+
+	// budget is the maximum value of `total`, or zero if there is no
+	// limit.
+	budget int
+}
+
+// record counts a spawn of `command`. If `enforceBudget` is set and
+// the spawn would exceed the budget, it returns a `*SpawnBudgetError`
+// instead of counting it. Checking and counting happen under the same
+// lock, so concurrent callers can't overshoot the budget.
+func (c *spawnCounter) record(command string, enforceBudget bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if enforceBudget && c.budget > 0 && c.total >= c.budget {
+		return &SpawnBudgetError{Command: command, Budget: c.budget}
+	}
+
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[command]++
+	c.total++
+	return nil
+}
+
+// subcommandName returns the name of the git subcommand in `args`
+// (e.g., "rev-parse"), skipping any leading options.
+func subcommandName(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-c" || arg == "-C":
+			// These options take a separate argument.
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg
+		}
+	}
+	return ""
+}
+
+// SetSpawnBudget limits the total number of `git` processes that may
+// be spawned for `repo` (including those that have already been
+// spawned) to `budget`, or removes the limit if `budget` is zero.
+// When the budget is used up, the short-lived commands that git-sizer
+// runs fail with a `*SpawnBudgetError`. The long-lived processes of
+// the object iterators are counted, but are always allowed to start,
+// so that a scan isn't cut off halfway.
+func (repo *Repository) SetSpawnBudget(budget int) {
+	repo.spawns.lock.Lock()
+	defer repo.spawns.lock.Unlock()
+	repo.spawns.budget = budget
+}
+
+// SpawnCounts returns the number of `git` processes that have been
+// spawned for `repo`, keyed by subcommand name.
+func (repo *Repository) SpawnCounts() map[string]int {
+	repo.spawns.lock.Lock()
+	defer repo.spawns.lock.Unlock()
+
+	counts := make(map[string]int, len(repo.spawns.counts))
+	for command, n := range repo.spawns.counts {
+		counts[command] = n
+	}
+	return counts
+}
+
+// SpawnTotal returns the total number of `git` processes that have
+// been spawned for `repo`.
+func (repo *Repository) SpawnTotal() int {
+	repo.spawns.lock.Lock()
+	defer repo.spawns.lock.Unlock()
+	return repo.spawns.total
+}
+
+// gitCommand is like `GitCommand()`, but fails with a
+// `*SpawnBudgetError` instead if the spawn budget is used up. It is
+// meant for short-lived commands.
+func (repo *Repository) gitCommand(args ...string) (*exec.Cmd, error) {
+	if err := repo.spawns.record(subcommandName(args), true); err != nil {
+		return nil, err
+	}
+	return repo.newGitCommand(args...), nil
+}
@@ -0,0 +1,77 @@
+package git_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestSpawnBudget(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "spawn-budget")
+	defer testRepo.Remove(t)
+
+	repo := testRepo.Repository(t)
+	start := repo.SpawnTotal()
+
+	_, err := repo.GitPath("objects")
+	require.NoError(t, err)
+	_, err = repo.RelevantConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, start+2, repo.SpawnTotal())
+	counts := repo.SpawnCounts()
+	assert.GreaterOrEqual(t, counts["rev-parse"], 1)
+	assert.GreaterOrEqual(t, counts["config"], 1)
+
+	repo.SetSpawnBudget(repo.SpawnTotal())
+
+	_, err = repo.RelevantConfig()
+	var budgetErr *git.SpawnBudgetError
+	require.True(t, errors.As(err, &budgetErr), "unexpected error: %v", err)
+	assert.Equal(t, "config", budgetErr.Command)
+	assert.Equal(t, repo.SpawnTotal(), budgetErr.Budget)
+
+	// Lifting the budget lets commands run again:
+	repo.SetSpawnBudget(0)
+	_, err = repo.RelevantConfig()
+	assert.NoError(t, err)
+}
+
+func TestSpawnBudgetConcurrent(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "spawn-budget-concurrent")
+	defer testRepo.Remove(t)
+
+	repo := testRepo.Repository(t)
+	budget := repo.SpawnTotal() + 5
+	repo.SetSpawnBudget(budget)
+
+	// Concurrent callers must not be able to overshoot the budget:
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.GitPath("objects"); err == nil {
+				lock.Lock()
+				succeeded++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, succeeded)
+	assert.Equal(t, budget, repo.SpawnTotal())
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/github/go-pipe/pipe"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// StoredObject is an object in the repository's object store, whether
+// or not it is reachable.
+type StoredObject struct {
+	OID        OID
+	ObjectType ObjectType
+
+	// DiskSize is the number of bytes that the object takes up on
+	// disk: the size of the loose object file, or of the (possibly
+	// deltified) entry in a packfile.
+	DiskSize counts.Count64
+}
+
+// WalkStoredObjects calls `fn` for each object in the repository's
+// object store (including unreachable objects, and objects in
+// alternates), in OID order, using a single `git cat-file
+// --batch-all-objects` process. If an object is stored more than
+// once, it is reported once, with the disk size of the copy that git
+// would read. If `fn` returns an error, the walk is aborted and that
+// error is returned.
+func (repo *Repository) WalkStoredObjects(ctx context.Context, fn func(StoredObject) error) error {
+	p := pipe.New()
+	p.Add(
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand(
+				"cat-file", "--batch-all-objects",
+				"--batch-check=%(objectname) %(objecttype) %(objectsize:disk)",
+			),
+		),
+		pipe.Function(
+			"read-stored-objects",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				in := bufio.NewReader(stdin)
+				for {
+					line, err := in.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							if line != "" {
+								return errors.New("'git cat-file' output ends unexpectedly")
+							}
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					words := strings.Fields(line)
+					if len(words) != 3 {
+						return fmt.Errorf("malformed line from 'git cat-file': %q", line)
+					}
+					oid, err := NewOID(words[0])
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					diskSize, err := strconv.ParseUint(words[2], 10, 64)
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+					obj := StoredObject{
+						OID:        oid,
+						ObjectType: ObjectType(words[1]),
+						DiskSize:   counts.Count64(diskSize),
+					}
+					if err := fn(obj); err != nil {
+						return err
+					}
+				}
+			},
+		),
+	)
+	return p.Run(ctx)
+}
@@ -0,0 +1,346 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// maxGitmodulesSize is the most of a `.gitmodules` file that
+// `SubmoduleList()` reads. Real ones are tiny; anything much bigger
+// is garbage.
+const maxGitmodulesSize = 1 << 20
+
+// SubmoduleConfig is the configuration of one submodule, as recorded
+// in a `.gitmodules` file.
+type SubmoduleConfig struct {
+	// Name is the submodule's name; i.e., the subsection of its
+	// `submodule` section.
+	Name string
+
+	// Path is the path of the submodule, relative to the root of the
+	// tree.
+	Path string
+
+	URL    string
+	Branch string
+	Update string
+}
+
+// SubmoduleList returns the submodules configured in the `.gitmodules`
+// file in the root of the tree `treeOID` (which may also be a commit),
+// in the order that they first appear in that file. If there is no
+// `.gitmodules` file, it returns an empty list. The results are
+// cached, so callers can ask about the same tree repeatedly.
+func (repo *Repository) SubmoduleList(ctx context.Context, treeOID OID) ([]SubmoduleConfig, error) {
+	repo.submodulesLock.Lock()
+	submodules, ok := repo.submodules[treeOID]
+	repo.submodulesLock.Unlock()
+	if ok {
+		return append([]SubmoduleConfig{}, submodules...), nil
+	}
+
+	entry, ok, err := repo.ReadTreeAtPath(treeOID.String(), ".gitmodules")
+	if err != nil {
+		return nil, err
+	}
+	submodules = []SubmoduleConfig{}
+	if ok && entry.ObjectType == "blob" {
+		err := repo.ReadBlobs(
+			ctx, []OID{entry.OID}, maxGitmodulesSize,
+			func(_ OID, _ counts.Count32, data []byte) error {
+				var err error
+				submodules, err = ParseGitmodules(data)
+				if err != nil {
+					return fmt.Errorf("parsing .gitmodules in %s: %w", treeOID, err)
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo.submodulesLock.Lock()
+	if repo.submodules == nil {
+		repo.submodules = make(map[OID][]SubmoduleConfig)
+	}
+	repo.submodules[treeOID] = submodules
+	repo.submodulesLock.Unlock()
+
+	return append([]SubmoduleConfig{}, submodules...), nil
+}
+
+// ParseGitmodules parses the contents of a `.gitmodules` file, which
+// uses the gitconfig syntax. Sections other than `submodule` are
+// ignored. If a key is repeated, the last value wins.
+func ParseGitmodules(data []byte) ([]SubmoduleConfig, error) {
+	submodules := []SubmoduleConfig{}
+	indexes := make(map[string]int)
+
+	err := parseConfigFile(data, func(section, subsection, key, value string) {
+		if section != "submodule" || subsection == "" {
+			return
+		}
+		i, ok := indexes[subsection]
+		if !ok {
+			i = len(submodules)
+			indexes[subsection] = i
+			submodules = append(submodules, SubmoduleConfig{Name: subsection})
+		}
+		sm := &submodules[i]
+		switch key {
+		case "path":
+			sm.Path = value
+		case "url":
+			sm.URL = value
+		case "branch":
+			sm.Branch = value
+		case "update":
+			sm.Update = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return submodules, nil
+}
+
+// parseConfigFile parses `data` as a file in gitconfig syntax, calling
+// `fn` for each variable. Section names and keys are passed in lower
+// case; subsection names are case-sensitive (except in the deprecated
+// `[section.subsection]` form). A key without a value has the value
+// "". Includes are not followed.
+func parseConfigFile(
+	data []byte, fn func(section, subsection, key, value string),
+) error {
+	p := configParser{data: data, line: 1}
+	var section, subsection string
+	for {
+		p.skipSpace(true)
+		c, ok := p.peek()
+		if !ok {
+			return nil
+		}
+		switch {
+		case c == '#' || c == ';':
+			p.skipComment()
+		case c == '[':
+			p.pos++
+			var err error
+			section, subsection, err = p.sectionHeader()
+			if err != nil {
+				return p.errorf("%w", err)
+			}
+		case isConfigKeyChar(c):
+			key := strings.ToLower(p.key())
+			if section == "" {
+				return p.errorf("variable %q outside of any section", key)
+			}
+			value, err := p.value()
+			if err != nil {
+				return p.errorf("%w", err)
+			}
+			fn(section, subsection, key, value)
+		default:
+			return p.errorf("unexpected character %q", c)
+		}
+	}
+}
+
+type configParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+func (p *configParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %w", p.line, fmt.Errorf(format, args...))
+}
+
+func (p *configParser) peek() (byte, bool) {
+	if p.pos >= len(p.data) {
+		return 0, false
+	}
+	return p.data[p.pos], true
+}
+
+func (p *configParser) next() (byte, bool) {
+	c, ok := p.peek()
+	if ok {
+		p.pos++
+		if c == '\n' {
+			p.line++
+		}
+	}
+	return c, ok
+}
+
+// skipSpace skips over spaces and tabs, and over newlines, too, if
+// `newlines` is set.
+func (p *configParser) skipSpace(newlines bool) {
+	for {
+		c, ok := p.peek()
+		if !ok || !(c == ' ' || c == '\t' || c == '\r' || (newlines && c == '\n')) {
+			return
+		}
+		p.next()
+	}
+}
+
+// skipComment skips to the end of the line, leaving the newline.
+func (p *configParser) skipComment() {
+	for {
+		c, ok := p.peek()
+		if !ok || c == '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func isConfigKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+func (p *configParser) key() string {
+	start := p.pos
+	for {
+		c, ok := p.peek()
+		if !ok || !isConfigKeyChar(c) {
+			return string(p.data[start:p.pos])
+		}
+		p.pos++
+	}
+}
+
+// sectionHeader parses the rest of a section header, after the `[`.
+func (p *configParser) sectionHeader() (string, string, error) {
+	start := p.pos
+	for {
+		c, ok := p.peek()
+		if !ok || !(isConfigKeyChar(c) || c == '.') {
+			break
+		}
+		p.pos++
+	}
+	name := string(p.data[start:p.pos])
+	if name == "" {
+		return "", "", errors.New("missing section name")
+	}
+
+	c, ok := p.next()
+	switch {
+	case ok && c == ']':
+		// The deprecated `[section.subsection]` form:
+		name = strings.ToLower(name)
+		if i := strings.IndexByte(name, '.'); i != -1 {
+			return name[:i], name[i+1:], nil
+		}
+		return name, "", nil
+	case ok && (c == ' ' || c == '\t'):
+		p.skipSpace(false)
+		if c, ok := p.next(); !ok || c != '"' {
+			return "", "", errors.New("expected quoted subsection name")
+		}
+		var sb strings.Builder
+		for {
+			c, ok := p.next()
+			if !ok || c == '\n' {
+				return "", "", errors.New("unterminated subsection name")
+			}
+			if c == '"' {
+				break
+			}
+			if c == '\\' {
+				c, ok = p.next()
+				if !ok || c == '\n' {
+					return "", "", errors.New("unterminated subsection name")
+				}
+			}
+			sb.WriteByte(c)
+		}
+		if c, ok := p.next(); !ok || c != ']' {
+			return "", "", errors.New("expected ']' after subsection name")
+		}
+		return strings.ToLower(name), sb.String(), nil
+	default:
+		return "", "", errors.New("malformed section header")
+	}
+}
+
+// value parses the rest of a variable's line, after the key, and
+// returns its value. Whitespace around the value is dropped, and
+// runs of whitespace within it (outside of quotes) are kept as
+// spaces, as Git does.
+func (p *configParser) value() (string, error) {
+	p.skipSpace(false)
+	c, ok := p.peek()
+	if !ok || c == '\n' || c == '#' || c == ';' {
+		// A key without a value.
+		return "", nil
+	}
+	if c != '=' {
+		return "", fmt.Errorf("expected '=', got %q", c)
+	}
+	p.pos++
+
+	var sb strings.Builder
+	inQuote := false
+	spaces := 0
+	for {
+		c, ok := p.next()
+		if !ok || (c == '\n' && !inQuote) {
+			if inQuote {
+				return "", errors.New("unterminated quoted value")
+			}
+			return sb.String(), nil
+		}
+		if c == '\n' {
+			return "", errors.New("unterminated quoted value")
+		}
+		if !inQuote && (c == '#' || c == ';') {
+			p.skipComment()
+			continue
+		}
+		if !inQuote && (c == ' ' || c == '\t' || c == '\r') {
+			if sb.Len() > 0 {
+				spaces++
+			}
+			continue
+		}
+		for ; spaces > 0; spaces-- {
+			sb.WriteByte(' ')
+		}
+		switch c {
+		case '"':
+			inQuote = !inQuote
+		case '\\':
+			c, ok := p.next()
+			if !ok {
+				return "", errors.New("backslash at end of file")
+			}
+			switch c {
+			case '\n':
+				// A line continuation.
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			case '\\', '"':
+				sb.WriteByte(c)
+			default:
+				return "", fmt.Errorf("invalid escape sequence '\\%c'", c)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
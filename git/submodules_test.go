@@ -0,0 +1,148 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestParseGitmodules(t *testing.T) {
+	t.Parallel()
+
+	submodules, err := git.ParseGitmodules([]byte(
+		"# A comment\n" +
+			"[submodule \"lib\"]\n" +
+			"\tpath = vendor/lib\n" +
+			"\turl = https://example.com/lib.git ; trailing comment\n" +
+			"\tbranch = main\n" +
+			"[core]\n" +
+			"\tbare = false\n" +
+			"[Submodule \"Docs Site\"]\n" +
+			"\tPath = \"docs/site with spaces\"\n" +
+			"\tURL = git@example.com:docs.git\n" +
+			"\tupdate = rebase\n" +
+			"[submodule \"lib\"]\n" +
+			"\turl = https://example.com/moved/lib.git\n" +
+			"[submodule.old]\n" +
+			"\tpath = old\\\n" +
+			"style\n" +
+			"\tignore\n" +
+			"[submodule \"we\\\"ird\"]\n" +
+			"\tpath = a  \"#b\"\\tc   \n",
+	))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]git.SubmoduleConfig{
+			{
+				Name:   "lib",
+				Path:   "vendor/lib",
+				URL:    "https://example.com/moved/lib.git",
+				Branch: "main",
+			},
+			{
+				Name:   "Docs Site",
+				Path:   "docs/site with spaces",
+				URL:    "git@example.com:docs.git",
+				Update: "rebase",
+			},
+			{Name: "old", Path: "oldstyle"},
+			{Name: "we\"ird", Path: "a  #b\tc"},
+		},
+		submodules,
+	)
+
+	submodules, err = git.ParseGitmodules(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, submodules)
+	assert.Empty(t, submodules)
+
+	for _, data := range []string{
+		"path = outside\n",
+		"[submodule \"x\"\n",
+		"[submodule \"x\"]\n\tpath = \"unterminated\n",
+		"[submodule \"x\"]\n\tpath = bad\\escape\n",
+		"[submodule \"x\"]\n\tpath vendor\n",
+		"[]\n",
+	} {
+		_, err := git.ParseGitmodules([]byte(data))
+		assert.Errorf(t, err, "parsing %q", data)
+	}
+}
+
+func TestSubmoduleList(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "submodule-list")
+	defer testRepo.Remove(t)
+
+	gitmodules := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(
+			w,
+			"[submodule \"lib\"]\n"+
+				"\tpath = vendor/lib\n"+
+				"\turl = https://example.com/lib.git\n",
+		)
+		return err
+	})
+	// Any commit will do as the submodule's commit:
+	gitlink := "1111111111111111111111111111111111111111"
+
+	vendor := mktree(t, testRepo, fmt.Sprintf("160000 commit %s\tlib\n", gitlink))
+	withModules := mktree(
+		t, testRepo,
+		fmt.Sprintf("100644 blob %s\t.gitmodules\n", gitmodules)+
+			fmt.Sprintf("040000 tree %s\tvendor\n", vendor),
+	)
+	// A `.gitmodules` in a subdirectory doesn't count:
+	withoutModules := mktree(
+		t, testRepo, fmt.Sprintf("040000 tree %s\tvendor\n", withModules),
+	)
+
+	ctx := context.Background()
+	repo := testRepo.Repository(t)
+
+	expected := []git.SubmoduleConfig{
+		{Name: "lib", Path: "vendor/lib", URL: "https://example.com/lib.git"},
+	}
+	for i := 0; i < 2; i++ {
+		// The second time, the result comes from the cache:
+		submodules, err := repo.SubmoduleList(ctx, withModules)
+		require.NoError(t, err)
+		assert.Equal(t, expected, submodules)
+
+		// Callers can't corrupt the cache:
+		submodules[0].URL = "changed"
+	}
+
+	submodules, err := repo.SubmoduleList(ctx, withoutModules)
+	require.NoError(t, err)
+	assert.NotNil(t, submodules)
+	assert.Empty(t, submodules)
+}
+
+// mktree creates a tree from `git ls-tree`-style `entries`, whose
+// objects don't have to exist.
+func mktree(t *testing.T, testRepo *testutils.TestRepo, entries string) git.OID {
+	t.Helper()
+
+	cmd := testRepo.GitCommand(t, "mktree", "--missing")
+	in, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	go func() {
+		defer in.Close()
+		_, _ = io.WriteString(in, entries)
+	}()
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	oid, err := git.NewOID(string(out[:len(out)-1]))
+	require.NoError(t, err)
+	return oid
+}
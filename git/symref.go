@@ -0,0 +1,150 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SymbolicReference represents a symbolic reference (e.g., `HEAD` or
+// `refs/remotes/origin/HEAD`) and the reference that it points at.
+type SymbolicReference struct {
+	// Refname is the full name of the symbolic reference itself.
+	Refname string
+
+	// Target is the full name of the reference that it points at.
+	Target string
+}
+
+// SymbolicRef returns the name of the reference that the symbolic
+// reference `name` points at, by calling `git symbolic-ref -q`. If
+// `name` is not a symbolic reference (e.g., if `HEAD` is detached),
+// return `"", false, nil`.
+func (repo *Repository) SymbolicRef(name string) (string, bool, error) {
+	cmd, err := repo.gitCommand("symbolic-ref", "-q", name)
+	if err != nil {
+		return "", false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			// This indicates that `name` is not a symbolic
+			// reference.
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("running 'git symbolic-ref -q %s': %w", name, err)
+	}
+
+	return string(bytes.TrimSpace(out)), true, nil
+}
+
+// RefExists returns true iff the reference with the full name
+// `refname` exists and points at a valid object.
+func (repo *Repository) RefExists(refname string) (bool, error) {
+	cmd, err := repo.gitCommand("show-ref", "--verify", "--quiet", refname)
+	if err != nil {
+		return false, err
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("running 'git show-ref --verify %s': %w", refname, err)
+	}
+
+	return true, nil
+}
+
+// SymbolicReferences returns the symbolic references under `refs/`
+// (e.g., `refs/remotes/origin/HEAD`) whose targets exist, as listed
+// by `git for-each-ref`, so it works with any reference backend. `git
+// for-each-ref` silently skips symbolic references whose targets
+// don't exist; see `LooseSymbolicReferences()`.
+func (repo *Repository) SymbolicReferences() ([]SymbolicReference, error) {
+	var symrefs []SymbolicReference
+	err := repo.ForEachRefWithOptions(
+		ForEachRefOptions{Fields: []string{"symref"}},
+		func(ref Ref) error {
+			if target := ref.Fields["symref"]; target != "" {
+				symrefs = append(symrefs, SymbolicReference{
+					Refname: ref.Refname,
+					Target:  target,
+				})
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading symbolic references: %w", err)
+	}
+	return symrefs, nil
+}
+
+// LooseSymbolicReferences returns the symbolic references under
+// `refs/` that are stored as loose files, including any whose targets
+// don't exist. With the default "files" backend, symbolic references
+// are never packed, so this finds the dangling ones that `git
+// for-each-ref` skips. With other backends (e.g., reftable), it finds
+// nothing.
+func (repo *Repository) LooseSymbolicReferences() ([]SymbolicReference, error) {
+	refsDir, err := repo.GitPath("refs")
+	if err != nil {
+		return nil, err
+	}
+
+	var symrefs []SymbolicReference
+	err = filepath.WalkDir(refsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(content, []byte("ref: ")) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(refsDir, path)
+		if err != nil {
+			return err
+		}
+		symrefs = append(symrefs, SymbolicReference{
+			Refname: "refs/" + filepath.ToSlash(rel),
+			Target:  strings.TrimSpace(string(content[len("ref: "):])),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading symbolic references: %w", err)
+	}
+
+	return symrefs, nil
+}
+
+// IsBare returns true iff `repo` is a bare repository.
+func (repo *Repository) IsBare() (bool, error) {
+	cmd, err := repo.gitCommand("rev-parse", "--is-bare-repository")
+	if err != nil {
+		return false, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("running 'git rev-parse --is-bare-repository': %w", err)
+	}
+	return string(bytes.TrimSpace(out)) == "true", nil
+}
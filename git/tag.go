@@ -11,15 +11,21 @@ type Tag struct {
 	Size         counts.Count32
 	Referent     OID
 	ReferentType ObjectType
+
+	// Headers describes anything unusual about the tag's headers.
+	Headers HeaderStats
 }
 
 // ParseTag parses the Git tag object whose contents are contained in
-// `data`. `oid` is used only in error messages.
+// `data`. `oid` is used only in error messages. Unknown and duplicated
+// headers are tolerated (only the first "object" and "type" headers
+// are used), but are recorded in the `Headers` field.
 func ParseTag(oid OID, data []byte) (*Tag, error) {
 	var referent OID
 	var referentFound bool
 	var referentType ObjectType
 	var referentTypeFound bool
+	headers := newHeaderStatsBuilder(tagHeaders)
 	iter, err := NewObjectHeaderIter(oid.String(), data)
 	if err != nil {
 		return nil, err
@@ -29,10 +35,11 @@ func ParseTag(oid OID, data []byte) (*Tag, error) {
 		if err != nil {
 			return nil, err
 		}
+		headers.add(key, value)
 		switch key {
 		case "object":
 			if referentFound {
-				return nil, fmt.Errorf("multiple referents found in tag %s", oid)
+				continue
 			}
 			referent, err = NewOID(value)
 			if err != nil {
@@ -41,7 +48,7 @@ func ParseTag(oid OID, data []byte) (*Tag, error) {
 			referentFound = true
 		case "type":
 			if referentTypeFound {
-				return nil, fmt.Errorf("multiple types found in tag %s", oid)
+				continue
 			}
 			referentType = ObjectType(value)
 			referentTypeFound = true
@@ -57,5 +64,6 @@ func ParseTag(oid OID, data []byte) (*Tag, error) {
 		Size:         counts.NewCount32(uint64(len(data))),
 		Referent:     referent,
 		ReferentType: referentType,
+		Headers:      headers.stats,
 	}, nil
 }
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaggedCommit describes a commit that is pointed at by a tag.
+type TaggedCommit struct {
+	// TagName is the name of the tag, without the `refs/tags/`
+	// prefix.
+	TagName string
+
+	// TagOID is the OID that the tag reference points at; for an
+	// annotated tag, this is the OID of the tag object.
+	TagOID OID
+
+	// CommitOID is the commit that the tag peels to.
+	CommitOID OID
+
+	// TagTime is the tagger timestamp of an annotated tag, or the
+	// author timestamp of the commit for a lightweight tag (or for
+	// an annotated tag that has no tagger).
+	TagTime time.Time
+}
+
+// TaggedCommits returns the commits that are pointed at (directly or
+// via annotated tags) by the references under `refs/tags/`, most
+// recently tagged first. Tags that don't peel to commits are omitted.
+func (repo *Repository) TaggedCommits() ([]TaggedCommit, error) {
+	cmd, err := repo.gitCommand(
+		"for-each-ref",
+		"--format=%(objectname) %(taggerdate:unix) %(authordate:unix) %(*authordate:unix) %(refname)",
+		"refs/tags/",
+	)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running 'git for-each-ref': %w", err)
+	}
+
+	var tags []TaggedCommit
+	var refnames []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		words := strings.SplitN(line, " ", 5)
+		if len(words) != 5 {
+			return nil, fmt.Errorf("malformed line from 'git for-each-ref': %q", line)
+		}
+		oid, err := NewOID(words[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing 'git for-each-ref' output: %w", err)
+		}
+
+		// Only one of the three timestamps is filled in for any
+		// particular reference, unless it is an annotated tag with
+		// no tagger (in which case we use the commit's):
+		var timestamp int64
+		for _, word := range words[1:4] {
+			if word == "" {
+				continue
+			}
+			timestamp, err = strconv.ParseInt(word, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp from 'git for-each-ref': %w", err)
+			}
+			break
+		}
+
+		refname := words[4]
+		tags = append(tags, TaggedCommit{
+			TagName: strings.TrimPrefix(refname, "refs/tags/"),
+			TagOID:  oid,
+			TagTime: time.Unix(timestamp, 0),
+		})
+		refnames = append(refnames, refname)
+	}
+
+	peeled, err := repo.PeeledCommits(refnames)
+	if err != nil {
+		return nil, err
+	}
+
+	taggedCommits := tags[:0]
+	for i, tag := range tags {
+		commit, ok := peeled[refnames[i]]
+		if !ok {
+			continue
+		}
+		tag.CommitOID = commit
+		taggedCommits = append(taggedCommits, tag)
+	}
+
+	sort.SliceStable(taggedCommits, func(i, j int) bool {
+		return taggedCommits[i].TagTime.After(taggedCommits[j].TagTime)
+	})
+
+	return taggedCommits, nil
+}
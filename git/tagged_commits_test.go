@@ -0,0 +1,82 @@
+package git_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestTaggedCommits(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "tagged-commits")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) (git.OID, time.Time) {
+		t.Helper()
+		commitTime := timestamp
+		cmd := testRepo.GitCommand(t, "commit-tree", "-m", msg, git.EmptyTreeOID.String())
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid, commitTime
+	}
+
+	tag := func(args ...string) time.Time {
+		t.Helper()
+		tagTime := timestamp
+		cmd := testRepo.GitCommand(t, append([]string{"tag"}, args...)...)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+		return tagTime
+	}
+
+	oldCommit, oldCommitTime := commit("old")
+	newCommit, _ := commit("new")
+
+	// A lightweight tag uses the commit's timestamp:
+	testRepo.UpdateRef(t, "refs/tags/light", oldCommit)
+
+	// An annotated tag uses the tagger's timestamp:
+	annotatedTime := tag("-a", "-m", "annotated", "annotated", newCommit.String())
+
+	// A tag of a tag peels all the way to the commit:
+	nestedTime := tag("-a", "-m", "nested", "nested", "annotated")
+
+	// A tag of a blob is ignored:
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "blob\n")
+		return err
+	})
+	testRepo.UpdateRef(t, "refs/tags/blob", blob)
+
+	repo := testRepo.Repository(t)
+
+	annotatedOID, err := repo.ResolveObject("refs/tags/annotated")
+	require.NoError(t, err)
+	nestedOID, err := repo.ResolveObject("refs/tags/nested")
+	require.NoError(t, err)
+
+	tagged, err := repo.TaggedCommits()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]git.TaggedCommit{
+			{TagName: "nested", TagOID: nestedOID, CommitOID: newCommit, TagTime: nestedTime},
+			{TagName: "annotated", TagOID: annotatedOID, CommitOID: newCommit, TagTime: annotatedTime},
+			{TagName: "light", TagOID: oldCommit, CommitOID: oldCommit, TagTime: oldCommitTime},
+		},
+		tagged,
+	)
+}
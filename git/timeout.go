@@ -0,0 +1,81 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrSubprocessTimeout is returned (wrapped) by
+// `RunGitCommandWithTimeout()` if the command didn't finish in time.
+var ErrSubprocessTimeout = errors.New("git subprocess timed out")
+
+// ErrSubprocessKilled is returned (wrapped) by the `CloseContext()`
+// methods of the iterators if their subprocesses had to be killed
+// because they didn't exit in time.
+var ErrSubprocessKilled = errors.New("git subprocess was killed")
+
+// subprocessGracePeriod is how long a timed-out subprocess is given
+// to exit after being asked to terminate before it is killed.
+var subprocessGracePeriod = 2 * time.Second
+
+// RunGitCommandWithTimeout runs `git` with the specified arguments
+// and returns its output. If the command doesn't finish within
+// `timeout`, it is asked to terminate and, if it hasn't exited after
+// a short grace period, killed, along with any processes that it has
+// started. In that case, the output that was produced before the
+// timeout is returned along with an error wrapping
+// `ErrSubprocessTimeout`.
+func (repo *Repository) RunGitCommandWithTimeout(
+	timeout time.Duration, args ...string,
+) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd, err := repo.gitCommand(args...)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+
+	description := strings.Join(args, " ")
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting 'git %s': %w", description, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitErr.Stderr = stderr.Bytes()
+			}
+			return stdout.Bytes(), fmt.Errorf("running 'git %s': %w", description, err)
+		}
+		return stdout.Bytes(), nil
+
+	case <-ctx.Done():
+		_ = terminateProcessGroup(cmd)
+		select {
+		case <-done:
+		case <-time.After(subprocessGracePeriod):
+			_ = killProcessGroup(cmd)
+			<-done
+		}
+		return stdout.Bytes(), fmt.Errorf(
+			"running 'git %s': %w after %s", description, ErrSubprocessTimeout, timeout,
+		)
+	}
+}
@@ -0,0 +1,131 @@
+package git_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestRunGitCommandWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "timeout")
+	t.Cleanup(func() { testRepo.Remove(t) })
+
+	repo := testRepo.Repository(t)
+
+	t.Run("fast", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := repo.RunGitCommandWithTimeout(
+			10*time.Second, "rev-parse", "--is-bare-repository",
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "true\n", string(out))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.RunGitCommandWithTimeout(
+			10*time.Second, "rev-parse", "--verify", "refs/heads/nonexistent",
+		)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, git.ErrSubprocessTimeout))
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("shell aliases need a POSIX shell")
+		}
+		t.Parallel()
+
+		start := time.Now()
+		out, err := repo.RunGitCommandWithTimeout(
+			200*time.Millisecond,
+			"-c", "alias.slow=!echo partial; sleep 30", "slow",
+		)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, git.ErrSubprocessTimeout))
+		assert.Equal(t, "partial\n", string(out))
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+func TestBatchObjectIterCloseContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("clean", func(t *testing.T) {
+		t.Parallel()
+
+		testRepo := testutils.NewTestRepo(t, true, "close-context-clean")
+		t.Cleanup(func() { testRepo.Remove(t) })
+
+		blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, "hello\n")
+			return err
+		})
+
+		repo := testRepo.Repository(t)
+		iter, err := repo.NewBatchObjectIter(ctx)
+		require.NoError(t, err)
+
+		// Objects that are never read are discarded:
+		for i := 0; i < 100; i++ {
+			require.NoError(t, iter.RequestObject(blob))
+		}
+
+		closeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		assert.NoError(t, iter.CloseContext(closeCtx))
+	})
+
+	t.Run("wedged", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("FIFOs need a POSIX system")
+		}
+		t.Parallel()
+
+		testRepo := testutils.NewTestRepo(t, true, "close-context-wedged")
+		t.Cleanup(func() { testRepo.Remove(t) })
+
+		repo := testRepo.Repository(t)
+
+		// Make git block while reading its configuration, by
+		// including a FIFO that nobody ever writes to:
+		fifo := filepath.Join(testRepo.Path, "wedge")
+		require.NoError(t, exec.Command("mkfifo", fifo).Run())
+		config, err := os.OpenFile(
+			filepath.Join(testRepo.Path, "config"), os.O_APPEND|os.O_WRONLY, 0,
+		)
+		require.NoError(t, err)
+		_, err = config.WriteString("[include]\n\tpath = " + fifo + "\n")
+		require.NoError(t, err)
+		require.NoError(t, config.Close())
+
+		iter, err := repo.NewBatchObjectIter(ctx)
+		require.NoError(t, err)
+
+		start := time.Now()
+		closeCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		err = iter.CloseContext(closeCtx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, git.ErrSubprocessKilled), err)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
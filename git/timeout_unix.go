@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package git
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup arranges for `cmd` to be started in a new process
+// group, so that it can be terminated along with its children.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminateProcessGroup asks the process group of `cmd` to exit.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcessGroup forcibly kills the process group of `cmd`.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package git
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows. There, only the `git`
+// process itself is killed on timeout, not any children that it has
+// started.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills `cmd`. Windows has no equivalent of
+// `SIGTERM` that works for console processes.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup kills `cmd`.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
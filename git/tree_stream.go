@@ -0,0 +1,195 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// TreeEntryIterator is implemented by the types that can iterate over
+// the entries of a tree: `*TreeIter`, which parses a tree that has
+// been read into memory, and `*TreeStream`, which parses it as it is
+// read.
+type TreeEntryIterator interface {
+	// NextEntry returns either the next entry in the tree, or a
+	// `false` boolean value if there are no more entries.
+	NextEntry() (TreeEntry, bool, error)
+}
+
+// maxFilemodeLength is the length of the longest valid filemode in a
+// tree entry (e.g., "100644"), plus one for the SP that follows it.
+const maxFilemodeLength = 7
+
+// TreeStream parses the entries of a tree directly from the output of
+// `git cat-file --batch`, so that the whole tree never has to be held
+// in memory. Unlike the entries returned by `TreeIter`, the names of
+// the entries don't share memory with each other.
+//
+// A `TreeStream` is only valid until the `BatchObjectIter` that
+// returned it is advanced.
+type TreeStream struct {
+	r *bufio.Reader
+
+	// remaining is the number of bytes of the tree that haven't been
+	// read yet.
+	remaining int64
+
+	// err is set if an entry couldn't be parsed. It is returned by
+	// all later calls to `NextEntry()`.
+	err error
+
+	// ioErr is set if reading failed, in which case the batch output
+	// is no longer framed correctly. (Malformed entries are detected
+	// without reading past the end of the tree, so they don't affect
+	// the framing.)
+	ioErr error
+
+	closed bool
+	done   chan struct{}
+}
+
+func newTreeStream(r *bufio.Reader, size int64) *TreeStream {
+	return &TreeStream{
+		r:         r,
+		remaining: size,
+		done:      make(chan struct{}),
+	}
+}
+
+// NextEntry returns either the next entry in the tree, or a `false`
+// boolean value if there are no more entries.
+func (s *TreeStream) NextEntry() (TreeEntry, bool, error) {
+	if s.closed {
+		return TreeEntry{}, false, errors.New("tree stream has already been closed")
+	}
+	if s.err != nil {
+		return TreeEntry{}, false, s.err
+	}
+	if s.remaining == 0 {
+		return TreeEntry{}, false, nil
+	}
+
+	entry, err := s.readEntry()
+	if err != nil {
+		s.err = err
+		return TreeEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (s *TreeStream) readEntry() (TreeEntry, error) {
+	var entry TreeEntry
+
+	// The filemode is short, so it is safe to peek at it without
+	// reading past the end of the tree:
+	peekLen := maxFilemodeLength
+	if int64(peekLen) > s.remaining {
+		peekLen = int(s.remaining)
+	}
+	buf, err := s.r.Peek(peekLen)
+	if err != nil {
+		return TreeEntry{}, s.ioError(err)
+	}
+	spAt := -1
+	for i, c := range buf {
+		if c == ' ' {
+			spAt = i
+			break
+		}
+	}
+	if spAt < 0 {
+		return TreeEntry{}, errors.New("failed to find SP after mode")
+	}
+	mode, err := strconv.ParseUint(string(buf[:spAt]), 8, 32)
+	if err != nil {
+		return TreeEntry{}, err
+	}
+	entry.Filemode = uint(mode)
+	if err := s.discard(int64(spAt + 1)); err != nil {
+		return TreeEntry{}, err
+	}
+
+	// Read the name one buffer at a time, so that we don't read past
+	// the end of the tree if it is malformed:
+	var name []byte
+	for {
+		peekLen := s.r.Size()
+		if int64(peekLen) > s.remaining {
+			peekLen = int(s.remaining)
+		}
+		if peekLen == 0 {
+			return TreeEntry{}, errors.New("failed to find NUL after filename")
+		}
+		buf, err := s.r.Peek(peekLen)
+		if err != nil {
+			return TreeEntry{}, s.ioError(err)
+		}
+		nulAt := -1
+		for i, c := range buf {
+			if c == 0 {
+				nulAt = i
+				break
+			}
+		}
+		if nulAt >= 0 {
+			name = append(name, buf[:nulAt]...)
+			if err := s.discard(int64(nulAt + 1)); err != nil {
+				return TreeEntry{}, err
+			}
+			break
+		}
+		name = append(name, buf...)
+		if err := s.discard(int64(len(buf))); err != nil {
+			return TreeEntry{}, err
+		}
+	}
+	entry.Name = string(name)
+
+	if s.remaining < 20 {
+		return TreeEntry{}, errors.New("tree entry ends unexpectedly")
+	}
+	if _, err := io.ReadFull(s.r, entry.OID.v[0:20]); err != nil {
+		return TreeEntry{}, s.ioError(err)
+	}
+	s.remaining -= 20
+
+	return entry, nil
+}
+
+// discard skips `n` bytes, which must already be buffered.
+func (s *TreeStream) discard(n int64) error {
+	if _, err := s.r.Discard(int(n)); err != nil {
+		return s.ioError(err)
+	}
+	s.remaining -= n
+	return nil
+}
+
+// ioError records that reading the tree failed with `err`.
+func (s *TreeStream) ioError(err error) error {
+	s.ioErr = fmt.Errorf("reading tree entry: %w", err)
+	return s.ioErr
+}
+
+// Close skips any entries that haven't been read yet and allows the
+// `BatchObjectIter` that returned `s` to continue. It is called
+// automatically by the iterator's `Next()` method, so callers only
+// have to call it if they want to release the iterator earlier.
+// Calling it more than once is harmless.
+func (s *TreeStream) Close() error {
+	if s.closed {
+		return s.ioErr
+	}
+	s.closed = true
+	if s.ioErr == nil && s.remaining > 0 {
+		if _, err := s.r.Discard(int(s.remaining)); err != nil {
+			s.ioErr = fmt.Errorf("skipping the rest of a tree: %w", err)
+		} else {
+			s.remaining = 0
+		}
+	}
+	close(s.done)
+	return s.ioErr
+}
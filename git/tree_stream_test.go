@@ -0,0 +1,127 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestTreeStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "tree-stream")
+	defer testRepo.Remove(t)
+
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "hello\n")
+		return err
+	})
+
+	// A tree with many entries, one of which has a name that is
+	// longer than the read buffer:
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("100644 blob %s\tfile-%04d", blob, i))
+	}
+	lines = append(lines, fmt.Sprintf("100644 blob %s\t%s", blob, strings.Repeat("x", 10000)))
+	cmd := testRepo.GitCommand(t, "mktree")
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	bigTree, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+
+	// A tree whose last entry has no NUL after its name:
+	cmd = testRepo.GitCommand(t, "hash-object", "-w", "-t", "tree", "--literally", "--stdin")
+	cmd.Stdin = strings.NewReader("100644 oops")
+	out, err = cmd.Output()
+	require.NoError(t, err)
+	badTree, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+
+	read := func(opts git.BatchObjectIterOptions, fn func(obj git.ObjectRecord)) {
+		t.Helper()
+
+		repo := testRepo.Repository(t)
+		iter, err := repo.NewBatchObjectIterWithOptions(ctx, opts)
+		require.NoError(t, err)
+
+		go func() {
+			defer iter.Close()
+			for _, oid := range []git.OID{bigTree, badTree, bigTree, blob} {
+				if err := iter.RequestObject(oid); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			obj, ok, err := iter.Next()
+			require.NoError(t, err)
+			if !ok {
+				return
+			}
+			fn(obj)
+		}
+	}
+
+	entries := func(iter git.TreeEntryIterator) ([]git.TreeEntry, error) {
+		var entries []git.TreeEntry
+		for {
+			entry, ok, err := iter.NextEntry()
+			if err != nil {
+				return entries, err
+			}
+			if !ok {
+				return entries, nil
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	// Read the trees the usual way:
+	var expected []git.TreeEntry
+	read(git.BatchObjectIterOptions{}, func(obj git.ObjectRecord) {
+		assert.Nil(t, obj.Tree)
+		if obj.OID == bigTree && expected == nil {
+			tree, err := git.ParseTree(obj.OID, obj.Data)
+			require.NoError(t, err)
+			expected, err = entries(tree.Iter())
+			require.NoError(t, err)
+		}
+	})
+	require.Len(t, expected, 1001)
+
+	// Now stream them. The entries of the second copy of the big
+	// tree aren't read at all; `Next()` has to skip them:
+	var oids []git.OID
+	read(git.BatchObjectIterOptions{StreamTrees: true}, func(obj git.ObjectRecord) {
+		oids = append(oids, obj.OID)
+		switch {
+		case obj.ObjectType != "tree":
+			assert.Nil(t, obj.Tree)
+			assert.Equal(t, "hello\n", string(obj.Data))
+		case len(oids) == 1:
+			require.NotNil(t, obj.Tree)
+			assert.Nil(t, obj.Data)
+			streamed, err := entries(obj.Tree)
+			require.NoError(t, err)
+			assert.Equal(t, expected, streamed)
+			assert.NoError(t, obj.Tree.Close())
+		case obj.OID == badTree:
+			_, err := entries(obj.Tree)
+			assert.EqualError(t, err, "failed to find NUL after filename")
+		}
+	})
+	assert.Equal(t, []git.OID{bigTree, badTree, bigTree, blob}, oids)
+}
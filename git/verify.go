@@ -0,0 +1,129 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 is what Git uses for object names.
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/github/go-pipe/pipe"
+)
+
+// OIDMismatch describes an object whose contents don't hash to its
+// OID; i.e., an object that is corrupt.
+type OIDMismatch struct {
+	// OID is the name under which the object is stored.
+	OID OID `json:"oid"`
+
+	// ObjectType is the type of the object.
+	ObjectType ObjectType `json:"object_type"`
+
+	// ComputedOID is the name that the object's contents hash to.
+	ComputedOID OID `json:"computed_oid"`
+}
+
+// newObjectHasher returns a hash that has been fed the header that Git
+// puts before the contents of an object with the specified type and
+// size when computing its name.
+func newObjectHasher(objectType ObjectType, size uint64) hash.Hash {
+	h := sha1.New() //nolint:gosec // SHA-1 is what Git uses for object names.
+	fmt.Fprintf(h, "%s %d\x00", objectType, size)
+	return h
+}
+
+func oidFromHasher(h hash.Hash) OID {
+	var oid OID
+	copy(oid.v[:], h.Sum(nil))
+	return oid
+}
+
+// ComputeOID returns the name of an object with the specified type and
+// contents, computed the way Git computes it. Since `OID` only supports
+// SHA-1, so does this function.
+func ComputeOID(objectType ObjectType, data []byte) OID {
+	h := newObjectHasher(objectType, uint64(len(data)))
+	h.Write(data)
+	return oidFromHasher(h)
+}
+
+// VerifyObjects reads the objects `oids` from `repo`, rehashes their
+// contents, and returns those whose contents don't hash to their
+// OIDs. Git doesn't check this when it reads objects, so this catches
+// corruption that would otherwise go unnoticed. The objects are
+// streamed, so even big blobs don't have to fit in memory.
+func (repo *Repository) VerifyObjects(ctx context.Context, oids []OID) ([]OIDMismatch, error) {
+	if len(oids) == 0 {
+		return nil, nil
+	}
+
+	var mismatches []OIDMismatch
+
+	p := pipe.New()
+	p.Add(
+		pipe.Function(
+			"request-objects",
+			func(_ context.Context, _ pipe.Env, _ io.Reader, stdout io.Writer) error {
+				out := bufio.NewWriter(stdout)
+				for _, oid := range oids {
+					if _, err := fmt.Fprintln(out, oid.String()); err != nil {
+						return fmt.Errorf("writing to 'git cat-file': %w", err)
+					}
+				}
+				return out.Flush()
+			},
+		),
+
+		pipe.CommandStage(
+			"git-cat-file",
+			repo.GitCommand("cat-file", "--batch", "--buffer"),
+		),
+
+		pipe.Function(
+			"verify-objects",
+			func(_ context.Context, _ pipe.Env, stdin io.Reader, _ io.Writer) error {
+				f := bufio.NewReader(stdin)
+				for {
+					header, err := f.ReadString('\n')
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+					batchHeader, err := ParseBatchHeader("", header)
+					if err != nil {
+						return fmt.Errorf("parsing output of 'git cat-file': %w", err)
+					}
+
+					size := uint64(batchHeader.ObjectSize)
+					h := newObjectHasher(batchHeader.ObjectType, size)
+					if _, err := io.CopyN(h, f, int64(size)); err != nil {
+						return fmt.Errorf(
+							"reading object data from 'git cat-file' for %s '%s': %w",
+							batchHeader.ObjectType, batchHeader.OID, err,
+						)
+					}
+					if _, err := f.Discard(1); err != nil {
+						return fmt.Errorf("reading from 'git cat-file': %w", err)
+					}
+
+					if computed := oidFromHasher(h); computed != batchHeader.OID {
+						mismatches = append(mismatches, OIDMismatch{
+							OID:         batchHeader.OID,
+							ObjectType:  batchHeader.ObjectType,
+							ComputedOID: computed,
+						})
+					}
+				}
+			},
+		),
+	)
+
+	if err := p.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	return mismatches, nil
+}
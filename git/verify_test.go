@@ -0,0 +1,74 @@
+package git_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestComputeOID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, git.EmptyBlobOID, git.ComputeOID("blob", nil))
+	assert.Equal(t, git.EmptyTreeOID, git.ComputeOID("tree", nil))
+}
+
+func TestVerifyObjects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "verify-objects")
+	defer testRepo.Remove(t)
+
+	createBlob := func(contents string) git.OID {
+		return testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+	}
+
+	goodOID := createBlob("good\n")
+	badOID := createBlob("original\n")
+
+	repo := testRepo.Repository(t)
+
+	mismatches, err := repo.VerifyObjects(ctx, []git.OID{goodOID, badOID})
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// Replace the contents of the loose object with something else of
+	// the same size:
+	path, err := repo.LooseObjectPath(badOID)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err = io.WriteString(zw, "blob 9\x00tampered\n")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.Chmod(path, 0o644))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	mismatches, err = repo.VerifyObjects(ctx, []git.OID{goodOID, badOID})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]git.OIDMismatch{
+			{
+				OID:         badOID,
+				ObjectType:  "blob",
+				ComputedOID: git.ComputeOID("blob", []byte("tampered\n")),
+			},
+		},
+		mismatches,
+	)
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// CommonDir returns the path of `repo`'s common directory, which
+// holds the object store and the references that are shared among
+// all of its worktrees, by calling `git rev-parse --git-common-dir`.
+// For a repository that was opened via a linked worktree, this is
+// the `GIT_DIR` of the main repository; otherwise, it is the same as
+// `GitDir()`. The returned path might be absolute or it might be
+// relative to the current directory.
+//
+// Code that needs a path within the repository should use
+// `GitPath()`, which knows which files are per-worktree and which
+// live in the common directory.
+func (repo *Repository) CommonDir() (string, error) {
+	cmd, err := repo.gitCommand("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("running 'git rev-parse --git-common-dir': %w", err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// IsLinkedWorktree returns true iff `repo` was opened via a linked
+// worktree (one created by `git worktree add`), whose `GIT_DIR` is
+// separate from the repository's common directory.
+func (repo *Repository) IsLinkedWorktree() (bool, error) {
+	commonDir, err := repo.CommonDir()
+	if err != nil {
+		return false, err
+	}
+
+	gitDir, err := canonicalPath(repo.gitDir)
+	if err != nil {
+		return false, err
+	}
+	commonDir, err = canonicalPath(commonDir)
+	if err != nil {
+		return false, err
+	}
+
+	return gitDir != commonDir, nil
+}
+
+// canonicalPath returns an absolute version of `path` with any
+// symbolic links resolved, so that two paths can be compared.
+func canonicalPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
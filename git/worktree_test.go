@@ -0,0 +1,100 @@
+package git_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/internal/testutils"
+)
+
+func TestLinkedWorktree(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "linked-worktree")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	// Some packed objects, some loose ones, and a symbolic reference:
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	commit("initial")
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adb").Run())
+	testRepo.AddFile(t, "b.txt", "Goodbye, world!\n")
+	commit("second")
+	require.NoError(t, testRepo.GitCommand(
+		t, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/master",
+	).Run())
+
+	worktree := testRepo.AddWorktree(t, "linked-worktree-wt")
+	defer worktree.Remove(t)
+
+	main := testRepo.Repository(t)
+	linked := worktree.Repository(t)
+
+	isLinked, err := main.IsLinkedWorktree()
+	require.NoError(t, err)
+	assert.False(t, isLinked)
+
+	isLinked, err = linked.IsLinkedWorktree()
+	require.NoError(t, err)
+	assert.True(t, isLinked)
+
+	mainCommonDir, err := main.CommonDir()
+	require.NoError(t, err)
+	linkedCommonDir, err := linked.CommonDir()
+	require.NoError(t, err)
+	mainCommonDir, err = filepath.EvalSymlinks(mainCommonDir)
+	require.NoError(t, err)
+	linkedCommonDir, err = filepath.EvalSymlinks(linkedCommonDir)
+	require.NoError(t, err)
+	assert.Equal(t, mainCommonDir, linkedCommonDir)
+
+	// Every analysis that looks at the filesystem must see the
+	// shared object store and references:
+	mainPacks, err := main.Packfiles()
+	require.NoError(t, err)
+	require.NotEmpty(t, mainPacks)
+	linkedPacks, err := linked.Packfiles()
+	require.NoError(t, err)
+	require.Len(t, linkedPacks, len(mainPacks))
+	for i := range mainPacks {
+		assert.Equal(t, filepath.Base(mainPacks[i].Path), filepath.Base(linkedPacks[i].Path))
+		assert.Equal(t, mainPacks[i].DiskSize, linkedPacks[i].DiskSize)
+	}
+
+	mainLoose, err := main.LooseObjects()
+	require.NoError(t, err)
+	require.NotEmpty(t, mainLoose)
+	linkedLoose, err := linked.LooseObjects()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, mainLoose, linkedLoose)
+
+	mainState, err := main.PackState()
+	require.NoError(t, err)
+	assert.True(t, mainState.HasBitmap)
+	linkedState, err := linked.PackState()
+	require.NoError(t, err)
+	assert.Equal(t, mainState, linkedState)
+
+	mainSymrefs, err := main.LooseSymbolicReferences()
+	require.NoError(t, err)
+	require.NotEmpty(t, mainSymrefs)
+	linkedSymrefs, err := linked.LooseSymbolicReferences()
+	require.NoError(t, err)
+	assert.Equal(t, mainSymrefs, linkedSymrefs)
+
+	full, err := linked.IsFull()
+	require.NoError(t, err)
+	assert.True(t, full)
+}
@@ -2,23 +2,36 @@ package main_test
 
 import (
 	"bytes"
+	"compress/zlib"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/github/git-sizer/counts"
 	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/refopts"
 	"github.com/github/git-sizer/internal/testutils"
 	"github.com/github/git-sizer/meter"
 	"github.com/github/git-sizer/sizes"
@@ -51,6 +64,79 @@ func TestExec(t *testing.T) {
 	assert.NoErrorf(t, err, "command failed; output: %#v", string(output))
 }
 
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "capabilities")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "README", "Hello, world!\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	run := func(args ...string) []byte {
+		t.Helper()
+		cmd := exec.Command(sizerExe(t), args...)
+		cmd.Dir = testRepo.Path
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		require.NoError(t, cmd.Run(), "running git-sizer %v", args)
+		return stdout.Bytes()
+	}
+
+	var capabilities struct {
+		Version       string   `json:"version"`
+		OutputFormats []string `json:"output_formats"`
+		JSONVersions  []int    `json:"json_versions"`
+		Options       []string `json:"options"`
+		Metrics       []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal(run("--capabilities"), &capabilities))
+
+	assert.ElementsMatch(
+		t,
+		[]string{"table", "compact", "json", "github-annotations", "metric"},
+		capabilities.OutputFormats,
+	)
+	assert.Equal(t, []int{1, 2}, capabilities.JSONVersions)
+
+	// Every advertised JSON version can be output:
+	for _, v := range capabilities.JSONVersions {
+		var report map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(
+			run("-j", fmt.Sprintf("--json-version=%d", v), "--no-progress"), &report,
+		), "JSON version %d", v)
+	}
+	assert.Contains(t, capabilities.Options, "json-version")
+	assert.Contains(t, capabilities.Options, "capabilities")
+	assert.NotContains(t, capabilities.Options, "dump-state")
+
+	metrics := make(map[string]bool)
+	for _, m := range capabilities.Metrics {
+		assert.NotEmpty(t, m.Description, m.Name)
+		metrics[m.Name] = true
+	}
+
+	// Every metric in a full report is listed:
+	var report map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(
+		run("-j", "--json-version=2", "-v", "--no-progress"), &report,
+	))
+	for name := range report {
+		if name == "repository" || name == "labels" {
+			continue
+		}
+		assert.Truef(t, metrics[name], "metric %q is missing from --capabilities", name)
+	}
+	assert.True(t, metrics["refgroup.tags"])
+}
+
 func newGitBomb(t *testing.T, repo *testutils.TestRepo, depth, breadth int, body string) {
 	t.Helper()
 
@@ -597,6 +683,7 @@ func TestBomb(t *testing.T) {
 		assert.Equal(t, counts.Count32(10), h.UniqueTreeCount, "unique tree count")
 		assert.Equal(t, counts.Count64(2910), h.UniqueTreeSize, "unique tree size")
 		assert.Equal(t, counts.Count64(100), h.UniqueTreeEntries, "unique tree entries")
+		assert.Equal(t, counts.Count64(101), h.EdgeCount, "edge count")
 		assert.Equal(t, counts.Count32(10), h.MaxTreeEntries, "max tree entries")
 		assert.Equal(t, "refs/heads/master:d0/d0/d0/d0/d0/d0/d0/d0/d0", h.MaxTreeEntriesTree.BestPath(), "max tree entries tree")
 
@@ -604,10 +691,15 @@ func TestBomb(t *testing.T) {
 		assert.Equal(t, counts.Count64(6), h.UniqueBlobSize, "unique blob size")
 		assert.Equal(t, counts.Count32(6), h.MaxBlobSize, "max blob size")
 		assert.Equal(t, "refs/heads/master:d0/d0/d0/d0/d0/d0/d0/d0/d0/f0", h.MaxBlobSizeBlob.BestPath(), "max blob size blob")
+		assert.Equal(t, counts.Count64(60), h.ReferencedBlobSize, "referenced blob size")
+		assert.Equal(t, 10.0, h.DedupRatio(), "dedup ratio")
 
 		assert.Equal(t, counts.Count32(0), h.UniqueTagCount, "unique tag count")
+		assert.Equal(t, counts.Count64(0), h.UniqueTagSize, "unique tag size")
 		assert.Equal(t, counts.Count32(0), h.MaxTagDepth, "max tag depth")
 
+		assert.Equal(t, counts.Count64(172+2910+6), h.TotalObjectDataSize(), "total object data size")
+
 		assert.Equal(t, counts.Count32(1), h.ReferenceCount, "reference count")
 
 		assert.Equal(t, counts.Count32(10), h.MaxPathDepth, "max path depth")
@@ -625,6 +717,10 @@ func TestBomb(t *testing.T) {
 		assert.Nil(t, h.MaxExpandedLinkCountTree, "max expanded link count tree")
 		assert.Equal(t, counts.Count32(0), h.MaxExpandedSubmoduleCount, "max expanded submodule count")
 		assert.Nil(t, h.MaxExpandedSubmoduleCountTree, "max expanded submodule count tree")
+		assert.Equal(t, counts.Count32(0xffffffff), h.MaxPathCount, "max path count")
+		assert.Equal(t, "refs/heads/master^{tree}", h.MaxPathCountTree.BestPath(), "max path count tree")
+		assert.Equal(t, counts.Count32(pow(10, 9)), h.MaxTreeWidth, "max tree width")
+		assert.Equal(t, "refs/heads/master^{tree}", h.MaxTreeWidthTree.BestPath(), "max tree width tree")
 	})
 
 	t.Run("partial", func(t *testing.T) {
@@ -649,6 +745,7 @@ func TestBomb(t *testing.T) {
 		assert.Equal(t, counts.Count32(8), h.UniqueTreeCount, "unique tree count")
 		assert.Equal(t, counts.Count64(2330), h.UniqueTreeSize, "unique tree size")
 		assert.Equal(t, counts.Count64(80), h.UniqueTreeEntries, "unique tree entries")
+		assert.Equal(t, counts.Count64(80), h.EdgeCount, "edge count")
 		assert.Equal(t, counts.Count32(10), h.MaxTreeEntries, "max tree entries")
 		assert.Equal(t, "master:d0/d0/d0/d0/d0/d0/d0/d0/d0", h.MaxTreeEntriesTree.BestPath(), "max tree entries tree")
 
@@ -677,6 +774,10 @@ func TestBomb(t *testing.T) {
 		assert.Nil(t, h.MaxExpandedLinkCountTree, "max expanded link count tree")
 		assert.Equal(t, counts.Count32(0), h.MaxExpandedSubmoduleCount, "max expanded submodule count")
 		assert.Nil(t, h.MaxExpandedSubmoduleCountTree, "max expanded submodule count tree")
+		assert.Equal(t, counts.Count32(pow(10, 8)), h.MaxPathCount, "max path count")
+		assert.Equal(t, "master:d0/d0", h.MaxPathCountTree.BestPath(), "max path count tree")
+		assert.Equal(t, counts.Count32(pow(10, 7)), h.MaxTreeWidth, "max tree width")
+		assert.Equal(t, "master:d0/d0", h.MaxTreeWidthTree.BestPath(), "max tree width tree")
 	})
 }
 
@@ -724,6 +825,16 @@ func TestTaggedTags(t *testing.T) {
 	)
 	require.NoError(t, err, "scanning repository")
 	assert.Equal(t, counts.Count32(3), h.MaxTagDepth, "tag depth")
+
+	var tagSize counts.Count64
+	for _, tag := range []string{"tag", "bag", "wag"} {
+		out, err := testRepo.GitCommand(t, "cat-file", "-s", "refs/tags/"+tag).Output()
+		require.NoError(t, err)
+		size, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+		require.NoError(t, err)
+		tagSize.Increment(counts.Count64(size))
+	}
+	assert.Equal(t, tagSize, h.UniqueTagSize, "unique tag size")
 }
 
 func TestFromSubdir(t *testing.T) {
@@ -825,6 +936,7 @@ func TestSubmodule(t *testing.T) {
 	assert.Equal(t, counts.Count32(2), h.UniqueBlobCount, "unique blob count")
 	assert.Equal(t, counts.Count32(2), h.MaxExpandedBlobCount, "max expanded blob count")
 	assert.Equal(t, counts.Count32(1), h.MaxExpandedSubmoduleCount, "max expanded submodule count")
+	assert.Equal(t, counts.Count32(3), h.MaxPathCount, "max path count")
 
 	// Analyze the submodule:
 	submTestRepo2 := testutils.TestRepo{
@@ -849,3 +961,5087 @@ func TestSubmodule(t *testing.T) {
 	assert.Equal(t, counts.Count32(2), h.UniqueBlobCount, "unique blob count")
 	assert.Equal(t, counts.Count32(3), h.MaxExpandedBlobCount, "max expanded blob count")
 }
+
+func TestRepositoryConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "repository-config")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial", "--allow-empty")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	require.NoError(t, testRepo.GitCommand(t, "branch", "other").Run())
+
+	// Delete the branch that `HEAD` points at:
+	require.NoError(t, testRepo.GitCommand(t, "update-ref", "-d", "refs/heads/master").Run())
+
+	// Create one valid and one dangling symbolic reference:
+	require.NoError(t, testRepo.GitCommand(
+		t, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/heads/other",
+	).Run())
+	require.NoError(t, testRepo.GitCommand(
+		t, "symbolic-ref", "refs/remotes/upstream/HEAD", "refs/remotes/upstream/gone",
+	).Run())
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	// The check is opt-in:
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+	assert.Nil(t, h.RepositoryConfig)
+
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone, CheckSymbolicRefs: true},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	require.NotNil(t, h.RepositoryConfig)
+	rc := h.RepositoryConfig
+	assert.Equal(t, "refs/heads/master", rc.DefaultBranch, "default branch")
+	assert.Equal(t, counts.Count32(1), rc.DefaultBranchMissing, "default branch missing")
+	assert.Equal(t, counts.Count32(0), rc.DetachedHead, "detached HEAD")
+	assert.Equal(t, counts.Count32(2), rc.SymbolicRefCount, "symbolic ref count")
+	assert.Equal(t, counts.Count32(1), rc.DanglingSymbolicRefCount, "dangling symbolic ref count")
+	assert.Equal(
+		t,
+		[]git.SymbolicReference{
+			{Refname: "refs/remotes/upstream/HEAD", Target: "refs/remotes/upstream/gone"},
+		},
+		rc.DanglingSymbolicRefs,
+	)
+}
+
+func TestExtensions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "extensions")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// Two blobs of the same size with the same extension; the one
+	// with the lower OID should be chosen as the largest:
+	testRepo.AddFile(t, "a.bin", "same-size-1\n")
+	testRepo.AddFile(t, "dir/b.BIN", "same-size-2\n")
+	testRepo.AddFile(t, "small.bin", "x\n")
+	testRepo.AddFile(t, "readme.txt", "Hello, world!\n")
+	testRepo.AddFile(t, "Makefile", "all:\n")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	oidA, err := repo.ResolveObject("HEAD:a.bin")
+	require.NoError(t, err)
+	oidB, err := repo.ResolveObject("HEAD:dir/b.BIN")
+	require.NoError(t, err)
+
+	expectedOID, expectedPath := oidA, "refs/heads/master:a.bin"
+	if oidB.String() < oidA.String() {
+		expectedOID, expectedPath = oidB, "refs/heads/master:dir/b.BIN"
+	}
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	require.Len(t, h.Extensions, 3)
+
+	bin := h.Extensions[".bin"]
+	require.NotNil(t, bin)
+	assert.Equal(t, counts.Count32(3), bin.BlobCount, "blob count")
+	assert.Equal(t, counts.Count64(26), bin.BlobSize, "blob size")
+	assert.Equal(t, counts.Count32(12), bin.MaxBlobSize, "max blob size")
+	assert.Equal(t, expectedOID, bin.MaxBlobOID, "max blob OID")
+	assert.Equal(t, expectedPath, bin.MaxBlob.BestPath(), "max blob path")
+
+	txt := h.Extensions[".txt"]
+	require.NotNil(t, txt)
+	assert.Equal(t, counts.Count32(1), txt.BlobCount, "blob count")
+	assert.Equal(t, counts.Count32(14), txt.MaxBlobSize, "max blob size")
+
+	none := h.Extensions[""]
+	require.NotNil(t, none)
+	assert.Equal(t, counts.Count32(1), none.BlobCount, "blob count")
+
+	assert.Equal(t, counts.Count32(3), h.DistinctExtensions)
+	assert.Equal(t, ".bin", h.DominantExtension)
+	assert.InDelta(t, 0.6, h.DominantExtensionShare, 1e-9)
+}
+
+func TestMinDedupBlobSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "min-dedup-blob-size")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	big := strings.Repeat("This line makes the blob big.\n", 10)
+	testRepo.AddFile(t, "small.txt", "x\n")
+	testRepo.AddFile(t, "copy.txt", "x\n")
+	testRepo.AddFile(t, "big.txt", big)
+	testRepo.AddFile(t, "dir/big.txt", big)
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	scan := func(minDedupBlobSize counts.Count32) *sizes.ExtStats {
+		t.Helper()
+		h, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, roots,
+			sizes.ScanOptions{NameStyle: sizes.NameStyleNone, MinDedupBlobSize: minDedupBlobSize},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err, "scanning repository")
+		txt := h.Extensions[".txt"]
+		require.NotNil(t, txt)
+		return txt
+	}
+
+	txt := scan(0)
+	assert.Equal(t, counts.Count32(2), txt.BlobCount)
+	assert.Equal(t, counts.Count64(2+len(big)), txt.BlobSize)
+
+	// The small blob is counted for each of its names, but the big
+	// one is still counted once:
+	txt = scan(10)
+	assert.Equal(t, counts.Count32(3), txt.BlobCount)
+	assert.Equal(t, counts.Count64(2+2+len(big)), txt.BlobSize)
+	assert.Equal(t, counts.Count32(len(big)), txt.MaxBlobSize)
+}
+
+func TestLFSPatterns(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "lfs-patterns")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	binary := func(n int) string {
+		return strings.Repeat("\x00\x01\x02\x03", n/4)
+	}
+
+	testRepo.AddFile(t, ".gitattributes", ""+
+		"[attr]lfs filter=lfs diff=lfs merge=lfs -text\n"+
+		"*.bin lfs\n"+
+		"\"assets/with space/*.png\" filter=lfs\n"+
+		"!*.dat filter=lfs\n"+
+		"*.txt -filter\n",
+	)
+	testRepo.AddFile(t, "pointer.bin", ""+
+		"version https://git-lfs.github.com/spec/v1\n"+
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n"+
+		"size 12345\n",
+	)
+	testRepo.AddFile(t, "straggler.bin", binary(20000))
+	testRepo.AddFile(t, "assets/with space/logo.png", binary(16000))
+	testRepo.AddFile(t, "data/huge.dat", binary(30000))
+	testRepo.AddFile(t, "notes.txt", strings.Repeat("text\n", 10000))
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	report, err := sizes.AnalyzeLFS(context.Background(), repo, sizes.LFSOptions{Tip: "HEAD", TopN: 10})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"*.bin", "assets/with space/*.png"}, report.Patterns)
+
+	var untracked []string
+	for _, blob := range report.Untracked {
+		untracked = append(untracked, blob.Path)
+	}
+	assert.Equal(t, []string{"straggler.bin", "assets/with space/logo.png"}, untracked)
+
+	require.Len(t, report.Unmatched, 1)
+	assert.Equal(t, "data/huge.dat", report.Unmatched[0].Path)
+	assert.Equal(t, counts.Count32(30000), report.Unmatched[0].Size)
+
+	// With a smaller `TopN`, only the biggest blobs are checked:
+	report, err = sizes.AnalyzeLFS(context.Background(), repo, sizes.LFSOptions{Tip: "HEAD", TopN: 3})
+	require.NoError(t, err)
+	assert.Len(t, report.Untracked, 1)
+	assert.Len(t, report.Unmatched, 1)
+}
+
+func TestFileCacheBackend(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "file-cache")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	testRepo.AddFile(t, "dir/b.txt", "Goodbye\n")
+	testRepo.AddFile(t, "dir/sub/c.txt", "Hmmm\n")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	treeOID, err := repo.ResolveObject("HEAD^{tree}")
+	require.NoError(t, err)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "tree-sizes.json")
+
+	scan := func() (sizes.HistorySize, sizes.TreeSize, bool) {
+		backend, err := sizes.OpenFileCacheBackend(cachePath)
+		require.NoError(t, err)
+
+		h, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, roots,
+			sizes.ScanOptions{NameStyle: sizes.NameStyleFull, TreeSizes: backend},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err, "scanning repository")
+
+		ts, ok := backend.Get(treeOID)
+		assert.Equal(t, 3, backend.Len())
+		require.NoError(t, backend.Close())
+		return h, ts, ok
+	}
+
+	// The largest tree object in the checkout:
+	var maxTreeSize counts.Count32
+	for _, name := range []string{"HEAD^{tree}", "HEAD:dir", "HEAD:dir/sub"} {
+		out, err := testRepo.GitCommand(t, "cat-file", "-s", name).Output()
+		require.NoError(t, err)
+		n, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 32)
+		require.NoError(t, err)
+		maxTreeSize.AdjustMaxIfNecessary(counts.NewCount32(n))
+	}
+
+	h, ts, ok := scan()
+	require.True(t, ok)
+	assert.Equal(t, counts.Count32(3), ts.PathCount)
+	assert.Equal(t, maxTreeSize, ts.MaxTreeSerializedSize)
+	assert.Equal(t, counts.Count32(3), h.UniqueTreeCount)
+
+	// The second scan gets the tree sizes from the cache file, but
+	// the statistics about the distinct trees must be the same:
+	cached, cachedTS, ok := scan()
+	require.True(t, ok)
+	assert.Equal(t, ts, cachedTS)
+	assert.Equal(t, counts.Count32(1), cached.UniqueCommitCount)
+	assert.Equal(t, h.UniqueTreeCount, cached.UniqueTreeCount)
+	assert.Equal(t, h.UniqueTreeEntries, cached.UniqueTreeEntries)
+	assert.Equal(t, h.MaxTreeEntries, cached.MaxTreeEntries)
+	assert.NotNil(t, cached.MaxTreeEntriesTree)
+	require.NotNil(t, cached.MaxPathDepthTree)
+	assert.Equal(t, h.MaxPathDepthTree.Path(), cached.MaxPathDepthTree.Path())
+	assert.Equal(t, h.ReferencedBlobSize, cached.ReferencedBlobSize)
+	assert.Equal(t, h.EntryTypes, cached.EntryTypes)
+	assert.Equal(t, h.ObjectTypeBreakdown(), cached.ObjectTypeBreakdown())
+	require.Contains(t, cached.Extensions, ".txt")
+	assert.Equal(t, h.Extensions[".txt"].BlobSize, cached.Extensions[".txt"].BlobSize)
+
+	// A cache file in another format (here, that of the first
+	// version, which had no version number) is rejected:
+	require.NoError(t, os.WriteFile(
+		cachePath, []byte(fmt.Sprintf(`{"%s": {"path_count": 3}}`, treeOID)), 0o644,
+	))
+	_, err = sizes.OpenFileCacheBackend(cachePath)
+	assert.ErrorIs(t, err, sizes.ErrFileCacheVersion)
+
+	// The cache files contain `TreeSize`s, so if its fields change,
+	// update this list and increase `FileCacheFormatVersion`:
+	var fields []string
+	treeSizeType := reflect.TypeOf(sizes.TreeSize{})
+	for i := 0; i < treeSizeType.NumField(); i++ {
+		f := treeSizeType.Field(i)
+		fields = append(fields, f.Name+" "+f.Type.String()+" "+f.Tag.Get("json"))
+	}
+	assert.Equal(t, 2, sizes.FileCacheFormatVersion)
+	assert.Equal(
+		t,
+		[]string{
+			"MaxPathDepth counts.Count32 max_path_depth",
+			"MaxPathLength counts.Count32 max_path_length",
+			"ExpandedTreeCount counts.Count32 expanded_tree_count",
+			"ExpandedBlobCount counts.Count32 expanded_blob_count",
+			"ExpandedBlobSize counts.Count64 expanded_blob_size",
+			"ExpandedLinkCount counts.Count32 expanded_link_count",
+			"ExpandedSubmoduleCount counts.Count32 expanded_submodule_count",
+			"ExpandedUnixSocketCount counts.Count32 expanded_unix_socket_count",
+			"ExpandedUnknownFiletypeCount counts.Count32 expanded_unknown_filetype_count",
+			"PathCount counts.Count32 path_count",
+			"LayerWidths []counts.Count32 layer_widths,omitempty",
+			"MaxTreeSerializedSize counts.Count32 max_tree_serialized_size",
+			"Saturated sizes.SaturatedCounts saturated,omitempty",
+			"ExemplarMap sizes.ExemplarMap -",
+		},
+		fields,
+	)
+}
+
+func TestLongFilenames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "long-filenames")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	blobOID := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "contents\n")
+		return err
+	})
+
+	// Names this long can't be created in most working trees, so
+	// add them directly to the index:
+	longName := strings.Repeat("x", 300)
+	for _, path := range []string{
+		longName,
+		"dir/" + longName,
+		"dir/" + strings.Repeat("y", 256),
+		"dir/" + strings.Repeat("z", 255),
+		"medium-length-name.txt",
+	} {
+		cmd := testRepo.GitCommand(
+			t, "update-index", "--add", "--cacheinfo",
+			fmt.Sprintf("100644,%s,%s", blobOID, path),
+		)
+		require.NoError(t, cmd.Run(), "adding %q to index", path)
+	}
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "long names")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	assert.Equal(t, counts.Count32(3), h.LongFilenameCount)
+	require.Len(t, h.LongFilenames, 2)
+	lengths := map[string]counts.Count32{}
+	for _, lf := range h.LongFilenames {
+		lengths[lf.Name] = lf.Length
+	}
+	assert.Equal(t, counts.Count32(300), lengths[longName])
+	assert.Equal(t, counts.Count32(256), lengths[strings.Repeat("y", 256)])
+
+	// The limit is configurable:
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleFull, MaxFilenameLength: 20},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+	assert.Equal(t, counts.Count32(5), h.LongFilenameCount)
+	assert.Len(t, h.LongFilenames, 4)
+}
+
+func TestWideTrees(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "wide-trees")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// Directories with 4, 6, 8, and 10 entries, each changed in a
+	// second commit, so that there are two versions of each:
+	for _, width := range []int{4, 6, 8, 10} {
+		for i := 0; i < width; i++ {
+			testRepo.AddFile(t, fmt.Sprintf("dir%d/file%d", width, i), "a\n")
+		}
+	}
+	cmd := testRepo.GitCommand(t, "commit", "-m", "first")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	for _, width := range []int{4, 6, 8, 10} {
+		testRepo.AddFile(t, fmt.Sprintf("dir%d/file0", width), "b\n")
+	}
+	cmd = testRepo.GitCommand(t, "commit", "-m", "second")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	// By default, none of these trees is too wide:
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+	assert.Equal(t, counts.Count32(0), h.WideTreeCount)
+	assert.Empty(t, h.WideTrees)
+
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleFull, WideTreeEntries: 5},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	// Two versions each of `dir6`, `dir8`, and `dir10`:
+	assert.Equal(t, counts.Count32(6), h.WideTreeCount)
+	require.Len(t, h.WideTrees, 6)
+	var entries []counts.Count32
+	for _, wt := range h.WideTrees {
+		entries = append(entries, wt.Entries)
+	}
+	assert.Equal(t, []counts.Count32{10, 10, 8, 8, 6, 6}, entries)
+	assert.Equal(t, "refs/heads/master:dir10", h.WideTrees[0].Tree.BestPath())
+
+	var problem *sizes.ProblemGroup
+	for _, g := range h.Problems(nil) {
+		if g.Code == sizes.ProblemWideTree {
+			g := g
+			problem = &g
+		}
+	}
+	require.NotNil(t, problem)
+	assert.Equal(t, counts.Count32(6), problem.Count)
+	assert.True(
+		t,
+		strings.HasSuffix(problem.Examples[0], " (refs/heads/master:dir10) has 10 entries"),
+		problem.Examples[0],
+	)
+}
+
+func TestCaseCollisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "case-collisions")
+	defer testRepo.Remove(t)
+
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "a\n")
+		return err
+	})
+
+	mktree := func(entries string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "mktree")
+		cmd.Stdin = strings.NewReader(entries)
+		out, err := cmd.Output()
+		require.NoError(t, err, "creating tree")
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	docs := mktree(fmt.Sprintf("100644 blob %s\tindex.md\n", blob))
+	// A symlink and a file, a tree and a file, and two files whose
+	// names differ only in case:
+	dir := mktree(fmt.Sprintf(
+		"120000 blob %[1]s\tConfig\n100644 blob %[1]s\tconfig\n"+
+			"040000 tree %[2]s\tDocs\n100644 blob %[1]s\tdocs\n"+
+			"100644 blob %[1]s\tREADME\n100755 blob %[1]s\treadme\n",
+		blob, docs,
+	))
+	root := mktree(fmt.Sprintf("040000 tree %s\tdir\n", dir))
+
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "case collisions", root.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err, "creating commit")
+	commit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/master", commit)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	// `README` and `readme` are both blobs, even though their modes
+	// differ:
+	assert.Equal(t, counts.Count32(1), h.CaseCollisionCount)
+
+	assert.Equal(t, counts.Count32(2), h.MixedTypeCaseCollisionCount)
+	require.Len(t, h.MixedTypeCaseCollisions, 2)
+	for i, expected := range []sizes.CaseCollision{
+		{Name1: "Config", Mode1: "120000", Name2: "config", Mode2: "100644"},
+		{Name1: "Docs", Mode1: "040000", Name2: "docs", Mode2: "100644"},
+	} {
+		c := h.MixedTypeCaseCollisions[i]
+		require.NotNil(t, c.Tree)
+		assert.Equal(t, dir, c.Tree.OID)
+		assert.Equal(t, "refs/heads/master:dir", c.Tree.BestPath())
+		c.Tree = nil
+		assert.Equal(t, expected, c)
+	}
+}
+
+func TestNormalizedNameCollisions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "normalized-name-collisions")
+	defer testRepo.Remove(t)
+
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "a\n")
+		return err
+	})
+
+	mktree := func(entries string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "mktree")
+		cmd.Stdin = strings.NewReader(entries)
+		out, err := cmd.Output()
+		require.NoError(t, err, "creating tree")
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	sub := mktree(fmt.Sprintf("100644 blob %s\tindex.md\n", blob))
+	// Two files whose names differ only in normalization, and a
+	// tree and a file whose names differ in normalization and case:
+	root := mktree(fmt.Sprintf(
+		"100644 blob %[1]s\t\u00c5ngstr\u00f6m\n100644 blob %[1]s\tA\u030angstro\u0308m\n"+
+			"040000 tree %[2]s\tCAFE\u0301\n100644 blob %[1]s\tcaf\u00e9\n",
+		blob, sub,
+	))
+
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "normalization", root.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err, "creating commit")
+	commit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/master", commit)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	for _, normalize := range []bool{false, true} {
+		normalize := normalize
+		t.Run(fmt.Sprintf("normalize=%t", normalize), func(t *testing.T) {
+			h, err := sizes.ScanRepositoryWithOptions(
+				ctx, repo, roots,
+				sizes.ScanOptions{NameStyle: sizes.NameStyleFull, NormalizeNames: normalize},
+				meter.NoProgressMeter,
+			)
+			require.NoError(t, err, "scanning repository")
+
+			if !normalize {
+				assert.Equal(t, counts.Count32(0), h.CaseCollisionCount)
+				assert.Equal(t, counts.Count32(0), h.MixedTypeCaseCollisionCount)
+				return
+			}
+
+			assert.Equal(t, counts.Count32(1), h.CaseCollisionCount)
+			assert.Equal(t, counts.Count32(1), h.MixedTypeCaseCollisionCount)
+			require.Len(t, h.MixedTypeCaseCollisions, 1)
+			c := h.MixedTypeCaseCollisions[0]
+			c.Tree = nil
+			assert.Equal(t, sizes.CaseCollision{
+				Name1: "CAFE\u0301", Mode1: "040000", Name2: "caf\u00e9", Mode2: "100644",
+			}, c)
+
+			// Lengths are still measured as stored, including
+			// the combining accent:
+			assert.Equal(t, counts.Count32(len("CAFE\u0301/index.md")), h.MaxPathLength)
+		})
+	}
+}
+
+func TestUnusualFiletypes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "unusual-filetypes")
+	defer testRepo.Remove(t)
+
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "a\n")
+		return err
+	})
+
+	mktree := func(entries string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "mktree")
+		cmd.Stdin = strings.NewReader(entries)
+		out, err := cmd.Output()
+		require.NoError(t, err, "creating tree")
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	// `git mktree` writes whatever modes it is given. A socket and a
+	// FIFO next to a normal file, twice:
+	dir := mktree(fmt.Sprintf(
+		"140000 blob %[1]s\tsocket\n010000 blob %[1]s\tfifo\n100644 blob %[1]s\tfile\n",
+		blob,
+	))
+	root := mktree(fmt.Sprintf("040000 tree %[1]s\ta\n040000 tree %[1]s\tb\n", dir))
+
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "unusual filetypes", root.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err, "creating commit")
+	commit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/master", commit)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	// The entries are only counted once, since `dir` is only
+	// examined once:
+	assert.Equal(t, counts.Count32(1), h.UnixSocketCount)
+	assert.Equal(t, counts.Count32(1), h.UnknownFiletypeCount)
+	assert.Equal(t, counts.Count32(2), h.MaxExpandedBlobCount)
+
+	// Git reads the socket and the FIFO as submodules, so they aren't
+	// counted as blobs:
+	assert.Equal(
+		t,
+		sizes.TypeBreakdown{TreeCount: 2, BlobCount: 1, SubmoduleCount: 2},
+		h.ObjectTypeBreakdown(),
+	)
+	assert.Equal(t, counts.Count64(5), h.UniqueTreeEntries)
+
+	// But the checkout includes both copies of each:
+	assert.Equal(t, counts.Count32(6), h.MaxPathCount)
+
+	// A commit range doesn't look up the objects of such entries as
+	// blobs either, which matters because they needn't exist:
+	cmd = testRepo.GitCommand(t, "mktree", "--missing")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(
+		"040000 tree %s\ta\n140000 blob %s\tsocket\n",
+		dir, strings.Repeat("1", 40),
+	))
+	out, err = cmd.Output()
+	require.NoError(t, err, "creating tree")
+	root2 := strings.TrimSpace(string(out))
+	cmd = testRepo.GitCommand(t, "commit-tree", "-m", "missing socket", "-p", commit.String(), root2)
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err = cmd.Output()
+	require.NoError(t, err, "creating commit")
+	commit2, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+
+	scanTree := func(treeSizes sizes.CacheBackend, oid git.OID) {
+		t.Helper()
+		_, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, []sizes.Root{sizes.NewExplicitRoot(oid.String(), oid)},
+			sizes.ScanOptions{NameStyle: sizes.NameStyleNone, TreeSizes: treeSizes},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err)
+	}
+
+	full := sizes.NewMemoryCacheBackend()
+	scanTree(full, commit2)
+	tree2, err := git.NewOID(root2)
+	require.NoError(t, err)
+	expected, ok := full.Get(tree2)
+	require.True(t, ok)
+
+	cache := sizes.NewMemoryCacheBackend()
+	scanTree(cache, commit)
+
+	// `dir` has no subtrees, so it has no layers below it, but it is
+	// counted at both of the paths where it appears:
+	dirSize, ok := cache.Get(dir)
+	require.True(t, ok)
+	assert.Nil(t, dirSize.LayerWidths)
+	assert.Equal(t, counts.Count32(1), dirSize.MaxTreeWidth())
+	rootSize, ok := cache.Get(root)
+	require.True(t, ok)
+	assert.Equal(t, []counts.Count32{2}, rootSize.LayerWidths)
+	assert.Equal(t, counts.Count32(2), rootSize.MaxTreeWidth())
+
+	ts, err := sizes.ComputeForCommitRange(ctx, repo, cache, commit, commit2)
+	require.NoError(t, err)
+	assert.Equal(t, expected, ts)
+}
+
+func TestTreeSizeExemplars(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "exemplars")
+	defer testRepo.Remove(t)
+
+	testRepo.AddFile(t, "a/b/c/deep.txt", "deep\n")
+	testRepo.AddFile(t, "a/a-rather-long-filename.txt", "long\n")
+	for i := 0; i < 20; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("wide/file-%02d.txt", i), "wide\n")
+	}
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	repo := testRepo.Repository(t)
+	resolve := func(name string) git.OID {
+		t.Helper()
+		oid, err := repo.ResolveObject(name)
+		require.NoError(t, err)
+		return oid
+	}
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	for _, track := range []bool{false, true} {
+		opts := sizes.ScanOptions{NameStyle: sizes.NameStyleFull, TrackExemplars: track}
+		opts.Graph = sizes.NewGraphWithOptions(opts)
+		_, err := sizes.ScanRepositoryWithOptions(ctx, repo, roots, opts, meter.NoProgressMeter)
+		require.NoError(t, err)
+
+		root := opts.Graph.GetTreeSize(resolve("HEAD^{tree}"))
+		if !track {
+			_, ok := root.Exemplar(sizes.ExemplarMaxPathDepth)
+			assert.False(t, ok)
+			continue
+		}
+
+		for metric, expected := range map[string]string{
+			sizes.ExemplarMaxPathDepth:          "HEAD:a/b/c/deep.txt",
+			sizes.ExemplarMaxPathLength:         "HEAD:a/a-rather-long-filename.txt",
+			sizes.ExemplarMaxTreeSerializedSize: "HEAD:wide",
+		} {
+			oid, ok := root.Exemplar(metric)
+			if assert.True(t, ok, metric) {
+				assert.Equal(t, resolve(expected), oid, metric)
+			}
+		}
+
+		// Subtrees have their own exemplars:
+		a := opts.Graph.GetTreeSize(resolve("HEAD:a"))
+		oid, ok := a.Exemplar(sizes.ExemplarMaxTreeSerializedSize)
+		require.True(t, ok)
+		assert.Equal(t, resolve("HEAD:a"), oid)
+	}
+}
+
+func TestTerminalTable(t *testing.T) {
+	t.Parallel()
+
+	oid, err := git.NewOID("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+
+	h := sizes.HistorySize{
+		UniqueCommitCount: 1200000,
+		UniqueBlobSize:    counts.Count64(math.MaxUint64),
+		MaxBlobSize:       50000000,
+		MaxBlobSizeBlob:   &sizes.Path{OID: oid},
+		MaxPathDepth:      200,
+		MaxTreeEntries:    100,
+	}
+
+	for _, p := range []struct {
+		width    int
+		expected string
+	}{
+		{
+			width: 80,
+			expected: "" +
+				"Name                              Value      Level of concern               Ref\n" +
+				"--------------------------------------------------------------------------------\n" +
+				"Overall repository size\n" +
+				"  Commits\n" +
+				"    Count                          1.20 M    **\n" +
+				"  Blobs\n" +
+				"    Total size                    >=16384 PiB  !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!\n" +
+				"  All objects\n" +
+				"    Total size                    >=16384 PiB  !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!\n" +
+				"\n" +
+				"Biggest objects\n" +
+				"  Blobs\n" +
+				"    Maximum size                   47.7 MiB  *****                          [1]\n" +
+				"\n" +
+				"Biggest checkouts\n" +
+				"  Maximum path depth                200      ********************\n" +
+				"\n" +
+				"[1]  0123456789abcdef0123456789abcdef01234567\n" +
+				"\n" +
+				"Problems:\n" +
+				"* warning: Metrics whose counters saturated [scan.saturated_metric] (2)\n" +
+				"    /totalObjectDataSize saturated at 18446744073709551615; the true value is at least that big\n" +
+				"    /uniqueBlobSize saturated at 18446744073709551615; the true value is at least that big\n",
+		},
+		{
+			width: 40,
+			expected: "" +
+				"Name            Value      Level... Ref\n" +
+				"----------------------------------------\n" +
+				"Overall repository size\n" +
+				"  Commits\n" +
+				"    Count        1.20 M    **\n" +
+				"  Blobs\n" +
+				"    Total size  >=16384 PiB  !!!!!!!+\n" +
+				"  All objects\n" +
+				"    Total size  >=16384 PiB  !!!!!!!+\n" +
+				"\n" +
+				"Biggest objects\n" +
+				"  Blobs\n" +
+				"    Maximum...   47.7 MiB  *****    [1]\n" +
+				"\n" +
+				"Biggest checkouts\n" +
+				"  Maximum p...    200      *******+\n" +
+				"\n" +
+				"[1]  0123456789abcdef0123456789abcdef012\n" +
+				"     34567\n" +
+				"\n" +
+				"Problems:\n" +
+				"* warning: Metrics whose counters saturated [scan.saturated_metric] (2)\n" +
+				"    /totalObjectDataSize saturated at 18446744073709551615; the true value is at least that big\n" +
+				"    /uniqueBlobSize saturated at 18446744073709551615; the true value is at least that big\n",
+		},
+	} {
+		p := p
+		t.Run(fmt.Sprintf("width=%d", p.width), func(t *testing.T) {
+			t.Parallel()
+
+			output := h.TerminalTableString(
+				nil, 1, sizes.NameStyleHash,
+				sizes.TerminalTableOptions{Width: p.width},
+			)
+			assert.Equal(t, p.expected, output)
+		})
+	}
+
+	t.Run("color", func(t *testing.T) {
+		t.Parallel()
+
+		output := h.TerminalTableString(
+			nil, 1, sizes.NameStyleHash,
+			sizes.TerminalTableOptions{Color: true},
+		)
+		assert.Contains(t, output, "\x1b[1mOverall repository size\x1b[0m\n")
+		assert.Contains(t, output, "\x1b[31m!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!\x1b[0m\n")
+		assert.Contains(t, output, "\x1b[33m*****\x1b[0m")
+	})
+
+	t.Run("level names", func(t *testing.T) {
+		t.Parallel()
+
+		styles, err := sizes.ParseConcernNames("green,yellow,red,black")
+		require.NoError(t, err)
+		styles[sizes.ConcernHigh] = sizes.ConcernStyle{Name: "red", Color: "\x1b[35m"}
+
+		output := h.TerminalTableString(
+			nil, 1, sizes.NameStyleHash,
+			sizes.TerminalTableOptions{Color: true, Styles: styles, LevelNames: true},
+		)
+		assert.Contains(t, output, "    Count                          1.20 M    \x1b[33myellow\x1b[0m\n")
+		assert.Contains(t, output, "    Total size                    >=16384 PiB  \x1b[31mblack\x1b[0m\n")
+		assert.Contains(t, output, "  Maximum path depth                200      \x1b[35mred\x1b[0m\n")
+	})
+}
+
+func TestConcernLevels(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []struct {
+		alert    sizes.Threshold
+		overflow bool
+		expected sizes.ConcernLevel
+		name     string
+	}{
+		{0, false, sizes.ConcernNone, "ok"},
+		{0.99, false, sizes.ConcernNone, "ok"},
+		{1, false, sizes.ConcernLow, "low"},
+		{9.9, false, sizes.ConcernLow, "low"},
+		{10, false, sizes.ConcernHigh, "high"},
+		{30, false, sizes.ConcernHigh, "high"},
+		{30.1, false, sizes.ConcernCritical, "critical"},
+		{0, true, sizes.ConcernCritical, "critical"},
+	} {
+		level := sizes.ConcernLevelOf(p.alert, p.overflow)
+		assert.Equalf(t, p.expected, level, "alert %g, overflow %t", p.alert, p.overflow)
+		assert.Equal(t, p.name, level.String())
+	}
+
+	styles, err := sizes.ParseConcernNames("ok, warn ,fail,fail")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", styles.Style(sizes.ConcernNone).Name)
+	assert.Equal(t, "warn", styles.Style(sizes.ConcernLow).Name)
+	assert.Equal(t, "fail", styles.Style(sizes.ConcernHigh).Name)
+	assert.Equal(t, "fail", styles.Style(sizes.ConcernCritical).Name)
+	assert.Equal(
+		t,
+		sizes.DefaultConcernStyles.Style(sizes.ConcernLow).Color,
+		styles.Style(sizes.ConcernLow).Color,
+		"colors default",
+	)
+
+	// Levels that aren't configured fall back to the defaults:
+	assert.Equal(
+		t,
+		sizes.DefaultConcernStyles[sizes.ConcernHigh],
+		sizes.ConcernStyles{}.Style(sizes.ConcernHigh),
+	)
+
+	for _, s := range []string{"", "ok,warn,fail", "ok,warn,,fail", "a,b,c,d,e"} {
+		_, err := sizes.ParseConcernNames(s)
+		assert.Errorf(t, err, "parsing %q", s)
+	}
+}
+
+func TestRefStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "ref-stats")
+	defer testRepo.Remove(t)
+
+	for _, refname := range []string{
+		"refs/heads/main",
+		"refs/heads/topic/one",
+		"refs/tags/v1.0",
+		"refs/tags/v1.1",
+		"refs/tags/v2.0",
+		"refs/remotes/origin/main",
+		"refs/notes/commits",
+		"refs/pull/1/head",
+	} {
+		testRepo.CreateReferencedOrphan(t, refname)
+	}
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	assert.Equal(t, sizes.RefStats{
+		BranchCount:            2,
+		TagCount:               3,
+		RemoteTrackingRefCount: 1,
+		OtherRefCount:          2,
+		AllRefCount:            8,
+	}, h.RefStats)
+	assert.Equal(t, h.ReferenceCount, h.RefStats.AllRefCount)
+}
+
+func TestRefNameConflicts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "ref-name-conflicts")
+	defer testRepo.Remove(t)
+
+	for _, refname := range []string{
+		"refs/heads/main",
+		"refs/heads/release",
+		"refs/heads/Straße",
+		"refs/tags/STRAßE",
+		"refs/tags/V1.0",
+		"refs/tags/v1.0",
+		"refs/tags/v2.0",
+		"refs/tags/release",
+		"refs/tags/Été",
+		"refs/tags/éTÉ",
+	} {
+		testRepo.CreateReferencedOrphan(t, refname)
+	}
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	// Non-ASCII names are folded, too, but "Straße" and "STRAßE" are
+	// in different namespaces:
+	assert.Equal(t, counts.Count32(2), h.RefStats.CaseConflictCount)
+	assert.Equal(
+		t,
+		[]sizes.RefNamePair{
+			{Refname1: "refs/tags/V1.0", Refname2: "refs/tags/v1.0"},
+			{Refname1: "refs/tags/Été", Refname2: "refs/tags/éTÉ"},
+		},
+		h.RefStats.CaseConflicts,
+	)
+	assert.Equal(t, counts.Count32(1), h.RefStats.TagShadowingBranchCount)
+	assert.Equal(
+		t,
+		[]sizes.RefNamePair{{Refname1: "refs/tags/release", Refname2: "refs/heads/release"}},
+		h.RefStats.TagsShadowingBranches,
+	)
+}
+
+func TestFoldedStacks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "folded-stacks")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "README", "Hello\n")
+	testRepo.AddFile(t, "src/main.c", "int main() {}\n")
+	testRepo.AddFile(t, "src/copy.c", "int main() {}\n")
+	testRepo.AddFile(t, "docs/a file;with semicolon.txt", "docs\n")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	testRepo.AddFile(t, "README", "Hello, world!\n")
+	cmd = testRepo.GitCommand(t, "commit", "-m", "update README")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WriteFoldedStacks(ctx, repo, roots, &buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+
+	// Both versions of README are listed; the two identical `.c`
+	// files are a single blob, so only one of them is:
+	require.Len(t, lines, 4)
+	assert.Equal(t, "README 14", lines[0])
+	assert.Equal(t, "README 6", lines[1])
+	assert.Equal(t, "docs;a file_with semicolon.txt 5", lines[2])
+	assert.Regexp(t, `^src;(main|copy)\.c 14$`, lines[3])
+}
+
+func TestBranchUnreachableTags(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "unreachable-tags")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "a.txt", "a\n")
+	commit("A")
+	require.NoError(t, testRepo.GitCommand(t, "tag", "reachable").Run())
+
+	bigContents := strings.Repeat("big\n", 10000)
+	testRepo.AddFile(t, "big.bin", bigContents)
+	commit("B")
+	cmd := testRepo.GitCommand(t, "tag", "-a", "-m", "release", "v1.0")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating annotated tag")
+	require.NoError(t, testRepo.GitCommand(t, "tag", "v1.0-light").Run())
+
+	// Rewrite the branch so that it no longer contains B:
+	require.NoError(t, testRepo.GitCommand(t, "reset", "--hard", "HEAD~").Run())
+	testRepo.AddFile(t, "c.txt", "c\n")
+	commit("C")
+
+	repo := testRepo.Repository(t)
+
+	commitB, err := repo.ResolveObject("v1.0^{commit}")
+	require.NoError(t, err)
+
+	stats, err := sizes.AnalyzeBranchUnreachableTags(ctx, repo, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, counts.Count32(2), stats.TagCount)
+
+	// The annotated tag, commit B, its tree, and the big blob:
+	assert.Equal(t, counts.Count32(4), stats.ObjectCount)
+	assert.Greater(t, uint64(stats.Size), uint64(len(bigContents)))
+
+	require.Len(t, stats.Tags, 2)
+
+	// The annotated tag object is the only thing that deleting
+	// `v1.0` alone would free:
+	assert.Equal(t, "refs/tags/v1.0", stats.Tags[0].Name)
+	assert.Equal(t, commitB, stats.Tags[0].Target)
+	assert.Equal(t, counts.Count32(1), stats.Tags[0].ExclusiveObjectCount)
+
+	// The lightweight tag keeps nothing alive by itself, since the
+	// annotated tag points at the same commit:
+	assert.Equal(t, "refs/tags/v1.0-light", stats.Tags[1].Name)
+	assert.Equal(t, commitB, stats.Tags[1].Target)
+	assert.Equal(t, counts.Count32(0), stats.Tags[1].ExclusiveObjectCount)
+	assert.Equal(t, counts.Count64(0), stats.Tags[1].ExclusiveSize)
+
+	stats, err = sizes.AnalyzeBranchUnreachableTags(ctx, repo, 1)
+	require.NoError(t, err)
+	assert.Len(t, stats.Tags, 1)
+
+	cmd = exec.Command(sizerExe(t), "--unreachable-tags", "--no-progress")
+	cmd.Dir = testRepo.Path
+	output, err := cmd.Output()
+	require.NoError(t, err, "running git-sizer --unreachable-tags")
+	assert.Contains(t, string(output), "Tags not reachable from any branch: 2")
+	assert.Contains(t, string(output), "refs/tags/v1.0 ")
+}
+
+func TestReplaceRefs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "replace-refs")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "file.txt", "small\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	bigContents := strings.Repeat("replacement\n", 1000)
+	replacement := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, bigContents)
+		return err
+	})
+
+	repo := testRepo.Repository(t)
+	original, err := repo.ResolveObject("HEAD:file.txt")
+	require.NoError(t, err)
+
+	require.NoError(
+		t,
+		testRepo.GitCommand(t, "replace", original.String(), replacement.String()).Run(),
+		"creating replace reference",
+	)
+
+	scan := func(repo *git.Repository) sizes.HistorySize {
+		t.Helper()
+
+		refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+		require.NoError(t, err)
+
+		roots := make([]sizes.Root, 0, len(refRoots))
+		for _, refRoot := range refRoots {
+			roots = append(roots, refRoot)
+		}
+
+		h, err := sizes.ScanRepositoryUsingGraph(
+			ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+		)
+		require.NoError(t, err, "scanning repository")
+		return h
+	}
+
+	// By default, the original blob is measured as stored, and the
+	// replacement is measured because the replace reference points
+	// at it:
+	h := scan(repo)
+	assert.Equal(t, counts.Count32(2), h.UniqueBlobCount)
+	assert.Equal(t, counts.Count64(len("small\n")+len(bigContents)), h.UniqueBlobSize)
+
+	// With replacements honored, the original OID takes on the size
+	// of the replacement:
+	replacingRepo, err := git.NewRepositoryFromGitDirWithOptions(
+		filepath.Join(testRepo.Path, ".git"), git.RepositoryOptions{UseReplaceRefs: true},
+	)
+	require.NoError(t, err)
+	h = scan(replacingRepo)
+	assert.Equal(t, counts.Count32(2), h.UniqueBlobCount)
+	assert.Equal(t, counts.Count64(2*len(bigContents)), h.UniqueBlobSize)
+}
+
+// cancelingMeter is a `meter.Progress` that cancels a context after a
+// specified number of trees have been processed.
+type cancelingMeter struct {
+	cancel   context.CancelFunc
+	inTrees  bool
+	count    int
+	maxTrees int
+}
+
+func (m *cancelingMeter) Start(format string) {
+	m.inTrees = strings.HasPrefix(format, "Processing trees")
+}
+
+func (m *cancelingMeter) Inc() {
+	if !m.inTrees {
+		return
+	}
+	m.count++
+	if m.count == m.maxTrees {
+		m.cancel()
+	}
+}
+
+func (m *cancelingMeter) Add(delta int64) {}
+
+func (m *cancelingMeter) Done() {}
+
+func TestTreeOrder(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "tree-order")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+		timestamp = timestamp.Add(time.Hour)
+	}
+
+	// One big directory early in history, which is then deleted,
+	// followed by lots of small directories:
+	for i := 0; i < 100; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("big/file-%03d.dat", i), strings.Repeat(fmt.Sprintf("%d\n", i), 500))
+	}
+	commit("add big directory")
+	require.NoError(t, testRepo.GitCommand(t, "rm", "-r", "-q", "big").Run())
+	commit("remove big directory")
+	for i := 0; i < 20; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("small-%02d/file.txt", i), fmt.Sprintf("%d\n", i))
+		commit(fmt.Sprintf("add small directory %d", i))
+	}
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(context.Background(), repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	scan := func(order sizes.TreeOrder, maxTrees int) (sizes.HistorySize, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		return sizes.ScanRepositoryWithOptions(
+			ctx, repo, roots,
+			sizes.ScanOptions{NameStyle: sizes.NameStyleFull, TreeOrder: order},
+			&cancelingMeter{cancel: cancel, maxTrees: maxTrees},
+		)
+	}
+
+	blobBytes := func(h sizes.HistorySize) counts.Count64 {
+		var total counts.Count64
+		for _, ext := range h.Extensions {
+			total.Increment(ext.BlobSize)
+		}
+		return total
+	}
+
+	// Complete scans give the same results regardless of the order:
+	full, err := scan(sizes.TreeOrderTraversal, -1)
+	require.NoError(t, err)
+	fullLargestFirst, err := scan(sizes.TreeOrderLargestFirst, -1)
+	require.NoError(t, err)
+	assert.Equal(t, full.UniqueTreeCount, fullLargestFirst.UniqueTreeCount)
+	assert.Equal(t, full.UniqueCommitCount, fullLargestFirst.UniqueCommitCount)
+	assert.Equal(t, full.MaxExpandedBlobSize, fullLargestFirst.MaxExpandedBlobSize)
+	assert.Equal(t, full.MaxTreeEntries, fullLargestFirst.MaxTreeEntries)
+	assert.Equal(t, blobBytes(full), blobBytes(fullLargestFirst))
+
+	// Interrupted scans return partial results:
+	traversal, err := scan(sizes.TreeOrderTraversal, 3)
+	require.ErrorIs(t, err, sizes.ErrScanInterrupted)
+	largestFirst, err := scan(sizes.TreeOrderLargestFirst, 3)
+	require.ErrorIs(t, err, sizes.ErrScanInterrupted)
+
+	assert.Equal(t, counts.Count32(0), largestFirst.UniqueCommitCount)
+	assert.Equal(t, counts.Count32(100), largestFirst.MaxTreeEntries)
+	assert.Greater(t, uint64(blobBytes(largestFirst)), uint64(blobBytes(traversal)))
+}
+
+func TestSizeClusters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "size-clusters")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// Lots of "exported" files whose sizes are within a few bytes of
+	// one another:
+	for i := 0; i < 30; i++ {
+		testRepo.AddFile(
+			t, fmt.Sprintf("export/part-%02d.csv", i),
+			fmt.Sprintf("%d,%s\n", i, strings.Repeat("x", 1000+i%5)),
+		)
+	}
+	// ...plus some files of assorted sizes:
+	for i := 0; i < 10; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("src/file-%d.txt", i), strings.Repeat("y", 600*(i+1)))
+	}
+	cmd := testRepo.GitCommand(t, "commit", "-m", "export")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	clusters, err := sizes.AnalyzeSizeClusters(ctx, repo, roots, sizes.SizeClusterOptions{
+		Tolerance:      0.01,
+		MinClusterSize: 20,
+		MinBlobSize:    500,
+		MaxExamples:    3,
+	})
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+
+	c := clusters[0]
+	assert.Equal(t, counts.Count32(30), c.BlobCount)
+	assert.Equal(t, counts.Count32(1003), c.MinSize)
+	assert.Equal(t, counts.Count32(1008), c.MaxSize)
+	assert.Len(t, c.Examples, 3)
+	for _, example := range c.Examples {
+		assert.True(t, strings.HasPrefix(example, "export/part-"), example)
+	}
+
+	// With no tolerance, only identical sizes are grouped:
+	clusters, err = sizes.AnalyzeSizeClusters(ctx, repo, roots, sizes.SizeClusterOptions{
+		MinClusterSize: 20,
+		MinBlobSize:    500,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, clusters)
+}
+
+func TestSimilarBlobs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "similar-blobs")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// pseudoText returns `n` lines of deterministic pseudo-random
+	// text, so that files generated with different seeds have
+	// nothing in common.
+	pseudoText := func(seed int64, n int) string {
+		r := rand.New(rand.NewSource(seed))
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "%d,%d,%x\n", i, r.Int63(), r.Int63())
+		}
+		return sb.String()
+	}
+
+	data := pseudoText(1, 5000)
+	lines := strings.SplitAfter(data, "\n")
+
+	// Three versions of the same data file: the original, one with
+	// a few rows changed, and one with rows inserted at the start and
+	// appended at the end:
+	testRepo.AddFile(t, "data/v1.csv", data)
+	edited := append([]string(nil), lines...)
+	for i := 1000; i < 5000; i += 1000 {
+		edited[i] = "edited\n"
+	}
+	testRepo.AddFile(t, "data/v2.csv", strings.Join(edited, ""))
+	testRepo.AddFile(t, "data/v3.csv", pseudoText(2, 300)+data+pseudoText(3, 300))
+
+	// ...and an unrelated file of about the same size:
+	testRepo.AddFile(t, "other.csv", pseudoText(4, 5000))
+
+	testRepo.AddFile(t, "small.txt", "small\n")
+
+	cmd := testRepo.GitCommand(t, "commit", "-m", "data")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	clusters, err := sizes.FindSimilarBlobs(ctx, repo, roots, sizes.SimilarBlobOptions{
+		MaxExamples: 3,
+	})
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+
+	c := clusters[0]
+	assert.Equal(t, counts.Count32(3), c.BlobCount)
+	assert.Len(t, c.OIDs, 3)
+	assert.Equal(t, []string{"data/v3.csv", "data/v1.csv", "data/v2.csv"}, c.Examples)
+
+	var total counts.Count64
+	for _, path := range c.Examples {
+		entry, ok, err := repo.ReadTreeAtPath("HEAD", path)
+		require.NoError(t, err)
+		require.True(t, ok)
+		total.Increment(counts.Count64(entry.Size))
+	}
+	assert.Equal(t, total, c.TotalSize)
+
+	// The result doesn't change from one run to the next:
+	again, err := sizes.FindSimilarBlobs(ctx, repo, roots, sizes.SimilarBlobOptions{
+		MaxExamples: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, clusters, again)
+
+	// If only the two largest blobs are compared, the cluster is
+	// smaller:
+	clusters, err = sizes.FindSimilarBlobs(ctx, repo, roots, sizes.SimilarBlobOptions{
+		MaxBlobs: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, counts.Count32(2), clusters[0].BlobCount)
+	assert.Empty(t, clusters[0].Examples)
+}
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "storage")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for i := 0; i < 3; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("file-%d.txt", i), fmt.Sprintf("contents %d\n", i))
+	}
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-a", "-d", "-q").Run())
+
+	repo := testRepo.Repository(t)
+
+	storage, err := sizes.ScanStorage(repo)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(0), storage.LooseObjectCount)
+	assert.Equal(t, counts.Count32(0), storage.RedundantLooseObjectCount)
+	assert.Equal(t, counts.Count32(1), storage.PackCount)
+	assert.Greater(t, uint64(storage.PackSize), uint64(0))
+
+	// Explode the pack into loose objects while keeping the pack.
+	// `git unpack-objects` skips objects that already exist, so the
+	// pack has to be moved out of the way while it runs:
+	packDir := filepath.Join(testRepo.Path, ".git", "objects", "pack")
+	packs, err := filepath.Glob(filepath.Join(packDir, "*.pack"))
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	stash := t.TempDir()
+	for _, ext := range []string{".pack", ".idx"} {
+		src := strings.TrimSuffix(packs[0], ".pack") + ext
+		require.NoError(t, os.Rename(src, filepath.Join(stash, "pack"+ext)))
+	}
+	packFile, err := os.Open(filepath.Join(stash, "pack.pack"))
+	require.NoError(t, err)
+	cmd = testRepo.GitCommand(t, "unpack-objects", "-q")
+	cmd.Stdin = packFile
+	require.NoError(t, cmd.Run(), "unpacking objects")
+	packFile.Close()
+	for _, ext := range []string{".pack", ".idx"} {
+		dst := strings.TrimSuffix(packs[0], ".pack") + ext
+		require.NoError(t, os.Rename(filepath.Join(stash, "pack"+ext), dst))
+	}
+
+	// One more loose object that isn't in the pack:
+	testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "unpacked\n")
+		return err
+	})
+
+	storage, err = sizes.ScanStorage(repo)
+	require.NoError(t, err)
+
+	// 3 blobs + 1 tree + 1 commit, plus the extra blob:
+	assert.Equal(t, counts.Count32(6), storage.LooseObjectCount)
+	assert.Equal(t, counts.Count32(5), storage.RedundantLooseObjectCount)
+	assert.Greater(t, uint64(storage.RedundantLooseObjectSize), uint64(0))
+	assert.Less(t, uint64(storage.RedundantLooseObjectSize), uint64(storage.LooseObjectSize))
+
+	require.NoError(t, testRepo.GitCommand(t, "prune-packed").Run())
+
+	storage, err = sizes.ScanStorage(repo)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(1), storage.LooseObjectCount)
+	assert.Equal(t, counts.Count32(0), storage.RedundantLooseObjectCount)
+
+	// The object store is only examined if requested:
+	ctx := context.Background()
+	h, err := sizes.ScanRepositoryWithOptions(
+		ctx, repo, nil, sizes.ScanOptions{NameStyle: sizes.NameStyleFull},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+	assert.Nil(t, h.Storage)
+	assert.NotContains(t, h.MetricNames(nil), "redundantLooseObjectCount")
+
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, nil,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleFull, CheckStorage: true},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, h.Storage)
+	assert.Equal(t, storage, *h.Storage)
+	assert.Contains(t, h.MetricNames(nil), "redundantLooseObjectCount")
+}
+
+func TestUncheckedMetricsOmitted(t *testing.T) {
+	t.Parallel()
+
+	// The metrics of the optional checks, by the title of their rows:
+	unchecked := map[string]string{
+		"maxBlobLineCount":          "Maximum line count",
+		"maxFileAgeInHistory":       "Oldest file (days)",
+		"medianFileAgeInHistory":    "Median file age (days)",
+		"defaultBranchMissing":      "Missing default branch",
+		"detachedHead":              "Detached HEAD",
+		"danglingSymbolicRefCount":  "Dangling symbolic refs",
+		"redundantLooseObjectCount": "Redundant loose objects",
+		"redundantLooseObjectSize":  "Redundant loose size",
+		"corruptObjectCount":        "Corrupt objects",
+		"bitmapCommitCount":         "Bitmapped commits",
+		"bitmapBitCount":            "Bitmapped objects",
+		"missingBitmap":             "Missing bitmap",
+		"otherRefsBlobSize":         "Other-ref-only blob size",
+		"otherRefsBlobPercent":      "Other-ref-only blob share",
+		"branchObjectSize":          "Branch-reachable size",
+		"otherRefOnlyObjectPercent": "Other-ref-only share",
+	}
+
+	var h sizes.HistorySize
+	names := h.MetricNames(nil)
+	table := h.TableString(nil, 0, sizes.NameStyleFull)
+	j, err := h.JSON(nil, 0, sizes.NameStyleFull)
+	require.NoError(t, err)
+	var report map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(j, &report))
+
+	for name, title := range unchecked {
+		assert.NotContains(t, names, name)
+		assert.NotContains(t, report, name)
+		assert.NotContains(t, table, title)
+	}
+}
+
+func TestTableAlignment(t *testing.T) {
+	t.Parallel()
+
+	// Every row, including those of all of the optional checks, has
+	// to fit in the columns of the table:
+	branches := counts.Count32(3)
+	h := sizes.HistorySize{
+		ReferenceGroups: map[sizes.RefGroupSymbol]*counts.Count32{
+			"branches": &branches,
+		},
+		MaxBlobLineCount:       new(counts.Count32),
+		MaxFileAgeInHistory:    new(counts.Count64),
+		MedianFileAgeInHistory: new(counts.Count64),
+		RepositoryConfig:       &sizes.RepositoryConfigStats{},
+		Storage:                &sizes.StorageBreakdown{},
+		CorruptObjectCount:     new(counts.Count32),
+		Bitmap:                 &sizes.BitmapStatus{},
+		DefaultBranchShare:     &sizes.DefaultBranchShare{},
+		RefKindShares:          &sizes.RefKindShares{},
+	}
+	refGroups := []sizes.RefGroup{{Symbol: "branches", Name: "Branches"}}
+
+	lines := strings.Split(h.TableString(refGroups, 0, sizes.NameStyleFull), "\n")
+	header := lines[0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "| ") {
+			continue
+		}
+		assert.Lenf(t, line, len(header), "misaligned row %q", line)
+		assert.Equalf(
+			t, strings.Index(header[1:], "|"), strings.Index(line[1:], "|"),
+			"misaligned row %q", line,
+		)
+	}
+}
+
+func TestSelectMetrics(t *testing.T) {
+	t.Parallel()
+
+	branches := counts.Count32(3)
+	h := sizes.HistorySize{
+		UniqueBlobSize: 1234567,
+		MaxPathLength:  150,
+		ReferenceGroups: map[sizes.RefGroupSymbol]*counts.Count32{
+			"branches": &branches,
+		},
+	}
+	refGroups := []sizes.RefGroup{{Symbol: "branches", Name: "Branches"}}
+
+	names := h.MetricNames(refGroups)
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Contains(t, names, "uniqueBlobSize")
+	assert.Contains(t, names, "refgroup.branches")
+
+	metrics, err := h.SelectMetrics(
+		refGroups, []string{"uniqueBlobSize", "refgroup.branches", "maxCheckoutPathLength"},
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WriteSelectedMetrics(&buf, metrics))
+	assert.Equal(
+		t,
+		"uniqueBlobSize=1234567\n"+
+			"refgroup.branches=3\n"+
+			"maxCheckoutPathLength=150\n",
+		buf.String(),
+	)
+
+	buf.Reset()
+	require.NoError(t, sizes.EncodeSelectedMetrics(&buf, metrics[:2]))
+	assert.JSONEq(t, `{"uniqueBlobSize": 1234567, "refgroup.branches": 3}`, buf.String())
+
+	// Metrics of checks that weren't run can't be selected:
+	_, err = h.SelectMetrics(refGroups, []string{"redundantLooseObjectCount"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wasn't computed")
+
+	_, err = h.SelectMetrics(refGroups, []string{"uniqueBlobSize", "nonsense"})
+	var unknown sizes.UnknownMetricError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, "nonsense", unknown.Name)
+	assert.Equal(t, names, unknown.Valid)
+	assert.Contains(t, err.Error(), "uniqueBlobSize")
+}
+
+func TestGitHubAnnotations(t *testing.T) {
+	t.Parallel()
+
+	h := sizes.HistorySize{
+		UniqueBlobCount:    3000000,
+		UniqueBlobSize:     400e9,
+		ReferencedBlobSize: 800e9,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, h.WriteGitHubAnnotations(&buf, nil, 1, sizes.NameStyleFull))
+	assert.Equal(
+		t,
+		"::error title=git-sizer%3A totalObjectDataSize::"+
+			"The total uncompressed size of all distinct objects "+
+			"(an upper bound on the size of a clone before compression): 373 GiB "+
+			"(level of concern: !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!)\n"+
+			"::warning title=git-sizer%3A uniqueBlobCount::"+
+			"The total number of distinct blob objects: 3.00 M (level of concern: **)\n"+
+			"::error title=git-sizer%3A uniqueBlobSize::"+
+			"The total size of all distinct blob objects: 373 GiB "+
+			"(level of concern: !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!)\n",
+		buf.String(),
+	)
+
+	// Items below the threshold are notices; "%" has to be escaped:
+	buf.Reset()
+	require.NoError(t, h.WriteGitHubAnnotations(&buf, nil, 0, sizes.NameStyleFull))
+	assert.Contains(
+		t, buf.String(),
+		"::notice title=git-sizer%3A dedupPercent::"+
+			"The referenced size as a percentage of the total size "+
+			"(e.g., 300%25 means that each blob is referenced three times on average): 200 %25\n",
+	)
+
+	// Blobs and trees are annotated with their paths:
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "github-annotations")
+	defer testRepo.Remove(t)
+
+	testRepo.AddFile(t, "assets/big,file.bin", strings.Repeat("x", 100000))
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit", "-m", "big file")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+	h, err = sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, h.WriteGitHubAnnotations(&buf, nil, 0, sizes.NameStyleFull))
+	assert.Contains(
+		t, buf.String(),
+		"::notice file=assets/big%2Cfile.bin,title=git-sizer%3A maxBlobSize::"+
+			"The size of the largest blob object: 97.7 KiB\n",
+	)
+
+	buf.Reset()
+	require.NoError(t, h.WriteGitHubAnnotations(&buf, nil, 0, sizes.NameStyleNone))
+	assert.Contains(
+		t, buf.String(),
+		"::notice title=git-sizer%3A maxBlobSize::The size of the largest blob object: 97.7 KiB\n",
+	)
+}
+
+func TestFlatten(t *testing.T) {
+	t.Parallel()
+
+	branches := counts.Count32(3)
+	h := sizes.HistorySize{
+		UniqueBlobSize: 1234567,
+		MaxPathLength:  150,
+		ReferenceGroups: map[sizes.RefGroupSymbol]*counts.Count32{
+			"branches": &branches,
+		},
+		Extensions: map[string]*sizes.ExtStats{
+			".txt": {BlobCount: 2, BlobSize: 30, MaxBlobSize: 20},
+			"":     {BlobCount: 1, BlobSize: 5, MaxBlobSize: 5},
+		},
+		RepositoryConfig: &sizes.RepositoryConfigStats{},
+		Storage:          &sizes.StorageBreakdown{},
+		Bitmap:           &sizes.BitmapStatus{},
+
+		CorruptObjectCount: new(counts.Count32),
+		MaxBlobLineCount:   new(counts.Count32),
+
+		MaxFileAgeInHistory:    new(counts.Count64),
+		MedianFileAgeInHistory: new(counts.Count64),
+
+		DefaultBranchShare: &sizes.DefaultBranchShare{},
+		RefKindShares:      &sizes.RefKindShares{},
+	}
+	refGroups := []sizes.RefGroup{{Symbol: "branches", Name: "Branches"}}
+
+	flat := h.Flatten(refGroups)
+
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// These keys are part of the output format, so don't change
+	// them (adding new ones is OK):
+	assert.Equal(
+		t,
+		[]string{
+			"/allRefCount",
+			"/bitmapBitCount",
+			"/bitmapCommitCount",
+			"/branchCount",
+			"/branchObjectSize",
+			"/corruptObjectCount",
+			"/danglingSymbolicRefCount",
+			"/dedupPercent",
+			"/defaultBranchMissing",
+			"/detachedHead",
+			"/duplicateHeaderObjectCount",
+			"/edgeCount",
+			"/emptyBlobCount",
+			"/emptyTreeCount",
+			"/extension/.txt/blobCount",
+			"/extension/.txt/blobSize",
+			"/extension/.txt/maxBlobSize",
+			"/extension//blobCount",
+			"/extension//blobSize",
+			"/extension//maxBlobSize",
+			"/longFilenameCount",
+			"/maxBlobLineCount",
+			"/maxBlobSize",
+			"/maxCheckoutBlobCount",
+			"/maxCheckoutBlobSize",
+			"/maxCheckoutLinkCount",
+			"/maxCheckoutPathCount",
+			"/maxCheckoutPathDepth",
+			"/maxCheckoutPathLength",
+			"/maxCheckoutSubmoduleCount",
+			"/maxCheckoutTreeCount",
+			"/maxCheckoutTreeWidth",
+			"/maxCommitParentCount",
+			"/maxCommitSize",
+			"/maxFileAgeInHistory",
+			"/maxHeaderLineLength",
+			"/maxHistoryDepth",
+			"/maxTagDepth",
+			"/maxTreeEntries",
+			"/medianFileAgeInHistory",
+			"/missingBitmap",
+			"/mixedTypeCaseCollisionCount",
+			"/otherRefCount",
+			"/otherRefOnlyObjectPercent",
+			"/otherRefOnlyObjectSize",
+			"/otherRefsBlobPercent",
+			"/otherRefsBlobSize",
+			"/redundantLooseObjectCount",
+			"/redundantLooseObjectSize",
+			"/referenceCount",
+			"/referencedBlobSize",
+			"/refgroup/branches",
+			"/remoteTrackingRefCount",
+			"/tagCount",
+			"/tagOnlyObjectSize",
+			"/totalObjectDataSize",
+			"/uniqueBlobCount",
+			"/uniqueBlobSize",
+			"/uniqueCommitCount",
+			"/uniqueCommitSize",
+			"/uniqueTagCount",
+			"/uniqueTagSize",
+			"/uniqueTreeCount",
+			"/uniqueTreeEntries",
+			"/uniqueTreeSize",
+			"/unixSocketCount",
+			"/unknownFiletypeCount",
+			"/unknownHeaderObjectCount",
+			"/wideTreeCount",
+		},
+		keys,
+	)
+
+	assert.Equal(
+		t,
+		sizes.MetricValue{
+			Value:          1234567,
+			Humanized:      "1.18 MiB",
+			Unit:           "B",
+			LevelOfConcern: 1234567 / 10e9,
+		},
+		flat["/uniqueBlobSize"],
+	)
+	assert.Equal(t, uint64(3), flat["/refgroup/branches"].Value)
+	assert.Equal(t, uint64(30), flat["/extension/.txt/blobSize"].Value)
+	assert.Equal(t, uint64(5), flat["/extension//maxBlobSize"].Value)
+	assert.InDelta(t, 1.5, flat["/maxCheckoutPathLength"].LevelOfConcern, 1e-9)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.EncodeFlatJSON(&buf, &h, refGroups))
+	var decoded map[string]sizes.MetricValue
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, flat, decoded)
+	assert.Less(
+		t,
+		strings.Index(buf.String(), `"/allRefCount"`),
+		strings.Index(buf.String(), `"/uniqueBlobSize"`),
+		"keys are sorted",
+	)
+}
+
+func TestSizeAccumulator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "accumulator")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for i := 0; i < 3; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("dir-%d/sub/file.txt", i), fmt.Sprintf("%d\n", i))
+		testRepo.AddFile(t, "README.md", strings.Repeat("readme\n", i+1))
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("commit %d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+	cmd := testRepo.GitCommand(t, "tag", "-m", "release", "v1")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating tag")
+
+	repo := testRepo.Repository(t)
+
+	// Find the objects, grouped by type:
+	out, err := testRepo.GitCommand(
+		t, "cat-file", "--batch-all-objects", "--batch-check=%(objecttype) %(objectname) %(objectsize)",
+	).Output()
+	require.NoError(t, err)
+	objects := make(map[string][]string)
+	blobSizes := make(map[string]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		objects[fields[0]] = append(objects[fields[0]], fields[1])
+		if fields[0] == "blob" {
+			size, err := strconv.ParseUint(fields[2], 10, 32)
+			require.NoError(t, err)
+			blobSizes[fields[1]] = size
+		}
+	}
+
+	// The commits have to be added parents first:
+	out, err = testRepo.GitCommand(t, "rev-list", "--reverse", "--topo-order", "--all").Output()
+	require.NoError(t, err)
+	objects["commit"] = strings.Fields(string(out))
+
+	read := func(objectType, name string) (git.OID, []byte) {
+		t.Helper()
+		oid, err := git.NewOID(name)
+		require.NoError(t, err)
+		data, err := testRepo.GitCommand(t, "cat-file", objectType, name).Output()
+		require.NoError(t, err)
+		return oid, data
+	}
+
+	acc := sizes.NewSizeAccumulator(sizes.ScanOptions{NameStyle: sizes.NameStyleNone})
+
+	// Trees can't be added before their blobs:
+	oid, data := read("tree", objects["tree"][0])
+	require.ErrorIs(t, acc.AddTree(oid, data), sizes.ErrMissingDependency)
+
+	for _, name := range objects["blob"] {
+		oid, err := git.NewOID(name)
+		require.NoError(t, err)
+		acc.AddBlob(oid, counts.Count32(blobSizes[name]))
+	}
+	// Tags and trees can be added in any order:
+	for _, name := range objects["tag"] {
+		require.NoError(t, acc.AddTag(read("tag", name)))
+	}
+	for _, name := range objects["tree"] {
+		require.NoError(t, acc.AddTree(read("tree", name)))
+	}
+
+	// Commits can't be added before their parents:
+	last := objects["commit"][len(objects["commit"])-1]
+	require.ErrorIs(t, acc.AddCommit(read("commit", last)), sizes.ErrMissingDependency)
+
+	for _, name := range objects["commit"] {
+		require.NoError(t, acc.AddCommit(read("commit", name)))
+	}
+
+	pushed, err := acc.Finalize()
+	require.NoError(t, err)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	pulled, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, pulled.UniqueCommitCount, pushed.UniqueCommitCount)
+	assert.Equal(t, pulled.UniqueCommitSize, pushed.UniqueCommitSize)
+	assert.Equal(t, pulled.MaxHistoryDepth, pushed.MaxHistoryDepth)
+	assert.Equal(t, pulled.UniqueTreeCount, pushed.UniqueTreeCount)
+	assert.Equal(t, pulled.UniqueTreeSize, pushed.UniqueTreeSize)
+	assert.Equal(t, pulled.UniqueBlobCount, pushed.UniqueBlobCount)
+	assert.Equal(t, pulled.UniqueBlobSize, pushed.UniqueBlobSize)
+	assert.Equal(t, pulled.UniqueTagCount, pushed.UniqueTagCount)
+	assert.Equal(t, pulled.MaxPathDepth, pushed.MaxPathDepth)
+	assert.Equal(t, pulled.MaxExpandedBlobCount, pushed.MaxExpandedBlobCount)
+	assert.Equal(t, pulled.Extensions, pushed.Extensions)
+}
+
+// httpObjectReader is a reference `sizes.ObjectReader` that reads
+// objects from an HTTP service.
+type httpObjectReader struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (r httpObjectReader) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// Network errors might go away:
+		return nil, sizes.TransientError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	switch {
+	case err != nil:
+		return nil, sizes.TransientError{Err: err}
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("GET %s: %w", path, sizes.ErrObjectNotFound)
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, sizes.TransientError{Err: fmt.Errorf("GET %s: %s", path, resp.Status)}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return body, nil
+}
+
+func (r httpObjectReader) ReadHeader(
+	ctx context.Context, oid git.OID,
+) (git.ObjectType, counts.Count32, error) {
+	body, err := r.get(ctx, "/header/"+oid.String())
+	if err != nil {
+		return "", 0, err
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("malformed header %q", body)
+	}
+	size, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed header %q: %w", body, err)
+	}
+	return git.ObjectType(fields[0]), counts.Count32(size), nil
+}
+
+func (r httpObjectReader) ReadObject(ctx context.Context, oid git.OID) ([]byte, error) {
+	return r.get(ctx, "/object/"+oid.String())
+}
+
+func TestScanObjectReader(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "object-reader")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for i := 0; i < 3; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("dir-%d/sub/file.txt", i), fmt.Sprintf("%d\n", i))
+		testRepo.AddFile(t, "README.md", strings.Repeat("readme\n", i+1))
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("commit %d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+	cmd := testRepo.GitCommand(t, "tag", "-m", "release", "v1")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating tag")
+
+	repo := testRepo.Repository(t)
+
+	// The service fails the first request for each object with a
+	// 503, and reports `missing` as not found:
+	var lock sync.Mutex
+	requested := make(map[string]bool)
+	missing := "none"
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		retry := !requested[req.URL.Path]
+		requested[req.URL.Path] = true
+		isMissing := strings.HasSuffix(req.URL.Path, "/"+missing)
+		lock.Unlock()
+		if retry {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		kind, name, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if isMissing {
+			http.NotFound(w, req)
+			return
+		}
+		var out []byte
+		var err error
+		switch kind {
+		case "header":
+			c := testRepo.GitCommand(t, "cat-file", "--batch-check=%(objecttype) %(objectsize)")
+			c.Stdin = strings.NewReader(name + "\n")
+			out, err = c.Output()
+		case "object":
+			typ, e := testRepo.GitCommand(t, "cat-file", "-t", name).Output()
+			if e != nil {
+				http.NotFound(w, req)
+				return
+			}
+			out, err = testRepo.GitCommand(
+				t, "cat-file", strings.TrimSpace(string(typ)), name,
+			).Output()
+		default:
+			http.NotFound(w, req)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(out)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	reader := httpObjectReader{baseURL: server.URL, client: server.Client()}
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	opts := sizes.ObjectReaderOptions{
+		ScanOptions: sizes.ScanOptions{NameStyle: sizes.NameStyleNone},
+		RetryDelay:  time.Millisecond,
+	}
+	remote, err := sizes.ScanObjectReader(ctx, reader, roots, opts)
+	require.NoError(t, err)
+
+	local, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, local.UniqueCommitCount, remote.UniqueCommitCount)
+	assert.Equal(t, local.UniqueCommitSize, remote.UniqueCommitSize)
+	assert.Equal(t, local.MaxHistoryDepth, remote.MaxHistoryDepth)
+	assert.Equal(t, local.UniqueTreeCount, remote.UniqueTreeCount)
+	assert.Equal(t, local.UniqueTreeSize, remote.UniqueTreeSize)
+	assert.Equal(t, local.UniqueBlobCount, remote.UniqueBlobCount)
+	assert.Equal(t, local.UniqueBlobSize, remote.UniqueBlobSize)
+	assert.Equal(t, local.UniqueTagCount, remote.UniqueTagCount)
+	assert.Equal(t, local.MaxExpandedBlobCount, remote.MaxExpandedBlobCount)
+
+	// Without retries, the 503s are fatal:
+	lock.Lock()
+	requested = make(map[string]bool)
+	lock.Unlock()
+	opts.MaxRetries = -1
+	_, err = sizes.ScanObjectReader(ctx, reader, roots, opts)
+	require.Error(t, err)
+	assert.True(t, sizes.IsRetriable(err))
+
+	// A missing object is a permanent error:
+	out, err := testRepo.GitCommand(t, "rev-parse", "HEAD^{tree}").Output()
+	require.NoError(t, err)
+	lock.Lock()
+	missing = strings.TrimSpace(string(out))
+	lock.Unlock()
+	opts.MaxRetries = 0
+	_, err = sizes.ScanObjectReader(ctx, reader, roots, opts)
+	require.ErrorIs(t, err, sizes.ErrObjectNotFound)
+	assert.False(t, sizes.IsRetriable(err))
+}
+
+func TestEmptyObjects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "empty-objects")
+	defer testRepo.Remove(t)
+
+	emptyBlob := testRepo.CreateObject(t, "blob", func(w io.Writer) error { return nil })
+	require.Equal(t, git.EmptyBlobOID, emptyBlob)
+	blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, "a\n")
+		return err
+	})
+
+	mktree := func(entries string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "mktree")
+		cmd.Stdin = strings.NewReader(entries)
+		out, err := cmd.Output()
+		require.NoError(t, err, "creating tree")
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	emptyTree := mktree("")
+	require.Equal(t, git.EmptyTreeOID, emptyTree)
+
+	subtree := mktree(fmt.Sprintf(
+		"100644 blob %s\t.gitkeep\n100644 blob %s\ta.txt\n", emptyBlob, blob,
+	))
+	// `subtree` appears twice, but its entries are only counted
+	// once:
+	root := mktree(fmt.Sprintf(
+		"100644 blob %s\t.gitkeep\n040000 tree %s\tdir1\n040000 tree %s\tdir2\n040000 tree %s\tempty\n",
+		emptyBlob, subtree, subtree, emptyTree,
+	))
+
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "empty objects", root.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err, "creating commit")
+	commit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/master", commit)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	assert.Equal(t, counts.Count32(2), h.EmptyBlobCount, "empty blob count")
+	assert.Equal(t, counts.Count32(1), h.EmptyTreeCount, "empty tree count")
+}
+
+func TestEmptyFilenames(t *testing.T) {
+	t.Parallel()
+
+	// Current versions of `git rev-list` refuse to walk a tree like
+	// this, so feed it to the graph directly:
+	blob, err := git.NewOID(strings.Repeat("1", 40))
+	require.NoError(t, err)
+	treeOID, err := git.NewOID(strings.Repeat("2", 40))
+	require.NoError(t, err)
+	tree, err := git.ParseTree(treeOID, []byte(fmt.Sprintf(
+		"100644 \x00%s100644 ok\x00%s", blob.Bytes(), blob.Bytes(),
+	)))
+	require.NoError(t, err)
+
+	graph := sizes.NewGraphWithOptions(sizes.ScanOptions{})
+	graph.RegisterBlob(blob, 9)
+	require.NoError(t, graph.RegisterTree(treeOID, tree))
+	h := graph.HistorySize()
+	assert.Equal(t, counts.Count32(1), h.EmptyFilenameCount)
+	assert.Equal(t, counts.Count32(2), h.MaxTreeEntries)
+
+	graph = sizes.NewGraphWithOptions(sizes.ScanOptions{Strict: true})
+	graph.RegisterBlob(blob, 9)
+	assert.ErrorIs(t, graph.RegisterTree(treeOID, tree), sizes.ErrCorruptTree)
+}
+
+func TestUnusualHeaders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "unusual-headers")
+	defer testRepo.Remove(t)
+
+	// Porcelain won't create objects like these, so write them by
+	// hand:
+	hashObject := func(objectType, contents string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "hash-object", "-t", objectType, "--literally", "-w", "--stdin")
+		cmd.Stdin = strings.NewReader(contents)
+		out, err := cmd.Output()
+		require.NoError(t, err, "writing %s", objectType)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	emptyTree := hashObject("tree", "")
+	base := hashObject("commit", fmt.Sprintf(
+		"tree %s\n"+
+			"author A U Thor <author@example.com> 1112911993 -0700\n"+
+			"committer C O Mitter <committer@example.com> 1112911993 -0700\n"+
+			"\n"+
+			"base\n",
+		emptyTree,
+	))
+	crafted := hashObject("commit", fmt.Sprintf(
+		"tree %s\n"+
+			"parent %s\n"+
+			"parent %s\n"+
+			"parent %s\n"+
+			"author A U Thor <author@example.com> 1112911993 -0700\n"+
+			"committer C O Mitter <committer@example.com> 1112911993 -0700\n"+
+			"encoding %s\n"+
+			"x-unknown whatever\n"+
+			"\n"+
+			"crafted\n",
+		emptyTree, base, base, base, strings.Repeat("x", 10000),
+	))
+	testRepo.UpdateRef(t, "refs/heads/master", crafted)
+
+	tag := hashObject("tag", fmt.Sprintf(
+		"object %s\n"+
+			"type commit\n"+
+			"tag v1\n"+
+			"tag v1-again\n"+
+			"tagger T A Gger <tagger@example.com> 1112911993 -0700\n"+
+			"\n"+
+			"tag\n",
+		base,
+	))
+	testRepo.UpdateRef(t, "refs/tags/v1", tag)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	assert.Equal(t, counts.Count32(2), h.UniqueCommitCount, "unique commit count")
+	assert.Equal(t, counts.Count32(len("encoding ")+10000), h.MaxHeaderLineLength)
+	assert.Equal(t, crafted, h.MaxHeaderLineLengthObject.OID)
+	assert.Equal(t, counts.Count32(1), h.UnknownHeaderObjectCount)
+	assert.Equal(t, crafted, h.UnknownHeaderObject.OID)
+	assert.Equal(t, counts.Count32(2), h.DuplicateHeaderObjectCount)
+	require.NotNil(t, h.DuplicateHeaderObject)
+}
+
+func TestAuthors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "authors")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for i, author := range []string{
+		"alice@example.com",
+		"bob@example.com",
+		"Alice@Example.com",
+		"alice@example.com",
+		"carol@example.com",
+	} {
+		cmd := testRepo.GitCommand(
+			t, "commit", "--allow-empty", "-m", fmt.Sprintf("commit %d", i),
+		)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		// Later entries override the ones from `AddAuthorInfo()`:
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_EMAIL="+author)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+
+	assert.Equal(t, counts.Count32(3), h.UniqueAuthorCount, "unique author count")
+	assert.Equal(t, counts.Count32(1), h.UniqueCommitterCount, "unique committer count")
+	assert.Equal(t, counts.Count32(3), h.MaxSingleAuthorCommitCount, "max single author commit count")
+}
+
+func TestTreemap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "treemap")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "README", "hello\n")
+	testRepo.AddFile(t, "src/main.go", strings.Repeat("m", 100))
+	testRepo.AddFile(t, "src/lib/a.go", strings.Repeat("a", 200))
+	testRepo.AddFile(t, "src/lib/b.go", strings.Repeat("a", 200))
+	testRepo.AddFile(t, "docs/guide.txt", strings.Repeat("g", 1000))
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	sizesByPath := make(map[string]counts.Count64)
+	blobCounts := make(map[string]counts.Count32)
+	var paths []string
+	err := sizes.WalkTreemap(ctx, repo, "HEAD", func(node sizes.TreemapNode) error {
+		paths = append(paths, node.Path)
+		sizesByPath[node.Path] = node.Size.ExpandedBlobSize
+		blobCounts[node.Path] = node.Size.ExpandedBlobCount
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "docs", "src", "src/lib"}, paths)
+	assert.Equal(t, map[string]counts.Count64{
+		"":        1506,
+		"docs":    1000,
+		"src":     500,
+		"src/lib": 400,
+	}, sizesByPath)
+	// Identical blobs are counted once per path:
+	assert.Equal(t, counts.Count32(2), blobCounts["src/lib"])
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WriteTreemap(ctx, repo, "HEAD", &buf))
+	assert.Equal(t, "1506\t.\n1000\tdocs\n500\tsrc\n400\tsrc/lib\n", buf.String())
+}
+
+func TestDefaultBranchShare(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "default-branch-share")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "README", strings.Repeat("r", 1000))
+	commit("initial")
+	require.NoError(t, testRepo.GitCommand(t, "branch", "-M", "main").Run())
+
+	// A side branch carrying a large blob that main doesn't have:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "-b", "side").Run())
+	testRepo.AddFile(t, "big.bin", strings.Repeat("b", 3000))
+	commit("add big file")
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "main").Run())
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone, DefaultBranch: "HEAD"},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	require.NotNil(t, h.DefaultBranchShare)
+	share := *h.DefaultBranchShare
+	assert.Equal(t, "refs/heads/main", share.DefaultBranch)
+	assert.Equal(t, counts.Count64(1000), share.DefaultBranchBlobSize)
+	assert.Equal(t, counts.Count64(3000), share.OtherRefsBlobSize)
+	assert.Equal(t, counts.Count64(4000), share.AllRefsBlobSize)
+	assert.Equal(t, counts.Count32(75), share.OtherRefsBlobPercent)
+
+	// The default branch can be overridden:
+	share, err = sizes.ScanDefaultBranchShare(ctx, repo, "refs/heads/side", roots)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count64(4000), share.DefaultBranchBlobSize)
+	assert.Equal(t, counts.Count64(0), share.OtherRefsBlobSize)
+	assert.Equal(t, counts.Count32(0), share.OtherRefsBlobPercent)
+
+	// The default branch counts towards the total even if it wasn't
+	// selected:
+	share, err = sizes.ScanDefaultBranchShare(ctx, repo, "main", nil)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count64(1000), share.DefaultBranchBlobSize)
+	assert.Equal(t, counts.Count64(0), share.OtherRefsBlobSize)
+	assert.Equal(t, counts.Count64(1000), share.AllRefsBlobSize)
+	assert.Equal(t, counts.Count32(0), share.OtherRefsBlobPercent)
+
+	// Without the option, the comparison is skipped:
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+	assert.Nil(t, h.DefaultBranchShare)
+}
+
+func TestRefKindShares(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "ref-kind-shares")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "README", strings.Repeat("r", 1000))
+	commit("initial")
+	require.NoError(t, testRepo.GitCommand(t, "branch", "-M", "main").Run())
+
+	// A tag on a commit that no branch contains, and a pull-request
+	// reference on top of that, carrying a large blob of its own:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "-b", "work").Run())
+	testRepo.AddFile(t, "tagged.bin", strings.Repeat("t", 2000))
+	commit("tagged")
+	require.NoError(t, testRepo.GitCommand(t, "tag", "v1").Run())
+	testRepo.AddFile(t, "pr.bin", strings.Repeat("p", 50000))
+	commit("pull request")
+	require.NoError(t, testRepo.GitCommand(t, "update-ref", "refs/pull/1/head", "HEAD").Run())
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "main").Run())
+	require.NoError(t, testRepo.GitCommand(t, "branch", "-D", "work").Run())
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone, RefKinds: true},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	// Each kind gets one commit, one tree, and one blob; the objects
+	// that the pull request shares with the tag and the branch are
+	// attributed to those:
+	require.NotNil(t, h.RefKindShares)
+	shares := *h.RefKindShares
+	assert.Equal(t, counts.Count32(3), shares.Branches.ObjectCount)
+	assert.Equal(t, counts.Count32(3), shares.TagsOnly.ObjectCount)
+	assert.Equal(t, counts.Count32(3), shares.OtherOnly.ObjectCount)
+	assert.Greater(t, uint64(shares.Branches.ObjectSize), uint64(1000))
+	assert.Greater(t, uint64(shares.TagsOnly.ObjectSize), uint64(2000))
+	assert.Greater(t, uint64(shares.OtherOnly.ObjectSize), uint64(50000))
+	assert.Equal(t, counts.Count32(94), shares.OtherOnlyPercent)
+
+	// Without the option, the breakdown is skipped:
+	h, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+	assert.Nil(t, h.RefKindShares)
+}
+
+func TestDumpDOT(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "dump-dot")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "README", strings.Repeat("r", 100))
+	testRepo.AddFile(t, "a/file.txt", strings.Repeat("a", 300))
+	testRepo.AddFile(t, "a/deep/file.txt", strings.Repeat("d", 100))
+	testRepo.AddFile(t, "b \"quoted\"/file.txt", strings.Repeat("a", 300))
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	root, err := repo.ResolveObject("HEAD^{tree}")
+	require.NoError(t, err)
+	a, err := repo.ResolveObject("HEAD:a")
+	require.NoError(t, err)
+	deep, err := repo.ResolveObject("HEAD:a/deep")
+	require.NoError(t, err)
+	b, err := repo.ResolveObject("HEAD:b \"quoted\"")
+	require.NoError(t, err)
+
+	treeSizes := sizes.NewMemoryCacheBackend()
+	_, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, []sizes.Root{sizes.NewExplicitRoot("HEAD", root)},
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone, TreeSizes: treeSizes},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.DumpDOT(ctx, repo, treeSizes, &buf, root, 1))
+	dot := buf.String()
+
+	assert.True(t, strings.HasPrefix(dot, "digraph trees {\n"), dot)
+	assert.True(t, strings.HasSuffix(dot, "}\n"), dot)
+	assert.Contains(
+		t, dot,
+		fmt.Sprintf("\t%q [label=\"%s\\n3 entries\", fillcolor=\"#ff0000\"];\n", root, root.String()[:8]),
+	)
+	// `a` holds 400 of the 800 bytes, so it is half red:
+	assert.Contains(
+		t, dot,
+		fmt.Sprintf("\t%q [label=\"%s\\n2 entries\", fillcolor=\"#ff8080\"];\n", a, a.String()[:8]),
+	)
+	assert.Contains(t, dot, fmt.Sprintf("\t%q -> %q [label=\"a\"];\n", root, a))
+	assert.Contains(t, dot, fmt.Sprintf("\t%q -> %q [label=\"b \\\"quoted\\\"\"];\n", root, b))
+	// `a/deep` is too deep:
+	assert.NotContains(t, dot, deep.String())
+
+	buf.Reset()
+	require.NoError(t, sizes.DumpDOT(ctx, repo, treeSizes, &buf, root, 2))
+	assert.Contains(t, buf.String(), fmt.Sprintf("\t%q -> %q [label=\"deep\"];\n", a, deep))
+}
+
+func TestVerifyOIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "verify-oids")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "original", git.EmptyTreeOID.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	commitOID, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/main", commitOID)
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	scan := func() sizes.HistorySize {
+		t.Helper()
+		h, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, roots,
+			sizes.ScanOptions{NameStyle: sizes.NameStyleNone, VerifyOIDs: true},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err)
+		return h
+	}
+
+	h := scan()
+	require.NotNil(t, h.CorruptObjectCount)
+	assert.Equal(t, counts.Count32(0), *h.CorruptObjectCount)
+
+	// Tamper with the commit message without changing its length:
+	data, err := testRepo.GitCommand(t, "cat-file", "commit", commitOID.String()).Output()
+	require.NoError(t, err)
+	data = bytes.Replace(data, []byte("original"), []byte("tampered"), 1)
+
+	path, err := repo.LooseObjectPath(commitOID)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "commit %d\x00", len(data))
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.Chmod(path, 0o644))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	h = scan()
+	require.NotNil(t, h.CorruptObjectCount)
+	assert.Equal(t, counts.Count32(1), *h.CorruptObjectCount)
+	assert.Equal(
+		t,
+		[]git.OIDMismatch{
+			{
+				OID:         commitOID,
+				ObjectType:  "commit",
+				ComputedOID: git.ComputeOID("commit", data),
+			},
+		},
+		h.CorruptObjects,
+	)
+}
+
+func TestDominantPrefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	newRepo := func(t *testing.T, name string, files map[string]int) *git.Repository {
+		t.Helper()
+
+		testRepo := testutils.NewTestRepo(t, false, name)
+		t.Cleanup(func() { testRepo.Remove(t) })
+
+		timestamp := time.Unix(1112911993, 0)
+		for path, size := range files {
+			// Make each file's contents distinct:
+			testRepo.AddFile(t, path, path+strings.Repeat("x", size-len(path)))
+		}
+		cmd := testRepo.GitCommand(t, "commit", "-m", "files")
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+
+		return testRepo.Repository(t)
+	}
+
+	t.Run("skewed", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t, "dominant-prefix-skewed", map[string]int{
+			"README":                      100,
+			"services/api/main.go":        400,
+			"services/legacy/old.c":       4000,
+			"services/legacy/vendor/a.js": 4500,
+			"services/legacy/vendor/b.js": 500,
+			"docs/guide.txt":              500,
+		})
+
+		p, err := sizes.FindDominantPrefix(ctx, repo, "HEAD", sizes.DominantPrefixOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, counts.Count64(10000), p.TotalSize)
+		assert.Equal(t, "services/legacy", p.Path)
+		assert.Equal(t, counts.Count64(9000), p.Size)
+		assert.InDelta(t, 90.0, p.Percent, 1e-9)
+		assert.False(t, p.Split)
+
+		// With a lower bar, it descends further:
+		p, err = sizes.FindDominantPrefix(
+			ctx, repo, "HEAD", sizes.DominantPrefixOptions{Coverage: 0.5},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "services/legacy/vendor", p.Path)
+		assert.InDelta(t, 50.0, p.Percent, 1e-9)
+
+		var buf bytes.Buffer
+		require.NoError(t, sizes.WriteDominantPrefix(&buf, p))
+		assert.Equal(
+			t,
+			"Total blob size: 9.77 KiB\n"+
+				"Dominant prefix: services/legacy/vendor/: 4.88 KiB (50.0%)\n",
+			buf.String(),
+		)
+	})
+
+	t.Run("balanced", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t, "dominant-prefix-balanced", map[string]int{
+			"README":        1000,
+			"frontend/a.js": 4000,
+			"backend/a.go":  3500,
+			"tools/a.sh":    1500,
+		})
+
+		p, err := sizes.FindDominantPrefix(ctx, repo, "HEAD", sizes.DominantPrefixOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "", p.Path, "the root is the answer")
+		assert.InDelta(t, 100.0, p.Percent, 1e-9)
+		assert.True(t, p.Split)
+		assert.Equal(
+			t,
+			[]sizes.PrefixShare{
+				{Path: "frontend", Size: 4000, Percent: 40},
+				{Path: "backend", Size: 3500, Percent: 35},
+				{Path: "tools", Size: 1500, Percent: 15},
+			},
+			p.Siblings,
+		)
+
+		// If the subdirectories can't cover the share, it's not a
+		// split:
+		p, err = sizes.FindDominantPrefix(
+			ctx, repo, "HEAD", sizes.DominantPrefixOptions{Coverage: 0.95},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "", p.Path)
+		assert.False(t, p.Split)
+		assert.Empty(t, p.Siblings)
+
+		// ...or if too many are needed:
+		p, err = sizes.FindDominantPrefix(
+			ctx, repo, "HEAD", sizes.DominantPrefixOptions{MaxSiblings: 2},
+		)
+		require.NoError(t, err)
+		assert.False(t, p.Split)
+	})
+
+	t.Run("tie", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(t, "dominant-prefix-tie", map[string]int{
+			"one/a.bin": 2000,
+			"two/a.bin": 2000,
+			"three/a":   100,
+		})
+
+		// Both `one` and `two` cover 40%, but neither is preferred:
+		p, err := sizes.FindDominantPrefix(
+			ctx, repo, "HEAD", sizes.DominantPrefixOptions{Coverage: 0.4},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "", p.Path)
+		assert.True(t, p.Split)
+		require.Len(t, p.Siblings, 2)
+		assert.Equal(t, "one", p.Siblings[0].Path)
+		assert.Equal(t, "two", p.Siblings[1].Path)
+	})
+}
+
+func TestMaxBlobLineCount(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "max-blob-line-count")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "short.txt", "one\ntwo\n")
+	testRepo.AddFile(t, "src/long.go", strings.Repeat("x := 1\n", 500))
+	// Bigger, but with fewer lines:
+	testRepo.AddFile(t, "wide.txt", strings.Repeat("y", 10000)+"\n")
+	// More LFs, but binary, so it is skipped:
+	testRepo.AddFile(t, "data.bin", strings.Repeat("\x00\n", 1000))
+	cmd := testRepo.GitCommand(t, "commit", "-m", "files")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	expectedOID, err := repo.ResolveObject("HEAD:src/long.go")
+	require.NoError(t, err)
+
+	lines, blob, err := sizes.MaxBlobLineCount(ctx, repo, roots, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(500), lines)
+	require.NotNil(t, blob)
+	assert.Equal(t, expectedOID, blob.OID)
+	assert.Equal(t, "src/long.go", blob.Path())
+
+	// Only the first `maxReadSize` bytes are read:
+	lines, blob, err = sizes.MaxBlobLineCount(ctx, repo, roots, 70)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(10), lines)
+	assert.Equal(t, expectedOID, blob.OID)
+}
+
+func TestAnalyzeIgnoredFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "analyze-ignored-files")
+	defer testRepo.Remove(t)
+
+	testRepo.AddFile(t, ".gitignore", "*.env\n")
+	timestamp := time.Unix(1112911993, 0)
+	cmd := testRepo.GitCommand(t, "commit", "-m", "ignore env files")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	for name, size := range map[string]int{"prod.env": 100, "dev.env": 23, "README": 1000} {
+		require.NoError(t, os.WriteFile(
+			filepath.Join(testRepo.Path, name), bytes.Repeat([]byte{'x'}, size), 0o644,
+		))
+	}
+
+	count, size, err := sizes.AnalyzeIgnoredFiles(ctx, testRepo.Repository(t))
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(2), count)
+	assert.Equal(t, counts.Count64(123), size)
+
+	bareRepo := testutils.NewTestRepo(t, true, "analyze-ignored-files-bare")
+	defer bareRepo.Remove(t)
+
+	_, _, err = sizes.AnalyzeIgnoredFiles(ctx, bareRepo.Repository(t))
+	assert.ErrorIs(t, err, git.ErrBareRepository)
+}
+
+func TestFileAgesInHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "file-ages")
+	defer testRepo.Remove(t)
+
+	const day = 24 * time.Hour
+	start := time.Unix(1112911993, 0)
+
+	commit := func(offset time.Duration, files map[string]string) {
+		t.Helper()
+		for path, contents := range files {
+			testRepo.AddFile(t, path, contents)
+		}
+		timestamp := start.Add(offset)
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("day %d", offset/day))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	commit(0, map[string]string{"a.txt": "a\n", "b.txt": "b1\n"})
+	commit(10*day, map[string]string{"b.txt": "b2\n", "c.txt": "c1\n"})
+	commit(20*day, map[string]string{"c.txt": "c2\n"})
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	now := start.Add(30*day + time.Hour)
+
+	// The blobs are 30, 30, 20, 20, and 10 days old:
+	ages, err := sizes.FileAgesInHistory(ctx, repo, roots, now)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count64(30), ages.MaxAge)
+	assert.Equal(t, counts.Count64(20), ages.MedianAge)
+	require.NotNil(t, ages.MaxAgeBlob)
+	oldest := ages.MaxAgeBlob.Path()
+	assert.Regexp(t, `^[0-9a-f]{40}:[ab]\.txt$`, oldest)
+	expectedOID, err := repo.ResolveObject("HEAD~2:" + oldest[41:])
+	require.NoError(t, err)
+	assert.Equal(t, expectedOID, ages.MaxAgeBlob.OID)
+
+	// File ages are informational, so they are shown whenever they
+	// were computed, but never with a level of concern:
+	h := sizes.HistorySize{
+		MaxFileAgeInHistory:     &ages.MaxAge,
+		MaxFileAgeInHistoryBlob: ages.MaxAgeBlob,
+		MedianFileAgeInHistory:  &ages.MedianAge,
+	}
+	table := h.TableString(nil, 1, sizes.NameStyleNone)
+	assert.Regexp(t, `\* Oldest file \(days\) +\| +30 +\| +\|`, table)
+	assert.Regexp(t, `\* Median file age \(days\) +\| +20 +\| +\|`, table)
+
+	// That includes ages of zero days:
+	var zero counts.Count64
+	h = sizes.HistorySize{MaxFileAgeInHistory: &zero, MedianFileAgeInHistory: &zero}
+	table = h.TableString(nil, 1, sizes.NameStyleNone)
+	assert.Regexp(t, `\* Oldest file \(days\) +\| +0 +\| +\|`, table)
+}
+
+func TestEstimateCloneCost(t *testing.T) {
+	t.Parallel()
+
+	h := sizes.HistorySize{
+		UniqueCommitCount:    100e3,
+		UniqueTreeCount:      300e3,
+		UniqueBlobCount:      600e3,
+		MaxExpandedBlobCount: 10e3,
+		MaxExpandedBlobSize:  400e6,
+		Storage: &sizes.StorageBreakdown{
+			PackSize:                 900e6,
+			LooseObjectSize:          150e6,
+			RedundantLooseObjectSize: 50e6,
+		},
+	}
+
+	e := h.EstimateCloneCost(10e6)
+	assert.Equal(t, counts.Count64(1000e6), e.DownloadSize)
+	assert.Equal(t, 100*time.Second, e.DownloadTime)
+	assert.Equal(t, 10*time.Second, e.IndexTime)
+	assert.Equal(t, 4*time.Second, e.CheckoutTime)
+	assert.Equal(t, 114*time.Second, e.Total)
+
+	// A faster connection only speeds up the download:
+	e = h.EstimateCloneCost(100e6)
+	assert.Equal(t, 10*time.Second, e.DownloadTime)
+	assert.Equal(t, 24*time.Second, e.Total)
+
+	// Without storage information, the object sizes are used:
+	h.Storage = nil
+	h.UniqueBlobSize = 2000e6
+	e = h.EstimateCloneCost(10e6)
+	assert.Equal(t, h.TotalObjectDataSize(), e.DownloadSize)
+}
+
+func TestSaturation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "saturation")
+	defer testRepo.Remove(t)
+
+	// 10^11 files in the checkout, which is more than a `Count32`
+	// can hold:
+	newGitBomb(t, testRepo, 11, 10, "boom!\n")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, counts.Count32(math.MaxUint32), h.MaxExpandedBlobCount)
+	// The blob size is 6 bytes, so this doesn't saturate a `Count64`:
+	assert.Equal(t, counts.Count64(6e11), h.MaxExpandedBlobSize)
+
+	assert.Equal(
+		t,
+		[]sizes.SaturatedMetric{
+			{Key: "/maxCheckoutBlobCount", Value: math.MaxUint32},
+			{Key: "/maxCheckoutPathCount", Value: math.MaxUint32},
+			{Key: "/maxCheckoutTreeCount", Value: math.MaxUint32},
+			{Key: "/maxCheckoutTreeWidth", Value: math.MaxUint32},
+		},
+		h.SaturatedMetrics(nil),
+	)
+
+	j, err := h.JSON(nil, 0, sizes.NameStyleNone)
+	require.NoError(t, err)
+	var decoded map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(j, &decoded))
+	item := func(name string) map[string]interface{} {
+		t.Helper()
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(decoded[name], &v))
+		return v
+	}
+	assert.Equal(t, true, item("maxCheckoutBlobCount")["saturated"])
+	assert.Equal(t, true, item("maxCheckoutBlobCount")["approximate"])
+	assert.NotContains(t, item("maxCheckoutBlobSize"), "saturated")
+	assert.NotContains(t, item("maxCheckoutBlobSize"), "approximate")
+
+	// The saturation is also reported as a problem:
+	var problems []struct {
+		Code     string `json:"code"`
+		Severity string `json:"severity"`
+		Count    int    `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(decoded["problems"], &problems))
+	require.Len(t, problems, 1)
+	assert.Equal(t, "scan.saturated_metric", problems[0].Code)
+	assert.Equal(t, "warning", problems[0].Severity)
+	assert.Equal(t, 4, problems[0].Count)
+
+	assert.Contains(t, h.TableString(nil, 0, sizes.NameStyleNone), "| >=4.29 G   |")
+}
+
+func TestEstimatePurge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "purge")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	big := strings.Repeat("a", 1000)
+	testRepo.AddFile(t, "big.bin", big)
+	testRepo.AddFile(t, "keep/same.dat", big)
+	testRepo.AddFile(t, "data/huge.iso", strings.Repeat("b", 2000))
+	testRepo.AddFile(t, "src/main.go", strings.Repeat("c", 100))
+	commit("initial")
+
+	testRepo.AddFile(t, "data/huge.iso", strings.Repeat("d", 3000))
+	commit("bigger")
+
+	tagged := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Repeat("e", 50))
+		return err
+	})
+	testRepo.UpdateRef(t, "refs/tags/blob", tagged)
+
+	repo := testRepo.Repository(t)
+	roots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	var rs []sizes.Root
+	for _, root := range roots {
+		rs = append(rs, root)
+	}
+
+	estimate := func(spec sizes.PurgeSpec) sizes.PurgeEstimate {
+		t.Helper()
+		e, err := sizes.EstimatePurge(ctx, repo, rs, spec)
+		require.NoError(t, err)
+		assert.Equal(t, counts.Count32(5), e.TotalBlobCount)
+		assert.Equal(t, counts.Count64(6150), e.TotalBlobSize)
+		return e
+	}
+
+	// Purging a directory removes every version of the files under it:
+	e := estimate(sizes.PurgeSpec{Paths: []string{"data"}})
+	assert.Equal(t, counts.Count32(2), e.PurgedBlobCount)
+	assert.Equal(t, counts.Count64(5000), e.PurgedBlobSize)
+	assert.Equal(t, counts.Count32(0), e.RetainedBlobCount)
+	assert.InDelta(t, 100*5000.0/6150.0, e.PurgedPercent, 1e-9)
+
+	// A blob that also appears at another path is retained:
+	e = estimate(sizes.PurgeSpec{Paths: []string{"big.bin"}})
+	assert.Equal(t, counts.Count32(0), e.PurgedBlobCount)
+	assert.Equal(t, counts.Count32(1), e.RetainedBlobCount)
+	assert.Equal(t, counts.Count64(1000), e.RetainedBlobSize)
+
+	// ...unless both paths are purged:
+	e = estimate(sizes.PurgeSpec{Paths: []string{"big.bin", "*.dat"}})
+	assert.Equal(t, counts.Count32(1), e.PurgedBlobCount)
+	assert.Equal(t, counts.Count64(1000), e.PurgedBlobSize)
+
+	// Blobs can be purged by OID, even if a tag points at them:
+	mainGo, err := repo.ResolveObject("HEAD:src/main.go")
+	require.NoError(t, err)
+	e = estimate(sizes.PurgeSpec{Blobs: []git.OID{mainGo, tagged}})
+	assert.Equal(t, counts.Count32(2), e.PurgedBlobCount)
+	assert.Equal(t, counts.Count64(150), e.PurgedBlobSize)
+	require.Len(t, e.BlobPaths, 2)
+	assert.Equal(t, mainGo, e.BlobPaths[0].OID)
+	assert.Equal(t, []string{"src/main.go"}, e.BlobPaths[0].Paths.Paths)
+	// The tagged blob doesn't appear in any tree:
+	assert.Equal(t, tagged, e.BlobPaths[1].OID)
+	assert.Equal(t, counts.Count32(0), e.BlobPaths[1].Paths.Count)
+
+	// Only the first few paths of a blob are retained, but all of
+	// them are counted, once each:
+	bigBin, err := repo.ResolveObject("HEAD:big.bin")
+	require.NoError(t, err)
+	e = estimate(sizes.PurgeSpec{Blobs: []git.OID{bigBin}, MaxPathsPerBlob: 1})
+	require.Len(t, e.BlobPaths, 1)
+	assert.Equal(t, counts.Count32(2), e.BlobPaths[0].Paths.Count)
+	assert.Equal(t, []string{"big.bin"}, e.BlobPaths[0].Paths.Paths)
+	assert.Equal(t, "appears at 2 paths (showing 1)", e.BlobPaths[0].Paths.String())
+
+	// Path patterns don't affect a blob that a tag points at:
+	e = estimate(sizes.PurgeSpec{Paths: []string{"*"}})
+	assert.Equal(t, counts.Count32(4), e.PurgedBlobCount)
+	assert.Equal(t, counts.Count64(6100), e.PurgedBlobSize)
+	assert.Equal(t, counts.Count32(0), e.RetainedBlobCount)
+}
+
+func TestProblemCodes(t *testing.T) {
+	t.Parallel()
+
+	codes := sizes.ProblemCodes()
+	seen := make(map[sizes.ProblemCode]bool)
+	for _, code := range codes {
+		assert.Falsef(t, seen[code], "duplicate problem code %q", code)
+		seen[code] = true
+
+		assert.Regexpf(t, `^[a-z]+\.[a-z_]+$`, string(code), "malformed problem code %q", code)
+		def, ok := code.Definition()
+		assert.True(t, ok)
+		assert.NotEmptyf(t, def.Description, "problem code %q has no description", code)
+	}
+
+	_, ok := sizes.ProblemCode("no.such_problem").Definition()
+	assert.False(t, ok)
+}
+
+func TestProblemsOnStderr(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "problems-on-stderr")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	testRepo.AddFile(t, "README", "Hello, world!\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+	require.NoError(t, testRepo.GitCommand(t, "tag", "master").Run())
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(sizerExe(t), append([]string{"--no-progress"}, args...)...)
+		cmd.Dir = testRepo.Path
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		require.NoError(t, cmd.Run(), "running git-sizer %v", args)
+		return stderr.String()
+	}
+
+	// Only the table, which doesn't list the problems itself,
+	// reports them on stderr:
+	assert.Equal(
+		t,
+		"warning: tag refs/tags/master has the same name as branch refs/heads/master\n",
+		run(),
+	)
+	assert.Empty(t, run("--json"))
+	assert.Empty(t, run("--json", "--json-version=2"))
+	assert.Empty(t, run("--metric", "uniqueCommitCount"))
+}
+
+func TestCheckConfig(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "check-config")
+	defer testRepo.Remove(t)
+
+	testRepo.ConfigAdd(t, "core.compression", "0")
+
+	repo := testRepo.Repository(t)
+
+	scan := func(checkConfig bool) sizes.HistorySize {
+		t.Helper()
+		h, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, nil,
+			sizes.ScanOptions{NameStyle: sizes.NameStyleNone, CheckConfig: checkConfig},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err)
+		return h
+	}
+
+	// The gitconfig is only read if requested:
+	h := scan(false)
+	assert.Nil(t, h.RelevantConfig)
+	assert.Empty(t, h.Problems(nil))
+
+	h = scan(true)
+	assert.Equal(t, "0", h.RelevantConfig["core.compression"])
+	groups := h.Problems(nil)
+	require.Len(t, groups, 1)
+	assert.Equal(t, sizes.ProblemCompressionDisabled, groups[0].Code)
+}
+
+func TestProblems(t *testing.T) {
+	t.Parallel()
+
+	oid := func(s string) git.OID {
+		oid, err := git.NewOID(strings.Repeat(s, 40))
+		require.NoError(t, err)
+		return oid
+	}
+
+	var corrupt []git.OIDMismatch
+	for i := 0; i < 12; i++ {
+		corrupt = append(corrupt, git.OIDMismatch{
+			OID: oid("1"), ObjectType: "blob", ComputedOID: oid("2"),
+		})
+	}
+	corruptCount := counts.Count32(len(corrupt))
+
+	// Trigger every kind of problem that a scan can detect:
+	h := sizes.HistorySize{
+		LongFilenameCount: 3,
+		LongFilenames: []sizes.LongFilename{
+			{Name: strings.Repeat("x", 300), Length: 300},
+		},
+		WideTreeCount: 5,
+		WideTrees: []sizes.WideTree{
+			{Entries: 20000, Tree: &sizes.Path{OID: oid("5")}},
+		},
+		EmptyTreeCount:              2,
+		EmptyFilenameCount:          1,
+		UnixSocketCount:             1,
+		UnknownFiletypeCount:        3,
+		MixedTypeCaseCollisionCount: 2,
+		MixedTypeCaseCollisions: []sizes.CaseCollision{
+			{
+				Name1: "Config", Mode1: "120000", Name2: "config", Mode2: "100644",
+				Tree: &sizes.Path{OID: oid("4")},
+			},
+		},
+		UnknownHeaderObjectCount:   1,
+		DuplicateHeaderObjectCount: 1,
+		CorruptObjectCount:         &corruptCount,
+		CorruptObjects:             corrupt,
+		RepositoryConfig: &sizes.RepositoryConfigStats{
+			DefaultBranch:            "refs/heads/gone",
+			DefaultBranchMissing:     1,
+			DetachedHead:             1,
+			DanglingSymbolicRefCount: 1,
+			DanglingSymbolicRefs: []git.SymbolicReference{
+				{Refname: "refs/remotes/origin/HEAD", Target: "refs/remotes/origin/gone"},
+			},
+		},
+		MaxExpandedBlobCount:          math.MaxUint32,
+		MaxTreeSaturated:              sizes.SaturatedExpandedBlobCount,
+		MaxFutureCommitDateSkew:       2 * 24 * 60 * 60,
+		MaxFutureCommitDateSkewCommit: &sizes.Path{OID: oid("3")},
+		Storage: &sizes.StorageBreakdown{
+			LooseObjectCount: 10000,
+			LooseObjectSize:  200 * 1024 * 1024,
+		},
+		UniqueCommitCount: 150000,
+		Bitmap:            &sizes.BitmapStatus{},
+		AccessPath:        sizes.AccessPathBitmap,
+		RefStats: sizes.RefStats{
+			CaseConflictCount: 1,
+			CaseConflicts: []sizes.RefNamePair{
+				{Refname1: "refs/tags/V1.0", Refname2: "refs/tags/v1.0"},
+			},
+			TagShadowingBranchCount: 1,
+			TagsShadowingBranches: []sizes.RefNamePair{
+				{Refname1: "refs/tags/main", Refname2: "refs/heads/main"},
+			},
+		},
+		RelevantConfig: map[string]string{
+			"gc.auto":          "0",
+			"gc.pruneExpire":   "never",
+			"core.compression": "0",
+			"pack.compression": "9",
+		},
+	}
+
+	groups := h.Problems(nil)
+
+	// Every registered code is emitted by some detection, and every
+	// emitted code is registered:
+	var emitted []sizes.ProblemCode
+	for _, g := range groups {
+		emitted = append(emitted, g.Code)
+		def, ok := g.Code.Definition()
+		require.Truef(t, ok, "problem code %q is not registered", g.Code)
+		assert.Equal(t, def.Severity, g.Severity)
+	}
+	sort.Slice(emitted, func(i, j int) bool { return emitted[i] < emitted[j] })
+	assert.Equal(t, sizes.ProblemCodes(), emitted)
+
+	// The most severe problems come first:
+	assert.Equal(t, sizes.ProblemCorruptObject, groups[0].Code)
+	assert.Equal(t, sizes.SeverityError, groups[0].Severity)
+	assert.Equal(t, counts.Count32(12), groups[0].Count)
+	assert.Len(t, groups[0].Examples, 10)
+	assert.Equal(
+		t,
+		"blob 1111111111111111111111111111111111111111 is corrupt; "+
+			"its contents hash to 2222222222222222222222222222222222222222",
+		groups[0].Examples[0],
+	)
+
+	byCode := make(map[sizes.ProblemCode]sizes.ProblemGroup)
+	for _, g := range groups {
+		byCode[g.Code] = g
+	}
+	assert.Equal(
+		t,
+		[]string{"commit 3333333333333333333333333333333333333333 is dated 48h0m0s in the future"},
+		byCode[sizes.ProblemFutureCommitDate].Examples,
+	)
+	assert.Equal(
+		t,
+		[]string{"there is no reachability bitmap, but there are 150000 commits"},
+		byCode[sizes.ProblemMissingBitmap].Examples,
+	)
+	assert.Equal(t, sizes.SeverityError, byCode[sizes.ProblemEmptyFilename].Severity)
+	assert.Contains(
+		t, byCode[sizes.ProblemSaturatedMetric].Examples,
+		"/maxCheckoutBlobCount saturated at 4294967295; the true value is at least that big",
+	)
+	assert.Equal(
+		t,
+		[]string{"gc.pruneExpire=never, and there are 200 MiB of loose objects, which will never be pruned"},
+		byCode[sizes.ProblemPruneNever].Examples,
+	)
+	assert.Equal(
+		t,
+		[]string{"gc.auto=0, and there are 10000 loose objects"},
+		byCode[sizes.ProblemAutoGCDisabled].Examples,
+	)
+	assert.Equal(
+		t, []string{"core.compression=0"}, byCode[sizes.ProblemCompressionDisabled].Examples,
+	)
+	assert.Equal(t, counts.Count32(3), byCode[sizes.ProblemLongFilename].Count)
+	assert.Len(t, byCode[sizes.ProblemLongFilename].Examples, 1)
+	assert.Equal(t, counts.Count32(2), byCode[sizes.ProblemEmptyTreeEntry].Count)
+	assert.Empty(t, byCode[sizes.ProblemEmptyTreeEntry].Examples)
+	assert.Equal(
+		t,
+		[]string{"refs/remotes/origin/HEAD points at refs/remotes/origin/gone, which doesn't exist"},
+		byCode[sizes.ProblemDanglingSymref].Examples,
+	)
+	assert.Equal(
+		t,
+		[]string{"refs/tags/V1.0 and refs/tags/v1.0 differ only in case"},
+		byCode[sizes.ProblemRefCaseConflict].Examples,
+	)
+	assert.Equal(
+		t,
+		[]string{"tag refs/tags/main has the same name as branch refs/heads/main"},
+		byCode[sizes.ProblemTagShadowsBranch].Examples,
+	)
+	assert.Equal(t, counts.Count32(2), byCode[sizes.ProblemMixedTypeCaseCollision].Count)
+	assert.Equal(
+		t,
+		[]string{
+			`"Config" (mode 120000) and "config" (mode 100644) differ only in case, ` +
+				"in tree 4444444444444444444444444444444444444444",
+		},
+		byCode[sizes.ProblemMixedTypeCaseCollision].Examples,
+	)
+
+	j, err := json.Marshal(groups[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"code":"object.corrupt","severity":"error"`)
+
+	// A clean repository has no problems:
+	assert.Empty(t, (&sizes.HistorySize{}).Problems(nil))
+}
+
+func TestEstimateRepack(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "repack")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	// Pseudo-random contents, so that zlib doesn't shrink them much:
+	var sb strings.Builder
+	x := uint32(1)
+	for sb.Len() < 20000 {
+		x = 1664525*x + 1013904223
+		fmt.Fprintf(&sb, "%08x\n", x)
+	}
+	contents := sb.String()
+
+	// Three versions of the same file, stored loose (so not as
+	// deltas), plus a large file elsewhere that has no siblings and
+	// a small one that is ignored:
+	for i := 0; i < 3; i++ {
+		testRepo.AddFile(t, "assets/model.bin", fmt.Sprintf("version %d\n%s", i, contents))
+		if i == 0 {
+			testRepo.AddFile(t, "other/unrelated.bin", contents)
+			testRepo.AddFile(t, "assets/small.bin", "small\n")
+		}
+		commit(fmt.Sprintf("version %d", i))
+	}
+
+	repo := testRepo.Repository(t)
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	opts := sizes.RepackOptions{MinBlobSize: 10000}
+	e, err := sizes.EstimateRepack(ctx, repo, roots, opts)
+	require.NoError(t, err)
+
+	require.Len(t, e.Candidates, 3)
+	assert.Equal(t, counts.Count32(3), e.CandidateCount)
+	var diskSize, maxDiskSize counts.Count64
+	for _, c := range e.Candidates {
+		assert.Equal(t, "assets/model.bin", c.Path)
+		assert.Equal(t, 2, c.Siblings)
+		assert.Greater(t, uint64(c.DiskSize), uint64(10000))
+		diskSize.Increment(c.DiskSize)
+		maxDiskSize.AdjustMaxIfNecessary(c.DiskSize)
+	}
+	assert.Equal(t, diskSize, e.CandidateDiskSize)
+	assert.Equal(t, counts.Count64(0.5*float64(diskSize-maxDiskSize)), e.MinSavings)
+	assert.Equal(t, counts.Count64(0.95*float64(diskSize-maxDiskSize)), e.MaxSavings)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WriteRepackEstimate(&buf, e, 2))
+	assert.Contains(t, buf.String(), "3 blobs")
+	assert.Contains(t, buf.String(), "...and 1 more")
+
+	// After a repack, the versions are stored as deltas:
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adf").Run())
+	e, err = sizes.EstimateRepack(ctx, repo, roots, opts)
+	require.NoError(t, err)
+	assert.Empty(t, e.Candidates)
+	assert.Equal(t, counts.Count64(0), e.MaxSavings)
+}
+
+func TestObjectTypeBreakdown(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "type-breakdown")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	mktree := func(entries ...string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "mktree", "--missing")
+		cmd.Stdin = strings.NewReader(strings.Join(entries, "\n") + "\n")
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	blob := func(contents string) git.OID {
+		return testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+	}
+
+	shared := blob("shared\n")
+	subtree := mktree(
+		fmt.Sprintf("100644 blob %s\ta.txt", shared),
+		fmt.Sprintf("100644 blob %s\tb.txt", blob("b\n")),
+	)
+	root := mktree(
+		fmt.Sprintf("100644 blob %s\tREADME", shared),
+		fmt.Sprintf("120000 blob %s\tlink", blob("README")),
+		fmt.Sprintf("160000 commit %s\tsub", strings.Repeat("1", 40)),
+		fmt.Sprintf("040000 tree %s\tdir", subtree),
+		fmt.Sprintf("040000 tree %s\tcopy", subtree),
+	)
+
+	cmd := testRepo.GitCommand(t, "commit-tree", "-m", "types", root.String())
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	commit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/main", commit)
+
+	repo := testRepo.Repository(t)
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleNone, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	// The subtree is referred to twice, but examined only once. The
+	// shared blob is counted once for each reference to it:
+	assert.Equal(
+		t,
+		sizes.TypeBreakdown{
+			TreeCount:      2,
+			BlobCount:      3,
+			SymlinkCount:   1,
+			SubmoduleCount: 1,
+		},
+		h.ObjectTypeBreakdown(),
+	)
+	assert.Equal(t, counts.Count64(7), h.UniqueTreeEntries)
+}
+
+// remotesGrouper puts the remote-tracking references in the "remotes"
+// refgroup.
+type remotesGrouper struct{}
+
+func (rg remotesGrouper) Categorize(refname string) (bool, []sizes.RefGroupSymbol) {
+	if strings.HasPrefix(refname, "refs/remotes/") {
+		return true, []sizes.RefGroupSymbol{"remotes"}
+	}
+	return true, nil
+}
+
+func (rg remotesGrouper) Groups() []sizes.RefGroup {
+	return []sizes.RefGroup{{Symbol: "remotes", Name: "Remote-tracking references"}}
+}
+
+func TestSimulateRefDeletion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "simulate-delete")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "small.txt", strings.Repeat("a", 10))
+	commit("initial")
+
+	// A big blob that only a remote-tracking reference knows about:
+	testRepo.AddFile(t, "huge.bin", strings.Repeat("b", 5000))
+	commit("add huge file")
+	cmd := testRepo.GitCommand(t, "update-ref", "refs/remotes/origin/feature", "HEAD")
+	require.NoError(t, cmd.Run(), "creating remote-tracking reference")
+	cmd = testRepo.GitCommand(t, "reset", "--hard", "HEAD~1")
+	require.NoError(t, cmd.Run(), "resetting branch")
+
+	repo := testRepo.Repository(t)
+	refRoots, err := sizes.CollectReferences(ctx, repo, remotesGrouper{})
+	require.NoError(t, err)
+	var roots []sizes.Root
+	for _, root := range refRoots {
+		roots = append(roots, root)
+	}
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, repo, roots, sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+	require.Equal(t, counts.Count64(5010), h.UniqueBlobSize)
+
+	e, err := sizes.SimulateRefDeletion(
+		ctx, repo, refRoots, sizes.RefDeletionSpec{Group: "remotes"}, &h,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refs/remotes/origin/feature"}, e.Refs)
+	assert.Equal(t, counts.Count32(3), e.ObjectCount)
+	assert.Equal(t, counts.Count32(1), e.CommitCount)
+	assert.Equal(t, counts.Count32(1), e.TreeCount)
+	assert.Equal(t, counts.Count32(1), e.BlobCount)
+	assert.Equal(t, counts.Count64(5000), e.BlobSize)
+	assert.Equal(t, counts.Count32(0), e.TagCount)
+
+	projected := make(map[string]sizes.ProjectedMetric)
+	for _, m := range e.Projected {
+		projected[m.Name] = m
+	}
+	assert.Equal(
+		t,
+		sizes.ProjectedMetric{Name: "unique_blob_size", Before: 5010, After: 10},
+		projected["unique_blob_size"],
+	)
+	assert.Equal(t, counts.Count64(1), projected["unique_commit_count"].After)
+	// The biggest blob and the only commit with a parent would be
+	// deleted, so the new maxima are unknown:
+	assert.True(t, projected["max_blob_size"].Unknown)
+	assert.True(t, projected["max_parent_count"].Unknown)
+	// There are no tags, so nothing changes:
+	assert.False(t, projected["max_tag_depth"].Unknown)
+
+	// The same references, selected by a filter:
+	e2, err := sizes.SimulateRefDeletion(
+		ctx, repo, refRoots,
+		sizes.RefDeletionSpec{Filter: git.PrefixFilter("refs/remotes/")}, nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, e.Size, e2.Size)
+	assert.Nil(t, e2.Projected)
+
+	// The reflogs of `HEAD` and of the branch still reach the commit:
+	e3, err := sizes.SimulateRefDeletion(
+		ctx, repo, refRoots,
+		sizes.RefDeletionSpec{Group: "remotes", KeepReflogs: true}, nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count32(0), e3.ObjectCount)
+}
+
+func TestCommitDateSkew(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "date-skew")
+	defer testRepo.Remove(t)
+
+	now := time.Now()
+	old := time.Unix(1112911993, 0)
+	recent := now.Add(-time.Hour).Truncate(time.Second)
+	future := now.Add(10 * 24 * time.Hour)
+
+	var parent string
+	var commits []string
+	for i, timestamp := range []time.Time{old, future, recent} {
+		timestamp := timestamp
+		tree := testRepo.CreateObject(t, "tree", func(w io.Writer) error { return nil })
+		args := []string{"commit-tree", "-m", fmt.Sprintf("commit %d", i), tree.String()}
+		if parent != "" {
+			args = append(args, "-p", parent)
+		}
+		cmd := testRepo.GitCommand(t, args...)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		out, err := cmd.Output()
+		require.NoError(t, err, "creating commit")
+		parent = strings.TrimSpace(string(out))
+		commits = append(commits, parent)
+	}
+	oid, err := git.NewOID(parent)
+	require.NoError(t, err)
+	testRepo.UpdateRef(t, "refs/heads/main", oid)
+
+	h, err := sizes.ScanRepositoryUsingGraph(
+		ctx, testRepo.Repository(t), []sizes.Root{sizes.NewExplicitRoot("main", oid)},
+		sizes.NameStyleFull, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	// The scan starts a little later than `now`:
+	skew, _ := h.MaxFutureCommitDateSkew.ToUint64()
+	assert.LessOrEqual(t, skew, uint64(10*24*60*60))
+	assert.Greater(t, skew, uint64(10*24*60*60-60))
+	assert.Equal(t, commits[1], h.MaxFutureCommitDateSkewCommit.OID.String())
+
+	// The oldest timestamp is compared with the newest one that is
+	// not in the future:
+	assert.Equal(
+		t, counts.NewCount64(uint64(recent.Unix()-old.Unix())), h.MaxPastCommitDateSkew,
+	)
+	assert.Equal(t, commits[0], h.MaxPastCommitDateSkewCommit.OID.String())
+
+	var codes []sizes.ProblemCode
+	for _, g := range h.Problems(nil) {
+		codes = append(codes, g.Code)
+	}
+	assert.Contains(t, codes, sizes.ProblemFutureCommitDate)
+}
+
+// emptyConfigger is a `refopts.Configger` without any refgroups.
+type emptyConfigger struct{}
+
+func (emptyConfigger) GetConfig(prefix string) (*git.Config, error) {
+	return &git.Config{Prefix: prefix}, nil
+}
+
+func TestFlexibleRefFilters(t *testing.T) {
+	t.Parallel()
+
+	refnames := []string{
+		"refs/heads/main",
+		"refs/heads/user@host",
+		"refs/heads/f\u00fcr",
+		"refs/heads/a b",
+		"refs/pull/1/head",
+		"refs/pull/1/merge",
+		"refs/pull/123/head",
+		"refs/changes/45/12345/42",
+		"refs/tags/v1",
+		"@bot/topic",
+	}
+
+	for _, p := range []struct {
+		arg      string
+		expected []string
+	}{
+		{"refs/pull", []string{"refs/pull/1/head", "refs/pull/1/merge", "refs/pull/123/head"}},
+		{"refs/pull/*/head", []string{"refs/pull/1/head", "refs/pull/123/head"}},
+		{"/refs/pull/[0-9]+/merge/", []string{"refs/pull/1/merge"}},
+		{"/refs/heads/main|refs/tags/.*/", []string{"refs/heads/main", "refs/tags/v1"}},
+		{"refs/changes/*/*/42", []string{"refs/changes/45/12345/42"}},
+		{"refs/heads/user@host", []string{"refs/heads/user@host"}},
+		{"refs/heads/f\u00fcr", []string{"refs/heads/f\u00fcr"}},
+		{"refs/heads/a b", []string{"refs/heads/a b"}},
+		// An undefined refgroup is an error:
+		{"@bot", nil},
+		// Not a refgroup, because refgroup names can't contain `/`:
+		{"@bot/topic", []string{"@bot/topic"}},
+		{"@branches", []string{
+			"refs/heads/main", "refs/heads/user@host", "refs/heads/f\u00fcr", "refs/heads/a b",
+		}},
+	} {
+		p := p
+		t.Run(p.arg, func(t *testing.T) {
+			t.Parallel()
+
+			rgb, err := refopts.NewRefGroupBuilder(emptyConfigger{})
+			require.NoError(t, err)
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			rgb.AddRefopts(flags)
+			err = flags.Parse([]string{"--include", p.arg})
+			if p.expected == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			rg, err := rgb.Finish(true)
+			require.NoError(t, err)
+
+			var walked []string
+			for _, refname := range refnames {
+				if walk, _ := rg.Categorize(refname); walk {
+					walked = append(walked, refname)
+				}
+			}
+			assert.Equal(t, p.expected, walked)
+		})
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "dump-state")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	testRepo.AddFile(t, "a.txt", "hello\n")
+	testRepo.AddFile(t, "b.txt", "world\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run())
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	var buf bytes.Buffer
+	_, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleNone, DumpState: &buf},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "# git-sizer internal state"))
+	assert.Contains(t, out, "known blob sizes: 2\n")
+	assert.Contains(t, out, "known tree sizes: 1\n")
+	assert.Contains(t, out, "known commit sizes: 1\n")
+	assert.Contains(t, out, "pending trees: 0\n")
+	assert.Contains(t, out, "sample of blob sizes:\n")
+}
+
+func TestWalkCommits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "walk-commits")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(path, contents string) git.OID {
+		t.Helper()
+		testRepo.AddFile(t, path, contents)
+		cmd := testRepo.GitCommand(t, "commit", "-m", "change "+path)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+		out, err := testRepo.GitCommand(t, "rev-parse", "HEAD").Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	base := commit("README", "hello\n")
+	commit("docs/a.md", "docs\n")
+	skipAt := commit("src/x.go", "package x\n")
+	tip := commit("docs/a.md", "more docs\n")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	graph := sizes.NewGraphWithOptions(sizes.ScanOptions{})
+	_, err = sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots, sizes.ScanOptions{Graph: graph}, meter.NoProgressMeter,
+	)
+	require.NoError(t, err)
+
+	// Count the commits that touch paths under "docs/":
+	var visits, docsCommits int
+	err = graph.WalkCommits(ctx, repo, []git.OID{tip}, func(v sizes.CommitVisit) error {
+		visits++
+		assert.True(t, v.SizeKnown)
+		assert.True(t, v.TreeSizeKnown)
+
+		parentTree := git.EmptyTreeOID
+		if len(v.Commit.Parents) > 0 {
+			out, err := repo.GitCommand(
+				"rev-parse", v.Commit.Parents[0].String()+"^{tree}",
+			).Output()
+			if err != nil {
+				return err
+			}
+			if parentTree, err = git.NewOID(strings.TrimSpace(string(out))); err != nil {
+				return err
+			}
+		}
+		entries, err := repo.DiffTree(parentTree, v.Commit.Tree)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Path, "docs/") {
+				docsCommits++
+				break
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, visits)
+	assert.Equal(t, 2, docsCommits)
+
+	// Pruning:
+	var visited []git.OID
+	err = graph.WalkCommits(ctx, repo, []git.OID{tip}, func(v sizes.CommitVisit) error {
+		visited = append(visited, v.OID)
+		if v.OID == skipAt {
+			return git.SkipAncestors
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []git.OID{tip, skipAt}, visited)
+
+	// The ancestors of a skipped commit are pruned even if they are
+	// also reachable from another tip:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "-b", "side", base.String()).Run())
+	side := commit("side.txt", "side\n")
+
+	visited = nil
+	err = graph.WalkCommits(ctx, repo, []git.OID{tip, side}, func(v sizes.CommitVisit) error {
+		visited = append(visited, v.OID)
+		if v.OID == skipAt {
+			return git.SkipAncestors
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []git.OID{tip, skipAt, side}, visited)
+}
+
+func TestStorageGrowth(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "storage-growth")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	now := time.Now()
+	repo := testRepo.Repository(t)
+
+	// Each commit adds three objects (commit, tree, and blob):
+	commit := func(name string) {
+		t.Helper()
+		testRepo.AddFile(t, name, name+"\n")
+		cmd := testRepo.GitCommand(t, "commit", "-m", name)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+	}
+	// Backdate the object files that have appeared since the last
+	// call:
+	backdated := make(map[string]bool)
+	backdate := func(age time.Duration) {
+		t.Helper()
+		var paths []string
+		loose, err := repo.LooseObjects()
+		require.NoError(t, err)
+		for _, obj := range loose {
+			path, err := repo.LooseObjectPath(obj.OID)
+			require.NoError(t, err)
+			paths = append(paths, path)
+		}
+		packs, err := repo.Packfiles()
+		require.NoError(t, err)
+		for _, pack := range packs {
+			paths = append(paths, pack.Path)
+		}
+		for _, path := range paths {
+			if !backdated[path] {
+				require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+				backdated[path] = true
+			}
+		}
+	}
+
+	commit("a.txt")
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adq").Run())
+	backdate(10 * 24 * time.Hour)
+
+	commit("b.txt")
+	backdate(2 * 24 * time.Hour)
+
+	commit("c.txt")
+	backdate(time.Hour)
+
+	// A new loose copy of a packed object doesn't make it look new.
+	// (`git hash-object` would only freshen the pack, so write the
+	// file by hand.)
+	out, err := testRepo.GitCommand(t, "rev-parse", "HEAD~2").Output()
+	require.NoError(t, err)
+	oldCommit, err := git.NewOID(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	data, err := testRepo.GitCommand(t, "cat-file", "commit", oldCommit.String()).Output()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "commit %d\x00", len(data))
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	path, err := repo.LooseObjectPath(oldCommit)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o777))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o444))
+
+	growth, err := sizes.ScanStorageGrowth(repo, now, sizes.DefaultGrowthWindows)
+	require.NoError(t, err)
+
+	assert.Equal(t, counts.Count32(9), growth.ObjectCount)
+	require.Len(t, growth.Windows, 3)
+	assert.Equal(t, int64(24*60*60), growth.Windows[0].Window)
+	assert.Equal(t, counts.Count32(3), growth.Windows[0].ObjectCount)
+	assert.Equal(t, counts.Count32(6), growth.Windows[1].ObjectCount)
+	assert.Equal(t, counts.Count32(9), growth.Windows[2].ObjectCount)
+	assert.Equal(t, growth.DiskSize, growth.Windows[2].DiskSize)
+	assert.Less(t, uint64(growth.Windows[0].DiskSize), uint64(growth.Windows[1].DiskSize))
+
+	require.NotNil(t, growth.OldestPack)
+	assert.WithinDuration(t, now.Add(-10*24*time.Hour), *growth.OldestPack, time.Second)
+	assert.Equal(t, growth.OldestPack, growth.NewestPack)
+
+	windows, err := sizes.ParseGrowthWindows("24h, 7d,2w")
+	require.NoError(t, err)
+	assert.Equal(
+		t, []time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 14 * 24 * time.Hour}, windows,
+	)
+	_, err = sizes.ParseGrowthWindows("7x")
+	assert.Error(t, err)
+}
+
+func TestScanPacks(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "scan-packs")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	repo := testRepo.Repository(t)
+
+	// Each commit adds three objects (commit, tree, and blob):
+	commit := func(name string) {
+		t.Helper()
+		testRepo.AddFile(t, name, name+"\n")
+		cmd := testRepo.GitCommand(t, "commit", "-m", name)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run())
+	}
+	runGit := func(args ...string) {
+		t.Helper()
+		require.NoError(t, testRepo.GitCommand(t, args...).Run(), "git %s", args)
+	}
+
+	r, err := sizes.ScanPacks(repo)
+	require.NoError(t, err)
+	assert.Empty(t, r.Packs)
+	assert.False(t, r.HasMultiPackIndex)
+
+	commit("a.txt")
+	runGit("repack", "-adq")
+	runGit("multi-pack-index", "write")
+
+	// An incremental pack, and then one containing everything,
+	// without deleting the old ones:
+	commit("b.txt")
+	runGit("repack", "-q")
+	runGit("repack", "-aq")
+
+	r, err = sizes.ScanPacks(repo)
+	require.NoError(t, err)
+	assert.True(t, r.HasMultiPackIndex)
+	assert.Equal(t, 1, r.MultiPackIndexPackCount)
+	require.Len(t, r.Packs, 3)
+
+	// The complete pack is the largest:
+	all := r.Packs[0]
+	assert.Equal(t, counts.Count32(6), all.ObjectCount)
+	assert.Equal(t, counts.Count32(6), all.DuplicateObjectCount)
+	assert.False(t, all.InMultiPackIndex)
+
+	var midxPacks int
+	var contentSize counts.Count64
+	for _, p := range r.Packs[1:] {
+		assert.Equal(t, counts.Count32(3), p.ObjectCount)
+		assert.Equal(t, counts.Count32(3), p.DuplicateObjectCount)
+		assert.LessOrEqual(t, p.DiskSize, all.DiskSize)
+		if p.InMultiPackIndex {
+			midxPacks++
+		}
+		contentSize.Increment(p.ContentSize)
+	}
+	assert.Equal(t, 1, midxPacks)
+	assert.Equal(t, all.ContentSize, contentSize)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WritePackReport(&buf, r))
+	assert.Contains(t, buf.String(), "3 packs, largest first:\n")
+	assert.Contains(t, buf.String(), "The multi-pack index covers 1 of the packs")
+}
+
+func TestComputeForCommitRange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "commit-range")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) git.OID {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+		out, err := testRepo.GitCommand(t, "rev-parse", "HEAD").Output()
+		require.NoError(t, err)
+		oid, err := git.NewOID(strings.TrimSpace(string(out)))
+		require.NoError(t, err)
+		return oid
+	}
+
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	testRepo.AddFile(t, "dir/b.txt", "Goodbye\n")
+	testRepo.AddFile(t, "dir/sub/c.txt", "Hmmm\n")
+	testRepo.AddFile(t, "other/d.txt", "Unchanged\n")
+	from := commit("initial")
+
+	testRepo.AddFile(t, "dir/sub/c.txt", "Hmmm, changed\n")
+	testRepo.AddFile(t, "dir/e.txt", "New file\n")
+	commit("second")
+	testRepo.AddFile(t, "a.txt", "Hello again\n")
+	to := commit("third")
+
+	repo := testRepo.Repository(t)
+
+	scanTree := func(treeSizes sizes.CacheBackend, oid git.OID) {
+		t.Helper()
+		_, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, []sizes.Root{sizes.NewExplicitRoot(oid.String(), oid)},
+			sizes.ScanOptions{NameStyle: sizes.NameStyleNone, TreeSizes: treeSizes},
+			meter.NoProgressMeter,
+		)
+		require.NoError(t, err)
+	}
+
+	toTree, err := repo.ResolveObject(to.String() + "^{tree}")
+	require.NoError(t, err)
+	full := sizes.NewMemoryCacheBackend()
+	scanTree(full, to)
+	expected, ok := full.Get(toTree)
+	require.True(t, ok)
+
+	// Without the sizes of the older trees (e.g., `other`), the
+	// range isn't enough:
+	_, err = sizes.ComputeForCommitRange(ctx, repo, sizes.NewMemoryCacheBackend(), from, to)
+	assert.ErrorIs(t, err, sizes.ErrTreeSizeNotCached)
+
+	cache := sizes.NewMemoryCacheBackend()
+	scanTree(cache, from)
+	assert.Equal(t, 4, cache.Len())
+
+	ts, err := sizes.ComputeForCommitRange(ctx, repo, cache, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, expected, ts)
+
+	// Only the trees in the range were added: new versions of the
+	// root tree, `dir`, and `dir/sub` from the second commit, and
+	// another root tree from the third:
+	assert.Equal(t, 4+4, cache.Len())
+
+	// The answer is now cached:
+	ts, err = sizes.ComputeForCommitRange(ctx, repo, cache, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, expected, ts)
+}
+
+func TestChunks(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		N    int    `json:"n"`
+		Name string `json:"name"`
+	}
+	var records []record
+	for i := 0; i < 7; i++ {
+		records = append(records, record{i, fmt.Sprintf("record %d", i)})
+	}
+
+	unchunked, err := sizes.EncodeJSONLines(records)
+	require.NoError(t, err)
+	assert.Equal(t, 7, bytes.Count(unchunked, []byte("\n")))
+
+	dir := t.TempDir()
+	chunks, err := sizes.WriteChunkFiles(dir, "records", records, 3)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	var concatenated []byte
+	for i, c := range chunks {
+		assert.Equal(t, fmt.Sprintf("records-%04d.jsonl", i+1), c.Name)
+		assert.Equal(t, 3*i, c.First)
+		data, err := os.ReadFile(filepath.Join(dir, c.Name))
+		require.NoError(t, err)
+		sum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(sum[:]), c.SHA256)
+		assert.Equal(t, c.RecordCount, bytes.Count(data, []byte("\n")))
+		concatenated = append(concatenated, data...)
+	}
+	assert.Equal(t, []int{3, 3, 1}, []int{
+		chunks[0].RecordCount, chunks[1].RecordCount, chunks[2].RecordCount,
+	})
+	assert.Equal(t, string(unchunked), string(concatenated))
+
+	// The chunks don't depend on anything but the records:
+	again, err := sizes.EncodeChunks(records, 3, func(index int, _ []byte) (string, error) {
+		return fmt.Sprintf("records-%04d.jsonl", index+1), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, chunks, again)
+
+	_, err = sizes.EncodeChunks(records, 0, nil)
+	assert.Error(t, err)
+
+	// `--chunk-size` only applies to `--delta-chains`:
+	testRepo := testutils.NewTestRepo(t, true, "chunks")
+	defer testRepo.Remove(t)
+
+	cmd := exec.Command(sizerExe(t), "--no-progress", "--chunk-size=3")
+	cmd.Dir = testRepo.Path
+	output, err := cmd.CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "--chunk-size can only be used with --delta-chains")
+}
+
+// gitSpawnCeiling is the most git processes that a scan of a small
+// repository, with all of the optional analyses enabled, is expected
+// to spawn. A plain scan takes about 25, most of them for setting up
+// (reading the configuration, finding the git directories, and
+// resolving symbolic references); each optional analysis adds a few
+// more. The number of processes shouldn't depend on the number of
+// objects, commits, or references; if this test starts failing, look
+// for a command that is being run once per object or once per
+// reference rather than being fed via stdin.
+const gitSpawnCeiling = 50
+
+func TestGitSpawnCeiling(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "git-spawn-ceiling")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	for i := 0; i < 5; i++ {
+		testRepo.AddFile(t, fmt.Sprintf("dir%d/file%d.txt", i%2, i), fmt.Sprintf("line %d\n", i))
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("commit %d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+
+		cmd = testRepo.GitCommand(t, "tag", "-a", "-m", "a tag", fmt.Sprintf("v%d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating tag")
+	}
+	require.NoError(t, testRepo.GitCommand(t, "branch", "side", "HEAD~2").Run())
+
+	// Every analysis that contributes to the main report (the modes
+	// that output something else instead are not included):
+	cmd := exec.Command(
+		sizerExe(t), "-j", "--no-progress",
+		"--default-branch", "--check-symrefs", "--check-storage", "--check-config", "--ref-kinds",
+		"--max-line-count-check", "--file-ages", "--bitmap-status", "--verify-oids",
+		"--normalize-names", "--max-filename-length=10", "--wide-tree-entries=2",
+	)
+	cmd.Dir = testRepo.Path
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	require.NoError(t, cmd.Run())
+
+	var report struct {
+		GitSpawns map[string]int `json:"git_spawns"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+
+	total := 0
+	for _, n := range report.GitSpawns {
+		total += n
+	}
+	assert.Greater(t, report.GitSpawns["cat-file"], 0)
+	assert.LessOrEqualf(
+		t, total, gitSpawnCeiling, "too many git processes: %v", report.GitSpawns,
+	)
+
+	// A budget that is too small makes git-sizer fail, naming the
+	// command that it couldn't run:
+	cmd = exec.Command(sizerExe(t), "--no-progress", "--spawn-budget=1")
+	cmd.Dir = testRepo.Path
+	output, err := cmd.CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "the budget of 1 git processes has been used up")
+}
+
+func TestFindContentDuplicates(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, true, "content-duplicates")
+	defer testRepo.Remove(t)
+
+	createBlob := func(contents string) git.OID {
+		return testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		})
+	}
+
+	// Long enough that a CRLF straddles the boundary between two
+	// of the chunks that are hashed:
+	long := strings.Repeat("x\n", 20000)
+
+	lfOID := createBlob("a\nb\n")
+	crlfOID := createBlob("a\r\nb\r\n")
+	longLFOID := createBlob(long)
+	longCRLFOID := createBlob(strings.ReplaceAll(long, "\n", "\r\n"))
+	// A lone CR is not a line ending:
+	crOID := createBlob("a\rb\r")
+	uniqueOID := createBlob("unique contents\n")
+
+	repo := testRepo.Repository(t)
+
+	sum, err := repo.BlobContentHash(crlfOID)
+	require.NoError(t, err)
+	assert.Equal(t, sha256.Sum256([]byte("a\r\nb\r\n")), sum)
+
+	g := sizes.NewGraph(sizes.NameStyleNone)
+	for _, oid := range []git.OID{lfOID, crlfOID, longLFOID, longCRLFOID, crOID, uniqueOID} {
+		g.RegisterBlob(oid, 5)
+	}
+
+	groups, err := g.FindContentDuplicates(context.Background(), repo)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	expected := map[string][]git.OID{}
+	shortSum := sha256.Sum256([]byte("a\nb\n"))
+	expected[hex.EncodeToString(shortSum[:])] = []git.OID{lfOID, crlfOID}
+	longSum := sha256.Sum256([]byte(long))
+	expected[hex.EncodeToString(longSum[:])] = []git.OID{longLFOID, longCRLFOID}
+
+	for _, group := range groups {
+		require.Contains(t, expected, group.SHA256)
+		assert.ElementsMatch(t, expected[group.SHA256], group.OIDs)
+		assert.Equal(t, counts.Count64(10), group.TotalSize)
+	}
+	assert.Less(t, groups[0].SHA256, groups[1].SHA256)
+}
+
+func TestLinkedWorktreeReport(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "linked-worktree-report")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "a.txt", "Hello, world!\n")
+	commit("initial")
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-ad").Run())
+	testRepo.AddFile(t, "b.txt", "Goodbye, world!\n")
+	commit("second")
+
+	// `HEAD` is per-worktree, so detach it in the main repository,
+	// too, to make the two reports comparable:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "--detach").Run())
+
+	worktree := testRepo.AddWorktree(t, "linked-worktree-report-wt")
+	defer worktree.Remove(t)
+
+	run := func(dir string, args ...string) []byte {
+		t.Helper()
+		args = append([]string{"-j", "--no-progress"}, args...)
+		cmd := exec.Command(sizerExe(t), args...)
+		cmd.Dir = dir
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+		return stdout.Bytes()
+	}
+
+	report := func(dir string) map[string]interface{} {
+		t.Helper()
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(run(dir, "--default-branch=master"), &v))
+		return v
+	}
+
+	mainReport := report(testRepo.Path)
+	linkedReport := report(worktree.Path)
+	assert.Nil(t, mainReport["linked_worktree"])
+	assert.Equal(t, true, linkedReport["linked_worktree"])
+	delete(linkedReport, "linked_worktree")
+
+	// The metadata identifies where the scan was run from:
+	mainMetadata := mainReport["repository"].(map[string]interface{})
+	linkedMetadata := linkedReport["repository"].(map[string]interface{})
+	assert.Equal(t, mainMetadata["head_oid"], linkedMetadata["head_oid"])
+	assert.NotEqual(t, mainMetadata["git_dir"], linkedMetadata["git_dir"])
+	delete(mainReport, "repository")
+	delete(linkedReport, "repository")
+
+	assert.Equal(t, mainReport, linkedReport)
+
+	growth := func(dir string) map[string]interface{} {
+		t.Helper()
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(run(dir, "--storage-growth"), &v))
+		// This is the time of the run:
+		delete(v, "now")
+		return v
+	}
+
+	assert.Equal(t, growth(testRepo.Path), growth(worktree.Path))
+}
+
+func TestDetachedHead(t *testing.T) {
+	t.Parallel()
+
+	testRepo := testutils.NewTestRepo(t, false, "detached-head")
+	defer testRepo.Remove(t)
+
+	scan := func(args ...string) map[string]interface{} {
+		t.Helper()
+		cmd := exec.Command(sizerExe(t), append([]string{"-j", "--no-progress"}, args...)...)
+		cmd.Dir = testRepo.Path
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		require.NoError(t, cmd.Run(), "stderr: %s", stderr.String())
+		var v map[string]interface{}
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &v))
+		return v
+	}
+
+	// An empty repository's `HEAD` points at an unborn branch, which
+	// is not an error:
+	assert.Equal(t, float64(0), scan()["unique_commit_count"])
+
+	timestamp := time.Unix(1112911993, 0)
+
+	commit := func(msg string) {
+		t.Helper()
+		cmd := testRepo.GitCommand(t, "commit", "-m", msg)
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	testRepo.AddFile(t, "a.txt", "on the branch\n")
+	commit("initial")
+
+	// A commit that is reachable only from the detached `HEAD`:
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "--detach").Run())
+	testRepo.AddFile(t, "b.txt", "only on the detached HEAD\n")
+	commit("detached")
+
+	v := scan()
+	assert.Equal(t, float64(2), v["unique_commit_count"])
+	assert.Equal(t, float64(2), v["unique_blob_count"])
+
+	// If explicit roots are given, only they are scanned:
+	assert.Equal(t, float64(1), scan("master")["unique_commit_count"])
+
+	// Likewise if references are selected explicitly, unless the
+	// detached `HEAD` is asked for:
+	assert.Equal(t, float64(1), scan("--branches")["unique_commit_count"])
+	assert.Equal(t, float64(2), scan("--branches", "--detached-head")["unique_commit_count"])
+	assert.Equal(t, float64(1), scan("--no-detached-head")["unique_commit_count"])
+}
+
+func TestInventory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, true, "inventory")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+
+	// Each step adds one blob, one tree, and one commit:
+	var parent git.OID
+	addCommit := func(i int) {
+		t.Helper()
+		blob := testRepo.CreateObject(t, "blob", func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "contents %d\n", i)
+			return err
+		})
+		tree := testRepo.CreateObject(t, "tree", func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "100644 file.txt\x00%s", blob.Bytes())
+			return err
+		})
+		commit := testRepo.CreateObject(t, "commit", func(w io.Writer) error {
+			fmt.Fprintf(w, "tree %s\n", tree)
+			if parent != git.NullOID {
+				fmt.Fprintf(w, "parent %s\n", parent)
+			}
+			_, err := fmt.Fprintf(
+				w,
+				"author Example <example@example.com> %d -0700\n"+
+					"committer Example <example@example.com> %d -0700\n"+
+					"\n"+
+					"Commit %d\n",
+				timestamp.Unix(), timestamp.Unix(), i,
+			)
+			return err
+		})
+		testRepo.UpdateRef(t, "refs/heads/master", commit)
+		parent = commit
+	}
+
+	for i := 0; i < 4; i++ {
+		addCommit(i)
+	}
+
+	repo := testRepo.Repository(t)
+
+	opts := sizes.InventoryOptions{SampleRate: 1, FalsePositiveRate: 0.001}
+	old, err := sizes.TakeInventory(ctx, repo, opts)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count64(4), old.Types["blob"].Count)
+	assert.Equal(t, counts.Count64(4), old.Types["tree"].Count)
+	assert.Equal(t, counts.Count64(4), old.Types["commit"].Count)
+	assert.Equal(t, uint64(12), old.SampledCount)
+	assert.NotZero(t, old.Bloom.BitCount)
+	assert.NotZero(t, old.Bloom.HashCount)
+	assert.Less(t, old.Bloom.FalsePositiveRate, 0.01)
+
+	// The inventory survives a round trip through a file, including
+	// the parameters of its Bloom filter:
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	require.NoError(t, sizes.WriteInventoryFile(path, old))
+	old, err = sizes.ReadInventoryFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(12), old.SampledCount)
+
+	// Simulate growth:
+	for i := 4; i < 10; i++ {
+		addCommit(i)
+	}
+
+	inv, delta, err := sizes.CompareInventory(ctx, repo, old, opts)
+	require.NoError(t, err)
+	assert.Equal(t, counts.Count64(10), inv.Types["blob"].Count)
+
+	require.Len(t, delta.Types, 4)
+	assert.True(t, delta.Anomalous)
+	for _, td := range delta.Types[:3] {
+		assert.Equal(t, counts.Count64(4), td.OldCount, td.ObjectType)
+		assert.Equal(t, counts.Count64(10), td.NewCount, td.ObjectType)
+		assert.Equal(t, int64(6), td.CountDelta, td.ObjectType)
+		assert.Positive(t, td.DiskSizeDelta, td.ObjectType)
+		assert.InDelta(t, 150.0, td.GrowthPercent, 1e-9, td.ObjectType)
+		assert.Equal(t, uint64(10), td.SampledCount, td.ObjectType)
+		assert.Equal(t, uint64(6), td.SampledNewCount, td.ObjectType)
+		assert.Equal(t, counts.Count64(6), td.EstimatedNewCount, td.ObjectType)
+		assert.Equal(t, counts.Count64(4), td.EstimatedExistingCount, td.ObjectType)
+		assert.True(t, td.Anomalous, td.ObjectType)
+	}
+	tags := delta.Types[3]
+	assert.Equal(t, git.ObjectType("tag"), tags.ObjectType)
+	assert.Equal(t, int64(0), tags.CountDelta)
+	assert.False(t, tags.Anomalous)
+
+	// Repacking changes the disk sizes, but not which objects exist:
+	require.NoError(t, testRepo.GitCommand(t, "repack", "-adq").Run())
+	_, delta, err = sizes.CompareInventory(
+		ctx, repo, inv, sizes.InventoryOptions{SampleRate: 1, MaxGrowthPercent: 200},
+	)
+	require.NoError(t, err)
+	assert.False(t, delta.Anomalous)
+	for _, td := range delta.Types[:3] {
+		assert.Equal(t, int64(0), td.CountDelta, td.ObjectType)
+		assert.NotZero(t, td.DiskSizeDelta, td.ObjectType)
+		assert.Equal(t, counts.Count64(0), td.EstimatedNewCount, td.ObjectType)
+		assert.Equal(t, counts.Count64(10), td.EstimatedExistingCount, td.ObjectType)
+	}
+
+	// Custom Bloom filter parameters are recorded:
+	custom, err := sizes.TakeInventory(
+		ctx, repo, sizes.InventoryOptions{SampleRate: 1, BloomBits: 4096, BloomHashes: 3},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4096), custom.Bloom.BitCount)
+	assert.Equal(t, 3, custom.Bloom.HashCount)
+	assert.Len(t, custom.Filter, 4096/8)
+
+	// Bloom filter parameters that don't match the data are rejected
+	// before anything is allocated:
+	for _, bloom := range []sizes.BloomParameters{
+		{BitCount: 1 << 62, HashCount: 3},
+		{BitCount: 4096 + 64, HashCount: 3},
+		{BitCount: 0, HashCount: 3},
+		{BitCount: 4096, HashCount: 0},
+		{BitCount: 4096, HashCount: 1 << 30},
+	} {
+		corrupt := *custom
+		corrupt.Bloom = bloom
+		_, _, err := sizes.CompareInventory(ctx, repo, &corrupt, opts)
+		assert.Errorf(t, err, "%+v", bloom)
+	}
+}
+
+func TestTreeSizeTable(t *testing.T) {
+	t.Parallel()
+
+	ts := sizes.TreeSize{
+		MaxPathDepth:      3,
+		ExpandedTreeCount: 5000,
+		ExpandedBlobCount: 2e6,
+		ExpandedBlobSize:  1234,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ts.WriteTable(&buf, sizes.TableOptions{
+		OnlyWarnings: true,
+		SortBy:       sizes.TableSortByValue,
+	}))
+	assert.Equal(
+		t,
+		"Metric                 Value    Human   Threshold  Status\n"+
+			"Number of files        2000000  2.00 M  50.0 k     CRIT\n"+
+			"Number of directories  5000     5.00 k  2.00 k     WARN\n",
+		buf.String(),
+	)
+
+	buf.Reset()
+	require.NoError(t, ts.WriteTable(&buf, sizes.TableOptions{
+		Colors: true,
+		SortBy: sizes.TableSortByName,
+	}))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 12)
+	assert.True(t, strings.HasPrefix(lines[1], "Maximum path depth"))
+	assert.True(t, strings.HasSuffix(lines[1], "OK"))
+	assert.True(t, strings.HasSuffix(lines[5], "\x1b[33mWARN\x1b[0m"), lines[5])
+	assert.True(t, strings.HasSuffix(lines[6], "\x1b[31mCRIT\x1b[0m"), lines[6])
+}
+
+func TestSizedTreeSize(t *testing.T) {
+	t.Parallel()
+
+	ts := sizes.TreeSize{
+		MaxPathDepth:     2,
+		ExpandedBlobSize: counts.NewCount64(math.MaxUint64),
+		PathCount:        counts.NewCount32(math.MaxUint32),
+		LayerWidths:      []counts.Count32{3, 2},
+		Saturated:        sizes.SaturatedExpandedBlobSize,
+	}
+
+	assert.Contains(t, ts.String(), "max_path_depth=2, ")
+	assert.Contains(t, ts.String(), "expanded_blob_size=18446744073709551615~, ")
+	// A count that really is the maximum value isn't approximate:
+	assert.Contains(t, ts.String(), "path_count=4294967295")
+	assert.NotContains(t, ts.String(), "path_count=4294967295~")
+
+	sized := ts.Sized()
+	assert.Equal(t, counts.SizedCount{Value: 3}, sized.MaxTreeWidth)
+	assert.True(t, sized.ExpandedBlobSize.Approximate)
+	assert.False(t, sized.PathCount.Approximate)
+
+	j, err := json.Marshal(sized)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"max_path_depth":{"value":2}`)
+	assert.Contains(t, string(j), `"expanded_blob_size":{"value":18446744073709551615,"approximate":true}`)
+	assert.Contains(t, string(j), `"path_count":{"value":4294967295}`)
+
+	sample := sizes.SizeSample{Size: ts}
+	j, err = json.Marshal(sample)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"expanded_blob_size":{"value":18446744073709551615,"approximate":true}`)
+}
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "labels")
+	defer testRepo.Remove(t)
+
+	timestamp := time.Unix(1112911993, 0)
+	testRepo.AddFile(t, "README", "Hello\n")
+	cmd := testRepo.GitCommand(t, "commit", "-m", "initial")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+
+	repo := testRepo.Repository(t)
+
+	refRoots, err := sizes.CollectReferences(ctx, repo, refGrouper{})
+	require.NoError(t, err)
+
+	roots := make([]sizes.Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		roots = append(roots, refRoot)
+	}
+
+	for _, bad := range []map[string]string{
+		{"": "x"},
+		{"Org": "x"},
+		{"org-name": "x"},
+		{"path": "x"},
+		{"json_version": "x"},
+	} {
+		_, err := sizes.ScanRepositoryWithOptions(
+			ctx, repo, roots, sizes.ScanOptions{Labels: bad}, meter.NoProgressMeter,
+		)
+		assert.Error(t, err, "labels %v", bad)
+	}
+
+	labels := map[string]string{"org": "acme", "shard_7": "eu west"}
+	h, err := sizes.ScanRepositoryWithOptions(
+		ctx, repo, roots,
+		sizes.ScanOptions{NameStyle: sizes.NameStyleFull, Labels: labels},
+		meter.NoProgressMeter,
+	)
+	require.NoError(t, err, "scanning repository")
+	assert.Equal(t, labels, h.Labels)
+
+	refGroups := []sizes.RefGroup{{Symbol: "", Name: "Refs to walk"}}
+
+	type labeled struct {
+		Labels map[string]string `json:"labels"`
+	}
+
+	j, err := json.Marshal(h)
+	require.NoError(t, err)
+	var v1 labeled
+	require.NoError(t, json.Unmarshal(j, &v1))
+	assert.Equal(t, labels, v1.Labels, "JSON version 1")
+
+	j, err = h.JSON(refGroups, 0, sizes.NameStyleFull)
+	require.NoError(t, err)
+	var v2 labeled
+	require.NoError(t, json.Unmarshal(j, &v2))
+	assert.Equal(t, labels, v2.Labels, "JSON version 2")
+
+	const header = "Labels: org=acme, shard_7=eu west\n\n"
+	for _, threshold := range []sizes.Threshold{0, 30} {
+		assert.True(
+			t,
+			strings.HasPrefix(h.TableString(refGroups, threshold, sizes.NameStyleFull), header),
+			"table at threshold %v", threshold,
+		)
+		assert.True(
+			t,
+			strings.HasPrefix(
+				h.TerminalTableString(
+					refGroups, threshold, sizes.NameStyleFull, sizes.TerminalTableOptions{},
+				),
+				header,
+			),
+			"compact table at threshold %v", threshold,
+		)
+	}
+}
+
+func TestHistoricalGrowth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	testRepo := testutils.NewTestRepo(t, false, "historical-growth")
+	defer testRepo.Remove(t)
+
+	const week = 7 * 24 * time.Hour
+	const day = 24 * time.Hour
+	// Intervals are counted from the epoch:
+	start := time.Unix(1112911993/int64(week/time.Second)*int64(week/time.Second), 0).Add(time.Hour)
+
+	// Add one file per commit, on these days:
+	for i, offset := range []time.Duration{0, 1 * day, 8 * day, 9 * day, 20 * day} {
+		testRepo.AddFile(t, fmt.Sprintf("file-%d.txt", i), strings.Repeat("x", 100*(i+1)))
+		timestamp := start.Add(offset)
+		cmd := testRepo.GitCommand(t, "commit", "-m", fmt.Sprintf("commit %d", i))
+		testutils.AddAuthorInfo(cmd, &timestamp)
+		require.NoError(t, cmd.Run(), "creating commit")
+	}
+
+	// A side branch, merged on day 27. Its commits aren't sampled,
+	// but its files count once they are merged:
+	cmd := testRepo.GitCommand(t, "checkout", "-q", "-b", "side", "HEAD~1")
+	require.NoError(t, cmd.Run())
+	testRepo.AddFile(t, "side.txt", "side\n")
+	timestamp := start.Add(10 * day)
+	cmd = testRepo.GitCommand(t, "commit", "-m", "side")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "creating commit")
+	require.NoError(t, testRepo.GitCommand(t, "checkout", "-q", "master").Run())
+	timestamp = start.Add(27 * day)
+	cmd = testRepo.GitCommand(t, "merge", "--no-ff", "-m", "merge", "side")
+	testutils.AddAuthorInfo(cmd, &timestamp)
+	require.NoError(t, cmd.Run(), "merging")
+
+	repo := testRepo.Repository(t)
+	master, err := repo.ResolveObject("master")
+	require.NoError(t, err)
+
+	treeSizes := sizes.NewMemoryCacheBackend()
+	samples, err := sizes.AnalyzeHistoricalGrowth(ctx, repo, treeSizes, master, week, 0)
+	require.NoError(t, err)
+
+	type sample struct {
+		days      int
+		blobCount counts.Count32
+		blobSize  counts.Count64
+	}
+	var got []sample
+	for _, s := range samples {
+		got = append(got, sample{
+			days:      int(s.Timestamp.Sub(start) / day),
+			blobCount: s.Size.ExpandedBlobCount,
+			blobSize:  s.Size.ExpandedBlobSize,
+		})
+	}
+	assert.Equal(
+		t,
+		[]sample{
+			{days: 1, blobCount: 2, blobSize: 300},
+			{days: 9, blobCount: 4, blobSize: 1000},
+			{days: 20, blobCount: 5, blobSize: 1500},
+			{days: 27, blobCount: 6, blobSize: 1505},
+		},
+		got,
+	)
+
+	// The same samples come from the cache, and the number of
+	// samples can be limited to the most recent ones:
+	limited, err := sizes.AnalyzeHistoricalGrowth(ctx, repo, treeSizes, master, week, 2)
+	require.NoError(t, err)
+	assert.Equal(t, samples[2:], limited)
+
+	var buf bytes.Buffer
+	require.NoError(t, sizes.WriteSizeSamplesCSV(&buf, limited))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "commit,timestamp,max_path_depth,"))
+	assert.True(
+		t,
+		strings.HasPrefix(
+			lines[2],
+			samples[3].CommitOID.String()+","+samples[3].Timestamp.Format(time.RFC3339)+",1,",
+		),
+		lines[2],
+	)
+
+	_, err = sizes.AnalyzeHistoricalGrowth(ctx, repo, treeSizes, master, 0, 0)
+	assert.Error(t, err)
+}
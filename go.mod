@@ -1,6 +1,6 @@
 module github.com/github/git-sizer
 
-go 1.17
+go 1.18
 
 require (
 	github.com/cli/safeexec v1.0.0
@@ -8,6 +8,7 @@ require (
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/text v0.14.0
 )
 
 require github.com/github/go-pipe v1.0.2
@@ -0,0 +1,115 @@
+// Package bitmap reads the headers of Git's reachability bitmap files
+// (`pack-*.bitmap` and `multi-pack-index-*.bitmap`), as described in
+// Git's `Documentation/technical/bitmap-format.txt`. It only reads as
+// far as the type indexes, which is enough to tell how many commits
+// have bitmaps and how many objects the bitmaps cover.
+package bitmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// signature is the magic number at the start of every bitmap file.
+var signature = [4]byte{'B', 'I', 'T', 'M'}
+
+// ErrBadSignature is returned if a file doesn't start with the bitmap
+// signature.
+var ErrBadSignature = errors.New("not a reachability bitmap file")
+
+// Stats is the information in the header of a bitmap file.
+type Stats struct {
+	// Version is the version of the file format. Only version 1 is
+	// supported.
+	Version uint16
+
+	// Flags are the option flags (e.g., whether the file has a
+	// name-hash cache).
+	Flags uint16
+
+	// CommitCount is the number of commits that have bitmaps.
+	CommitCount int
+
+	// BitCount is the number of objects that the bitmaps cover,
+	// i.e., the length of the longest of the type indexes.
+	BitCount int
+}
+
+// Read reads the header and the type indexes of the bitmap file in
+// `r`. `hashSize` is the length in bytes of the repository's object
+// IDs (20 for SHA-1).
+func Read(r io.Reader, hashSize int) (Stats, error) {
+	br := bufio.NewReader(r)
+
+	var header struct {
+		Signature  [4]byte
+		Version    uint16
+		Flags      uint16
+		EntryCount uint32
+	}
+	if err := binary.Read(br, binary.BigEndian, &header); err != nil {
+		return Stats{}, fmt.Errorf("reading bitmap header: %w", err)
+	}
+	if header.Signature != signature {
+		return Stats{}, ErrBadSignature
+	}
+	if header.Version != 1 {
+		return Stats{}, fmt.Errorf("unsupported bitmap version %d", header.Version)
+	}
+
+	stats := Stats{
+		Version:     header.Version,
+		Flags:       header.Flags,
+		CommitCount: int(header.EntryCount),
+	}
+
+	// Skip the checksum of the pack or multi-pack index:
+	if _, err := br.Discard(hashSize); err != nil {
+		return Stats{}, fmt.Errorf("reading bitmap checksum: %w", err)
+	}
+
+	// The type indexes for commits, trees, blobs, and tags:
+	for i := 0; i < 4; i++ {
+		bitCount, err := skipEWAH(br)
+		if err != nil {
+			return Stats{}, fmt.Errorf("reading type index %d: %w", i, err)
+		}
+		if bitCount > stats.BitCount {
+			stats.BitCount = bitCount
+		}
+	}
+
+	return stats, nil
+}
+
+// skipEWAH reads past an EWAH-compressed bitmap in `br`, returning
+// its length in bits.
+func skipEWAH(br *bufio.Reader) (int, error) {
+	var header struct {
+		BitCount  uint32
+		WordCount uint32
+	}
+	if err := binary.Read(br, binary.BigEndian, &header); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+
+	// Skip the 64-bit words, and then the position of the last
+	// run-length word:
+	if _, err := io.CopyN(io.Discard, br, 8*int64(header.WordCount)+4); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+
+	return int(header.BitCount), nil
+}
+
+// unexpectedEOF converts `io.EOF` into `io.ErrUnexpectedEOF`, since
+// the file must continue past any type index.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
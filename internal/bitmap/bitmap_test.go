@@ -0,0 +1,56 @@
+package bitmap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/github/git-sizer/internal/bitmap"
+)
+
+// ewah returns an EWAH bitmap with the specified length in bits and
+// number of (zero) words.
+func ewah(bitCount, wordCount uint32) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, bitCount)
+	_ = binary.Write(&buf, binary.BigEndian, wordCount)
+	buf.Write(make([]byte, 8*wordCount))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(0))
+	return buf.Bytes()
+}
+
+func bitmapFile(version uint16, entryCount uint32, bitCounts ...uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("BITM")
+	_ = binary.Write(&buf, binary.BigEndian, version)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(0x11))
+	_ = binary.Write(&buf, binary.BigEndian, entryCount)
+	buf.Write(bytes.Repeat([]byte{0xab}, 20))
+	for _, n := range bitCounts {
+		buf.Write(ewah(n, (n+63)/64))
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	stats, err := bitmap.Read(bytes.NewReader(bitmapFile(1, 7, 100, 250, 1000, 3)), 20)
+	require.NoError(t, err)
+	assert.Equal(t, bitmap.Stats{Version: 1, Flags: 0x11, CommitCount: 7, BitCount: 1000}, stats)
+
+	_, err = bitmap.Read(bytes.NewReader([]byte("PACK\x00\x00\x00\x02\x00\x00\x00\x03")), 20)
+	assert.ErrorIs(t, err, bitmap.ErrBadSignature)
+
+	_, err = bitmap.Read(bytes.NewReader(bitmapFile(2, 7, 1, 1, 1, 1)), 20)
+	assert.Error(t, err)
+
+	// A truncated file:
+	data := bitmapFile(1, 7, 100, 250, 1000, 3)
+	_, err = bitmap.Read(bytes.NewReader(data[:len(data)-20]), 20)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,241 @@
+// Package gitattributes parses `.gitattributes` files and matches
+// paths against the patterns that they contain.
+package gitattributes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// State is the state of an attribute for a path.
+type State int
+
+const (
+	// Unspecified means that no pattern says anything about the
+	// attribute (or that it was explicitly reset with `!attr`).
+	Unspecified State = iota
+
+	// Set means that the attribute was set (`attr`).
+	Set
+
+	// Unset means that the attribute was unset (`-attr`).
+	Unset
+
+	// Value means that the attribute was set to a value
+	// (`attr=value`).
+	Value
+)
+
+// Assignment is a single attribute assignment on a `.gitattributes`
+// line.
+type Assignment struct {
+	Name  string
+	State State
+
+	// Value is the value of the attribute if `State` is `Value`.
+	Value string
+}
+
+// Line is one pattern line from a `.gitattributes` file.
+type Line struct {
+	// Pattern is the (unquoted) pattern.
+	Pattern string
+
+	// Dir is the directory containing the `.gitattributes` file,
+	// relative to the top of the tree, without a trailing slash
+	// ("" for the top-level file).
+	Dir string
+
+	// Assignments are the attribute assignments on this line,
+	// with macros already expanded.
+	Assignments []Assignment
+
+	matcher *Matcher
+}
+
+// File is a parsed `.gitattributes` file.
+type File struct {
+	// Dir is the directory containing the file, relative to the
+	// top of the tree, without a trailing slash ("" for the
+	// top-level file).
+	Dir string
+
+	Lines []Line
+
+	// Ignored are the lines that git itself ignores (e.g., negated
+	// patterns, which are forbidden in `.gitattributes`).
+	Ignored []string
+}
+
+// builtinMacros are the macros that git always defines.
+var builtinMacros = map[string][]Assignment{
+	"binary": {
+		{Name: "diff", State: Unset},
+		{Name: "merge", State: Unset},
+		{Name: "text", State: Unset},
+	},
+}
+
+// Parse parses the contents of a `.gitattributes` file that lives in
+// directory `dir` (relative to the top of the tree; "" for the
+// top-level file). Macro definitions (`[attr]name ...`) are expanded
+// into the assignments of the lines that use them. Following git,
+// macros can only be defined in the top-level file; definitions
+// elsewhere are ignored.
+func Parse(dir string, contents []byte) (*File, error) {
+	dir = strings.Trim(dir, "/")
+	macros := make(map[string][]Assignment, len(builtinMacros))
+	for name, assignments := range builtinMacros {
+		macros[name] = assignments
+	}
+
+	f := &File{Dir: dir}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		pattern, rest, err := splitPattern(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(pattern, "[attr]") {
+			if dir != "" {
+				f.Ignored = append(f.Ignored, line)
+				continue
+			}
+			name := pattern[len("[attr]"):]
+			macros[name] = expand(parseAssignments(rest), macros, 0)
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "!") {
+			f.Ignored = append(f.Ignored, line)
+			continue
+		}
+
+		matcher, err := Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.Lines = append(f.Lines, Line{
+			Pattern:     pattern,
+			Dir:         dir,
+			Assignments: expand(parseAssignments(rest), macros, 0),
+			matcher:     matcher,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// splitPattern splits `line` into its pattern, which might be
+// C-quoted, and the rest of the line.
+func splitPattern(line string) (string, string, error) {
+	if line[0] != '"' {
+		i := strings.IndexAny(line, " \t")
+		if i == -1 {
+			return line, "", nil
+		}
+		return line[:i], line[i:], nil
+	}
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			pattern, err := strconv.Unquote(line[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("malformed quoted pattern %s: %w", line[:i+1], err)
+			}
+			return pattern, line[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted pattern: %s", line)
+}
+
+func parseAssignments(s string) []Assignment {
+	var assignments []Assignment
+	for _, word := range strings.Fields(s) {
+		switch {
+		case strings.HasPrefix(word, "-"):
+			assignments = append(assignments, Assignment{Name: word[1:], State: Unset})
+		case strings.HasPrefix(word, "!"):
+			assignments = append(assignments, Assignment{Name: word[1:], State: Unspecified})
+		default:
+			if i := strings.IndexByte(word, '='); i != -1 {
+				assignments = append(
+					assignments,
+					Assignment{Name: word[:i], State: Value, Value: word[i+1:]},
+				)
+			} else {
+				assignments = append(assignments, Assignment{Name: word, State: Set})
+			}
+		}
+	}
+	return assignments
+}
+
+// expand replaces any macros that are set in `assignments` with the
+// assignments that they stand for. The macro itself is kept, too, as
+// git does.
+func expand(assignments []Assignment, macros map[string][]Assignment, depth int) []Assignment {
+	if depth > 10 {
+		return assignments
+	}
+	var expanded []Assignment
+	for _, a := range assignments {
+		expanded = append(expanded, a)
+		if a.State != Set {
+			continue
+		}
+		if macro, ok := macros[a.Name]; ok {
+			expanded = append(expanded, expand(macro, macros, depth+1)...)
+		}
+	}
+	return expanded
+}
+
+// Matches returns true iff the pattern of `l` matches `path` (a path
+// relative to the top of the tree).
+func (l Line) Matches(path string) bool {
+	if l.Dir != "" {
+		if !strings.HasPrefix(path, l.Dir+"/") {
+			return false
+		}
+		path = path[len(l.Dir)+1:]
+	}
+	return l.matcher.Match(path)
+}
+
+// Lookup returns the state of attribute `name` for `path` according
+// to `files`, which must be ordered from lowest to highest precedence
+// (i.e., the top-level file first, then files in deeper directories).
+// Within a file, later lines take precedence.
+func Lookup(files []*File, path, name string) Assignment {
+	result := Assignment{Name: name}
+	for _, f := range files {
+		for _, line := range f.Lines {
+			if !line.Matches(path) {
+				continue
+			}
+			for _, a := range line.Assignments {
+				if a.Name == name {
+					result = a
+				}
+			}
+		}
+	}
+	return result
+}
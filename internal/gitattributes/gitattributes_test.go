@@ -0,0 +1,84 @@
+package gitattributes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"*.bin", "a.bin", true},
+		{"*.bin", "dir/sub/a.bin", true},
+		{"*.bin", "a.bin.txt", false},
+		{"a?c", "abc", true},
+		{"a?c", "a/c", false},
+		{"[ab].txt", "b.txt", true},
+		{"[!ab].txt", "b.txt", false},
+		{"[!ab].txt", "c.txt", true},
+		{"/top.bin", "top.bin", true},
+		{"/top.bin", "dir/top.bin", false},
+		{"dir/*.bin", "dir/a.bin", true},
+		{"dir/*.bin", "dir/sub/a.bin", false},
+		{"dir/**/*.bin", "dir/a.bin", true},
+		{"dir/**/*.bin", "dir/sub/deeper/a.bin", true},
+		{"**/build/*", "x/y/build/out", true},
+		{"**/build/*", "build/out", true},
+		{"vendor/**", "vendor/a/b", true},
+		{"vendor/**", "other/a/b", false},
+		{`\*.bin`, "*.bin", true},
+		{`\*.bin`, "a.bin", false},
+	} {
+		p := p
+		t.Run(p.pattern+" "+p.path, func(t *testing.T) {
+			m, err := Compile(p.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, p.matches, m.Match(p.path))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	top, err := Parse("", []byte(""+
+		"# A comment\n"+
+		"[attr]lfs filter=lfs diff=lfs merge=lfs -text\n"+
+		"*.bin lfs\n"+
+		"\"with space/*.png\" filter=lfs\n"+
+		"!*.dat filter=lfs\n"+
+		"*.jpg binary filter=lfs\n"+
+		"special.jpg !filter\n",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"!*.dat filter=lfs"}, top.Ignored)
+	require.Len(t, top.Lines, 4)
+	assert.Equal(t, "with space/*.png", top.Lines[1].Pattern)
+
+	nested, err := Parse("sub", []byte(""+
+		"[attr]ignored -text\n"+
+		"*.bin -filter\n",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[attr]ignored -text"}, nested.Ignored)
+
+	files := []*File{top, nested}
+
+	a := Lookup(files, "dir/a.bin", "filter")
+	assert.Equal(t, Value, a.State)
+	assert.Equal(t, "lfs", a.Value)
+
+	assert.Equal(t, Unset, Lookup(files, "dir/a.bin", "text").State)
+	assert.Equal(t, Unset, Lookup(files, "sub/a.bin", "filter").State)
+	assert.Equal(t, Value, Lookup(files, "with space/x.png", "filter").State)
+	assert.Equal(t, Unspecified, Lookup(files, "x.dat", "filter").State)
+	assert.Equal(t, Unset, Lookup(files, "photo.jpg", "diff").State)
+	assert.Equal(t, Unspecified, Lookup(files, "special.jpg", "filter").State)
+}
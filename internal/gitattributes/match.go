@@ -0,0 +1,116 @@
+package gitattributes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches paths against a single gitignore-style glob
+// pattern.
+type Matcher struct {
+	pattern string
+	re      *regexp.Regexp
+
+	// basename is true if the pattern contains no slash, in which
+	// case it is matched against the last component of the path
+	// only.
+	basename bool
+}
+
+// Compile compiles `pattern`, which uses the same syntax as patterns
+// in `.gitignore` and `.gitattributes` files: `*` and `?` don't match
+// `/`, `[...]` is a character class, and `**` matches across
+// directories when it forms a whole path component. A pattern with no
+// slash matches the basename of a path in any directory; otherwise it
+// is anchored at the top of the tree.
+func Compile(pattern string) (*Matcher, error) {
+	m := &Matcher{pattern: pattern}
+	p := pattern
+	if !strings.Contains(p, "/") {
+		m.basename = true
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' &&
+				(i == 0 || p[i-1] == '/') &&
+				(i+2 == len(p) || p[i+2] == '/') {
+				switch {
+				case i+2 == len(p):
+					// Trailing `**`: everything inside.
+					sb.WriteString(".*")
+				default:
+					// `**/`: zero or more directories.
+					sb.WriteString("(?:.*/)?")
+					i++
+				}
+				i++
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			j := i + 1
+			if j < len(p) && (p[j] == '!' || p[j] == '^') {
+				j++
+			}
+			if j < len(p) && p[j] == ']' {
+				j++
+			}
+			for j < len(p) && p[j] != ']' {
+				j++
+			}
+			if j >= len(p) {
+				sb.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := p[i+1 : j]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[")
+			sb.WriteString(strings.ReplaceAll(class, `\`, `\\`))
+			sb.WriteString("]")
+			i = j
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				c = p[i]
+			}
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("compiling pattern %q: %w", pattern, err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// Match returns true iff `path` (relative to the directory that the
+// pattern is relative to) matches the pattern.
+func (m *Matcher) Match(path string) bool {
+	if m.basename {
+		if i := strings.LastIndexByte(path, '/'); i != -1 {
+			path = path[i+1:]
+		}
+	}
+	return m.re.MatchString(path)
+}
+
+// String returns the original pattern.
+func (m *Matcher) String() string {
+	return m.pattern
+}
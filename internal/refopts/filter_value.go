@@ -75,19 +75,32 @@ func (v *filterValue) Set(s string) error {
 // Interpret an option argument flexibly:
 //
 // * If it is bracketed with `/` characters, treat it as a regexp.
+//   Reference names can't start with `/`, so this can't shadow a
+//   literal name or prefix.
 //
-// * If it starts with `@`, then consider it a refgroup name. That
-//   refgroup must already be defined. Use its filter. This construct
-//   is only allowed at the top level.
+// * If it starts with `@` and the rest looks like a refgroup name (it
+//   contains no `/` and doesn't start with `{`), then consider it a
+//   refgroup name. That refgroup must already be defined. Use its
+//   filter. This construct is only allowed at the top level.
 //
-// * Otherwise treat it as a prefix.
+// * If it contains glob metacharacters (`*`, `?`, or `[`), none of
+//   which are allowed in reference names, treat it as a glob pattern
+//   (e.g., `refs/pull/*/head`).
+//
+// * Otherwise treat it as a prefix. Reference names can contain
+//   characters like `@`, `{`, and non-ASCII letters, which are
+//   matched literally.
 func (v *filterValue) interpretFlexibly(s string) (git.ReferenceFilter, error) {
 	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
 		pattern := s[1 : len(s)-1]
-		return git.RegexpFilter(pattern)
+		f, err := git.RegexpFilter(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp %q: %w", pattern, err)
+		}
+		return f, nil
 	}
 
-	if len(s) >= 1 && s[0] == '@' {
+	if isRefGroupReference(s) {
 		name := sizes.RefGroupSymbol(s[1:])
 		if name == "" {
 			return nil, errors.New("missing refgroup name")
@@ -101,9 +114,27 @@ func (v *filterValue) interpretFlexibly(s string) (git.ReferenceFilter, error) {
 		return refGroupFilter{refGroup}, nil
 	}
 
+	if git.IsGlob(s) {
+		f, err := git.GlobFilter(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", s, err)
+		}
+		return f, nil
+	}
+
 	return git.PrefixFilter(s), nil
 }
 
+// isRefGroupReference returns true iff `s` has the form `@REFGROUP`.
+// Refgroup names are dot-separated, so an argument containing `/` is
+// a reference prefix that happens to start with `@`, and `@{...}` is
+// Git's syntax for reflogs and upstreams, not a refgroup.
+func isRefGroupReference(s string) bool {
+	return strings.HasPrefix(s, "@") &&
+		!strings.HasPrefix(s, "@{") &&
+		!strings.Contains(s, "/")
+}
+
 func (v *filterValue) Get() interface{} {
 	return nil
 }
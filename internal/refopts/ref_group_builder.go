@@ -252,6 +252,13 @@ func (rgb *RefGroupBuilder) AddRefopts(flags *pflag.FlagSet) {
 	flag.Deprecated = "use --include=@REFGROUP"
 }
 
+// HasRefopts returns true if any reference selection options (e.g.,
+// `--branches` or `--include`) have been processed. It must be called
+// before `Finish()`.
+func (rgb *RefGroupBuilder) HasRefopts() bool {
+	return rgb.topLevelGroup.filter != nil
+}
+
 // Finish collects the information gained from processing the options
 // and returns a `sizes.RefGrouper`.
 func (rgb *RefGroupBuilder) Finish(defaultAll bool) (sizes.RefGrouper, error) {
@@ -87,6 +87,27 @@ func (repo *TestRepo) Clone(t *testing.T, pattern string) *TestRepo {
 	}
 }
 
+// AddWorktree creates a linked worktree of `repo` at a temporary path
+// constructed using `pattern`, with a detached `HEAD` pointing at the
+// same commit as `repo`'s. The caller is responsible for removing it
+// when done by calling `Remove()`.
+func (repo *TestRepo) AddWorktree(t *testing.T, pattern string) *TestRepo {
+	t.Helper()
+
+	// `git worktree add` accepts an empty directory:
+	path, err := os.MkdirTemp("", pattern)
+	require.NoError(t, err)
+
+	err = repo.GitCommand(
+		t, "worktree", "add", "--detach", path, "HEAD",
+	).Run()
+	require.NoError(t, err)
+
+	return &TestRepo{
+		Path: path,
+	}
+}
+
 // Repository returns a `*git.Repository` for `repo`.
 func (repo *TestRepo) Repository(t *testing.T) *git.Repository {
 	t.Helper()
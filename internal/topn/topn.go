@@ -0,0 +1,137 @@
+// Package topn keeps track of the greatest few items out of a stream
+// of items, using a bounded heap.
+package topn
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// TopN retains the `n` greatest of the items that are added to it,
+// according to a comparison function. Items that compare equal are
+// ranked in the order that they were added: an earlier item ranks
+// higher than a later one that ties with it, so the results don't
+// depend on the heap's internal order.
+//
+// The zero value is not usable; use `New()`.
+type TopN[T any] struct {
+	n    int
+	less func(a, b T) bool
+
+	// h is a min-heap, so that the lowest-ranked retained item,
+	// which is the first to be evicted, is at `h.entries[0]`.
+	h entryHeap[T]
+
+	// seq is the number of items that have been added.
+	seq uint64
+}
+
+// New returns a `TopN` that retains the `n` greatest items, where
+// `less(a, b)` reports whether `a` is less than `b`. If `n` is zero
+// or negative, no items are retained.
+func New[T any](n int, less func(a, b T) bool) *TopN[T] {
+	if n < 0 {
+		n = 0
+	}
+	t := &TopN[T]{n: n, less: less}
+	t.h.less = t.entryLess
+	return t
+}
+
+type entry[T any] struct {
+	item T
+	seq  uint64
+}
+
+// entryLess reports whether `a` ranks lower than `b`.
+func (t *TopN[T]) entryLess(a, b entry[T]) bool {
+	if t.less(a.item, b.item) {
+		return true
+	}
+	if t.less(b.item, a.item) {
+		return false
+	}
+	// Among ties, the later one ranks lower:
+	return a.seq > b.seq
+}
+
+// Add offers `item`, and reports whether it was retained (it might
+// still be evicted later by greater items).
+func (t *TopN[T]) Add(item T) bool {
+	e := entry[T]{item: item, seq: t.seq}
+	t.seq++
+
+	if len(t.h.entries) < t.n {
+		heap.Push(&t.h, e)
+		return true
+	}
+
+	// Most items in a long stream are smaller than everything that
+	// has been retained, so check that first, without touching the
+	// heap. (A new item that ties with the lowest-ranked retained
+	// item ranks below it, so it is rejected, too.)
+	if t.n == 0 || !t.entryLess(t.h.entries[0], e) {
+		return false
+	}
+	t.h.entries[0] = e
+	heap.Fix(&t.h, 0)
+	return true
+}
+
+// Len returns the number of items that are currently retained.
+func (t *TopN[T]) Len() int {
+	return len(t.h.entries)
+}
+
+// Full reports whether `n` items are retained, in which case
+// `Min()` is the threshold that new items have to exceed.
+func (t *TopN[T]) Full() bool {
+	return len(t.h.entries) >= t.n
+}
+
+// Min returns the lowest-ranked retained item, or `false` if there
+// are none.
+func (t *TopN[T]) Min() (T, bool) {
+	if len(t.h.entries) == 0 {
+		var zero T
+		return zero, false
+	}
+	return t.h.entries[0].item, true
+}
+
+// Items returns the retained items, greatest first (and, among ties,
+// in the order that they were added).
+func (t *TopN[T]) Items() []T {
+	entries := make([]entry[T], len(t.h.entries))
+	copy(entries, t.h.entries)
+	sort.Slice(entries, func(i, j int) bool {
+		return t.entryLess(entries[j], entries[i])
+	})
+
+	items := make([]T, len(entries))
+	for i, e := range entries {
+		items[i] = e.item
+	}
+	return items
+}
+
+// entryHeap implements `heap.Interface` as a min-heap on `less`.
+type entryHeap[T any] struct {
+	entries []entry[T]
+	less    func(a, b entry[T]) bool
+}
+
+func (h *entryHeap[T]) Len() int           { return len(h.entries) }
+func (h *entryHeap[T]) Less(i, j int) bool { return h.less(h.entries[i], h.entries[j]) }
+func (h *entryHeap[T]) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *entryHeap[T]) Push(x interface{}) {
+	h.entries = append(h.entries, x.(entry[T]))
+}
+
+func (h *entryHeap[T]) Pop() interface{} {
+	n := len(h.entries)
+	e := h.entries[n-1]
+	h.entries = h.entries[:n-1]
+	return e
+}
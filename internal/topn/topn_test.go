@@ -0,0 +1,103 @@
+package topn_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/github/git-sizer/internal/topn"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func TestTopN(t *testing.T) {
+	t.Parallel()
+
+	top := topn.New(3, intLess)
+	_, ok := top.Min()
+	assert.False(t, ok)
+	assert.Empty(t, top.Items())
+
+	assert.True(t, top.Add(5))
+	assert.True(t, top.Add(1))
+	assert.False(t, top.Full())
+	assert.True(t, top.Add(3))
+	assert.True(t, top.Full())
+	assert.Equal(t, []int{5, 3, 1}, top.Items())
+
+	min, ok := top.Min()
+	assert.True(t, ok)
+	assert.Equal(t, 1, min)
+
+	// Too small:
+	assert.False(t, top.Add(0))
+	// Big enough; evicts 1:
+	assert.True(t, top.Add(4))
+	assert.Equal(t, []int{5, 4, 3}, top.Items())
+	assert.Equal(t, 3, top.Len())
+
+	// Nothing is retained if n is zero:
+	none := topn.New(0, intLess)
+	assert.False(t, none.Add(1))
+	assert.Empty(t, none.Items())
+}
+
+func TestTopNRandom(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(42))
+	for _, n := range []int{1, 2, 10, 100} {
+		var all []int
+		top := topn.New(n, intLess)
+		for i := 0; i < 1000; i++ {
+			v := r.Intn(500)
+			all = append(all, v)
+			top.Add(v)
+		}
+
+		sort.Sort(sort.Reverse(sort.IntSlice(all)))
+		assert.Equalf(t, all[:n], top.Items(), "top %d", n)
+	}
+}
+
+func TestTopNTies(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		key  int
+		name string
+	}
+	less := func(a, b item) bool { return a.key < b.key }
+
+	top := topn.New(3, less)
+	for _, it := range []item{
+		{1, "a"}, {2, "b"}, {1, "c"}, {2, "d"}, {1, "e"}, {2, "f"}, {2, "g"},
+	} {
+		top.Add(it)
+	}
+
+	// Among equal items, the earliest ones win and are listed first:
+	assert.Equal(t, []item{{2, "b"}, {2, "d"}, {2, "f"}}, top.Items())
+
+	// The result doesn't depend on how the items were interleaved:
+	for trial := 0; trial < 20; trial++ {
+		r := rand.New(rand.NewSource(int64(trial)))
+		var items []item
+		for i := 0; i < 50; i++ {
+			items = append(items, item{key: r.Intn(3), name: string(rune('A' + i))})
+		}
+
+		top := topn.New(10, less)
+		for _, it := range items {
+			top.Add(it)
+		}
+
+		expected := append([]item(nil), items...)
+		sort.SliceStable(expected, func(i, j int) bool {
+			return expected[i].key > expected[j].key
+		})
+		assert.Equal(t, expected[:10], top.Items())
+	}
+}
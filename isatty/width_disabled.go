@@ -0,0 +1,10 @@
+//go:build !isatty
+// +build !isatty
+
+package isatty
+
+// TerminalWidth is a stub implementation of `TerminalWidth()` that
+// always reports that the width is unknown.
+func TerminalWidth(fd uintptr) (int, bool) {
+	return 0, false
+}
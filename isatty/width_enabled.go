@@ -0,0 +1,28 @@
+//go:build isatty
+// +build isatty
+
+package isatty
+
+/*
+#include <sys/ioctl.h>
+
+static int terminal_width(int fd) {
+	struct winsize ws;
+	if (ioctl(fd, TIOCGWINSZ, &ws) != 0) {
+		return -1;
+	}
+	return ws.ws_col;
+}
+*/
+import "C"
+
+// TerminalWidth tries to determine the width, in columns, of the
+// terminal connected to `fd`. It returns `0, false` if `fd` is not a
+// terminal or its width can't be determined.
+func TerminalWidth(fd uintptr) (int, bool) {
+	width := int(C.terminal_width(C.int(fd)))
+	if width <= 0 {
+		return 0, false
+	}
+	return width, true
+}
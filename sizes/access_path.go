@@ -0,0 +1,39 @@
+package sizes
+
+import "github.com/github/git-sizer/git"
+
+// AccessPath describes how reachability queries (used by
+// `--default-branch`, `--simulate-delete`, and the like) were
+// answered. The main scan always reads every object, so it isn't
+// affected.
+type AccessPath string
+
+const (
+	// AccessPathWalk means that git walked the history to answer
+	// reachability queries.
+	AccessPathWalk AccessPath = "walk"
+
+	// AccessPathBitmap means that git used the reachability bitmap
+	// (and the commit-graph) to answer reachability queries.
+	AccessPathBitmap AccessPath = "bitmap"
+)
+
+// ChooseAccessPath decides how `repo` should answer reachability
+// queries and configures it accordingly. The bitmap is used if the
+// repository is fully packed (see `git.PackState.FullyPacked()`),
+// unless `forceWalk` is set (e.g., to verify the fast path's
+// results).
+func ChooseAccessPath(repo *git.Repository, forceWalk bool) (AccessPath, error) {
+	path := AccessPathWalk
+	if !forceWalk {
+		ps, err := repo.PackState()
+		if err != nil {
+			return "", err
+		}
+		if ps.FullyPacked() {
+			path = AccessPathBitmap
+		}
+	}
+	repo.SetUseBitmapIndex(path == AccessPathBitmap)
+	return path, nil
+}
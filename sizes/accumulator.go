@@ -0,0 +1,176 @@
+package sizes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// ErrMissingDependency is returned (wrapped) by the `SizeAccumulator`
+// methods if an object is added before an object that it depends on.
+var ErrMissingDependency = errors.New("object added before its dependency")
+
+// SizeAccumulator computes size statistics for objects that the
+// caller pushes into it, for callers that already have a stream of
+// objects from somewhere other than a `git.Repository` (e.g., a pack
+// indexer or a server that is receiving a push). It is a thin layer
+// over the same `Graph` that `ScanRepositoryUsingGraph()` fills from
+// `git cat-file`.
+//
+// Each object should be added exactly once. The objects must be added
+// in an order that satisfies these constraints:
+//
+//   - A blob must be added before any tree that contains it.
+//   - Trees can be added in any order relative to each other.
+//   - A commit must be added after its tree and all of the trees
+//     under it, and after all of its parents.
+//   - Tags can be added at any time, in any order.
+//
+// References can be added at any time.
+type SizeAccumulator struct {
+	graph *Graph
+}
+
+// NewSizeAccumulator returns a new, empty `SizeAccumulator`.
+// `opts.TreeOrder` is ignored, since the caller chooses the order.
+func NewSizeAccumulator(opts ScanOptions) *SizeAccumulator {
+	return &SizeAccumulator{
+		graph: NewGraphWithOptions(opts),
+	}
+}
+
+// AddBlob records a blob with the specified size. (The blob's contents
+// aren't needed. Its paths are learned from the trees that contain
+// it.)
+func (a *SizeAccumulator) AddBlob(oid git.OID, size counts.Count32) {
+	a.graph.RegisterBlob(oid, size)
+}
+
+// AddTree records a tree, whose contents (in the format output by
+// `git cat-file tree`) are `data`.
+func (a *SizeAccumulator) AddTree(oid git.OID, data []byte) error {
+	tree, err := git.ParseTree(oid, data)
+	if err != nil {
+		return err
+	}
+
+	iter := tree.Iter()
+	for {
+		entry, ok, err := iter.NextEntry()
+		if err != nil {
+			return fmt.Errorf("parsing tree %s: %w", oid, err)
+		}
+		if !ok {
+			break
+		}
+		switch entry.Filemode & 0o170000 {
+		case 0o40000, 0o160000, 0o120000:
+			// Trees can be added in any order, submodules
+			// aren't needed, and symlinks' sizes aren't used.
+		default:
+			if !a.graph.hasBlobSize(entry.OID) {
+				return fmt.Errorf(
+					"%w: tree %s contains blob %s", ErrMissingDependency, oid, entry.OID,
+				)
+			}
+		}
+	}
+
+	return a.graph.RegisterTree(oid, tree)
+}
+
+// AddCommit records a commit, whose contents (in the format output by
+// `git cat-file commit`) are `data`.
+func (a *SizeAccumulator) AddCommit(oid git.OID, data []byte) error {
+	commit, err := git.ParseCommit(oid, data)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := a.TreeSize(commit.Tree); !ok {
+		return fmt.Errorf(
+			"%w: the tree %s of commit %s is not complete",
+			ErrMissingDependency, commit.Tree, oid,
+		)
+	}
+	for _, parent := range commit.Parents {
+		if !a.graph.hasCommitSize(parent) {
+			return fmt.Errorf(
+				"%w: commit %s has parent %s", ErrMissingDependency, oid, parent,
+			)
+		}
+	}
+
+	a.graph.RegisterCommit(oid, commit)
+	return nil
+}
+
+// AddTag records an annotated tag, whose contents (in the format
+// output by `git cat-file tag`) are `data`.
+func (a *SizeAccumulator) AddTag(oid git.OID, data []byte) error {
+	tag, err := git.ParseTag(oid, data)
+	if err != nil {
+		return err
+	}
+
+	a.graph.RegisterTag(oid, tag)
+	return nil
+}
+
+// AddReference records a reference, which belongs to `groups`.
+func (a *SizeAccumulator) AddReference(ref git.Reference, groups []RefGroupSymbol) {
+	a.graph.RegisterReference(ref, groups)
+	a.graph.RegisterName(ref.Refname, ref.OID)
+}
+
+// TreeSize returns the size of the tree `oid`, if it is known (i.e.,
+// if the tree and all of the trees under it have been added).
+func (a *SizeAccumulator) TreeSize(oid git.OID) (TreeSize, bool) {
+	a.graph.treeLock.Lock()
+	defer a.graph.treeLock.Unlock()
+
+	return a.graph.treeSizes.Get(oid)
+}
+
+// Finalize returns the statistics for all of the objects that have
+// been added. It returns an error if some trees or tags are still
+// waiting for objects that they refer to.
+func (a *SizeAccumulator) Finalize() (HistorySize, error) {
+	g := a.graph
+
+	g.treeLock.Lock()
+	pendingTrees := len(g.treeRecords)
+	g.treeLock.Unlock()
+	g.tagLock.Lock()
+	pendingTags := len(g.tagRecords)
+	g.tagLock.Unlock()
+
+	if pendingTrees != 0 || pendingTags != 0 {
+		return HistorySize{}, fmt.Errorf(
+			"%w: %d tree(s) and %d tag(s) are incomplete",
+			ErrMissingDependency, pendingTrees, pendingTags,
+		)
+	}
+
+	return g.HistorySize(), nil
+}
+
+// hasBlobSize returns true iff the size of blob `oid` is known.
+func (g *Graph) hasBlobSize(oid git.OID) bool {
+	g.blobLock.Lock()
+	defer g.blobLock.Unlock()
+
+	_, ok := g.blobSizes[oid]
+	return ok
+}
+
+// hasCommitSize returns true iff the size of commit `oid` is known.
+func (g *Graph) hasCommitSize(oid git.OID) bool {
+	g.commitLock.Lock()
+	defer g.commitLock.Unlock()
+
+	_, ok := g.commitSizes[oid]
+	return ok
+}
@@ -0,0 +1,32 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/git"
+)
+
+// RootsAsOf returns roots for the commits that the references in
+// `refRoots` pointed at as of `date` (see `git.CommitAsOf()`), so that
+// scanning them sizes the repository as it was then. References that
+// didn't point at a commit at that time are omitted. The roots are
+// named like `REFNAME@{DATE}`.
+func RootsAsOf(repo *git.Repository, refRoots []RefRoot, date string) ([]Root, error) {
+	t, err := repo.ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]Root, 0, len(refRoots))
+	for _, refRoot := range refRoots {
+		oid, ok, err := repo.CommitAsOf(refRoot.Name(), t)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %q as of %q: %w", refRoot.Name(), date, err)
+		}
+		if !ok {
+			continue
+		}
+		roots = append(roots, NewExplicitRoot(fmt.Sprintf("%s@{%s}", refRoot.Name(), date), oid))
+	}
+	return roots, nil
+}
@@ -0,0 +1,40 @@
+package sizes
+
+import (
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// authorStats keeps track of how many commits each author and
+// committer has made. Emails are folded to lower case so that trivial
+// variations are counted together.
+//
+// Note that these data identify individual people. They are only used
+// to compute the aggregate counts in `HistorySize`, but even those
+// should be treated as sensitive.
+type authorStats struct {
+	authorCounts map[string]counts.Count32
+	committers   map[string]struct{}
+}
+
+func newAuthorStats() authorStats {
+	return authorStats{
+		authorCounts: make(map[string]counts.Count32),
+		committers:   make(map[string]struct{}),
+	}
+}
+
+// recordAuthors records a commit by `authorEmail` and
+// `committerEmail`, updating the author-related fields of `s`.
+func (s *HistorySize) recordAuthors(a *authorStats, authorEmail, committerEmail string) {
+	authorEmail = strings.ToLower(authorEmail)
+	n := a.authorCounts[authorEmail]
+	n.Increment(1)
+	a.authorCounts[authorEmail] = n
+	s.MaxSingleAuthorCommitCount.AdjustMaxIfNecessary(n)
+	s.UniqueAuthorCount = counts.NewCount32(uint64(len(a.authorCounts)))
+
+	a.committers[strings.ToLower(committerEmail)] = struct{}{}
+	s.UniqueCommitterCount = counts.NewCount32(uint64(len(a.committers)))
+}
@@ -0,0 +1,56 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// MissingBitmapCommitThreshold is the number of commits above which a
+// repository without a reachability bitmap is reported as a problem.
+// Without a bitmap, serving a clone of such a repository requires
+// walking its whole history.
+const MissingBitmapCommitThreshold = 100000
+
+// BitmapStatus describes a repository's reachability bitmap.
+type BitmapStatus struct {
+	// Present is true if there is a reachability bitmap.
+	Present bool `json:"present"`
+
+	// Path is the path of the bitmap that git would use.
+	Path string `json:"path,omitempty"`
+
+	// CommitCount is the number of commits that have bitmaps.
+	CommitCount counts.Count32 `json:"commit_count"`
+
+	// BitCount is the number of objects that the bitmap covers.
+	BitCount counts.Count32 `json:"bit_count"`
+}
+
+// CheckBitmap examines the reachability bitmap of `repo`, if any.
+func CheckBitmap(repo *git.Repository) (*BitmapStatus, error) {
+	stats, err := repo.BitmapStats()
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return &BitmapStatus{}, nil
+	}
+
+	return &BitmapStatus{
+		Present:     true,
+		Path:        stats.Path,
+		CommitCount: counts.NewCount32(uint64(stats.CommitCount)),
+		BitCount:    counts.NewCount32(uint64(stats.BitCount)),
+	}, nil
+}
+
+// missingBitmap returns 1 if the bitmap was checked and is missing,
+// even though the repository has more than
+// `MissingBitmapCommitThreshold` commits; otherwise, 0.
+func (s *HistorySize) missingBitmap() counts.Count32 {
+	if s.Bitmap == nil || s.Bitmap.Present ||
+		s.UniqueCommitCount <= MissingBitmapCommitThreshold {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,184 @@
+package sizes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/github/git-sizer/git"
+)
+
+// CacheBackend stores the `TreeSize`s that have been computed during
+// a scan. Trees are by far the most numerous objects whose sizes have
+// to be remembered, so for very large repositories it can be useful
+// to keep them somewhere other than in memory, or to keep them across
+// runs.
+//
+// Other backends (e.g., one based on an embedded key-value store like
+// bbolt, with OIDs as keys and JSON-encoded `TreeSize`s as values)
+// only have to implement this interface. Implementations must be safe
+// for concurrent use.
+type CacheBackend interface {
+	// Get returns the size of the tree `oid`, if it is known.
+	Get(oid git.OID) (TreeSize, bool)
+
+	// Set records the size of the tree `oid`.
+	Set(oid git.OID, ts TreeSize)
+
+	// Close releases any resources held by the backend, persisting
+	// its contents if applicable.
+	Close() error
+}
+
+// MemoryCacheBackend is a `CacheBackend` that keeps everything in a
+// map. This is the default.
+type MemoryCacheBackend struct {
+	lock      sync.Mutex
+	treeSizes map[git.OID]TreeSize
+}
+
+// NewMemoryCacheBackend returns an empty `MemoryCacheBackend`.
+func NewMemoryCacheBackend() *MemoryCacheBackend {
+	return &MemoryCacheBackend{
+		treeSizes: make(map[git.OID]TreeSize),
+	}
+}
+
+// Get implements `CacheBackend`.
+func (b *MemoryCacheBackend) Get(oid git.OID) (TreeSize, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ts, ok := b.treeSizes[oid]
+	return ts, ok
+}
+
+// Set implements `CacheBackend`.
+func (b *MemoryCacheBackend) Set(oid git.OID, ts TreeSize) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.treeSizes[oid] = ts
+}
+
+// Close implements `CacheBackend`.
+func (b *MemoryCacheBackend) Close() error {
+	return nil
+}
+
+// Len returns the number of tree sizes in the cache.
+func (b *MemoryCacheBackend) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.treeSizes)
+}
+
+// FileCacheFormatVersion is the version of the format of the files
+// that `FileCacheBackend` reads and writes. It has to be increased
+// whenever the fields of `TreeSize` or their meanings change, since
+// the sizes in a file that was written with other fields can't be
+// trusted.
+const FileCacheFormatVersion = 2
+
+// ErrFileCacheVersion is returned (wrapped) by
+// `OpenFileCacheBackend()` if the cache file was written in a
+// different format version (see `FileCacheFormatVersion`).
+var ErrFileCacheVersion = errors.New("cache file has the wrong format version")
+
+// fileCacheContents is the contents of a cache file.
+type fileCacheContents struct {
+	Version   int                 `json:"version"`
+	TreeSizes map[string]TreeSize `json:"tree_sizes"`
+}
+
+// FileCacheBackend is a `CacheBackend` that is loaded from a JSON
+// file when it is opened and written back to that file when it is
+// closed, so that tree sizes can be reused by later scans of the same
+// repository. While it is open, its contents are held in memory.
+type FileCacheBackend struct {
+	MemoryCacheBackend
+
+	path string
+}
+
+// OpenFileCacheBackend opens the cache file at `path`. If the file
+// doesn't exist yet, the cache starts out empty and the file is
+// created when the backend is closed. A file that was written in
+// another format version is rejected; it has to be deleted so that
+// the cache can be rebuilt.
+func OpenFileCacheBackend(path string) (*FileCacheBackend, error) {
+	b := &FileCacheBackend{
+		MemoryCacheBackend: *NewMemoryCacheBackend(),
+		path:               path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return b, nil
+		}
+		return nil, fmt.Errorf("reading cache file: %w", err)
+	}
+
+	var contents fileCacheContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("parsing cache file %s: %w", path, err)
+	}
+	if contents.Version != FileCacheFormatVersion {
+		return nil, fmt.Errorf(
+			"%w: %s has version %d, but version %d is needed",
+			ErrFileCacheVersion, path, contents.Version, FileCacheFormatVersion,
+		)
+	}
+	for s, ts := range contents.TreeSizes {
+		oid, err := git.NewOID(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing cache file %s: %w", path, err)
+		}
+		b.treeSizes[oid] = ts
+	}
+
+	return b, nil
+}
+
+// Close writes the contents of the cache to its file. The file is
+// replaced atomically, so an interrupted write doesn't corrupt an
+// existing cache.
+func (b *FileCacheBackend) Close() error {
+	b.lock.Lock()
+	contents := fileCacheContents{
+		Version:   FileCacheFormatVersion,
+		TreeSizes: make(map[string]TreeSize, len(b.treeSizes)),
+	}
+	for oid, ts := range b.treeSizes {
+		contents.TreeSizes[oid.String()] = ts
+	}
+	b.lock.Unlock()
+
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxCaseCollisions is the maximum number of examples of case
+// collisions between entries of different types that are kept in
+// `HistorySize`.
+const maxCaseCollisions = 10
+
+// CaseCollision describes two entries in the same tree whose names
+// differ only in case, and which are of different types (e.g., a
+// symlink `Config` next to a file `config`). On a case-insensitive
+// filesystem only one of them can be checked out, and which one wins
+// depends on the platform.
+type CaseCollision struct {
+	// Name1 and Mode1 are the name and mode (in octal, as in `git
+	// ls-tree`) of the entry that comes first in the tree.
+	Name1 string `json:"name1"`
+	Mode1 string `json:"mode1"`
+
+	// Name2 and Mode2 are those of the entry that comes later.
+	Name2 string `json:"name2"`
+	Mode2 string `json:"mode2"`
+
+	// Tree is the tree that contains both entries.
+	Tree *Path `json:"tree,omitempty"`
+}
+
+// entryKind returns the kind of object that a tree entry with mode
+// `filemode` refers to.
+func entryKind(filemode uint) string {
+	switch filemode & 0o170000 {
+	case 0o40000:
+		return "tree"
+	case 0o160000:
+		return "submodule"
+	case 0o120000:
+		return "symlink"
+	default:
+		return "blob"
+	}
+}
+
+// caseFolder finds the entries of a single tree whose names differ
+// only in case (and, if `normalize` is set, in Unicode
+// normalization).
+type caseFolder struct {
+	// normalize is set if names should be converted to NFC before
+	// they are case-folded.
+	normalize bool
+
+	// entries counts the entries added so far. The first one is
+	// kept in `first` and `firstFolded`, and `seen` is only allocated
+	// once a second one comes along.
+	entries     int
+	first       git.TreeEntry
+	firstFolded string
+
+	// seen maps the case-folded name of each entry to the first
+	// entry with that folded name.
+	seen map[string]git.TreeEntry
+
+	// sameKind counts the collisions between entries of the same
+	// kind; the others are recorded in full in `mixed`.
+	sameKind counts.Count32
+	mixed    []CaseCollision
+}
+
+// add checks whether `entry` collides with an earlier entry.
+func (f *caseFolder) add(entry git.TreeEntry) {
+	name := entry.Name
+	if f.normalize {
+		name = norm.NFC.String(name)
+	}
+	folded := foldCase(name)
+
+	f.entries++
+	var other git.TreeEntry
+	switch f.entries {
+	case 1:
+		f.first, f.firstFolded = entry, folded
+		return
+	case 2:
+		f.seen = map[string]git.TreeEntry{f.firstFolded: f.first}
+		fallthrough
+	default:
+		var ok bool
+		other, ok = f.seen[folded]
+		if !ok {
+			f.seen[folded] = entry
+			return
+		}
+	}
+	if entryKind(other.Filemode) == entryKind(entry.Filemode) {
+		f.sameKind.Increment(1)
+		return
+	}
+	f.mixed = append(f.mixed, CaseCollision{
+		Name1: other.Name,
+		Mode1: fmt.Sprintf("%06o", other.Filemode),
+		Name2: entry.Name,
+		Mode2: fmt.Sprintf("%06o", entry.Filemode),
+	})
+}
+
+// registerCaseCollisions records the collisions that `f` found in the
+// tree `oid`.
+func (g *Graph) registerCaseCollisions(oid git.OID, f *caseFolder) {
+	if f.sameKind == 0 && len(f.mixed) == 0 {
+		return
+	}
+
+	g.historyLock.Lock()
+	defer g.historyLock.Unlock()
+
+	s := &g.historySize
+	s.CaseCollisionCount.Increment(f.sameKind)
+	for _, c := range f.mixed {
+		s.MixedTypeCaseCollisionCount.Increment(1)
+		if len(s.MixedTypeCaseCollisions) < maxCaseCollisions {
+			setPath(g.pathResolver, &c.Tree, oid, "tree")
+			s.MixedTypeCaseCollisions = append(s.MixedTypeCaseCollisions, c)
+		}
+	}
+}
+
+// mixedTypeCaseCollisionTree returns the tree containing the first
+// mixed-type case collision that was found, or nil if there were none.
+func mixedTypeCaseCollisionTree(s *HistorySize) *Path {
+	if len(s.MixedTypeCaseCollisions) == 0 {
+		return nil
+	}
+	return s.MixedTypeCaseCollisions[0].Tree
+}
@@ -0,0 +1,93 @@
+package sizes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Chunk describes one piece of a long list of records that was
+// written in pieces by `EncodeChunks()`.
+type Chunk struct {
+	// Name identifies the chunk (e.g., the name of the file that
+	// holds it).
+	Name string `json:"name"`
+
+	// First is the index, within the whole list, of the first record
+	// in the chunk.
+	First int `json:"first"`
+
+	// RecordCount is the number of records in the chunk.
+	RecordCount int `json:"record_count"`
+
+	// SHA256 is the hex-encoded SHA-256 of the chunk's contents.
+	SHA256 string `json:"sha256"`
+}
+
+// EncodeJSONLines encodes `records` as JSON, one record per line.
+func EncodeJSONLines[T any](records []T) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeChunks splits `records` into chunks of at most `chunkSize`
+// records, encodes each one like `EncodeJSONLines()`, and passes it to
+// `fn` along with its (zero-based) index. `fn` returns the name of
+// the chunk, which is recorded in the result. The records must
+// already be in a deterministic order (e.g., sorted), so that the same
+// data always produces the same chunks. Concatenating the chunks
+// reproduces the output of `EncodeJSONLines(records)`.
+func EncodeChunks[T any](
+	records []T, chunkSize int, fn func(index int, data []byte) (string, error),
+) ([]Chunk, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	var chunks []Chunk
+	for first := 0; first < len(records); first += chunkSize {
+		end := first + chunkSize
+		if end > len(records) {
+			end = len(records)
+		}
+		data, err := EncodeJSONLines(records[first:end])
+		if err != nil {
+			return nil, err
+		}
+		name, err := fn(len(chunks), data)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		chunks = append(chunks, Chunk{
+			Name:        name,
+			First:       first,
+			RecordCount: end - first,
+			SHA256:      hex.EncodeToString(sum[:]),
+		})
+	}
+	return chunks, nil
+}
+
+// WriteChunkFiles is like `EncodeChunks()`, but writes the chunks to
+// files in `dir` called `PREFIX-0001.jsonl`, `PREFIX-0002.jsonl`,
+// etc., and names the chunks after the files.
+func WriteChunkFiles[T any](dir, prefix string, records []T, chunkSize int) ([]Chunk, error) {
+	return EncodeChunks(records, chunkSize, func(index int, data []byte) (string, error) {
+		name := fmt.Sprintf("%s-%04d.jsonl", prefix, index+1)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return "", fmt.Errorf("writing chunk: %w", err)
+		}
+		return name, nil
+	})
+}
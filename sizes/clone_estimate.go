@@ -0,0 +1,92 @@
+package sizes
+
+import (
+	"time"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// The assumptions behind `EstimateCloneCost()`. They are rough
+// figures for a typical developer machine with an SSD.
+const (
+	// indexObjectsPerSecond is how many objects `git index-pack`
+	// can process (including resolving deltas) per second.
+	indexObjectsPerSecond = 100e3
+
+	// checkoutFilesPerSecond is how many files a checkout can
+	// create per second, not counting the time to write their
+	// contents.
+	checkoutFilesPerSecond = 5e3
+
+	// checkoutBytesPerSecond is how fast a checkout can write file
+	// contents.
+	checkoutBytesPerSecond = 200e6
+)
+
+// CloneEstimate is a rough estimate of what it costs to clone a
+// repository. See `EstimateCloneCost()`.
+type CloneEstimate struct {
+	// DownloadSize is the estimated number of bytes transferred.
+	DownloadSize counts.Count64 `json:"download_size"`
+
+	// DownloadTime is the estimated time to transfer
+	// `DownloadSize` bytes.
+	DownloadTime time.Duration `json:"download_time"`
+
+	// IndexTime is the estimated time for the client to index the
+	// objects that it received.
+	IndexTime time.Duration `json:"index_time"`
+
+	// CheckoutTime is the estimated time to check out the biggest
+	// checkout.
+	CheckoutTime time.Duration `json:"checkout_time"`
+
+	// Total is the sum of the other times.
+	Total time.Duration `json:"total"`
+}
+
+// EstimateCloneCost estimates how long it would take to clone the
+// repository over a connection with a bandwidth of
+// `bandwidthBytesPerSec`.
+//
+// This is a crude heuristic, useful for capacity planning, not a
+// guarantee. It assumes that the download is about as big as the
+// repository's packs plus its loose objects that aren't packed (which
+// is only known if `s.Storage` was filled in; otherwise, the
+// uncompressed size of the objects is used, which is a big
+// overestimate), that indexing time is proportional to the number of
+// objects, and that checkout time depends on the number and size of
+// the files in the biggest checkout. It ignores latency, server-side
+// work, and the effects of compression on the wire.
+func (s *HistorySize) EstimateCloneCost(bandwidthBytesPerSec float64) CloneEstimate {
+	var e CloneEstimate
+
+	st := s.storage()
+	if st.PackSize != 0 || st.LooseObjectSize != 0 {
+		e.DownloadSize = st.PackSize
+		e.DownloadSize.Increment(st.LooseObjectSize - st.RedundantLooseObjectSize)
+	} else {
+		e.DownloadSize = s.TotalObjectDataSize()
+	}
+
+	if bandwidthBytesPerSec > 0 {
+		e.DownloadTime = seconds(float64(e.DownloadSize) / bandwidthBytesPerSec)
+	}
+
+	objectCount := float64(s.UniqueCommitCount) + float64(s.UniqueTreeCount) +
+		float64(s.UniqueBlobCount) + float64(s.UniqueTagCount)
+	e.IndexTime = seconds(objectCount / indexObjectsPerSecond)
+
+	e.CheckoutTime = seconds(
+		float64(s.MaxExpandedBlobCount)/checkoutFilesPerSecond +
+			float64(s.MaxExpandedBlobSize)/checkoutBytesPerSecond,
+	)
+
+	e.Total = e.DownloadTime + e.IndexTime + e.CheckoutTime
+	return e
+}
+
+// seconds converts a number of seconds into a `time.Duration`.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,68 @@
+package sizes
+
+import (
+	"time"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// FutureCommitDateThreshold is how far in the future (in seconds) a
+// commit timestamp can be before it is reported as a problem. Clocks
+// disagree a bit, and time zones are easy to get wrong, so up to a day
+// is tolerated.
+const FutureCommitDateThreshold = 24 * 60 * 60
+
+// commitDateStats keeps track of the range of the commit timestamps
+// seen so far, to compute the date skew metrics of `HistorySize`.
+type commitDateStats struct {
+	// now is the current time, in seconds since the epoch. It is
+	// fixed when the scan starts, so that the results don't depend
+	// on how long the scan takes.
+	now int64
+
+	// oldest is the oldest timestamp seen.
+	oldest     int64
+	haveOldest bool
+
+	// newest is the newest timestamp seen that is not in the future.
+	newest     int64
+	haveNewest bool
+}
+
+func newCommitDateStats(now time.Time) commitDateStats {
+	return commitDateStats{now: now.Unix()}
+}
+
+// recordCommitDates records the author and committer timestamps of
+// the commit `oid` (-1 meaning unknown), updating the date skew fields
+// of `s`.
+func (s *HistorySize) recordCommitDates(
+	g *Graph, d *commitDateStats, oid git.OID, authorTime, committerTime int64,
+) {
+	for _, t := range []int64{authorTime, committerTime} {
+		if t < 0 {
+			continue
+		}
+
+		if t > d.now {
+			skew := counts.NewCount64(uint64(t - d.now))
+			if s.MaxFutureCommitDateSkew.AdjustMaxIfNecessary(skew) {
+				setPath(g.pathResolver, &s.MaxFutureCommitDateSkewCommit, oid, "commit")
+			}
+		} else if !d.haveNewest || t > d.newest {
+			d.newest = t
+			d.haveNewest = true
+		}
+
+		if !d.haveOldest || t < d.oldest {
+			d.oldest = t
+			d.haveOldest = true
+			setPath(g.pathResolver, &s.MaxPastCommitDateSkewCommit, oid, "commit")
+		}
+	}
+
+	if d.haveOldest && d.haveNewest && d.newest > d.oldest {
+		s.MaxPastCommitDateSkew = counts.NewCount64(uint64(d.newest - d.oldest))
+	}
+}
@@ -0,0 +1,140 @@
+package sizes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// ErrTreeSizeNotCached is returned (wrapped) by
+// `ComputeForCommitRange()` if a tree that it needs lies outside of
+// the range and its size is not in the cache.
+var ErrTreeSizeNotCached = errors.New("tree size not in cache")
+
+// ComputeForCommitRange returns the size of the tree of commit `to`,
+// reading only the objects that are in `from..to` (as listed by `git
+// rev-list --objects`). This is much cheaper than a full scan when,
+// for example, a CI job compares a branch before and after a merge.
+//
+// The sizes of the trees reachable from `from` must already be in
+// `treeSizes`; e.g., because it is a `FileCacheBackend` that was
+// filled by an earlier scan. The sizes of the trees in the range are
+// added to it, so that it can be used for the next range. The blobs
+// in the range are read from the range itself; the sizes of older
+// blobs that are referenced by new trees are looked up separately.
+func ComputeForCommitRange(
+	ctx context.Context, repo *git.Repository, treeSizes CacheBackend, from, to git.OID,
+) (TreeSize, error) {
+	return computeTreeSizeExcluding(ctx, repo, treeSizes, to, []git.OID{from})
+}
+
+// computeTreeSizeExcluding returns the size of the tree of commit
+// `to`, reading only the objects that are reachable from `to` but
+// not from any of `exclude`. The sizes of the trees reachable from
+// `exclude` must already be in `treeSizes`. If `exclude` is empty,
+// all of the objects reachable from `to` are read.
+func computeTreeSizeExcluding(
+	ctx context.Context, repo *git.Repository, treeSizes CacheBackend,
+	to git.OID, exclude []git.OID,
+) (TreeSize, error) {
+	toTree, err := repo.ResolveObject(to.String() + "^{tree}")
+	if err != nil {
+		return TreeSize{}, err
+	}
+	if size, ok := treeSizes.Get(toTree); ok {
+		return size, nil
+	}
+
+	g := NewGraphWithOptions(ScanOptions{NameStyle: NameStyleNone, TreeSizes: treeSizes})
+
+	var treeOIDs []git.OID
+	err = repo.WalkExclusiveObjects(
+		ctx, []git.OID{to}, exclude, git.ExclusiveObjectsOptions{},
+		func(oid git.OID, objectType git.ObjectType, size counts.Count64) error {
+			switch objectType {
+			case "blob":
+				n, _ := size.ToUint64()
+				g.RegisterBlob(oid, counts.NewCount32(n))
+			case "tree":
+				treeOIDs = append(treeOIDs, oid)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return TreeSize{}, fmt.Errorf("listing objects reachable from %s: %w", to, err)
+	}
+
+	trees := make([]*git.Tree, 0, len(treeOIDs))
+	err = readObjects(ctx, repo, treeOIDs, func(obj git.ObjectRecord) error {
+		if obj.ObjectType != "tree" {
+			return fmt.Errorf("expected tree; read %#v", obj.ObjectType)
+		}
+		tree, err := git.ParseTree(obj.OID, obj.Data)
+		if err != nil {
+			return err
+		}
+		trees = append(trees, tree)
+		return nil
+	})
+	if err != nil {
+		return TreeSize{}, err
+	}
+	if len(trees) != len(treeOIDs) {
+		return TreeSize{}, errors.New("fewer trees read than expected")
+	}
+
+	// New trees often refer to blobs that didn't change within the
+	// range, so they weren't listed above:
+	var unknown []git.OID
+	seen := make(map[git.OID]bool)
+	for _, tree := range trees {
+		iter := tree.Iter()
+		for {
+			entry, ok, err := iter.NextEntry()
+			if err != nil {
+				return TreeSize{}, err
+			}
+			if !ok {
+				break
+			}
+			// Only regular files are counted as blobs (see
+			// `treeRecord.initialize()`):
+			if entry.Filemode&0o170000 != 0o100000 {
+				continue
+			}
+			if _, ok := g.blobSizes[entry.OID]; ok || seen[entry.OID] {
+				continue
+			}
+			seen[entry.OID] = true
+			unknown = append(unknown, entry.OID)
+		}
+	}
+	blobSizes, err := repo.ObjectSizes(unknown)
+	if err != nil {
+		return TreeSize{}, fmt.Errorf("reading blob sizes: %w", err)
+	}
+	for oid, size := range blobSizes {
+		n, _ := size.ToUint64()
+		g.blobSizes[oid] = BlobSize{Size: counts.NewCount32(n)}
+	}
+
+	// `git rev-list` emits trees before their subtrees, so register
+	// them in the opposite order to keep few of them pending:
+	for i := len(trees) - 1; i >= 0; i-- {
+		if err := g.RegisterTree(treeOIDs[i], trees[i]); err != nil {
+			return TreeSize{}, err
+		}
+	}
+
+	size, ok := treeSizes.Get(toTree)
+	if !ok {
+		return TreeSize{}, fmt.Errorf(
+			"%w: some trees reachable from %v are needed for %s", ErrTreeSizeNotCached, exclude, to,
+		)
+	}
+	return size, nil
+}
@@ -0,0 +1,58 @@
+package sizes
+
+import (
+	"context"
+
+	"github.com/github/git-sizer/git"
+)
+
+// CommitVisit describes one commit visited by `Graph.WalkCommits()`.
+type CommitVisit struct {
+	OID git.OID
+
+	// Commit holds the parsed commit: its parents, tree, size, and
+	// dates.
+	Commit *git.Commit
+
+	// Size and TreeSize are the sizes that the graph has computed
+	// for the commit and its tree. They are only set if `SizeKnown`
+	// (respectively `TreeSizeKnown`) is, which is the case if the
+	// object was processed by a scan that used this graph.
+	Size          CommitSize
+	SizeKnown     bool
+	TreeSize      TreeSize
+	TreeSizeKnown bool
+}
+
+// WalkCommits calls `fn` once for each commit that is reachable from
+// `tips`, which must be commits, for custom analyses of the history.
+// The order of the visits is unspecified, except that each commit is
+// visited before its parents. If `fn` returns `git.SkipAncestors`,
+// the walk doesn't visit that commit's ancestors, even those that are
+// also reachable from other commits; if it returns any other error,
+// the walk is aborted and the error is returned.
+//
+// The sizes that `g` already knows (e.g., because it was used for a
+// scan of the same history) are included in the visits, so they don't
+// have to be recomputed.
+func (g *Graph) WalkCommits(
+	ctx context.Context, repo *git.Repository, tips []git.OID,
+	fn func(CommitVisit) error,
+) error {
+	return repo.WalkCommits(ctx, tips, func(oid git.OID, commit *git.Commit) error {
+		visit := CommitVisit{
+			OID:    oid,
+			Commit: commit,
+		}
+
+		g.commitLock.Lock()
+		visit.Size, visit.SizeKnown = g.commitSizes[oid]
+		g.commitLock.Unlock()
+
+		g.treeLock.Lock()
+		visit.TreeSize, visit.TreeSizeKnown = g.treeSizes.Get(commit.Tree)
+		g.treeLock.Unlock()
+
+		return fn(visit)
+	})
+}
@@ -0,0 +1,110 @@
+package sizes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConcernLevel is a coarse classification of an item's level of
+// concern, for renderers that want to style items by how bad they are
+// rather than by their exact values.
+type ConcernLevel int
+
+const (
+	// ConcernNone is for items whose level of concern is less than
+	// 1 (i.e., that would be shown without any stars).
+	ConcernNone ConcernLevel = iota
+
+	// ConcernLow is for items with at least 1 but fewer than 10
+	// stars.
+	ConcernLow
+
+	// ConcernHigh is for items with 10 to 30 stars.
+	ConcernHigh
+
+	// ConcernCritical is for items with more than 30 stars (shown
+	// as "!!!...") or whose values overflowed.
+	ConcernCritical
+
+	numConcernLevels = int(ConcernCritical) + 1
+)
+
+// ConcernLevelOf returns the `ConcernLevel` for an item whose level of
+// concern is `alert` (or whose value overflowed, if `overflow` is set).
+func ConcernLevelOf(alert Threshold, overflow bool) ConcernLevel {
+	switch {
+	case overflow || alert > 30:
+		return ConcernCritical
+	case alert >= 10:
+		return ConcernHigh
+	case alert >= 1:
+		return ConcernLow
+	default:
+		return ConcernNone
+	}
+}
+
+// String returns the default name of `l`.
+func (l ConcernLevel) String() string {
+	return DefaultConcernStyles.Style(l).Name
+}
+
+// ConcernStyle describes how a `ConcernLevel` is displayed.
+type ConcernStyle struct {
+	// Name is the word used for the level; e.g., "warn".
+	Name string
+
+	// Color is the ANSI escape sequence that is used to color items
+	// at this level, or "" to leave them uncolored. It is only used
+	// if the output is colored.
+	Color string
+}
+
+// ConcernStyles maps levels of concern to their styles. Levels that
+// are missing from the map use `DefaultConcernStyles`.
+type ConcernStyles map[ConcernLevel]ConcernStyle
+
+// DefaultConcernStyles are the styles that are used if no others are
+// configured.
+var DefaultConcernStyles = ConcernStyles{
+	ConcernNone:     {Name: "ok"},
+	ConcernLow:      {Name: "low", Color: ansiYellow},
+	ConcernHigh:     {Name: "high", Color: ansiRed},
+	ConcernCritical: {Name: "critical", Color: ansiRed},
+}
+
+// Style returns the style for `level`, falling back to the default.
+func (styles ConcernStyles) Style(level ConcernLevel) ConcernStyle {
+	if style, ok := styles[level]; ok {
+		return style
+	}
+	return DefaultConcernStyles[level]
+}
+
+// ParseConcernNames parses a comma-separated list of names for the
+// levels of concern, from `ConcernNone` to `ConcernCritical` (e.g.,
+// "green,yellow,red,red"), and returns the corresponding styles, with
+// the default colors.
+func ParseConcernNames(s string) (ConcernStyles, error) {
+	names := strings.Split(s, ",")
+	if len(names) != numConcernLevels {
+		return nil, fmt.Errorf(
+			"expected %d comma-separated concern level names; got %q",
+			numConcernLevels, s,
+		)
+	}
+
+	styles := make(ConcernStyles, numConcernLevels)
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty concern level name in %q", s)
+		}
+		level := ConcernLevel(i)
+		styles[level] = ConcernStyle{
+			Name:  name,
+			Color: DefaultConcernStyles[level].Color,
+		}
+	}
+	return styles, nil
+}
@@ -0,0 +1,85 @@
+package sizes
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sort"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DuplicateGroup is a set of distinct blobs whose contents are the
+// same except for their line endings; e.g., a file that was
+// committed once with CRLF and once with LF line endings. (Blobs
+// whose contents are exactly the same always have the same OID, so
+// they are a single blob.)
+type DuplicateGroup struct {
+	// SHA256 is the hex-encoded SHA-256 of the blobs' contents with
+	// their line endings normalized to LF (see
+	// `git.Repository.BlobContentHashes()`).
+	SHA256 string `json:"sha256"`
+
+	// TotalSize is the sum of the sizes of the blobs, in bytes.
+	TotalSize counts.Count64 `json:"total_size"`
+
+	// OIDs are the names of the blobs, sorted.
+	OIDs []git.OID `json:"oids"`
+}
+
+// FindContentDuplicates hashes the contents of every blob that has
+// been registered with `g`, with CRLF line endings normalized to LF,
+// and returns the groups of blobs that have the same normalized
+// contents, ordered by that hash. Since this has to read every blob,
+// it is expensive and not part of the normal scan.
+func (g *Graph) FindContentDuplicates(
+	ctx context.Context, repo *git.Repository,
+) ([]DuplicateGroup, error) {
+	g.blobLock.Lock()
+	blobSizes := make(map[git.OID]BlobSize, len(g.blobSizes))
+	oids := make([]git.OID, 0, len(g.blobSizes))
+	for oid, size := range g.blobSizes {
+		blobSizes[oid] = size
+		oids = append(oids, oid)
+	}
+	g.blobLock.Unlock()
+
+	sortOIDs(oids)
+
+	byHash := make(map[[32]byte][]git.OID)
+	err := repo.BlobContentHashes(ctx, oids, true, func(oid git.OID, sum [32]byte) error {
+		byHash[sum] = append(byHash[sum], oid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []DuplicateGroup
+	for sum, oids := range byHash {
+		if len(oids) < 2 {
+			continue
+		}
+		group := DuplicateGroup{
+			SHA256: hex.EncodeToString(sum[:]),
+			OIDs:   oids,
+		}
+		for _, oid := range oids {
+			group.TotalSize.Increment(counts.Count64(blobSizes[oid].Size))
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].SHA256 < groups[j].SHA256
+	})
+
+	return groups, nil
+}
+
+// sortOIDs sorts `oids` in place by their binary values.
+func sortOIDs(oids []git.OID) {
+	sort.Slice(oids, func(i, j int) bool {
+		return bytes.Compare(oids[i].Bytes(), oids[j].Bytes()) < 0
+	})
+}
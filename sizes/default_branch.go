@@ -0,0 +1,114 @@
+package sizes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DefaultBranchShare compares the blob data that is reachable from the
+// default branch with the blob data that is reachable from all of the
+// references that were scanned plus the default branch; i.e., it tells
+// how much smaller the repository would be if only the default branch
+// were kept. The default branch is always part of the total, even if
+// the reference selection excluded it, so that the share is never
+// computed against a total that doesn't contain it.
+type DefaultBranchShare struct {
+	// DefaultBranch is the name of the branch that was compared, or
+	// "" if the comparison wasn't requested.
+	DefaultBranch string `json:"default_branch,omitempty"`
+
+	// DefaultBranchBlobSize is the total size of the distinct blobs
+	// that are reachable from the default branch.
+	DefaultBranchBlobSize counts.Count64 `json:"default_branch_blob_size"`
+
+	// AllRefsBlobSize is the total size of the distinct blobs that
+	// are reachable from the default branch or from any of the
+	// scanned references.
+	AllRefsBlobSize counts.Count64 `json:"all_refs_blob_size"`
+
+	// OtherRefsBlobSize is the total size of the distinct blobs
+	// that are reachable from the other references but not from
+	// the default branch.
+	OtherRefsBlobSize counts.Count64 `json:"other_refs_blob_size"`
+
+	// OtherRefsBlobPercent is `OtherRefsBlobSize` as a percentage of
+	// `AllRefsBlobSize`, rounded to the nearest integer.
+	OtherRefsBlobPercent counts.Count32 `json:"other_refs_blob_percent"`
+}
+
+// ScanDefaultBranchShare measures how much of the blob data that is
+// reachable from `roots` or from `defaultBranch` is reachable only
+// from `roots`. If
+// `defaultBranch` is "HEAD", the branch that `HEAD` points at is used.
+// An abbreviated reference name (e.g., "main") is expanded the way git
+// would (see `git.Repository.ExpandRef()`).
+func ScanDefaultBranchShare(
+	ctx context.Context, repo *git.Repository, defaultBranch string, roots []Root,
+) (DefaultBranchShare, error) {
+	if defaultBranch == "HEAD" {
+		target, ok, err := repo.SymbolicRef("HEAD")
+		if err != nil {
+			return DefaultBranchShare{}, err
+		}
+		if !ok {
+			return DefaultBranchShare{}, fmt.Errorf(
+				"HEAD is detached; please specify the default branch explicitly",
+			)
+		}
+		defaultBranch = target
+	} else if refname, err := repo.ExpandRef(defaultBranch); err == nil {
+		// Report abbreviated names like "main" by their full names.
+		defaultBranch = refname
+	} else if errors.Is(err, git.ErrAmbiguousRef) {
+		return DefaultBranchShare{}, fmt.Errorf("default branch: %w", err)
+	}
+
+	tip, err := repo.ResolveObject(defaultBranch)
+	if err != nil {
+		return DefaultBranchShare{}, fmt.Errorf(
+			"resolving default branch %q: %w", defaultBranch, err,
+		)
+	}
+
+	var others []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			others = append(others, root.OID())
+		}
+	}
+
+	share := DefaultBranchShare{DefaultBranch: defaultBranch}
+
+	// Every blob that is reachable from any reference is counted
+	// exactly once, either here...
+	_, share.DefaultBranchBlobSize, err = repo.ReachableBlobsSize(
+		ctx, []git.OID{tip}, nil,
+	)
+	if err != nil {
+		return DefaultBranchShare{}, err
+	}
+
+	// ...or here:
+	_, share.OtherRefsBlobSize, err = repo.ReachableBlobsSize(
+		ctx, others, []git.OID{tip},
+	)
+	if err != nil {
+		return DefaultBranchShare{}, err
+	}
+
+	share.AllRefsBlobSize = share.DefaultBranchBlobSize
+	share.AllRefsBlobSize.Increment(share.OtherRefsBlobSize)
+
+	if share.AllRefsBlobSize != 0 {
+		share.OtherRefsBlobPercent = counts.NewCount32(
+			(100*uint64(share.OtherRefsBlobSize) + uint64(share.AllRefsBlobSize)/2) /
+				uint64(share.AllRefsBlobSize),
+		)
+	}
+
+	return share, nil
+}
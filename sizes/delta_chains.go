@@ -0,0 +1,85 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// DeltaChainStats summarizes how deeply the objects in a repository
+// are deltified.
+type DeltaChainStats struct {
+	// DeltaObjectCount is the number of objects that are stored as
+	// deltas.
+	DeltaObjectCount counts.Count32 `json:"delta_object_count"`
+
+	// MaxDeltaChainLength is the length of the longest delta chain;
+	// i.e., the most deltas that have to be applied to reconstruct
+	// a single object. Git's default maximum is 50; much longer
+	// chains mean that the repository was repacked with a high
+	// `--depth`, which makes reading objects slow.
+	MaxDeltaChainLength counts.Count32 `json:"max_delta_chain_length"`
+
+	// Longest holds the objects with the longest chains, longest
+	// first.
+	Longest []git.DeltaChain `json:"longest,omitempty"`
+
+	// Chunks, if set, describes the files that `Longest` was
+	// written to instead (see `WriteChunkFiles()`).
+	Chunks []Chunk `json:"chunks,omitempty"`
+}
+
+// ScanDeltaChains computes `DeltaChainStats` for all of the objects in
+// `repo`, keeping the `topN` objects with the longest chains.
+func ScanDeltaChains(ctx context.Context, repo *git.Repository, topN int) (DeltaChainStats, error) {
+	var stats DeltaChainStats
+
+	// Among chains of the same length, the one with the lower OID
+	// ranks higher, since they are visited in OID order:
+	top := topn.New(topN, func(c1, c2 git.DeltaChain) bool {
+		return c1.Length < c2.Length
+	})
+	err := repo.WalkDeltaChains(ctx, func(c git.DeltaChain) error {
+		stats.DeltaObjectCount.Increment(1)
+		stats.MaxDeltaChainLength.AdjustMaxIfNecessary(counts.NewCount32(uint64(c.Length)))
+		top.Add(c)
+		return nil
+	})
+	if err != nil {
+		return DeltaChainStats{}, fmt.Errorf("reading delta chains: %w", err)
+	}
+	stats.Longest = top.Items()
+
+	return stats, nil
+}
+
+// WriteDeltaChains writes a human-readable description of `stats` to
+// `w`.
+func WriteDeltaChains(w io.Writer, stats DeltaChainStats) error {
+	if _, err := fmt.Fprintf(
+		w, "%d objects are stored as deltas; the longest delta chain has length %d\n",
+		stats.DeltaObjectCount, stats.MaxDeltaChainLength,
+	); err != nil {
+		return err
+	}
+	for _, c := range stats.Longest {
+		if _, err := fmt.Fprintf(
+			w, "    %s %-6s %6d (base %s)\n", c.OID, c.ObjectType, c.Length, c.Base,
+		); err != nil {
+			return err
+		}
+	}
+	for _, c := range stats.Chunks {
+		if _, err := fmt.Fprintf(
+			w, "    %d objects from #%d in %s (sha256 %s)\n",
+			c.RecordCount, c.First+1, c.Name, c.SHA256,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DirectFilesChanged returns the number of blobs (including symlinks)
+// that differ between the trees `oldTree` and `newTree`, regardless
+// of how much their contents changed. This is the "how many files
+// does this change touch" number that is useful, for example, for
+// flagging overly large changes in CI. No blob contents are read.
+func DirectFilesChanged(repo *git.Repository, oldTree, newTree git.OID) (counts.Count32, error) {
+	entries, err := repo.DiffTree(oldTree, newTree)
+	if err != nil {
+		return 0, err
+	}
+
+	var count counts.Count32
+	for _, entry := range entries {
+		if entry.IsBlob() {
+			count.Increment(1)
+		}
+	}
+	return count, nil
+}
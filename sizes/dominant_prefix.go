@@ -0,0 +1,236 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+const (
+	// DefaultPrefixCoverage is the default for
+	// `DominantPrefixOptions.Coverage`.
+	DefaultPrefixCoverage = 0.8
+
+	// DefaultMaxPrefixSiblings is the default for
+	// `DominantPrefixOptions.MaxSiblings`.
+	DefaultMaxPrefixSiblings = 5
+)
+
+// DominantPrefixOptions controls `FindDominantPrefix()`.
+type DominantPrefixOptions struct {
+	// Coverage is the fraction (between 0 and 1) of the total blob
+	// size that the prefix has to cover. If it is zero,
+	// `DefaultPrefixCoverage` is used.
+	Coverage float64
+
+	// MaxSiblings is the maximum number of siblings to report if
+	// the size is split among several directories. If it is zero,
+	// `DefaultMaxPrefixSiblings` is used.
+	MaxSiblings int
+}
+
+// PrefixShare is the share of a tree's blob data that is under a
+// directory.
+type PrefixShare struct {
+	// Path is the path of the directory, without a trailing slash,
+	// or "" for the root tree.
+	Path string `json:"path"`
+
+	// Size is the total size of the blobs under the directory.
+	Size counts.Count64 `json:"size"`
+
+	// Percent is `Size` as a percentage of the total.
+	Percent float64 `json:"percent"`
+}
+
+// DominantPrefix is the result of `FindDominantPrefix()`.
+type DominantPrefix struct {
+	// PrefixShare describes the deepest directory that covers the
+	// requested share of the data. It is the root tree if no
+	// subdirectory does.
+	PrefixShare
+
+	// TotalSize is the total size of the blobs in the tree.
+	TotalSize counts.Count64 `json:"total_size"`
+
+	// Split is set if no single subdirectory of the prefix covers the
+	// requested share, but a few of them do together (or if several
+	// tie for it). In that case, `Siblings` holds those
+	// subdirectories, biggest first.
+	Split    bool          `json:"split"`
+	Siblings []PrefixShare `json:"siblings,omitempty"`
+}
+
+// FindDominantPrefix finds the deepest directory in the tree of
+// `treeish` (e.g., a branch name) under which at least
+// `opts.Coverage` of the tree's blob data lives. It descends from the
+// root tree as long as a single subdirectory covers the requested
+// share, using the tree sizes from a scan of the tree. Sizes are
+// counted as in a checkout: a blob that appears at several paths is
+// counted at each of them.
+func FindDominantPrefix(
+	ctx context.Context, repo *git.Repository, treeish string, opts DominantPrefixOptions,
+) (DominantPrefix, error) {
+	coverage := opts.Coverage
+	if coverage == 0 {
+		coverage = DefaultPrefixCoverage
+	}
+	if coverage < 0 || coverage > 1 {
+		return DominantPrefix{}, fmt.Errorf("coverage %g is not between 0 and 1", coverage)
+	}
+	maxSiblings := opts.MaxSiblings
+	if maxSiblings == 0 {
+		maxSiblings = DefaultMaxPrefixSiblings
+	}
+
+	oid, treeSizes, err := scanTreeSizes(ctx, repo, treeish)
+	if err != nil {
+		return DominantPrefix{}, err
+	}
+	rootSize, ok := treeSizes.Get(oid)
+	if !ok {
+		return DominantPrefix{}, fmt.Errorf("size of tree %s is not known", oid)
+	}
+
+	total := rootSize.ExpandedBlobSize
+	share := func(path string, size counts.Count64) PrefixShare {
+		s := PrefixShare{Path: path, Size: size}
+		if total != 0 {
+			s.Percent = 100 * float64(size) / float64(total)
+		}
+		return s
+	}
+	needed := coverage * float64(total)
+
+	prefix := DominantPrefix{
+		PrefixShare: share("", total),
+		TotalSize:   total,
+	}
+	if total == 0 {
+		return prefix, nil
+	}
+
+	var children []prefixChild
+	for {
+		children, err = childTrees(ctx, repo, treeSizes, prefix.Path, oid)
+		if err != nil {
+			return DominantPrefix{}, err
+		}
+		if len(children) == 0 || float64(children[0].Size) < needed {
+			break
+		}
+		if len(children) > 1 && children[1].Size == children[0].Size {
+			// Two subdirectories tie, and each of them covers
+			// enough; there's no reason to prefer either one.
+			break
+		}
+		prefix.PrefixShare = share(children[0].Path, children[0].Size)
+		oid = children[0].oid
+	}
+
+	// See whether a few of the biggest subdirectories together cover
+	// the requested share. Subdirectories that tie with the last one
+	// that is needed are included, too:
+	var siblings []PrefixShare
+	var sum counts.Count64
+	for _, child := range children {
+		if len(siblings) == maxSiblings {
+			break
+		}
+		if float64(sum) >= needed && child.Size != siblings[len(siblings)-1].Size {
+			break
+		}
+		siblings = append(siblings, share(child.Path, child.Size))
+		sum.Increment(child.Size)
+	}
+	if len(siblings) > 1 && float64(sum) >= needed {
+		prefix.Split = true
+		prefix.Siblings = siblings
+	}
+
+	return prefix, nil
+}
+
+// prefixChild is a subdirectory that `FindDominantPrefix()` might
+// descend into.
+type prefixChild struct {
+	PrefixShare
+	oid git.OID
+}
+
+// childTrees returns the subdirectories of the tree `oid`, whose path
+// is `path`, biggest first (and alphabetically among those with the
+// same size).
+func childTrees(
+	ctx context.Context, repo *git.Repository, treeSizes CacheBackend,
+	path string, oid git.OID,
+) ([]prefixChild, error) {
+	trees, err := readTrees(ctx, repo, []git.OID{oid})
+	if err != nil {
+		return nil, err
+	}
+
+	var children []prefixChild
+	for _, entry := range trees[oid] {
+		if entry.Filemode&0o170000 != 0o40000 {
+			continue
+		}
+		size, ok := treeSizes.Get(entry.OID)
+		if !ok {
+			return nil, fmt.Errorf("size of tree %s is not known", entry.OID)
+		}
+		childPath := entry.Name
+		if path != "" {
+			childPath = path + "/" + entry.Name
+		}
+		children = append(children, prefixChild{
+			PrefixShare: PrefixShare{Path: childPath, Size: size.ExpandedBlobSize},
+			oid:         entry.OID,
+		})
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Size != children[j].Size {
+			return children[i].Size > children[j].Size
+		}
+		return children[i].Path < children[j].Path
+	})
+
+	return children, nil
+}
+
+// WriteDominantPrefix writes a human-readable description of `p` to
+// `w`.
+func WriteDominantPrefix(w io.Writer, p DominantPrefix) error {
+	format := func(s PrefixShare) string {
+		path := s.Path + "/"
+		if s.Path == "" {
+			path = "(root)"
+		}
+		value, unit := counts.Binary.Format(s.Size, "B")
+		return fmt.Sprintf("%s: %s %s (%.1f%%)", path, value, unit, s.Percent)
+	}
+
+	value, unit := counts.Binary.Format(p.TotalSize, "B")
+	if _, err := fmt.Fprintf(w, "Total blob size: %s %s\n", value, unit); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Dominant prefix: %s\n", format(p.PrefixShare)); err != nil {
+		return err
+	}
+	if p.Split {
+		if _, err := fmt.Fprintln(w, "Split among:"); err != nil {
+			return err
+		}
+		for _, s := range p.Siblings {
+			if _, err := fmt.Fprintf(w, "    %s\n", format(s)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
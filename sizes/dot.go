@@ -0,0 +1,187 @@
+package sizes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/github/git-sizer/git"
+)
+
+// DumpDOT writes the trees under `root` to `w` as a Graphviz DOT
+// graph, to help debug unexpected size results. Each node is a tree,
+// labeled with its abbreviated OID and its number of entries. Each
+// edge is labeled with the name under which the parent refers to the
+// child. The nodes are shaded from white to red according to their
+// `ExpandedBlobSize` (as recorded in `treeSizes`) relative to that of
+// `root`; trees whose sizes aren't known are left white.
+//
+// Only trees up to `maxDepth` levels below `root` are included.
+// Identical subtrees appear only once, so a tree that is referred to
+// from several places has several incoming edges.
+func DumpDOT(
+	ctx context.Context, repo *git.Repository, treeSizes CacheBackend,
+	w io.Writer, root git.OID, maxDepth int,
+) error {
+	out := bufio.NewWriter(w)
+
+	var rootSize float64
+	if ts, ok := treeSizes.Get(root); ok {
+		rootSize = float64(ts.ExpandedBlobSize)
+	}
+
+	fmt.Fprintln(out, "digraph trees {")
+	fmt.Fprintln(out, "\tnode [shape=box, style=filled];")
+
+	seen := map[git.OID]bool{root: true}
+	level := []git.OID{root}
+	for depth := 0; len(level) != 0; depth++ {
+		trees, err := readTrees(ctx, repo, level)
+		if err != nil {
+			return err
+		}
+
+		var next []git.OID
+		for _, oid := range level {
+			entries := trees[oid]
+
+			color := "#ffffff"
+			if ts, ok := treeSizes.Get(oid); ok && rootSize > 0 {
+				// Fade the green and blue components as the
+				// size approaches that of the root:
+				c := 255 - int(255*float64(ts.ExpandedBlobSize)/rootSize)
+				if c < 0 {
+					c = 0
+				}
+				color = fmt.Sprintf("#ff%02x%02x", c, c)
+			}
+			fmt.Fprintf(
+				out, "\t%s [label=\"%s\\n%d entries\", fillcolor=\"%s\"];\n",
+				dotNodeID(oid), oid.String()[:8], len(entries), color,
+			)
+
+			if depth == maxDepth {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.Filemode&0o170000 != 0o40000 {
+					continue
+				}
+				fmt.Fprintf(
+					out, "\t%s -> %s [label=%s];\n",
+					dotNodeID(oid), dotNodeID(entry.OID), dotQuote(entry.Name),
+				)
+				if !seen[entry.OID] {
+					seen[entry.OID] = true
+					next = append(next, entry.OID)
+				}
+			}
+		}
+		level = next
+	}
+
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// readTrees reads the trees `oids` from `repo` and returns their
+// entries.
+func readTrees(
+	ctx context.Context, repo *git.Repository, oids []git.OID,
+) (map[git.OID][]git.TreeEntry, error) {
+	trees := make(map[git.OID][]git.TreeEntry, len(oids))
+	err := readObjects(ctx, repo, oids, func(obj git.ObjectRecord) error {
+		if obj.ObjectType != "tree" {
+			return fmt.Errorf("expected tree; read %#v", obj.ObjectType)
+		}
+		tree, err := git.ParseTree(obj.OID, obj.Data)
+		if err != nil {
+			return err
+		}
+
+		var entries []git.TreeEntry
+		iter := tree.Iter()
+		for {
+			entry, ok, err := iter.NextEntry()
+			if err != nil {
+				return fmt.Errorf("parsing tree %s: %w", obj.OID, err)
+			}
+			if !ok {
+				break
+			}
+			entries = append(entries, entry)
+		}
+		trees[obj.OID] = entries
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(trees) != len(oids) {
+		return nil, errors.New("fewer trees read than expected")
+	}
+
+	return trees, nil
+}
+
+// readObjects reads the objects `oids` from `repo` and calls `fn` for
+// each of them, in order. If `fn` returns an error, reading is aborted
+// and that error is returned.
+func readObjects(
+	ctx context.Context, repo *git.Repository, oids []git.OID,
+	fn func(git.ObjectRecord) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objectIter, err := repo.NewBatchObjectIter(ctx)
+	if err != nil {
+		return err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		defer objectIter.Close()
+
+		errChan <- func() error {
+			for _, oid := range oids {
+				if err := objectIter.RequestObject(oid); err != nil {
+					return fmt.Errorf("requesting object '%s': %w", oid, err)
+				}
+			}
+			return nil
+		}()
+	}()
+
+	for {
+		obj, ok, err := objectIter.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := fn(obj); err != nil {
+			return err
+		}
+	}
+
+	return <-errChan
+}
+
+// dotNodeID returns the DOT identifier of the node for tree `oid`.
+func dotNodeID(oid git.OID) string {
+	return "\"" + oid.String() + "\""
+}
+
+// dotQuote returns `s` as a quoted DOT string.
+func dotQuote(s string) string {
+	return "\"" + strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+		"\n", "\\n",
+	).Replace(s) + "\""
+}
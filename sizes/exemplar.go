@@ -0,0 +1,74 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// The names of the `TreeSize` maxima whose exemplars are tracked (see
+// `ExemplarMap`).
+const (
+	// ExemplarMaxPathDepth is the object at the end of the deepest
+	// path.
+	ExemplarMaxPathDepth = "max_path_depth"
+
+	// ExemplarMaxPathLength is the object at the end of the longest
+	// path.
+	ExemplarMaxPathLength = "max_path_length"
+
+	// ExemplarMaxTreeSerializedSize is the largest tree object.
+	ExemplarMaxTreeSerializedSize = "max_tree_serialized_size"
+)
+
+// ExemplarMap remembers, for each of several maxima, which object
+// achieved it; e.g., which blob is at the end of a tree's deepest
+// path. The zero value doesn't track anything, so that `TreeSize`s
+// (which embed an `ExemplarMap`) don't pay for it unless
+// `ScanOptions.TrackExemplars` is set.
+//
+// Exemplars are not stored in persistent tree-size caches. If a
+// subtree's size comes from such a cache, its own OID stands in for
+// the object within it.
+type ExemplarMap struct {
+	exemplars map[string]git.OID
+}
+
+// NewExemplarMap returns an `ExemplarMap` that tracks exemplars.
+func NewExemplarMap() ExemplarMap {
+	return ExemplarMap{exemplars: make(map[string]git.OID)}
+}
+
+// Record records `oid` as the exemplar of `metric` if `value` is
+// greater than `currentMax`, the metric's maximum so far. It must be
+// called before the maximum is adjusted. It does nothing if `m`
+// isn't tracking exemplars.
+func (m *ExemplarMap) Record(metric string, oid git.OID, value, currentMax counts.Count64) {
+	if m.exemplars == nil || value <= currentMax {
+		return
+	}
+	m.exemplars[metric] = oid
+}
+
+// Exemplar returns the object that achieved the maximum `metric`, if
+// it is known.
+func (m ExemplarMap) Exemplar(metric string) (git.OID, bool) {
+	oid, ok := m.exemplars[metric]
+	return oid, ok
+}
+
+// exemplarOr returns the exemplar of `metric`, or `oid` if it isn't
+// known.
+func (m ExemplarMap) exemplarOr(metric string, oid git.OID) git.OID {
+	if exemplar, ok := m.exemplars[metric]; ok {
+		return exemplar
+	}
+	return oid
+}
+
+// adjustMax raises `*max`, which must be one of the fields of `s`, to
+// `value` if necessary, recording `oid` as the exemplar of `metric`
+// if it does.
+func (s *TreeSize) adjustMax(metric string, max *counts.Count32, value counts.Count32, oid git.OID) {
+	s.Record(metric, oid, counts.Count64(value), counts.Count64(*max))
+	max.AdjustMaxIfNecessary(value)
+}
@@ -0,0 +1,78 @@
+package sizes
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// ExtStats holds statistics about the distinct blobs whose filenames
+// have a particular extension. Each blob is attributed to the
+// extension of the first filename under which it is encountered.
+type ExtStats struct {
+	// The number of distinct blobs with this extension.
+	BlobCount counts.Count32 `json:"blob_count"`
+
+	// The total size of the distinct blobs with this extension.
+	BlobSize counts.Count64 `json:"blob_size"`
+
+	// The size of the largest blob with this extension.
+	MaxBlobSize counts.Count32 `json:"max_blob_size"`
+
+	// The OID of the largest blob with this extension. If there are
+	// several blobs with the maximum size, this is the one with the
+	// lowest OID.
+	MaxBlobOID git.OID `json:"max_blob_oid"`
+
+	// A path to the largest blob with this extension.
+	MaxBlob *Path `json:"max_blob,omitempty"`
+}
+
+// FileExtension returns the extension of `filename` (including the
+// leading "."), folded to lower case, or "" if it has no extension.
+func FileExtension(filename string) string {
+	return strings.ToLower(path.Ext(filename))
+}
+
+// oidLess returns true iff `oid1` sorts before `oid2`.
+func oidLess(oid1, oid2 git.OID) bool {
+	return bytes.Compare(oid1.Bytes(), oid2.Bytes()) < 0
+}
+
+// recordBlobExtension records that the blob `oid` with size `size`
+// was found under filename `name`. This must be called at most once
+// per blob, and before the tree entry is reported to the
+// `PathResolver`, so that the path to the largest blob is resolved
+// via this occurrence.
+func (s *HistorySize) recordBlobExtension(
+	g *Graph, oid git.OID, name string, size BlobSize,
+) {
+	ext := FileExtension(name)
+	es, ok := s.Extensions[ext]
+	if !ok {
+		es = &ExtStats{}
+		s.Extensions[ext] = es
+		s.DistinctExtensions.Increment(1)
+	}
+
+	es.BlobCount.Increment(1)
+	s.extensionBlobCount.Increment(1)
+	if dominant, ok := s.Extensions[s.DominantExtension]; !ok ||
+		es.BlobCount > dominant.BlobCount ||
+		(es.BlobCount == dominant.BlobCount && ext < s.DominantExtension) {
+		s.DominantExtension = ext
+	}
+	s.DominantExtensionShare = float64(s.Extensions[s.DominantExtension].BlobCount) /
+		float64(s.extensionBlobCount)
+	es.BlobSize.Increment(counts.Count64(size.Size))
+	if es.BlobCount == 1 ||
+		size.Size > es.MaxBlobSize ||
+		(size.Size == es.MaxBlobSize && oidLess(oid, es.MaxBlobOID)) {
+		es.MaxBlobSize = size.Size
+		es.MaxBlobOID = oid
+		setPath(g.pathResolver, &es.MaxBlob, oid, "blob")
+	}
+}
@@ -0,0 +1,136 @@
+package sizes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// FileAges holds the results of `FileAgesInHistory()`.
+type FileAges struct {
+	// MaxAge is the age, in days, of the oldest blob.
+	MaxAge counts.Count64
+
+	// MaxAgeBlob is the oldest blob, with its path in the commit
+	// that introduced it.
+	MaxAgeBlob *Path
+
+	// MedianAge is the median age, in days, of all of the blobs
+	// that were seen.
+	MedianAge counts.Count64
+}
+
+// firstSighting is the earliest commit that introduced a blob.
+type firstSighting struct {
+	time   int64
+	commit git.OID
+	path   string
+}
+
+// FileAgesInHistory computes how long ago each blob that is reachable
+// from the walked `roots` was introduced (i.e., the time from the
+// committer date of the earliest commit that introduced it until
+// `now`), and returns the maximum and median of those ages, in days.
+// A blob that was never modified after it was introduced has the age
+// of the file itself, so very old blobs may be forgotten artifacts.
+//
+// The introducing commits are found in a single pass over the history
+// (see `git.Repository.WalkBlobIntroductions()`), so the cost is that
+// of diffing each commit against its parents, not of listing the tree
+// of every commit.
+func FileAgesInHistory(
+	ctx context.Context, repo *git.Repository, roots []Root, now time.Time,
+) (FileAges, error) {
+	var names []string
+	for _, root := range roots {
+		if root.Walk() {
+			names = append(names, root.OID().String())
+		}
+	}
+	peeled, err := repo.PeeledCommits(names)
+	if err != nil {
+		return FileAges{}, err
+	}
+	tips := make([]git.OID, 0, len(peeled))
+	for _, name := range names {
+		if oid, ok := peeled[name]; ok {
+			tips = append(tips, oid)
+		}
+	}
+
+	firstSeen := make(map[git.OID]firstSighting)
+	err = repo.WalkBlobIntroductions(ctx, tips, func(intro git.BlobIntroduction) error {
+		// Break ties by commit and path, so that the result is
+		// deterministic:
+		if old, ok := firstSeen[intro.OID]; ok {
+			switch {
+			case old.time != intro.Time:
+				if old.time < intro.Time {
+					return nil
+				}
+			case old.commit != intro.Commit:
+				if old.commit.String() < intro.Commit.String() {
+					return nil
+				}
+			case old.path <= intro.Path:
+				return nil
+			}
+		}
+		firstSeen[intro.OID] = firstSighting{
+			time: intro.Time, commit: intro.Commit, path: intro.Path,
+		}
+		return nil
+	})
+	if err != nil {
+		return FileAges{}, err
+	}
+
+	if len(firstSeen) == 0 {
+		return FileAges{}, nil
+	}
+
+	ages := make([]uint64, 0, len(firstSeen))
+	var result FileAges
+	var maxAge uint64
+	var oldest git.OID
+	for oid, sighting := range firstSeen {
+		age := ageInDays(now, sighting.time)
+		ages = append(ages, age)
+		// Break ties by OID, so that the result is deterministic:
+		if result.MaxAgeBlob == nil || age > maxAge ||
+			age == maxAge && oid.String() < oldest.String() {
+			maxAge = age
+			result.MaxAge = counts.NewCount64(age)
+			result.MaxAgeBlob = &Path{
+				OID:          oid,
+				objectType:   "blob",
+				parent:       &Path{OID: sighting.commit, objectType: "commit"},
+				relativePath: sighting.path,
+			}
+			oldest = oid
+		}
+	}
+
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	n := len(ages)
+	if n%2 == 1 {
+		result.MedianAge = counts.NewCount64(ages[n/2])
+	} else {
+		result.MedianAge = counts.NewCount64((ages[n/2-1] + ages[n/2]) / 2)
+	}
+
+	return result, nil
+}
+
+// ageInDays returns the number of whole days from `t` (in seconds
+// since the epoch) until `now`, or zero if `t` is in the future.
+func ageInDays(now time.Time, t int64) uint64 {
+	seconds := now.Unix() - t
+	if seconds <= 0 {
+		return 0
+	}
+	return uint64(seconds / (24 * 60 * 60))
+}
@@ -0,0 +1,162 @@
+package sizes
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// MetricValue is the value of a single metric in the flat
+// representation returned by `Flatten()`.
+type MetricValue struct {
+	// Value is the raw value of the metric. If the value
+	// overflowed, it is the maximum value of its type, and
+	// `Overflow` is set.
+	Value    uint64 `json:"value"`
+	Overflow bool   `json:"overflow,omitempty"`
+
+	// Humanized is the value formatted for humans, including its
+	// unit; e.g., "1.23 MiB".
+	Humanized string `json:"humanized"`
+
+	// Unit is the unit of the value (e.g., "B"), or "" if it is a
+	// plain count.
+	Unit string `json:"unit"`
+
+	// LevelOfConcern is the value divided by the metric's reference
+	// value, i.e., the number of stars that the table would show. It
+	// is zero for informational metrics, which have no reference
+	// value.
+	LevelOfConcern float64 `json:"levelOfConcern"`
+}
+
+// Flatten returns every metric in `s` as a map from a JSON Pointer
+// (RFC 6901) to its value, so that consumers can look up a single
+// metric without knowing about the structure of the report. The keys
+// are
+//
+//	/<symbol>                       a metric from the table, using the
+//	                                same symbols as `--json-version=2`
+//	/refgroup/<group>               the reference count of a group
+//	/extension/<ext>/blobCount      statistics about the blobs with a
+//	/extension/<ext>/blobSize       filename extension (`<ext>` includes
+//	/extension/<ext>/maxBlobSize    the "."; it is empty for files
+//	                                without an extension)
+//
+// with "~" and "/" within a component escaped as "~0" and "~1". New
+// metrics add new keys, but existing keys don't change.
+func (s *HistorySize) Flatten(refGroups []RefGroup) map[string]MetricValue {
+	items := s.metricRegistry(refGroups)
+
+	flat := make(map[string]MetricValue, len(items)+3*len(s.Extensions))
+	for symbol, i := range items {
+		var key string
+		if group := strings.TrimPrefix(symbol, "refgroup."); group != symbol {
+			key = jsonPointer("refgroup", group)
+		} else {
+			key = jsonPointer(symbol)
+		}
+		flat[key] = i.metricValue()
+	}
+
+	for ext, i := range s.extensionItems() {
+		flat[jsonPointer("extension", ext.ext, ext.stat)] = i.metricValue()
+	}
+
+	return flat
+}
+
+// SaturatedMetric is a metric whose counter hit its maximum value, so
+// that the true value is at least as big as the one reported (which is
+// shown with a ">=" prefix in tables).
+type SaturatedMetric struct {
+	// Key is the metric's key in the output of `Flatten()`.
+	Key string `json:"key"`
+
+	// Value is the maximum value of the metric's counter.
+	Value uint64 `json:"value"`
+}
+
+// SaturatedMetrics returns the metrics in `s` whose values saturated,
+// sorted by key. Counters saturate rather than wrapping around, so
+// once a value has hit the maximum, it stays there.
+func (s *HistorySize) SaturatedMetrics(refGroups []RefGroup) []SaturatedMetric {
+	var saturated []SaturatedMetric
+	for key, value := range s.Flatten(refGroups) {
+		if value.Overflow {
+			saturated = append(saturated, SaturatedMetric{Key: key, Value: value.Value})
+		}
+	}
+	sort.Slice(saturated, func(i, j int) bool {
+		return saturated[i].Key < saturated[j].Key
+	})
+	return saturated
+}
+
+// extensionStat identifies one of the per-extension statistics.
+type extensionStat struct {
+	ext  string
+	stat string
+}
+
+// extensionItems returns items for the per-extension statistics.
+func (s *HistorySize) extensionItems() map[extensionStat]*item {
+	I := newItem
+	metric := counts.Metric
+	binary := counts.Binary
+
+	items := make(map[extensionStat]*item, 3*len(s.Extensions))
+	for ext, es := range s.Extensions {
+		items[extensionStat{ext, "blobCount"}] = I(
+			"extensionBlobCount", "Count",
+			"The number of distinct blobs with this extension",
+			nil, es.BlobCount, metric, "", 1.5e6,
+		)
+		items[extensionStat{ext, "blobSize"}] = I(
+			"extensionBlobSize", "Total size",
+			"The total size of the distinct blobs with this extension",
+			nil, es.BlobSize, binary, "B", 10e9,
+		)
+		items[extensionStat{ext, "maxBlobSize"}] = I(
+			"extensionMaxBlobSize", "Maximum size",
+			"The size of the largest blob with this extension",
+			es.MaxBlob, es.MaxBlobSize, binary, "B", 10e6,
+		)
+	}
+	return items
+}
+
+func (i *item) metricValue() MetricValue {
+	value, overflow := i.value.ToUint64()
+	valueString, unitString := i.humaner.Format(i.value, i.unit)
+	return MetricValue{
+		Value:          value,
+		Overflow:       overflow,
+		Humanized:      strings.TrimSpace(valueString + " " + unitString),
+		Unit:           i.unit,
+		LevelOfConcern: i.concernRatio(value),
+	}
+}
+
+// jsonPointer joins `components` into a JSON Pointer, escaping them as
+// required by RFC 6901.
+func jsonPointer(components ...string) string {
+	var sb strings.Builder
+	for _, c := range components {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(c, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// EncodeFlatJSON writes the metrics returned by `s.Flatten(refGroups)`
+// to `w` as a JSON object whose keys are sorted.
+func EncodeFlatJSON(w io.Writer, s *HistorySize, refGroups []RefGroup) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	// `encoding/json` always sorts the keys of maps:
+	return enc.Encode(s.Flatten(refGroups))
+}
@@ -0,0 +1,55 @@
+package sizes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/github/git-sizer/git"
+)
+
+// WriteFoldedStacks writes one line to `w` for each blob reachable
+// from the roots in `roots` that should be walked, in the "folded
+// stacks" format understood by flame graph tools like
+// `flamegraph.pl`:
+//
+//     dir;subdir;file.txt 1234
+//
+// i.e., the blob's path with the components separated by `;`,
+// followed by its size in bytes. Each blob is listed once, under the
+// first path at which it was found, so the sizes add up to the total
+// size of the distinct blobs. The lines are written as they are
+// found, so memory use doesn't grow with the size of the repository.
+func WriteFoldedStacks(
+	ctx context.Context, repo *git.Repository, roots []Root, w io.Writer,
+) error {
+	var oids []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			oids = append(oids, root.OID())
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	err := repo.WalkBlobPaths(ctx, oids, func(blob git.BlobPath) error {
+		_, err := fmt.Fprintf(out, "%s %d\n", foldedStack(blob), blob.Size)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// foldedStack returns the stack that represents `blob`. Any `;` in
+// the path is replaced with `_`, since it would otherwise be taken as
+// a separator. A blob without a path (because it was a root itself)
+// is represented by its OID.
+func foldedStack(blob git.BlobPath) string {
+	if blob.Path == "" {
+		return blob.OID.String()
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(blob.Path, ";", "_"), "/", ";")
+}
@@ -0,0 +1,137 @@
+package sizes
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// objectHeader is the OID and size of an object that has been found
+// but not yet processed.
+type objectHeader struct {
+	oid        git.OID
+	objectSize counts.Count32
+}
+
+// TreeOrder determines the order in which trees are processed during
+// a scan. The order doesn't affect the results of a complete scan
+// (except for which of several equally-good objects is named in the
+// footnotes), but it determines what a scan that is interrupted
+// partway through has managed to see.
+type TreeOrder int
+
+const (
+	// TreeOrderTraversal processes trees in the order that `git
+	// rev-list --objects` finds them. This keeps the number of trees
+	// that are waiting for their subtrees small, and is the default.
+	TreeOrderTraversal TreeOrder = iota
+
+	// TreeOrderLargestFirst processes the trees with the largest
+	// objects (roughly, those with the most entries) first, so that
+	// an interrupted scan has seen the heavy hitters.
+	TreeOrderLargestFirst
+)
+
+// treeFrontier holds the trees that are waiting to be processed.
+type treeFrontier interface {
+	// Push adds a tree to the frontier.
+	Push(obj objectHeader)
+
+	// Pop removes and returns the tree that should be processed
+	// next, or returns `false` if the frontier is empty.
+	Pop() (objectHeader, bool)
+
+	// Len returns the number of trees in the frontier.
+	Len() int
+}
+
+// newTreeFrontier returns an empty frontier that yields trees in the
+// specified order.
+func newTreeFrontier(order TreeOrder) treeFrontier {
+	switch order {
+	case TreeOrderTraversal:
+		return &fifoTreeFrontier{}
+	case TreeOrderLargestFirst:
+		return &largestFirstTreeFrontier{}
+	default:
+		panic(fmt.Sprintf("unexpected TreeOrder %d", order))
+	}
+}
+
+// fifoTreeFrontier yields trees in the order that they were pushed.
+type fifoTreeFrontier struct {
+	objs []objectHeader
+}
+
+func (f *fifoTreeFrontier) Push(obj objectHeader) {
+	f.objs = append(f.objs, obj)
+}
+
+func (f *fifoTreeFrontier) Pop() (objectHeader, bool) {
+	if len(f.objs) == 0 {
+		return objectHeader{}, false
+	}
+	obj := f.objs[0]
+	f.objs = f.objs[1:]
+	return obj, true
+}
+
+func (f *fifoTreeFrontier) Len() int {
+	return len(f.objs)
+}
+
+// largestFirstTreeFrontier yields the largest tree first. Trees of
+// equal size are yielded in the order that they were pushed.
+type largestFirstTreeFrontier struct {
+	h treeHeap
+	n int
+}
+
+func (f *largestFirstTreeFrontier) Push(obj objectHeader) {
+	heap.Push(&f.h, treeHeapEntry{obj, f.n})
+	f.n++
+}
+
+func (f *largestFirstTreeFrontier) Pop() (objectHeader, bool) {
+	if f.h.Len() == 0 {
+		return objectHeader{}, false
+	}
+	return heap.Pop(&f.h).(treeHeapEntry).objectHeader, true
+}
+
+func (f *largestFirstTreeFrontier) Len() int {
+	return f.h.Len()
+}
+
+type treeHeapEntry struct {
+	objectHeader
+	seq int
+}
+
+// treeHeap implements `heap.Interface` as a max-heap on object size.
+type treeHeap []treeHeapEntry
+
+func (h treeHeap) Len() int { return len(h) }
+
+func (h treeHeap) Less(i, j int) bool {
+	if h[i].objectSize != h[j].objectSize {
+		return h[i].objectSize > h[j].objectSize
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h treeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *treeHeap) Push(x interface{}) {
+	*h = append(*h, x.(treeHeapEntry))
+}
+
+func (h *treeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
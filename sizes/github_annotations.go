@@ -0,0 +1,115 @@
+package sizes
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteGitHubAnnotations writes the items of `s` whose level of
+// concern is at least `threshold` to `w` as GitHub Actions workflow
+// commands (e.g., `::warning title=...::message`), so that they show
+// up as annotations when git-sizer is run in a workflow. Critical
+// items are written as errors, other items with stars as warnings,
+// and the rest (which are only written if `threshold` is less than
+// 1) as notices. The items are written in order of their symbols.
+//
+// If an item refers to a blob or a tree, and `nameStyle` allows
+// names, the annotation's `file` is the object's path within its
+// commit.
+func (s *HistorySize) WriteGitHubAnnotations(
+	w io.Writer, refGroups []RefGroup, threshold Threshold, nameStyle NameStyle,
+) error {
+	items := make(map[string]*item)
+	s.contents(refGroups).CollectItems(items)
+
+	symbols := make([]string, 0, len(items))
+	for symbol := range items {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		i := items[symbol]
+		if _, ok := i.levelOfConcern(threshold); !ok {
+			continue
+		}
+		if _, err := io.WriteString(w, i.gitHubAnnotation(nameStyle)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gitHubAnnotation returns the workflow command for `i`, including a
+// trailing LF.
+func (i *item) gitHubAnnotation(nameStyle NameStyle) string {
+	alert, overflow := i.alert()
+
+	var command string
+	switch ConcernLevelOf(alert, overflow) {
+	case ConcernCritical:
+		command = "error"
+	case ConcernNone:
+		command = "notice"
+	default:
+		command = "warning"
+	}
+
+	var properties []string
+	if nameStyle != NameStyleNone {
+		if file := annotationFile(i.path); file != "" {
+			properties = append(properties, "file="+escapeAnnotationProperty(file))
+		}
+	}
+	properties = append(properties, "title="+escapeAnnotationProperty("git-sizer: "+i.symbol))
+
+	valueString, unitString := i.humaner.Format(i.value, i.unit)
+	value := strings.TrimSpace(valueString + " " + unitString)
+	levelOfConcern, _ := i.levelOfConcern(0)
+	message := fmt.Sprintf("%s: %s", i.description, value)
+	if levelOfConcern != "" {
+		message += fmt.Sprintf(" (level of concern: %s)", levelOfConcern)
+	}
+
+	return fmt.Sprintf(
+		"::%s %s::%s\n", command, strings.Join(properties, ","), escapeAnnotationData(message),
+	)
+}
+
+// annotationFile returns the path of the blob or tree `p` relative to
+// the root of its commit's tree (e.g., "src/big.bin" for
+// "refs/heads/main:src/big.bin"), or "" if it doesn't have one.
+func annotationFile(p *Path) string {
+	if p == nil || (p.objectType != "blob" && p.objectType != "tree") {
+		return ""
+	}
+	_, file, ok := strings.Cut(p.Path(), ":")
+	if !ok {
+		return ""
+	}
+	return file
+}
+
+// escapeAnnotationData escapes the message of a workflow command.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	).Replace(s)
+}
+
+// escapeAnnotationProperty escapes the value of a property of a
+// workflow command.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	).Replace(s)
+}
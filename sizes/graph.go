@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/github/git-sizer/counts"
 	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
 	"github.com/github/git-sizer/meter"
 )
 
+// ErrCorruptTree is wrapped by the error that a strict scan returns if
+// it finds a corrupt tree (see `ScanOptions.Strict`).
+var ErrCorruptTree = errors.New("corrupt tree")
+
 type Root interface {
 	Name() string
 	OID() git.OID
@@ -23,6 +30,139 @@ type ReferenceRoot interface {
 	Groups() []RefGroupSymbol
 }
 
+// ScanOptions holds the options that affect how a repository is
+// scanned.
+type ScanOptions struct {
+	// NameStyle specifies whether the output should include full
+	// names, hashes only, or nothing in the footnotes.
+	NameStyle NameStyle
+
+	// SeenBlobs, if set, is used to remember which blobs have
+	// already been attributed to a filename extension. If it is
+	// nil, an exact (map-backed) set is used. An approximate set
+	// (e.g., `git.BloomOIDSet`) uses much less memory for huge
+	// repositories, but its false positives cause some blobs to be
+	// omitted from the per-extension statistics.
+	SeenBlobs git.OIDSet
+
+	// MinDedupBlobSize, if nonzero, is the size below which blobs
+	// are not recorded in `SeenBlobs`. This can shrink the set
+	// dramatically, since most blobs are small. The price is that a
+	// small blob that appears under several filenames is counted
+	// once for each of them in the per-extension statistics, which
+	// thus overstate the number and size of small blobs. (More
+	// precisely, such a blob is counted once for each entry in a
+	// distinct tree that refers to it.) Blobs at least this big are
+	// still counted once.
+	MinDedupBlobSize counts.Count32
+
+	// TreeSizes, if set, is where the sizes of trees are stored. If
+	// it is nil, a `MemoryCacheBackend` is used. If the backend
+	// already knows the size of a tree (e.g., because it was filled
+	// by an earlier scan), that size is used instead of being
+	// computed from the tree's subtrees. The tree's entries are still
+	// processed, so it contributes to the history-wide statistics
+	// just like any other tree. The caller is responsible for closing
+	// the backend.
+	TreeSizes CacheBackend
+
+	// TreeOrder determines the order in which trees are processed.
+	// It only matters if the scan is interrupted.
+	TreeOrder TreeOrder
+
+	// MaxFilenameLength is the length in bytes above which a single
+	// filename is reported as too long. If it is zero,
+	// `DefaultMaxFilenameLength` is used.
+	MaxFilenameLength int
+
+	// WideTreeEntries is the number of entries above which a single
+	// tree is reported as too wide. If it is zero,
+	// `DefaultWideTreeEntries` is used.
+	WideTreeEntries int
+
+	// DefaultBranch, if set, is the name of the default branch, whose
+	// blob data is compared with that of all of the roots (see
+	// `DefaultBranchShare`). "HEAD" means the branch that `HEAD`
+	// points at. The comparison requires two extra walks of the
+	// history, so it is skipped if this is empty.
+	DefaultBranch string
+
+	// CheckSymbolicRefs, if set, causes `HEAD` and the other
+	// symbolic references to be checked for targets that don't
+	// exist (see `ScanRepositoryConfig()`).
+	CheckSymbolicRefs bool
+
+	// CheckStorage, if set, causes the loose objects and packfiles
+	// of the whole object store to be examined (see
+	// `ScanStorage()`).
+	CheckStorage bool
+
+	// CheckConfig, if set, causes the gitconfig settings that affect
+	// the repository's size to be read (see
+	// `HistorySize.RelevantConfig`), so that problems like disabled
+	// compression can be reported. This requires an extra `git
+	// config` process.
+	CheckConfig bool
+
+	// RefKinds, if set, causes the objects reachable from the
+	// references to be attributed to branches, tags, and other
+	// references (see `RefKindShares`). This requires three extra
+	// walks of the history.
+	RefKinds bool
+
+	// VerifyOIDs, if set, causes the contents of every object that
+	// is scanned to be rehashed and compared with its OID, like a
+	// `git fsck` of the reachable objects. Any mismatches are
+	// reported in `HistorySize.CorruptObjects`. This requires
+	// reading the contents of all of the blobs, so it is expensive.
+	VerifyOIDs bool
+
+	// DumpState, if set, is where a summary of the graph's internal
+	// state is written when the scan ends, whether or not it
+	// succeeded (see `Graph.DumpState()`). It is meant for debugging.
+	DumpState io.Writer
+
+	// Strict, if set, makes the scan fail with an error wrapping
+	// `ErrCorruptTree` if it finds a tree entry with an empty
+	// filename. Otherwise, such entries are only counted (see
+	// `HistorySize.EmptyFilenameCount`).
+	Strict bool
+
+	// NormalizeNames, if set, makes the case-collision checks
+	// compare the names of tree entries after converting them to
+	// Unicode Normalization Form C, so that names that look
+	// identical but are encoded differently (e.g., "é" as one
+	// precomposed character in one commit and as "e" plus a
+	// combining accent, as macOS tends to write it, in another) are
+	// reported as colliding. Filesystems that normalize names can
+	// only check out one such entry. Other metrics, including
+	// `MaxFilenameLength` and the path-length statistics, still
+	// measure names as they are stored, so a decomposed name counts
+	// each combining mark as a separate character.
+	NormalizeNames bool
+
+	// TrackExemplars, if set, makes the `TreeSize` of each tree
+	// record which objects achieved some of its maxima (e.g., the
+	// blob at the end of its deepest path); see
+	// `TreeSize.Exemplar()`. This costs a small map per tree, so it
+	// is off by default.
+	TrackExemplars bool
+
+	// Labels are user-defined names and values (e.g., the
+	// organization or the shard that a repository belongs to) that
+	// are copied to `HistorySize.Labels`, so that they appear in
+	// every output format. The names must be valid according to
+	// `ValidateLabels()`.
+	Labels map[string]string
+
+	// Graph, if set, is the graph that the scan records its results
+	// in, so that the caller can use it afterwards (e.g., for
+	// `Graph.WalkCommits()`). It must have been created by
+	// `NewGraphWithOptions()` with the same options and must not have
+	// been used for another scan. If it is nil, a new graph is used.
+	Graph *Graph
+}
+
 // ScanRepositoryUsingGraph scans `repo`, using `rg` to decide which
 // references to scan and how to group them. `nameStyle` specifies
 // whether the output should include full names, hashes only, or
@@ -37,7 +177,113 @@ func ScanRepositoryUsingGraph(
 	nameStyle NameStyle,
 	progressMeter meter.Progress,
 ) (HistorySize, error) {
-	graph := NewGraph(nameStyle)
+	return ScanRepositoryWithOptions(
+		ctx, repo, roots, ScanOptions{NameStyle: nameStyle}, progressMeter,
+	)
+}
+
+// ScanRepositoryWithOptions is like `ScanRepositoryUsingGraph()`, but
+// takes its options in a `ScanOptions`.
+func ScanRepositoryWithOptions(
+	ctx context.Context,
+	repo *git.Repository,
+	roots []Root,
+	opts ScanOptions,
+	progressMeter meter.Progress,
+) (HistorySize, error) {
+	if err := ValidateLabels(opts.Labels); err != nil {
+		return HistorySize{}, err
+	}
+
+	graph := opts.Graph
+	if graph == nil {
+		graph = NewGraphWithOptions(opts)
+	}
+	if opts.DumpState != nil {
+		defer func() {
+			_ = graph.DumpState(opts.DumpState)
+		}()
+	}
+
+	verifier := objectVerifier{enabled: opts.VerifyOIDs}
+
+	historySize, err := scanGraph(ctx, repo, roots, opts, graph, &verifier, progressMeter)
+	if err != nil {
+		return historySize, err
+	}
+
+	if len(opts.Labels) != 0 {
+		historySize.Labels = make(map[string]string, len(opts.Labels))
+		for key, value := range opts.Labels {
+			historySize.Labels[key] = value
+		}
+	}
+
+	if opts.CheckSymbolicRefs {
+		historySize.RepositoryConfig, err = ScanRepositoryConfig(repo)
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("checking symbolic references: %w", err)
+		}
+	}
+
+	if opts.CheckStorage {
+		storage, err := ScanStorage(repo)
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("checking object storage: %w", err)
+		}
+		historySize.Storage = &storage
+	}
+
+	if opts.CheckConfig {
+		historySize.RelevantConfig, err = repo.RelevantConfig()
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("reading gitconfig: %w", err)
+		}
+	}
+
+	if opts.VerifyOIDs {
+		historySize.CorruptObjects, err = verifier.finish(ctx, repo)
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("verifying objects: %w", err)
+		}
+		corrupt := counts.NewCount32(uint64(len(historySize.CorruptObjects)))
+		historySize.CorruptObjectCount = &corrupt
+	}
+
+	if opts.DefaultBranch != "" {
+		share, err := ScanDefaultBranchShare(ctx, repo, opts.DefaultBranch, roots)
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("comparing with the default branch: %w", err)
+		}
+		historySize.DefaultBranchShare = &share
+	}
+
+	if opts.RefKinds {
+		shares, err := ScanRefKindShares(ctx, repo, roots)
+		if err != nil {
+			return HistorySize{}, fmt.Errorf("attributing objects to reference kinds: %w", err)
+		}
+		historySize.RefKindShares = &shares
+	}
+
+	return historySize, nil
+}
+
+// scanGraph walks the objects reachable from `roots`, registering
+// them in `graph`, and returns the resulting `HistorySize`. Unlike
+// `ScanRepositoryWithOptions()`, it doesn't run any of the optional
+// analyses; the options only affect how the objects are read. The
+// objects that are read are also passed to `verifier`.
+func scanGraph(
+	ctx context.Context,
+	repo *git.Repository,
+	roots []Root,
+	opts ScanOptions,
+	graph *Graph,
+	verifier *objectVerifier,
+	progressMeter meter.Progress,
+) (HistorySize, error) {
+	nameStyle := opts.NameStyle
 
 	objIter, err := repo.NewObjectIter(ctx)
 	if err != nil {
@@ -64,13 +310,8 @@ func ScanRepositoryUsingGraph(
 		}()
 	}()
 
-	type ObjectHeader struct {
-		oid        git.OID
-		objectSize counts.Count32
-	}
-
 	type CommitHeader struct {
-		ObjectHeader
+		objectHeader
 		tree git.OID
 	}
 
@@ -87,7 +328,8 @@ func ScanRepositoryUsingGraph(
 	//   This is relatively inconsequential because blobs can't point
 	//   at any other objects.
 	//
-	// * Trees are processed in roughly reverse-chronological order
+	// * Trees are, by default, processed in roughly
+	//   reverse-chronological order
 	//   (the order that they come out of `git rev-parse --date-order
 	//   --objects`). This is more efficient than the reverse because
 	//   the Git command outputs the whole tree corresponding to a
@@ -115,7 +357,8 @@ func ScanRepositoryUsingGraph(
 	//   favor certain references when naming commits that are pointed
 	//   to by multiple references, but it doesn't seem worth the
 	//   effort.)
-	var trees, tags []ObjectHeader
+	trees := newTreeFrontier(opts.TreeOrder)
+	var tags []objectHeader
 	var commits []CommitHeader
 
 	progressMeter.Start("Processing blobs: %d")
@@ -131,12 +374,13 @@ func ScanRepositoryUsingGraph(
 		case "blob":
 			progressMeter.Inc()
 			graph.RegisterBlob(obj.OID, obj.ObjectSize)
+			verifier.addBlob(obj.OID)
 		case "tree":
-			trees = append(trees, ObjectHeader{obj.OID, obj.ObjectSize})
+			trees.Push(objectHeader{obj.OID, obj.ObjectSize})
 		case "commit":
-			commits = append(commits, CommitHeader{ObjectHeader{obj.OID, obj.ObjectSize}, git.NullOID})
+			commits = append(commits, CommitHeader{objectHeader{obj.OID, obj.ObjectSize}, git.NullOID})
 		case "tag":
-			tags = append(tags, ObjectHeader{obj.OID, obj.ObjectSize})
+			tags = append(tags, objectHeader{obj.OID, obj.ObjectSize})
 		default:
 			return HistorySize{}, fmt.Errorf("unexpected object type: %s", obj.ObjectType)
 		}
@@ -148,16 +392,27 @@ func ScanRepositoryUsingGraph(
 		return HistorySize{}, err
 	}
 
-	objectIter, err := repo.NewBatchObjectIter(ctx)
+	// Parse trees as they are read, so that huge trees don't have to
+	// be held in memory, unless their contents are needed for
+	// verification:
+	objectIter, err := repo.NewBatchObjectIterWithOptions(
+		ctx, git.BatchObjectIterOptions{StreamTrees: !opts.VerifyOIDs},
+	)
 	if err != nil {
 		return HistorySize{}, err
 	}
 
+	treeCount := trees.Len()
+
 	go func() {
 		defer objectIter.Close()
 
 		errChan <- func() error {
-			for _, obj := range trees {
+			for {
+				obj, ok := trees.Pop()
+				if !ok {
+					break
+				}
 				if err := objectIter.RequestObject(obj.oid); err != nil {
 					return fmt.Errorf("requesting tree '%s': %w", obj.oid, err)
 				}
@@ -181,7 +436,12 @@ func ScanRepositoryUsingGraph(
 	}()
 
 	progressMeter.Start("Processing trees: %d")
-	for range trees {
+	for i := 0; i < treeCount; i++ {
+		if ctx.Err() != nil {
+			progressMeter.Done()
+			return interruptedScan(ctx, graph, objectIter, errChan)
+		}
+
 		obj, ok, err := objectIter.Next()
 		if err != nil {
 			return HistorySize{}, err
@@ -193,11 +453,16 @@ func ScanRepositoryUsingGraph(
 			return HistorySize{}, fmt.Errorf("expected tree; read %#v", obj.ObjectType)
 		}
 		progressMeter.Inc()
-		tree, err := git.ParseTree(obj.OID, obj.Data)
-		if err != nil {
-			return HistorySize{}, err
+		if obj.Tree != nil {
+			err = graph.RegisterTreeEntries(obj.OID, obj.ObjectSize, obj.Tree)
+		} else {
+			verifier.check(obj)
+			var tree *git.Tree
+			tree, err = git.ParseTree(obj.OID, obj.Data)
+			if err == nil {
+				err = graph.RegisterTree(obj.OID, tree)
+			}
 		}
-		err = graph.RegisterTree(obj.OID, tree)
 		if err != nil {
 			return HistorySize{}, err
 		}
@@ -219,6 +484,7 @@ func ScanRepositoryUsingGraph(
 		if obj.ObjectType != "commit" {
 			return HistorySize{}, fmt.Errorf("expected commit; read %#v", obj.ObjectType)
 		}
+		verifier.check(obj)
 		commit, err := git.ParseCommit(obj.OID, obj.Data)
 		if err != nil {
 			return HistorySize{}, err
@@ -255,6 +521,7 @@ func ScanRepositoryUsingGraph(
 		if obj.ObjectType != "tag" {
 			return HistorySize{}, fmt.Errorf("expected tag; read %#v", obj.ObjectType)
 		}
+		verifier.check(obj)
 		tag, err := git.ParseTag(obj.OID, obj.Data)
 		if err != nil {
 			return HistorySize{}, err
@@ -285,14 +552,50 @@ func ScanRepositoryUsingGraph(
 	return graph.HistorySize(), nil
 }
 
+// ErrScanInterrupted is returned (wrapped) by
+// `ScanRepositoryWithOptions()` if its context is canceled while it is
+// processing trees. In that case, the `HistorySize` that is returned
+// along with the error holds the partial results: the blob
+// statistics are complete, but only the trees that were processed
+// (and, for checkout-related statistics, whose subtrees were all
+// processed) are counted, and no commits or tags are.
+var ErrScanInterrupted = errors.New("scan interrupted")
+
+// interruptedScan cleans up after a scan that was interrupted while
+// processing trees, and returns the partial results.
+func interruptedScan(
+	ctx context.Context, graph *Graph, objectIter *git.BatchObjectIter, errChan <-chan error,
+) (HistorySize, error) {
+	// Drain the iterator so that its goroutines and subprocesses
+	// finish. The errors are expected, since the context has been
+	// canceled:
+	for {
+		_, ok, _ := objectIter.Next()
+		if !ok {
+			break
+		}
+	}
+	<-errChan
+
+	return graph.partialHistorySize(), fmt.Errorf("%w: %v", ErrScanInterrupted, ctx.Err())
+}
+
 // Graph is an object graph that is being built up.
 type Graph struct {
 	blobLock  sync.Mutex
 	blobSizes map[git.OID]BlobSize
 
+	// blobsWithExtension records the blobs that have already been
+	// attributed to a filename extension. Protected by `blobLock`.
+	blobsWithExtension git.OIDSet
+
+	// minDedupBlobSize is the size below which blobs are attributed
+	// to extensions without being recorded in `blobsWithExtension`.
+	minDedupBlobSize counts.Count32
+
 	treeLock    sync.Mutex
 	treeRecords map[git.OID]*treeRecord
-	treeSizes   map[git.OID]TreeSize
+	treeSizes   CacheBackend
 
 	commitLock  sync.Mutex
 	commitSizes map[git.OID]CommitSize
@@ -305,16 +608,83 @@ type Graph struct {
 	historyLock sync.Mutex
 	historySize HistorySize
 
+	// The number of commits by each author. Protected by
+	// `historyLock`.
+	authors authorStats
+
+	// The range of the commit timestamps. Protected by
+	// `historyLock`.
+	commitDates commitDateStats
+
+	// The names of the references seen so far. Protected by
+	// `historyLock`.
+	refNames refNameStats
+
+	// The overlong filenames that have already been reported in
+	// `historySize.LongFilenames`. Protected by `historyLock`.
+	longFilenames map[string]struct{}
+
+	// The widest of the trees that are reported in
+	// `historySize.WideTrees`. Protected by `historyLock`.
+	wideTrees *topn.TopN[WideTree]
+
 	pathResolver PathResolver
+
+	// maxFilenameLength is the length above which filenames are
+	// reported as too long.
+	maxFilenameLength int
+
+	// wideTreeEntries is the number of entries above which trees
+	// are reported as too wide.
+	wideTreeEntries int
+
+	// strict is set if corrupt trees should cause the scan to fail.
+	strict bool
+
+	// normalizeNames is set if the names of tree entries should be
+	// converted to NFC before checking for case collisions.
+	normalizeNames bool
+
+	// trackExemplars is set if `TreeSize`s should record which
+	// objects achieved their maxima.
+	trackExemplars bool
 }
 
 // NewGraph creates and returns a new `*Graph` instance.
 func NewGraph(nameStyle NameStyle) *Graph {
+	return NewGraphWithOptions(ScanOptions{NameStyle: nameStyle})
+}
+
+// NewGraphWithOptions creates and returns a new `*Graph` instance
+// configured according to `opts`.
+func NewGraphWithOptions(opts ScanOptions) *Graph {
+	seenBlobs := opts.SeenBlobs
+	if seenBlobs == nil {
+		seenBlobs = git.NewExactOIDSet()
+	}
+
+	treeSizes := opts.TreeSizes
+	if treeSizes == nil {
+		treeSizes = NewMemoryCacheBackend()
+	}
+
+	maxFilenameLength := opts.MaxFilenameLength
+	if maxFilenameLength == 0 {
+		maxFilenameLength = DefaultMaxFilenameLength
+	}
+
+	wideTreeEntries := opts.WideTreeEntries
+	if wideTreeEntries == 0 {
+		wideTreeEntries = DefaultWideTreeEntries
+	}
+
 	return &Graph{
-		blobSizes: make(map[git.OID]BlobSize),
+		blobSizes:          make(map[git.OID]BlobSize),
+		blobsWithExtension: seenBlobs,
+		minDedupBlobSize:   opts.MinDedupBlobSize,
 
 		treeRecords: make(map[git.OID]*treeRecord),
-		treeSizes:   make(map[git.OID]TreeSize),
+		treeSizes:   treeSizes,
 
 		commitSizes: make(map[git.OID]CommitSize),
 
@@ -323,9 +693,20 @@ func NewGraph(nameStyle NameStyle) *Graph {
 
 		historySize: HistorySize{
 			ReferenceGroups: make(map[RefGroupSymbol]*counts.Count32),
+			Extensions:      make(map[string]*ExtStats),
 		},
 
-		pathResolver: NewPathResolver(nameStyle),
+		authors:     newAuthorStats(),
+		commitDates: newCommitDateStats(time.Now()),
+		refNames:    newRefNameStats(),
+
+		pathResolver: NewPathResolver(opts.NameStyle),
+
+		maxFilenameLength: maxFilenameLength,
+		wideTreeEntries:   wideTreeEntries,
+		strict:            opts.Strict,
+		normalizeNames:    opts.NormalizeNames,
+		trackExemplars:    opts.TrackExemplars,
 	}
 }
 
@@ -361,6 +742,14 @@ func (g *Graph) HistorySize() HistorySize {
 	return g.historySize
 }
 
+// partialHistorySize returns the size data that have been collected
+// so far, even if some objects are still pending.
+func (g *Graph) partialHistorySize() HistorySize {
+	g.historyLock.Lock()
+	defer g.historyLock.Unlock()
+	return g.historySize
+}
+
 // RegisterBlob records that the specified `oid` is a blob with the
 // specified size.
 func (g *Graph) RegisterBlob(oid git.OID, objectSize counts.Count32) {
@@ -377,6 +766,26 @@ func (g *Graph) RegisterBlob(oid git.OID, objectSize counts.Count32) {
 	g.historyLock.Unlock()
 }
 
+// registerBlobExtension attributes the blob `oid` to the extension of
+// `name`, unless it has already been attributed to an extension.
+// Blobs smaller than `g.minDedupBlobSize` aren't remembered, so they
+// are attributed every time.
+func (g *Graph) registerBlobExtension(oid git.OID, name string, size BlobSize) {
+	if size.Size >= g.minDedupBlobSize {
+		g.blobLock.Lock()
+		added := g.blobsWithExtension.Add(oid)
+		g.blobLock.Unlock()
+
+		if !added {
+			return
+		}
+	}
+
+	g.historyLock.Lock()
+	g.historySize.recordBlobExtension(g, oid, name, size)
+	g.historyLock.Unlock()
+}
+
 // The `Require*Size` functions behave as follows:
 //
 // * If the size of the object with name `oid` is already known. In
@@ -398,7 +807,7 @@ func (g *Graph) GetBlobSize(oid git.OID) BlobSize {
 func (g *Graph) RequireTreeSize(oid git.OID, listener func(TreeSize)) (TreeSize, bool) {
 	g.treeLock.Lock()
 
-	size, ok := g.treeSizes[oid]
+	size, ok := g.treeSizes.Get(oid)
 	if ok {
 		g.treeLock.Unlock()
 
@@ -407,7 +816,7 @@ func (g *Graph) RequireTreeSize(oid git.OID, listener func(TreeSize)) (TreeSize,
 
 	record, ok := g.treeRecords[oid]
 	if !ok {
-		record = newTreeRecord(oid)
+		record = newTreeRecord(oid, g.trackExemplars)
 		g.treeRecords[oid] = record
 	}
 	record.addListener(listener)
@@ -420,7 +829,7 @@ func (g *Graph) RequireTreeSize(oid git.OID, listener func(TreeSize)) (TreeSize,
 func (g *Graph) GetTreeSize(oid git.OID) TreeSize {
 	g.treeLock.Lock()
 
-	size, ok := g.treeSizes[oid]
+	size, ok := g.treeSizes.Get(oid)
 	if !ok {
 		panic("tree size not available!")
 	}
@@ -430,30 +839,45 @@ func (g *Graph) GetTreeSize(oid git.OID) TreeSize {
 
 // Record that the specified `oid` is the specified `tree`.
 func (g *Graph) RegisterTree(oid git.OID, tree *git.Tree) error {
-	g.treeLock.Lock()
+	return g.RegisterTreeEntries(oid, tree.Size(), tree.Iter())
+}
 
-	if _, ok := g.treeSizes[oid]; ok {
-		panic(fmt.Sprintf("tree %s registered twice!", oid))
-	}
+// RegisterTreeEntries is like `RegisterTree()`, but takes the tree's
+// size and an iterator over its entries (e.g., a `*git.TreeStream`)
+// rather than the parsed tree.
+func (g *Graph) RegisterTreeEntries(
+	oid git.OID, objectSize counts.Count32, entries git.TreeEntryIterator,
+) error {
+	g.treeLock.Lock()
 
 	// See if we already have a record for this tree:
 	record, ok := g.treeRecords[oid]
 	if !ok {
-		record = newTreeRecord(oid)
-		g.treeRecords[oid] = record
+		record = newTreeRecord(oid, g.trackExemplars)
+		if size, ok := g.treeSizes.Get(oid); ok {
+			// The recursive size is already known (e.g., from a
+			// persistent cache), so it needn't be computed from
+			// the subtrees. But the entries still have to be
+			// processed for the statistics about distinct
+			// trees. Nobody can be waiting for the record, so
+			// it isn't published.
+			record.cachedSize = &size
+		} else {
+			g.treeRecords[oid] = record
+		}
 	}
 
 	g.treeLock.Unlock()
 
 	// Let the record take care of the rest:
-	return record.initialize(g, oid, tree)
+	return record.initialize(g, oid, objectSize, entries)
 }
 
 func (g *Graph) finalizeTreeSize(
 	oid git.OID, size TreeSize, objectSize counts.Count32, treeEntries counts.Count32,
 ) {
 	g.treeLock.Lock()
-	g.treeSizes[oid] = size
+	g.treeSizes.Set(oid, size)
 	delete(g.treeRecords, oid)
 	g.treeLock.Unlock()
 
@@ -488,25 +912,57 @@ type treeRecord struct {
 
 	// The listeners waiting to learn our size.
 	listeners []func(TreeSize)
+
+	// cachedSize, if set, is the tree's recursive size, which was
+	// already known when the tree was registered. In that case, the
+	// record doesn't wait for the sizes of its subtrees.
+	cachedSize *TreeSize
 }
 
-func newTreeRecord(oid git.OID) *treeRecord {
-	return &treeRecord{
+func newTreeRecord(oid git.OID, trackExemplars bool) *treeRecord {
+	r := &treeRecord{
 		oid:     oid,
 		size:    TreeSize{ExpandedTreeCount: 1},
 		pending: -1,
 	}
+	if trackExemplars {
+		r.size.ExemplarMap = NewExemplarMap()
+	}
+	return r
 }
 
-// Initialize `r` (which is empty) based on `tree`.
-func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
+// Initialize `r` (which is empty) based on the tree's size and
+// entries.
+func (r *treeRecord) initialize(
+	g *Graph, oid git.OID, objectSize counts.Count32, iter git.TreeEntryIterator,
+) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	r.objectSize = tree.Size()
+	r.objectSize = objectSize
+	r.size.adjustMax(ExemplarMaxTreeSerializedSize, &r.size.MaxTreeSerializedSize, objectSize, oid)
 	r.pending = 0
 
-	iter := tree.Iter()
+	// The number of entries referring to the empty blob and the
+	// empty tree:
+	var emptyBlobs, emptyTrees counts.Count32
+
+	// The number of entries with empty names:
+	var emptyNames counts.Count32
+
+	// The number of entries with the modes of Unix sockets, and of
+	// unknown types:
+	var unixSockets, unknownFiletypes counts.Count32
+
+	// The number of entries of each type:
+	var entryTypes TypeBreakdown
+
+	// The total size of the blobs that the entries refer to:
+	var referencedBlobSize counts.Count64
+
+	// Entries whose names differ only in case:
+	folder := caseFolder{normalize: g.normalizeNames}
+
 	for {
 		entry, ok, err := iter.NextEntry()
 		if err != nil {
@@ -516,10 +972,31 @@ func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
 			break
 		}
 		name := entry.Name
+		if name == "" {
+			// Git never writes such entries, but buggy importers
+			// (e.g., feeding `git fast-import`) might:
+			if g.strict {
+				return fmt.Errorf("%w %s: entry with an empty filename", ErrCorruptTree, oid)
+			}
+			emptyNames.Increment(1)
+		}
+		if len(name) > g.maxFilenameLength {
+			g.registerLongFilename(oid, name)
+		}
+		folder.add(entry)
 
 		switch {
 		case entry.Filemode&0o170000 == 0o40000:
 			// Tree
+			if entry.OID == git.EmptyTreeOID {
+				emptyTrees.Increment(1)
+			}
+			entryTypes.TreeCount.Increment(1)
+			if r.cachedSize != nil {
+				g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
+				r.entryCount.Increment(1)
+				break
+			}
 			listener := func(size TreeSize) {
 				// This listener is called when the tree pointed to by
 				// `entry` has been fully processed.
@@ -528,7 +1005,7 @@ func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
 
 				g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
 
-				r.size.addDescendent(name, size)
+				r.size.addDescendent(name, entry.OID, size)
 				r.pending--
 				// This might inform *our* listeners that we are now
 				// fully processed:
@@ -536,7 +1013,7 @@ func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
 			}
 			treeSize, ok := g.RequireTreeSize(entry.OID, listener)
 			if ok {
-				r.size.addDescendent(name, treeSize)
+				r.size.addDescendent(name, entry.OID, treeSize)
 			} else {
 				r.pending++
 			}
@@ -544,26 +1021,72 @@ func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
 
 		case entry.Filemode&0o170000 == 0o160000:
 			// Commit (i.e., submodule)
-			r.size.addSubmodule(name)
+			entryTypes.SubmoduleCount.Increment(1)
+			r.size.addSubmodule(name, entry.OID)
 			r.entryCount.Increment(1)
 
 		case entry.Filemode&0o170000 == 0o120000:
 			// Symlink
+			entryTypes.SymlinkCount.Increment(1)
 			g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
 
-			r.size.addLink(name)
+			r.size.addLink(name, entry.OID)
+			r.entryCount.Increment(1)
+
+		case entry.Filemode&0o170000 == 0o140000:
+			// Unix socket. Git never creates these, but `git
+			// mktree` and importers can. Git reads such entries as
+			// gitlinks (so `git rev-list --objects` never lists
+			// their objects), so count them as submodules in
+			// `entryTypes`.
+			unixSockets.Increment(1)
+			entryTypes.SubmoduleCount.Increment(1)
+			g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
+
+			r.size.addUnixSocket(name, entry.OID)
+			r.entryCount.Increment(1)
+
+		case entry.Filemode&0o170000 != 0o100000:
+			// Some other type that Git doesn't know about. (Git
+			// itself treats such entries, like sockets, as
+			// submodules when it reads the tree, so count them as
+			// such in `entryTypes`.)
+			unknownFiletypes.Increment(1)
+			entryTypes.SubmoduleCount.Increment(1)
+			g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
+
+			r.size.addUnknownFiletype(name, entry.OID)
 			r.entryCount.Increment(1)
 
 		default:
 			// Blob
+			if entry.OID == git.EmptyBlobOID {
+				emptyBlobs.Increment(1)
+			}
+			entryTypes.BlobCount.Increment(1)
+			blobSize := g.GetBlobSize(entry.OID)
+			g.registerBlobExtension(entry.OID, name, blobSize)
+
 			g.pathResolver.RecordTreeEntry(oid, name, entry.OID)
 
-			blobSize := g.GetBlobSize(entry.OID)
-			r.size.addBlob(name, blobSize)
+			r.size.addBlob(name, entry.OID, blobSize)
+			referencedBlobSize.Increment(counts.Count64(blobSize.Size))
 			r.entryCount.Increment(1)
 		}
 	}
 
+	g.historyLock.Lock()
+	g.historySize.EmptyBlobCount.Increment(emptyBlobs)
+	g.historySize.EmptyTreeCount.Increment(emptyTrees)
+	g.historySize.EmptyFilenameCount.Increment(emptyNames)
+	g.historySize.UnixSocketCount.Increment(unixSockets)
+	g.historySize.UnknownFiletypeCount.Increment(unknownFiletypes)
+	g.historySize.EntryTypes.add(entryTypes)
+	g.historySize.ReferencedBlobSize.Increment(referencedBlobSize)
+	g.historyLock.Unlock()
+
+	g.registerCaseCollisions(oid, &folder)
+
 	r.maybeFinalize(g)
 
 	return nil
@@ -571,6 +1094,9 @@ func (r *treeRecord) initialize(g *Graph, oid git.OID, tree *git.Tree) error {
 
 func (r *treeRecord) maybeFinalize(g *Graph) {
 	if r.pending == 0 {
+		if r.cachedSize != nil {
+			r.size = *r.cachedSize
+		}
 		g.finalizeTreeSize(r.oid, r.size, r.objectSize, r.entryCount)
 		for _, listener := range r.listeners {
 			listener(r.size)
@@ -628,6 +1154,11 @@ func (g *Graph) RegisterCommit(oid git.OID, commit *git.Commit) {
 
 	g.historyLock.Lock()
 	g.historySize.recordCommit(g, oid, size, commit.Size, parentCount)
+	g.historySize.recordHeaders(g, oid, "commit", commit.Headers)
+	g.historySize.recordAuthors(&g.authors, commit.AuthorEmail, commit.CommitterEmail)
+	g.historySize.recordCommitDates(
+		g, &g.commitDates, oid, commit.AuthorTime, commit.CommitterTime,
+	)
 	g.historyLock.Unlock()
 }
 
@@ -670,6 +1201,10 @@ func (g *Graph) RegisterTag(oid git.OID, tag *git.Tag) {
 
 	g.tagLock.Unlock()
 
+	g.historyLock.Lock()
+	g.historySize.recordHeaders(g, oid, "tag", tag.Headers)
+	g.historyLock.Unlock()
+
 	// Let the record take care of the rest:
 	record.initialize(g, oid, tag)
 }
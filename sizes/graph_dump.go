@@ -0,0 +1,127 @@
+package sizes
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/git-sizer/git"
+)
+
+// dumpSampleSize is the number of entries of each kind that
+// `DumpState()` shows.
+const dumpSampleSize = 5
+
+// DumpState writes a human-readable summary of the internal state of
+// `g` to `w`: how many sizes are known, how many trees and tags are
+// still waiting for their dependencies, and a sample of each. It is a
+// debugging aid for walks that produce surprising numbers. The format
+// is internal and may change at any time; don't parse it.
+//
+// It is safe to call while a scan is in progress, though the numbers
+// might then not be mutually consistent.
+func (g *Graph) DumpState(w io.Writer) error {
+	g.blobLock.Lock()
+	blobCount := len(g.blobSizes)
+	blobSample := sampleOIDs(g.blobSizes)
+	blobSizes := make([]BlobSize, len(blobSample))
+	for i, oid := range blobSample {
+		blobSizes[i] = g.blobSizes[oid]
+	}
+	g.blobLock.Unlock()
+
+	g.treeLock.Lock()
+	treeCount := "unknown"
+	if b, ok := g.treeSizes.(interface{ Len() int }); ok {
+		treeCount = fmt.Sprintf("%d", b.Len())
+	}
+	pendingTrees := make([]*treeRecord, 0, len(g.treeRecords))
+	for _, oid := range sampleOIDs(g.treeRecords) {
+		pendingTrees = append(pendingTrees, g.treeRecords[oid])
+	}
+	pendingTreeCount := len(g.treeRecords)
+	g.treeLock.Unlock()
+
+	g.commitLock.Lock()
+	commitCount := len(g.commitSizes)
+	g.commitLock.Unlock()
+
+	g.tagLock.Lock()
+	tagCount := len(g.tagSizes)
+	pendingTags := make([]*tagRecord, 0, len(g.tagRecords))
+	for _, oid := range sampleOIDs(g.tagRecords) {
+		pendingTags = append(pendingTags, g.tagRecords[oid])
+	}
+	pendingTagCount := len(g.tagRecords)
+	g.tagLock.Unlock()
+
+	lines := []string{
+		"# git-sizer internal state (for debugging only; the format is unstable)",
+		fmt.Sprintf("known blob sizes: %d", blobCount),
+		fmt.Sprintf("known tree sizes: %s", treeCount),
+		fmt.Sprintf("known commit sizes: %d", commitCount),
+		fmt.Sprintf("known tag sizes: %d", tagCount),
+		fmt.Sprintf("pending trees: %d", pendingTreeCount),
+		fmt.Sprintf("pending tags: %d", pendingTagCount),
+	}
+
+	if len(blobSample) > 0 {
+		lines = append(lines, "sample of blob sizes:")
+		for i, oid := range blobSample {
+			lines = append(lines, fmt.Sprintf("    %s size=%d", oid, blobSizes[i].Size))
+		}
+	}
+
+	if len(pendingTrees) > 0 {
+		lines = append(lines, "sample of pending trees:")
+		for _, r := range pendingTrees {
+			r.lock.Lock()
+			line := fmt.Sprintf("    %s ", r.oid)
+			if r.pending == -1 {
+				line += "not yet read"
+			} else {
+				line += fmt.Sprintf(
+					"entries=%d waiting-for=%d", r.entryCount, r.pending,
+				)
+			}
+			line += fmt.Sprintf(" listeners=%d", len(r.listeners))
+			r.lock.Unlock()
+			lines = append(lines, line)
+		}
+	}
+
+	if len(pendingTags) > 0 {
+		lines = append(lines, "sample of pending tags:")
+		for _, r := range pendingTags {
+			r.lock.Lock()
+			line := fmt.Sprintf(
+				"    %s waiting-for=%d listeners=%d", r.oid, r.pending, len(r.listeners),
+			)
+			r.lock.Unlock()
+			lines = append(lines, line)
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleOIDs returns the `dumpSampleSize` lowest keys of `m`, sorted,
+// so that the sample is reproducible.
+func sampleOIDs[V any](m map[git.OID]V) []git.OID {
+	oids := make([]git.OID, 0, len(m))
+	for oid := range m {
+		oids = append(oids, oid)
+	}
+	sort.Slice(oids, func(i, j int) bool {
+		return oids[i].String() < oids[j].String()
+	})
+	if len(oids) > dumpSampleSize {
+		oids = oids[:dumpSampleSize]
+	}
+	return oids
+}
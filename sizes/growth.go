@@ -0,0 +1,219 @@
+package sizes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DefaultGrowthWindows are the windows that `ScanStorageGrowth()`
+// reports by default.
+var DefaultGrowthWindows = []time.Duration{
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// GrowthWindow describes the objects that arrived within a window of
+// time before the scan.
+type GrowthWindow struct {
+	// Window is the length of the window, in seconds.
+	Window int64 `json:"window"`
+
+	ObjectCount counts.Count32 `json:"object_count"`
+	DiskSize    counts.Count64 `json:"disk_size"`
+}
+
+// StorageGrowth describes how recently the objects in a repository
+// arrived, judging by the modification times of the files that hold
+// them. Unlike commit dates, these can't be forged by whoever pushed
+// the objects.
+//
+// The times are a property of the object store, not of the history:
+// `git repack` writes a new pack containing old objects, which makes
+// them look new again, and `git gc` does the same for all of them. So
+// these numbers only measure growth if the repository's maintenance
+// schedule is taken into account.
+type StorageGrowth struct {
+	// Now is the time that the windows end.
+	Now time.Time `json:"now"`
+
+	// ObjectCount and DiskSize cover all of the objects. Objects
+	// that are stored more than once (e.g., loose and in a pack) are
+	// counted once, using their oldest copy.
+	ObjectCount counts.Count32 `json:"object_count"`
+	DiskSize    counts.Count64 `json:"disk_size"`
+
+	// Windows holds the objects that arrived within each window.
+	Windows []GrowthWindow `json:"windows"`
+
+	// OldestPack and NewestPack are the modification times of the
+	// oldest and newest packfiles, if there are any. If the newest
+	// pack holds most of the objects, a repack probably reset their
+	// clock.
+	OldestPack *time.Time `json:"oldest_pack,omitempty"`
+	NewestPack *time.Time `json:"newest_pack,omitempty"`
+}
+
+// growthObject is the oldest known copy of an object.
+type growthObject struct {
+	modTime  time.Time
+	diskSize counts.Count64
+}
+
+// ScanStorageGrowth attributes each object in `repo` (not including
+// alternates) to the modification time of the pack or loose object
+// file that holds it, and totals up the objects that arrived within
+// each of `windows` before `now`.
+func ScanStorageGrowth(
+	repo *git.Repository, now time.Time, windows []time.Duration,
+) (StorageGrowth, error) {
+	growth := StorageGrowth{Now: now}
+
+	objects := make(map[git.OID]growthObject)
+	record := func(oid git.OID, modTime time.Time, diskSize counts.Count64) {
+		if old, ok := objects[oid]; ok && !modTime.Before(old.modTime) {
+			return
+		}
+		objects[oid] = growthObject{modTime, diskSize}
+	}
+
+	packs, err := repo.Packfiles()
+	if err != nil {
+		return StorageGrowth{}, err
+	}
+	for _, pack := range packs {
+		modTime := pack.ModTime
+		if growth.OldestPack == nil || modTime.Before(*growth.OldestPack) {
+			growth.OldestPack = &modTime
+		}
+		if growth.NewestPack == nil || modTime.After(*growth.NewestPack) {
+			growth.NewestPack = &modTime
+		}
+
+		packObjects, err := repo.PackObjects(pack)
+		if err != nil {
+			return StorageGrowth{}, fmt.Errorf("reading pack index: %w", err)
+		}
+		for _, obj := range packObjects {
+			record(obj.OID, pack.ModTime, obj.DiskSize)
+		}
+	}
+
+	loose, err := repo.LooseObjects()
+	if err != nil {
+		return StorageGrowth{}, err
+	}
+	for _, obj := range loose {
+		record(obj.OID, obj.ModTime, obj.DiskSize)
+	}
+
+	growth.Windows = make([]GrowthWindow, len(windows))
+	for i, window := range windows {
+		growth.Windows[i].Window = int64(window / time.Second)
+	}
+	for _, obj := range objects {
+		growth.ObjectCount.Increment(1)
+		growth.DiskSize.Increment(obj.diskSize)
+		age := now.Sub(obj.modTime)
+		for i, window := range windows {
+			if age <= window {
+				growth.Windows[i].ObjectCount.Increment(1)
+				growth.Windows[i].DiskSize.Increment(obj.diskSize)
+			}
+		}
+	}
+
+	return growth, nil
+}
+
+// ParseGrowthWindows parses a comma-separated list of durations, like
+// "24h,7d,30d". Besides the units that `time.ParseDuration()`
+// accepts, "d" (days) and "w" (weeks) are allowed.
+func ParseGrowthWindows(s string) ([]time.Duration, error) {
+	var windows []time.Duration
+	for _, word := range strings.Split(s, ",") {
+		word = strings.TrimSpace(word)
+		var unit time.Duration
+		switch {
+		case strings.HasSuffix(word, "d"):
+			unit = 24 * time.Hour
+		case strings.HasSuffix(word, "w"):
+			unit = 7 * 24 * time.Hour
+		}
+		var window time.Duration
+		if unit != 0 {
+			n, err := strconv.ParseUint(word[:len(word)-1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid window %q", word)
+			}
+			window = time.Duration(n) * unit
+		} else {
+			var err error
+			window, err = time.ParseDuration(word)
+			if err != nil {
+				return nil, fmt.Errorf("invalid window %q", word)
+			}
+		}
+		if window <= 0 {
+			return nil, fmt.Errorf("invalid window %q", word)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// formatWindow formats `seconds` compactly; e.g., "7d" or "12h".
+func formatWindow(seconds int64) string {
+	switch {
+	case seconds%(24*60*60) == 0:
+		return fmt.Sprintf("%dd", seconds/(24*60*60))
+	case seconds%(60*60) == 0:
+		return fmt.Sprintf("%dh", seconds/(60*60))
+	default:
+		return (time.Duration(seconds) * time.Second).String()
+	}
+}
+
+// WriteStorageGrowth writes a human-readable description of `growth`
+// to `w`.
+func WriteStorageGrowth(w io.Writer, growth StorageGrowth) error {
+	format := func(n counts.Count64) string {
+		value, unit := counts.Binary.Format(n, "B")
+		return value + " " + unit
+	}
+
+	if _, err := fmt.Fprintf(
+		w, "%d objects (%s) on disk; arrived within the last:\n",
+		growth.ObjectCount, format(growth.DiskSize),
+	); err != nil {
+		return err
+	}
+	for _, window := range growth.Windows {
+		if _, err := fmt.Fprintf(
+			w, "    %-6s %8d objects  %10s\n",
+			formatWindow(window.Window), window.ObjectCount, format(window.DiskSize),
+		); err != nil {
+			return err
+		}
+	}
+	if growth.OldestPack != nil {
+		if _, err := fmt.Fprintf(
+			w, "Packs were written between %s and %s.\n",
+			growth.OldestPack.Format(time.RFC3339), growth.NewestPack.Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(
+		w,
+		"Note: these times come from the object files, so repacking makes\n"+
+			"old objects look new.\n",
+	)
+	return err
+}
@@ -0,0 +1,29 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/git"
+)
+
+// DetachedHeadRoot returns a root for the commit that `HEAD` points
+// at if `HEAD` is detached (e.g., after `git checkout <commit>`, as CI
+// systems often do). That commit might not be reachable from any
+// reference, in which case scanning only the references would miss
+// exactly what the user has checked out. If `HEAD` is a symbolic
+// reference (including in an empty repository, whose `HEAD` points
+// at an unborn branch), the second return value is false.
+func DetachedHeadRoot(repo *git.Repository) (Root, bool, error) {
+	if _, ok, err := repo.SymbolicRef("HEAD"); err != nil {
+		return nil, false, err
+	} else if ok {
+		return nil, false, nil
+	}
+
+	oid, err := repo.ResolveObject("HEAD")
+	if err != nil {
+		return nil, false, fmt.Errorf("resolving detached HEAD: %w", err)
+	}
+
+	return NewExplicitRoot("HEAD", oid), true, nil
+}
@@ -0,0 +1,28 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/git"
+)
+
+// recordHeaders records anything unusual about the headers of the
+// commit or tag `oid`. The first object found with each kind of
+// problem is kept as an example.
+func (s *HistorySize) recordHeaders(
+	g *Graph, oid git.OID, objectType string, headers git.HeaderStats,
+) {
+	if s.MaxHeaderLineLength.AdjustMaxIfNecessary(headers.MaxHeaderLineLength) {
+		setPath(g.pathResolver, &s.MaxHeaderLineLengthObject, oid, objectType)
+	}
+	if headers.UnknownHeaderCount != 0 {
+		s.UnknownHeaderObjectCount.Increment(1)
+		if s.UnknownHeaderObject == nil {
+			setPath(g.pathResolver, &s.UnknownHeaderObject, oid, objectType)
+		}
+	}
+	if headers.DuplicateHeaderCount != 0 {
+		s.DuplicateHeaderObjectCount.Increment(1)
+		if s.DuplicateHeaderObject == nil {
+			setPath(g.pathResolver, &s.DuplicateHeaderObject, oid, objectType)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package sizes
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// DefaultMaxGrowthSamples is the number of samples that
+// `AnalyzeHistoricalGrowth()` takes if no other limit is specified: a
+// year's worth of weekly samples.
+const DefaultMaxGrowthSamples = 52
+
+// SizeSample is the size of the tree of one commit in the history of
+// a reference.
+type SizeSample struct {
+	CommitOID git.OID `json:"commit"`
+
+	// Timestamp is the commit's committer date.
+	Timestamp time.Time `json:"timestamp"`
+
+	Size TreeSize `json:"size"`
+}
+
+// MarshalJSON encodes `s` with the counts of its tree size marked as
+// approximate if they saturated. See `TreeSize.Sized()`.
+func (s SizeSample) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		CommitOID git.OID       `json:"commit"`
+		Timestamp time.Time     `json:"timestamp"`
+		Size      SizedTreeSize `json:"size"`
+	}{
+		CommitOID: s.CommitOID,
+		Timestamp: s.Timestamp,
+		Size:      s.Size.Sized(),
+	})
+}
+
+// AnalyzeHistoricalGrowth samples the first-parent history of the
+// commit `ref`. The history is divided into intervals of length
+// `interval` (e.g., a week), counting from the epoch, and the most
+// recent commit in each interval is sampled, for at most
+// `maxSamples` of the most recent intervals (or
+// `DefaultMaxGrowthSamples` if it is zero). The samples are returned
+// in chronological order, so that they can be charted to find when
+// the size of a checkout jumped.
+//
+// The tree sizes are looked up in, or else computed and stored in,
+// `treeSizes`. Each sample after the first only has to read the
+// objects that are new since the previous one.
+func AnalyzeHistoricalGrowth(
+	ctx context.Context, repo *git.Repository, treeSizes CacheBackend,
+	ref git.OID, interval time.Duration, maxSamples int,
+) ([]SizeSample, error) {
+	seconds := int64(interval / time.Second)
+	if seconds <= 0 {
+		return nil, fmt.Errorf("invalid sampling interval %s", interval)
+	}
+	if maxSamples <= 0 {
+		maxSamples = DefaultMaxGrowthSamples
+	}
+
+	history, err := repo.FirstParentHistory(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// The most recent commit in each interval, keyed by the number
+	// of the interval. Of commits with the same date, the one that
+	// is closest to `ref` wins.
+	latest := make(map[int64]git.CommitTime)
+	for _, c := range history {
+		bucket := c.Time / seconds
+		if c.Time < 0 && c.Time%seconds != 0 {
+			bucket--
+		}
+		if old, ok := latest[bucket]; !ok || c.Time > old.Time {
+			latest[bucket] = c
+		}
+	}
+
+	// Keep the most recent `maxSamples` intervals, oldest first:
+	top := topn.New(maxSamples, func(b1, b2 int64) bool { return b1 < b2 })
+	for bucket := range latest {
+		top.Add(bucket)
+	}
+	buckets := top.Items()
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	samples := make([]SizeSample, 0, len(buckets))
+	var exclude []git.OID
+	for _, bucket := range buckets {
+		c := latest[bucket]
+
+		size, err := computeTreeSizeExcluding(ctx, repo, treeSizes, c.OID, exclude)
+		if errors.Is(err, ErrTreeSizeNotCached) {
+			// Some trees that are reachable from the previous
+			// sample, but not from its tree, are needed; read
+			// everything:
+			size, err = computeTreeSizeExcluding(ctx, repo, treeSizes, c.OID, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("computing the size of %s: %w", c.OID, err)
+		}
+
+		samples = append(samples, SizeSample{
+			CommitOID: c.OID,
+			Timestamp: time.Unix(c.Time, 0).UTC(),
+			Size:      size,
+		})
+		exclude = []git.OID{c.OID}
+	}
+
+	return samples, nil
+}
+
+// WriteSizeSamplesCSV writes `samples` to `w` as CSV, with a header
+// row, one row per sample, and one column per tree size metric.
+func WriteSizeSamplesCSV(w io.Writer, samples []SizeSample) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"commit", "timestamp",
+		"max_path_depth", "max_path_length",
+		"expanded_tree_count", "expanded_blob_count", "expanded_blob_size",
+		"expanded_link_count", "expanded_submodule_count",
+		"path_count", "max_tree_width", "max_tree_serialized_size",
+	}); err != nil {
+		return err
+	}
+
+	for _, sample := range samples {
+		sized := sample.Size.Sized()
+		record := []string{sample.CommitOID.String(), sample.Timestamp.Format(time.RFC3339)}
+		for _, n := range []uint64{
+			sized.MaxPathDepth.Value, sized.MaxPathLength.Value,
+			sized.ExpandedTreeCount.Value, sized.ExpandedBlobCount.Value,
+			sized.ExpandedBlobSize.Value,
+			sized.ExpandedLinkCount.Value, sized.ExpandedSubmoduleCount.Value,
+			sized.PathCount.Value, sized.MaxTreeWidth.Value,
+			sized.MaxTreeSerializedSize.Value,
+		} {
+			record = append(record, strconv.FormatUint(n, 10))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,63 @@
+package sizes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// AnalyzeIgnoredFiles returns the number and the total size of the
+// untracked files in `repo`'s working tree that are ignored (see
+// `git.Repository.ListIgnoredFiles()`). Ignored files that are big or
+// numerous are often build products or local secrets that could be
+// committed by accident with `git add -f`. Symbolic links are counted
+// with their own sizes rather than those of their targets. It returns
+// `git.ErrBareRepository` if the repository is bare.
+func AnalyzeIgnoredFiles(
+	ctx context.Context, repo *git.Repository,
+) (counts.Count32, counts.Count64, error) {
+	bare, err := repo.IsBare()
+	if err != nil {
+		return 0, 0, err
+	}
+	if bare {
+		return 0, 0, git.ErrBareRepository
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	iter, err := repo.ListIgnoredFiles(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count counts.Count32
+	var size counts.Count64
+	for {
+		path, ok, err := iter.Next()
+		if err != nil {
+			return 0, 0, fmt.Errorf("listing ignored files: %w", err)
+		}
+		if !ok {
+			return count, size, nil
+		}
+
+		fi, err := os.Lstat(filepath.Join(repo.WorkTree(), filepath.FromSlash(path)))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// It was removed in the meantime.
+				continue
+			}
+			return 0, 0, err
+		}
+		count.Increment(1)
+		size.Increment(counts.Count64(fi.Size()))
+	}
+}
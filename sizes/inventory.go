@@ -0,0 +1,434 @@
+package sizes
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// InventoryVersion is the version of the inventory file format that
+// `WriteInventoryFile()` writes and `ReadInventoryFile()` accepts.
+const InventoryVersion = 1
+
+const (
+	// DefaultInventorySampleRate is the fraction of the OIDs that are
+	// recorded in an inventory's Bloom filter if no other rate is
+	// specified.
+	DefaultInventorySampleRate = 1.0 / 16
+
+	// DefaultInventoryFalsePositiveRate is the false-positive rate
+	// that an inventory's Bloom filter is sized for if its size isn't
+	// specified.
+	DefaultInventoryFalsePositiveRate = 0.01
+
+	// DefaultMaxGrowthPercent is the growth in the number of objects
+	// of a type, as a percentage of the previous number, above which
+	// the growth is flagged as anomalous.
+	DefaultMaxGrowthPercent = 50
+)
+
+// maxBloomHashes is the largest number of hash functions that is
+// chosen automatically for an inventory's Bloom filter.
+const maxBloomHashes = 32
+
+// maxBloomHashCount is the largest number of hash functions that an
+// inventory's Bloom filter may use at all. Even that many is absurd;
+// the limit is there so that a corrupt inventory file is rejected
+// rather than taking forever to check.
+const maxBloomHashCount = 1024
+
+// inventoryTypes are the object types that an inventory reports, in
+// the order that they are reported.
+var inventoryTypes = []git.ObjectType{"blob", "tree", "commit", "tag"}
+
+// InventoryOptions controls how an inventory is taken and compared.
+// The zero value selects the defaults.
+type InventoryOptions struct {
+	// SampleRate is the fraction of the OIDs (chosen by their
+	// leading bits, so that the same OIDs are chosen each time) that
+	// are recorded in the Bloom filter.
+	SampleRate float64
+
+	// BloomBits and BloomHashes are the size of the Bloom filter and
+	// the number of hash functions that it uses. If `BloomBits` is
+	// zero, the filter is sized for the number of sampled OIDs and
+	// `FalsePositiveRate`. If only `BloomHashes` is zero, the optimal
+	// number of hash functions for the size is used.
+	BloomBits   uint64
+	BloomHashes int
+
+	// FalsePositiveRate is the false-positive rate that the Bloom
+	// filter is sized for if `BloomBits` is zero.
+	FalsePositiveRate float64
+
+	// MaxGrowthPercent is the growth in the number of objects of a
+	// type, as a percentage of the previous number, above which the
+	// growth is flagged as anomalous.
+	MaxGrowthPercent float64
+}
+
+// ObjectTypeInventory is the number of objects of a type and the
+// space that they take up on disk.
+type ObjectTypeInventory struct {
+	Count    counts.Count64 `json:"count"`
+	DiskSize counts.Count64 `json:"disk_size"`
+}
+
+// BloomParameters records how an inventory's Bloom filter was built.
+type BloomParameters struct {
+	BitCount  uint64 `json:"bit_count"`
+	HashCount int    `json:"hash_count"`
+
+	// FalsePositiveRate is the expected false-positive rate of the
+	// filter, given the number of OIDs that were added to it.
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+}
+
+// Inventory is a compact summary of a repository's object store: the
+// number of objects of each type and their size on disk, plus a Bloom
+// filter holding a sample of their OIDs. A later inventory can be
+// compared with it (see `CompareInventory()`) to tell how much the
+// store has grown, and how much of the growth consists of new
+// objects.
+type Inventory struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Types map[git.ObjectType]ObjectTypeInventory `json:"types"`
+
+	// SampleRate is the fraction of the OIDs that were added to
+	// `Filter`, and SampledCount is their number.
+	SampleRate   float64 `json:"sample_rate"`
+	SampledCount uint64  `json:"sampled_count"`
+
+	Bloom BloomParameters `json:"bloom"`
+
+	// Filter holds the bits of the Bloom filter (see
+	// `git.BloomOIDSet.MarshalBinary()`).
+	Filter []byte `json:"filter"`
+}
+
+// ObjectTypeDelta describes how the objects of one type changed
+// between two inventories.
+type ObjectTypeDelta struct {
+	ObjectType git.ObjectType `json:"object_type"`
+
+	OldCount   counts.Count64 `json:"old_count"`
+	NewCount   counts.Count64 `json:"new_count"`
+	CountDelta int64          `json:"count_delta"`
+
+	OldDiskSize   counts.Count64 `json:"old_disk_size"`
+	NewDiskSize   counts.Count64 `json:"new_disk_size"`
+	DiskSizeDelta int64          `json:"disk_size_delta"`
+
+	// GrowthPercent is `CountDelta` as a percentage of `OldCount`,
+	// or zero if `OldCount` is zero.
+	GrowthPercent float64 `json:"growth_percent"`
+
+	// SampledCount is the number of current objects whose OIDs fall
+	// into the old inventory's sample, and SampledNewCount is the
+	// number of those that the old inventory's Bloom filter doesn't
+	// contain.
+	SampledCount    uint64 `json:"sampled_count"`
+	SampledNewCount uint64 `json:"sampled_new_count"`
+
+	// EstimatedNewCount is the estimated number of current objects
+	// that didn't exist at the time of the old inventory, and
+	// EstimatedExistingCount is the estimated number of those that
+	// did (whose disk size might nevertheless have changed if they
+	// were repacked). If none of the current objects were sampled,
+	// `EstimatedNewCount` is taken to be the positive part of
+	// `CountDelta`.
+	EstimatedNewCount      counts.Count64 `json:"estimated_new_count"`
+	EstimatedExistingCount counts.Count64 `json:"estimated_existing_count"`
+
+	// Anomalous is set if `GrowthPercent` exceeds the limit.
+	Anomalous bool `json:"anomalous"`
+}
+
+// InventoryDelta compares the current state of an object store with
+// an earlier inventory.
+type InventoryDelta struct {
+	// Since is the time of the earlier inventory.
+	Since time.Time `json:"since"`
+
+	Types []ObjectTypeDelta `json:"types"`
+
+	// Anomalous is set if the growth of any type is anomalous.
+	Anomalous bool `json:"anomalous"`
+}
+
+// isSampled returns true iff `oid` falls within a sample of the given
+// rate. The decision depends only on the OID's leading bits, which
+// are effectively random, so that successive inventories sample the
+// same OIDs.
+func isSampled(oid git.OID, rate float64) bool {
+	return float64(binary.BigEndian.Uint32(oid.Bytes()[:4])) < rate*(1<<32)
+}
+
+// typeSample counts the current objects of one type that fall into
+// the old inventory's sample.
+type typeSample struct {
+	sampled    uint64
+	sampledNew uint64
+}
+
+// scanInventory takes an inventory of `repo`. If `old` is non-nil,
+// it also checks the objects that fall into `old`'s sample against
+// its Bloom filter.
+func scanInventory(
+	ctx context.Context, repo *git.Repository, opts InventoryOptions, old *Inventory,
+) (*Inventory, map[git.ObjectType]typeSample, error) {
+	sampleRate := opts.SampleRate
+	if sampleRate == 0 {
+		sampleRate = DefaultInventorySampleRate
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, nil, fmt.Errorf("invalid inventory sample rate %g", sampleRate)
+	}
+
+	if opts.BloomHashes < 0 || opts.BloomHashes > maxBloomHashCount {
+		return nil, nil, fmt.Errorf("invalid number of Bloom filter hashes %d", opts.BloomHashes)
+	}
+
+	var oldFilter *git.BloomOIDSet
+	if old != nil {
+		var err error
+		oldFilter, err = old.bloomFilter()
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading inventory: %w", err)
+		}
+	}
+
+	inv := Inventory{
+		Version:    InventoryVersion,
+		CreatedAt:  time.Now(),
+		Types:      make(map[git.ObjectType]ObjectTypeInventory),
+		SampleRate: sampleRate,
+	}
+	samples := make(map[git.ObjectType]typeSample)
+	var sampled []git.OID
+
+	err := repo.WalkStoredObjects(ctx, func(obj git.StoredObject) error {
+		ti := inv.Types[obj.ObjectType]
+		ti.Count.Increment(1)
+		ti.DiskSize.Increment(obj.DiskSize)
+		inv.Types[obj.ObjectType] = ti
+
+		if isSampled(obj.OID, sampleRate) {
+			sampled = append(sampled, obj.OID)
+		}
+
+		if oldFilter != nil && isSampled(obj.OID, old.SampleRate) {
+			s := samples[obj.ObjectType]
+			s.sampled++
+			if !oldFilter.Contains(obj.OID) {
+				s.sampledNew++
+			}
+			samples[obj.ObjectType] = s
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing objects: %w", err)
+	}
+
+	var filter *git.BloomOIDSet
+	if opts.BloomBits != 0 {
+		hashCount := opts.BloomHashes
+		if hashCount == 0 {
+			hashCount = int(math.Round(
+				float64(opts.BloomBits) / math.Max(float64(len(sampled)), 1) * math.Ln2,
+			))
+			// More hash functions than this only slow things
+			// down; they can't help much with so few OIDs:
+			if hashCount > maxBloomHashes {
+				hashCount = maxBloomHashes
+			}
+		}
+		filter = git.NewBloomOIDSetWithSize(opts.BloomBits, hashCount)
+	} else {
+		fpr := opts.FalsePositiveRate
+		if fpr == 0 {
+			fpr = DefaultInventoryFalsePositiveRate
+		}
+		filter = git.NewBloomOIDSet(uint64(len(sampled)), fpr)
+		if opts.BloomHashes != 0 {
+			filter = git.NewBloomOIDSetWithSize(filter.BitCount(), opts.BloomHashes)
+		}
+	}
+	for _, oid := range sampled {
+		filter.Add(oid)
+	}
+
+	inv.SampledCount = uint64(len(sampled))
+	inv.Bloom = BloomParameters{
+		BitCount:          filter.BitCount(),
+		HashCount:         filter.HashCount(),
+		FalsePositiveRate: filter.FalsePositiveRate(inv.SampledCount),
+	}
+	inv.Filter, err = filter.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &inv, samples, nil
+}
+
+// TakeInventory takes an inventory of the objects in `repo`'s object
+// store (including unreachable ones).
+func TakeInventory(
+	ctx context.Context, repo *git.Repository, opts InventoryOptions,
+) (*Inventory, error) {
+	inv, _, err := scanInventory(ctx, repo, opts, nil)
+	return inv, err
+}
+
+// CompareInventory takes a new inventory of `repo` and compares it
+// with `old`. It returns the new inventory (so that it can be saved
+// for the next comparison) and the differences between the two.
+//
+// The number of genuinely new objects of each type is estimated from
+// the objects whose OIDs fall into `old`'s sample: those that `old`'s
+// Bloom filter doesn't contain are new. (A new object is mistaken for
+// an old one with the filter's false-positive rate, which the
+// estimate corrects for.) This tells apart growth due to new objects
+// from changes due to repacking, which alter disk sizes but not OIDs.
+func CompareInventory(
+	ctx context.Context, repo *git.Repository, old *Inventory, opts InventoryOptions,
+) (*Inventory, *InventoryDelta, error) {
+	inv, samples, err := scanInventory(ctx, repo, opts, old)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxGrowthPercent := opts.MaxGrowthPercent
+	if maxGrowthPercent == 0 {
+		maxGrowthPercent = DefaultMaxGrowthPercent
+	}
+
+	delta := InventoryDelta{Since: old.CreatedAt}
+	for _, objectType := range inventoryTypes {
+		o, n := old.Types[objectType], inv.Types[objectType]
+		td := ObjectTypeDelta{
+			ObjectType:    objectType,
+			OldCount:      o.Count,
+			NewCount:      n.Count,
+			CountDelta:    int64(n.Count) - int64(o.Count),
+			OldDiskSize:   o.DiskSize,
+			NewDiskSize:   n.DiskSize,
+			DiskSizeDelta: int64(n.DiskSize) - int64(o.DiskSize),
+		}
+		if o.Count != 0 {
+			td.GrowthPercent = 100 * float64(td.CountDelta) / float64(o.Count)
+		}
+
+		s := samples[objectType]
+		td.SampledCount = s.sampled
+		td.SampledNewCount = s.sampledNew
+		if s.sampled != 0 {
+			fraction := float64(s.sampledNew) / float64(s.sampled) /
+				(1 - old.Bloom.FalsePositiveRate)
+			estimate := math.Round(math.Min(fraction, 1) * float64(n.Count))
+			td.EstimatedNewCount = counts.Count64(estimate)
+		} else if td.CountDelta > 0 {
+			td.EstimatedNewCount = counts.Count64(td.CountDelta)
+		}
+		td.EstimatedExistingCount = n.Count - td.EstimatedNewCount
+
+		td.Anomalous = td.GrowthPercent > maxGrowthPercent
+		if td.Anomalous {
+			delta.Anomalous = true
+		}
+		delta.Types = append(delta.Types, td)
+	}
+
+	return inv, &delta, nil
+}
+
+// WriteInventoryFile writes `inv` to the file at `path` as JSON.
+func WriteInventoryFile(path string, inv *Inventory) error {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing inventory: %w", err)
+	}
+	return nil
+}
+
+// bloomFilter recreates the Bloom filter of `inv`. The parameters are
+// checked against the data before anything is allocated, so that a
+// corrupt or malicious inventory file can't make us allocate more
+// memory than the file itself takes.
+func (inv *Inventory) bloomFilter() (*git.BloomOIDSet, error) {
+	bitCount, hashCount := inv.Bloom.BitCount, inv.Bloom.HashCount
+	if hashCount < 1 || hashCount > maxBloomHashCount {
+		return nil, fmt.Errorf("invalid Bloom filter hash count %d", hashCount)
+	}
+	if bitCount < 64 || bitCount > 8*uint64(len(inv.Filter)) {
+		return nil, fmt.Errorf(
+			"Bloom filter of %d bits doesn't match its %d bytes of data",
+			bitCount, len(inv.Filter),
+		)
+	}
+
+	filter := git.NewBloomOIDSetWithSize(bitCount, hashCount)
+	if err := filter.UnmarshalBinary(inv.Filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// ReadInventoryFile reads an inventory that was written by
+// `WriteInventoryFile()`.
+func ReadInventoryFile(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading inventory: %w", err)
+	}
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("parsing inventory %s: %w", path, err)
+	}
+	if inv.Version != InventoryVersion {
+		return nil, fmt.Errorf(
+			"inventory %s has version %d; expected %d", path, inv.Version, InventoryVersion,
+		)
+	}
+	return &inv, nil
+}
+
+// WriteInventoryDelta writes a human-readable description of `delta`
+// to `w`.
+func WriteInventoryDelta(w io.Writer, delta *InventoryDelta) error {
+	if _, err := fmt.Fprintf(
+		w, "Object store changes since %s:\n", delta.Since.Format(time.RFC3339),
+	); err != nil {
+		return err
+	}
+	for _, td := range delta.Types {
+		value, unit := counts.Binary.Format(td.NewDiskSize, "B")
+		flag := ""
+		if td.Anomalous {
+			flag = "  [anomalous growth]"
+		}
+		if _, err := fmt.Fprintf(
+			w, "    %-7s %10d objects (%+d, %+.1f%%; ~%d new)  %s %s%s\n",
+			td.ObjectType+":", td.NewCount, td.CountDelta, td.GrowthPercent,
+			td.EstimatedNewCount, value, unit, flag,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
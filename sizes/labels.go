@@ -0,0 +1,78 @@
+package sizes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReservedLabels are the label names that can't be used in
+// `ScanOptions.Labels`, because consumers of the reports already use
+// them for the repository's path and the version of the report's
+// format.
+var ReservedLabels = []string{"path", "json_version"}
+
+// ValidateLabels checks that every key of `labels` is a valid label
+// name: a nonempty string of lowercase ASCII letters, digits, and
+// underscores that is not one of `ReservedLabels`.
+func ValidateLabels(labels map[string]string) error {
+	for _, key := range sortedLabelKeys(labels) {
+		if key == "" {
+			return fmt.Errorf("invalid label name %q: it must not be empty", key)
+		}
+		for _, c := range key {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+				return fmt.Errorf(
+					"invalid label name %q: it may only contain lowercase letters, digits, and '_'",
+					key,
+				)
+			}
+		}
+		for _, reserved := range ReservedLabels {
+			if key == reserved {
+				return fmt.Errorf("invalid label name %q: it is reserved", key)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseLabel parses a label given as "NAME=VALUE" and adds it to
+// `labels`. It doesn't check that the name is valid; see
+// `ValidateLabels()`.
+func ParseLabel(labels map[string]string, s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("label %q is not of the form NAME=VALUE", s)
+	}
+	if _, ok := labels[key]; ok {
+		return fmt.Errorf("label %q is set more than once", key)
+	}
+	labels[key] = value
+	return nil
+}
+
+func sortedLabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelsHeader returns a line listing the labels of `s`, followed by
+// a blank line, for the start of the tabular outputs, or "" if there
+// are no labels.
+func (s *HistorySize) labelsHeader() string {
+	if len(s.Labels) == 0 {
+		return ""
+	}
+
+	keys := sortedLabelKeys(s.Labels)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+s.Labels[key])
+	}
+	return "Labels: " + strings.Join(pairs, ", ") + "\n\n"
+}
@@ -0,0 +1,250 @@
+package sizes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/gitattributes"
+)
+
+// lfsPointerPrefix is how every Git LFS pointer file begins.
+var lfsPointerPrefix = []byte("version https://git-lfs.github.com/spec/v1\n")
+
+// lfsPointerMaxSize is the largest size that we consider for an LFS
+// pointer file. (Real pointers are around 130 bytes.)
+const lfsPointerMaxSize = 1024
+
+// binarySniffLength is how much of a blob we look at to decide
+// whether it is binary. This is the same heuristic that git uses.
+const binarySniffLength = 8000
+
+// LFSOptions controls `AnalyzeLFS`.
+type LFSOptions struct {
+	// Tip is the commit (or anything that `git ls-tree` accepts)
+	// whose tree is analyzed.
+	Tip string
+
+	// TopN is the number of largest blobs that are checked.
+	TopN int
+
+	// Nested controls whether `.gitattributes` files in
+	// subdirectories are taken into account, too. Otherwise, only
+	// the top-level file is read.
+	Nested bool
+}
+
+// LFSBlob describes a blob that was flagged by `AnalyzeLFS`.
+type LFSBlob struct {
+	OID  git.OID        `json:"oid"`
+	Size counts.Count32 `json:"size"`
+
+	// Path is an example path at which the blob appears in the
+	// tip.
+	Path string `json:"path"`
+}
+
+// LFSReport is the result of `AnalyzeLFS`.
+type LFSReport struct {
+	// Patterns are the patterns that set `filter=lfs`, in the
+	// order that they were read.
+	Patterns []string `json:"patterns"`
+
+	// Untracked are large blobs whose paths match an LFS pattern
+	// but which are stored directly in git rather than as LFS
+	// pointers.
+	Untracked []LFSBlob `json:"untracked"`
+
+	// Unmatched are large binary blobs whose paths don't match any
+	// LFS pattern.
+	Unmatched []LFSBlob `json:"unmatched"`
+}
+
+// AnalyzeLFS compares the Git LFS patterns in the `.gitattributes`
+// file(s) at `opts.Tip` with the largest blobs in that tree, to find
+// big files that slipped past LFS.
+func AnalyzeLFS(ctx context.Context, repo *git.Repository, opts LFSOptions) (LFSReport, error) {
+	var report LFSReport
+
+	entries, err := repo.ReadTreeRecursive(opts.Tip)
+	if err != nil {
+		return LFSReport{}, err
+	}
+
+	var attributesEntries []git.TreeListEntry
+	var blobs []git.TreeListEntry
+	for _, entry := range entries {
+		if entry.ObjectType != "blob" || entry.Filemode&0o170000 != 0o100000 {
+			continue
+		}
+		blobs = append(blobs, entry)
+
+		if path.Base(entry.Path) != ".gitattributes" {
+			continue
+		}
+		if !opts.Nested && path.Dir(entry.Path) != "." {
+			continue
+		}
+		attributesEntries = append(attributesEntries, entry)
+	}
+
+	files, err := readGitattributes(ctx, repo, attributesEntries)
+	if err != nil {
+		return LFSReport{}, err
+	}
+
+	// Shallower files have lower precedence:
+	sort.SliceStable(files, func(i, j int) bool {
+		return depth(files[i]) < depth(files[j])
+	})
+
+	for _, f := range files {
+		for _, line := range f.Lines {
+			for _, a := range line.Assignments {
+				if a.Name == "filter" && a.State == gitattributes.Value && a.Value == "lfs" {
+					report.Patterns = append(report.Patterns, line.Pattern)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Size > blobs[j].Size
+	})
+
+	// Only the first path of each blob is reported:
+	seen := make(map[git.OID]bool)
+	candidates := blobs[:0]
+	for _, entry := range blobs {
+		if !seen[entry.OID] {
+			seen[entry.OID] = true
+			candidates = append(candidates, entry)
+		}
+	}
+
+	// LFS pointers don't count towards `TopN`, so we can't tell in
+	// advance how many blobs have to be read. Read them in batches
+	// of as many as are still needed; usually, one is enough.
+	checked := 0
+	for checked < opts.TopN && len(candidates) > 0 {
+		batch := candidates
+		if n := opts.TopN - checked; len(batch) > n {
+			batch = batch[:n]
+		}
+		candidates = candidates[len(batch):]
+
+		oids := make([]git.OID, len(batch))
+		for i, entry := range batch {
+			oids[i] = entry.OID
+		}
+		i := 0
+		err := repo.ReadBlobs(
+			ctx, oids, binarySniffLength,
+			func(_ git.OID, _ counts.Count32, head []byte) error {
+				entry := batch[i]
+				i++
+				if entry.Size <= lfsPointerMaxSize && bytes.HasPrefix(head, lfsPointerPrefix) {
+					return nil
+				}
+				checked++
+
+				blob := LFSBlob{
+					OID:  entry.OID,
+					Size: counts.NewCount32(entry.Size),
+					Path: entry.Path,
+				}
+				filter := gitattributes.Lookup(files, entry.Path, "filter")
+				switch {
+				case filter.State == gitattributes.Value && filter.Value == "lfs":
+					report.Untracked = append(report.Untracked, blob)
+				case filter.State == gitattributes.Unspecified && bytes.IndexByte(head, 0) != -1:
+					report.Unmatched = append(report.Unmatched, blob)
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			return LFSReport{}, err
+		}
+	}
+
+	return report, nil
+}
+
+// readGitattributes reads and parses the `.gitattributes` files in
+// `entries`, in order, using a single `git cat-file` process.
+func readGitattributes(
+	ctx context.Context, repo *git.Repository, entries []git.TreeListEntry,
+) ([]*gitattributes.File, error) {
+	oids := make([]git.OID, len(entries))
+	for i, entry := range entries {
+		oids[i] = entry.OID
+	}
+
+	files := make([]*gitattributes.File, 0, len(entries))
+	err := repo.ReadBlobs(
+		ctx, oids, 1<<20,
+		func(_ git.OID, _ counts.Count32, contents []byte) error {
+			entry := entries[len(files)]
+			dir := path.Dir(entry.Path)
+			if dir == "." {
+				dir = ""
+			}
+			f, err := gitattributes.Parse(dir, contents)
+			if err != nil {
+				return fmt.Errorf("parsing %s: %w", entry.Path, err)
+			}
+			files = append(files, f)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// depth returns the directory depth of the `.gitattributes` file `f`.
+func depth(f *gitattributes.File) int {
+	if f.Dir == "" {
+		return 0
+	}
+	return 1 + strings.Count(f.Dir, "/")
+}
+
+// WriteLFSReport writes a human-readable version of `report` to `w`.
+func WriteLFSReport(w io.Writer, report LFSReport) error {
+	if _, err := fmt.Fprintf(w, "LFS patterns: %d\n", len(report.Patterns)); err != nil {
+		return err
+	}
+	for _, pattern := range report.Patterns {
+		if _, err := fmt.Fprintf(w, "    %s\n", pattern); err != nil {
+			return err
+		}
+	}
+
+	for _, list := range []struct {
+		title string
+		blobs []LFSBlob
+	}{
+		{"Large blobs that match an LFS pattern but aren't LFS pointers", report.Untracked},
+		{"Large binary blobs that no LFS pattern covers", report.Unmatched},
+	} {
+		if _, err := fmt.Fprintf(w, "%s: %d\n", list.title, len(list.blobs)); err != nil {
+			return err
+		}
+		for _, blob := range list.blobs {
+			value, unit := counts.Binary.Format(blob.Size, "B")
+			if _, err := fmt.Fprintf(w, "    %s %s  %s\n", value, unit, blob.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package sizes
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DefaultMaxLineCountReadSize is the number of bytes of each blob that
+// `--max-line-count-check` reads.
+const DefaultMaxLineCountReadSize = 16 * 1024 * 1024
+
+// MaxBlobLineCount reads each blob that is reachable from `roots` and
+// returns the number of lines (LF characters) in the blob that has
+// the most of them, along with that blob's path. Blobs that contain
+// NUL bytes are assumed to be binary and are skipped. At most
+// `maxReadSize` bytes of each blob are read, so the line counts of
+// bigger blobs are lower bounds. Since this has to read the contents
+// of every blob, it is not part of the normal scan, but the blobs are
+// all read by a single `git cat-file --batch` process.
+func MaxBlobLineCount(
+	ctx context.Context, repo *git.Repository, roots []Root, maxReadSize int64,
+) (counts.Count32, *Path, error) {
+	var oids []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			oids = append(oids, root.OID())
+		}
+	}
+
+	var blobs []git.OID
+	paths := make(map[git.OID]string)
+	err := repo.WalkBlobPaths(ctx, oids, func(blob git.BlobPath) error {
+		blobs = append(blobs, blob.OID)
+		paths[blob.OID] = blob.Path
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var maxLines counts.Count32
+	var maxBlob *Path
+	err = repo.ReadBlobs(
+		ctx, blobs, maxReadSize,
+		func(oid git.OID, _ counts.Count32, data []byte) error {
+			if bytes.IndexByte(data, 0) != -1 {
+				return nil
+			}
+			lines := counts.NewCount32(uint64(bytes.Count(data, []byte{'\n'})))
+			if maxBlob == nil || lines > maxLines {
+				maxLines = lines
+				maxBlob = &Path{OID: oid, objectType: "blob", relativePath: paths[oid]}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return maxLines, maxBlob, nil
+}
@@ -0,0 +1,70 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DefaultMaxFilenameLength is the default limit on the length of a
+// single path component, in bytes. Many filesystems can't store
+// longer filenames.
+const DefaultMaxFilenameLength = 255
+
+// maxLongFilenames is the maximum number of distinct overlong
+// filenames that are reported as examples.
+const maxLongFilenames = 10
+
+// LongFilename describes a tree entry whose name is longer than the
+// configured limit.
+type LongFilename struct {
+	// Name is the offending filename.
+	Name string `json:"name"`
+
+	// Length is the length of `Name` in bytes.
+	Length counts.Count32 `json:"length"`
+
+	// Tree is the first tree in which the name was seen.
+	Tree *Path `json:"tree,omitempty"`
+}
+
+// registerLongFilename records that the tree `oid` contains an entry
+// called `name` that exceeds the maximum filename length.
+func (g *Graph) registerLongFilename(oid git.OID, name string) {
+	g.historyLock.Lock()
+	g.historySize.recordLongFilename(g, oid, name)
+	g.historyLock.Unlock()
+}
+
+func (s *HistorySize) recordLongFilename(g *Graph, oid git.OID, name string) {
+	s.LongFilenameCount.Increment(1)
+
+	if len(s.LongFilenames) >= maxLongFilenames {
+		return
+	}
+
+	// Each name is only reported once, even if it appears in many
+	// trees (e.g., in many versions of the same directory):
+	if _, ok := g.longFilenames[name]; ok {
+		return
+	}
+	if g.longFilenames == nil {
+		g.longFilenames = make(map[string]struct{})
+	}
+	g.longFilenames[name] = struct{}{}
+
+	lf := LongFilename{
+		Name:   name,
+		Length: counts.NewCount32(uint64(len(name))),
+	}
+	setPath(g.pathResolver, &lf.Tree, oid, "tree")
+	s.LongFilenames = append(s.LongFilenames, lf)
+}
+
+// longFilenameTree returns the tree containing the first overlong
+// filename that was found, or nil if there were none.
+func longFilenameTree(s *HistorySize) *Path {
+	if len(s.LongFilenames) == 0 {
+		return nil
+	}
+	return s.LongFilenames[0].Tree
+}
@@ -0,0 +1,190 @@
+package sizes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// UnknownMetricError is returned by `HistorySize.SelectMetrics()` if
+// it is asked for a metric that doesn't exist.
+type UnknownMetricError struct {
+	// Name is the name that was asked for.
+	Name string
+
+	// Valid are the names of all of the metrics, sorted.
+	Valid []string
+}
+
+func (err UnknownMetricError) Error() string {
+	return fmt.Sprintf(
+		"unknown metric %q; valid metrics are: %s",
+		err.Name, strings.Join(err.Valid, ", "),
+	)
+}
+
+// SelectedMetric is a metric returned by `HistorySize.SelectMetrics()`.
+type SelectedMetric struct {
+	// Name is the metric's canonical name.
+	Name string
+
+	MetricValue
+}
+
+// metricRegistry returns the metrics in `s`, keyed by their canonical
+// names. These are the symbols that `--json-version=2` uses as keys;
+// e.g., "uniqueBlobCount" or "refgroup.branches".
+func (s *HistorySize) metricRegistry(refGroups []RefGroup) map[string]*item {
+	items := make(map[string]*item)
+	s.contents(refGroups).CollectItems(items)
+	return items
+}
+
+// withAllChecks returns a copy of `s` in which each of the optional
+// checks that wasn't run is filled in as if it had found nothing, so
+// that all of the metrics are in its registry.
+func (s HistorySize) withAllChecks() HistorySize {
+	if s.MaxBlobLineCount == nil {
+		s.MaxBlobLineCount = new(counts.Count32)
+	}
+	if s.MaxFileAgeInHistory == nil {
+		s.MaxFileAgeInHistory = new(counts.Count64)
+	}
+	if s.MedianFileAgeInHistory == nil {
+		s.MedianFileAgeInHistory = new(counts.Count64)
+	}
+	if s.RepositoryConfig == nil {
+		s.RepositoryConfig = &RepositoryConfigStats{}
+	}
+	if s.Storage == nil {
+		s.Storage = &StorageBreakdown{}
+	}
+	if s.CorruptObjectCount == nil {
+		s.CorruptObjectCount = new(counts.Count32)
+	}
+	if s.Bitmap == nil {
+		s.Bitmap = &BitmapStatus{}
+	}
+	if s.DefaultBranchShare == nil {
+		s.DefaultBranchShare = &DefaultBranchShare{}
+	}
+	if s.RefKindShares == nil {
+		s.RefKindShares = &RefKindShares{}
+	}
+	return s
+}
+
+// MetricNames returns the canonical names of the metrics in `s`,
+// sorted.
+func (s *HistorySize) MetricNames(refGroups []RefGroup) []string {
+	items := s.metricRegistry(refGroups)
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricInfo describes a metric that git-sizer can report, without
+// its value.
+type MetricInfo struct {
+	// Name is the metric's canonical name.
+	Name string `json:"name"`
+
+	// Title is the metric's name in the tabular output.
+	Title string `json:"title"`
+
+	Description    string  `json:"description"`
+	Unit           string  `json:"unit"`
+	Prefixes       string  `json:"prefixes"`
+	ReferenceValue float64 `json:"referenceValue"`
+}
+
+// AvailableMetrics describes the metrics that git-sizer reports,
+// sorted by name. It is generated from the same registry as the
+// reports themselves, so it is always complete, except that the
+// metrics for reference groups are only included for `refGroups`.
+func AvailableMetrics(refGroups []RefGroup) []MetricInfo {
+	s := HistorySize{
+		ReferenceGroups: make(map[RefGroupSymbol]*counts.Count32, len(refGroups)),
+	}
+	for _, rg := range refGroups {
+		s.ReferenceGroups[rg.Symbol] = new(counts.Count32)
+	}
+	s = s.withAllChecks()
+	items := s.metricRegistry(refGroups)
+
+	metrics := make([]MetricInfo, 0, len(items))
+	for _, name := range s.MetricNames(refGroups) {
+		i := items[name]
+		metrics = append(metrics, MetricInfo{
+			Name:           name,
+			Title:          i.name,
+			Description:    i.description,
+			Unit:           i.unit,
+			Prefixes:       i.humaner.Name(),
+			ReferenceValue: i.scale,
+		})
+	}
+	return metrics
+}
+
+// SelectMetrics returns the values of the metrics named `names`, in
+// the same order. If any of the names is not the canonical name of a
+// metric, it returns an `UnknownMetricError`.
+func (s *HistorySize) SelectMetrics(
+	refGroups []RefGroup, names []string,
+) ([]SelectedMetric, error) {
+	items := s.metricRegistry(refGroups)
+
+	selected := make([]SelectedMetric, 0, len(names))
+	for _, name := range names {
+		i, ok := items[name]
+		if !ok {
+			all := s.withAllChecks()
+			if _, ok := all.metricRegistry(refGroups)[name]; ok {
+				return nil, fmt.Errorf(
+					"metric %q wasn't computed; see `--capabilities` for the option that enables it",
+					name,
+				)
+			}
+			return nil, UnknownMetricError{
+				Name:  name,
+				Valid: s.MetricNames(refGroups),
+			}
+		}
+		selected = append(selected, SelectedMetric{Name: name, MetricValue: i.metricValue()})
+	}
+
+	return selected, nil
+}
+
+// WriteSelectedMetrics writes `metrics` to `w`, one `name=value` line
+// per metric, with the raw (not humanized) values.
+func WriteSelectedMetrics(w io.Writer, metrics []SelectedMetric) error {
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "%s=%d\n", m.Name, m.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeSelectedMetrics writes `metrics` to `w` as a JSON object
+// mapping each metric's name to its raw value.
+func EncodeSelectedMetrics(w io.Writer, metrics []SelectedMetric) error {
+	values := make(map[string]uint64, len(metrics))
+	for _, m := range metrics {
+		values[m.Name] = m.Value
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	// `encoding/json` always sorts the keys of maps:
+	return enc.Encode(values)
+}
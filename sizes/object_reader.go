@@ -0,0 +1,302 @@
+package sizes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// ErrObjectNotFound is returned (wrapped) by an `ObjectReader` if the
+// object that was asked for doesn't exist in the object store.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectReader reads objects from an object store other than a local
+// repository (e.g., a network service in front of a large object
+// store). It is the counterpart of `git cat-file --batch-check` and
+// `git cat-file --batch` for `ScanObjectReader()`.
+//
+// Errors are handled according to the following contract:
+//
+//   - If the object doesn't exist, the error must wrap
+//     `ErrObjectNotFound`. This error is permanent.
+//   - If the failure might go away if the request is repeated (e.g.,
+//     a timeout or an HTTP 503), the error must be a (possibly
+//     wrapped) `TransientError`. Such reads are retried.
+//   - Any other error is treated as permanent and aborts the scan.
+//
+// Implementations must be safe for concurrent use.
+type ObjectReader interface {
+	// ReadHeader returns the type and size of the object `oid`.
+	// This is all that is needed for blobs, so it should avoid
+	// transferring the object's contents if possible.
+	ReadHeader(ctx context.Context, oid git.OID) (git.ObjectType, counts.Count32, error)
+
+	// ReadObject returns the contents of the object `oid`, in the
+	// format output by `git cat-file <type>`. It is only called for
+	// trees, commits, and tags.
+	ReadObject(ctx context.Context, oid git.OID) ([]byte, error)
+}
+
+// TransientError wraps an error from an `ObjectReader` that might not
+// happen again if the read is retried.
+type TransientError struct {
+	Err error
+}
+
+func (err TransientError) Error() string {
+	return fmt.Sprintf("transient error: %s", err.Err)
+}
+
+func (err TransientError) Unwrap() error {
+	return err.Err
+}
+
+// IsRetriable returns true iff `err` is or wraps a `TransientError`.
+func IsRetriable(err error) bool {
+	var te TransientError
+	return errors.As(err, &te)
+}
+
+// DefaultObjectReadRetries is the number of times that a read that
+// failed with a `TransientError` is retried if no other limit is
+// specified.
+const DefaultObjectReadRetries = 3
+
+// ObjectReaderOptions holds the options for `ScanObjectReader()`.
+type ObjectReaderOptions struct {
+	ScanOptions
+
+	// MaxRetries is the number of times that a read that failed with
+	// a `TransientError` is retried before giving up. If it is
+	// zero, `DefaultObjectReadRetries` is used; if it is negative,
+	// reads are not retried.
+	MaxRetries int
+
+	// RetryDelay is how long to wait before the first retry. The
+	// delay doubles for each subsequent retry. If it is zero, 100ms
+	// is used.
+	RetryDelay time.Duration
+}
+
+// ScanObjectReader computes the size statistics for the objects that
+// are reachable from the walked `roots`, reading them via `reader`
+// rather than from a local repository. The objects are fed into a
+// `SizeAccumulator` in an order that satisfies its constraints.
+func ScanObjectReader(
+	ctx context.Context, reader ObjectReader, roots []Root, opts ObjectReaderOptions,
+) (HistorySize, error) {
+	if err := ValidateLabels(opts.Labels); err != nil {
+		return HistorySize{}, err
+	}
+
+	s := objectReaderScan{
+		reader: reader,
+		opts:   opts,
+		acc:    NewSizeAccumulator(opts.ScanOptions),
+		seen:   make(map[git.OID]struct{}),
+	}
+
+	for _, root := range roots {
+		if refRoot, ok := root.(ReferenceRoot); ok {
+			s.acc.AddReference(refRoot.Reference(), refRoot.Groups())
+		}
+		if !root.Walk() {
+			continue
+		}
+		if err := s.walk(ctx, root.OID()); err != nil {
+			return HistorySize{}, err
+		}
+	}
+
+	hs, err := s.acc.Finalize()
+	if err != nil {
+		return HistorySize{}, err
+	}
+	hs.Labels = opts.Labels
+	return hs, nil
+}
+
+type objectReaderScan struct {
+	reader ObjectReader
+	opts   ObjectReaderOptions
+	acc    *SizeAccumulator
+
+	// seen contains the objects that have been read (though maybe
+	// not yet added to `acc`).
+	seen map[git.OID]struct{}
+}
+
+// pendingObject is an object on the stack of `objectReaderScan.walk()`.
+// It is added to the accumulator once everything that it refers to
+// (which is pushed above it on the stack) has been added.
+type pendingObject struct {
+	oid        git.OID
+	objectType git.ObjectType
+	size       counts.Count32
+	data       []byte
+	expanded   bool
+}
+
+// walk adds `oid` and all of the objects that are reachable from it
+// to the accumulator, dependencies first. It uses an explicit stack
+// rather than recursion, because histories can be very deep.
+func (s *objectReaderScan) walk(ctx context.Context, oid git.OID) error {
+	stack := []pendingObject{{oid: oid}}
+	for len(stack) != 0 {
+		obj := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if obj.expanded {
+			if err := s.add(obj); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := s.seen[obj.oid]; ok {
+			continue
+		}
+		s.seen[obj.oid] = struct{}{}
+
+		if err := s.read(ctx, &obj); err != nil {
+			return err
+		}
+		deps, err := dependencies(obj)
+		if err != nil {
+			return err
+		}
+
+		obj.expanded = true
+		stack = append(stack, obj)
+		for _, dep := range deps {
+			if _, ok := s.seen[dep]; !ok {
+				stack = append(stack, pendingObject{oid: dep})
+			}
+		}
+	}
+
+	return nil
+}
+
+// read fills in the type, size, and (except for blobs) contents of
+// `obj`.
+func (s *objectReaderScan) read(ctx context.Context, obj *pendingObject) error {
+	if err := s.retry(ctx, func() error {
+		var err error
+		obj.objectType, obj.size, err = s.reader.ReadHeader(ctx, obj.oid)
+		return err
+	}); err != nil {
+		return fmt.Errorf("reading header of object %s: %w", obj.oid, err)
+	}
+
+	if obj.objectType == "blob" {
+		return nil
+	}
+
+	if err := s.retry(ctx, func() error {
+		var err error
+		obj.data, err = s.reader.ReadObject(ctx, obj.oid)
+		return err
+	}); err != nil {
+		return fmt.Errorf("reading %s %s: %w", obj.objectType, obj.oid, err)
+	}
+
+	return nil
+}
+
+// retry calls `f` until it succeeds, fails with an error that is not
+// retriable, or has been retried `MaxRetries` times.
+func (s *objectReaderScan) retry(ctx context.Context, f func() error) error {
+	maxRetries := s.opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultObjectReadRetries
+	}
+	delay := s.opts.RetryDelay
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || !IsRetriable(err) || attempt >= maxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// dependencies returns the objects that have to be added to a
+// `SizeAccumulator` before `obj`.
+func dependencies(obj pendingObject) ([]git.OID, error) {
+	switch obj.objectType {
+	case "blob":
+		return nil, nil
+	case "tree":
+		tree, err := git.ParseTree(obj.oid, obj.data)
+		if err != nil {
+			return nil, err
+		}
+		var deps []git.OID
+		iter := tree.Iter()
+		for {
+			entry, ok, err := iter.NextEntry()
+			if err != nil {
+				return nil, fmt.Errorf("parsing tree %s: %w", obj.oid, err)
+			}
+			if !ok {
+				return deps, nil
+			}
+			switch entry.Filemode & 0o170000 {
+			case 0o160000, 0o120000:
+				// Submodules aren't in this object store, and
+				// symlinks' sizes aren't used.
+			default:
+				deps = append(deps, entry.OID)
+			}
+		}
+	case "commit":
+		commit, err := git.ParseCommit(obj.oid, obj.data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]git.OID{commit.Tree}, commit.Parents...), nil
+	case "tag":
+		tag, err := git.ParseTag(obj.oid, obj.data)
+		if err != nil {
+			return nil, err
+		}
+		return []git.OID{tag.Referent}, nil
+	default:
+		return nil, fmt.Errorf("object %s has unexpected type %q", obj.oid, obj.objectType)
+	}
+}
+
+// add adds `obj`, whose dependencies have all been added already, to
+// the accumulator.
+func (s *objectReaderScan) add(obj pendingObject) error {
+	switch obj.objectType {
+	case "blob":
+		s.acc.AddBlob(obj.oid, obj.size)
+		return nil
+	case "tree":
+		return s.acc.AddTree(obj.oid, obj.data)
+	case "commit":
+		return s.acc.AddCommit(obj.oid, obj.data)
+	case "tag":
+		return s.acc.AddTag(obj.oid, obj.data)
+	default:
+		return fmt.Errorf("object %s has unexpected type %q", obj.oid, obj.objectType)
+	}
+}
@@ -17,16 +17,23 @@ func (s BlobSize) String() string {
 	return fmt.Sprintf("blob_size=%d", s.Size)
 }
 
+// String returns the counts of `s`, with `~` appended to any that
+// saturated.
 func (s TreeSize) String() string {
+	sized := s.Sized()
 	return fmt.Sprintf(
-		"max_path_depth=%d, max_path_length=%d, "+
-			"expanded_tree_count=%d, "+
-			"expanded_blob_count=%d, expanded_blob_size=%d, "+
-			"expanded_link_count=%d, expanded_submodule_count=%d",
-		s.MaxPathDepth, s.MaxPathLength,
-		s.ExpandedTreeCount,
-		s.ExpandedBlobCount, s.ExpandedBlobSize,
-		s.ExpandedLinkCount, s.ExpandedSubmoduleCount,
+		"max_path_depth=%s, max_path_length=%s, "+
+			"expanded_tree_count=%s, "+
+			"expanded_blob_count=%s, expanded_blob_size=%s, "+
+			"expanded_link_count=%s, expanded_submodule_count=%s, "+
+			"expanded_unix_socket_count=%s, expanded_unknown_filetype_count=%s, "+
+			"path_count=%s",
+		sized.MaxPathDepth, sized.MaxPathLength,
+		sized.ExpandedTreeCount,
+		sized.ExpandedBlobCount, sized.ExpandedBlobSize,
+		sized.ExpandedLinkCount, sized.ExpandedSubmoduleCount,
+		sized.ExpandedUnixSocketCount, sized.ExpandedUnknownFiletypeCount,
+		sized.PathCount,
 	)
 }
 
@@ -116,7 +123,11 @@ type item struct {
 	value       counts.Humanable
 	humaner     counts.Humaner
 	unit        string
-	scale       float64
+
+	// scale is the value at which the item starts to be concerning.
+	// Zero means that the item is only informational, and has no
+	// level of concern at all.
+	scale float64
 }
 
 func newItem(
@@ -174,11 +185,15 @@ func (i *item) Footnote(nameStyle NameStyle) string {
 // return the string that should be used as its "level of concern" and
 // `true`; otherwise, return `"", false`.
 func (i *item) levelOfConcern(threshold Threshold) (string, bool) {
-	value, overflow := i.value.ToUint64()
+	if i.scale == 0 {
+		// Informational items are only added to the contents
+		// if they were computed, so they are always shown:
+		return "", true
+	}
+	alert, overflow := i.alert()
 	if overflow {
 		return "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!", true
 	}
-	alert := Threshold(float64(value) / i.scale)
 	if alert < threshold {
 		return "", false
 	}
@@ -188,13 +203,35 @@ func (i *item) levelOfConcern(threshold Threshold) (string, bool) {
 	return stars[:int(alert)], true
 }
 
+// alert returns this item's level of concern, and whether its value
+// overflowed. Informational items always have a level of zero.
+func (i *item) alert() (Threshold, bool) {
+	if i.scale == 0 {
+		return 0, false
+	}
+	value, overflow := i.value.ToUint64()
+	if overflow {
+		return 0, true
+	}
+	return Threshold(float64(value) / i.scale), false
+}
+
+// concernRatio returns `value` divided by this item's scale, or zero
+// if the item is informational.
+func (i *item) concernRatio(value uint64) float64 {
+	if i.scale == 0 {
+		return 0
+	}
+	return float64(value) / i.scale
+}
+
 func (i *item) CollectItems(items map[string]*item) {
 	items[i.symbol] = i
 }
 
 func (i *item) MarshalJSON() ([]byte, error) {
 	// How we want to emit an item as JSON.
-	value, _ := i.value.ToUint64()
+	value, overflow := i.value.ToUint64()
 
 	stat := struct {
 		Description       string  `json:"description"`
@@ -203,6 +240,8 @@ func (i *item) MarshalJSON() ([]byte, error) {
 		Prefixes          string  `json:"prefixes"`
 		ReferenceValue    float64 `json:"referenceValue"`
 		LevelOfConcern    float64 `json:"levelOfConcern"`
+		Saturated         bool    `json:"saturated,omitempty"`
+		Approximate       bool    `json:"approximate,omitempty"`
 		ObjectName        string  `json:"objectName,omitempty"`
 		ObjectDescription string  `json:"objectDescription,omitempty"`
 	}{
@@ -211,7 +250,11 @@ func (i *item) MarshalJSON() ([]byte, error) {
 		Unit:           i.unit,
 		Prefixes:       i.humaner.Name(),
 		ReferenceValue: i.scale,
-		LevelOfConcern: float64(value) / i.scale,
+		LevelOfConcern: i.concernRatio(value),
+		Saturated:      overflow,
+	}
+	if sized, ok := i.value.(counts.SizedCount); ok {
+		stat.Approximate = sized.Approximate
 	}
 
 	if i.path != nil && i.path.OID != git.NullOID {
@@ -385,10 +428,12 @@ func (s *HistorySize) TableString(
 	contents.Emit(&t)
 
 	if t.buf.Len() == 0 {
-		return "No problems above the current threshold were found\n"
+		return s.labelsHeader() + "No problems above the current threshold were found\n" +
+			s.problemsTrailer(refGroups)
 	}
 
-	return t.generateHeader() + t.buf.String() + t.footnotes.String()
+	return s.labelsHeader() + t.generateHeader() + t.buf.String() + t.footnotes.String() +
+		s.problemsTrailer(refGroups)
 }
 
 func (t *table) indented(sectionHeader string, depth int) *table {
@@ -460,7 +505,22 @@ func (s *HistorySize) JSON(
 	contents := s.contents(refGroups)
 	items := make(map[string]*item)
 	contents.CollectItems(items)
-	j, err := json.MarshalIndent(items, "", "    ")
+
+	output := make(map[string]interface{}, len(items)+2)
+	for name, item := range items {
+		output[name] = item
+	}
+	if s.Repository != nil {
+		output["repository"] = s.Repository
+	}
+	if len(s.Labels) != 0 {
+		output["labels"] = s.Labels
+	}
+	if problems := s.Problems(refGroups); len(problems) != 0 {
+		output["problems"] = problems
+	}
+
+	j, err := json.MarshalIndent(output, "", "    ")
 	return j, err
 }
 
@@ -470,6 +530,10 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 	metric := counts.Metric
 	binary := counts.Binary
 
+	// The checkout maxima are approximate if the tree that achieved
+	// them saturated:
+	checkout := s.MaxTreeSaturated.sized
+
 	//nolint:prealloc // The length is not known in advance.
 	var rgis []tableContents
 	for _, rg := range refGroups {
@@ -489,6 +553,114 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 		rgis = append(rgis, rgi.Indented(indent))
 	}
 
+	// The optional checks only contribute items if they were run, so
+	// that a check that was skipped isn't reported as having found
+	// nothing:
+	blobs := []tableContents{
+		I("maxBlobSize", "Maximum size",
+			"The size of the largest blob object",
+			s.MaxBlobSizeBlob, s.MaxBlobSize, binary, "B", 10e6),
+	}
+	if s.MaxBlobLineCount != nil {
+		blobs = append(blobs,
+			I("maxBlobLineCount", "Maximum line count",
+				"The number of lines in the text blob with the most lines (only checked with `--max-line-count-check`)",
+				s.MaxBlobLineCountBlob, *s.MaxBlobLineCount, metric, "", 100e3),
+		)
+	}
+	if s.MaxFileAgeInHistory != nil && s.MedianFileAgeInHistory != nil {
+		blobs = append(blobs,
+			I("maxFileAgeInHistory", "Oldest file (days)",
+				"The number of days since the oldest blob was introduced (only checked with `--file-ages`)",
+				s.MaxFileAgeInHistoryBlob, *s.MaxFileAgeInHistory, metric, "", 0),
+			I("medianFileAgeInHistory", "Median file age (days)",
+				"The median number of days since each blob was introduced (only checked with `--file-ages`)",
+				nil, *s.MedianFileAgeInHistory, metric, "", 0),
+		)
+	}
+	blobs = append(blobs,
+		I("emptyBlobCount", "Empty file entries",
+			"The number of tree entries that refer to the empty blob (e.g., placeholder files)",
+			nil, s.EmptyBlobCount, metric, "", 5000),
+	)
+
+	var repositoryConfig []tableContents
+	if s.RepositoryConfig != nil {
+		repositoryConfig = append(repositoryConfig,
+			I("defaultBranchMissing", "Missing default branch",
+				"1 if HEAD points at a branch that doesn't exist (only checked with `--check-symrefs`)",
+				nil, s.RepositoryConfig.DefaultBranchMissing, metric, "", 0.1),
+			I("detachedHead", "Detached HEAD",
+				"1 if HEAD is detached in a bare repository (only checked with `--check-symrefs`)",
+				nil, s.RepositoryConfig.DetachedHead, metric, "", 0.1),
+			I("danglingSymbolicRefCount", "Dangling symbolic refs",
+				"The number of symbolic references whose targets don't exist (only checked with `--check-symrefs`)",
+				nil, s.RepositoryConfig.DanglingSymbolicRefCount, metric, "", 1),
+		)
+	}
+
+	var storage []tableContents
+	if s.Storage != nil {
+		storage = append(storage,
+			I("redundantLooseObjectCount", "Redundant loose objects",
+				"The number of loose objects that are also packed (run `git prune-packed` to remove them; only checked with `--check-storage`)",
+				nil, s.Storage.RedundantLooseObjectCount, metric, "", 1000),
+			I("redundantLooseObjectSize", "Redundant loose size",
+				"The disk space used by loose objects that are also packed (run `git prune-packed` to remove them; only checked with `--check-storage`)",
+				nil, s.Storage.RedundantLooseObjectSize, binary, "B", 10e6),
+		)
+	}
+	if s.CorruptObjectCount != nil {
+		storage = append(storage,
+			I("corruptObjectCount", "Corrupt objects",
+				"The number of objects whose contents don't match their OIDs (only checked with `--verify-oids`)",
+				nil, *s.CorruptObjectCount, metric, "", 0.1),
+		)
+	}
+	if s.Bitmap != nil {
+		storage = append(storage,
+			I("bitmapCommitCount", "Bitmapped commits",
+				"The number of commits in the reachability bitmap (only checked with `--bitmap-status`)",
+				nil, s.Bitmap.CommitCount, metric, "", 10e6),
+			I("bitmapBitCount", "Bitmapped objects",
+				"The number of objects covered by the reachability bitmap (only checked with `--bitmap-status`)",
+				nil, s.Bitmap.BitCount, metric, "", 1e9),
+			I("missingBitmap", "Missing bitmap",
+				"1 if there is no reachability bitmap even though there are more than 100 k commits, which makes clones slow (only checked with `--bitmap-status`)",
+				nil, s.missingBitmap(), metric, "", 0.1),
+		)
+	}
+
+	var defaultBranch []tableContents
+	if s.DefaultBranchShare != nil {
+		defaultBranch = append(defaultBranch,
+			I("otherRefsBlobSize", "Other-ref-only blob size",
+				"The size of the blobs that are reachable from other references but not from the default branch (only checked with `--default-branch`)",
+				nil, s.DefaultBranchShare.OtherRefsBlobSize, binary, "B", 10e9),
+			I("otherRefsBlobPercent", "Other-ref-only blob share",
+				"The percentage of blob data that isn't reachable from the default branch (if it is large, consider archiving or deleting other references; only checked with `--default-branch`)",
+				nil, s.DefaultBranchShare.OtherRefsBlobPercent, metric, "%", 50),
+		)
+	}
+
+	var refKinds []tableContents
+	if s.RefKindShares != nil {
+		refKinds = append(refKinds,
+			I("branchObjectSize", "Branch-reachable size",
+				"The size of the objects that are reachable from any branch (only checked with `--ref-kinds`)",
+				nil, s.RefKindShares.Branches.ObjectSize, binary, "B", 1e12),
+			I("tagOnlyObjectSize", "Tag-only size",
+				"The size of the objects that are reachable from tags but not from any branch (only checked with `--ref-kinds`)",
+				nil, s.RefKindShares.TagsOnly.ObjectSize, binary, "B", 1e12),
+			I("otherRefOnlyObjectSize", "Other-ref-only size",
+				"The size of the objects that are reachable only from references other than branches and tags, like `refs/stash`, notes, or pull-request references (only checked with `--ref-kinds`)",
+				nil, s.RefKindShares.OtherOnly.ObjectSize, binary, "B", 10e9),
+			I("otherRefOnlyObjectPercent", "Other-ref-only share",
+				"The percentage of object data that is reachable only from references other than branches and tags (only checked with `--ref-kinds`)",
+				nil, s.RefKindShares.OtherOnlyPercent, metric, "%", 50),
+		)
+	}
+
 	return S(
 		"",
 		S(
@@ -524,6 +696,12 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 				I("uniqueBlobSize", "Total size",
 					"The total size of all distinct blob objects",
 					nil, s.UniqueBlobSize, binary, "B", 10e9),
+				I("referencedBlobSize", "Referenced size",
+					"The total size of the blobs referred to by the entries of all distinct trees, counting a blob once per distinct tree entry",
+					nil, s.ReferencedBlobSize, binary, "B", 1e12),
+				I("dedupPercent", "Deduplication ratio",
+					"The referenced size as a percentage of the total size (e.g., 300% means that each blob is referenced three times on average)",
+					nil, s.dedupPercent(), metric, "%", 1e6),
 			),
 
 			S(
@@ -531,6 +709,19 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 				I("uniqueTagCount", "Count",
 					"The total number of annotated tags",
 					nil, s.UniqueTagCount, metric, "", 25e3),
+				I("uniqueTagSize", "Total size",
+					"The total size of all annotated tag objects",
+					nil, s.UniqueTagSize, binary, "B", 25e6),
+			),
+
+			S(
+				"All objects",
+				I("totalObjectDataSize", "Total size",
+					"The total uncompressed size of all distinct objects (an upper bound on the size of a clone before compression)",
+					nil, s.TotalObjectDataSize(), binary, "B", 12e9),
+				I("edgeCount", "Edges",
+					"The total number of references from one object to another (tree entries, commit trees and parents, and tag targets)",
+					nil, s.EdgeCount, metric, "", 50e6),
 			),
 
 			S(
@@ -559,13 +750,27 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 				I("maxTreeEntries", "Maximum entries",
 					"The most entries in any single tree",
 					s.MaxTreeEntriesTree, s.MaxTreeEntries, metric, "", 1000),
+				I("wideTreeCount", "Overly wide trees",
+					"The number of distinct trees with so many entries that Git gets slow",
+					wideTreeTree(s), s.WideTreeCount, metric, "", 1),
+				I("longFilenameCount", "Overlong filenames",
+					"The number of tree entries whose names are too long for many filesystems",
+					longFilenameTree(s), s.LongFilenameCount, metric, "", 1),
+				I("mixedTypeCaseCollisionCount", "Mixed-type collisions",
+					"The number of tree entries whose names differ only in case from an entry of another type (e.g., a symlink and a file) in the same tree",
+					mixedTypeCaseCollisionTree(s), s.MixedTypeCaseCollisionCount, metric, "", 1),
+				I("unixSocketCount", "Unix sockets",
+					"The number of tree entries with the mode of a Unix socket, which Git never creates and which can't be checked out on Windows",
+					nil, s.UnixSocketCount, metric, "", 1),
+				I("unknownFiletypeCount", "Unknown file types",
+					"The number of tree entries whose modes aren't of any type that Git knows about",
+					nil, s.UnknownFiletypeCount, metric, "", 1),
+				I("emptyTreeCount", "Empty tree entries",
+					"The number of tree entries that refer to the empty tree",
+					nil, s.EmptyTreeCount, metric, "", 100),
 			),
 
-			S("Blobs",
-				I("maxBlobSize", "Maximum size",
-					"The size of the largest blob object",
-					s.MaxBlobSizeBlob, s.MaxBlobSize, binary, "B", 10e6),
-			),
+			S("Blobs", blobs...),
 		),
 
 		S("History structure",
@@ -577,31 +782,92 @@ func (s *HistorySize) contents(refGroups []RefGroup) tableContents {
 				s.MaxTagDepthTag, s.MaxTagDepth, metric, "", 1.001),
 		),
 
+		S("Object headers",
+			I("maxHeaderLineLength", "Longest header line",
+				"The longest header line in any commit or tag",
+				s.MaxHeaderLineLengthObject, s.MaxHeaderLineLength, binary, "B", 5000),
+			I("unknownHeaderObjectCount", "Unknown headers",
+				"The number of commits and tags with headers that git doesn't know",
+				s.UnknownHeaderObject, s.UnknownHeaderObjectCount, metric, "", 1),
+			I("duplicateHeaderObjectCount", "Duplicate headers",
+				"The number of commits and tags with headers that are repeated more often than git allows",
+				s.DuplicateHeaderObject, s.DuplicateHeaderObjectCount, metric, "", 1),
+		),
+
 		S("Biggest checkouts",
 			I("maxCheckoutTreeCount", "Number of directories",
 				"The number of directories in the largest checkout",
-				s.MaxExpandedTreeCountTree, s.MaxExpandedTreeCount, metric, "", 2000),
+				s.MaxExpandedTreeCountTree,
+				checkout(s.MaxExpandedTreeCount, SaturatedExpandedTreeCount),
+				metric, "", 2000),
+			I("maxCheckoutTreeWidth", "Maximum tree width",
+				"The maximum number of directories at any single depth in any checkout, counting a directory once for each path at which it appears",
+				s.MaxTreeWidthTree,
+				checkout(s.MaxTreeWidth, SaturatedMaxTreeWidth),
+				metric, "", 1000),
 			I("maxCheckoutPathDepth", "Maximum path depth",
 				"The maximum path depth in any checkout",
-				s.MaxPathDepthTree, s.MaxPathDepth, metric, "", 10),
+				s.MaxPathDepthTree,
+				checkout(s.MaxPathDepth, SaturatedMaxPathDepth),
+				metric, "", 10),
 			I("maxCheckoutPathLength", "Maximum path length",
 				"The maximum path length in any checkout",
-				s.MaxPathLengthTree, s.MaxPathLength, binary, "B", 100),
+				s.MaxPathLengthTree,
+				checkout(s.MaxPathLength, SaturatedMaxPathLength),
+				binary, "B", 100),
 
 			I("maxCheckoutBlobCount", "Number of files",
 				"The maximum number of files in any checkout",
-				s.MaxExpandedBlobCountTree, s.MaxExpandedBlobCount, metric, "", 50e3),
+				s.MaxExpandedBlobCountTree,
+				checkout(s.MaxExpandedBlobCount, SaturatedExpandedBlobCount),
+				metric, "", 50e3),
 			I("maxCheckoutBlobSize", "Total size of files",
 				"The maximum sum of file sizes in any checkout",
-				s.MaxExpandedBlobSizeTree, s.MaxExpandedBlobSize, binary, "B", 1e9),
+				s.MaxExpandedBlobSizeTree,
+				checkout(s.MaxExpandedBlobSize, SaturatedExpandedBlobSize),
+				binary, "B", 1e9),
 
 			I("maxCheckoutLinkCount", "Number of symlinks",
 				"The maximum number of symlinks in any checkout",
-				s.MaxExpandedLinkCountTree, s.MaxExpandedLinkCount, metric, "", 25e3),
+				s.MaxExpandedLinkCountTree,
+				checkout(s.MaxExpandedLinkCount, SaturatedExpandedLinkCount),
+				metric, "", 25e3),
 
 			I("maxCheckoutSubmoduleCount", "Number of submodules",
 				"The maximum number of submodules in any checkout",
-				s.MaxExpandedSubmoduleCountTree, s.MaxExpandedSubmoduleCount, metric, "", 100),
+				s.MaxExpandedSubmoduleCountTree,
+				checkout(s.MaxExpandedSubmoduleCount, SaturatedExpandedSubmoduleCount),
+				metric, "", 100),
+
+			I("maxCheckoutPathCount", "Number of paths",
+				"The maximum number of paths (files, symlinks, and submodules) in any checkout",
+				s.MaxPathCountTree, checkout(s.MaxPathCount, SaturatedPathCount), metric, "", 50e3),
+		),
+
+		S("References by kind",
+			I("branchCount", "Branches",
+				"The number of branches (refs/heads/*)",
+				nil, s.RefStats.BranchCount, metric, "", 25e3),
+			I("tagCount", "Tags",
+				"The number of tags (refs/tags/*)",
+				nil, s.RefStats.TagCount, metric, "", 100e3),
+			I("remoteTrackingRefCount", "Remote-tracking references",
+				"The number of remote-tracking references (refs/remotes/*)",
+				nil, s.RefStats.RemoteTrackingRefCount, metric, "", 50e3),
+			I("otherRefCount", "Other references",
+				"The number of references that are not branches, tags, or remote-tracking references",
+				nil, s.RefStats.OtherRefCount, metric, "", 100e3),
+			I("allRefCount", "All references",
+				"The total number of references, of any kind",
+				nil, s.RefStats.AllRefCount, metric, "", 100e3),
 		),
+
+		S("Repository configuration", repositoryConfig...),
+
+		S("Storage", storage...),
+
+		S("Default branch", defaultBranch...),
+
+		S("Reference kinds", refKinds...),
 	)
 }
@@ -0,0 +1,172 @@
+package sizes
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// PackStats describes the contents of a single packfile.
+type PackStats struct {
+	// Name is the filename of the pack (e.g., "pack-1234abcd.pack").
+	Name string `json:"name"`
+
+	// DiskSize is the size of the `.pack` file.
+	DiskSize counts.Count64 `json:"disk_size"`
+
+	// ObjectCount is the number of objects in the pack.
+	ObjectCount counts.Count32 `json:"object_count"`
+
+	// ContentSize is the total uncompressed size of the objects in
+	// the pack, as `git cat-file` would report them.
+	ContentSize counts.Count64 `json:"content_size"`
+
+	// DuplicateObjectCount is the number of the pack's objects that
+	// are also stored in at least one other pack. A repack would
+	// keep only one copy of each.
+	DuplicateObjectCount counts.Count32 `json:"duplicate_object_count"`
+
+	// InMultiPackIndex is true if the repository's multi-pack index
+	// covers this pack.
+	InMultiPackIndex bool `json:"in_multi_pack_index"`
+}
+
+// PackReport describes how a repository's objects are distributed
+// among its packfiles.
+type PackReport struct {
+	// Packs holds the packfiles (not including those in alternates),
+	// largest first.
+	Packs []PackStats `json:"packs"`
+
+	// HasMultiPackIndex is true if the repository has a multi-pack
+	// index. Such an index spans several packs, and maps each object
+	// that is stored in more than one of them to just one copy.
+	HasMultiPackIndex bool `json:"has_multi_pack_index"`
+
+	// MultiPackIndexPackCount is the number of packs that the
+	// multi-pack index covers. Packs written after the index was
+	// aren't covered, so this can be less than `len(Packs)`.
+	MultiPackIndexPackCount int `json:"multi_pack_index_pack_count,omitempty"`
+}
+
+// ScanPacks reports, for each packfile in `repo`, its size, the
+// number of objects that it contains, and their total uncompressed
+// size. Each pack's objects are read from its own `.idx` file, so an
+// object that is stored in several packs counts towards each of them
+// (see `PackStats.DuplicateObjectCount`), even if a multi-pack index
+// chooses one of the copies.
+func ScanPacks(repo *git.Repository) (PackReport, error) {
+	var report PackReport
+
+	midxPacks, ok, err := repo.MultiPackIndexPacks()
+	if err != nil {
+		return PackReport{}, err
+	}
+	report.HasMultiPackIndex = ok
+	report.MultiPackIndexPackCount = len(midxPacks)
+	inMIDX := make(map[string]bool, len(midxPacks))
+	for _, name := range midxPacks {
+		inMIDX[strings.TrimSuffix(name, ".idx")] = true
+	}
+
+	packs, err := repo.Packfiles()
+	if err != nil {
+		return PackReport{}, err
+	}
+
+	// The number of packs that each object appears in:
+	packCounts := make(map[git.OID]int)
+	packObjects := make([][]git.PackObject, len(packs))
+	for i, pack := range packs {
+		objects, err := repo.PackObjects(pack)
+		if err != nil {
+			return PackReport{}, fmt.Errorf("reading pack index: %w", err)
+		}
+		packObjects[i] = objects
+		for _, obj := range objects {
+			packCounts[obj.OID]++
+		}
+	}
+
+	report.Packs = make([]PackStats, len(packs))
+	for i, pack := range packs {
+		name := filepath.Base(pack.Path)
+		stats := PackStats{
+			Name:             name,
+			DiskSize:         pack.DiskSize,
+			ObjectCount:      counts.NewCount32(uint64(len(packObjects[i]))),
+			InMultiPackIndex: inMIDX[strings.TrimSuffix(name, ".pack")],
+		}
+
+		oids := make([]git.OID, len(packObjects[i]))
+		for j, obj := range packObjects[i] {
+			oids[j] = obj.OID
+			if packCounts[obj.OID] > 1 {
+				stats.DuplicateObjectCount.Increment(1)
+			}
+		}
+		headers, err := repo.GetObjectBatchCheckDedup(oids)
+		if err != nil {
+			return PackReport{}, fmt.Errorf("reading the objects in %s: %w", name, err)
+		}
+		for _, header := range headers {
+			stats.ContentSize.Increment(counts.Count64(header.ObjectSize))
+		}
+
+		report.Packs[i] = stats
+	}
+
+	sort.Slice(report.Packs, func(i, j int) bool {
+		pi, pj := report.Packs[i], report.Packs[j]
+		if pi.DiskSize != pj.DiskSize {
+			return pi.DiskSize > pj.DiskSize
+		}
+		return pi.Name < pj.Name
+	})
+
+	return report, nil
+}
+
+// WritePackReport writes a human-readable description of `report` to
+// `w`.
+func WritePackReport(w io.Writer, report PackReport) error {
+	format := func(n counts.Count64) string {
+		value, unit := counts.Binary.Format(n, "B")
+		return value + " " + unit
+	}
+
+	if len(report.Packs) == 0 {
+		_, err := fmt.Fprintf(w, "There are no packfiles.\n")
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%d packs, largest first:\n", len(report.Packs)); err != nil {
+		return err
+	}
+	for _, p := range report.Packs {
+		midx := ""
+		if p.InMultiPackIndex {
+			midx = " [midx]"
+		}
+		if _, err := fmt.Fprintf(
+			w, "    %s%s\n        %10s on disk  %8d objects (%d also in other packs)  %10s of content\n",
+			p.Name, midx, format(p.DiskSize), p.ObjectCount, p.DuplicateObjectCount,
+			format(p.ContentSize),
+		); err != nil {
+			return err
+		}
+	}
+	if report.HasMultiPackIndex {
+		if _, err := fmt.Fprintf(
+			w, "The multi-pack index covers %d of the packs (marked [midx]).\n",
+			report.MultiPackIndexPackCount,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// DefaultMaxPathsPerObject is the number of paths that a `PathList`
+// retains if no other limit is specified.
+const DefaultMaxPathsPerObject = 10
+
+// PathList records the paths at which an object appears. A blob can
+// appear at an enormous number of paths (e.g., a license file that is
+// vendored into every directory), so only the first few paths are
+// retained; the rest are only counted. That bounds the memory that it
+// takes to track the paths of many objects.
+type PathList struct {
+	// Count is the number of paths that were added, including those
+	// that weren't retained.
+	Count counts.Count32 `json:"count"`
+
+	// Paths are the first paths that were added, in the order that
+	// they were added.
+	Paths []string `json:"paths"`
+
+	// max is the maximum length of `Paths`.
+	max int
+}
+
+// NewPathList returns an empty `PathList` that retains at most `max`
+// paths, or `DefaultMaxPathsPerObject` if `max` is zero.
+func NewPathList(max int) *PathList {
+	if max == 0 {
+		max = DefaultMaxPathsPerObject
+	}
+	return &PathList{max: max}
+}
+
+// Add records that the object appears at `path`. A path that is
+// already retained is ignored. Other repeats can't be recognized, so
+// once some paths haven't been retained, `Count` is only an upper
+// bound.
+func (pl *PathList) Add(path string) {
+	for _, p := range pl.Paths {
+		if p == path {
+			return
+		}
+	}
+	pl.Count.Increment(1)
+	if len(pl.Paths) < pl.max {
+		pl.Paths = append(pl.Paths, path)
+	}
+}
+
+// Truncated returns true iff some of the paths weren't retained.
+func (pl *PathList) Truncated() bool {
+	return int(pl.Count) > len(pl.Paths)
+}
+
+// String describes the number of paths; e.g., "appears at 1000000
+// paths (showing 10)".
+func (pl *PathList) String() string {
+	noun := "paths"
+	if pl.Count == 1 {
+		noun = "path"
+	}
+	if pl.Truncated() {
+		return fmt.Sprintf("appears at %d %s (showing %d)", pl.Count, noun, len(pl.Paths))
+	}
+	return fmt.Sprintf("appears at %d %s", pl.Count, noun)
+}
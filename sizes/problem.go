@@ -0,0 +1,468 @@
+package sizes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// ProblemCode is a stable identifier for a kind of problem that a scan
+// can detect. Codes have the form "<area>.<problem>". Once published,
+// a code doesn't change, so tools can use it to route problems.
+type ProblemCode string
+
+const (
+	// ProblemLongFilename is a tree entry whose name is longer than
+	// `ScanOptions.MaxFilenameLength`.
+	ProblemLongFilename ProblemCode = "name.too_long"
+
+	// ProblemWideTree is a tree with more entries than
+	// `ScanOptions.WideTreeEntries`.
+	ProblemWideTree ProblemCode = "tree.too_many_entries"
+
+	// ProblemEmptyTreeEntry is a tree entry that refers to the empty
+	// tree.
+	ProblemEmptyTreeEntry ProblemCode = "tree.empty_tree_entry"
+
+	// ProblemEmptyFilename is a tree entry whose name is empty.
+	ProblemEmptyFilename ProblemCode = "tree.empty_filename"
+
+	// ProblemUnixSocket is a tree entry with the mode of a Unix
+	// socket.
+	ProblemUnixSocket ProblemCode = "tree.unix_socket"
+
+	// ProblemUnknownFiletype is a tree entry whose mode isn't of any
+	// type that Git knows about.
+	ProblemUnknownFiletype ProblemCode = "tree.unknown_filetype"
+
+	// ProblemMixedTypeCaseCollision is a tree entry whose name
+	// differs only in case from that of an entry of another type
+	// (e.g., a symlink and a file) in the same tree.
+	ProblemMixedTypeCaseCollision ProblemCode = "tree.mixed_type_case_collision"
+
+	// ProblemUnknownHeader is a commit or tag with a header that Git
+	// doesn't know about.
+	ProblemUnknownHeader ProblemCode = "object.unknown_header"
+
+	// ProblemDuplicateHeader is a commit or tag with a header that
+	// appears more often than it should.
+	ProblemDuplicateHeader ProblemCode = "object.duplicate_header"
+
+	// ProblemCorruptObject is an object whose contents don't hash to
+	// its OID.
+	ProblemCorruptObject ProblemCode = "object.corrupt"
+
+	// ProblemFutureCommitDate is a commit whose author or committer
+	// timestamp is more than `FutureCommitDateThreshold` seconds
+	// ahead of the time of the scan.
+	ProblemFutureCommitDate ProblemCode = "commit.future_date"
+
+	// ProblemPruneNever is a `gc.pruneExpire=never` setting in a
+	// repository with a lot of loose object data, which will never
+	// be cleaned up.
+	ProblemPruneNever ProblemCode = "config.prune_never"
+
+	// ProblemAutoGCDisabled is a `gc.auto=0` setting in a repository
+	// with more loose objects than `git gc --auto` would tolerate by
+	// default.
+	ProblemAutoGCDisabled ProblemCode = "config.auto_gc_disabled"
+
+	// ProblemCompressionDisabled is a compression level setting of 0.
+	ProblemCompressionDisabled ProblemCode = "config.compression_disabled"
+
+	// ProblemMissingDefaultBranch is a `HEAD` that points at a
+	// reference that doesn't exist.
+	ProblemMissingDefaultBranch ProblemCode = "ref.missing_default_branch"
+
+	// ProblemDetachedHead is a detached `HEAD` in a bare repository.
+	ProblemDetachedHead ProblemCode = "ref.detached_head"
+
+	// ProblemDanglingSymref is a symbolic reference whose target
+	// doesn't exist.
+	ProblemDanglingSymref ProblemCode = "ref.dangling_symref"
+
+	// ProblemRefCaseConflict is a reference whose name differs only
+	// in case from that of another reference.
+	ProblemRefCaseConflict ProblemCode = "ref.case_conflict"
+
+	// ProblemTagShadowsBranch is a tag with the same name as a
+	// branch.
+	ProblemTagShadowsBranch ProblemCode = "ref.tag_shadows_branch"
+
+	// ProblemMissingBitmap is a repository with more than
+	// `MissingBitmapCommitThreshold` commits but no reachability
+	// bitmap.
+	ProblemMissingBitmap ProblemCode = "storage.missing_bitmap"
+
+	// ProblemSaturatedMetric is a metric whose counter saturated, so
+	// that its true value is unknown.
+	ProblemSaturatedMetric ProblemCode = "scan.saturated_metric"
+
+	// ProblemBitmapAccessPath is a scan whose reachability queries
+	// were answered using the reachability bitmap (see
+	// `AccessPathBitmap`).
+	ProblemBitmapAccessPath ProblemCode = "scan.bitmap_access_path"
+)
+
+// ProblemSeverity is how serious a problem is.
+type ProblemSeverity int
+
+const (
+	// SeverityNotice is for problems that are worth knowing about,
+	// and that the statistics already reflect.
+	SeverityNotice ProblemSeverity = iota
+
+	// SeverityWarning is for problems that make the results less
+	// trustworthy.
+	SeverityWarning
+
+	// SeverityError is for problems with the repository's integrity.
+	SeverityError
+)
+
+// String returns the name of `sev`; e.g., "warning".
+func (sev ProblemSeverity) String() string {
+	switch sev {
+	case SeverityNotice:
+		return "notice"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("ProblemSeverity(%d)", int(sev))
+	}
+}
+
+// MarshalJSON renders `sev` as its name.
+func (sev ProblemSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sev.String())
+}
+
+// ProblemDefinition describes a `ProblemCode`.
+type ProblemDefinition struct {
+	Severity ProblemSeverity
+
+	// Description is a one-line description of the kind of problem.
+	Description string
+
+	// Template is a `fmt` format string that describes a single
+	// instance of the problem. The arguments that it expects depend
+	// on the code.
+	Template string
+}
+
+// problemRegistry holds the definition of every `ProblemCode`. Every
+// problem that is reported must use a code that is registered here.
+var problemRegistry = map[ProblemCode]ProblemDefinition{
+	ProblemLongFilename: {
+		Severity:    SeverityNotice,
+		Description: "Filenames that are longer than the maximum filename length",
+		Template:    "filename %q is %d bytes long",
+	},
+	ProblemWideTree: {
+		Severity:    SeverityNotice,
+		Description: "Trees with so many entries that Git gets slow",
+		Template:    "tree %s has %d entries",
+	},
+	ProblemEmptyTreeEntry: {
+		Severity:    SeverityNotice,
+		Description: "Tree entries that refer to the empty tree",
+	},
+	ProblemEmptyFilename: {
+		Severity:    SeverityError,
+		Description: "Tree entries with empty filenames, which Git considers corrupt",
+	},
+	ProblemUnixSocket: {
+		Severity:    SeverityWarning,
+		Description: "Tree entries with the mode of a Unix socket, which can't be checked out portably",
+	},
+	ProblemUnknownFiletype: {
+		Severity:    SeverityWarning,
+		Description: "Tree entries whose modes aren't of any type that Git knows about",
+	},
+	ProblemMixedTypeCaseCollision: {
+		Severity: SeverityWarning,
+		Description: "Tree entries of different types whose names differ only in case, " +
+			"which check out differently depending on the platform",
+		Template: "%q (mode %s) and %q (mode %s) differ only in case, in tree %s",
+	},
+	ProblemUnknownHeader: {
+		Severity:    SeverityNotice,
+		Description: "Commits and tags with headers that Git doesn't know about",
+	},
+	ProblemDuplicateHeader: {
+		Severity:    SeverityNotice,
+		Description: "Commits and tags with duplicated headers",
+	},
+	ProblemCorruptObject: {
+		Severity:    SeverityError,
+		Description: "Objects whose contents don't hash to their OIDs",
+		Template:    "%s %s is corrupt; its contents hash to %s",
+	},
+	ProblemMissingDefaultBranch: {
+		Severity:    SeverityNotice,
+		Description: "HEAD points at a reference that doesn't exist",
+		Template:    "HEAD points at %s, which doesn't exist",
+	},
+	ProblemDetachedHead: {
+		Severity:    SeverityNotice,
+		Description: "HEAD is detached in a bare repository",
+	},
+	ProblemDanglingSymref: {
+		Severity:    SeverityNotice,
+		Description: "Symbolic references whose targets don't exist",
+		Template:    "%s points at %s, which doesn't exist",
+	},
+	ProblemRefCaseConflict: {
+		Severity: SeverityWarning,
+		Description: "References whose names differ only in case, which collide on " +
+			"case-insensitive filesystems; rename or delete one of each pair",
+		Template: "%s and %s differ only in case",
+	},
+	ProblemTagShadowsBranch: {
+		Severity: SeverityWarning,
+		Description: "Tags with the same names as branches, which make those names " +
+			"ambiguous; rename the tags or the branches",
+		Template: "tag %s has the same name as branch %s",
+	},
+	ProblemFutureCommitDate: {
+		Severity:    SeverityWarning,
+		Description: "Commits dated in the future, which confuse 'git log --since' and '--until'",
+		Template:    "commit %s is dated %s in the future",
+	},
+	ProblemPruneNever: {
+		Severity:    SeverityWarning,
+		Description: "Unreachable objects are never pruned",
+		Template:    "gc.pruneExpire=never, and there are %s of loose objects, which will never be pruned",
+	},
+	ProblemAutoGCDisabled: {
+		Severity:    SeverityWarning,
+		Description: "Automatic garbage collection is disabled",
+		Template:    "gc.auto=0, and there are %d loose objects",
+	},
+	ProblemCompressionDisabled: {
+		Severity:    SeverityNotice,
+		Description: "Objects are stored without zlib compression",
+		Template:    "%s=0",
+	},
+	ProblemMissingBitmap: {
+		Severity: SeverityWarning,
+		Description: "Big repositories without a reachability bitmap, which makes clones " +
+			"slow; run 'git repack -adb'",
+		Template: "there is no reachability bitmap, but there are %d commits",
+	},
+	ProblemSaturatedMetric: {
+		Severity:    SeverityWarning,
+		Description: "Metrics whose counters saturated",
+		Template:    "%s saturated at %d; the true value is at least that big",
+	},
+	ProblemBitmapAccessPath: {
+		Severity: SeverityNotice,
+		Description: "The repository is fully packed, so reachability is computed " +
+			"using its bitmap (use --no-fast-path to avoid this)",
+	},
+}
+
+// ProblemCodes returns all of the registered problem codes, sorted.
+func ProblemCodes() []ProblemCode {
+	codes := make([]ProblemCode, 0, len(problemRegistry))
+	for code := range problemRegistry {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Definition returns the definition of `code`, and whether it is
+// registered.
+func (code ProblemCode) Definition() (ProblemDefinition, bool) {
+	def, ok := problemRegistry[code]
+	return def, ok
+}
+
+// maxProblemExamples is the maximum number of examples that are
+// included in a `ProblemGroup`.
+const maxProblemExamples = 10
+
+// ProblemGroup summarizes all of the problems with a particular code.
+type ProblemGroup struct {
+	Code        ProblemCode     `json:"code"`
+	Severity    ProblemSeverity `json:"severity"`
+	Description string          `json:"description"`
+
+	// Count is the number of instances of the problem.
+	Count counts.Count32 `json:"count"`
+
+	// Examples describe some of the instances (at most
+	// `maxProblemExamples` of them). Some kinds of problems are only
+	// counted, and have no examples.
+	Examples []string `json:"examples,omitempty"`
+}
+
+// problemCollector builds up `ProblemGroup`s.
+type problemCollector struct {
+	groups []ProblemGroup
+}
+
+// group returns the group for `code`, creating it if necessary. A
+// code that isn't registered (which `TestProblems` guards against) is
+// reported as a warning, described by the code itself.
+func (pc *problemCollector) group(code ProblemCode) *ProblemGroup {
+	def, ok := code.Definition()
+	if !ok {
+		def = ProblemDefinition{Severity: SeverityWarning, Description: string(code)}
+	}
+	for i := range pc.groups {
+		if pc.groups[i].Code == code {
+			return &pc.groups[i]
+		}
+	}
+	pc.groups = append(pc.groups, ProblemGroup{
+		Code:        code,
+		Severity:    def.Severity,
+		Description: def.Description,
+	})
+	return &pc.groups[len(pc.groups)-1]
+}
+
+// count records `n` instances of `code` without examples.
+func (pc *problemCollector) count(code ProblemCode, n counts.Count32) {
+	if n == 0 {
+		return
+	}
+	pc.group(code).Count.Increment(n)
+}
+
+// atLeast raises the number of instances of `code` to `n`, if it is
+// lower. This is for problems that are counted separately from their
+// examples.
+func (pc *problemCollector) atLeast(code ProblemCode, n counts.Count32) {
+	if n == 0 {
+		return
+	}
+	pc.group(code).Count.AdjustMaxIfNecessary(n)
+}
+
+// add records one instance of `code`, described by the code's
+// template applied to `args`.
+func (pc *problemCollector) add(code ProblemCode, args ...interface{}) {
+	g := pc.group(code)
+	g.Count.Increment(1)
+	if len(g.Examples) < maxProblemExamples {
+		if def, ok := code.Definition(); ok && def.Template != "" {
+			g.Examples = append(g.Examples, fmt.Sprintf(def.Template, args...))
+		}
+	}
+}
+
+// Problems returns the problems that the scan detected, grouped by
+// code, most severe first (and by code within a severity). `refGroups`
+// is used to name saturated metrics, as in `Flatten()`.
+func (s *HistorySize) Problems(refGroups []RefGroup) []ProblemGroup {
+	var pc problemCollector
+
+	for _, lf := range s.LongFilenames {
+		pc.add(ProblemLongFilename, lf.Name, lf.Length)
+	}
+	// `LongFilenames` only holds distinct names, but the count
+	// includes every tree entry:
+	pc.atLeast(ProblemLongFilename, s.LongFilenameCount)
+
+	for _, wt := range s.WideTrees {
+		pc.add(ProblemWideTree, wt.Tree, wt.Entries)
+	}
+	pc.atLeast(ProblemWideTree, s.WideTreeCount)
+
+	pc.count(ProblemEmptyTreeEntry, s.EmptyTreeCount)
+	pc.count(ProblemEmptyFilename, s.EmptyFilenameCount)
+	pc.count(ProblemUnixSocket, s.UnixSocketCount)
+	pc.count(ProblemUnknownFiletype, s.UnknownFiletypeCount)
+
+	for _, c := range s.MixedTypeCaseCollisions {
+		pc.add(ProblemMixedTypeCaseCollision, c.Name1, c.Mode1, c.Name2, c.Mode2, c.Tree)
+	}
+	pc.atLeast(ProblemMixedTypeCaseCollision, s.MixedTypeCaseCollisionCount)
+	pc.count(ProblemUnknownHeader, s.UnknownHeaderObjectCount)
+	pc.count(ProblemDuplicateHeader, s.DuplicateHeaderObjectCount)
+
+	for _, m := range s.CorruptObjects {
+		pc.add(ProblemCorruptObject, m.ObjectType, m.OID, m.ComputedOID)
+	}
+
+	if s.MaxFutureCommitDateSkew > FutureCommitDateThreshold {
+		pc.add(
+			ProblemFutureCommitDate, s.MaxFutureCommitDateSkewCommit,
+			time.Duration(s.MaxFutureCommitDateSkew)*time.Second,
+		)
+	}
+
+	s.configProblems(&pc)
+
+	rc := s.repositoryConfig()
+	if rc.DefaultBranchMissing != 0 {
+		pc.add(ProblemMissingDefaultBranch, rc.DefaultBranch)
+	}
+	pc.count(ProblemDetachedHead, rc.DetachedHead)
+	for _, symref := range rc.DanglingSymbolicRefs {
+		pc.add(ProblemDanglingSymref, symref.Refname, symref.Target)
+	}
+	pc.atLeast(ProblemDanglingSymref, rc.DanglingSymbolicRefCount)
+
+	if s.missingBitmap() != 0 {
+		pc.add(ProblemMissingBitmap, s.UniqueCommitCount)
+	}
+
+	rs := s.RefStats
+	for _, pair := range rs.CaseConflicts {
+		pc.add(ProblemRefCaseConflict, pair.Refname1, pair.Refname2)
+	}
+	pc.atLeast(ProblemRefCaseConflict, rs.CaseConflictCount)
+	for _, pair := range rs.TagsShadowingBranches {
+		pc.add(ProblemTagShadowsBranch, pair.Refname1, pair.Refname2)
+	}
+	pc.atLeast(ProblemTagShadowsBranch, rs.TagShadowingBranchCount)
+
+	for _, m := range s.SaturatedMetrics(refGroups) {
+		pc.add(ProblemSaturatedMetric, m.Key, m.Value)
+	}
+
+	if s.AccessPath == AccessPathBitmap {
+		pc.count(ProblemBitmapAccessPath, 1)
+	}
+
+	sort.SliceStable(pc.groups, func(i, j int) bool {
+		gi, gj := pc.groups[i], pc.groups[j]
+		if gi.Severity != gj.Severity {
+			return gi.Severity > gj.Severity
+		}
+		return gi.Code < gj.Code
+	})
+	return pc.groups
+}
+
+// problemsTrailer describes the problems that the scan detected, for
+// the end of a table, or returns "" if there were none.
+func (s *HistorySize) problemsTrailer(refGroups []RefGroup) string {
+	groups := s.Problems(refGroups)
+	if len(groups) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nProblems:\n")
+	for _, g := range groups {
+		fmt.Fprintf(&sb, "* %s: %s [%s] (%d)\n", g.Severity, g.Description, g.Code, g.Count)
+		for _, example := range g.Examples {
+			fmt.Fprintf(&sb, "    %s\n", example)
+		}
+		if more := int(g.Count) - len(g.Examples); len(g.Examples) > 0 && more > 0 {
+			fmt.Fprintf(&sb, "    ...and %d more\n", more)
+		}
+	}
+	return sb.String()
+}
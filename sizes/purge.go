@@ -0,0 +1,359 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/gitattributes"
+)
+
+// PurgeSpec describes the blobs that a proposed history rewrite (e.g.,
+// with `git filter-repo`) would remove.
+type PurgeSpec struct {
+	// Blobs are blobs that would be removed wherever they appear.
+	Blobs []git.OID
+
+	// Paths are gitignore-style patterns (see
+	// `gitattributes.Compile()`). A blob would be removed from every
+	// path that matches one of the patterns, or that is under a
+	// directory that matches one of them.
+	Paths []string
+
+	// MaxPathsPerBlob is the number of paths that are retained for
+	// each of `Blobs` (see `PurgeEstimate.BlobPaths`). If it is zero,
+	// `DefaultMaxPathsPerObject` is used.
+	MaxPathsPerBlob int
+}
+
+// PurgeEstimate is the result of `EstimatePurge()`.
+type PurgeEstimate struct {
+	// TotalBlobCount and TotalBlobSize describe all of the blobs that
+	// are reachable now.
+	TotalBlobCount counts.Count32 `json:"total_blob_count"`
+	TotalBlobSize  counts.Count64 `json:"total_blob_size"`
+
+	// PurgedBlobCount and PurgedBlobSize describe the blobs that would
+	// become unreachable after the rewrite.
+	PurgedBlobCount counts.Count32 `json:"purged_blob_count"`
+	PurgedBlobSize  counts.Count64 `json:"purged_blob_size"`
+
+	// RetainedBlobCount and RetainedBlobSize describe the blobs that
+	// match the spec somewhere, but would still be reachable because
+	// they also appear at a path that doesn't match (or are pointed
+	// at directly by a tag or a root).
+	RetainedBlobCount counts.Count32 `json:"retained_blob_count"`
+	RetainedBlobSize  counts.Count64 `json:"retained_blob_size"`
+
+	// PurgedPercent is `PurgedBlobSize` as a percentage of
+	// `TotalBlobSize`.
+	PurgedPercent float64 `json:"purged_percent"`
+
+	// BlobPaths lists, for each of `PurgeSpec.Blobs` that is
+	// reachable, the paths at which it appears. If a tree containing
+	// the blob appears at more than one path (e.g., a vendored
+	// directory that was copied), only the first of them is counted.
+	BlobPaths []BlobPaths `json:"blob_paths,omitempty"`
+}
+
+// BlobPaths is a blob together with the paths at which it appears.
+type BlobPaths struct {
+	OID   git.OID   `json:"oid"`
+	Paths *PathList `json:"paths"`
+}
+
+// blobFate records what a history rewrite would do to a blob.
+type blobFate uint8
+
+const (
+	// blobMatched is set if the blob appears somewhere that the
+	// rewrite would remove it from.
+	blobMatched blobFate = 1 << iota
+
+	// blobKept is set if the blob appears somewhere that the rewrite
+	// would leave alone.
+	blobKept
+)
+
+// treeVisit is a tree at a particular path.
+type treeVisit struct {
+	oid  git.OID
+	path string
+}
+
+// EstimatePurge estimates how much smaller the history reachable from
+// `roots` would be if the blobs described by `spec` were purged from
+// it. A blob is only counted as reclaimed if it would become
+// unreachable; i.e., if every place that it appears in the history
+// matches `spec`. To find out, every tree is examined at every path at
+// which it appears, so this is slower than a normal scan and holds all
+// trees in memory.
+//
+// Only the blobs are counted. Trees and commits that refer to purged
+// blobs would be rewritten rather than removed, so their number
+// wouldn't change much, and the rewrite itself is not simulated.
+func EstimatePurge(
+	ctx context.Context, repo *git.Repository, roots []Root, spec PurgeSpec,
+) (PurgeEstimate, error) {
+	matchers := make([]*gitattributes.Matcher, 0, len(spec.Paths))
+	for _, pattern := range spec.Paths {
+		m, err := gitattributes.Compile(pattern)
+		if err != nil {
+			return PurgeEstimate{}, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+		matchers = append(matchers, m)
+	}
+	purgedBlobs := git.NewExactOIDSet()
+	blobPaths := make(map[git.OID]*PathList, len(spec.Blobs))
+	for _, oid := range spec.Blobs {
+		purgedBlobs.Add(oid)
+		blobPaths[oid] = NewPathList(spec.MaxPathsPerBlob)
+	}
+	matchesPath := func(path string) bool {
+		for _, m := range matchers {
+			if m.Match(path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rootOIDs := git.NewExactOIDSet()
+	for _, root := range roots {
+		if root.Walk() {
+			rootOIDs.Add(root.OID())
+		}
+	}
+
+	blobSizes := make(map[git.OID]counts.Count32)
+	fates := make(map[git.OID]blobFate)
+	var commitsAndTags, trees, topTrees []git.OID
+
+	// A blob that is a root, or that a tag points at directly, only
+	// goes away if it is purged by OID:
+	addTopBlob := func(oid git.OID) {
+		if purgedBlobs.Contains(oid) {
+			fates[oid] |= blobMatched
+		} else {
+			fates[oid] |= blobKept
+		}
+	}
+
+	objIter, err := repo.NewObjectIter(ctx)
+	if err != nil {
+		return PurgeEstimate{}, err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		defer objIter.Close()
+
+		errChan <- func() error {
+			for _, root := range roots {
+				if !root.Walk() {
+					continue
+				}
+				if err := objIter.AddRoot(root.OID()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+	}()
+
+	for {
+		obj, ok, err := objIter.Next()
+		if err != nil {
+			return PurgeEstimate{}, err
+		}
+		if !ok {
+			break
+		}
+		switch obj.ObjectType {
+		case "blob":
+			blobSizes[obj.OID] = obj.ObjectSize
+			if rootOIDs.Contains(obj.OID) {
+				addTopBlob(obj.OID)
+			}
+		case "tree":
+			trees = append(trees, obj.OID)
+			if rootOIDs.Contains(obj.OID) {
+				topTrees = append(topTrees, obj.OID)
+			}
+		case "commit", "tag":
+			commitsAndTags = append(commitsAndTags, obj.OID)
+		default:
+			return PurgeEstimate{}, fmt.Errorf(
+				"unexpected object type %q for object %s", obj.ObjectType, obj.OID,
+			)
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		return PurgeEstimate{}, err
+	}
+
+	// Find the trees that are checked out at the top level, and the
+	// blobs that tags point at directly:
+	err = readObjects(ctx, repo, commitsAndTags, func(obj git.ObjectRecord) error {
+		switch obj.ObjectType {
+		case "commit":
+			commit, err := git.ParseCommit(obj.OID, obj.Data)
+			if err != nil {
+				return err
+			}
+			topTrees = append(topTrees, commit.Tree)
+		case "tag":
+			tag, err := git.ParseTag(obj.OID, obj.Data)
+			if err != nil {
+				return err
+			}
+			switch tag.ReferentType {
+			case "tree":
+				topTrees = append(topTrees, tag.Referent)
+			case "blob":
+				addTopBlob(tag.Referent)
+			}
+		default:
+			return fmt.Errorf("expected commit or tag; read %#v", obj.ObjectType)
+		}
+		return nil
+	})
+	if err != nil {
+		return PurgeEstimate{}, err
+	}
+
+	treeEntries, err := readTrees(ctx, repo, trees)
+	if err != nil {
+		return PurgeEstimate{}, err
+	}
+
+	// Walk each tree at each path at which it appears. If a directory
+	// matches, then everything under it is purged (`purged`). Without
+	// path patterns, the path doesn't affect the outcome, so each tree
+	// is only walked once:
+	visited := make(map[treeVisit]bool)
+	// The paths of the blobs in `blobPaths` are only recorded the first
+	// time that each tree is walked, so that the memory needed doesn't
+	// depend on the number of paths at which a tree appears. (The same
+	// blob and path can still be reached via different versions of its
+	// parent trees; `PathList` ignores repeats of the paths that it
+	// retains.)
+	walkedTrees := make(map[git.OID]bool)
+	var walk func(oid git.OID, path string, purged bool) error
+	walk = func(oid git.OID, path string, purged bool) error {
+		visit := treeVisit{oid: oid}
+		if len(matchers) != 0 {
+			visit.path = path
+		}
+		if visited[visit] {
+			return nil
+		}
+		visited[visit] = true
+		recordPaths := !walkedTrees[oid]
+		walkedTrees[oid] = true
+
+		entries, ok := treeEntries[oid]
+		if !ok {
+			return fmt.Errorf("tree %s was not read", oid)
+		}
+		for _, entry := range entries {
+			entryPath := entry.Name
+			if path != "" {
+				entryPath = path + "/" + entry.Name
+			}
+			entryPurged := purged || matchesPath(entryPath)
+
+			switch entry.Filemode & 0o170000 {
+			case 0o40000:
+				if err := walk(entry.OID, entryPath, entryPurged); err != nil {
+					return err
+				}
+			case 0o160000:
+				// Submodules don't refer to objects in this
+				// repository.
+			default:
+				if paths, ok := blobPaths[entry.OID]; ok && recordPaths {
+					paths.Add(entryPath)
+				}
+				if entryPurged || purgedBlobs.Contains(entry.OID) {
+					fates[entry.OID] |= blobMatched
+				} else {
+					fates[entry.OID] |= blobKept
+				}
+			}
+		}
+		return nil
+	}
+	for _, oid := range topTrees {
+		if err := walk(oid, "", false); err != nil {
+			return PurgeEstimate{}, err
+		}
+	}
+
+	var e PurgeEstimate
+	for oid, size := range blobSizes {
+		e.TotalBlobCount.Increment(1)
+		e.TotalBlobSize.Increment(counts.Count64(size))
+
+		switch fates[oid] {
+		case blobMatched:
+			e.PurgedBlobCount.Increment(1)
+			e.PurgedBlobSize.Increment(counts.Count64(size))
+		case blobMatched | blobKept:
+			e.RetainedBlobCount.Increment(1)
+			e.RetainedBlobSize.Increment(counts.Count64(size))
+		}
+	}
+	if e.TotalBlobSize != 0 {
+		e.PurgedPercent = 100 * float64(e.PurgedBlobSize) / float64(e.TotalBlobSize)
+	}
+
+	for _, oid := range spec.Blobs {
+		paths, ok := blobPaths[oid]
+		if _, reachable := blobSizes[oid]; !ok || !reachable {
+			continue
+		}
+		e.BlobPaths = append(e.BlobPaths, BlobPaths{OID: oid, Paths: paths})
+		// Only list each blob once:
+		delete(blobPaths, oid)
+	}
+
+	return e, nil
+}
+
+// WritePurgeEstimate writes a human-readable description of `e` to
+// `w`.
+func WritePurgeEstimate(w io.Writer, e PurgeEstimate) error {
+	format := func(count counts.Count32, size counts.Count64) string {
+		value, unit := counts.Binary.Format(size, "B")
+		return fmt.Sprintf("%d blobs, %s %s", count, value, unit)
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"Reachable blobs: %s\n"+
+			"Would be purged: %s (%.1f%%)\n"+
+			"Matched but still reachable elsewhere: %s\n",
+		format(e.TotalBlobCount, e.TotalBlobSize),
+		format(e.PurgedBlobCount, e.PurgedBlobSize), e.PurgedPercent,
+		format(e.RetainedBlobCount, e.RetainedBlobSize),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, bp := range e.BlobPaths {
+		if _, err := fmt.Fprintf(w, "Blob %s %s\n", bp.OID, bp.Paths); err != nil {
+			return err
+		}
+		for _, path := range bp.Paths.Paths {
+			if _, err := fmt.Fprintf(w, "    %s\n", path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// RefDeletionSpec selects the references whose deletion
+// `SimulateRefDeletion()` simulates. A reference is selected if it
+// belongs to `Group` or if it passes `Filter`.
+type RefDeletionSpec struct {
+	// Group, if non-empty, selects the references that the
+	// `RefGrouper` that collected them put in this group.
+	Group RefGroupSymbol
+
+	// Filter, if non-nil, selects the references that pass it.
+	Filter git.ReferenceFilter
+
+	// KeepReflogs, if set, treats objects that are still reachable
+	// from reflog entries as retained, as `git gc` would until the
+	// reflogs expire.
+	KeepReflogs bool
+}
+
+func (spec RefDeletionSpec) matches(root RefRoot) bool {
+	if spec.Group != "" {
+		for _, group := range root.Groups() {
+			if group == spec.Group {
+				return true
+			}
+		}
+	}
+	return spec.Filter != nil && spec.Filter.Filter(root.Name())
+}
+
+// ProjectedMetric is the value that a metric would have after the
+// simulated deletion.
+type ProjectedMetric struct {
+	// Name is the metric's name, as used in the JSON output of
+	// `HistorySize`.
+	Name string `json:"name"`
+
+	Before counts.Count64 `json:"before"`
+	After  counts.Count64 `json:"after"`
+
+	// Unknown is set for maxima whose holder would be deleted. The
+	// new maximum can't be determined without a rescan; `After` is
+	// then only an upper bound.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// RefDeletionEstimate is the result of `SimulateRefDeletion()`.
+type RefDeletionEstimate struct {
+	// Refs are the names of the references that would be deleted.
+	Refs []string `json:"refs"`
+
+	// ObjectCount and Size describe all of the objects that are
+	// reachable only from the deleted references. The other fields
+	// break them down by type.
+	ObjectCount counts.Count32 `json:"object_count"`
+	Size        counts.Count64 `json:"size"`
+
+	CommitCount counts.Count32 `json:"commit_count"`
+	CommitSize  counts.Count64 `json:"commit_size"`
+	TreeCount   counts.Count32 `json:"tree_count"`
+	TreeSize    counts.Count64 `json:"tree_size"`
+	BlobCount   counts.Count32 `json:"blob_count"`
+	BlobSize    counts.Count64 `json:"blob_size"`
+	TagCount    counts.Count32 `json:"tag_count"`
+	TagSize     counts.Count64 `json:"tag_size"`
+
+	// Projected holds the headline metrics as they would be after
+	// the deletion. It is only filled in if a `HistorySize` was
+	// supplied.
+	Projected []ProjectedMetric `json:"projected,omitempty"`
+}
+
+// SimulateRefDeletion estimates what deleting the references among
+// `refRoots` that are selected by `spec` would remove: the objects
+// that are reachable from them but from none of the other references
+// in `refRoots`.
+//
+// If `s` is non-nil, it should be the result of scanning `refRoots`,
+// and the estimate also projects its headline metrics: unique totals
+// are reduced by the removed objects, and maxima are kept, or marked
+// as unknown if the object holding them would be removed. (The
+// projection assumes that the deleted references were among those
+// scanned.)
+func SimulateRefDeletion(
+	ctx context.Context, repo *git.Repository, refRoots []RefRoot,
+	spec RefDeletionSpec, s *HistorySize,
+) (RefDeletionEstimate, error) {
+	var e RefDeletionEstimate
+	var include, exclude []git.OID
+	for _, root := range refRoots {
+		if spec.matches(root) {
+			e.Refs = append(e.Refs, root.Name())
+			include = append(include, root.OID())
+		} else {
+			exclude = append(exclude, root.OID())
+		}
+	}
+
+	removed := git.NewExactOIDSet()
+	err := repo.WalkExclusiveObjects(
+		ctx, include, exclude,
+		git.ExclusiveObjectsOptions{ExcludeReflogs: spec.KeepReflogs},
+		func(oid git.OID, objectType git.ObjectType, size counts.Count64) error {
+			e.ObjectCount.Increment(1)
+			e.Size.Increment(size)
+			switch objectType {
+			case "commit":
+				e.CommitCount.Increment(1)
+				e.CommitSize.Increment(size)
+			case "tree":
+				e.TreeCount.Increment(1)
+				e.TreeSize.Increment(size)
+			case "blob":
+				e.BlobCount.Increment(1)
+				e.BlobSize.Increment(size)
+			case "tag":
+				e.TagCount.Increment(1)
+				e.TagSize.Increment(size)
+			}
+			if s != nil {
+				removed.Add(oid)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return RefDeletionEstimate{}, fmt.Errorf("walking objects: %w", err)
+	}
+
+	if s != nil {
+		e.Projected = e.project(s, removed)
+	}
+
+	return e, nil
+}
+
+// project computes the projected headline metrics of `s` after the
+// objects in `removed` are gone.
+func (e *RefDeletionEstimate) project(
+	s *HistorySize, removed *git.ExactOIDSet,
+) []ProjectedMetric {
+	var metrics []ProjectedMetric
+
+	addTotal := func(name string, before, minus counts.Count64) {
+		metrics = append(metrics, ProjectedMetric{
+			Name:   name,
+			Before: before,
+			After:  subtractCount(before, minus),
+		})
+	}
+	// A maximum survives unless the object that holds it is removed.
+	// If the holder is unknown (`nil`), the maximum is unknown
+	// whenever `anyRemoved` is set.
+	addMax := func(name string, before counts.Count64, holder *Path, anyRemoved bool) {
+		m := ProjectedMetric{Name: name, Before: before, After: before}
+		if holder != nil {
+			m.Unknown = removed.Contains(holder.OID)
+		} else {
+			m.Unknown = before != 0 && anyRemoved
+		}
+		metrics = append(metrics, m)
+	}
+	// Widen a count, keeping it saturated if it was:
+	c64 := func(n counts.Count32) counts.Count64 {
+		if _, overflow := n.ToUint64(); overflow {
+			return math.MaxUint64
+		}
+		return counts.Count64(n)
+	}
+
+	addTotal("unique_commit_count", c64(s.UniqueCommitCount), c64(e.CommitCount))
+	addTotal("unique_commit_size", s.UniqueCommitSize, e.CommitSize)
+	addMax("max_commit_size", c64(s.MaxCommitSize), s.MaxCommitSizeCommit, e.CommitCount != 0)
+	addMax("max_history_depth", c64(s.MaxHistoryDepth), nil, e.CommitCount != 0)
+	addMax("max_parent_count", c64(s.MaxParentCount), s.MaxParentCountCommit, e.CommitCount != 0)
+
+	addTotal("unique_tree_count", c64(s.UniqueTreeCount), c64(e.TreeCount))
+	addTotal("unique_tree_size", s.UniqueTreeSize, e.TreeSize)
+	addMax("max_tree_entries", c64(s.MaxTreeEntries), s.MaxTreeEntriesTree, e.TreeCount != 0)
+
+	addTotal("unique_blob_count", c64(s.UniqueBlobCount), c64(e.BlobCount))
+	addTotal("unique_blob_size", s.UniqueBlobSize, e.BlobSize)
+	addMax("max_blob_size", c64(s.MaxBlobSize), s.MaxBlobSizeBlob, e.BlobCount != 0)
+
+	addTotal("unique_tag_count", c64(s.UniqueTagCount), c64(e.TagCount))
+	addTotal("unique_tag_size", s.UniqueTagSize, e.TagSize)
+	addMax("max_tag_depth", c64(s.MaxTagDepth), s.MaxTagDepthTag, e.TagCount != 0)
+
+	addMax("max_path_depth", c64(s.MaxPathDepth), s.MaxPathDepthTree, e.TreeCount != 0)
+	addMax("max_path_length", c64(s.MaxPathLength), s.MaxPathLengthTree, e.TreeCount != 0)
+	addMax(
+		"max_expanded_blob_size", s.MaxExpandedBlobSize, s.MaxExpandedBlobSizeTree,
+		e.TreeCount != 0,
+	)
+
+	return metrics
+}
+
+// subtractCount returns `n1 - n2`, or zero if `n2` is bigger. If `n1`
+// is saturated, it stays that way, since its true value is unknown.
+func subtractCount(n1, n2 counts.Count64) counts.Count64 {
+	if _, overflow := n1.ToUint64(); overflow {
+		return n1
+	}
+	if n2 >= n1 {
+		return 0
+	}
+	return n1 - n2
+}
+
+// WriteRefDeletionEstimate writes a human-readable description of `e`
+// to `w`.
+func WriteRefDeletionEstimate(w io.Writer, e RefDeletionEstimate) error {
+	format := func(n counts.Count64) string {
+		value, unit := counts.Binary.Format(n, "B")
+		return value + " " + unit
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"Deleting %d references would remove %d objects (%s):\n"+
+			"    %8d commits  %10s\n"+
+			"    %8d trees    %10s\n"+
+			"    %8d blobs    %10s\n"+
+			"    %8d tags     %10s\n",
+		len(e.Refs), e.ObjectCount, format(e.Size),
+		e.CommitCount, format(e.CommitSize),
+		e.TreeCount, format(e.TreeSize),
+		e.BlobCount, format(e.BlobSize),
+		e.TagCount, format(e.TagSize),
+	); err != nil {
+		return err
+	}
+
+	if len(e.Projected) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "Projected metrics:\n"); err != nil {
+		return err
+	}
+	for _, m := range e.Projected {
+		after := fmt.Sprintf("%d", m.After)
+		if m.Unknown {
+			after = fmt.Sprintf("unknown (at most %d)", m.After)
+		}
+		if _, err := fmt.Fprintf(
+			w, "    %-24s %12d -> %s\n", m.Name, m.Before, after,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package sizes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// RefKind classifies references by what keeps them alive: branches,
+// tags, or anything else (e.g., `refs/stash`, `refs/notes/*`,
+// `refs/pull/*`, remote-tracking references, or custom namespaces).
+type RefKind string
+
+const (
+	RefKindBranch RefKind = "branch"
+	RefKindTag    RefKind = "tag"
+	RefKindOther  RefKind = "other"
+)
+
+// refKindOf returns the kind of the reference named `refname`.
+func refKindOf(refname string) RefKind {
+	switch {
+	case strings.HasPrefix(refname, "refs/heads/"):
+		return RefKindBranch
+	case strings.HasPrefix(refname, "refs/tags/"):
+		return RefKindTag
+	default:
+		return RefKindOther
+	}
+}
+
+// RefKindSize is the number and total size of the objects attributed
+// to a kind of reference.
+type RefKindSize struct {
+	ObjectCount counts.Count32 `json:"object_count"`
+	ObjectSize  counts.Count64 `json:"object_size"`
+}
+
+// RefKindShares breaks the objects that are reachable from the scanned
+// references down by the kind of reference that keeps them alive. An
+// object that is reachable from several kinds is attributed to the
+// highest-priority one (branch, then tag, then other), so each object
+// is counted exactly once. `OtherOnly` thus answers the question of
+// why a repository is bigger than its branches and tags suggest.
+type RefKindShares struct {
+	// Branches holds the objects reachable from any branch.
+	Branches RefKindSize `json:"branches"`
+
+	// TagsOnly holds the objects reachable from a tag but not from
+	// any branch.
+	TagsOnly RefKindSize `json:"tags_only"`
+
+	// OtherOnly holds the objects reachable only from other
+	// references.
+	OtherOnly RefKindSize `json:"other_only"`
+
+	// OtherOnlyPercent is the size of `OtherOnly` as a percentage of
+	// the total, rounded to the nearest integer.
+	OtherOnlyPercent counts.Count32 `json:"other_only_percent"`
+}
+
+// ScanRefKindShares attributes the objects that are reachable from
+// the references among `roots` to the kinds of the references (see
+// `RefKindShares`). Roots that aren't references (e.g., explicit
+// commits given on the command line) and references that aren't
+// walked are ignored. Sizes are the uncompressed sizes of the
+// objects.
+func ScanRefKindShares(
+	ctx context.Context, repo *git.Repository, roots []Root,
+) (RefKindShares, error) {
+	tips := make(map[RefKind][]git.OID)
+	for _, root := range roots {
+		refRoot, ok := root.(ReferenceRoot)
+		if !ok || !refRoot.Walk() {
+			continue
+		}
+		kind := refKindOf(refRoot.Reference().Refname)
+		tips[kind] = append(tips[kind], refRoot.OID())
+	}
+
+	var shares RefKindShares
+
+	var err error
+	shares.Branches.ObjectCount, shares.Branches.ObjectSize, err = repo.ReachableObjectsSize(
+		ctx, tips[RefKindBranch], nil,
+	)
+	if err != nil {
+		return RefKindShares{}, err
+	}
+
+	exclude := tips[RefKindBranch]
+	shares.TagsOnly.ObjectCount, shares.TagsOnly.ObjectSize, err = repo.ReachableObjectsSize(
+		ctx, tips[RefKindTag], exclude,
+	)
+	if err != nil {
+		return RefKindShares{}, err
+	}
+
+	exclude = append(exclude[:len(exclude):len(exclude)], tips[RefKindTag]...)
+	shares.OtherOnly.ObjectCount, shares.OtherOnly.ObjectSize, err = repo.ReachableObjectsSize(
+		ctx, tips[RefKindOther], exclude,
+	)
+	if err != nil {
+		return RefKindShares{}, err
+	}
+
+	total := shares.Branches.ObjectSize
+	total.Increment(shares.TagsOnly.ObjectSize)
+	total.Increment(shares.OtherOnly.ObjectSize)
+	if total != 0 {
+		shares.OtherOnlyPercent = counts.NewCount32(
+			(100*uint64(shares.OtherOnly.ObjectSize) + uint64(total)/2) / uint64(total),
+		)
+	}
+
+	return shares, nil
+}
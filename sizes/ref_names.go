@@ -0,0 +1,128 @@
+package sizes
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxRefNamePairs is the maximum number of examples of each kind of
+// confusing reference name that are kept in `RefStats`.
+const maxRefNamePairs = 10
+
+// RefNamePair is a pair of references whose names are confusingly
+// similar.
+type RefNamePair struct {
+	Refname1 string `json:"refname1"`
+	Refname2 string `json:"refname2"`
+}
+
+// refNameStats remembers the names of the references seen so far, to
+// detect confusing combinations of names.
+type refNameStats struct {
+	// folded maps the case-folded name of each reference to the
+	// first reference seen with that folded name.
+	folded map[string]string
+
+	// branches and tags hold the short names of the branches and
+	// tags.
+	branches map[string]struct{}
+	tags     map[string]struct{}
+}
+
+func newRefNameStats() refNameStats {
+	return refNameStats{
+		folded:   make(map[string]string),
+		branches: make(map[string]struct{}),
+		tags:     make(map[string]struct{}),
+	}
+}
+
+// foldCase returns `s` with Unicode simple case folding applied to
+// each character, so that names that differ only in case (e.g.,
+// "V1.0" and "v1.0", or "Ǆ" and "ǅ") fold to the same string. This is
+// roughly the comparison that a case-insensitive filesystem makes,
+// which is where such names collide.
+func foldCase(s string) string {
+	// Fast path for ASCII, where the smallest equivalent of each
+	// letter is its upper-case version:
+	hasLower := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return foldCaseUnicode(s)
+		}
+		if 'a' <= c && c <= 'z' {
+			hasLower = true
+		}
+	}
+	if !hasLower {
+		return s
+	}
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		b[i] = c
+	}
+	return string(b)
+}
+
+// foldCaseUnicode is the general case of `foldCase()`.
+func foldCaseUnicode(s string) string {
+	return strings.Map(func(r rune) rune {
+		// `unicode.SimpleFold()` cycles through the characters that
+		// are equivalent to `r`; use the smallest one:
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < folded {
+				folded = f
+			}
+		}
+		return folded
+	}, s)
+}
+
+// recordRefNameConflicts checks whether `refname` differs only in case
+// from a reference that was already recorded, and whether it is a tag
+// with the same name as a branch (or vice versa), and updates the
+// corresponding fields of `rs`.
+func (rs *RefStats) recordRefNameConflicts(n *refNameStats, refname string) {
+	folded := foldCase(refname)
+	if other, ok := n.folded[folded]; ok {
+		rs.CaseConflictCount.Increment(1)
+		if len(rs.CaseConflicts) < maxRefNamePairs {
+			rs.CaseConflicts = append(rs.CaseConflicts, RefNamePair{other, refname})
+		}
+	} else {
+		n.folded[folded] = refname
+	}
+
+	switch {
+	case strings.HasPrefix(refname, "refs/heads/"):
+		name := strings.TrimPrefix(refname, "refs/heads/")
+		n.branches[name] = struct{}{}
+		if _, ok := n.tags[name]; ok {
+			rs.addTagShadowingBranch("refs/tags/"+name, refname)
+		}
+	case strings.HasPrefix(refname, "refs/tags/"):
+		name := strings.TrimPrefix(refname, "refs/tags/")
+		n.tags[name] = struct{}{}
+		if _, ok := n.branches[name]; ok {
+			rs.addTagShadowingBranch(refname, "refs/heads/"+name)
+		}
+	}
+}
+
+// addTagShadowingBranch records that the tag `tag` has the same short
+// name as the branch `branch`.
+func (rs *RefStats) addTagShadowingBranch(tag, branch string) {
+	rs.TagShadowingBranchCount.Increment(1)
+	if len(rs.TagsShadowingBranches) < maxRefNamePairs {
+		rs.TagsShadowingBranches = append(
+			rs.TagsShadowingBranches, RefNamePair{tag, branch},
+		)
+	}
+}
@@ -0,0 +1,59 @@
+package sizes
+
+import (
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// RefStats counts the references that were processed, broken down by
+// the kind of reference.
+type RefStats struct {
+	// BranchCount is the number of references under `refs/heads/`.
+	BranchCount counts.Count32 `json:"branch_count"`
+
+	// TagCount is the number of references under `refs/tags/`.
+	TagCount counts.Count32 `json:"tag_count"`
+
+	// RemoteTrackingRefCount is the number of references under
+	// `refs/remotes/`.
+	RemoteTrackingRefCount counts.Count32 `json:"remote_tracking_ref_count"`
+
+	// OtherRefCount is the number of references that don't fall into
+	// any of the above categories (e.g., `refs/pull/*`, `refs/notes/*`,
+	// or `refs/stash`).
+	OtherRefCount counts.Count32 `json:"other_ref_count"`
+
+	// AllRefCount is the total number of references.
+	AllRefCount counts.Count32 `json:"all_ref_count"`
+
+	// CaseConflictCount is the number of references whose names
+	// differ only in case from that of another reference. Such
+	// references collide on case-insensitive filesystems.
+	// CaseConflicts holds some examples.
+	CaseConflictCount counts.Count32 `json:"case_conflict_count"`
+	CaseConflicts     []RefNamePair  `json:"case_conflicts,omitempty"`
+
+	// TagShadowingBranchCount is the number of tags that have the
+	// same name as a branch, which makes the name ambiguous.
+	// TagsShadowingBranches holds some examples, as (tag, branch)
+	// pairs.
+	TagShadowingBranchCount counts.Count32 `json:"tag_shadowing_branch_count"`
+	TagsShadowingBranches   []RefNamePair  `json:"tags_shadowing_branches,omitempty"`
+}
+
+// recordRefname counts the reference `refname` in the appropriate
+// category.
+func (rs *RefStats) recordRefname(refname string) {
+	rs.AllRefCount.Increment(1)
+	switch {
+	case strings.HasPrefix(refname, "refs/heads/"):
+		rs.BranchCount.Increment(1)
+	case strings.HasPrefix(refname, "refs/tags/"):
+		rs.TagCount.Increment(1)
+	case strings.HasPrefix(refname, "refs/remotes/"):
+		rs.RemoteTrackingRefCount.Increment(1)
+	default:
+		rs.OtherRefCount.Increment(1)
+	}
+}
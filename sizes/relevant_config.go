@@ -0,0 +1,45 @@
+package sizes
+
+import (
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+)
+
+const (
+	// gcAutoDefault is Git's default for `gc.auto`: the number of
+	// loose objects above which `git gc --auto` packs them.
+	gcAutoDefault = 6700
+
+	// neverPrunedLooseSizeThreshold is the amount of loose object
+	// data above which `gc.pruneExpire=never` is reported.
+	neverPrunedLooseSizeThreshold = 100 * 1024 * 1024
+)
+
+// configProblems records the problems that the gitconfig settings in
+// `s.RelevantConfig` cause, given the current state of the object
+// storage.
+func (s *HistorySize) configProblems(pc *problemCollector) {
+	config := s.RelevantConfig
+	storage := s.storage()
+
+	// Unreachable objects are kept loose (or in cruft packs) until
+	// they expire, so the loose objects are where garbage piles up
+	// if it is never pruned:
+	if v, ok := config["gc.pruneExpire"]; ok && strings.EqualFold(v, "never") &&
+		storage.LooseObjectSize > neverPrunedLooseSizeThreshold {
+		value, unit := counts.Binary.Format(storage.LooseObjectSize, "B")
+		pc.add(ProblemPruneNever, value+" "+unit)
+	}
+
+	if v, ok := config["gc.auto"]; ok && strings.TrimSpace(v) == "0" &&
+		storage.LooseObjectCount > gcAutoDefault {
+		pc.add(ProblemAutoGCDisabled, storage.LooseObjectCount)
+	}
+
+	for _, key := range []string{"core.compression", "core.looseCompression", "pack.compression"} {
+		if v, ok := config[key]; ok && strings.TrimSpace(v) == "0" {
+			pc.add(ProblemCompressionDisabled, key)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// DefaultRepackMinBlobSize is the default for
+// `RepackOptions.MinBlobSize`.
+const DefaultRepackMinBlobSize = 1024 * 1024
+
+const (
+	// repackSimilarSizeRatio is how much bigger than the next
+	// smaller one a blob can be and still count as a version of the
+	// same file.
+	repackSimilarSizeRatio = 2
+
+	// The range of the fraction of a candidate's disk size that
+	// storing it as a delta is assumed to save. Versions of the same
+	// file usually delta very well, but some binary formats don't.
+	repackMinSavingsFraction = 0.5
+	repackMaxSavingsFraction = 0.95
+)
+
+// RepackOptions controls `EstimateRepack()`.
+type RepackOptions struct {
+	// MinBlobSize is the size below which blobs are ignored. If it
+	// is zero, `DefaultRepackMinBlobSize` is used.
+	MinBlobSize counts.Count32
+}
+
+// RepackCandidate is a large blob that is stored without a delta, but
+// that looks like one of several versions of the same file, at least
+// one other of which is also stored without a delta.
+type RepackCandidate struct {
+	OID git.OID `json:"oid"`
+
+	// Path is the first path at which the blob was found.
+	Path string `json:"path"`
+
+	Size     counts.Count32 `json:"size"`
+	DiskSize counts.Count64 `json:"disk_size"`
+
+	// Siblings is the number of other large blobs that look like
+	// versions of the same file.
+	Siblings int `json:"siblings"`
+}
+
+// RepackEstimate is the result of `EstimateRepack()`. It is a
+// heuristic estimate, not a prediction: whether blobs really delta
+// against each other depends on their contents.
+type RepackEstimate struct {
+	// Candidates are the blobs that a more aggressive repack might
+	// store as deltas, biggest on disk first.
+	Candidates []RepackCandidate `json:"candidates"`
+
+	// CandidateCount and CandidateDiskSize summarize `Candidates`.
+	CandidateCount    counts.Count32 `json:"candidate_count"`
+	CandidateDiskSize counts.Count64 `json:"candidate_disk_size"`
+
+	// MinSavings and MaxSavings bound the estimated number of bytes
+	// of disk space that a repack could save.
+	MinSavings counts.Count64 `json:"min_savings"`
+	MaxSavings counts.Count64 `json:"max_savings"`
+}
+
+// EstimateRepack looks for large blobs reachable from `roots` that
+// are stored in full (not as deltas), even though they look like
+// versions of the same file as other large blobs, which suggests that
+// `git repack -ad` with a bigger `--window` or `--depth` could shrink
+// them.
+//
+// Blobs count as versions of the same file if they were found in the
+// same directory, have the same filename extension, and have similar
+// sizes. Within each such group, one blob has to stay whole to serve
+// as the delta base (the biggest one on disk is assumed); each of the
+// others is assumed to shrink by between 50% and 95% of its disk
+// size.
+func EstimateRepack(
+	ctx context.Context, repo *git.Repository, roots []Root, opts RepackOptions,
+) (RepackEstimate, error) {
+	minSize := opts.MinBlobSize
+	if minSize == 0 {
+		minSize = DefaultRepackMinBlobSize
+	}
+
+	var oids []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			oids = append(oids, root.OID())
+		}
+	}
+
+	type groupKey struct {
+		dir, ext string
+	}
+	groups := make(map[groupKey][]git.BlobStorage)
+	err := repo.WalkBlobStorage(ctx, oids, func(blob git.BlobStorage) error {
+		if blob.Size < minSize {
+			return nil
+		}
+		key := groupKey{dir: path.Dir(blob.Path), ext: FileExtension(blob.Path)}
+		groups[key] = append(groups[key], blob)
+		return nil
+	})
+	if err != nil {
+		return RepackEstimate{}, err
+	}
+
+	var e RepackEstimate
+	for _, blobs := range groups {
+		sort.Slice(blobs, func(i, j int) bool {
+			return blobs[i].Size < blobs[j].Size
+		})
+
+		// Split the group wherever there is a big jump in size:
+		start := 0
+		for i := 1; i <= len(blobs); i++ {
+			if i < len(blobs) &&
+				uint64(blobs[i].Size) <= repackSimilarSizeRatio*uint64(blobs[i-1].Size) {
+				continue
+			}
+			e.addCluster(blobs[start:i])
+			start = i
+		}
+	}
+
+	sort.Slice(e.Candidates, func(i, j int) bool {
+		ci, cj := e.Candidates[i], e.Candidates[j]
+		if ci.DiskSize != cj.DiskSize {
+			return ci.DiskSize > cj.DiskSize
+		}
+		return ci.Path < cj.Path
+	})
+
+	return e, nil
+}
+
+// addCluster records the candidates among `blobs`, which look like
+// versions of the same file: the ones that are stored in full, if
+// there are several.
+func (e *RepackEstimate) addCluster(blobs []git.BlobStorage) {
+	if len(blobs) < 2 {
+		return
+	}
+
+	var candidates []RepackCandidate
+	var diskSize, maxDiskSize counts.Count64
+	for _, blob := range blobs {
+		if blob.IsDelta() {
+			continue
+		}
+		candidates = append(candidates, RepackCandidate{
+			OID:      blob.OID,
+			Path:     blob.Path,
+			Size:     blob.Size,
+			DiskSize: blob.DiskSize,
+			Siblings: len(blobs) - 1,
+		})
+		diskSize.Increment(blob.DiskSize)
+		maxDiskSize.AdjustMaxIfNecessary(blob.DiskSize)
+	}
+	if len(candidates) < 2 {
+		// At most one of them is stored in full, which is as good
+		// as it gets.
+		return
+	}
+
+	e.Candidates = append(e.Candidates, candidates...)
+	e.CandidateCount.Increment(counts.NewCount32(uint64(len(candidates))))
+	e.CandidateDiskSize.Increment(diskSize)
+
+	// The biggest one is assumed to stay whole, as the delta base:
+	shrinkable := float64(diskSize - maxDiskSize)
+	e.MinSavings.Increment(counts.Count64(repackMinSavingsFraction * shrinkable))
+	e.MaxSavings.Increment(counts.Count64(repackMaxSavingsFraction * shrinkable))
+}
+
+// WriteRepackEstimate writes a human-readable description of `e` to
+// `w`, listing at most `maxCandidates` of the candidates.
+func WriteRepackEstimate(w io.Writer, e RepackEstimate, maxCandidates int) error {
+	format := func(n counts.Count64) string {
+		value, unit := counts.Binary.Format(n, "B")
+		return value + " " + unit
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"Large blobs stored without deltas that look like versions of the same file:\n"+
+			"    %d blobs, %s on disk\n"+
+			"Estimated savings from a repack (a rough heuristic): %s to %s\n",
+		e.CandidateCount, format(e.CandidateDiskSize),
+		format(e.MinSavings), format(e.MaxSavings),
+	); err != nil {
+		return err
+	}
+
+	for i, c := range e.Candidates {
+		if i == maxCandidates {
+			_, err := fmt.Fprintf(w, "    ...and %d more\n", len(e.Candidates)-i)
+			return err
+		}
+		if _, err := fmt.Fprintf(
+			w, "    %10s  %s (%s)\n", format(c.DiskSize), c.Path, c.OID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
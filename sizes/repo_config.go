@@ -0,0 +1,114 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// RepositoryConfigStats describes the state of `HEAD` and the other
+// symbolic references in a repository. Problems here (e.g., `HEAD`
+// pointing at a branch that has been deleted) don't make a repository
+// bigger, but they confuse clients.
+type RepositoryConfigStats struct {
+	// DefaultBranch is the full name of the reference that `HEAD`
+	// points at, or "" if `HEAD` is detached.
+	DefaultBranch string `json:"default_branch,omitempty"`
+
+	// DefaultBranchMissing is 1 if `HEAD` points at a reference
+	// that doesn't exist, and 0 otherwise.
+	DefaultBranchMissing counts.Count32 `json:"default_branch_missing"`
+
+	// DetachedHead is 1 if `HEAD` is detached in a bare repository,
+	// and 0 otherwise. (A detached `HEAD` is normal in a working
+	// copy, but in a bare repository it means that there is no
+	// default branch.)
+	DetachedHead counts.Count32 `json:"detached_head"`
+
+	// SymbolicRefCount is the number of symbolic references other
+	// than `HEAD`.
+	SymbolicRefCount counts.Count32 `json:"symbolic_ref_count"`
+
+	// DanglingSymbolicRefCount is the number of symbolic
+	// references other than `HEAD` whose targets don't exist.
+	DanglingSymbolicRefCount counts.Count32 `json:"dangling_symbolic_ref_count"`
+
+	// DanglingSymbolicRefs are the symbolic references other than
+	// `HEAD` whose targets don't exist.
+	DanglingSymbolicRefs []git.SymbolicReference `json:"dangling_symbolic_refs,omitempty"`
+}
+
+// ScanRepositoryConfig examines `HEAD` and the other symbolic
+// references in `repo`. The references are listed using a single
+// `git for-each-ref`, so that the existence of the targets doesn't
+// have to be checked one by one.
+func ScanRepositoryConfig(repo *git.Repository) (*RepositoryConfigStats, error) {
+	var stats RepositoryConfigStats
+
+	refnames := make(map[string]bool)
+	var symrefs []git.SymbolicReference
+	err := repo.ForEachRefWithOptions(
+		git.ForEachRefOptions{Fields: []string{"symref"}},
+		func(ref git.Ref) error {
+			refnames[ref.Refname] = true
+			if target := ref.Fields["symref"]; target != "" {
+				symrefs = append(symrefs, git.SymbolicReference{
+					Refname: ref.Refname,
+					Target:  target,
+				})
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing references: %w", err)
+	}
+
+	// `git for-each-ref` skips symbolic references whose targets
+	// don't exist, so look for those separately:
+	loose, err := repo.LooseSymbolicReferences()
+	if err != nil {
+		return nil, err
+	}
+	var dangling []git.SymbolicReference
+	for _, symref := range loose {
+		if !refnames[symref.Refname] {
+			dangling = append(dangling, symref)
+		}
+	}
+
+	target, ok, err := repo.SymbolicRef("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		stats.DefaultBranch = target
+		if !refnames[target] {
+			stats.DefaultBranchMissing = 1
+		}
+	} else {
+		bare, err := repo.IsBare()
+		if err != nil {
+			return nil, err
+		}
+		if bare {
+			stats.DetachedHead = 1
+		}
+	}
+
+	stats.SymbolicRefCount = counts.NewCount32(uint64(len(symrefs) + len(dangling)))
+	stats.DanglingSymbolicRefCount = counts.NewCount32(uint64(len(dangling)))
+	stats.DanglingSymbolicRefs = dangling
+
+	return &stats, nil
+}
+
+// repositoryConfig returns the state of the symbolic references in
+// `s`, or a zero value if they weren't checked.
+func (s *HistorySize) repositoryConfig() RepositoryConfigStats {
+	if s.RepositoryConfig == nil {
+		return RepositoryConfigStats{}
+	}
+	return *s.RepositoryConfig
+}
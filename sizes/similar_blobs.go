@@ -0,0 +1,342 @@
+package sizes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+const (
+	// DefaultSimilarBlobCount is the default for
+	// `SimilarBlobOptions.MaxBlobs`.
+	DefaultSimilarBlobCount = 50
+
+	// DefaultSimilarBlobContentBudget is the default for
+	// `SimilarBlobOptions.ContentBudget`.
+	DefaultSimilarBlobContentBudget = 1024 * 1024
+
+	// DefaultSimilarBlobThreshold is the default for
+	// `SimilarBlobOptions.Threshold`.
+	DefaultSimilarBlobThreshold = 0.5
+)
+
+const (
+	// fingerprintWindow is the length, in bytes, of the windows
+	// whose hashes make up a fingerprint.
+	fingerprintWindow = 64
+
+	// fingerprintSampleMask selects which windows are sampled: a
+	// window is sampled if the low bits of its hash are zero, so
+	// about one in 32 windows is sampled. Since the choice depends
+	// only on the window's contents, the same windows are sampled
+	// in two blobs even if their contents are shifted relative to
+	// each other; e.g., by an insertion, or because one is the
+	// concatenation of the other with something else.
+	fingerprintSampleMask = 31
+
+	// fingerprintBase is the multiplier of the rolling hash.
+	fingerprintBase = 0x100000001b3
+)
+
+// SimilarBlobOptions controls `FindSimilarBlobs()`.
+type SimilarBlobOptions struct {
+	// MaxBlobs is the number of blobs, largest first, that are
+	// compared. If it is zero, `DefaultSimilarBlobCount` is used.
+	MaxBlobs int
+
+	// ContentBudget is the number of bytes read from the start of
+	// each blob. If it is zero, `DefaultSimilarBlobContentBudget` is
+	// used.
+	ContentBudget int64
+
+	// Threshold is the fraction of the smaller of two blobs'
+	// fingerprints that must also be in the other one for the blobs
+	// to be considered similar. If it is zero,
+	// `DefaultSimilarBlobThreshold` is used.
+	Threshold float64
+
+	// MaxExamples is the number of example paths reported for each
+	// cluster.
+	MaxExamples int
+}
+
+// SimilarBlobCluster is a group of large, distinct blobs whose
+// contents look related; e.g., versions of the same data file, or
+// a file and the concatenation of it with another. It is found by a
+// heuristic, so the blobs are not necessarily related.
+type SimilarBlobCluster struct {
+	// BlobCount is the number of blobs in the cluster.
+	BlobCount counts.Count32 `json:"blob_count"`
+
+	// TotalSize is the sum of the sizes of the blobs in the
+	// cluster.
+	TotalSize counts.Count64 `json:"total_size"`
+
+	// OIDs are the names of the blobs, largest first.
+	OIDs []git.OID `json:"oids"`
+
+	// Examples are the first paths at which some of the blobs were
+	// found, largest blob first.
+	Examples []string `json:"examples"`
+}
+
+// similarBlob is a candidate blob for `FindSimilarBlobs()`.
+type similarBlob struct {
+	oid  git.OID
+	size counts.Count32
+	path string
+
+	// fingerprint is the sorted, distinct sampled window hashes.
+	fingerprint []uint64
+}
+
+// FindSimilarBlobs finds the largest blobs reachable from the roots
+// in `roots` that should be walked, computes a fingerprint of the
+// start of each one, and returns the clusters of blobs whose
+// fingerprints overlap by at least `opts.Threshold`, biggest (by
+// total size) first. Two blobs are in the same cluster if they are
+// similar, or are both similar to a third blob in the cluster.
+//
+// This is a heuristic: blobs with lots of boilerplate in common
+// (e.g., the same header) may be reported as similar even though
+// they aren't otherwise related. But the result depends only on the
+// blobs, so it is the same from one run to the next.
+func FindSimilarBlobs(
+	ctx context.Context, repo *git.Repository, roots []Root, opts SimilarBlobOptions,
+) ([]SimilarBlobCluster, error) {
+	maxBlobs := opts.MaxBlobs
+	if maxBlobs <= 0 {
+		maxBlobs = DefaultSimilarBlobCount
+	}
+	budget := opts.ContentBudget
+	if budget <= 0 {
+		budget = DefaultSimilarBlobContentBudget
+	}
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultSimilarBlobThreshold
+	}
+
+	var oids []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			oids = append(oids, root.OID())
+		}
+	}
+
+	// Keep the largest blobs, breaking ties by OID so that the
+	// choice doesn't depend on the order of the walk:
+	top := topn.New(maxBlobs, func(b1, b2 similarBlob) bool {
+		if b1.size != b2.size {
+			return b1.size < b2.size
+		}
+		return bytes.Compare(b1.oid.Bytes(), b2.oid.Bytes()) > 0
+	})
+	err := repo.WalkBlobPaths(ctx, oids, func(blob git.BlobPath) error {
+		path := blob.Path
+		if path == "" {
+			path = blob.OID.String()
+		}
+		top.Add(similarBlob{oid: blob.OID, size: blob.Size, path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	blobs := top.Items()
+
+	blobOIDs := make([]git.OID, len(blobs))
+	for i := range blobs {
+		blobOIDs[i] = blobs[i].oid
+	}
+	i := 0
+	err = repo.ReadBlobs(ctx, blobOIDs, budget, func(_ git.OID, _ counts.Count32, data []byte) error {
+		blobs[i].fingerprint = blobFingerprint(data)
+		i++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Union-find over the indexes of `blobs`. The representative
+	// of each set is its smallest index, i.e., its largest blob.
+	parent := make([]int, len(blobs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range blobs {
+		for j := i + 1; j < len(blobs); j++ {
+			if fingerprintOverlap(blobs[i].fingerprint, blobs[j].fingerprint) < threshold {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			if ri < rj {
+				parent[rj] = ri
+			} else if rj < ri {
+				parent[ri] = rj
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var reps []int
+	for i := range blobs {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			reps = append(reps, r)
+		}
+		members[r] = append(members[r], i)
+	}
+
+	var clusters []SimilarBlobCluster
+	for _, r := range reps {
+		indexes := members[r]
+		if len(indexes) < 2 {
+			continue
+		}
+		cluster := SimilarBlobCluster{
+			BlobCount: counts.NewCount32(uint64(len(indexes))),
+			OIDs:      make([]git.OID, 0, len(indexes)),
+			Examples:  []string{},
+		}
+		for _, i := range indexes {
+			cluster.TotalSize.Increment(counts.Count64(blobs[i].size))
+			cluster.OIDs = append(cluster.OIDs, blobs[i].oid)
+			if len(cluster.Examples) < opts.MaxExamples {
+				cluster.Examples = append(cluster.Examples, blobs[i].path)
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	// `reps` is in order of the clusters' largest blobs, which
+	// breaks ties deterministically:
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].TotalSize > clusters[j].TotalSize
+	})
+
+	return clusters, nil
+}
+
+// blobFingerprint returns the sorted, distinct hashes of the sampled
+// windows of `data`. Data shorter than one window has an empty
+// fingerprint.
+func blobFingerprint(data []byte) []uint64 {
+	if len(data) < fingerprintWindow {
+		return nil
+	}
+
+	// outFactor is `fingerprintBase^fingerprintWindow`, the factor
+	// of the byte that leaves the window:
+	var outFactor uint64 = 1
+	for i := 0; i < fingerprintWindow; i++ {
+		outFactor *= fingerprintBase
+	}
+
+	seen := make(map[uint64]struct{})
+	var h uint64
+	for i, c := range data {
+		h = h*fingerprintBase + uint64(c)
+		if i >= fingerprintWindow {
+			h -= outFactor * uint64(data[i-fingerprintWindow])
+		}
+		if i < fingerprintWindow-1 {
+			continue
+		}
+		if m := mix64(h); m&fingerprintSampleMask == 0 {
+			seen[m] = struct{}{}
+		}
+	}
+
+	fingerprint := make([]uint64, 0, len(seen))
+	for m := range seen {
+		fingerprint = append(fingerprint, m)
+	}
+	sort.Slice(fingerprint, func(i, j int) bool {
+		return fingerprint[i] < fingerprint[j]
+	})
+	return fingerprint
+}
+
+// mix64 scrambles the bits of `h` (this is the finalizer of
+// SplitMix64), so that its low bits depend on all of the bytes of
+// the window.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// fingerprintOverlap returns the fraction of the hashes in the
+// smaller of the sorted fingerprints `a` and `b` that are also in the
+// other one, or zero if either is empty.
+func fingerprintOverlap(a, b []uint64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			common++
+			i++
+			j++
+		}
+	}
+
+	smaller := len(a)
+	if len(b) < smaller {
+		smaller = len(b)
+	}
+	return float64(common) / float64(smaller)
+}
+
+// WriteSimilarBlobs writes a human-readable description of
+// `clusters` to `w`.
+func WriteSimilarBlobs(w io.Writer, clusters []SimilarBlobCluster) error {
+	if _, err := fmt.Fprintf(
+		w,
+		"Clusters of large blobs with similar contents (a heuristic): %d\n",
+		len(clusters),
+	); err != nil {
+		return err
+	}
+
+	for _, c := range clusters {
+		value, unit := counts.Binary.Format(c.TotalSize, "B")
+		if _, err := fmt.Fprintf(
+			w, "    %d blobs, %s %s\n", c.BlobCount, value, unit,
+		); err != nil {
+			return err
+		}
+		for _, example := range c.Examples {
+			if _, err := fmt.Fprintf(w, "        %s\n", example); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
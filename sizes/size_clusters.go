@@ -0,0 +1,182 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// SizeClusterOptions controls `AnalyzeSizeClusters`.
+type SizeClusterOptions struct {
+	// Tolerance is how much bigger than the smallest blob in a
+	// cluster the other blobs in the cluster may be, as a fraction
+	// of the smallest blob's size. E.g., 0.01 allows sizes up to 1%
+	// bigger. Zero means that the sizes must be identical.
+	Tolerance float64
+
+	// MinClusterSize is the smallest number of blobs that counts as
+	// a cluster. If it is zero, `DefaultMinClusterSize` is used.
+	MinClusterSize int
+
+	// MinBlobSize is the size below which blobs are ignored. Lots
+	// of small files of similar sizes are normal, so they aren't
+	// interesting.
+	MinBlobSize counts.Count32
+
+	// MaxExamples is the number of example paths reported for each
+	// cluster.
+	MaxExamples int
+}
+
+// DefaultMinClusterSize is the `MinClusterSize` that is used if none
+// is specified.
+const DefaultMinClusterSize = 100
+
+// SizeCluster is a group of distinct blobs whose sizes are nearly
+// identical, which is a hint that they were generated or exported by
+// a tool (e.g., CSV exports or image tiles).
+type SizeCluster struct {
+	// MinSize and MaxSize are the sizes of the smallest and
+	// biggest blobs in the cluster.
+	MinSize counts.Count32 `json:"min_size"`
+	MaxSize counts.Count32 `json:"max_size"`
+
+	// BlobCount is the number of distinct blobs in the cluster.
+	BlobCount counts.Count32 `json:"blob_count"`
+
+	// TotalSize is the sum of the sizes of the blobs in the
+	// cluster.
+	TotalSize counts.Count64 `json:"total_size"`
+
+	// Examples are paths of some of the blobs in the cluster, in
+	// the order that they were found.
+	Examples []string `json:"examples"`
+}
+
+// sizedBlob is a blob's size, together with the order in which it was
+// found, which is used to choose representative paths.
+type sizedBlob struct {
+	size  counts.Count32
+	index int
+}
+
+// AnalyzeSizeClusters walks the blobs reachable from the roots in
+// `roots` that should be walked and returns the clusters of distinct
+// blobs with near-identical sizes, largest cluster (by blob count)
+// first. Clusters are formed by sorting the blobs by size and
+// greedily grouping each blob with the following ones whose sizes are
+// within `opts.Tolerance` of it.
+func AnalyzeSizeClusters(
+	ctx context.Context, repo *git.Repository, roots []Root, opts SizeClusterOptions,
+) ([]SizeCluster, error) {
+	minClusterSize := opts.MinClusterSize
+	if minClusterSize <= 0 {
+		minClusterSize = DefaultMinClusterSize
+	}
+
+	var oids []git.OID
+	for _, root := range roots {
+		if root.Walk() {
+			oids = append(oids, root.OID())
+		}
+	}
+
+	var blobs []sizedBlob
+	var paths []string
+	err := repo.WalkBlobPaths(ctx, oids, func(blob git.BlobPath) error {
+		if blob.Size < opts.MinBlobSize {
+			return nil
+		}
+		path := blob.Path
+		if path == "" {
+			path = blob.OID.String()
+		}
+		blobs = append(blobs, sizedBlob{size: blob.Size, index: len(paths)})
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].size < blobs[j].size
+	})
+
+	var clusters []SizeCluster
+	for start := 0; start < len(blobs); {
+		limit := float64(blobs[start].size) * (1 + opts.Tolerance)
+		end := start + 1
+		for end < len(blobs) && float64(blobs[end].size) <= limit {
+			end++
+		}
+
+		if end-start >= minClusterSize {
+			clusters = append(clusters, newSizeCluster(blobs[start:end], paths, opts.MaxExamples))
+		}
+		start = end
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].BlobCount > clusters[j].BlobCount
+	})
+
+	return clusters, nil
+}
+
+func newSizeCluster(blobs []sizedBlob, paths []string, maxExamples int) SizeCluster {
+	cluster := SizeCluster{
+		MinSize:   blobs[0].size,
+		MaxSize:   blobs[len(blobs)-1].size,
+		BlobCount: counts.NewCount32(uint64(len(blobs))),
+	}
+
+	// The examples are the blobs that were seen first; i.e., the ones
+	// with the lowest indexes:
+	top := topn.New(maxExamples, func(i1, i2 int) bool { return i1 > i2 })
+	for _, blob := range blobs {
+		cluster.TotalSize.Increment(counts.Count64(blob.size))
+		top.Add(blob.index)
+	}
+
+	indexes := top.Items()
+	cluster.Examples = make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		cluster.Examples = append(cluster.Examples, paths[i])
+	}
+
+	return cluster
+}
+
+// WriteSizeClusters writes a human-readable list of `clusters` to
+// `w`.
+func WriteSizeClusters(w io.Writer, clusters []SizeCluster) error {
+	if _, err := fmt.Fprintf(
+		w, "Clusters of blobs with near-identical sizes: %d\n", len(clusters),
+	); err != nil {
+		return err
+	}
+
+	for _, c := range clusters {
+		minValue, minUnit := counts.Binary.Format(c.MinSize, "B")
+		maxValue, maxUnit := counts.Binary.Format(c.MaxSize, "B")
+		totalValue, totalUnit := counts.Binary.Format(c.TotalSize, "B")
+		if _, err := fmt.Fprintf(
+			w, "    %d blobs of %s %s to %s %s, %s %s in total\n",
+			c.BlobCount, minValue, minUnit, maxValue, maxUnit, totalValue, totalUnit,
+		); err != nil {
+			return err
+		}
+		for _, example := range c.Examples {
+			if _, err := fmt.Fprintf(w, "        %s\n", example); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -38,45 +38,260 @@ type TreeSize struct {
 
 	// The total number of submodules referenced, including duplicates.
 	ExpandedSubmoduleCount counts.Count32 `json:"expanded_submodule_count"`
+
+	// The total number of entries with the mode of a Unix socket
+	// (0140000), including duplicates. Git never creates such
+	// entries, and they can't be checked out on Windows.
+	ExpandedUnixSocketCount counts.Count32 `json:"expanded_unix_socket_count"`
+
+	// The total number of entries whose modes aren't any of the
+	// types that Git knows about (files, symlinks, directories, and
+	// submodules) or a Unix socket, including duplicates.
+	ExpandedUnknownFiletypeCount counts.Count32 `json:"expanded_unknown_filetype_count"`
+
+	// The total number of leaf paths (blobs, symlinks, and
+	// submodules), counting each path once regardless of whether
+	// the objects are duplicates. This is the number of files that
+	// a checkout would create.
+	PathCount counts.Count32 `json:"path_count"`
+
+	// The number of directories at each depth below this tree in a
+	// checkout: `LayerWidths[i]` is the number of directories `i+1`
+	// levels below it. Like the other expanded counts, this counts
+	// paths, not distinct trees, so a subtree that appears at
+	// several paths is counted once for each of them. It is nil for
+	// a tree that has no subtrees, which most trees don't, so that
+	// only trees with subtrees pay for the slice, which is as long as
+	// they are deep.
+	LayerWidths []counts.Count32 `json:"layer_widths,omitempty"`
+
+	// The size of the largest tree object, including this one, in
+	// bytes. This is how much data git has to read to list the
+	// biggest directory, which is a performance concern for `git
+	// checkout` when a tree has many (or many long-named) entries.
+	MaxTreeSerializedSize counts.Count32 `json:"max_tree_serialized_size"`
+
+	// Saturated records which of the counts above saturated, so that
+	// their values are only lower bounds. See `Sized()`.
+	Saturated SaturatedCounts `json:"saturated,omitempty"`
+
+	// ExemplarMap records which objects achieved some of the maxima
+	// above, if `ScanOptions.TrackExemplars` was set. See
+	// `Exemplar()`.
+	ExemplarMap `json:"-"`
+}
+
+// SaturatedCounts is a set of the counts of a `TreeSize`, as a bit
+// mask.
+type SaturatedCounts uint16
+
+// The counts of a `TreeSize` that are accumulated over its
+// descendants, and so can saturate. These values are stored in
+// tree-size caches, so they mustn't be renumbered.
+const (
+	SaturatedMaxPathDepth SaturatedCounts = 1 << iota
+	SaturatedMaxPathLength
+	SaturatedExpandedTreeCount
+	SaturatedExpandedBlobCount
+	SaturatedExpandedBlobSize
+	SaturatedExpandedLinkCount
+	SaturatedExpandedSubmoduleCount
+	SaturatedExpandedUnixSocketCount
+	SaturatedExpandedUnknownFiletypeCount
+	SaturatedPathCount
+	SaturatedMaxTreeWidth
+)
+
+// mark adds `count` to `*s` if `saturated` is set.
+func (s *SaturatedCounts) mark(count SaturatedCounts, saturated bool) {
+	if saturated {
+		*s |= count
+	}
+}
+
+// adjustMax updates whether the maximum of the count `count` is
+// saturated, after it was compared against the corresponding count of
+// a tree whose saturated counts are `from`. `adjusted` is true if the
+// tree set a new maximum, and `reached` is true if the tree's count
+// now equals the maximum.
+func (s *SaturatedCounts) adjustMax(
+	count SaturatedCounts, adjusted, reached bool, from SaturatedCounts,
+) {
+	if adjusted {
+		*s &^= count
+	}
+	if reached {
+		*s |= from & count
+	}
+}
+
+// sized returns `n` as a `counts.SizedCount`, which is approximate
+// iff `count` is in `s`.
+func (s SaturatedCounts) sized(n counts.Humanable, count SaturatedCounts) counts.SizedCount {
+	value, _ := n.ToUint64()
+	return counts.SizedCount{Value: value, Approximate: s&count != 0}
+}
+
+// MaxTreeWidth returns the largest number of directories at any
+// single depth of a checkout of this tree, including the tree itself
+// as the only directory at depth zero (i.e., the size of the largest
+// layer of a breadth-first checkout). See `LayerWidths`.
+func (s *TreeSize) MaxTreeWidth() counts.Count32 {
+	width := counts.Count32(1)
+	for _, w := range s.LayerWidths {
+		width.AdjustMaxIfNecessary(w)
+	}
+	return width
+}
+
+// SizedTreeSize is a `TreeSize` whose counts record explicitly
+// whether they saturated. See `TreeSize.Sized()`.
+type SizedTreeSize struct {
+	MaxPathDepth                 counts.SizedCount `json:"max_path_depth"`
+	MaxPathLength                counts.SizedCount `json:"max_path_length"`
+	ExpandedTreeCount            counts.SizedCount `json:"expanded_tree_count"`
+	ExpandedBlobCount            counts.SizedCount `json:"expanded_blob_count"`
+	ExpandedBlobSize             counts.SizedCount `json:"expanded_blob_size"`
+	ExpandedLinkCount            counts.SizedCount `json:"expanded_link_count"`
+	ExpandedSubmoduleCount       counts.SizedCount `json:"expanded_submodule_count"`
+	ExpandedUnixSocketCount      counts.SizedCount `json:"expanded_unix_socket_count"`
+	ExpandedUnknownFiletypeCount counts.SizedCount `json:"expanded_unknown_filetype_count"`
+	PathCount                    counts.SizedCount `json:"path_count"`
+	MaxTreeWidth                 counts.SizedCount `json:"max_tree_width"`
+	MaxTreeSerializedSize        counts.SizedCount `json:"max_tree_serialized_size"`
 }
 
-func (s *TreeSize) addDescendent(filename string, s2 TreeSize) {
-	s.MaxPathDepth.AdjustMaxIfNecessary(s2.MaxPathDepth.Plus(1))
+// Sized returns the counts of `s`, each marked as approximate if it
+// saturated (according to `s.Saturated`). `TreeSize` itself keeps the
+// compact fixed-size counts, since it is stored for every tree in the
+// graph and in tree-size caches.
+func (s *TreeSize) Sized() SizedTreeSize {
+	sat := s.Saturated
+	return SizedTreeSize{
+		MaxPathDepth:                 sat.sized(s.MaxPathDepth, SaturatedMaxPathDepth),
+		MaxPathLength:                sat.sized(s.MaxPathLength, SaturatedMaxPathLength),
+		ExpandedTreeCount:            sat.sized(s.ExpandedTreeCount, SaturatedExpandedTreeCount),
+		ExpandedBlobCount:            sat.sized(s.ExpandedBlobCount, SaturatedExpandedBlobCount),
+		ExpandedBlobSize:             sat.sized(s.ExpandedBlobSize, SaturatedExpandedBlobSize),
+		ExpandedLinkCount:            sat.sized(s.ExpandedLinkCount, SaturatedExpandedLinkCount),
+		ExpandedSubmoduleCount:       sat.sized(s.ExpandedSubmoduleCount, SaturatedExpandedSubmoduleCount),
+		ExpandedUnixSocketCount:      sat.sized(s.ExpandedUnixSocketCount, SaturatedExpandedUnixSocketCount),
+		ExpandedUnknownFiletypeCount: sat.sized(s.ExpandedUnknownFiletypeCount, SaturatedExpandedUnknownFiletypeCount),
+		PathCount:                    sat.sized(s.PathCount, SaturatedPathCount),
+		MaxTreeWidth:                 sat.sized(s.MaxTreeWidth(), SaturatedMaxTreeWidth),
+		MaxTreeSerializedSize:        counts.SizedCount{Value: uint64(s.MaxTreeSerializedSize)},
+	}
+}
+
+func (s *TreeSize) addDescendent(filename string, oid git.OID, s2 TreeSize) {
+	// If one of `s2`'s counts saturated, then so does the
+	// corresponding count of `s`, whether it is a sum or a maximum:
+	s.Saturated |= s2.Saturated
+
+	depth := s2.MaxPathDepth
+	s.Saturated.mark(SaturatedMaxPathDepth, depth.Increment(1))
+	s.adjustMax(
+		ExemplarMaxPathDepth, &s.MaxPathDepth, depth,
+		s2.exemplarOr(ExemplarMaxPathDepth, oid),
+	)
+	pathLength := counts.NewCount32(uint64(len(filename)))
 	if s2.MaxPathLength > 0 {
-		s.MaxPathLength.AdjustMaxIfNecessary(
-			(counts.NewCount32(uint64(len(filename))) + 1).Plus(s2.MaxPathLength),
+		s.Saturated.mark(
+			SaturatedMaxPathLength,
+			pathLength.Increment(1) || pathLength.Increment(s2.MaxPathLength),
 		)
-	} else {
-		s.MaxPathLength.AdjustMaxIfNecessary(counts.NewCount32(uint64(len(filename))))
 	}
-	s.ExpandedTreeCount.Increment(s2.ExpandedTreeCount)
-	s.ExpandedBlobCount.Increment(s2.ExpandedBlobCount)
-	s.ExpandedBlobSize.Increment(s2.ExpandedBlobSize)
-	s.ExpandedLinkCount.Increment(s2.ExpandedLinkCount)
-	s.ExpandedSubmoduleCount.Increment(s2.ExpandedSubmoduleCount)
+	s.adjustMax(
+		ExemplarMaxPathLength, &s.MaxPathLength, pathLength,
+		s2.exemplarOr(ExemplarMaxPathLength, oid),
+	)
+	s.Saturated.mark(
+		SaturatedExpandedTreeCount,
+		s.ExpandedTreeCount.Increment(s2.ExpandedTreeCount),
+	)
+	s.Saturated.mark(
+		SaturatedExpandedBlobCount,
+		s.ExpandedBlobCount.Increment(s2.ExpandedBlobCount),
+	)
+	s.Saturated.mark(SaturatedExpandedBlobSize, s.ExpandedBlobSize.Increment(s2.ExpandedBlobSize))
+	s.Saturated.mark(
+		SaturatedExpandedLinkCount,
+		s.ExpandedLinkCount.Increment(s2.ExpandedLinkCount),
+	)
+	s.Saturated.mark(
+		SaturatedExpandedSubmoduleCount,
+		s.ExpandedSubmoduleCount.Increment(s2.ExpandedSubmoduleCount),
+	)
+	s.Saturated.mark(
+		SaturatedExpandedUnixSocketCount,
+		s.ExpandedUnixSocketCount.Increment(s2.ExpandedUnixSocketCount),
+	)
+	s.Saturated.mark(
+		SaturatedExpandedUnknownFiletypeCount,
+		s.ExpandedUnknownFiletypeCount.Increment(s2.ExpandedUnknownFiletypeCount),
+	)
+	s.Saturated.mark(SaturatedPathCount, s.PathCount.Increment(s2.PathCount))
+	s.adjustMax(
+		ExemplarMaxTreeSerializedSize, &s.MaxTreeSerializedSize, s2.MaxTreeSerializedSize,
+		s2.exemplarOr(ExemplarMaxTreeSerializedSize, oid),
+	)
+	// The subtree itself is one level below this tree, and its own
+	// layers are one level deeper:
+	for len(s.LayerWidths) < len(s2.LayerWidths)+1 {
+		s.LayerWidths = append(s.LayerWidths, 0)
+	}
+	s.Saturated.mark(SaturatedMaxTreeWidth, s.LayerWidths[0].Increment(1))
+	for i, w := range s2.LayerWidths {
+		s.Saturated.mark(SaturatedMaxTreeWidth, s.LayerWidths[i+1].Increment(w))
+	}
+}
+
+// Record that the object has a leaf (e.g., a blob) named `filename`
+// as a direct descendant.
+func (s *TreeSize) addLeaf(filename string, oid git.OID) {
+	s.adjustMax(ExemplarMaxPathDepth, &s.MaxPathDepth, 1, oid)
+	s.adjustMax(
+		ExemplarMaxPathLength, &s.MaxPathLength,
+		counts.NewCount32(uint64(len(filename))), oid,
+	)
+	s.Saturated.mark(SaturatedPathCount, s.PathCount.Increment(1))
 }
 
 // Record that the object has a blob of the specified `size` as a
 // direct descendant.
-func (s *TreeSize) addBlob(filename string, size BlobSize) {
-	s.MaxPathDepth.AdjustMaxIfNecessary(1)
-	s.MaxPathLength.AdjustMaxIfNecessary(counts.NewCount32(uint64(len(filename))))
-	s.ExpandedBlobSize.Increment(counts.Count64(size.Size))
-	s.ExpandedBlobCount.Increment(1)
+func (s *TreeSize) addBlob(filename string, oid git.OID, size BlobSize) {
+	s.addLeaf(filename, oid)
+	s.Saturated.mark(
+		SaturatedExpandedBlobSize, s.ExpandedBlobSize.Increment(counts.Count64(size.Size)),
+	)
+	s.Saturated.mark(SaturatedExpandedBlobCount, s.ExpandedBlobCount.Increment(1))
 }
 
 // Record that the object has a link as a direct descendant.
-func (s *TreeSize) addLink(filename string) {
-	s.MaxPathDepth.AdjustMaxIfNecessary(1)
-	s.MaxPathLength.AdjustMaxIfNecessary(counts.NewCount32(uint64(len(filename))))
-	s.ExpandedLinkCount.Increment(1)
+func (s *TreeSize) addLink(filename string, oid git.OID) {
+	s.addLeaf(filename, oid)
+	s.Saturated.mark(SaturatedExpandedLinkCount, s.ExpandedLinkCount.Increment(1))
 }
 
 // Record that the object has a submodule as a direct descendant.
-func (s *TreeSize) addSubmodule(filename string) {
-	s.MaxPathDepth.AdjustMaxIfNecessary(1)
-	s.MaxPathLength.AdjustMaxIfNecessary(counts.NewCount32(uint64(len(filename))))
-	s.ExpandedSubmoduleCount.Increment(1)
+func (s *TreeSize) addSubmodule(filename string, oid git.OID) {
+	s.addLeaf(filename, oid)
+	s.Saturated.mark(SaturatedExpandedSubmoduleCount, s.ExpandedSubmoduleCount.Increment(1))
+}
+
+// Record that the object has a Unix socket as a direct descendant.
+func (s *TreeSize) addUnixSocket(filename string, oid git.OID) {
+	s.addLeaf(filename, oid)
+	s.Saturated.mark(SaturatedExpandedUnixSocketCount, s.ExpandedUnixSocketCount.Increment(1))
+}
+
+// Record that the object has an entry of an unknown type as a direct
+// descendant.
+func (s *TreeSize) addUnknownFiletype(filename string, oid git.OID) {
+	s.addLeaf(filename, oid)
+	s.Saturated.mark(
+		SaturatedExpandedUnknownFiletypeCount, s.ExpandedUnknownFiletypeCount.Increment(1),
+	)
 }
 
 type CommitSize struct {
@@ -119,6 +334,34 @@ type HistorySize struct {
 	// The commit with the maximum number of direct parents.
 	MaxParentCountCommit *Path `json:"max_parent_count_commit,omitempty"`
 
+	// The number of distinct author email addresses (folded to
+	// lower case). Author statistics identify the people who work
+	// on a repository, so treat them as sensitive.
+	UniqueAuthorCount counts.Count32 `json:"unique_author_count"`
+
+	// The number of distinct committer email addresses (folded to
+	// lower case).
+	UniqueCommitterCount counts.Count32 `json:"unique_committer_count"`
+
+	// The largest number of commits by any single author email.
+	MaxSingleAuthorCommitCount counts.Count32 `json:"max_single_author_commit_count"`
+
+	// The number of seconds by which the commit timestamp (author or
+	// committer) that is furthest in the future is ahead of the time
+	// of the scan.
+	MaxFutureCommitDateSkew counts.Count64 `json:"max_future_commit_date_skew"`
+
+	// The commit with the timestamp that is furthest in the future.
+	MaxFutureCommitDateSkewCommit *Path `json:"max_future_commit_date_skew_commit,omitempty"`
+
+	// The number of seconds by which the oldest commit timestamp
+	// (author or committer) is behind the newest one that is not in
+	// the future.
+	MaxPastCommitDateSkew counts.Count64 `json:"max_past_commit_date_skew"`
+
+	// The commit with the oldest timestamp.
+	MaxPastCommitDateSkewCommit *Path `json:"max_past_commit_date_skew_commit,omitempty"`
+
 	// The total number of unique trees analyzed.
 	UniqueTreeCount counts.Count32 `json:"unique_tree_count"`
 
@@ -140,26 +383,112 @@ type HistorySize struct {
 	// The total size of all of the unique blobs analyzed.
 	UniqueBlobSize counts.Count64 `json:"unique_blob_size"`
 
+	// The total size of the blobs referred to by the entries of all
+	// of the unique trees analyzed, counted once per distinct tree
+	// entry. Identical trees are only counted once, so this already
+	// reflects Git's deduplication of trees; it measures how much the
+	// distinct trees share blobs.
+	ReferencedBlobSize counts.Count64 `json:"referenced_blob_size"`
+
 	// The maximum size of any analyzed blob.
 	MaxBlobSize counts.Count32 `json:"max_blob_size"`
 
 	// The biggest blob found.
 	MaxBlobSizeBlob *Path `json:"max_blob_size_blob,omitempty"`
 
+	// The number of lines in the text blob with the most lines
+	// (blobs that contain NUL bytes are skipped). This is
+	// only computed if requested (see `MaxBlobLineCount()`);
+	// otherwise, it is nil.
+	MaxBlobLineCount *counts.Count32 `json:"max_blob_line_count,omitempty"`
+
+	// The blob with the most lines.
+	MaxBlobLineCountBlob *Path `json:"max_blob_line_count_blob,omitempty"`
+
+	// The age, in days, of the oldest blob, measured from the
+	// earliest commit that contains it. This is only computed if
+	// requested (see `FileAgesInHistory()`); otherwise, it is nil.
+	MaxFileAgeInHistory *counts.Count64 `json:"max_file_age_in_history,omitempty"`
+
+	// The oldest blob.
+	MaxFileAgeInHistoryBlob *Path `json:"max_file_age_in_history_blob,omitempty"`
+
+	// The median age, in days, of the blobs. Like
+	// `MaxFileAgeInHistory`, it is nil unless it was computed.
+	MedianFileAgeInHistory *counts.Count64 `json:"median_file_age_in_history,omitempty"`
+
 	// The total number of unique tag objects analyzed.
 	UniqueTagCount counts.Count32 `json:"unique_tag_count"`
 
+	// The total size of all unique tag objects analyzed.
+	UniqueTagSize counts.Count64 `json:"unique_tag_size"`
+
 	// The maximum number of tags in a chain.
 	MaxTagDepth counts.Count32 `json:"max_tag_depth"`
 
 	// The tag with the maximum tag depth.
 	MaxTagDepthTag *Path `json:"max_tag_depth_tag,omitempty"`
 
+	// The total number of references from one analyzed object to
+	// another: tree entries (including submodules), the trees and
+	// parents of commits, and the targets of tags. Together with the
+	// number of objects, it describes how densely connected the
+	// object graph is.
+	EdgeCount counts.Count64 `json:"edge_count"`
+
+	// The length of the longest header line in any commit or tag.
+	MaxHeaderLineLength counts.Count32 `json:"max_header_line_length"`
+
+	// The commit or tag with the longest header line.
+	MaxHeaderLineLengthObject *Path `json:"max_header_line_length_object,omitempty"`
+
+	// The number of commits and tags with headers that git doesn't
+	// know.
+	UnknownHeaderObjectCount counts.Count32 `json:"unknown_header_object_count"`
+
+	// An example of a commit or tag with unknown headers.
+	UnknownHeaderObject *Path `json:"unknown_header_object,omitempty"`
+
+	// The number of commits and tags with headers that are repeated
+	// more often than git allows.
+	DuplicateHeaderObjectCount counts.Count32 `json:"duplicate_header_object_count"`
+
+	// An example of a commit or tag with duplicated headers.
+	DuplicateHeaderObject *Path `json:"duplicate_header_object,omitempty"`
+
 	// The number of references analyzed. Note that we don't eliminate
 	// duplicates if the user passes the same reference more than
 	// once.
 	ReferenceCount counts.Count32 `json:"reference_count"`
 
+	// The references, broken down by kind.
+	RefStats RefStats `json:"ref_stats"`
+
+	// Repository identifies the repository that was scanned. It
+	// isn't set by the scan itself; see `git.Repository.Metadata()`.
+	Repository *git.RepositoryMetadata `json:"repository,omitempty"`
+
+	// Labels are the user-defined labels of the scan (see
+	// `ScanOptions.Labels`).
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// AccessPath tells how reachability queries were answered. It
+	// isn't set by the scan itself; see `ChooseAccessPath()`. It is
+	// empty if no reachability queries were run.
+	AccessPath AccessPath `json:"access_path,omitempty"`
+
+	// GitSpawns counts the git processes that were spawned to
+	// produce the report, keyed by subcommand name. It isn't set by
+	// the scan itself; see `git.Repository.SpawnCounts()`.
+	GitSpawns map[string]int `json:"git_spawns,omitempty"`
+
+	// LinkedWorktree is true if the repository was scanned via a
+	// linked worktree rather than via the main repository. The
+	// results are the same either way, except for anything that
+	// depends on `HEAD`, which is per-worktree. It isn't set by the
+	// scan itself; see `git.Repository.IsLinkedWorktree()`.
+	LinkedWorktree bool `json:"linked_worktree,omitempty"`
+
 	// ReferenceGroups keeps track of how many references in each
 	// reference group were scanned.
 	ReferenceGroups map[RefGroupSymbol]*counts.Count32 `json:"reference_groups"`
@@ -210,6 +539,170 @@ type HistorySize struct {
 
 	// The tree with the maximum expanded submodule count.
 	MaxExpandedSubmoduleCountTree *Path `json:"max_expanded_submodule_count_tree,omitempty"`
+
+	// The maximum number of leaf paths (blobs, symlinks, and
+	// submodules) in any checkout.
+	MaxPathCount counts.Count32 `json:"max_path_count"`
+
+	// The tree with the maximum path count.
+	MaxPathCountTree *Path `json:"max_path_count_tree,omitempty"`
+
+	// The maximum number of directories at any single depth in any
+	// checkout, counting a directory once for each path at which it
+	// appears (see `TreeSize.LayerWidths`).
+	MaxTreeWidth counts.Count32 `json:"max_tree_width"`
+
+	// The tree with the maximum tree width.
+	MaxTreeWidthTree *Path `json:"max_tree_width_tree,omitempty"`
+
+	// MaxTreeSaturated records which of the checkout maxima above
+	// were achieved by a tree whose count saturated, so that their
+	// values are only lower bounds.
+	MaxTreeSaturated SaturatedCounts `json:"max_tree_saturated,omitempty"`
+
+	// Extensions holds statistics about distinct blobs, broken down
+	// by filename extension.
+	Extensions map[string]*ExtStats `json:"extensions"`
+
+	// DistinctExtensions is the number of distinct extensions in
+	// `Extensions` (files without an extension count as one more).
+	DistinctExtensions counts.Count32 `json:"distinct_extensions"`
+
+	// DominantExtension is the extension with the most distinct
+	// blobs (of several with the same count, the one that sorts
+	// first), and DominantExtensionShare is the fraction of all
+	// of the distinct blobs that it has. A share close to 1 suggests
+	// a history that is mostly generated output, rather than a
+	// diverse codebase.
+	DominantExtension      string  `json:"dominant_extension"`
+	DominantExtensionShare float64 `json:"dominant_extension_share"`
+
+	// extensionBlobCount is the sum of the `BlobCount`s in
+	// `Extensions`.
+	extensionBlobCount counts.Count32
+
+	// The number of tree entries whose names are longer than the
+	// maximum filename length.
+	LongFilenameCount counts.Count32 `json:"long_filename_count"`
+
+	// Up to `maxLongFilenames` distinct names that are longer than
+	// the maximum filename length.
+	LongFilenames []LongFilename `json:"long_filenames,omitempty"`
+
+	// The number of distinct trees with more entries than the
+	// configured limit (see `ScanOptions.WideTreeEntries`).
+	WideTreeCount counts.Count32 `json:"wide_tree_count"`
+
+	// The widest of the trees counted in `WideTreeCount`, widest
+	// first.
+	WideTrees []WideTree `json:"wide_trees,omitempty"`
+
+	// The number of tree entries (in distinct trees) that refer to
+	// the empty blob; e.g., `.gitkeep` placeholder files.
+	EmptyBlobCount counts.Count32 `json:"empty_blob_count"`
+
+	// The number of tree entries (in distinct trees) that refer to
+	// the empty tree. Git doesn't normally create such entries.
+	EmptyTreeCount counts.Count32 `json:"empty_tree_count"`
+
+	// The number of tree entries (in distinct trees) whose names are
+	// empty. Such trees are corrupt; see `ScanOptions.Strict`.
+	EmptyFilenameCount counts.Count32 `json:"empty_filename_count"`
+
+	// The number of tree entries (in distinct trees) with the mode
+	// of a Unix socket, which can't be checked out portably.
+	UnixSocketCount counts.Count32 `json:"unix_socket_count"`
+
+	// The number of tree entries (in distinct trees) whose modes
+	// aren't of any type that Git knows about.
+	UnknownFiletypeCount counts.Count32 `json:"unknown_filetype_count"`
+
+	// The number of tree entries (in distinct trees) whose names
+	// differ only in case from an earlier entry of the same kind
+	// (e.g., two files `README` and `readme`) in the same tree.
+	CaseCollisionCount counts.Count32 `json:"case_collision_count"`
+
+	// The number of tree entries (in distinct trees) whose names
+	// differ only in case from an earlier entry of a different kind
+	// (e.g., a symlink `Config` and a file `config`) in the same
+	// tree.
+	MixedTypeCaseCollisionCount counts.Count32 `json:"mixed_type_case_collision_count"`
+
+	// Some examples of mixed-type case collisions.
+	MixedTypeCaseCollisions []CaseCollision `json:"mixed_type_case_collisions,omitempty"`
+
+	// EntryTypes counts the entries of each type in the distinct
+	// trees. See `ObjectTypeBreakdown()`.
+	EntryTypes TypeBreakdown `json:"entry_types"`
+
+	// RepositoryConfig describes the state of `HEAD` and the other
+	// symbolic references. It is only filled in if
+	// `ScanOptions.CheckSymbolicRefs` is set.
+	RepositoryConfig *RepositoryConfigStats `json:"repository_config,omitempty"`
+
+	// Storage describes how the repository's objects are stored on
+	// disk. It is only filled in if `ScanOptions.CheckStorage` is
+	// set.
+	Storage *StorageBreakdown `json:"storage,omitempty"`
+
+	// Bitmap describes the repository's reachability bitmap. It is
+	// only filled in if requested (see `CheckBitmap()`).
+	Bitmap *BitmapStatus `json:"bitmap,omitempty"`
+
+	// RelevantConfig holds the gitconfig settings that affect the
+	// repository's size (see `git.RelevantConfigKeys`). Settings
+	// that are not set are omitted. It is only filled in if
+	// `ScanOptions.CheckConfig` is set.
+	RelevantConfig map[string]string `json:"relevant_config,omitempty"`
+
+	// DefaultBranchShare compares the blob data that is reachable
+	// from the default branch with that reachable from all
+	// references. It is only filled in if
+	// `ScanOptions.DefaultBranch` is set.
+	DefaultBranchShare *DefaultBranchShare `json:"default_branch_share,omitempty"`
+
+	// RefKindShares attributes the reachable objects to branches,
+	// tags, and other references. It is only filled in if
+	// `ScanOptions.RefKinds` is set.
+	RefKindShares *RefKindShares `json:"ref_kind_shares,omitempty"`
+
+	// CorruptObjectCount is the number of objects whose contents
+	// don't hash to their OIDs. It is only computed if
+	// `ScanOptions.VerifyOIDs` is set; otherwise, it is nil.
+	CorruptObjectCount *counts.Count32 `json:"corrupt_object_count,omitempty"`
+
+	// CorruptObjects describes the objects whose contents don't hash
+	// to their OIDs.
+	CorruptObjects []git.OIDMismatch `json:"corrupt_objects,omitempty"`
+}
+
+// TotalObjectDataSize returns the total uncompressed size of all of
+// the unique objects analyzed. This is an upper bound on the amount
+// of data that `git clone` would have to transfer before delta
+// compression and zlib are taken into account.
+func (s *HistorySize) TotalObjectDataSize() counts.Count64 {
+	total := s.UniqueCommitSize
+	total.Increment(s.UniqueTreeSize)
+	total.Increment(s.UniqueBlobSize)
+	total.Increment(s.UniqueTagSize)
+	return total
+}
+
+// DedupRatio returns `ReferencedBlobSize / UniqueBlobSize`, i.e., how
+// many times each byte of blob data is referenced by a tree entry on
+// average, or zero if there are no blobs. A high ratio means that
+// content-addressing is eliminating a lot of redundancy, even though
+// the checkouts may still be big.
+func (s *HistorySize) DedupRatio() float64 {
+	if s.UniqueBlobSize == 0 {
+		return 0
+	}
+	return float64(s.ReferencedBlobSize) / float64(s.UniqueBlobSize)
+}
+
+// dedupPercent returns `DedupRatio()` as a rounded percentage.
+func (s *HistorySize) dedupPercent() counts.Count32 {
+	return counts.NewCount32(uint64(100*s.DedupRatio() + 0.5))
 }
 
 // Convenience function: forget `*path` if it is non-nil and overwrite
@@ -240,31 +733,87 @@ func (s *HistorySize) recordTree(
 	s.UniqueTreeCount.Increment(1)
 	s.UniqueTreeSize.Increment(counts.Count64(size))
 	s.UniqueTreeEntries.Increment(counts.Count64(treeEntries))
+	s.EdgeCount.Increment(counts.Count64(treeEntries))
 	if s.MaxTreeEntries.AdjustMaxIfNecessary(treeEntries) {
 		setPath(g.pathResolver, &s.MaxTreeEntriesTree, oid, "tree")
 	}
+	if int64(treeEntries) > int64(g.wideTreeEntries) {
+		s.recordWideTree(g, oid, treeEntries)
+	}
 
-	if s.MaxPathDepth.AdjustMaxIfNecessary(treeSize.MaxPathDepth) {
+	treeWidth := treeSize.MaxTreeWidth()
+	adjusted := s.MaxPathDepth.AdjustMaxIfNecessary(treeSize.MaxPathDepth)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxPathDepthTree, oid, "tree")
 	}
-	if s.MaxPathLength.AdjustMaxIfNecessary(treeSize.MaxPathLength) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedMaxPathDepth, adjusted,
+		s.MaxPathDepth == treeSize.MaxPathDepth, treeSize.Saturated,
+	)
+	adjusted = s.MaxPathLength.AdjustMaxIfNecessary(treeSize.MaxPathLength)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxPathLengthTree, oid, "tree")
 	}
-	if s.MaxExpandedTreeCount.AdjustMaxIfNecessary(treeSize.ExpandedTreeCount) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedMaxPathLength, adjusted,
+		s.MaxPathLength == treeSize.MaxPathLength, treeSize.Saturated,
+	)
+	adjusted = s.MaxExpandedTreeCount.AdjustMaxIfNecessary(treeSize.ExpandedTreeCount)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxExpandedTreeCountTree, oid, "tree")
 	}
-	if s.MaxExpandedBlobCount.AdjustMaxIfNecessary(treeSize.ExpandedBlobCount) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedExpandedTreeCount, adjusted,
+		s.MaxExpandedTreeCount == treeSize.ExpandedTreeCount, treeSize.Saturated,
+	)
+	adjusted = s.MaxExpandedBlobCount.AdjustMaxIfNecessary(treeSize.ExpandedBlobCount)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxExpandedBlobCountTree, oid, "tree")
 	}
-	if s.MaxExpandedBlobSize.AdjustMaxIfNecessary(treeSize.ExpandedBlobSize) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedExpandedBlobCount, adjusted,
+		s.MaxExpandedBlobCount == treeSize.ExpandedBlobCount, treeSize.Saturated,
+	)
+	adjusted = s.MaxExpandedBlobSize.AdjustMaxIfNecessary(treeSize.ExpandedBlobSize)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxExpandedBlobSizeTree, oid, "tree")
 	}
-	if s.MaxExpandedLinkCount.AdjustMaxIfNecessary(treeSize.ExpandedLinkCount) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedExpandedBlobSize, adjusted,
+		s.MaxExpandedBlobSize == treeSize.ExpandedBlobSize, treeSize.Saturated,
+	)
+	adjusted = s.MaxExpandedLinkCount.AdjustMaxIfNecessary(treeSize.ExpandedLinkCount)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxExpandedLinkCountTree, oid, "tree")
 	}
-	if s.MaxExpandedSubmoduleCount.AdjustMaxIfNecessary(treeSize.ExpandedSubmoduleCount) {
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedExpandedLinkCount, adjusted,
+		s.MaxExpandedLinkCount == treeSize.ExpandedLinkCount, treeSize.Saturated,
+	)
+	adjusted = s.MaxExpandedSubmoduleCount.AdjustMaxIfNecessary(treeSize.ExpandedSubmoduleCount)
+	if adjusted {
 		setPath(g.pathResolver, &s.MaxExpandedSubmoduleCountTree, oid, "tree")
 	}
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedExpandedSubmoduleCount, adjusted,
+		s.MaxExpandedSubmoduleCount == treeSize.ExpandedSubmoduleCount, treeSize.Saturated,
+	)
+	adjusted = s.MaxPathCount.AdjustMaxIfNecessary(treeSize.PathCount)
+	if adjusted {
+		setPath(g.pathResolver, &s.MaxPathCountTree, oid, "tree")
+	}
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedPathCount, adjusted,
+		s.MaxPathCount == treeSize.PathCount, treeSize.Saturated,
+	)
+	adjusted = s.MaxTreeWidth.AdjustMaxIfNecessary(treeWidth)
+	if adjusted {
+		setPath(g.pathResolver, &s.MaxTreeWidthTree, oid, "tree")
+	}
+	s.MaxTreeSaturated.adjustMax(
+		SaturatedMaxTreeWidth, adjusted,
+		s.MaxTreeWidth == treeWidth, treeSize.Saturated,
+	)
 }
 
 func (s *HistorySize) recordCommit(
@@ -273,6 +822,7 @@ func (s *HistorySize) recordCommit(
 ) {
 	s.UniqueCommitCount.Increment(1)
 	s.UniqueCommitSize.Increment(counts.Count64(size))
+	s.EdgeCount.Increment(1 + counts.Count64(parentCount))
 	if s.MaxCommitSize.AdjustMaxIfPossible(size) {
 		setPath(g.pathResolver, &s.MaxCommitSizeCommit, oid, "commit")
 	}
@@ -284,6 +834,8 @@ func (s *HistorySize) recordCommit(
 
 func (s *HistorySize) recordTag(g *Graph, oid git.OID, tagSize TagSize, size counts.Count32) {
 	s.UniqueTagCount.Increment(1)
+	s.UniqueTagSize.Increment(counts.Count64(size))
+	s.EdgeCount.Increment(1)
 	if s.MaxTagDepth.AdjustMaxIfNecessary(tagSize.TagDepth) {
 		setPath(g.pathResolver, &s.MaxTagDepthTag, oid, "tag")
 	}
@@ -291,6 +843,8 @@ func (s *HistorySize) recordTag(g *Graph, oid git.OID, tagSize TagSize, size cou
 
 func (s *HistorySize) recordReference(g *Graph, ref git.Reference) {
 	s.ReferenceCount.Increment(1)
+	s.RefStats.recordRefname(ref.Refname)
+	s.RefStats.recordRefNameConflicts(&g.refNames, ref.Refname)
 }
 
 func (s *HistorySize) recordReferenceGroup(g *Graph, group RefGroupSymbol) {
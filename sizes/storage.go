@@ -0,0 +1,86 @@
+package sizes
+
+import (
+	"fmt"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+)
+
+// StorageBreakdown describes how a repository's objects are stored
+// on disk.
+type StorageBreakdown struct {
+	// LooseObjectCount is the number of loose objects.
+	LooseObjectCount counts.Count32 `json:"loose_object_count"`
+
+	// LooseObjectSize is the disk space used by loose objects.
+	LooseObjectSize counts.Count64 `json:"loose_object_size"`
+
+	// RedundantLooseObjectCount is the number of loose objects that
+	// are also stored in a packfile. These are typically left
+	// behind by a repack that didn't prune the loose objects, and
+	// can be removed by `git prune-packed`.
+	RedundantLooseObjectCount counts.Count32 `json:"redundant_loose_object_count"`
+
+	// RedundantLooseObjectSize is the disk space used by the
+	// redundant loose objects.
+	RedundantLooseObjectSize counts.Count64 `json:"redundant_loose_object_size"`
+
+	// PackCount is the number of packfiles.
+	PackCount counts.Count32 `json:"pack_count"`
+
+	// PackSize is the disk space used by packfiles (not counting
+	// their indexes).
+	PackSize counts.Count64 `json:"pack_size"`
+}
+
+// ScanStorage examines how the objects in `repo` are stored.
+func ScanStorage(repo *git.Repository) (StorageBreakdown, error) {
+	var storage StorageBreakdown
+
+	packs, err := repo.Packfiles()
+	if err != nil {
+		return StorageBreakdown{}, err
+	}
+	for _, pack := range packs {
+		storage.PackCount.Increment(1)
+		storage.PackSize.Increment(pack.DiskSize)
+	}
+
+	loose, err := repo.LooseObjects()
+	if err != nil {
+		return StorageBreakdown{}, err
+	}
+	if len(loose) == 0 {
+		return storage, nil
+	}
+
+	looseOIDs := git.NewExactOIDSet()
+	for _, obj := range loose {
+		storage.LooseObjectCount.Increment(1)
+		storage.LooseObjectSize.Increment(obj.DiskSize)
+		looseOIDs.Add(obj.OID)
+	}
+
+	packed, err := repo.PackedObjects(looseOIDs)
+	if err != nil {
+		return StorageBreakdown{}, fmt.Errorf("reading pack indexes: %w", err)
+	}
+	for _, obj := range loose {
+		if packed.Contains(obj.OID) {
+			storage.RedundantLooseObjectCount.Increment(1)
+			storage.RedundantLooseObjectSize.Increment(obj.DiskSize)
+		}
+	}
+
+	return storage, nil
+}
+
+// storage returns `s.Storage`, or an empty `StorageBreakdown` if the
+// storage wasn't examined.
+func (s *HistorySize) storage() StorageBreakdown {
+	if s.Storage == nil {
+		return StorageBreakdown{}
+	}
+	return *s.Storage
+}
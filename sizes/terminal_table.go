@@ -0,0 +1,335 @@
+package sizes
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultTerminalWidth is the width that `TerminalTableString()` uses
+// if no width is specified (e.g., because the output is not going to
+// a terminal).
+const DefaultTerminalWidth = 80
+
+// minTerminalWidth is the narrowest width that the compact table
+// renderer will try to fit into.
+const minTerminalWidth = 40
+
+const (
+	ansiBold   = "\x1b[1m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+)
+
+// TerminalTableOptions controls how `TerminalTableString()` renders
+// its output.
+type TerminalTableOptions struct {
+	// Width is the number of columns available. If it is zero,
+	// `DefaultTerminalWidth` is used.
+	Width int
+
+	// Color controls whether ANSI escape sequences are used to
+	// highlight section headers and levels of concern.
+	Color bool
+
+	// Styles determines the names and colors of the levels of
+	// concern. Levels that it doesn't mention use
+	// `DefaultConcernStyles`.
+	Styles ConcernStyles
+
+	// LevelNames, if set, causes the name of each item's level of
+	// concern (e.g., "high") to be shown instead of a bar of stars.
+	LevelNames bool
+}
+
+// TerminalTableString renders the items whose level of concern is at
+// least `threshold` as a compact table that fits within
+// `opts.Width` columns. Unlike `TableString()`, the columns are sized
+// to the available width, the footnote citations get a column of
+// their own, and long footnotes are wrapped.
+func (s *HistorySize) TerminalTableString(
+	refGroups []RefGroup, threshold Threshold, nameStyle NameStyle,
+	opts TerminalTableOptions,
+) string {
+	width := opts.Width
+	if width == 0 {
+		width = DefaultTerminalWidth
+	}
+	if width < minTerminalWidth {
+		width = minTerminalWidth
+	}
+
+	r := newTerminalTable(width, threshold, nameStyle, opts)
+
+	var sections [][]string
+	if root, ok := s.contents(refGroups).(*section); ok {
+		for _, c := range root.contents {
+			if lines := r.render(c, 0); len(lines) > 0 {
+				sections = append(sections, lines)
+			}
+		}
+	}
+
+	if len(sections) == 0 {
+		return s.labelsHeader() + "No problems above the current threshold were found\n" +
+			s.problemsTrailer(refGroups)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s.labelsHeader())
+	r.writeHeader(&sb)
+	for i, lines := range sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		for _, line := range lines {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+	r.writeFootnotes(&sb)
+	sb.WriteString(s.problemsTrailer(refGroups))
+
+	return sb.String()
+}
+
+// terminalTable holds the state needed while rendering a compact
+// table.
+type terminalTable struct {
+	threshold  Threshold
+	nameStyle  NameStyle
+	color      bool
+	styles     ConcernStyles
+	levelNames bool
+	footnotes  *Footnotes
+
+	width     int
+	nameWidth int
+	barWidth  int
+}
+
+const (
+	// The value column holds a five-character number, a space,
+	// and a unit of up to three characters:
+	terminalValueWidth = 9
+
+	// The footnote citation column, e.g., "[12]":
+	terminalCitationWidth = 4
+
+	// The number of columns used by everything except the name and
+	// the level-of-concern bar, including the gaps between columns:
+	terminalFixedWidth = terminalValueWidth + terminalCitationWidth + 2 + 2 + 1
+
+	terminalPreferredNameWidth = 28
+	terminalMinBarWidth        = 8
+)
+
+func newTerminalTable(
+	width int, threshold Threshold, nameStyle NameStyle, opts TerminalTableOptions,
+) *terminalTable {
+	// Give the names as much room as the old-style table does, if
+	// possible, and the bar whatever is left over:
+	barWidth := width - terminalFixedWidth - terminalPreferredNameWidth
+	if barWidth > len(stars) {
+		barWidth = len(stars)
+	} else if barWidth < terminalMinBarWidth {
+		barWidth = terminalMinBarWidth
+	}
+
+	return &terminalTable{
+		threshold:  threshold,
+		nameStyle:  nameStyle,
+		color:      opts.Color,
+		styles:     opts.Styles,
+		levelNames: opts.LevelNames,
+		footnotes:  NewFootnotes(),
+		width:      width,
+		nameWidth:  width - terminalFixedWidth - barWidth,
+		barWidth:   barWidth,
+	}
+}
+
+// render returns the lines for `c`, indented for `depth`. It returns
+// nil if none of the items in `c` are interesting.
+func (r *terminalTable) render(c tableContents, depth int) []string {
+	switch c := c.(type) {
+	case *section:
+		childDepth := depth
+		if c.name != "" {
+			childDepth++
+		}
+		var lines []string
+		for _, child := range c.contents {
+			lines = append(lines, r.render(child, childDepth)...)
+		}
+		if len(lines) == 0 || c.name == "" {
+			return lines
+		}
+		return append([]string{r.formatSectionHeader(c.name, depth)}, lines...)
+
+	case *indentedItem:
+		return r.render(c.tableContents, depth+c.depth)
+
+	case *item:
+		if line, ok := r.formatItem(c, depth); ok {
+			return []string{line}
+		}
+		return nil
+
+	default:
+		panic(fmt.Sprintf("unexpected table contents type %T", c))
+	}
+}
+
+func (r *terminalTable) writeHeader(sb *strings.Builder) {
+	line := r.formatRow(
+		"Name", "Value", padRight(truncate("Level of concern", r.barWidth), r.barWidth), "Ref",
+	)
+	sb.WriteString(line)
+	sb.WriteString("\n")
+	sb.WriteString(strings.Repeat("-", r.width))
+	sb.WriteString("\n")
+}
+
+func (r *terminalTable) formatSectionHeader(name string, depth int) string {
+	header := truncate(strings.Repeat("  ", depth)+name, r.width)
+	if r.color {
+		return ansiBold + header + ansiReset
+	}
+	return header
+}
+
+// formatItem formats `i` as a table row, returning `"", false` if it
+// is below the threshold.
+func (r *terminalTable) formatItem(i *item, depth int) (string, bool) {
+	if _, ok := i.levelOfConcern(r.threshold); !ok {
+		return "", false
+	}
+	alert, overflow := i.alert()
+
+	valueString, unitString := i.humaner.Format(i.value, i.unit)
+	value := fmt.Sprintf("%s %-3s", padLeft(valueString, 5), unitString)
+
+	var bar, barColor string
+	if i.scale != 0 {
+		bar, barColor = r.bar(alert, overflow)
+	}
+	paddedBar := padRight(bar, r.barWidth)
+	if r.color && bar != "" && barColor != "" {
+		paddedBar = barColor + bar + ansiReset + paddedBar[len(bar):]
+	}
+
+	citation := r.footnotes.CreateCitation(i.Footnote(r.nameStyle))
+
+	return r.formatRow(strings.Repeat("  ", depth)+i.name, value, paddedBar, citation), true
+}
+
+// bar returns the level-of-concern bar for `alert` (or the name of its
+// level, if `r.levelNames` is set), truncated to fit in the bar column,
+// along with the color that it should be shown in. A bar that had to
+// be truncated ends in `+`.
+func (r *terminalTable) bar(alert Threshold, overflow bool) (string, string) {
+	level := ConcernLevelOf(alert, overflow)
+	style := r.styles.Style(level)
+
+	if r.levelNames {
+		return truncate(style.Name, r.barWidth), style.Color
+	}
+
+	var bar string
+	if level == ConcernCritical {
+		bar = strings.Repeat("!", len(stars))
+	} else {
+		bar = stars[:int(alert)]
+	}
+	if len(bar) > r.barWidth {
+		bar = bar[:r.barWidth-1] + "+"
+	}
+	return bar, style.Color
+}
+
+func (r *terminalTable) formatRow(name, value, bar, citation string) string {
+	line := fmt.Sprintf(
+		"%s  %s  %s %s",
+		padRight(truncate(name, r.nameWidth), r.nameWidth),
+		padRight(value, terminalValueWidth),
+		bar,
+		citation,
+	)
+	return strings.TrimRight(line, " ")
+}
+
+// writeFootnotes writes the footnotes, wrapping any that are too wide
+// for the terminal. Continuation lines are indented to line up with
+// the start of the footnote text.
+func (r *terminalTable) writeFootnotes(sb *strings.Builder) {
+	if len(r.footnotes.footnotes) == 0 {
+		return
+	}
+
+	const indent = terminalCitationWidth + 1
+	sb.WriteString("\n")
+	for i, footnote := range r.footnotes.footnotes {
+		citation := fmt.Sprintf("[%d]", i+1)
+		for j, line := range wrapPath(footnote, r.width-indent) {
+			if j == 0 {
+				fmt.Fprintf(sb, "%-*s %s\n", terminalCitationWidth, citation, line)
+			} else {
+				fmt.Fprintf(sb, "%s%s\n", spaces[:indent], line)
+			}
+		}
+	}
+}
+
+// wrapPath splits `s` into lines of at most `width` characters,
+// preferring to break after a `/`.
+func wrapPath(s string, width int) []string {
+	var lines []string
+	for utf8.RuneCountInString(s) > width {
+		cut := runeOffset(s, width)
+		if i := strings.LastIndexByte(s[:cut], '/'); i > 0 {
+			cut = i + 1
+		}
+		lines = append(lines, s[:cut])
+		s = s[cut:]
+	}
+	return append(lines, s)
+}
+
+// truncate shortens `s` to at most `width` characters, marking the
+// truncation with "...".
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= 3 {
+		return s[:runeOffset(s, width)]
+	}
+	return s[:runeOffset(s, width-3)] + "..."
+}
+
+// runeOffset returns the byte offset of the `n`th rune in `s`.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+func padLeft(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
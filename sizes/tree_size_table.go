@@ -0,0 +1,143 @@
+package sizes
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/github/git-sizer/counts"
+)
+
+// TableSortKey selects the order of the rows written by
+// `TreeSize.WriteTable`.
+type TableSortKey int
+
+const (
+	// TableSortByName orders the rows alphabetically by metric name.
+	TableSortByName TableSortKey = iota
+
+	// TableSortByValue orders the rows by how far their values
+	// exceed their thresholds, worst first.
+	TableSortByValue
+)
+
+// TableOptions controls how `TreeSize.WriteTable` formats its output.
+type TableOptions struct {
+	// Colors causes the status column to be colored using ANSI
+	// escape sequences.
+	Colors bool
+
+	// OnlyWarnings omits the rows whose values are below their
+	// thresholds.
+	OnlyWarnings bool
+
+	// SortBy selects the order of the rows.
+	SortBy TableSortKey
+}
+
+// treeSizeRow is one row of the table written by
+// `TreeSize.WriteTable`.
+type treeSizeRow struct {
+	name    string
+	value   counts.SizedCount
+	humaner counts.Humaner
+	unit    string
+
+	// scale is the value at which the metric starts to be
+	// concerning. It is the same as the scale of the corresponding
+	// "Biggest checkouts" item in the usual output.
+	scale float64
+}
+
+func (r treeSizeRow) alert() (Threshold, bool) {
+	value, overflow := r.value.ToUint64()
+	return Threshold(float64(value) / r.scale), overflow
+}
+
+func (s TreeSize) tableRows() []treeSizeRow {
+	sized := s.Sized()
+	return []treeSizeRow{
+		{"Number of directories", sized.ExpandedTreeCount, counts.Metric, "", 2000},
+		{"Maximum path depth", sized.MaxPathDepth, counts.Metric, "", 10},
+		{"Maximum path length", sized.MaxPathLength, counts.Binary, "B", 100},
+		{"Maximum tree width", sized.MaxTreeWidth, counts.Metric, "", 1000},
+		{"Number of files", sized.ExpandedBlobCount, counts.Metric, "", 50e3},
+		{"Total size of files", sized.ExpandedBlobSize, counts.Binary, "B", 1e9},
+		{"Number of symlinks", sized.ExpandedLinkCount, counts.Metric, "", 25e3},
+		{"Number of submodules", sized.ExpandedSubmoduleCount, counts.Metric, "", 100},
+		{"Number of Unix sockets", sized.ExpandedUnixSocketCount, counts.Metric, "", 1},
+		{"Number of unknown file types", sized.ExpandedUnknownFiletypeCount, counts.Metric, "", 1},
+		{"Number of paths", sized.PathCount, counts.Metric, "", 50e3},
+	}
+}
+
+// tableStatus returns the word shown in the status column for
+// `level`.
+func tableStatus(level ConcernLevel) (string, string) {
+	switch level {
+	case ConcernNone:
+		return "OK", ""
+	case ConcernCritical:
+		return "CRIT", ansiRed
+	default:
+		return "WARN", ansiYellow
+	}
+}
+
+// WriteTable writes the sizes of the checkout described by `s` to
+// `w` as an aligned table, showing each metric's value both as a
+// number and in human-readable form, alongside the threshold at
+// which it becomes concerning and a status of OK, WARN, or CRIT.
+func (s TreeSize) WriteTable(w io.Writer, opts TableOptions) error {
+	rows := s.tableRows()
+
+	switch opts.SortBy {
+	case TableSortByName:
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].name < rows[j].name
+		})
+	case TableSortByValue:
+		sort.SliceStable(rows, func(i, j int) bool {
+			ai, _ := rows[i].alert()
+			aj, _ := rows[j].alert()
+			return ai > aj
+		})
+	default:
+		return fmt.Errorf("unknown table sort key %d", opts.SortBy)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\tValue\tHuman\tThreshold\tStatus")
+
+	for _, row := range rows {
+		alert, overflow := row.alert()
+		level := ConcernLevelOf(alert, overflow)
+		if opts.OnlyWarnings && level == ConcernNone {
+			continue
+		}
+
+		numeral, unit := row.humaner.Format(row.value, row.unit)
+		thresholdNumeral, thresholdUnit := row.humaner.FormatNumber(
+			uint64(row.scale), row.unit,
+		)
+
+		// The status is the last column, so coloring it doesn't
+		// throw off the alignment of the others.
+		status, color := tableStatus(level)
+		if opts.Colors && color != "" {
+			status = color + status + ansiReset
+		}
+
+		fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\t%s\n",
+			row.name, row.value,
+			strings.TrimSpace(numeral+" "+unit),
+			strings.TrimSpace(thresholdNumeral+" "+thresholdUnit),
+			status,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,114 @@
+package sizes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/meter"
+)
+
+// TreemapNode is a directory, with the sizes of everything under it.
+type TreemapNode struct {
+	// Path is the path of the directory relative to the root tree,
+	// or "" for the root tree itself.
+	Path string
+
+	OID git.OID
+
+	// Size holds the recursive sizes of the tree.
+	Size TreeSize
+}
+
+// WalkTreemap calls `fn` for the tree of `treeish` (anything that `git
+// ls-tree` accepts; e.g., a commit or tree name) and for each tree
+// under it, parents before children, with its path and recursive
+// sizes. This is the data needed for a treemap of the tree.
+//
+// The recursive sizes are computed by scanning the tree, the same way
+// as for the checkout statistics. The directories are then streamed
+// from `git ls-tree`, so apart from the tree sizes themselves, memory
+// use doesn't depend on the size of the tree.
+func WalkTreemap(
+	ctx context.Context, repo *git.Repository, treeish string,
+	fn func(TreemapNode) error,
+) error {
+	oid, treeSizes, err := scanTreeSizes(ctx, repo, treeish)
+	if err != nil {
+		return err
+	}
+
+	node := func(path string, oid git.OID) (TreemapNode, error) {
+		size, ok := treeSizes.Get(oid)
+		if !ok {
+			return TreemapNode{}, fmt.Errorf("size of tree %s is not known", oid)
+		}
+		return TreemapNode{Path: path, OID: oid, Size: size}, nil
+	}
+
+	root, err := node("", oid)
+	if err != nil {
+		return err
+	}
+	if err := fn(root); err != nil {
+		return err
+	}
+
+	return repo.WalkTrees(ctx, oid.String(), func(entry git.TreeListEntry) error {
+		n, err := node(entry.Path, entry.OID)
+		if err != nil {
+			return err
+		}
+		return fn(n)
+	})
+}
+
+// scanTreeSizes resolves `treeish` to a tree and computes the sizes of
+// that tree and all of the trees under it.
+func scanTreeSizes(
+	ctx context.Context, repo *git.Repository, treeish string,
+) (git.OID, CacheBackend, error) {
+	oid, err := repo.ResolveObject(treeish + "^{tree}")
+	if err != nil {
+		return git.OID{}, nil, err
+	}
+
+	// Only the tree sizes are needed, so skip the optional analyses
+	// that `ScanRepositoryWithOptions()` might run:
+	treeSizes := NewMemoryCacheBackend()
+	opts := ScanOptions{NameStyle: NameStyleNone, TreeSizes: treeSizes}
+	_, err = scanGraph(
+		ctx, repo, []Root{NewExplicitRoot(treeish, oid)}, opts,
+		NewGraphWithOptions(opts), &objectVerifier{}, meter.NoProgressMeter,
+	)
+	if err != nil {
+		return git.OID{}, nil, fmt.Errorf("computing tree sizes: %w", err)
+	}
+
+	return oid, treeSizes, nil
+}
+
+// WriteTreemap writes one line to `w` for the tree of `treeish` and
+// each tree under it, consisting of the total size of the blobs under
+// the tree (counting duplicates), a TAB, and the tree's path ("." for
+// the root tree). See `WalkTreemap()`.
+func WriteTreemap(
+	ctx context.Context, repo *git.Repository, treeish string, w io.Writer,
+) error {
+	out := bufio.NewWriter(w)
+	err := WalkTreemap(ctx, repo, treeish, func(node TreemapNode) error {
+		path := node.Path
+		if path == "" {
+			path = "."
+		}
+		_, err := fmt.Fprintf(out, "%d\t%s\n", counts.Count64(node.Size.ExpandedBlobSize), path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,37 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+)
+
+// TypeBreakdown counts tree entries by the type of object that they
+// refer to.
+type TypeBreakdown struct {
+	TreeCount      counts.Count64 `json:"tree_count"`
+	BlobCount      counts.Count64 `json:"blob_count"`
+	SymlinkCount   counts.Count64 `json:"symlink_count"`
+	SubmoduleCount counts.Count64 `json:"submodule_count"`
+}
+
+// add adds the counts in `other` to `b`.
+func (b *TypeBreakdown) add(other TypeBreakdown) {
+	b.TreeCount.Increment(other.TreeCount)
+	b.BlobCount.Increment(other.BlobCount)
+	b.SymlinkCount.Increment(other.SymlinkCount)
+	b.SubmoduleCount.Increment(other.SubmoduleCount)
+}
+
+// ObjectTypeBreakdown returns the number of references of each type
+// that the scan encountered; i.e., the number of tree entries of each
+// type, summed over all of the distinct trees. Unlike the unique
+// object counts, an object that is referred to from several trees is
+// counted once per reference (but a tree that appears in several
+// commits is only examined once). The total equals
+// `UniqueTreeEntries`. It shows how much of the history is made up of
+// references to file contents, as opposed to structure.
+//
+// Entries with the modes of Unix sockets or of unknown types are
+// counted as submodules, since that is how Git reads them.
+func (s *HistorySize) ObjectTypeBreakdown() TypeBreakdown {
+	return s.EntryTypes
+}
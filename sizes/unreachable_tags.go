@@ -0,0 +1,270 @@
+package sizes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// BranchUnreachableTag describes a tag whose commit is not contained
+// in any branch.
+type BranchUnreachableTag struct {
+	// Name is the full name of the tag reference.
+	Name string `json:"name"`
+
+	// Target is the commit that the tag peels to.
+	Target git.OID `json:"target"`
+
+	// ExclusiveObjectCount and ExclusiveSize describe the objects
+	// that are reachable from this tag but neither from any branch
+	// nor from any of the other branch-unreachable tags; i.e., the
+	// objects that could be pruned if only this tag were deleted.
+	ExclusiveObjectCount counts.Count32 `json:"exclusive_object_count"`
+	ExclusiveSize        counts.Count64 `json:"exclusive_size"`
+}
+
+// BranchUnreachableTagStats summarizes the tags whose commits are not
+// contained in any branch.
+type BranchUnreachableTagStats struct {
+	// TagCount is the number of tags whose commits are not
+	// reachable from any branch.
+	TagCount counts.Count32 `json:"tag_count"`
+
+	// ObjectCount and Size describe the objects that are reachable
+	// from those tags but not from any branch.
+	ObjectCount counts.Count32 `json:"object_count"`
+	Size        counts.Count64 `json:"size"`
+
+	// Tags holds the details for the tags that keep the most data
+	// alive, biggest first.
+	Tags []BranchUnreachableTag `json:"tags,omitempty"`
+}
+
+// AnalyzeBranchUnreachableTags finds the tags (annotated or
+// lightweight) whose commits are not reachable from any branch, and
+// measures how much data is kept alive only by them. Details are
+// returned for at most `topN` tags. Tags that don't point (directly
+// or via other tags) at commits are ignored.
+func AnalyzeBranchUnreachableTags(
+	ctx context.Context, repo *git.Repository, topN int,
+) (BranchUnreachableTagStats, error) {
+	var stats BranchUnreachableTagStats
+
+	iter, err := repo.NewReferenceIter(ctx)
+	if err != nil {
+		return BranchUnreachableTagStats{}, err
+	}
+
+	var branches []git.OID
+	tagOIDs := make(map[string]git.OID)
+	var tagNames []string
+	for {
+		ref, ok, err := iter.Next()
+		if err != nil {
+			return BranchUnreachableTagStats{}, err
+		}
+		if !ok {
+			break
+		}
+		switch {
+		case strings.HasPrefix(ref.Refname, "refs/heads/"):
+			branches = append(branches, ref.OID)
+		case strings.HasPrefix(ref.Refname, "refs/tags/"):
+			tagOIDs[ref.Refname] = ref.OID
+			tagNames = append(tagNames, ref.Refname)
+		}
+	}
+
+	targets, err := repo.PeeledCommits(tagNames)
+	if err != nil {
+		return BranchUnreachableTagStats{}, err
+	}
+
+	var targetOIDs []git.OID
+	for _, name := range tagNames {
+		if target, ok := targets[name]; ok {
+			targetOIDs = append(targetOIDs, target)
+		}
+	}
+
+	// The tag targets that are not reachable from any branch:
+	unreachable, err := repo.ReachableCommits(targetOIDs, branches)
+	if err != nil {
+		return BranchUnreachableTagStats{}, err
+	}
+
+	var tags []BranchUnreachableTag
+	var roots []git.OID
+	for _, name := range tagNames {
+		target, ok := targets[name]
+		if !ok || !unreachable.Contains(target) {
+			continue
+		}
+		stats.TagCount.Increment(1)
+		tags = append(tags, BranchUnreachableTag{Name: name, Target: target})
+		roots = append(roots, tagOIDs[name])
+	}
+
+	// A single walk finds the objects that are reachable from those
+	// tags but not from any branch:
+	objectSizes := make(map[git.OID]counts.Count64)
+	var parents []git.OID
+	err = repo.WalkExclusiveObjects(
+		ctx, roots, branches, git.ExclusiveObjectsOptions{},
+		func(oid git.OID, objectType git.ObjectType, size counts.Count64) error {
+			stats.ObjectCount.Increment(1)
+			stats.Size.Increment(size)
+			objectSizes[oid] = size
+			if objectType != "blob" {
+				parents = append(parents, oid)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return BranchUnreachableTagStats{}, err
+	}
+
+	owners, err := exclusiveOwners(ctx, repo, roots, objectSizes, parents)
+	if err != nil {
+		return BranchUnreachableTagStats{}, err
+	}
+	for oid, owner := range owners {
+		if owner != sharedOwner {
+			tags[owner].ExclusiveObjectCount.Increment(1)
+			tags[owner].ExclusiveSize.Increment(objectSizes[oid])
+		}
+	}
+
+	top := topn.New(topN, func(t1, t2 BranchUnreachableTag) bool {
+		return t1.ExclusiveSize < t2.ExclusiveSize
+	})
+	for _, tag := range tags {
+		top.Add(tag)
+	}
+	stats.Tags = top.Items()
+
+	return stats, nil
+}
+
+// WriteBranchUnreachableTags writes a human-readable summary of
+// `stats` to `w`.
+func WriteBranchUnreachableTags(w io.Writer, stats BranchUnreachableTagStats) error {
+	value, unit := counts.Binary.Format(stats.Size, "B")
+	if _, err := fmt.Fprintf(
+		w, "Tags not reachable from any branch: %d (keeping %d objects, %s %s, alive)\n",
+		stats.TagCount, stats.ObjectCount, value, unit,
+	); err != nil {
+		return err
+	}
+	for _, tag := range stats.Tags {
+		value, unit := counts.Binary.Format(tag.ExclusiveSize, "B")
+		if _, err := fmt.Fprintf(
+			w, "    %s %s  %s (%s)\n", value, unit, tag.Name, tag.Target,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedOwner is the owner recorded by `exclusiveOwners()` for objects
+// that are reachable from more than one root.
+const sharedOwner = -1
+
+// exclusiveOwners determines, for each of the objects in `objects`,
+// which of `roots` it is reachable from (without passing through
+// objects outside of `objects`). The result maps each object to the
+// index of the only root that it is reachable from, or to
+// `sharedOwner` if it is reachable from more than one. `parents` are
+// the objects in `objects` that can refer to other objects; they are
+// read in a single `git cat-file --batch` process.
+func exclusiveOwners(
+	ctx context.Context, repo *git.Repository, roots []git.OID,
+	objects map[git.OID]counts.Count64, parents []git.OID,
+) (map[git.OID]int, error) {
+	children := make(map[git.OID][]git.OID, len(parents))
+	addChild := func(parent, child git.OID) {
+		if _, ok := objects[child]; ok {
+			children[parent] = append(children[parent], child)
+		}
+	}
+	err := readObjects(ctx, repo, parents, func(obj git.ObjectRecord) error {
+		switch obj.ObjectType {
+		case "commit":
+			commit, err := git.ParseCommit(obj.OID, obj.Data)
+			if err != nil {
+				return err
+			}
+			addChild(obj.OID, commit.Tree)
+			for _, parent := range commit.Parents {
+				addChild(obj.OID, parent)
+			}
+		case "tree":
+			tree, err := git.ParseTree(obj.OID, obj.Data)
+			if err != nil {
+				return err
+			}
+			iter := tree.Iter()
+			for {
+				entry, ok, err := iter.NextEntry()
+				if err != nil {
+					return err
+				}
+				if !ok {
+					break
+				}
+				addChild(obj.OID, entry.OID)
+			}
+		case "tag":
+			tag, err := git.ParseTag(obj.OID, obj.Data)
+			if err != nil {
+				return err
+			}
+			addChild(obj.OID, tag.Referent)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Propagate the owners down from the roots. Each object's owner
+	// changes at most twice (from unset to a root, then to
+	// `sharedOwner`), so this is linear in the number of objects.
+	type item struct {
+		oid   git.OID
+		owner int
+	}
+	owners := make(map[git.OID]int, len(objects))
+	for i, root := range roots {
+		stack := []item{{root, i}}
+		for len(stack) > 0 {
+			it := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if _, ok := objects[it.oid]; !ok {
+				continue
+			}
+			owner, seen := owners[it.oid]
+			switch {
+			case !seen:
+				owners[it.oid] = it.owner
+			case owner == it.owner || owner == sharedOwner:
+				continue
+			default:
+				it.owner = sharedOwner
+				owners[it.oid] = sharedOwner
+			}
+			for _, child := range children[it.oid] {
+				stack = append(stack, item{child, it.owner})
+			}
+		}
+	}
+
+	return owners, nil
+}
@@ -0,0 +1,52 @@
+package sizes
+
+import (
+	"context"
+
+	"github.com/github/git-sizer/git"
+)
+
+// objectVerifier checks that the objects read during a scan hash to
+// their OIDs, if `ScanOptions.VerifyOIDs` is set.
+type objectVerifier struct {
+	enabled bool
+
+	// blobs are the blobs that still have to be verified. (The
+	// scan only reads their sizes, so they have to be read again.)
+	blobs []git.OID
+
+	mismatches []git.OIDMismatch
+}
+
+// addBlob remembers to verify blob `oid` later.
+func (v *objectVerifier) addBlob(oid git.OID) {
+	if v.enabled {
+		v.blobs = append(v.blobs, oid)
+	}
+}
+
+// check verifies an object whose contents have already been read.
+func (v *objectVerifier) check(obj git.ObjectRecord) {
+	if !v.enabled {
+		return
+	}
+	if computed := git.ComputeOID(obj.ObjectType, obj.Data); computed != obj.OID {
+		v.mismatches = append(v.mismatches, git.OIDMismatch{
+			OID:         obj.OID,
+			ObjectType:  obj.ObjectType,
+			ComputedOID: computed,
+		})
+	}
+}
+
+// finish verifies the blobs and returns all of the mismatches that
+// were found.
+func (v *objectVerifier) finish(
+	ctx context.Context, repo *git.Repository,
+) ([]git.OIDMismatch, error) {
+	mismatches, err := repo.VerifyObjects(ctx, v.blobs)
+	if err != nil {
+		return nil, err
+	}
+	return append(v.mismatches, mismatches...), nil
+}
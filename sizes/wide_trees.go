@@ -0,0 +1,65 @@
+package sizes
+
+import (
+	"github.com/github/git-sizer/counts"
+	"github.com/github/git-sizer/git"
+	"github.com/github/git-sizer/internal/topn"
+)
+
+// DefaultWideTreeEntries is the default number of entries above which
+// a tree is reported as too wide. Git gets slow when a single
+// directory has tens of thousands of entries.
+const DefaultWideTreeEntries = 10000
+
+// maxWideTrees is the number of the widest trees that are recorded in
+// `HistorySize.WideTrees`.
+const maxWideTrees = 10
+
+// WideTree describes a tree with more entries than the configured
+// limit.
+type WideTree struct {
+	// Entries is the number of entries in the tree.
+	Entries counts.Count32 `json:"entries"`
+
+	Tree *Path `json:"tree,omitempty"`
+}
+
+// recordWideTree records that the tree `oid`, which has `entries`
+// entries, exceeds the limit on the number of entries. Only the
+// widest `maxWideTrees` trees are remembered.
+func (s *HistorySize) recordWideTree(g *Graph, oid git.OID, entries counts.Count32) {
+	s.WideTreeCount.Increment(1)
+
+	if g.wideTrees == nil {
+		g.wideTrees = topn.New(maxWideTrees, func(wt1, wt2 WideTree) bool {
+			return wt1.Entries < wt2.Entries
+		})
+	}
+
+	// A tree that isn't wider than the narrowest one that is
+	// retained would be rejected anyway; don't resolve its path:
+	narrowest, ok := g.wideTrees.Min()
+	if g.wideTrees.Full() && ok && entries <= narrowest.Entries {
+		return
+	}
+
+	wt := WideTree{Entries: entries}
+	setPath(g.pathResolver, &wt.Tree, oid, "tree")
+	if g.wideTrees.Full() && ok {
+		g.pathResolver.ForgetPath(narrowest.Tree)
+	}
+	g.wideTrees.Add(wt)
+
+	// Widest first, and earlier ones first among those with the
+	// same number of entries:
+	s.WideTrees = g.wideTrees.Items()
+}
+
+// wideTreeTree returns the widest of the trees that exceed the limit
+// on the number of entries, or nil if there were none.
+func wideTreeTree(s *HistorySize) *Path {
+	if len(s.WideTrees) == 0 {
+		return nil
+	}
+	return s.WideTrees[0].Tree
+}